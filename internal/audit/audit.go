@@ -0,0 +1,238 @@
+// Package audit keeps one log of the changes made to every entity: who
+// changed which one, how, and when. Services record each change once it is
+// made:
+//
+//	audit.Record(ctx, audit.Product, p.Id, audit.Update, audit.Diff(before, p))
+//
+// The actor is the user ctx acts for, none for startup and background jobs,
+// and entries belong to the store of ctx. Recording is best-effort: the
+// change has already been made, so a failure is logged rather than
+// returned. Admins read the log through GET /audit.
+//
+// Stock movements have their own ledger (inventory.StockMovement), and user
+// activity (logins, tokens) stays with the user package as the account's
+// security log; neither is repeated here.
+package audit
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/utils"
+)
+
+// Entry is one change to an entity.
+type Entry struct {
+	Id         int            `json:"id"`
+	ActorId    *int           `json:"actor_id,omitempty"` // Nil for changes made at startup or by background jobs
+	EntityType string         `json:"entity_type"`
+	EntityId   int            `json:"entity_id"`
+	Action     string         `json:"action"`
+	Diff       map[string]any `json:"diff,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// Entity types.
+const (
+	Product   = "product"
+	Inventory = "inventory"
+	Order     = "order"
+	User      = "user"
+	Role      = "role"
+	Location  = "location"
+	Store     = "store"
+	Tag       = "tag" // Managed inventory tags and labels
+)
+
+// Actions every entity shares; entities add their own, such as "payment"
+// for orders.
+const (
+	Create  = "create"
+	Update  = "update"
+	Delete  = "delete"
+	Restore = "restore"
+	Purge   = "purge"
+)
+
+// Filter narrows a listing of the log. Zero fields match everything, and
+// zero times leave that end of the range open; both ends are inclusive.
+type Filter struct {
+	EntityType string
+	EntityId   int // Only with EntityType
+	ActorId    int
+	Action     string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+// Log is where entries are kept.
+type Log interface {
+	Append(ctx context.Context, e *Entry) error
+	List(ctx context.Context, f Filter) ([]*Entry, error) // Newest first
+}
+
+var auditLog Log
+
+// SetLog sets the log Record appends to and List reads; nil, the default,
+// records nothing.
+func SetLog(l Log) {
+	auditLog = l
+}
+
+// Record appends a change to the log. diff is what changed, usually from
+// Diff; nil for changes that speak for themselves, like a delete. Values of
+// custom keys stored encrypted (see database.NewFieldCipher) are left out,
+// only noting that they changed.
+func Record(ctx context.Context, entityType string, entityId int, action string, diff map[string]any) {
+	if auditLog == nil {
+		return
+	}
+
+	e := &Entry{EntityType: entityType, EntityId: entityId, Action: action, Diff: diff}
+	if actor, ok := ctx.Value(utils.UserIDKey).(int); ok {
+		e.ActorId = &actor
+	}
+	for name := range diff {
+		key, ok := strings.CutPrefix(name, "custom.")
+		if ok && database.EncryptedField(entityType, key) {
+			diff[name] = map[string]any{"encrypted": true}
+		}
+	}
+
+	if err := auditLog.Append(ctx, e); err != nil {
+		log.Printf("audit: failed to record %s of %s %d: %v", action, entityType, entityId, err)
+	}
+}
+
+// List reads the log, newest first. Without a log set it is always empty.
+func List(ctx context.Context, f Filter) ([]*Entry, error) {
+	if auditLog == nil {
+		return []*Entry{}, nil
+	}
+	return auditLog.List(ctx, f)
+}
+
+// bookkeeping are the fields Diff leaves out: they change with every write
+// or identify the entity rather than describe it.
+var bookkeeping = map[string]bool{
+	"Id": true, "Revision": true, "Version": true, "StoreId": true,
+	"Created": true, "Updated": true, "Deleted": true,
+	"CreatedAt": true, "UpdatedAt": true, "DeletedAt": true,
+}
+
+// Diff describes how an entity changed from before to after, two values of
+// one struct type or pointers to them: every changed field as
+// {"from": ..., "to": ...} under its JSON name. A nil before describes a
+// new entity, with only "to" for the fields that are set. Maps of
+// map[string]any (Custom) are compared key by key, as "custom.<key>".
+// Bookkeeping fields, those hidden from JSON and those tagged audit:"-" are
+// left out.
+func Diff(before, after any) map[string]any {
+	from, to := fields(before), fields(after)
+	created := len(from) == 0
+	out := map[string]any{}
+	for name, t := range to {
+		f, ok := from[name]
+		switch {
+		case created:
+			if !isZero(t) {
+				out[name] = map[string]any{"to": t}
+			}
+		case !ok:
+			out[name] = map[string]any{"to": t}
+		case !equal(f, t):
+			out[name] = map[string]any{"from": f, "to": t}
+		}
+	}
+	for name, f := range from {
+		if _, ok := to[name]; !ok {
+			out[name] = map[string]any{"from": f}
+		}
+	}
+	return out
+}
+
+// fields flattens a struct into its audited fields by name.
+func fields(v any) map[string]any {
+	out := map[string]any{}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return out
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return out
+	}
+
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		f := rt.Field(i)
+		if !f.IsExported() || bookkeeping[f.Name] || f.Tag.Get("audit") == "-" {
+			continue
+		}
+		name := fieldName(f)
+		if name == "" {
+			continue
+		}
+
+		fv := reflect.Indirect(rv.Field(i))
+		if !fv.IsValid() {
+			out[name] = nil // A nil pointer
+			continue
+		}
+		if m, ok := fv.Interface().(map[string]any); ok {
+			for k, x := range m {
+				out[name+"."+k] = x
+			}
+			continue
+		}
+		out[name] = fv.Interface()
+	}
+	return out
+}
+
+func isZero(v any) bool {
+	return v == nil || reflect.ValueOf(v).IsZero()
+}
+
+func equal(a, b any) bool {
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// fieldName is the JSON name of f, or its Go name in snake case where it
+// has none; "" if JSON leaves it out.
+func fieldName(f reflect.StructField) string {
+	if tag, ok := f.Tag.Lookup("json"); ok {
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+
+	// "LastLoginIP" is last_login_ip, "AvatarURL" avatar_url
+	r := []rune(f.Name)
+	var b strings.Builder
+	for i, c := range r {
+		if unicode.IsUpper(c) && i > 0 && (unicode.IsLower(r[i-1]) || i+1 < len(r) && unicode.IsLower(r[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(c))
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/iteranya/practicing-go/internal/utils"
+)
+
+var entityTypes = []string{Product, Inventory, Order, User, Role, Location, Store, Tag}
+
+type Handler struct {
+	log Log
+}
+
+func NewHandler(l Log) *Handler {
+	return &Handler{log: l}
+}
+
+// The log spans every entity, user accounts included, so reading it takes
+// the user admin permission.
+func (h *Handler) Routes() []utils.Route {
+	return []utils.Route{
+		{Pattern: "GET /audit", Perm: utils.UserAdmin, Handler: h.HandleList}, // ?entity=product&entity_id=3&actor=5&action=update&created_from=...&created_to=...
+	}
+}
+
+// LIST (newest first)
+func (h *Handler) HandleList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit <= 0 {
+		limit = 50
+	}
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+
+	f := Filter{EntityType: query.Get("entity"), Action: query.Get("action"), Limit: limit, Offset: (page - 1) * limit}
+	if f.EntityType != "" && !slices.Contains(entityTypes, f.EntityType) {
+		http.Error(w, "Invalid entity", http.StatusBadRequest)
+		return
+	}
+	for _, p := range []struct {
+		name string
+		dest *int
+	}{{"entity_id", &f.EntityId}, {"actor", &f.ActorId}} {
+		if s := query.Get(p.name); s != "" {
+			id, err := strconv.Atoi(s)
+			if err != nil {
+				http.Error(w, "Invalid "+p.name, http.StatusBadRequest)
+				return
+			}
+			*p.dest = id
+		}
+	}
+	if f.EntityId != 0 && f.EntityType == "" {
+		http.Error(w, "entity_id needs entity", http.StatusBadRequest)
+		return
+	}
+	period := utils.PeriodFromQuery(query)
+	f.From, f.To = period.CreatedFrom, period.CreatedTo
+
+	entries, err := h.log.List(r.Context(), f)
+	if err != nil {
+		h.respondWithJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, entries)
+}
+
+func (h *Handler) respondWithJSON(w http.ResponseWriter, code int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/iteranya/practicing-go/internal/database"
+)
+
+type repository struct {
+	db      *database.DB
+	dialect database.Dialect
+}
+
+// NewRepository returns the log kept in the audit_log table.
+func NewRepository(db *sql.DB) Log {
+	return &repository{db: database.Observe(db, "audit_log"), dialect: database.DialectOf(db)}
+}
+
+// Append adds an entry to the log of the store of ctx.
+func (r *repository) Append(ctx context.Context, e *Entry) error {
+	var diffJSON []byte
+	if e.Diff != nil {
+		var err error
+		if diffJSON, err = json.Marshal(e.Diff); err != nil {
+			return fmt.Errorf("failed to marshal audit diff: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO audit_log (store_id, actor_id, entity_type, entity_id, action, diff)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	args := []any{database.StoreOf(ctx), e.ActorId, e.EntityType, e.EntityId, e.Action, diffJSON}
+
+	err := r.dialect.InsertReturning(ctx, r.db, "audit_log", query, "id, created_at", args, &e.Id, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to append audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the entries of the store of ctx matching f, newest first.
+func (r *repository) List(ctx context.Context, f Filter) ([]*Entry, error) {
+	where := "store_id = $1"
+	args := []any{database.StoreOf(ctx)}
+	argPos := 2
+
+	for _, c := range []struct {
+		cond string
+		val  any
+		set  bool
+	}{
+		{"entity_type = $%d", f.EntityType, f.EntityType != ""},
+		{"entity_id = $%d", f.EntityId, f.EntityType != "" && f.EntityId != 0},
+		{"actor_id = $%d", f.ActorId, f.ActorId != 0},
+		{"action = $%d", f.Action, f.Action != ""},
+	} {
+		if !c.set {
+			continue
+		}
+		where += " AND " + fmt.Sprintf(c.cond, argPos)
+		args = append(args, c.val)
+		argPos++
+	}
+	period, periodArgs := database.Period{CreatedFrom: f.From, CreatedTo: f.To}.Where(argPos)
+	where += period
+	args = append(args, periodArgs...)
+	argPos += len(periodArgs)
+
+	query := fmt.Sprintf(`
+		SELECT id, actor_id, entity_type, entity_id, action, diff, created_at
+		FROM audit_log
+		WHERE %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, where, argPos, argPos+1)
+	args = append(args, f.Limit, f.Offset)
+
+	entries, err := database.Select(ctx, r.db, scanEntry, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log: %w", err)
+	}
+	if entries == nil {
+		entries = []*Entry{}
+	}
+	return entries, nil
+}
+
+func scanEntry(scanner database.Scanner) (*Entry, error) {
+	e := &Entry{}
+	var actorId sql.NullInt64
+	var diffJSON []byte
+
+	if err := scanner.Scan(&e.Id, &actorId, &e.EntityType, &e.EntityId, &e.Action, &diffJSON, &e.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+	}
+	if actorId.Valid {
+		id := int(actorId.Int64)
+		e.ActorId = &id
+	}
+	if len(diffJSON) > 0 {
+		if err := json.Unmarshal(diffJSON, &e.Diff); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal audit diff: %w", err)
+		}
+	}
+	return e, nil
+}
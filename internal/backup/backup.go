@@ -0,0 +1,236 @@
+// Package backup writes everything the POS keeps (roles, locations, users,
+// inventory, products and orders, trash included) as one JSON archive for
+// off-site copies, and restores it (see Restore):
+//
+//	{"version": 1, "created_at": "...", "roles": [...], "locations": [...], ...}
+//
+// Sections come in dependency order, each after the ones its rows refer to.
+// Entities appear as their own APIs return them. The archive is encoded as
+// it is read, a batch of rows at a time, so a large order history never has
+// to fit in memory. Rows are read outside a transaction: on a busy store,
+// take backups when it is closed for a consistent copy.
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/entities/inventory"
+	"github.com/iteranya/practicing-go/internal/entities/location"
+	"github.com/iteranya/practicing-go/internal/entities/order"
+	"github.com/iteranya/practicing-go/internal/entities/product"
+	"github.com/iteranya/practicing-go/internal/entities/role"
+	"github.com/iteranya/practicing-go/internal/entities/user"
+)
+
+// Version is the archive format. It goes up when a restore would have to
+// read an archive differently.
+const Version = 1
+
+// batchSize is how many rows are read per query for the larger tables.
+const batchSize = 500
+
+type Options struct {
+	Hashes bool // Include password hashes, so accounts keep their passwords on restore
+}
+
+// Sources are the repositories the archive is read from.
+type Sources struct {
+	Roles     role.RoleRepository
+	Users     user.UserRepository
+	Locations location.LocationRepository
+	Inventory inventory.InventoryRepository
+	Products  product.ProductRepository
+	Orders    order.OrderRepository
+}
+
+// userRecord is a user as archived: the API fields, the stores they are
+// assigned to and, if asked for, the password hash.
+type userRecord struct {
+	user.UserResponse
+	Anonymized bool   `json:"anonymized,omitempty"`
+	Locations  []int  `json:"locations,omitempty"`
+	Hash       string `json:"hash,omitempty"`
+}
+
+// section is one array of the archive. each passes its rows to emit in
+// order.
+type section struct {
+	name string
+	each func(ctx context.Context, emit func(any) error) error
+}
+
+// Write writes the archive to w. On error w holds a truncated archive,
+// which won't parse.
+func Write(ctx context.Context, w io.Writer, src Sources, opts Options) error {
+	header, err := json.Marshal(struct {
+		Version   int       `json:"version"`
+		CreatedAt time.Time `json:"created_at"`
+	}{Version, time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	// Reopen the header object so the sections follow as its keys
+	if _, err := w.Write(header[:len(header)-1]); err != nil {
+		return err
+	}
+
+	for _, s := range src.sections(opts) {
+		if err := writeSection(ctx, w, s); err != nil {
+			return fmt.Errorf("writing %s: %w", s.name, err)
+		}
+	}
+
+	_, err = io.WriteString(w, "}\n")
+	return err
+}
+
+func (src Sources) sections(opts Options) []section {
+	return []section{
+		{"roles", func(ctx context.Context, emit func(any) error) error {
+			for _, list := range []func(context.Context) ([]*role.Role, error){src.Roles.List, src.Roles.ListDeleted} {
+				roles, err := list(ctx)
+				if err != nil {
+					return err
+				}
+				if err := emitAll(roles, emit); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"locations", func(ctx context.Context, emit func(any) error) error {
+			locations, err := src.Locations.List(ctx)
+			if err != nil {
+				return err
+			}
+			return emitAll(locations, emit)
+		}},
+		{"users", func(ctx context.Context, emit func(any) error) error {
+			// Live, trashed and anonymized: orders keep pointing at all of them
+			for _, list := range []user.UserListOptions{{}, {Deleted: true}, {Deleted: true, Anonymized: true}} {
+				list.SortBy, list.Limit = "id", batchSize
+				err := batches(func(offset int) ([]*user.User, error) {
+					list.Offset = offset
+					users, _, err := src.Users.List(ctx, list)
+					return users, err
+				}, func(u *user.User) error {
+					locations, err := src.Users.GetLocationIDs(ctx, u.Id)
+					if err != nil {
+						return err
+					}
+					rec := userRecord{UserResponse: user.NewUserResponse(u), Anonymized: list.Anonymized, Locations: locations}
+					if opts.Hashes {
+						rec.Hash = u.Hash
+					}
+					return emit(rec)
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"inventory", func(ctx context.Context, emit func(any) error) error {
+			for _, deleted := range []bool{false, true} {
+				err := batches(func(offset int) ([]*inventory.Inventory, error) {
+					items, _, err := src.Inventory.List(ctx, inventory.ListOptions{
+						Deleted: deleted, SortBy: "id", Limit: batchSize, Offset: offset,
+					})
+					return items, err
+				}, func(inv *inventory.Inventory) error { return emit(inv) })
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"products", func(ctx context.Context, emit func(any) error) error {
+			for _, deleted := range []bool{false, true} {
+				err := batches(func(offset int) ([]*product.Product, error) {
+					products, _, err := src.Products.List(ctx, product.ProductListOptions{
+						Deleted: deleted, SortBy: "id", Limit: batchSize, Offset: offset,
+					})
+					return products, err
+				}, func(p *product.Product) error { return emit(p) })
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"orders", func(ctx context.Context, emit func(any) error) error {
+			// Archived ones too; they restore into orders and are archived again
+			for _, archived := range []bool{false, true} {
+				err := batches(func(offset int) ([]*order.Order, error) {
+					orders, _, err := src.Orders.List(ctx, order.OrderListOptions{
+						Archived: archived, SortBy: "id", SortOrder: "asc", Limit: batchSize, Offset: offset,
+					})
+					return orders, err
+				}, func(o *order.Order) error { return emit(o) })
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+	}
+}
+
+// writeSection writes s as `,"name":[...]`, one row per line.
+func writeSection(ctx context.Context, w io.Writer, s section) error {
+	if _, err := fmt.Fprintf(w, ",%q:[", s.name); err != nil {
+		return err
+	}
+
+	sep := "\n"
+	err := s.each(ctx, func(v any) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, sep); err != nil {
+			return err
+		}
+		sep = ",\n"
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n]")
+	return err
+}
+
+// batches calls fetch with growing offsets until it returns a short batch,
+// handing each row to fn.
+func batches[T any](fetch func(offset int) ([]T, error), fn func(T) error) error {
+	for offset := 0; ; offset += batchSize {
+		rows, err := fetch(offset)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		if len(rows) < batchSize {
+			return nil
+		}
+	}
+}
+
+func emitAll[T any](rows []T, emit func(any) error) error {
+	for _, row := range rows {
+		if err := emit(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
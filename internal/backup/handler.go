@@ -0,0 +1,115 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/utils"
+)
+
+// superuser is what a caller needs to download or restore a backup: the
+// archive holds every resource, so every resource's admin permission.
+var superuser = []string{
+	utils.InventoryAdmin, utils.OrderAdmin, utils.ProductAdmin,
+	utils.UserAdmin, utils.RoleAdmin, utils.LocationAdmin,
+}
+
+type Handler struct {
+	src Sources
+	txm database.TxManager
+}
+
+func NewHandler(src Sources, txm database.TxManager) *Handler {
+	return &Handler{src: src, txm: txm}
+}
+
+func (h *Handler) Routes() []utils.Route {
+	return []utils.Route{
+		{Pattern: "GET /backup", Perm: utils.UserAdmin, Handler: h.HandleBackup},           // ?hashes=true adds password hashes
+		{Pattern: "POST /backup/restore", Perm: utils.UserAdmin, Handler: h.HandleRestore}, // ?skip_conflicts=true&dry_run=true
+	}
+}
+
+// BACKUP
+// Streams the archive as a download. Once it has started, an error can only
+// cut it short; the truncated JSON won't parse, and the cause is logged.
+func (h *Handler) HandleBackup(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// The server's WriteTimeout would otherwise cut a large archive off
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	utils.LiftRequestTimeout(r.Context())
+
+	hashes, _ := strconv.ParseBool(r.URL.Query().Get("hashes"))
+	filename := "backup-" + time.Now().UTC().Format("2006-01-02") + ".json"
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	if err := Write(r.Context(), w, h.src, Options{Hashes: hashes}); err != nil {
+		log.Printf("Backup failed: %v", err)
+	}
+}
+
+// RESTORE
+// Loads an archive sent as the request body and responds with the report.
+// Conflicts respond 409 with the report and load nothing, unless
+// skip_conflicts is set.
+func (h *Handler) HandleRestore(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+
+	// The server's ReadTimeout would otherwise cut a large archive off
+	if err := http.NewResponseController(w).SetReadDeadline(time.Time{}); err != nil {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	skip, _ := strconv.ParseBool(query.Get("skip_conflicts"))
+	dryRun, _ := strconv.ParseBool(query.Get("dry_run"))
+
+	report, err := Restore(r.Context(), h.txm, r.Body, h.src, RestoreOptions{SkipConflicts: skip, DryRun: dryRun})
+	switch {
+	case errors.Is(err, ErrConflicts):
+		h.respondWithJSON(w, http.StatusConflict, map[string]any{"error": err.Error(), "report": report})
+	case errors.Is(err, ErrInvalidArchive), errors.Is(err, ErrUnsupportedVersion):
+		h.respondWithJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case err != nil:
+		h.respondWithJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	default:
+		h.respondWithJSON(w, http.StatusOK, report)
+	}
+}
+
+// authorize checks the caller holds every resource's admin permission,
+// responding 403 if not.
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	subject, _ := utils.SubjectFrom(r.Context())
+	for _, perm := range superuser {
+		if !subject.Can(perm) {
+			http.Error(w, "Access Denied: Missing "+perm, http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
+
+func (h *Handler) respondWithJSON(w http.ResponseWriter, code int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,299 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/entities/inventory"
+	"github.com/iteranya/practicing-go/internal/entities/location"
+	"github.com/iteranya/practicing-go/internal/entities/order"
+	"github.com/iteranya/practicing-go/internal/entities/product"
+	"github.com/iteranya/practicing-go/internal/entities/role"
+	"github.com/iteranya/practicing-go/internal/entities/user"
+)
+
+var (
+	ErrInvalidArchive     = errors.New("invalid backup archive")
+	ErrUnsupportedVersion = errors.New("unsupported backup version")
+	ErrConflicts          = errors.New("backup conflicts with existing data")
+)
+
+// errDryRun rolls a dry run back once it has gone through the archive.
+var errDryRun = errors.New("dry run")
+
+type RestoreOptions struct {
+	SkipConflicts bool // Keep the existing rows that clash and load the rest, instead of loading nothing
+	DryRun        bool // Go through the whole archive, then roll back
+}
+
+// Conflict is an archived row that clashes with one already in the
+// database, by id or by a unique key such as the slug.
+type Conflict struct {
+	Section string `json:"section"`
+	Id      int    `json:"id"`
+	Key     string `json:"key,omitempty"` // Slug, or username for users
+}
+
+// Report says what Restore loaded, or would have.
+type Report struct {
+	Version   int            `json:"version"`
+	Restored  map[string]int `json:"restored"` // Rows loaded per section
+	Conflicts []Conflict     `json:"conflicts"`
+	DryRun    bool           `json:"dry_run,omitempty"`
+}
+
+// Restore loads an archive written by Write, in one transaction: either all
+// of it goes in or none of it does. Rows keep their ids, so a store moved
+// to a new server keeps its order numbers and references. Rows that clash
+// with existing ones are reported as conflicts, and unless SkipConflicts is
+// set any conflict rolls the whole restore back with ErrConflicts.
+//
+// Users archived without hashes come back without a password; an admin
+// has to reset theirs before they can sign in with one.
+func Restore(ctx context.Context, txm database.TxManager, r io.Reader, dst Sources, opts RestoreOptions) (*Report, error) {
+	report := &Report{Restored: map[string]int{}, Conflicts: []Conflict{}, DryRun: opts.DryRun}
+
+	err := txm.Run(ctx, func(ctx context.Context, tx database.SQLClient) error {
+		l := &loader{ctx: ctx, tx: tx, dst: dst, report: report}
+		if err := l.read(json.NewDecoder(r)); err != nil {
+			return err
+		}
+		if len(report.Conflicts) > 0 && !opts.SkipConflicts {
+			return ErrConflicts
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		err = nil
+	}
+	return report, err
+}
+
+// loader reads an archive section by section and imports each row as it
+// is decoded.
+type loader struct {
+	ctx    context.Context
+	tx     database.SQLClient
+	dst    Sources
+	report *Report
+}
+
+func (l *loader) read(dec *json.Decoder) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		key, _ := tok.(string)
+
+		if key == "version" {
+			if err := dec.Decode(&l.report.Version); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+			}
+			if l.report.Version != Version {
+				return fmt.Errorf("%w: %d", ErrUnsupportedVersion, l.report.Version)
+			}
+			continue
+		}
+		if l.report.Version == 0 {
+			return fmt.Errorf("%w: no version ahead of %q", ErrInvalidArchive, key)
+		}
+
+		if err := l.section(key, dec); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+// section loads the rows of one section.
+func (l *loader) section(key string, dec *json.Decoder) error {
+	switch key {
+	case "roles":
+		return l.roles(dec)
+	case "locations":
+		return eachRow(dec, func(loc *location.Location) error {
+			return l.imported("locations", loc.Id, loc.Slug, func() (bool, error) {
+				return l.dst.Locations.Import(l.ctx, l.tx, loc)
+			})
+		})
+	case "users":
+		return eachRow(dec, l.user)
+	case "inventory":
+		return eachBatch(dec, func(items []*inventory.Inventory) error {
+			return importedAll(l, "inventory", items, func(inv *inventory.Inventory) (int, string) { return inv.Id, inv.Slug },
+				func() ([]bool, error) { return l.dst.Inventory.Import(l.ctx, l.tx, items) })
+		})
+	case "products":
+		return eachBatch(dec, func(products []*product.Product) error {
+			return importedAll(l, "products", products, func(p *product.Product) (int, string) { return p.Id, p.Slug },
+				func() ([]bool, error) { return l.dst.Products.Import(l.ctx, l.tx, products) })
+		})
+	case "orders":
+		return eachBatch(dec, func(orders []*order.Order) error {
+			return importedAll(l, "orders", orders, func(o *order.Order) (int, string) { return o.Id, "" },
+				func() ([]bool, error) { return l.dst.Orders.Import(l.ctx, l.tx, orders) })
+		})
+	}
+
+	// created_at, and anything a later version adds
+	var skip json.RawMessage
+	if err := dec.Decode(&skip); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	return nil
+}
+
+// roles are read in full and loaded parents first, since a role refers to
+// its parent.
+func (l *loader) roles(dec *json.Decoder) error {
+	var roles []*role.Role
+	if err := eachRow(dec, func(r *role.Role) error {
+		roles = append(roles, r)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	parents := map[string]string{}
+	for _, r := range roles {
+		parents[r.Slug] = r.Parent
+	}
+	depth := func(slug string) int {
+		d := 0
+		for p := parents[slug]; p != "" && d < len(roles); p = parents[p] {
+			d++
+		}
+		return d
+	}
+	sort.SliceStable(roles, func(i, j int) bool { return depth(roles[i].Slug) < depth(roles[j].Slug) })
+
+	for _, r := range roles {
+		if err := l.imported("roles", r.Id, r.Slug, func() (bool, error) {
+			return l.dst.Roles.Import(l.ctx, l.tx, r)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *loader) user(rec *userRecord) error {
+	u := &user.User{
+		Id:                 rec.Id,
+		Username:           rec.Username,
+		DisplayName:        rec.DisplayName,
+		Email:              rec.Email,
+		Hash:               rec.Hash,
+		Role:               rec.Role,
+		Active:             rec.Active,
+		Revision:           rec.Revision,
+		LastLoginAt:        rec.LastLoginAt,
+		LastLoginIP:        rec.LastLoginIP,
+		AvatarURL:          rec.AvatarURL,
+		CreatedAt:          rec.CreatedAt,
+		UpdatedAt:          rec.UpdatedAt,
+		Setting:            rec.Setting,
+		Custom:             rec.Custom,
+		MustChangePassword: rec.MustChangePassword,
+		TempRole:           rec.TempRole,
+		TempRoleExpiresAt:  rec.TempRoleExpiresAt,
+		DeletedAt:          rec.DeletedAt,
+	}
+	return l.imported("users", u.Id, u.Username, func() (bool, error) {
+		return l.dst.Users.Import(l.ctx, l.tx, u, rec.Anonymized, rec.Locations)
+	})
+}
+
+// imported runs one row's import and counts it as restored, or as a
+// conflict if it clashed.
+func (l *loader) imported(section string, id int, key string, importRow func() (bool, error)) error {
+	ok, err := importRow()
+	if err != nil {
+		return fmt.Errorf("restoring %s %d: %w", section, id, err)
+	}
+	if !ok {
+		l.report.Conflicts = append(l.report.Conflicts, Conflict{Section: section, Id: id, Key: key})
+		return nil
+	}
+	l.report.Restored[section]++
+	return nil
+}
+
+// importedAll runs one batch's import and counts each row as restored, or
+// as a conflict if it clashed. key names a row in the report.
+func importedAll[T any](l *loader, section string, rows []T, key func(T) (int, string), importRows func() ([]bool, error)) error {
+	ok, err := importRows()
+	if err != nil {
+		return fmt.Errorf("restoring %s: %w", section, err)
+	}
+	for i, row := range rows {
+		if ok[i] {
+			l.report.Restored[section]++
+			continue
+		}
+		id, k := key(row)
+		l.report.Conflicts = append(l.report.Conflicts, Conflict{Section: section, Id: id, Key: k})
+	}
+	return nil
+}
+
+// eachBatch decodes a JSON array in batches of batchSize, handing each to
+// fn, for the sections that are bulk-loaded.
+func eachBatch[T any](dec *json.Decoder, fn func([]*T) error) error {
+	var batch []*T
+	err := eachRow(dec, func(row *T) error {
+		batch = append(batch, row)
+		if len(batch) < batchSize {
+			return nil
+		}
+		full := batch
+		batch = nil
+		return fn(full)
+	})
+	if err != nil || len(batch) == 0 {
+		return err
+	}
+	return fn(batch)
+}
+
+// eachRow decodes a JSON array one element at a time, handing each to fn.
+func eachRow[T any](dec *json.Decoder, fn func(*T) error) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+	for dec.More() {
+		row := new(T)
+		if err := dec.Decode(row); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	if tok != want {
+		return fmt.Errorf("%w: expected %v, found %v", ErrInvalidArchive, want, tok)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("captcha verification failed")
+
+// Verifier checks a challenge token solved in the browser. It returns
+// ErrInvalidToken when the token is rejected; any other error means the
+// provider couldn't be reached.
+type Verifier interface {
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+const (
+	HCaptchaURL  = "https://api.hcaptcha.com/siteverify"
+	TurnstileURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+)
+
+// SiteVerify implements the siteverify protocol shared by hCaptcha and
+// Cloudflare Turnstile: POST the secret and token, read back {"success": bool}.
+type SiteVerify struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewSiteVerify(verifyURL, secret string) *SiteVerify {
+	return &SiteVerify{
+		url:    verifyURL,
+		secret: secret,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// New builds a verifier for a provider name ("hcaptcha" or "turnstile").
+func New(provider, secret string) (*SiteVerify, error) {
+	switch strings.ToLower(provider) {
+	case "hcaptcha":
+		return NewSiteVerify(HCaptchaURL, secret), nil
+	case "turnstile":
+		return NewSiteVerify(TurnstileURL, secret), nil
+	default:
+		return nil, fmt.Errorf("unknown captcha provider %q", provider)
+	}
+}
+
+func (v *SiteVerify) Verify(ctx context.Context, token, remoteIP string) error {
+	if token == "" {
+		return ErrInvalidToken
+	}
+
+	form := url.Values{"secret": {v.secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("captcha verify request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("captcha verify returned %s", resp.Status)
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode captcha response: %w", err)
+	}
+
+	if !result.Success {
+		// A wrong secret is our misconfiguration, not the user's bad token
+		for _, code := range result.ErrorCodes {
+			if strings.HasPrefix(code, "missing-input-secret") || strings.HasPrefix(code, "invalid-input-secret") {
+				return fmt.Errorf("captcha provider rejected the secret: %s", code)
+			}
+		}
+		return ErrInvalidToken
+	}
+
+	return nil
+}
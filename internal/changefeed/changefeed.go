@@ -0,0 +1,105 @@
+// Package changefeed carries events between API instances, so that clients
+// streaming from one instance behind a load balancer see orders and stock
+// changes made through any of them.
+//
+// Services raise events through hooks as before; Relay turns a hub's
+// Publish into a hook that goes through the feed instead, and delivers what
+// comes back out of it to the hub:
+//
+//	invSvc.OnStockAlert(changefeed.Relay(feed, "stock_alert", stockAlerts.Publish))
+package changefeed
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// Feed delivers published payloads to the subscribers of their topic on
+// every instance, the publishing one included. Delivery is best effort: an
+// instance that is disconnected when an event is published misses it.
+type Feed interface {
+	Publish(topic string, payload []byte) error
+	// Subscribe registers fn for topic. Subscribe at startup, before
+	// anything is published.
+	Subscribe(topic string, fn func(payload []byte))
+}
+
+// New builds a feed for a kind name: "local" (the default) keeps events
+// within this instance, "postgres" sends them through NOTIFY on dsn.
+func New(kind, dsn string) (Feed, error) {
+	switch strings.ToLower(kind) {
+	case "", "local":
+		return NewLocal(), nil
+	case "postgres":
+		return NewPostgres(dsn)
+	default:
+		return nil, fmt.Errorf("unknown change feed %q", kind)
+	}
+}
+
+// Relay subscribes deliver to topic and returns a function that publishes
+// events to it, for use as a service hook. Events are JSON on the wire.
+// Publishing failures are logged rather than returned: the change that
+// raised the event has already happened.
+func Relay[T any](f Feed, topic string, deliver func(T)) func(T) {
+	f.Subscribe(topic, func(payload []byte) {
+		var event T
+		if err := json.Unmarshal(payload, &event); err != nil {
+			log.Printf("Change feed: bad %s event: %v", topic, err)
+			return
+		}
+		deliver(event)
+	})
+
+	return func(event T) {
+		payload, err := json.Marshal(event)
+		if err == nil {
+			err = f.Publish(topic, payload)
+		}
+		if err != nil {
+			log.Printf("Change feed: publishing %s failed: %v", topic, err)
+		}
+	}
+}
+
+// subscribers is the topic registry the feeds share.
+type subscribers struct {
+	mu     sync.RWMutex
+	topics map[string][]func([]byte)
+}
+
+func (s *subscribers) Subscribe(topic string, fn func(payload []byte)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.topics == nil {
+		s.topics = make(map[string][]func([]byte))
+	}
+	s.topics[topic] = append(s.topics[topic], fn)
+}
+
+func (s *subscribers) dispatch(topic string, payload []byte) {
+	s.mu.RLock()
+	fns := s.topics[topic]
+	s.mu.RUnlock()
+	for _, fn := range fns {
+		fn(payload)
+	}
+}
+
+// Local delivers events within this instance only, as they are published.
+// It suits a single instance, or databases without a notification channel.
+type Local struct {
+	subscribers
+}
+
+func NewLocal() *Local {
+	return &Local{}
+}
+
+func (l *Local) Publish(topic string, payload []byte) error {
+	l.dispatch(topic, payload)
+	return nil
+}
@@ -0,0 +1,89 @@
+package changefeed
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Channel is the NOTIFY channel every instance listens on.
+const Channel = "pos_changes"
+
+// publishTimeout bounds a NOTIFY, so a struggling database can't hold up
+// the request that raised the event for long.
+const publishTimeout = 5 * time.Second
+
+// envelope is a notification's payload: the topic, then the event.
+type envelope struct {
+	Topic string          `json:"topic"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// Postgres sends events through NOTIFY and delivers those from every
+// instance, this one included, as they arrive on its LISTEN connection.
+// Payloads must stay under Postgres' 8000 byte limit.
+type Postgres struct {
+	subscribers
+	db       *sql.DB
+	listener *pq.Listener
+}
+
+// NewPostgres opens a publishing connection and a listener on dsn. The
+// listener reconnects on its own; events sent while it is down are lost.
+func NewPostgres(dsn string) (*Postgres, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(2)
+
+	listener := pq.NewListener(dsn, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Change feed: listener: %v", err)
+		}
+	})
+	if err := listener.Listen(Channel); err != nil {
+		listener.Close()
+		db.Close()
+		return nil, err
+	}
+
+	p := &Postgres{db: db, listener: listener}
+	go p.listen()
+	return p, nil
+}
+
+func (p *Postgres) Publish(topic string, payload []byte) error {
+	msg, err := json.Marshal(envelope{Topic: topic, Data: payload})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	_, err = p.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, Channel, string(msg))
+	return err
+}
+
+// Close stops listening and closes both connections.
+func (p *Postgres) Close() error {
+	p.listener.Close()
+	return p.db.Close()
+}
+
+func (p *Postgres) listen() {
+	for n := range p.listener.Notify {
+		if n == nil { // Reconnected; anything sent meanwhile is gone
+			continue
+		}
+		var env envelope
+		if err := json.Unmarshal([]byte(n.Extra), &env); err != nil {
+			log.Printf("Change feed: bad notification: %v", err)
+			continue
+		}
+		p.dispatch(env.Topic, env.Data)
+	}
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// maxBulkParams keeps a multi-row INSERT under every database's limit on
+// bound parameters; SQLite's, at 32766, is the lowest.
+const maxBulkParams = 30000
+
+// BulkInsert inserts rows into table, each row holding one value per
+// column, in as few round trips as the database allows: COPY on Postgres
+// when c is a transaction, multi-row INSERTs otherwise. Values go in as
+// they are, with no SQL around them, so bind NULL as nil and JSON as a
+// string (COPY would write []byte as bytea).
+func (d Dialect) BulkInsert(ctx context.Context, c SQLClient, table string, columns []string, rows [][]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if tx, ok := sqlTx(c); ok && d == Postgres {
+		return copyIn(ctx, tx, table, columns, rows)
+	}
+
+	perInsert := max(1, maxBulkParams/len(columns))
+	for start := 0; start < len(rows); start += perInsert {
+		var f Filter
+		var b strings.Builder
+		fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", table, strings.Join(columns, ", "))
+		for i, row := range rows[start:min(start+perInsert, len(rows))] {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteByte('(')
+			for j, v := range row {
+				if j > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteString(f.Arg(v))
+			}
+			b.WriteByte(')')
+		}
+		if _, err := c.ExecContext(ctx, b.String(), f.Args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JSONText binds encoded JSON for BulkInsert: as a string, or NULL for nil.
+func JSONText(b []byte) any {
+	if b == nil {
+		return nil
+	}
+	return string(b)
+}
+
+// StageTable creates an empty temporary table called name with the given
+// columns of table, to bulk-load rows into before merging them with one
+// INSERT ... SELECT. It lasts until the transaction ends on Postgres, and
+// for the connection elsewhere, so a table left over by an earlier failed
+// load is dropped first.
+func (d Dialect) StageTable(ctx context.Context, c SQLClient, name, table, columns string) error {
+	create := "CREATE TEMPORARY TABLE " + name
+	switch d {
+	case Postgres:
+		create += " ON COMMIT DROP"
+	case SQLite:
+		if _, err := c.ExecContext(ctx, "DROP TABLE IF EXISTS temp."+name); err != nil {
+			return err
+		}
+	case MySQL:
+		if _, err := c.ExecContext(ctx, "DROP TEMPORARY TABLE IF EXISTS "+name); err != nil {
+			return err
+		}
+	}
+	_, err := c.ExecContext(ctx, create+" AS SELECT "+columns+" FROM "+table+" WHERE 1 = 0")
+	return err
+}
+
+// copyIn streams rows into table with COPY.
+func copyIn(ctx context.Context, tx *sql.Tx, table string, columns []string, rows [][]any) error {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = strings.Trim(col, `"`) // CopyIn quotes them itself
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, names...))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return err
+		}
+	}
+	_, err = stmt.ExecContext(ctx) // Flushes the buffered rows
+	return err
+}
+
+// sqlTx finds the transaction behind c, if it is one.
+func sqlTx(c SQLClient) (*sql.Tx, bool) {
+	switch c := c.(type) {
+	case *sql.Tx:
+		return c, true
+	case *Tx:
+		return c.Tx, true
+	case timedClient:
+		return sqlTx(c.c)
+	}
+	return nil, false
+}
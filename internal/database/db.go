@@ -3,10 +3,15 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	_ "github.com/lib/pq" // PostgreSQL Driver
+	"modernc.org/sqlite"  // SQLite driver, pure Go
 )
 
 // SQLClient is an interface that matches both *sql.DB and *sql.Tx.
@@ -19,19 +24,32 @@ type SQLClient interface {
 
 // Config holds database configuration
 type Config struct {
-	Driver          string // "postgres" or "sqlite3"
-	DSN             string // Data Source Name (Connection string)
+	Driver          string // "postgres", "sqlite3" or "mysql" (MariaDB too)
+	DSN             string // Data Source Name (Connection string); a file path for SQLite
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+
+	// CurrentDSN, if set, is asked for the DSN whenever a connection is
+	// opened, so credentials rotated in a secret store reach new
+	// connections. Open ones keep theirs until ConnMaxLifetime.
+	CurrentDSN func() string
 }
 
 // NewDatabase establishes a connection and ensures the DB is reachable.
 func NewDatabase(cfg Config) (*sql.DB, error) {
-	db, err := sql.Open(cfg.Driver, cfg.DSN)
+	open := connectorFor(cfg.Driver)
+	var connector driver.Connector
+	var err error
+	if cfg.CurrentDSN != nil {
+		connector, err = newRotatingConnector(cfg.CurrentDSN, open)
+	} else {
+		connector, err = open(cfg.DSN)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
+	db := sql.OpenDB(connector)
 
 	// Set connection pool settings
 	db.SetMaxOpenConns(cfg.MaxOpenConns)
@@ -48,3 +66,126 @@ func NewDatabase(cfg Config) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// connectorFor returns how to connect to a DSN with the named driver.
+func connectorFor(name string) func(dsn string) (driver.Connector, error) {
+	switch name {
+	case "sqlite3", "sqlite":
+		return func(dsn string) (driver.Connector, error) {
+			return registeredConnector("sqlite", sqliteDSN(dsn))
+		}
+	case "mysql", "mariadb":
+		return mysqlConnectorFor
+	}
+	return func(dsn string) (driver.Connector, error) {
+		return registeredConnector(name, dsn)
+	}
+}
+
+// registeredConnector connects with a driver registered with database/sql.
+func registeredConnector(name, dsn string) (driver.Connector, error) {
+	db, err := sql.Open(name, "") // Only to look the driver up; it connects lazily
+	if err != nil {
+		return nil, err
+	}
+	drv := db.Driver()
+	db.Close()
+
+	if dc, ok := drv.(driver.DriverContext); ok {
+		return dc.OpenConnector(dsn)
+	}
+	return dsnConnector{drv, dsn}, nil
+}
+
+// dsnConnector is a driver.Connector for drivers that don't provide one.
+type dsnConnector struct {
+	drv driver.Driver
+	dsn string
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.drv.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.drv }
+
+// rotatingConnector opens every connection with the DSN current at the
+// time, building a new connector when it changes.
+type rotatingConnector struct {
+	dsn  func() string
+	open func(dsn string) (driver.Connector, error)
+
+	mu   sync.Mutex
+	last string
+	c    driver.Connector
+}
+
+func newRotatingConnector(dsn func() string, open func(string) (driver.Connector, error)) (*rotatingConnector, error) {
+	r := &rotatingConnector{dsn: dsn, open: open}
+	if _, err := r.current(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingConnector) current() (driver.Connector, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if dsn := r.dsn(); r.c == nil || dsn != r.last {
+		c, err := r.open(dsn)
+		if err != nil {
+			return nil, err
+		}
+		r.c, r.last = c, dsn
+	}
+	return r.c, nil
+}
+
+func (r *rotatingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c, err := r.current()
+	if err != nil {
+		return nil, err
+	}
+	return c.Connect(ctx)
+}
+
+// Driver is the same for every DSN, so the first connector's will do.
+func (r *rotatingConnector) Driver() driver.Driver {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.c.Driver()
+}
+
+func init() {
+	// Queries use NOW() on both databases. This one returns the same text
+	// sqliteDSN makes the driver write for a time.Time, so stored times and
+	// NOW() compare correctly as strings.
+	sqlite.MustRegisterScalarFunction("now", 0, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+		return time.Now().UTC().Format(sqliteTimeFormat), nil
+	})
+}
+
+// sqliteTimeFormat is how times are stored in SQLite: UTC, so that text order
+// is time order.
+const sqliteTimeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// sqliteDSN adds the connection settings the repositories rely on:
+// enforced foreign keys, waiting rather than failing on a busy database,
+// transactions that take the write lock up front (there's no SELECT ... FOR
+// UPDATE), and times stored in UTC as sqliteTimeFormat.
+func sqliteDSN(dsn string) string {
+	if !strings.HasPrefix(dsn, "file:") {
+		dsn = "file:" + dsn
+	}
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_txlock", "immediate")
+	params.Set("_time_format", "sqlite")
+	params.Set("_timezone", "UTC")
+	params.Set("_texttotime", "1")
+
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + params.Encode()
+}
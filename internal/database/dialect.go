@@ -0,0 +1,368 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+)
+
+// Dialect is the SQL flavour behind a connection. Queries are written for
+// PostgreSQL and ask the dialect only for the pieces SQLite and MySQL spell
+// differently. Placeholders need no help: Postgres and SQLite bind $1, $2,
+// ... by position, and MySQL connections rewrite them (see mysql.go).
+// RETURNING does need help on MySQL, which has none; see InsertReturning.
+type Dialect int
+
+const (
+	Postgres Dialect = iota
+	SQLite
+	MySQL // MySQL 8.0.17+ or MariaDB 10.9+, in ANSI mode (see mysqlConnectorFor)
+)
+
+// DialectOf reports the dialect of db's driver.
+func DialectOf(db *sql.DB) Dialect {
+	switch db.Driver().(type) {
+	case *sqlite.Driver:
+		return SQLite
+	case mysqlDriver:
+		return MySQL
+	}
+	return Postgres
+}
+
+func (d Dialect) String() string {
+	switch d {
+	case SQLite:
+		return "sqlite"
+	case MySQL:
+		return "mysql"
+	}
+	return "postgres"
+}
+
+// ILike matches expr against a LIKE pattern ignoring case. SQLite's LIKE
+// already ignores case, for ASCII letters only.
+func (d Dialect) ILike(expr, pattern string) string {
+	switch d {
+	case SQLite:
+		return expr + " LIKE " + pattern
+	case MySQL:
+		// Values pulled out of JSON compare as binary, so fold case here
+		return "LOWER(" + expr + ") LIKE LOWER(" + pattern + ")"
+	}
+	return expr + " ILIKE " + pattern
+}
+
+// AnyOf tests expr for membership in a list bound with Array.
+func (d Dialect) AnyOf(expr, param string) string {
+	switch d {
+	case SQLite:
+		return expr + " IN (SELECT value FROM json_each(" + param + "))"
+	case MySQL:
+		return "JSON_CONTAINS(" + param + ", JSON_ARRAY(" + expr + "))"
+	}
+	return expr + " = ANY(" + param + ")"
+}
+
+// Array wraps a slice for binding as a list: a Postgres array, or a JSON
+// array elsewhere. A nil slice binds NULL on all of them.
+func (d Dialect) Array(v any) any {
+	if d == Postgres {
+		return pq.Array(v)
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice && rv.IsNil() {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		// Only slices of plain values are ever bound; anything else is a bug
+		panic("database: can't bind array: " + err.Error())
+	}
+	return string(b)
+}
+
+// ForUpdate is the row-locking clause for a SELECT inside a transaction,
+// limited to the given table aliases. SQLite locks the whole database for
+// the transaction instead (see NewDatabase), so it has none. MariaDB has no
+// OF, so MySQL locks the matching rows of every table in the query.
+func (d Dialect) ForUpdate(of ...string) string {
+	if d == SQLite {
+		return ""
+	}
+	if len(of) > 0 && d == Postgres {
+		return " FOR UPDATE OF " + strings.Join(of, ", ")
+	}
+	return " FOR UPDATE"
+}
+
+// Least and Greatest pick the smallest or largest of their arguments.
+func (d Dialect) Least(exprs ...string) string {
+	if d == SQLite {
+		return "MIN(" + strings.Join(exprs, ", ") + ")"
+	}
+	return "LEAST(" + strings.Join(exprs, ", ") + ")"
+}
+
+func (d Dialect) Greatest(exprs ...string) string {
+	if d == SQLite {
+		return "MAX(" + strings.Join(exprs, ", ") + ")"
+	}
+	return "GREATEST(" + strings.Join(exprs, ", ") + ")"
+}
+
+// SecondsBetween is the time from one timestamp to a later one, in seconds.
+func (d Dialect) SecondsBetween(from, to string) string {
+	switch d {
+	case SQLite:
+		return "((julianday(" + to + ") - julianday(" + from + ")) * 86400)"
+	case MySQL:
+		return "(TIMESTAMPDIFF(MICROSECOND, " + from + ", " + to + ") / 1000000)"
+	}
+	return "EXTRACT(EPOCH FROM (" + to + ") - (" + from + "))"
+}
+
+// Epoch converts a timestamp to whole seconds since 1970, rounding down as
+// time.Time's Unix does.
+func (d Dialect) Epoch(expr string) string {
+	switch d {
+	case SQLite:
+		return "unixepoch(" + expr + ")"
+	case MySQL:
+		return "FLOOR(UNIX_TIMESTAMP(" + expr + "))"
+	}
+	return "FLOOR(EXTRACT(EPOCH FROM " + expr + "))::bigint"
+}
+
+// CastInt and CastFloat convert expr to a 64-bit integer or a double.
+func (d Dialect) CastInt(expr string) string {
+	if d == MySQL {
+		return "CAST(" + expr + " AS SIGNED)"
+	}
+	return "CAST(" + expr + " AS BIGINT)"
+}
+
+func (d Dialect) CastFloat(expr string) string {
+	if d == MySQL {
+		return "CAST(" + expr + " AS DOUBLE)"
+	}
+	return "CAST(" + expr + " AS DOUBLE PRECISION)"
+}
+
+// NullsLast is an ORDER BY term for expr in the given direction with NULLs
+// after every value.
+func (d Dialect) NullsLast(expr, direction string) string {
+	if d == MySQL {
+		return expr + " IS NULL, " + expr + " " + direction
+	}
+	return expr + " " + direction + " NULLS LAST"
+}
+
+var excluded = regexp.MustCompile(`\bEXCLUDED\.(\w+)`)
+
+// OnConflict ends an INSERT that updates the existing row when one with the
+// same cols (a unique key) is already there. Assignments are written the
+// Postgres way, with EXCLUDED.col for the value being inserted; with none,
+// the existing row is left alone. MySQL applies it to a clash on any unique
+// key, not only cols.
+func (d Dialect) OnConflict(cols string, set ...string) string {
+	if d == MySQL {
+		if len(set) == 0 {
+			first, _, _ := strings.Cut(cols, ",")
+			set = []string{first + " = " + first}
+		}
+		return "ON DUPLICATE KEY UPDATE " + excluded.ReplaceAllString(strings.Join(set, ", "), "VALUES($1)")
+	}
+	if len(set) == 0 {
+		return "ON CONFLICT (" + cols + ") DO NOTHING"
+	}
+	return "ON CONFLICT (" + cols + ") DO UPDATE SET " + strings.Join(set, ", ")
+}
+
+// InsertReturning runs an INSERT into table and scans the returning columns
+// of the new row, or the updated one after an OnConflict, into dest. MySQL
+// has no RETURNING, so there the row is read back by its LAST_INSERT_ID();
+// table's key must be id.
+func (d Dialect) InsertReturning(ctx context.Context, c SQLClient, table, query, returning string, args []any, dest ...any) error {
+	if d != MySQL {
+		return c.QueryRowContext(ctx, query+" RETURNING "+returning, args...).Scan(dest...)
+	}
+
+	if strings.Contains(query, "ON DUPLICATE KEY UPDATE") {
+		query += ", id = LAST_INSERT_ID(id)" // Otherwise an update reports no id
+	}
+	return d.readBack(ctx, c, table, query, returning, args, dest)
+}
+
+// ResetSequence moves the id sequence of table past its largest id, after
+// rows were inserted with ids of their own. SQLite and MySQL keep up by
+// themselves.
+func (d Dialect) ResetSequence(ctx context.Context, c SQLClient, table string) error {
+	if d != Postgres {
+		return nil
+	}
+	query := fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %s`, table, table)
+	_, err := c.ExecContext(ctx, query)
+	return err
+}
+
+var firstWhere = regexp.MustCompile(`\sWHERE\s`)
+
+// UpdateReturning runs an UPDATE of at most one row and scans the returning
+// columns into dest, or returns sql.ErrNoRows if no row matched. On MySQL the
+// row is found again by its id, recorded with LAST_INSERT_ID(id) ahead of
+// the first WHERE, which must be the UPDATE's own.
+func (d Dialect) UpdateReturning(ctx context.Context, c SQLClient, query, returning string, args []any, dest ...any) error {
+	if d != MySQL {
+		return c.QueryRowContext(ctx, query+" RETURNING "+returning, args...).Scan(dest...)
+	}
+
+	table := strings.Fields(query)[1]
+	loc := firstWhere.FindStringIndex(query)
+	if loc == nil {
+		return fmt.Errorf("database: UpdateReturning needs a WHERE clause")
+	}
+	query = query[:loc[0]] + ", id = LAST_INSERT_ID(id)" + query[loc[0]:]
+	return d.readBack(ctx, c, table, query, returning, args, dest)
+}
+
+// readBack runs a statement that leaves the id of the row it touched in
+// LAST_INSERT_ID() and selects that row's returning columns into dest.
+func (d Dialect) readBack(ctx context.Context, c SQLClient, table, query, returning string, args, dest []any) error {
+	result, err := c.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if id == 0 {
+		return sql.ErrNoRows // No row inserted or matched
+	}
+	return c.QueryRowContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", returning, table), id).Scan(dest...)
+}
+
+// JSON columns are JSONB in Postgres, JSON text in SQLite and JSON in
+// MySQL. The helpers below keep the Postgres operators, so GIN indexes still
+// apply there.
+
+// JSONType names the type of a JSON value: 'object', 'array', ...
+func (d Dialect) JSONType(doc string) string {
+	switch d {
+	case SQLite:
+		return "json_type(" + doc + ")"
+	case MySQL:
+		return "LOWER(JSON_TYPE(" + doc + "))"
+	}
+	return "jsonb_typeof(" + doc + ")"
+}
+
+// JSONEach is a FROM item expanding a JSON object into rows of alias.key;
+// JSONEachValue is the matching value, as text. It may refer to earlier
+// FROM items.
+func (d Dialect) JSONEach(doc, alias string) string {
+	switch d {
+	case SQLite:
+		return "json_each(" + doc + ") AS " + alias
+	case MySQL:
+		return "JSON_TABLE(JSON_KEYS(" + doc + "), '$[*]' COLUMNS (\"key\" VARCHAR(255) PATH '$')) AS " + alias
+	}
+	return "jsonb_each_text(" + doc + ") AS " + alias + "(key, value)"
+}
+
+func (d Dialect) JSONEachValue(doc, alias string) string {
+	if d == MySQL {
+		return "JSON_UNQUOTE(JSON_EXTRACT(" + doc + ", CONCAT('$.\"', " + alias + ".\"key\", '\"')))"
+	}
+	return alias + ".value"
+}
+
+// JSONElements is a FROM item expanding a JSON array of strings into rows
+// of alias.value. It may refer to earlier FROM items.
+func (d Dialect) JSONElements(doc, alias string) string {
+	switch d {
+	case SQLite:
+		return "json_each(" + doc + ") AS " + alias
+	case MySQL:
+		return "JSON_TABLE(" + doc + ", '$[*]' COLUMNS (\"value\" VARCHAR(255) PATH '$')) AS " + alias
+	}
+	return "jsonb_array_elements_text(" + doc + ") AS " + alias + "(value)"
+}
+
+// JSONAgg collects values into a JSON array. Postgres and MySQL return NULL
+// for no rows where SQLite returns [], so callers COALESCE when it matters.
+func (d Dialect) JSONAgg(expr string) string {
+	switch d {
+	case SQLite:
+		return "json_group_array(" + expr + ")"
+	case MySQL:
+		return "JSON_ARRAYAGG(" + expr + ")"
+	}
+	return "jsonb_agg(" + expr + ")"
+}
+
+// JSONHasElement tests whether a JSON array holds the string elem.
+func (d Dialect) JSONHasElement(doc, elem string) string {
+	switch d {
+	case SQLite:
+		return "EXISTS (SELECT 1 FROM json_each(" + doc + ") WHERE value = " + elem + ")"
+	case MySQL:
+		return "JSON_CONTAINS(" + doc + ", JSON_QUOTE(" + elem + "))"
+	}
+	return doc + " ? " + elem
+}
+
+// JSONHasKey tests whether a JSON object has the key.
+func (d Dialect) JSONHasKey(doc, key string) string {
+	switch d {
+	case SQLite:
+		return "EXISTS (SELECT 1 FROM json_each(" + doc + ") WHERE key = " + key + ")"
+	case MySQL:
+		return "JSON_CONTAINS(JSON_KEYS(" + doc + "), JSON_QUOTE(" + key + "))"
+	}
+	return doc + " ? " + key
+}
+
+// JSONHasAnyKey tests whether a JSON object has any key in a list bound
+// with Array.
+func (d Dialect) JSONHasAnyKey(doc, param string) string {
+	switch d {
+	case SQLite:
+		return "EXISTS (SELECT 1 FROM json_each(" + doc + ") WHERE key IN (SELECT value FROM json_each(" + param + ")))"
+	case MySQL:
+		return "JSON_OVERLAPS(JSON_KEYS(" + doc + "), " + param + ")"
+	}
+	return doc + " ?| " + param
+}
+
+// JSONContains tests whether a JSON array holds every element of another,
+// bound as JSON.
+func (d Dialect) JSONContains(doc, param string) string {
+	switch d {
+	case SQLite:
+		return "NOT EXISTS (SELECT 1 FROM json_each(" + param + ") AS want" +
+			" WHERE want.value NOT IN (SELECT value FROM json_each(" + doc + ")))"
+	case MySQL:
+		return "JSON_CONTAINS(" + doc + ", " + param + ")"
+	}
+	return doc + " @> " + param
+}
+
+// JSONWithout is a JSON array minus every occurrence of the string elem.
+func (d Dialect) JSONWithout(doc, elem string) string {
+	switch d {
+	case SQLite:
+		return "(SELECT json_group_array(value) FROM json_each(" + doc + ") WHERE value <> " + elem + ")"
+	case MySQL:
+		return "(SELECT COALESCE(JSON_ARRAYAGG(w.\"value\"), JSON_ARRAY()) FROM " + d.JSONElements(doc, "w") +
+			" WHERE w.\"value\" <> " + elem + ")"
+	}
+	return doc + " - " + elem
+}
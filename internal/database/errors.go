@@ -0,0 +1,100 @@
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// IsUniqueViolation reports whether err is a unique or primary key violation.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var liteErr *sqlite.Error
+	if errors.As(err, &liteErr) {
+		return liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE ||
+			liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == 1062 // ER_DUP_ENTRY
+	}
+	return false
+}
+
+// IsForeignKeyViolation reports whether err is a foreign key violation.
+func IsForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23503"
+	}
+	var liteErr *sqlite.Error
+	if errors.As(err, &liteErr) {
+		return liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+	}
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		// ER_ROW_IS_REFERENCED_2, ER_NO_REFERENCED_ROW_2
+		return myErr.Number == 1451 || myErr.Number == 1452
+	}
+	return false
+}
+
+// Constraint names the constraint err violated, the way Postgres names them
+// by default, e.g. "users_email_key". SQLite only reports the columns of
+// unique constraints (or the index name, for expression indexes), so the
+// name is rebuilt from those; for other violations it returns "". MySQL
+// reports the unique key, which the MySQL schema names the Postgres way.
+func Constraint(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Constraint
+	}
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		if myErr.Number != 1062 {
+			return ""
+		}
+		// "Duplicate entry 'a@b.c' for key 'users.users_email_key'"; MariaDB
+		// and MySQL before 8.0.19 leave out the table
+		i := strings.LastIndex(myErr.Message, " for key '")
+		if i < 0 {
+			return ""
+		}
+		key := strings.TrimSuffix(myErr.Message[i+len(" for key '"):], "'")
+		if _, name, ok := strings.Cut(key, "."); ok {
+			return name
+		}
+		return key
+	}
+	var liteErr *sqlite.Error
+	if !errors.As(err, &liteErr) || !IsUniqueViolation(err) {
+		return ""
+	}
+
+	// "constraint failed: UNIQUE constraint failed: users.email (2067)"
+	_, cols, ok := strings.Cut(liteErr.Error(), "UNIQUE constraint failed: ")
+	if !ok {
+		return ""
+	}
+	cols, _, _ = strings.Cut(cols, " (")
+	if index, ok := strings.CutPrefix(cols, "index '"); ok {
+		return strings.TrimSuffix(index, "'")
+	}
+	var table string
+	var names []string
+	for _, col := range strings.Split(cols, ", ") {
+		t, c, _ := strings.Cut(col, ".")
+		table = t
+		if c != "store_id" { // Keys are per store, and named for what they keep unique
+			names = append(names, c)
+		}
+	}
+	return table + "_" + strings.Join(names, "_") + "_key"
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+)
+
+// The custom JSON of products, inventory, orders and users can hold
+// personal data, e.g. a customer's phone number on an order. Keys chosen
+// with SetFieldCipher are encrypted with AES-GCM before the JSON is written
+// and decrypted when it is read, so the database (and its dumps) only ever
+// hold ciphertext for them. Encrypted values are strings,
+// "enc:<key id>:<base64 nonce and ciphertext>", sealed together with the
+// entity and key they belong to so they can't be moved to another. User
+// settings have fixed keys with nothing sensitive, and aren't covered.
+
+var (
+	ErrNoFieldKey      = errors.New("custom field is encrypted but no key can decrypt it")
+	ErrInvalidFieldKey = errors.New("field encryption keys must be 32 bytes, base64 encoded")
+)
+
+var encryptedRe = regexp.MustCompile(`^enc:([0-9a-f]{8}):([A-Za-z0-9+/]+)$`)
+
+// FieldCipher encrypts the values of designated custom keys.
+type FieldCipher struct {
+	kid    string                 // Of the key new values are encrypted with
+	keys   map[string]cipher.AEAD // By key id, the current key and older ones
+	fields map[string]bool        // "phone" for every entity, "order.phone" for one
+}
+
+var fieldCipher *FieldCipher
+
+// NewFieldCipher encrypts fields, custom keys given as "key" for every
+// entity or "entity.key" for one (product, inventory, order or user), with
+// the first of keys. The others only decrypt, so values written under
+// them stay readable after a rotation until they are written again. Keys
+// are 32 random bytes, base64 encoded.
+func NewFieldCipher(fields []string, keys ...string) (*FieldCipher, error) {
+	if len(keys) == 0 {
+		return nil, ErrInvalidFieldKey
+	}
+	c := &FieldCipher{keys: map[string]cipher.AEAD{}, fields: map[string]bool{}}
+	for i, encoded := range keys {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(key) != 32 {
+			return nil, ErrInvalidFieldKey
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(key)
+		kid := hex.EncodeToString(sum[:4])
+		if i == 0 {
+			c.kid = kid
+		}
+		c.keys[kid] = aead
+	}
+	for _, f := range fields {
+		if f = strings.TrimSpace(f); f != "" {
+			c.fields[f] = true
+		}
+	}
+	return c, nil
+}
+
+// SetFieldCipher sets the cipher MarshalCustom and UnmarshalCustom use;
+// nil encrypts nothing.
+func SetFieldCipher(c *FieldCipher) {
+	fieldCipher = c
+}
+
+// EncryptedField reports whether the custom key of entity is stored
+// encrypted, for output that would otherwise hold it in the clear.
+func EncryptedField(entity, key string) bool {
+	return fieldCipher != nil && fieldCipher.covers(entity, key)
+}
+
+func (c *FieldCipher) covers(entity, key string) bool {
+	return c.fields[key] || c.fields[entity+"."+key]
+}
+
+func (c *FieldCipher) seal(entity, key string, v any) (string, error) {
+	plain, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	aead := c.keys[c.kid]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plain, []byte(entity+"."+key))
+	return "enc:" + c.kid + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *FieldCipher) open(entity, key, kid, data string) (any, error) {
+	var aead cipher.AEAD
+	if c != nil {
+		aead = c.keys[kid]
+	}
+	if aead == nil {
+		return nil, ErrNoFieldKey
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(data)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, errors.New("malformed encrypted value")
+	}
+	nonce, ct := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ct, []byte(entity+"."+key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	var v any
+	if err := json.Unmarshal(plain, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// looksEncrypted reports whether v would be read back as an encrypted value.
+func looksEncrypted(v any) bool {
+	s, ok := v.(string)
+	return ok && encryptedRe.MatchString(s)
+}
+
+// MarshalCustom encodes the custom data of an entity for its JSON column,
+// encrypting the values of designated keys. custom itself is left as is.
+// Other strings that look encrypted are encrypted too, so they read back
+// as written rather than fail to decrypt.
+func MarshalCustom(entity string, custom map[string]any) ([]byte, error) {
+	c := fieldCipher
+	if custom == nil {
+		return json.Marshal(custom)
+	}
+	sealed := maps.Clone(custom)
+	for key, v := range custom {
+		if c == nil {
+			if looksEncrypted(v) {
+				return nil, fmt.Errorf("custom.%s: %w", key, ErrNoFieldKey)
+			}
+			continue
+		}
+		if v == nil || !c.covers(entity, key) && !looksEncrypted(v) {
+			continue
+		}
+		enc, err := c.seal(entity, key, v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt custom.%s: %w", key, err)
+		}
+		sealed[key] = enc
+	}
+	return json.Marshal(sealed)
+}
+
+// UnmarshalCustom decodes the custom data of an entity read from its JSON
+// column, decrypting every encrypted value, designated or not any more.
+// Values written before their key was designated are read as they are,
+// and encrypted the next time the entity is written.
+func UnmarshalCustom(entity string, data []byte, custom *map[string]any) error {
+	if err := json.Unmarshal(data, custom); err != nil {
+		return err
+	}
+	for key, v := range *custom {
+		s, _ := v.(string)
+		m := encryptedRe.FindStringSubmatch(s)
+		if m == nil {
+			continue
+		}
+		plain, err := fieldCipher.open(entity, key, m[1], m[2])
+		if err != nil {
+			return fmt.Errorf("custom.%s: %w", key, err)
+		}
+		(*custom)[key] = plain
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Keyset orders a listing by one column, with the id breaking ties, and
+// pages it by cursor: each page starts after the last row of the one
+// before, so rows added or removed in between don't shift the pages the
+// way they do with an offset, and deep pages cost no more than the first.
+//
+//	k := database.Keyset{Column: sortBy, Direction: sortOrder}
+//	if err := k.After(r.dialect, &f, opts.After); err != nil { ... }
+//	query += f.Where + k.OrderBy(r.dialect) + f.Page(opts.Limit, 0)
+//
+// Timestamp columns (named *_at) are keyed by their whole seconds, as the
+// entities carry them.
+type Keyset struct {
+	Column    string // Sort column, checked with SortColumn
+	Direction string // ASC or DESC, from SortDirection
+	Nullable  bool   // The column may be NULL; NULLs come last either way
+}
+
+// cursor is what a cursor string encodes: where the page ended, and which
+// ordering it belongs to.
+type cursor struct {
+	Column string `json:"c"`
+	Key    any    `json:"k"`
+	Id     int    `json:"id"`
+}
+
+// Cursor encodes the end of a page whose last row has the given key (its
+// value of Column, in Unix seconds for timestamps, or nil for NULL) and id.
+func (k Keyset) Cursor(key any, id int) string {
+	data, err := json.Marshal(cursor{Column: k.Column, Key: key, Id: id})
+	if err != nil {
+		// Keys are strings, numbers or nil; anything else is a bug
+		panic("database: can't encode cursor: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// OrderBy returns the ORDER BY clause.
+func (k Keyset) OrderBy(d Dialect) string {
+	if k.Column == "id" {
+		return " ORDER BY id " + k.Direction
+	}
+	key := k.key(d) + " " + k.Direction
+	if k.Nullable {
+		key = d.NullsLast(k.key(d), k.Direction)
+	}
+	return " ORDER BY " + key + ", id " + k.Direction
+}
+
+// After adds the condition for the rows after cursor to f. An empty cursor
+// is the first page and adds nothing; one from another ordering is
+// ErrInvalidCursor.
+func (k Keyset) After(d Dialect, f *Filter, cursorText string) error {
+	if cursorText == "" {
+		return nil
+	}
+	c, err := decodeCursor(cursorText)
+	if err != nil || c.Column != k.Column {
+		return ErrInvalidCursor
+	}
+
+	op := ">"
+	if k.Direction == "DESC" {
+		op = "<"
+	}
+	id := f.Arg(c.Id)
+	if k.Column == "id" {
+		f.And("id " + op + " " + id)
+		return nil
+	}
+
+	key := k.key(d)
+	if c.Key == nil { // Among the NULLs, which come last
+		f.And(fmt.Sprintf("(%s IS NULL AND id %s %s)", key, op, id))
+		return nil
+	}
+	value := f.Arg(c.Key)
+	cond := fmt.Sprintf("%s %s %s OR (%s = %s AND id %s %s)", key, op, value, key, value, op, id)
+	if k.Nullable {
+		cond += " OR " + key + " IS NULL"
+	}
+	f.And("(" + cond + ")")
+	return nil
+}
+
+// Position decodes a cursor from Cursor back into the key and id of the row
+// the page ended at, for listings paged without SQL. The key is a string,
+// an int64 or nil. Like After, it refuses a cursor from another ordering
+// with ErrInvalidCursor.
+func (k Keyset) Position(cursorText string) (key any, id int, err error) {
+	c, err := decodeCursor(cursorText)
+	if err != nil || c.Column != k.Column {
+		return nil, 0, ErrInvalidCursor
+	}
+	return c.Key, c.Id, nil
+}
+
+func (k Keyset) key(d Dialect) string {
+	if strings.HasSuffix(k.Column, "_at") {
+		return d.Epoch(k.Column)
+	}
+	return k.Column
+}
+
+func decodeCursor(text string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(text)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var c cursor
+	if err := dec.Decode(&c); err != nil {
+		return nil, err
+	}
+
+	switch key := c.Key.(type) {
+	case json.Number:
+		n, err := key.Int64() // Every numeric sort column holds integers
+		if err != nil {
+			return nil, err
+		}
+		c.Key = n
+	case string, nil:
+	default:
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	k := Keyset{Column: "name", Direction: "ASC"}
+	for _, key := range []any{"tea", int64(1700000000), nil} {
+		gotKey, gotId, err := k.Position(k.Cursor(key, 42))
+		if err != nil {
+			t.Fatalf("Position(Cursor(%v, 42)): %v", key, err)
+		}
+		if gotKey != key || gotId != 42 {
+			t.Errorf("Position(Cursor(%v, 42)) = %v, %d", key, gotKey, gotId)
+		}
+	}
+}
+
+func TestInvalidCursor(t *testing.T) {
+	k := Keyset{Column: "name", Direction: "ASC"}
+	raw := func(json string) string { return base64.RawURLEncoding.EncodeToString([]byte(json)) }
+	tests := map[string]string{
+		"another ordering": Keyset{Column: "price"}.Cursor(int64(100), 1),
+		"not base64":       "%%%",
+		"not json":         raw("tea"),
+		"fractional key":   raw(`{"c":"name","k":1.5,"id":1}`),
+		"object key":       raw(`{"c":"name","k":{},"id":1}`),
+	}
+	for name, cursor := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := k.Position(cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("Position: err = %v, want ErrInvalidCursor", err)
+			}
+			var f Filter
+			if err := k.After(Postgres, &f, cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("After: err = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestKeysetAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		k     Keyset
+		key   any
+		where string
+		args  []any
+	}{
+		{"first page", Keyset{Column: "name", Direction: "ASC"}, "", "", nil},
+		{"by id", Keyset{Column: "id", Direction: "DESC"}, nil, " AND id < $1", []any{7}},
+		{"ascending", Keyset{Column: "name", Direction: "ASC"}, "tea", " AND (name > $2 OR (name = $2 AND id > $1))", []any{7, "tea"}},
+		{"descending", Keyset{Column: "price", Direction: "DESC"}, int64(300), " AND (price < $2 OR (price = $2 AND id < $1))", []any{7, int64(300)}},
+		{"nullable", Keyset{Column: "barcode", Direction: "ASC", Nullable: true}, "4006", " AND (barcode > $2 OR (barcode = $2 AND id > $1) OR barcode IS NULL)", []any{7, "4006"}},
+		{"among the nulls", Keyset{Column: "barcode", Direction: "ASC", Nullable: true}, nil, " AND (barcode IS NULL AND id > $1)", []any{7}},
+		{"timestamp", Keyset{Column: "created_at", Direction: "ASC"}, int64(1700000000), " AND (unixepoch(created_at) > $2 OR (unixepoch(created_at) = $2 AND id > $1))", []any{7, int64(1700000000)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := ""
+			if tt.where != "" {
+				cursor = tt.k.Cursor(tt.key, 7)
+			}
+			var f Filter
+			if err := tt.k.After(SQLite, &f, cursor); err != nil {
+				t.Fatal(err)
+			}
+			if f.Where != tt.where || !slices.Equal(f.Args, tt.args) {
+				t.Errorf("After = %q %v, want %q %v", f.Where, f.Args, tt.where, tt.args)
+			}
+		})
+	}
+}
+
+// TestKeysetPaging pages through rows with ties and NULLs on SQLite and
+// checks the pages add up to the whole ordered listing, each row once.
+func TestKeysetPaging(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1) // Every connection would be its own database
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`
+		CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT, price INTEGER NOT NULL);
+		INSERT INTO items (id, name, price) VALUES
+			(1, 'tea', 300), (2, NULL, 100), (3, 'cocoa', 300), (4, 'tea', 200),
+			(5, NULL, 300), (6, 'coffee', 100), (7, 'tea', 100)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range []Keyset{
+		{Column: "id", Direction: "ASC"},
+		{Column: "price", Direction: "ASC"},
+		{Column: "price", Direction: "DESC"},
+		{Column: "name", Direction: "ASC", Nullable: true},
+		{Column: "name", Direction: "DESC", Nullable: true},
+	} {
+		t.Run(k.Column+" "+k.Direction, func(t *testing.T) {
+			want := selectIds(t, db, "SELECT id, "+k.Column+" FROM items"+k.OrderBy(SQLite), nil)
+
+			var got []int
+			cursor := ""
+			for range len(want) + 1 {
+				var f Filter
+				if err := k.After(SQLite, &f, cursor); err != nil {
+					t.Fatal(err)
+				}
+				query := "SELECT id, " + k.Column + " FROM items WHERE 1 = 1" + f.Where + k.OrderBy(SQLite) + f.Page(2, 0)
+				var last struct {
+					id  int
+					key any
+				}
+				page := selectIds(t, db, query, f.Args, &last.id, &last.key)
+				if len(page) == 0 {
+					break
+				}
+				got = append(got, page...)
+				cursor = k.Cursor(last.key, last.id)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("pages = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// selectIds runs query, whose rows are an id and a sort key, and returns
+// the ids. The last row is scanned into last, if given.
+func selectIds(t *testing.T, db *sql.DB, query string, args []any, last ...any) []int {
+	t.Helper()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		var key any
+		if err := rows.Scan(&id, &key); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+		if len(last) == 2 {
+			*last[0].(*int), *last[1].(*any) = id, key
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
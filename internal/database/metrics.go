@@ -0,0 +1,211 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Each repository sends its queries through a DB named after it, which
+// times them into that repository's histogram:
+//
+//	r := &fooRepository{db: database.Observe(db, "foo")}
+//
+// Transactions begun on it are timed the same way, and methods handed a
+// client by a service time it with On. QueryContext is timed until the
+// first row is ready, not while the caller reads the rest. The same
+// wrappers bound every statement by the query timeout and log slow ones
+// (see SetQueryTimeout and SetSlowQuery).
+
+// latencyBuckets are the histogram's upper bounds.
+var latencyBuckets = [...]time.Duration{
+	time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond,
+	50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 5 * time.Second,
+}
+
+// histogram counts query latencies. The last count is for queries slower
+// than every bucket.
+type histogram struct {
+	counts [len(latencyBuckets) + 1]atomic.Uint64
+	sum    atomic.Int64 // Nanoseconds
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i := sort.Search(len(latencyBuckets), func(i int) bool { return d <= latencyBuckets[i] })
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+}
+
+var (
+	histogramsMu sync.Mutex
+	histograms   = map[string]*histogram{}
+)
+
+func histogramFor(name string) *histogram {
+	histogramsMu.Lock()
+	defer histogramsMu.Unlock()
+	h, ok := histograms[name]
+	if !ok {
+		h = &histogram{}
+		histograms[name] = h
+	}
+	return h
+}
+
+// Bucket is one histogram bucket: queries that took at most LeMs
+// milliseconds, or any longer for the last bucket, whose LeMs is 0.
+type Bucket struct {
+	LeMs  float64 `json:"le_ms,omitempty"`
+	Count uint64  `json:"count"` // Cumulative, as Prometheus counts them
+}
+
+// QueryLatency is one repository's histogram.
+type QueryLatency struct {
+	Count   uint64   `json:"count"`
+	TotalMs float64  `json:"total_ms"`
+	Buckets []Bucket `json:"buckets"`
+}
+
+// QueryLatencies returns every repository's histogram since startup.
+func QueryLatencies() map[string]QueryLatency {
+	histogramsMu.Lock()
+	defer histogramsMu.Unlock()
+
+	out := make(map[string]QueryLatency, len(histograms))
+	for name, h := range histograms {
+		var q QueryLatency
+		for i := range h.counts {
+			q.Count += h.counts[i].Load()
+			b := Bucket{Count: q.Count}
+			if i < len(latencyBuckets) {
+				b.LeMs = ms(latencyBuckets[i])
+			}
+			q.Buckets = append(q.Buckets, b)
+		}
+		q.TotalMs = ms(time.Duration(h.sum.Load()))
+		out[name] = q
+	}
+	return out
+}
+
+// PoolStats is the connection pool's state, from sql.DBStats.
+type PoolStats struct {
+	MaxOpen           int     `json:"max_open"`
+	Open              int     `json:"open"`
+	InUse             int     `json:"in_use"`
+	Idle              int     `json:"idle"`
+	WaitCount         int64   `json:"wait_count"`      // Queries that had to wait for a connection
+	WaitMs            float64 `json:"wait_ms"`         // Total time spent waiting
+	MaxIdleClosed     int64   `json:"max_idle_closed"` // Closed for exceeding MaxIdleConns
+	MaxLifetimeClosed int64   `json:"max_lifetime_closed"`
+}
+
+func PoolStatsOf(db *sql.DB) PoolStats {
+	s := db.Stats()
+	return PoolStats{
+		MaxOpen:           s.MaxOpenConnections,
+		Open:              s.OpenConnections,
+		InUse:             s.InUse,
+		Idle:              s.Idle,
+		WaitCount:         s.WaitCount,
+		WaitMs:            ms(s.WaitDuration),
+		MaxIdleClosed:     s.MaxIdleClosed,
+		MaxLifetimeClosed: s.MaxLifetimeClosed,
+	}
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// DB is a *sql.DB whose queries are timed into a repository's histogram.
+type DB struct {
+	*sql.DB
+	name string
+	hist *histogram
+}
+
+// Observe returns db timed under name.
+func Observe(db *sql.DB, name string) *DB {
+	return &DB{DB: db, name: name, hist: histogramFor(name)}
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return timedClient{d.DB, d.name, d.hist}.ExecContext(ctx, query, args...)
+}
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return timedClient{d.DB, d.name, d.hist}.QueryContext(ctx, query, args...)
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return timedClient{d.DB, d.name, d.hist}.QueryRowContext(ctx, query, args...)
+}
+
+func (d *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := d.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, name: d.name, hist: d.hist}, nil
+}
+
+// On returns c timed into this repository's histogram, for queries on a
+// client (usually a transaction) a service handed in.
+func (d *DB) On(c SQLClient) SQLClient {
+	return timedClient{c, d.name, d.hist}
+}
+
+// Tx is a transaction begun on a DB, timed like it.
+type Tx struct {
+	*sql.Tx
+	name string
+	hist *histogram
+}
+
+func (t *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return timedClient{t.Tx, t.name, t.hist}.ExecContext(ctx, query, args...)
+}
+
+func (t *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return timedClient{t.Tx, t.name, t.hist}.QueryContext(ctx, query, args...)
+}
+
+func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return timedClient{t.Tx, t.name, t.hist}.QueryRowContext(ctx, query, args...)
+}
+
+type timedClient struct {
+	c    SQLClient
+	name string
+	hist *histogram
+}
+
+func (t timedClient) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	stmtCtx, cancel := statementContext(ctx)
+	defer cancel()
+	defer t.since(ctx, stmtCtx, query, time.Now())
+	return t.c.ExecContext(stmtCtx, query, args...)
+}
+
+func (t timedClient) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	stmtCtx, _ := statementContext(ctx)
+	defer t.since(ctx, stmtCtx, query, time.Now())
+	return t.c.QueryContext(stmtCtx, query, args...)
+}
+
+func (t timedClient) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	stmtCtx, _ := statementContext(ctx)
+	defer t.since(ctx, stmtCtx, query, time.Now())
+	return t.c.QueryRowContext(stmtCtx, query, args...)
+}
+
+func (t timedClient) since(ctx, stmtCtx context.Context, query string, start time.Time) {
+	d := time.Since(start)
+	t.hist.observe(d)
+	logStatement(t.name, ctx, stmtCtx, query, d)
+}
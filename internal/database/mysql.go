@@ -0,0 +1,198 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL and MariaDB take ? placeholders where the repositories write $1,
+// $2, ... Rather than keep two copies of every query, connections opened
+// by NewDatabase rewrite them on the way to the server, repeating
+// arguments a query uses more than once. NOW() becomes NOW(6) on the way
+// too; without a precision MySQL drops the fraction of a second.
+
+// mysqlConnectorFor connects with the session settings the repositories rely on:
+// ANSI mode, so "desc" quotes an identifier and || concatenates, UTC for
+// every time value, rows affected counting matched rows rather than changed
+// ones, as Postgres does, and multi-statement scripts for migrations.
+func mysqlConnectorFor(dsn string) (driver.Connector, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	cfg.ClientFoundRows = true
+	cfg.MultiStatements = true
+	cfg.InterpolateParams = true
+	if cfg.Params == nil {
+		cfg.Params = map[string]string{}
+	}
+	cfg.Params["sql_mode"] = "'ANSI,STRICT_ALL_TABLES'"
+	cfg.Params["time_zone"] = "'+00:00'"
+
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return mysqlConnector{connector}, nil
+}
+
+type mysqlConnector struct {
+	driver.Connector
+}
+
+func (c mysqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &mysqlConn{conn}, nil
+}
+
+func (c mysqlConnector) Driver() driver.Driver {
+	return mysqlDriver{}
+}
+
+// mysqlDriver identifies MySQL connections to DialectOf.
+type mysqlDriver struct{}
+
+func (mysqlDriver) Open(dsn string) (driver.Conn, error) {
+	return nil, fmt.Errorf("database: open MySQL connections with NewDatabase")
+}
+
+// mysqlConn rewrites placeholders and hands everything else to the
+// driver's own connection.
+type mysqlConn struct {
+	driver.Conn
+}
+
+func (c *mysqlConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *mysqlConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	query, order := rebind(query)
+	stmt, err := c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &mysqlStmt{Stmt: stmt, order: order}, nil
+}
+
+func (c *mysqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	query, order := rebind(query)
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, reorder(args, order))
+}
+
+func (c *mysqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	query, order := rebind(query)
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, reorder(args, order))
+}
+
+func (c *mysqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *mysqlConn) Ping(ctx context.Context) error {
+	return c.Conn.(driver.Pinger).Ping(ctx)
+}
+
+func (c *mysqlConn) CheckNamedValue(nv *driver.NamedValue) error {
+	return c.Conn.(driver.NamedValueChecker).CheckNamedValue(nv)
+}
+
+func (c *mysqlConn) ResetSession(ctx context.Context) error {
+	return c.Conn.(driver.SessionResetter).ResetSession(ctx)
+}
+
+func (c *mysqlConn) IsValid() bool {
+	return c.Conn.(driver.Validator).IsValid()
+}
+
+type mysqlStmt struct {
+	driver.Stmt
+	order []int
+}
+
+// NumInput is unknown: the caller passes one argument per $n, which isn't
+// the number of ? the server sees.
+func (s *mysqlStmt) NumInput() int {
+	return -1
+}
+
+func (s *mysqlStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.Stmt.(driver.StmtExecContext).ExecContext(ctx, reorder(args, s.order))
+}
+
+func (s *mysqlStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.Stmt.(driver.StmtQueryContext).QueryContext(ctx, reorder(args, s.order))
+}
+
+// rebind replaces each $n outside quotes and comments with ?, and returns
+// the n of each in order. It also gives NOW() its precision.
+func rebind(query string) (string, []int) {
+	var b strings.Builder
+	var order []int
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String(), order
+			}
+			b.WriteString(query[i : i+end+2])
+			i += end + 1
+			continue
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String(), order
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+			continue
+		case c == 'N' && strings.HasPrefix(query[i:], "NOW()"):
+			b.WriteString("NOW(6)")
+			i += len("NOW()") - 1
+			continue
+		case c == '$':
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			if n, err := strconv.Atoi(query[i+1 : j]); err == nil {
+				b.WriteByte('?')
+				order = append(order, n)
+				i = j - 1
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), order
+}
+
+// reorder lays args out in placeholder order.
+func reorder(args []driver.NamedValue, order []int) []driver.NamedValue {
+	if len(args) == 0 {
+		return args
+	}
+	out := make([]driver.NamedValue, len(order))
+	for i, n := range order {
+		if n >= 1 && n <= len(args) {
+			out[i] = args[n-1]
+		}
+		out[i].Ordinal = i + 1
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// Entity tables record created_at, written once on insert, and updated_at,
+// moved on every change (with revision, where the table has one). Inserts
+// set both explicitly: SQLite couldn't give the columns a NOW() default.
+
+// Period narrows a listing by when rows were created or last updated. Zero
+// times leave that end of the range open; both ends are inclusive.
+type Period struct {
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	UpdatedFrom time.Time
+	UpdatedTo   time.Time
+}
+
+// Where returns the " AND ..." conditions for p, numbering placeholders from
+// argPos, together with their arguments.
+func (p Period) Where(argPos int) (string, []any) {
+	var where string
+	var args []any
+	for _, b := range []struct {
+		cond string
+		at   time.Time
+	}{
+		{"created_at >= $%d", p.CreatedFrom},
+		{"created_at <= $%d", p.CreatedTo},
+		{"updated_at >= $%d", p.UpdatedFrom},
+		{"updated_at <= $%d", p.UpdatedTo},
+	} {
+		if b.at.IsZero() {
+			continue
+		}
+		where += " AND " + fmt.Sprintf(b.cond, argPos)
+		args = append(args, b.at)
+		argPos++
+	}
+	return where, args
+}
+
+// FromUnix turns an entity's Unix timestamp back into a time to bind, or
+// nil for 0, as a Deleted that isn't set is.
+func FromUnix(sec int64) *time.Time {
+	if sec == 0 {
+		return nil
+	}
+	t := time.Unix(sec, 0).UTC()
+	return &t
+}
@@ -0,0 +1,253 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Repositories keep their SQL and their scan functions; the plumbing around
+// them lives here. A scan function reads one row into an entity:
+//
+//	func (r *fooRepository) scanFoo(s database.Scanner) (*Foo, error)
+//
+// and is handed to Get for single lookups and to Select for listings, which
+// build their WHERE clause, ordering and page with a Filter.
+
+// Scanner is a *sql.Row or *sql.Rows. It is an alias so scan functions
+// written against the bare interface fit too.
+type Scanner = interface {
+	Scan(dest ...any) error
+}
+
+// Select runs query and scans every row it returns, in order. It returns nil
+// if there are none.
+func Select[T any](ctx context.Context, c SQLClient, scan func(Scanner) (T, error), query string, args ...any) ([]T, error) {
+	rows, err := c.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []T
+	for rows.Next() {
+		v, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return out, nil
+}
+
+// Value is the scan function for a single-column row, for selecting a list
+// of ids or slugs.
+func Value[T any](s Scanner) (T, error) {
+	var v T
+	err := s.Scan(&v)
+	return v, err
+}
+
+// Get runs a query for a single row and scans it, returning notFound if
+// there is no such row.
+func Get[T any](ctx context.Context, c SQLClient, scan func(Scanner) (T, error), notFound error, query string, args ...any) (T, error) {
+	v, err := scan(c.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		var zero T
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, notFound
+		}
+		return zero, err
+	}
+	return v, nil
+}
+
+// Exists reports whether table has a row matching where, such as a
+// clashing key before an insert that has to say which one clashed.
+func Exists(ctx context.Context, c SQLClient, table, where string, args ...any) (bool, error) {
+	var one int
+	err := c.QueryRowContext(ctx, fmt.Sprintf(`SELECT 1 FROM %s WHERE %s LIMIT 1`, table, where), args...).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ExecAffected runs a statement and reports whether it changed any row.
+// Callers turn false into their own not-found (or conflict) error.
+func ExecAffected(ctx context.Context, c SQLClient, query string, args ...any) (bool, error) {
+	n, err := ExecCount(ctx, c, query, args...)
+	return n > 0, err
+}
+
+// ExecCount runs a statement and returns the number of rows it changed.
+func ExecCount(ctx context.Context, c SQLClient, query string, args ...any) (int, error) {
+	result, err := c.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(rows), nil
+}
+
+// Filter collects the conditions of a listing's WHERE clause and their
+// arguments, numbering placeholders as they are added:
+//
+//	var f database.Filter
+//	if opts.MinPrice > 0 {
+//		f.And("price >= " + f.Arg(opts.MinPrice))
+//	}
+//	query += f.Where + " ORDER BY ..." + f.Page(opts.Limit, opts.Offset)
+//	rows, err := database.Select(ctx, r.db, r.scanFoo, query, f.Args...)
+type Filter struct {
+	Where string // " AND ..." conditions, empty for none
+	Args  []any
+}
+
+// Arg adds v to the arguments and returns its placeholder.
+func (f *Filter) Arg(v any) string {
+	f.Args = append(f.Args, v)
+	return fmt.Sprintf("$%d", len(f.Args))
+}
+
+// And adds a condition.
+func (f *Filter) And(cond string) {
+	f.Where += " AND " + cond
+}
+
+// Period adds the created and updated bounds of p.
+func (f *Filter) Period(p Period) {
+	where, args := p.Where(len(f.Args) + 1)
+	f.Where += where
+	f.Args = append(f.Args, args...)
+}
+
+// Page returns the LIMIT and OFFSET clauses for one page, leaving out either
+// when it is zero.
+func (f *Filter) Page(limit, offset int) string {
+	var page string
+	if limit > 0 {
+		page += " LIMIT " + f.Arg(limit)
+	}
+	if offset > 0 {
+		page += " OFFSET " + f.Arg(offset)
+	}
+	return page
+}
+
+// Outer returns a filter for conditions on a query enclosing the one f
+// filters, numbering its placeholders on from f's; its Args hold f's too.
+func (f Filter) Outer() Filter {
+	return Filter{Args: slices.Clone(f.Args)}
+}
+
+// Listing is the query for one page of a listing. With Total it also
+// counts every row the listing matches, whatever the page, in the same
+// round trip: COUNT(*) OVER() over the rows matching Filter, with the
+// cursor, order and page applied around it so they don't narrow the count.
+//
+//	var f database.Filter
+//	f.And("store_id = " + f.Arg(database.StoreOf(ctx)))
+//	cursor := f.Outer()
+//	if err := k.After(r.dialect, &cursor, opts.After); err != nil { ... }
+//	items, total, err := database.SelectListing(ctx, r.db, r.scanFoo, database.Listing{
+//		Columns: fooColumns, Table: "foos", Where: database.Live, Filter: f, Cursor: cursor,
+//		OrderBy: k.OrderBy(r.dialect), Limit: opts.Limit, Offset: opts.Offset, Total: opts.Total,
+//	})
+type Listing struct {
+	Columns string // SELECT list, as the scan function reads it
+	Table   string
+	Where   string // Condition on Table, with Filter's after it
+	Filter  Filter // What the listing matches
+	Cursor  Filter // From Filter.Outer: where the page starts, such as a keyset cursor
+	OrderBy string
+	Limit   int
+	Offset  int
+	Total   bool
+}
+
+// query returns the SQL of the page and its arguments. Counted, the rows
+// are numbered in a subquery named after Table, so Columns, the cursor and
+// OrderBy read them as they would the table itself.
+func (l Listing) query() (string, []any) {
+	page := l.Cursor
+	if len(page.Args) < len(l.Filter.Args) {
+		page = l.Filter.Outer() // No cursor
+	}
+	matching := " FROM " + l.Table + " WHERE " + l.Where + l.Filter.Where
+	var query string
+	if l.Total {
+		query = "SELECT " + l.Columns + ", listing_total FROM (SELECT " + l.Table + ".*, COUNT(*) OVER() AS listing_total" +
+			matching + ") " + l.Table + " WHERE 1=1" + page.Where
+	} else {
+		query = "SELECT " + l.Columns + matching + page.Where
+	}
+	query += l.OrderBy + page.Page(l.Limit, l.Offset)
+	return query, page.Args
+}
+
+// SelectListing runs l and scans the rows of its page, returning nil if
+// there are none. The total is how many rows match in all with l.Total, and
+// 0 without. A page past the last has no rows to carry the count, so only
+// then is it counted separately.
+func SelectListing[T any](ctx context.Context, c SQLClient, scan func(Scanner) (T, error), l Listing) ([]T, int, error) {
+	query, args := l.query()
+	if !l.Total {
+		rows, err := Select(ctx, c, scan, query, args...)
+		return rows, 0, err
+	}
+
+	var total int
+	rows, err := Select(ctx, c, func(s Scanner) (T, error) {
+		return scan(totalScanner{s, &total})
+	}, query, args...)
+	if err != nil || len(rows) > 0 || l.Offset == 0 && l.Cursor.Where == "" {
+		return rows, total, err
+	}
+
+	query = "SELECT COUNT(*) FROM " + l.Table + " WHERE " + l.Where + l.Filter.Where
+	err = c.QueryRowContext(ctx, query, l.Filter.Args...).Scan(&total)
+	return rows, total, err
+}
+
+// totalScanner reads the count a Listing with Total adds after the columns
+// of each row.
+type totalScanner struct {
+	Scanner
+	total *int
+}
+
+func (s totalScanner) Scan(dest ...any) error {
+	return s.Scanner.Scan(append(dest, s.total)...)
+}
+
+// SortColumn returns the requested sort column if it is one of allowed, and
+// fallback otherwise. Only columns from allowed ever reach the query.
+func SortColumn(requested, fallback string, allowed ...string) string {
+	if slices.Contains(allowed, requested) {
+		return requested
+	}
+	return fallback
+}
+
+// SortDirection turns a requested "asc" or "desc" into ASC or DESC, and
+// anything else into fallback.
+func SortDirection(requested, fallback string) string {
+	switch requested {
+	case "asc":
+		return "ASC"
+	case "desc":
+		return "DESC"
+	}
+	return fallback
+}
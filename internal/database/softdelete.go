@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Tables that keep deleted rows (inventory, products, roles, users) share
+// one convention: a nullable deleted_at, NULL while the row is live and the
+// time it went to the trash otherwise. Lookups and listings add Live to
+// their WHERE clause unless they are showing the trash, Restore brings a
+// row back, and a purge job removes rows that have been in the trash for
+// longer than the retention period (see TrashedBefore). All three keep to
+// the store of ctx.
+
+// Live is the WHERE condition for rows that are not in the trash.
+const Live = "deleted_at IS NULL"
+
+// Trashed is the WHERE condition for rows that are.
+const Trashed = "deleted_at IS NOT NULL"
+
+// SoftDelete moves the live row of table with the given id to the trash,
+// along with any extra assignments such as "active = FALSE", and moves its
+// updated_at. It reports false if there is no such live row.
+func SoftDelete(ctx context.Context, c SQLClient, table string, id int, set ...string) (bool, error) {
+	return setDeleted(ctx, c, table, id, "NOW()", Live, set)
+}
+
+// Restore takes the row of table with the given id out of the trash, along
+// with any extra assignments. It reports false if the row isn't in the
+// trash.
+func Restore(ctx context.Context, c SQLClient, table string, id int, set ...string) (bool, error) {
+	return setDeleted(ctx, c, table, id, "NULL", Trashed, set)
+}
+
+func setDeleted(ctx context.Context, c SQLClient, table string, id int, value, where string, set []string) (bool, error) {
+	assign := "deleted_at = " + value + ", updated_at = NOW()"
+	for _, s := range set {
+		assign += ", " + s
+	}
+	query := fmt.Sprintf(`UPDATE %s SET %s WHERE id = $1 AND store_id = $2 AND %s`, table, assign, where)
+
+	result, err := c.ExecContext(ctx, query, id, StoreOf(ctx))
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// TrashedBefore returns the ids of rows of table that went to the trash
+// before the cutoff, oldest first, for purge jobs to work through. Extra
+// conditions narrow it further.
+func TrashedBefore(ctx context.Context, c SQLClient, table string, cutoff time.Time, and ...string) ([]int, error) {
+	where := "deleted_at < $1 AND store_id = $2"
+	for _, cond := range and {
+		where += " AND " + cond
+	}
+	query := fmt.Sprintf(`SELECT id FROM %s WHERE %s ORDER BY deleted_at, id`, table, where)
+
+	rows, err := c.QueryContext(ctx, query, cutoff, StoreOf(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,35 @@
+package database
+
+import "context"
+
+// Several stores (shops) can share one database. Every entity table has a
+// store_id, and the context of a request carries the store it acts for,
+// taken from its token or X-Store header. Repositories keep every query to
+// that store, and give new rows to it:
+//
+//	query := `SELECT ... FROM products WHERE id = $1 AND store_id = $2`
+//	row := r.db.QueryRowContext(ctx, query, id, database.StoreOf(ctx))
+//
+// Rows reached only through an entity (an item's movements, a user's
+// devices) have no store_id of their own and are looked up through it.
+// Background jobs run once per store, with each one in the context.
+
+// DefaultStore is the store of a context that names none, and the one rows
+// from before stores existed belong to. A deployment serving a single shop
+// never needs another.
+const DefaultStore = 1
+
+type storeKey struct{}
+
+// WithStore returns ctx acting for the given store.
+func WithStore(ctx context.Context, id int) context.Context {
+	return context.WithValue(ctx, storeKey{}, id)
+}
+
+// StoreOf returns the store ctx acts for.
+func StoreOf(ctx context.Context) int {
+	if id, ok := ctx.Value(storeKey{}).(int); ok {
+		return id
+	}
+	return DefaultStore
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+var (
+	queryTimeout = 30 * time.Second
+	slowQuery    time.Duration
+)
+
+// SetQueryTimeout sets how long a repository statement may run before its
+// context is cancelled, freeing its connection; 0 leaves statements
+// unbounded. It applies to each statement on its own, and only ever
+// shortens a deadline the caller's context already has.
+func SetQueryTimeout(d time.Duration) {
+	queryTimeout = d
+}
+
+// SetSlowQuery sets how long a repository statement may take before it is
+// logged as slow; 0, the default, logs none. Only the SQL is logged, never
+// its arguments, which can hold personal data.
+func SetSlowQuery(d time.Duration) {
+	slowQuery = d
+}
+
+// statementContext bounds one statement by the query timeout. Exec can
+// cancel as soon as it returns; a query's rows are read after the call, so
+// its context is left to expire with the timeout or with the caller's
+// context, whichever ends first.
+func statementContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if queryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, queryTimeout)
+}
+
+// logStatement logs a statement of the named repository that took d if it
+// ran into the query timeout or was slow. ctx is the caller's context and
+// stmtCtx the one statementContext made from it; a statement cut short
+// because the caller gave up, such as a client going away, didn't run into
+// the timeout.
+func logStatement(name string, ctx, stmtCtx context.Context, query string, d time.Duration) {
+	switch {
+	case ctx.Err() == nil && errors.Is(stmtCtx.Err(), context.DeadlineExceeded):
+		log.Printf("Query on %s cancelled after %s, the query timeout: %s", name, d.Round(time.Millisecond), statement(query))
+	case slowQuery > 0 && d >= slowQuery:
+		log.Printf("Slow query on %s took %s: %s", name, d.Round(time.Millisecond), statement(query))
+	}
+}
+
+// statement is query on one line, cut short if it is long.
+func statement(query string) string {
+	s := strings.Join(strings.Fields(query), " ")
+	if len(s) > 500 {
+		s = s[:500] + "..."
+	}
+	return s
+}
@@ -7,7 +7,6 @@ import (
 )
 
 // TxManager handles the execution of functions within a database transaction.
-// Currently Unused
 type TxManager interface {
 	// Run executes the given function within a transaction.
 	// The function receives a context and a SQLClient (the transaction).
@@ -0,0 +1,118 @@
+package directory
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/iteranya/practicing-go/internal/entities/user"
+)
+
+// Config describes an LDAP or Active Directory server. Users are found with
+// a search bound as the service account, then verified by binding as the
+// user's own DN.
+type Config struct {
+	URL          string // ldaps://dc.example.com:636 or ldap://...
+	StartTLS     bool   // Upgrade an ldap:// connection before binding
+	BindDN       string // Service account used for the user search
+	BindPassword string
+	BaseDN       string
+	// UserFilter locates the user, %s is replaced by the escaped username.
+	// "(uid=%s)" for OpenLDAP, "(sAMAccountName=%s)" for Active Directory.
+	UserFilter string
+	Timeout    time.Duration
+}
+
+// LDAPAuthenticator implements user.Authenticator against a directory.
+type LDAPAuthenticator struct {
+	cfg Config
+}
+
+func NewLDAPAuthenticator(cfg Config) *LDAPAuthenticator {
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(uid=%s)"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &LDAPAuthenticator{cfg: cfg}
+}
+
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, username, password string) (*user.ExternalIdentity, error) {
+	// An empty password is an "unauthenticated bind" that many servers accept
+	if username == "" || password == "" {
+		return nil, user.ErrInvalidCredentials
+	}
+
+	conn, err := a.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+		return nil, fmt.Errorf("ldap service bind failed: %w", err)
+	}
+
+	search := ldap.NewSearchRequest(
+		a.cfg.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(a.cfg.Timeout.Seconds()), false,
+		fmt.Sprintf(a.cfg.UserFilter, ldap.EscapeFilter(username)),
+		[]string{"dn", "mail", "displayName", "cn"},
+		nil,
+	)
+	result, err := conn.Search(search)
+	if err != nil {
+		return nil, fmt.Errorf("ldap search failed: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, user.ErrInvalidCredentials
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, user.ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap user bind failed: %w", err)
+	}
+
+	name := entry.GetAttributeValue("displayName")
+	if name == "" {
+		name = entry.GetAttributeValue("cn")
+	}
+
+	return &user.ExternalIdentity{
+		Provider: "ldap",
+		Email:    strings.ToLower(entry.GetAttributeValue("mail")),
+		Name:     name,
+	}, nil
+}
+
+func (a *LDAPAuthenticator) connect() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(a.cfg.URL, ldap.DialWithDialer(&net.Dialer{Timeout: a.cfg.Timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("ldap connect failed: %w", err)
+	}
+	conn.SetTimeout(a.cfg.Timeout)
+
+	if a.cfg.StartTLS {
+		u, err := url.Parse(a.cfg.URL)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("invalid ldap url: %w", err)
+		}
+		if err := conn.StartTLS(&tls.Config{ServerName: u.Hostname()}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap starttls failed: %w", err)
+		}
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,10 @@
+package inventory
+
+import "github.com/iteranya/practicing-go/internal/utils"
+
+// AlertHub fans stock alerts out to live subscribers (the SSE stream).
+type AlertHub = utils.Hub[StockAlert]
+
+func NewAlertHub() *AlertHub {
+	return utils.NewHub[StockAlert]()
+}
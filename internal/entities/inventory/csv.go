@@ -0,0 +1,208 @@
+package inventory
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ImportRowResult describes what happened to a single CSV row during import.
+type ImportRowResult struct {
+	Row    int    `json:"row"` // 1-based line number in the CSV, header is row 1
+	Slug   string `json:"slug"`
+	Status string `json:"status"` // created, updated, error
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport is returned by the bulk import endpoint.
+type ImportReport struct {
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+const (
+	ImportStatusCreated = "created"
+	ImportStatusUpdated = "updated"
+	ImportStatusError   = "error"
+)
+
+// importRequiredColumns must be present in the header. The optional columns
+// are stock, tags ("|"-separated), min_stock and max_stock. Header names are matched case-insensitively
+// and spaces are treated as underscores ("Min Stock" works).
+var importRequiredColumns = []string{"slug", "name"}
+
+// parsedRow is a CSV row after parsing, before it reaches the database.
+type parsedRow struct {
+	line int
+	item *Inventory
+	err  error
+}
+
+// parseImportCSV reads the whole CSV and converts each data row into an
+// Inventory. Row-level problems are attached to the row instead of aborting,
+// so the caller can report them individually. Only structural problems
+// (unreadable CSV, missing required header) return an error.
+func parseImportCSV(r io.Reader) ([]parsedRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1 // validated per row below
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: empty csv", ErrInvalidInput)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	cols := make(map[string]int, len(header))
+	for i, h := range header {
+		name := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(h)), " ", "_")
+		cols[name] = i
+	}
+	for _, c := range importRequiredColumns {
+		if _, ok := cols[c]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalidInput, c)
+		}
+	}
+
+	var rows []parsedRow
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rows = append(rows, parsedRow{line: line, err: parseErr.Err})
+				continue
+			}
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+
+		rows = append(rows, parseImportRecord(line, record, cols))
+	}
+
+	return rows, nil
+}
+
+func parseImportRecord(line int, record []string, cols map[string]int) parsedRow {
+	get := func(name string) string {
+		i, ok := cols[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	inv := &Inventory{
+		Slug: get("slug"),
+		Name: get("name"),
+	}
+	row := parsedRow{line: line, item: inv}
+
+	for _, t := range strings.Split(get("tags"), tagSeparator) {
+		if t = strings.TrimSpace(t); t != "" {
+			inv.Tags = append(inv.Tags, t)
+		}
+	}
+
+	if inv.Slug == "" || inv.Name == "" {
+		row.err = errors.New("slug and name are required")
+		return row
+	}
+
+	if v := get("stock"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			row.err = fmt.Errorf("invalid stock %q", v)
+			return row
+		}
+		inv.Stock = n
+	}
+
+	if v := get("min_stock"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			row.err = fmt.Errorf("invalid min stock %q", v)
+			return row
+		}
+		inv.MinStock = n
+	}
+
+	if v := get("max_stock"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			row.err = fmt.Errorf("invalid max stock %q", v)
+			return row
+		}
+		inv.MaxStock = n
+	}
+
+	return row
+}
+
+// tagSeparator joins multiple tags inside the single "tags" CSV column.
+const tagSeparator = "|"
+
+// exportColumns is the header written by writeExportCSV. The first six
+// columns line up with the import format so an export can be re-imported.
+var exportColumns = []string{"slug", "name", "stock", "tags", "min_stock", "max_stock", "id", "desc", "label", "unit_cost", "barcode", "custom"}
+
+// writeExportCSV writes items as CSV. Custom fields are serialized as a
+// single JSON column so arbitrary keys survive the round trip. Without
+// withCost the unit_cost column is left empty.
+func writeExportCSV(w io.Writer, items []*Inventory, withCost bool) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(exportColumns); err != nil {
+		return fmt.Errorf("failed to write csv header: %w", err)
+	}
+
+	for _, inv := range items {
+		custom := ""
+		if len(inv.Custom) > 0 {
+			b, err := json.Marshal(inv.Custom)
+			if err != nil {
+				return fmt.Errorf("failed to marshal custom data: %w", err)
+			}
+			custom = string(b)
+		}
+
+		unitCost := ""
+		if withCost {
+			unitCost = strconv.FormatInt(inv.UnitCost, 10)
+		}
+
+		record := []string{
+			inv.Slug,
+			inv.Name,
+			strconv.FormatInt(inv.Stock, 10),
+			strings.Join(inv.Tags, tagSeparator),
+			strconv.FormatInt(inv.MinStock, 10),
+			strconv.FormatInt(inv.MaxStock, 10),
+			strconv.Itoa(inv.Id),
+			inv.Desc,
+			inv.Label,
+			unitCost,
+			inv.Barcode,
+			custom,
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write csv row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -1,28 +1,78 @@
 package inventory
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/utils"
 )
 
 type InventoryHandler struct {
 	service InventoryService
+	alerts  *AlertHub
 }
 
-func NewInventoryHandler(service InventoryService) *InventoryHandler {
-	return &InventoryHandler{service: service}
+func NewInventoryHandler(service InventoryService, alerts *AlertHub) *InventoryHandler {
+	return &InventoryHandler{service: service, alerts: alerts}
 }
 
-// RegisterRoutes helper to attach handlers to a mux
-func (h *InventoryHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("POST /inventory", h.HandleCreate)
-	mux.HandleFunc("GET /inventory", h.HandleList)
-	mux.HandleFunc("GET /inventory/{id}", h.HandleGet) // supports id or slug
-	mux.HandleFunc("PUT /inventory/{id}", h.HandleUpdate)
-	mux.HandleFunc("DELETE /inventory/{id}", h.HandleDelete)
-	mux.HandleFunc("PATCH /inventory/{id}/stock", h.HandleAdjustStock)
+// Routes lists the inventory endpoints and the permission each one needs
+func (h *InventoryHandler) Routes() []utils.Route {
+	return []utils.Route{
+		{Pattern: "POST /inventory", Perm: utils.PermInventoryCreate, Handler: h.HandleCreate},
+		{Pattern: "POST /inventory/import", Perm: utils.PermInventoryCreate, Handler: h.HandleImport},
+		{Pattern: "GET /inventory", Perm: utils.PermInventoryRead, Handler: h.HandleList},
+		{Pattern: "GET /inventory/export", Perm: utils.PermInventoryRead, Handler: h.HandleExport},
+		{Pattern: "GET /inventory/summary", Perm: utils.PermInventoryRead, Handler: h.HandleSummary},
+		{Pattern: "GET /inventory/alerts", Perm: utils.PermInventoryRead, Handler: h.HandleAlerts},        // Server-Sent Events stream
+		{Pattern: "GET /inventory/{id}", Perm: utils.PermInventoryRead, Handler: h.HandleGet},             // supports id or slug
+		{Pattern: "GET /inventory/barcode", Perm: utils.PermInventoryRead, Handler: h.HandleGetByBarcode}, // ?code=...
+		{Pattern: "PUT /inventory/{id}", Perm: utils.PermInventoryUpdate, Handler: h.HandleUpdate},
+		{Pattern: "DELETE /inventory/{id}", Perm: utils.PermInventoryDelete, Handler: h.HandleDelete}, // soft delete, see ?deleted=true
+		{Pattern: "POST /inventory/{id}/restore", Perm: utils.PermInventoryDelete, Handler: h.HandleRestore},
+		{Pattern: "DELETE /inventory/{id}/purge", Perm: utils.PermInventoryDelete, Handler: h.HandlePurge},
+		{Pattern: "PATCH /inventory/{id}/stock", Perm: utils.PermInventoryUpdate, Handler: h.HandleAdjustStock},
+		{Pattern: "GET /inventory/{id}/movements", Perm: utils.PermInventoryRead, Handler: h.HandleMovements},
+		{Pattern: "GET /inventory/{id}/products", Perm: utils.PermInventoryRead, Handler: h.HandleProductsUsing},
+		{Pattern: "POST /inventory/{id}/reservations", Perm: utils.PermInventoryUpdate, Handler: h.HandleReserve},
+
+		// Snapshots
+		{Pattern: "GET /inventory/{id}/history", Perm: utils.PermInventoryRead, Handler: h.HandleHistory},
+		{Pattern: "GET /inventory/snapshots", Perm: utils.PermInventoryRead, Handler: h.HandleSnapshotsOn},
+
+		// Supplier prices
+		{Pattern: "GET /inventory/{id}/suppliers", Perm: utils.PermInventoryRead, Handler: h.HandleGetSupplierPrices},
+		{Pattern: "PUT /inventory/{id}/suppliers", Perm: utils.PermInventoryUpdate, Handler: h.HandleSetSupplierPrice},
+		{Pattern: "DELETE /inventory/{id}/suppliers/{supplier}", Perm: utils.PermInventoryUpdate, Handler: h.HandleRemoveSupplierPrice},
+		{Pattern: "GET /inventory/suppliers/compare", Perm: utils.PermInventoryRead, Handler: h.HandleCompareSuppliers},
+
+		// Analytics
+		{Pattern: "GET /inventory/dashboard", Perm: utils.PermInventoryRead, Handler: h.HandleParDashboard},
+		{Pattern: "GET /inventory/forecast", Perm: utils.PermInventoryRead, Handler: h.HandleForecast},
+		{Pattern: "GET /inventory/consumption", Perm: utils.PermInventoryRead, Handler: h.HandleConsumptionReport},
+		{Pattern: "GET /inventory/turnover", Perm: utils.PermInventoryRead, Handler: h.HandleTurnoverReport},
+
+		// Stocktakes (physical counts)
+		{Pattern: "POST /stocktakes", Perm: utils.PermInventoryUpdate, Handler: h.HandleOpenStocktake},
+		{Pattern: "PUT /stocktakes/{id}/counts", Perm: utils.PermInventoryUpdate, Handler: h.HandleRecordCount},
+		{Pattern: "POST /stocktakes/{id}/close", Perm: utils.PermInventoryUpdate, Handler: h.HandleCloseStocktake},
+		{Pattern: "GET /stocktakes/{id}/report", Perm: utils.PermInventoryRead, Handler: h.HandleStocktakeReport},
+		{Pattern: "GET /stocktakes/report", Perm: utils.PermInventoryRead, Handler: h.HandleShrinkageReport},
+
+		// Managed tags and labels
+		{Pattern: "GET /inventory-tags", Perm: utils.PermInventoryRead, Handler: h.HandleListTags}, // ?kind=tag|label
+		{Pattern: "POST /inventory-tags", Perm: utils.PermInventoryCreate, Handler: h.HandleCreateTag},
+		{Pattern: "PUT /inventory-tags/{id}", Perm: utils.PermInventoryUpdate, Handler: h.HandleRenameTag},
+		{Pattern: "DELETE /inventory-tags/{id}", Perm: utils.PermInventoryDelete, Handler: h.HandleDeleteTag},
+	}
 }
 
 // CREATE
@@ -39,7 +89,7 @@ func (h *InventoryHandler) HandleCreate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusCreated, created)
+	h.respondWithJSON(w, r, http.StatusCreated, created)
 }
 
 // GET (By ID or Slug based on format)
@@ -61,37 +111,125 @@ func (h *InventoryHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, result)
+	h.respondWithJSON(w, r, http.StatusOK, result)
+}
+
+// GET BY BARCODE (for scanners during receiving / stocktakes)
+// The code is a query parameter rather than a path segment because
+// "/inventory/barcode/{code}" would be ambiguous with "/inventory/{id}/history"
+// and friends, which net/http rejects at registration.
+func (h *InventoryHandler) HandleGetByBarcode(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.GetByBarcode(r.Context(), r.URL.Query().Get("code"))
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, result)
 }
 
 // LIST / SEARCH
 func (h *InventoryHandler) HandleList(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query()
+	params := h.parseListParams(r)
 
-	limit, _ := strconv.Atoi(query.Get("limit"))
-	if limit <= 0 {
-		limit = 20
+	items, total, err := h.service.ListInventory(r.Context(), params)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
 	}
-	page, _ := strconv.Atoi(query.Get("page"))
-	if page <= 0 {
-		page = 1
+
+	// Pagination total goes in a header so the body stays a plain array
+	if params.Query == "" {
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+		// A full page may have more after it
+		if len(items) == params.Limit {
+			w.Header().Set("X-Next-Cursor", nextCursor(items[len(items)-1], params.SortBy, params.SortOrder))
+		}
 	}
 
-	params := ListParams{
-		Tag:   query.Get("tag"),
-		Label: query.Get("label"),
-		Query: query.Get("q"), // ?q=something triggers search
-		Limit: limit,
-		Page:  page,
+	h.respondWithJSON(w, r, http.StatusOK, items)
+}
+
+// EXPORT (CSV)
+// Accepts the same filters as LIST; pagination is ignored.
+func (h *InventoryHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	params := h.parseListParams(r)
+
+	// Buffer first so a failed query still gets a proper JSON error response
+	var buf bytes.Buffer
+	if err := h.service.ExportCSV(r.Context(), params, &buf); err != nil {
+		h.respondWithError(w, err)
+		return
 	}
 
-	items, err := h.service.ListInventory(r.Context(), params)
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="inventory.csv"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// SUMMARY
+func (h *InventoryHandler) HandleSummary(w http.ResponseWriter, r *http.Request) {
+	summary, err := h.service.GetSummary(r.Context())
 	if err != nil {
 		h.respondWithError(w, err)
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, items)
+	h.respondWithJSON(w, r, http.StatusOK, summary)
+}
+
+// ALERTS (Server-Sent Events)
+// Streams low-stock and out-of-stock alerts in the caller's store as they
+// happen. Each alert is an SSE event named after its kind with the JSON alert
+// as data; a comment line is sent periodically to keep proxies from closing
+// an idle stream.
+func (h *InventoryHandler) HandleAlerts(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	// The server's WriteTimeout would otherwise cut the stream off
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	utils.LiftRequestTimeout(r.Context())
+
+	alerts, cancel := h.alerts.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case alert := <-alerts:
+			if alert.Store != database.StoreOf(r.Context()) {
+				continue
+			}
+			data, err := json.Marshal(alert)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", alert.Kind, data); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
 }
 
 // UPDATE
@@ -114,7 +252,7 @@ func (h *InventoryHandler) HandleUpdate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+	h.respondWithJSON(w, r, http.StatusOK, map[string]string{"status": "updated"})
 }
 
 // DELETE
@@ -131,7 +269,41 @@ func (h *InventoryHandler) HandleDelete(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+	h.respondWithJSON(w, r, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// RESTORE (undo a soft delete)
+func (h *InventoryHandler) HandleRestore(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RestoreInventory(r.Context(), id); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, map[string]string{"status": "restored"})
+}
+
+// PURGE (permanent, only for soft-deleted items no recipe uses)
+func (h *InventoryHandler) HandlePurge(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.PurgeInventory(r.Context(), id); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, map[string]string{"status": "purged"})
 }
 
 // ADJUST STOCK
@@ -143,26 +315,576 @@ func (h *InventoryHandler) HandleAdjustStock(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Expecting JSON: {"delta": 10} or {"delta": -5}
+	// Expecting JSON: {"delta": -5, "reason": "waste", "note": "dropped a tray"}
+	// Receiving may include the price paid: {"delta": 10, "reason": "received", "unit_cost": 1200}
+	var body struct {
+		Delta    int64  `json:"delta"`
+		Reason   string `json:"reason"`
+		Note     string `json:"note"`
+		UnitCost int64  `json:"unit_cost"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value(utils.UserIDKey).(int)
+
+	result, err := h.service.AdjustStock(r.Context(), id, StockAdjustment{
+		Delta:    body.Delta,
+		Reason:   body.Reason,
+		Note:     body.Note,
+		UnitCost: body.UnitCost,
+		UserId:   userID,
+	})
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, result)
+}
+
+// MOVEMENT HISTORY
+func (h *InventoryHandler) HandleMovements(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 50
+	}
+
+	movements, err := h.service.GetMovements(r.Context(), id, limit)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, movements)
+}
+
+// IMPORT (CSV)
+// Accepts either a raw text/csv body or a multipart form with a "file" field.
+func (h *InventoryHandler) HandleImport(w http.ResponseWriter, r *http.Request) {
+	body := io.Reader(r.Body)
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Missing file field", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	report, err := h.service.ImportCSV(r.Context(), body)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, report)
+}
+
+// STOCK HISTORY
+// ?start_date=2024-01-01&end_date=2024-01-31 (defaults to the last 30 days)
+func (h *InventoryHandler) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	start, end := h.parseDateRange(r)
+
+	history, err := h.service.GetStockHistory(r.Context(), id, start, end)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, history)
+}
+
+// SNAPSHOTS ON A DAY
+// ?date=2024-01-01 answers "what did we have on the 1st?"
+func (h *InventoryHandler) HandleSnapshotsOn(w http.ResponseWriter, r *http.Request) {
+	day, err := time.Parse("2006-01-02", r.URL.Query().Get("date"))
+	if err != nil {
+		http.Error(w, "Invalid or missing date (YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+
+	snapshots, err := h.service.GetStockOn(r.Context(), day)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, snapshots)
+}
+
+// RESERVE
+func (h *InventoryHandler) HandleReserve(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Expecting JSON: {"order_id": 12, "quantity": 2, "ttl_minutes": 30}
+	var body struct {
+		OrderId    int   `json:"order_id"`
+		Quantity   int64 `json:"quantity"`
+		TTLMinutes int   `json:"ttl_minutes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if body.TTLMinutes <= 0 {
+		body.TTLMinutes = 30
+	}
+
+	res, err := h.service.Reserve(r.Context(), id, body.OrderId, body.Quantity, time.Duration(body.TTLMinutes)*time.Minute)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusCreated, res)
+}
+
+// PRODUCTS USING ITEM
+func (h *InventoryHandler) HandleProductsUsing(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	products, err := h.service.GetProductsUsing(r.Context(), id)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, products)
+}
+
+// LIST SUPPLIER PRICES
+func (h *InventoryHandler) HandleGetSupplierPrices(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	prices, err := h.service.GetSupplierPrices(r.Context(), id)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, prices)
+}
+
+// SET SUPPLIER PRICE (create or replace)
+func (h *InventoryHandler) HandleSetSupplierPrice(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Expecting JSON: {"supplier": "acme", "unit_price": 1200, "lead_time_days": 2}
+	var body struct {
+		Supplier     string `json:"supplier"`
+		UnitPrice    int64  `json:"unit_price"`
+		LeadTimeDays int    `json:"lead_time_days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.service.SetSupplierPrice(r.Context(), id, SupplierPrice{
+		Supplier:     body.Supplier,
+		UnitPrice:    body.UnitPrice,
+		LeadTimeDays: body.LeadTimeDays,
+	})
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, saved)
+}
+
+// REMOVE SUPPLIER PRICE
+func (h *InventoryHandler) HandleRemoveSupplierPrice(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RemoveSupplierPrice(r.Context(), id, r.PathValue("supplier")); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, map[string]string{"status": "supplier price removed"})
+}
+
+// COMPARE SUPPLIERS
+// ?below_min=true restricts the comparison to items that need reordering
+func (h *InventoryHandler) HandleCompareSuppliers(w http.ResponseWriter, r *http.Request) {
+	belowThreshold, _ := strconv.ParseBool(r.URL.Query().Get("below_min"))
+
+	comparisons, err := h.service.CompareSuppliers(r.Context(), belowThreshold)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, comparisons)
+}
+
+// OPEN STOCKTAKE
+func (h *InventoryHandler) HandleOpenStocktake(w http.ResponseWriter, r *http.Request) {
+	// Expecting JSON (optional): {"note": "month end, dry store"}
+	var body struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID, _ := r.Context().Value(utils.UserIDKey).(int)
+
+	session, err := h.service.OpenStocktake(r.Context(), body.Note, userID)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusCreated, session)
+}
+
+// RECORD COUNT (repeat to overwrite)
+func (h *InventoryHandler) HandleRecordCount(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Expecting JSON: {"inventory_id": 3, "counted": 42}
+	var body struct {
+		InventoryId int   `json:"inventory_id"`
+		Counted     int64 `json:"counted"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RecordCount(r.Context(), id, body.InventoryId, body.Counted); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, map[string]string{"status": "count recorded"})
+}
+
+// CLOSE STOCKTAKE (applies counts to stock, returns the variance report)
+func (h *InventoryHandler) HandleCloseStocktake(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value(utils.UserIDKey).(int)
+
+	report, err := h.service.CloseStocktake(r.Context(), id, userID)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, report)
+}
+
+// STOCKTAKE REPORT (one session, per item)
+func (h *InventoryHandler) HandleStocktakeReport(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.GetStocktakeReport(r.Context(), id)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, report)
+}
+
+// SHRINKAGE REPORT (sessions closed in range, per session and per tag)
+// ?start_date=2024-01-01&end_date=2024-01-31
+func (h *InventoryHandler) HandleShrinkageReport(w http.ResponseWriter, r *http.Request) {
+	start, end := h.parseDateRange(r)
+
+	report, err := h.service.GetShrinkageReport(r.Context(), start, end)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, report)
+}
+
+// LIST MANAGED TAGS
+func (h *InventoryHandler) HandleListTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.service.ListTags(r.Context(), r.URL.Query().Get("kind"))
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, tags)
+}
+
+// CREATE MANAGED TAG
+func (h *InventoryHandler) HandleCreateTag(w http.ResponseWriter, r *http.Request) {
+	// Expecting JSON: {"kind": "tag", "name": "dairy"}
+	var body struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	tag, err := h.service.CreateTag(r.Context(), body.Kind, body.Name)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusCreated, tag)
+}
+
+// RENAME MANAGED TAG (cascades to items)
+func (h *InventoryHandler) HandleRenameTag(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	// Expecting JSON: {"name": "dairy-free"}
 	var body struct {
-		Delta int64 `json:"delta"`
+		Name string `json:"name"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.service.AdjustStock(r.Context(), id, body.Delta); err != nil {
+	tag, err := h.service.RenameTag(r.Context(), id, body.Name)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, tag)
+}
+
+// DELETE MANAGED TAG (removed from items too)
+func (h *InventoryHandler) HandleDeleteTag(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteTag(r.Context(), id); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, map[string]string{"status": "tag deleted"})
+}
+
+// PAR LEVEL DASHBOARD
+func (h *InventoryHandler) HandleParDashboard(w http.ResponseWriter, r *http.Request) {
+	dashboard, err := h.service.GetParDashboard(r.Context())
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, dashboard)
+}
+
+// CONSUMPTION FORECAST
+// ?days=30 sets the lookback window used to compute average daily usage
+func (h *InventoryHandler) HandleForecast(w http.ResponseWriter, r *http.Request) {
+	days, _ := strconv.Atoi(r.URL.Query().Get("days"))
+	if days <= 0 {
+		days = 30
+	}
+
+	forecasts, err := h.service.ForecastConsumption(r.Context(), days)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, forecasts)
+}
+
+// CONSUMPTION REPORT
+// ?start_date=2024-01-01&end_date=2024-01-31 (defaults to the last 30 days)
+func (h *InventoryHandler) HandleConsumptionReport(w http.ResponseWriter, r *http.Request) {
+	start, end := h.parseDateRange(r)
+
+	report, err := h.service.GetConsumptionReport(r.Context(), start, end)
+	if err != nil {
 		h.respondWithError(w, err)
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "stock updated"})
+	h.respondWithJSON(w, r, http.StatusOK, report)
 }
 
 // --- Helpers ---
 
-func (h *InventoryHandler) respondWithJSON(w http.ResponseWriter, code int, payload any) {
+// TURNOVER REPORT
+// ?start_date=2024-01-01&end_date=2024-01-31, slowest-moving items first
+func (h *InventoryHandler) HandleTurnoverReport(w http.ResponseWriter, r *http.Request) {
+	start, end := h.parseDateRange(r)
+
+	report, err := h.service.GetTurnoverReport(r.Context(), start, end)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, report)
+}
+
+func (h *InventoryHandler) parseDateRange(r *http.Request) (time.Time, time.Time) {
+	query := r.URL.Query()
+	now := time.Now()
+
+	// Default: Last 30 days
+	start := now.AddDate(0, 0, -30)
+	end := now
+
+	if s := query.Get("start_date"); s != "" {
+		if t, err := time.Parse("2006-01-02", s); err == nil {
+			start = t
+		}
+	}
+	if e := query.Get("end_date"); e != "" {
+		if t, err := time.Parse("2006-01-02", e); err == nil {
+			// End of day
+			end = t.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+		}
+	}
+	return start, end
+}
+
+func (h *InventoryHandler) parseListParams(r *http.Request) ListParams {
+	query := r.URL.Query()
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit <= 0 {
+		limit = 20
+	}
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+
+	var stockMin, stockMax *int64
+	if v, err := strconv.ParseInt(query.Get("stock_min"), 10, 64); err == nil {
+		stockMin = &v
+	}
+	if v, err := strconv.ParseInt(query.Get("stock_max"), 10, 64); err == nil {
+		stockMax = &v
+	}
+
+	belowThreshold, _ := strconv.ParseBool(query.Get("below_min"))
+	deleted, _ := strconv.ParseBool(query.Get("deleted"))
+
+	// ?tags=a,b matches items carrying both a and b. The legacy single
+	// ?tag=a is still accepted and folded into the same containment filter.
+	var tags []string
+	if v := strings.TrimSpace(query.Get("tag")); v != "" {
+		tags = append(tags, v)
+	}
+	if v := query.Get("tags"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tags = append(tags, t)
+			}
+		}
+	}
+
+	return ListParams{
+		Deleted:        deleted,
+		Tags:           tags,
+		Label:          query.Get("label"),
+		Query:          query.Get("q"), // ?q=something triggers search
+		StockMin:       stockMin,
+		StockMax:       stockMax,
+		BelowThreshold: belowThreshold,
+		Period:         utils.PeriodFromQuery(query),
+		SortBy:         query.Get("sort"),  // name, stock, slug, created_at, updated_at
+		SortOrder:      query.Get("order"), // asc, desc
+		Limit:          limit,
+		Page:           page,
+		After:          query.Get("after"), // X-Next-Cursor of the page before
+	}
+}
+
+// respondWithJSON leaves out the fields the caller may not see, such as
+// unit costs for staff without inventory:cost.
+func (h *InventoryHandler) respondWithJSON(w http.ResponseWriter, r *http.Request, code int, payload any) {
+	payload, err := utils.Redact(r.Context(), payload)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 	if payload != nil {
@@ -173,11 +895,23 @@ func (h *InventoryHandler) respondWithJSON(w http.ResponseWriter, code int, payl
 func (h *InventoryHandler) respondWithError(w http.ResponseWriter, err error) {
 	var statusCode int
 	switch {
-	case errors.Is(err, ErrNotFound):
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrStocktakeNotFound):
 		statusCode = http.StatusNotFound
 	case errors.Is(err, ErrInvalidInput):
 		statusCode = http.StatusBadRequest
-	case errors.Is(err, ErrDuplicateSlug):
+	case errors.Is(err, ErrDuplicateSlug), errors.Is(err, ErrDuplicateBarcode), errors.Is(err, ErrConflict):
+		statusCode = http.StatusConflict
+	case errors.Is(err, ErrInsufficientStock):
+		statusCode = http.StatusConflict
+	case errors.Is(err, ErrInvalidReason):
+		statusCode = http.StatusBadRequest
+	case errors.Is(err, ErrInUse):
+		statusCode = http.StatusConflict
+	case errors.Is(err, ErrStocktakeClosed):
+		statusCode = http.StatusConflict
+	case errors.Is(err, ErrTagNotFound):
+		statusCode = http.StatusNotFound
+	case errors.Is(err, ErrDuplicateTag):
 		statusCode = http.StatusConflict
 	default:
 		statusCode = http.StatusInternalServerError
@@ -1,12 +1,210 @@
 package inventory
 
+import "time"
+
 type Inventory struct {
-	Id     int
-	Slug   string
-	Name   string
-	Desc   string
-	Tag    string
-	Label  string
-	Stock  int64
-	Custom map[string]any
+	Id       int
+	Slug     string
+	Name     string
+	Desc     string
+	Tags     []string // Stored as a JSONB array
+	Label    string
+	Stock    int64
+	MinStock int64  // Reorder threshold (par level)
+	MaxStock int64  // Upper par level, 0 means no ceiling
+	UnitCost int64  `perm:"inventory:cost"` // Weighted average cost per unit, same minor units as product prices
+	Reserved int64  `audit:"-"`             // Held by open orders (read-only, computed from reservations)
+	Barcode  string // EAN/UPC or any scanner code, optional but unique
+	Custom   map[string]any
+	Created  int64 // Unix timestamps
+	Updated  int64
+	Deleted  int64 // Unix timestamp of the soft delete, 0 while active
+
+	// Revision goes up with every change, stock movements included. Updates
+	// send the one they read and fail with ErrConflict if it has moved on.
+	Revision int
+}
+
+// StockSnapshot is the recorded stock level of one item on one day.
+type StockSnapshot struct {
+	InventoryId int
+	Slug        string
+	TakenOn     time.Time // Date only, time component is zero
+	Stock       int64
+}
+
+// Summary holds cheap aggregates over the whole inventory.
+type Summary struct {
+	TotalSKUs  int            `json:"total_skus"`
+	TotalUnits int64          `json:"total_units"`
+	TotalValue int64          `json:"total_value" perm:"inventory:cost"` // Sum of stock * unit cost
+	ByTag      map[string]int `json:"by_tag"`                            // Per tag, untagged items under ""; multi-tagged items count once per tag
 }
+
+// Par level buckets, ordered from most to least urgent
+const (
+	ParCritical    = "critical"    // Out of stock or below half the minimum
+	ParLow         = "low"         // Below the minimum
+	ParOk          = "ok"          // Between min and max
+	ParOverstocked = "overstocked" // Above the maximum
+)
+
+// StockMovement is one entry in an item's stock ledger.
+type StockMovement struct {
+	Id          int
+	InventoryId int
+	Delta       int64
+	StockAfter  int64  // Stock level right after this movement
+	Reason      string // One of the Reason* constants
+	Note        string
+	UnitCost    int64 `perm:"inventory:cost"` // Cost per unit paid on receiving, 0 if not given
+	UserId      int   // Who made it, 0 if unknown
+	Created     int64 // Unix timestamp
+}
+
+// Reason codes for stock movements
+const (
+	ReasonReceived   = "received"   // Delivery from a supplier
+	ReasonWaste      = "waste"      // Spoiled, expired, spilled
+	ReasonDamaged    = "damaged"    // Broken in storage or transit
+	ReasonCorrection = "correction" // Fixing a data-entry mistake
+	ReasonStocktake  = "stocktake"  // Aligning to a physical count
+	ReasonReturn     = "return"     // Returned to supplier
+	ReasonTransfer   = "transfer"   // Moved to/from another location
+	ReasonSale       = "sale"       // Used by an order; only orders record it
+)
+
+// explainedLossReasons are outflows that are accounted for and therefore
+// don't count as shrinkage in the consumption report.
+var explainedLossReasons = map[string]struct{}{
+	ReasonWaste:    {},
+	ReasonDamaged:  {},
+	ReasonReturn:   {},
+	ReasonTransfer: {},
+}
+
+var validReasons = map[string]struct{}{
+	ReasonReceived:   {},
+	ReasonWaste:      {},
+	ReasonDamaged:    {},
+	ReasonCorrection: {},
+	ReasonStocktake:  {},
+	ReasonReturn:     {},
+	ReasonTransfer:   {},
+}
+
+// IsValidReason checks if a string is one of the defined reason codes.
+func IsValidReason(reason string) bool {
+	_, ok := validReasons[reason]
+	return ok
+}
+
+// SupplierPrice is what one supplier charges for an item and how long
+// they take to deliver.
+type SupplierPrice struct {
+	Id           int
+	InventoryId  int
+	Slug         string // Inventory slug, filled on reads
+	Supplier     string
+	UnitPrice    int64 `perm:"inventory:cost"`
+	LeadTimeDays int
+}
+
+// ProductRef is a lightweight view of a product that uses an inventory item.
+type ProductRef struct {
+	Id       int
+	Slug     string
+	Name     string
+	Avail    bool
+	Quantity int // Amount of the item the recipe consumes per unit sold
+}
+
+// Reservation holds stock for an order until it is released or expires.
+type Reservation struct {
+	Id          int
+	InventoryId int
+	OrderId     int
+	Quantity    int64
+	ExpiresAt   int64 // Unix timestamp
+	Created     int64 // Unix timestamp
+}
+
+// OrderUsage is how much of one inventory item an order's products use.
+type OrderUsage struct {
+	InventoryId int
+	Slug        string
+	Quantity    int64
+}
+
+// ManagedTag is a tag or label that is curated centrally instead of being
+// free text on each item. Renaming one cascades to every item using it.
+type ManagedTag struct {
+	Id    int
+	Kind  string // TagKindTag or TagKindLabel
+	Name  string
+	Items int // Number of items using it (read-only)
+}
+
+const (
+	TagKindTag   = "tag"
+	TagKindLabel = "label"
+)
+
+// StocktakeSession is one physical count of (part of) the inventory.
+type StocktakeSession struct {
+	Id      int    `json:"id"`
+	Status  string `json:"status"` // StocktakeOpen or StocktakeClosed
+	Note    string `json:"note"`
+	UserId  int    `json:"user_id"` // Who opened it, 0 if unknown
+	Created int64  `json:"created"` // Unix timestamp
+	Closed  int64  `json:"closed"`  // Unix timestamp, 0 while open
+}
+
+const (
+	StocktakeOpen   = "open"
+	StocktakeClosed = "closed"
+)
+
+// StocktakeCount is the counted quantity of one item in a session. Expected,
+// Variance and UnitCost are only filled in once the session is closed.
+//
+//	Variance = Counted - Expected (negative means units went missing)
+type StocktakeCount struct {
+	SessionId   int      `json:"session_id"`
+	InventoryId int      `json:"inventory_id"`
+	Slug        string   `json:"slug"`
+	Name        string   `json:"name"`
+	Tags        []string `json:"tags"`
+	Counted     int64    `json:"counted"`
+	Expected    int64    `json:"expected"`
+	Variance    int64    `json:"variance"`
+	UnitCost    int64    `json:"unit_cost" perm:"inventory:cost"`
+}
+
+// AvailabilityEvent is emitted when a product is automatically switched off
+// (86'd) because an ingredient ran out, or back on after a restock.
+type AvailabilityEvent struct {
+	ProductId   int    `json:"product_id"`
+	ProductSlug string `json:"product_slug"`
+	Avail       bool   `json:"avail"`
+	Cause       string `json:"cause"` // Inventory slug whose stock change triggered it
+	At          int64  `json:"at"`    // Unix timestamp
+}
+
+// StockAlert is raised when an adjustment takes an item below its reorder
+// threshold or out of stock.
+type StockAlert struct {
+	Kind        string `json:"kind"` // AlertLowStock or AlertOutOfStock
+	InventoryId int    `json:"inventory_id"`
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Stock       int64  `json:"stock"`
+	MinStock    int64  `json:"min_stock"`
+	Store       int    `json:"store"` // Only streamed to callers in this store
+	At          int64  `json:"at"`    // Unix timestamp
+}
+
+const (
+	AlertLowStock   = "low_stock"
+	AlertOutOfStock = "out_of_stock"
+)
@@ -6,38 +6,146 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
 )
 
 var (
-	ErrNotFound      = errors.New("inventory not found")
-	ErrInvalidInput  = errors.New("invalid input")
-	ErrDuplicateSlug = errors.New("slug already exists")
+	ErrNotFound          = errors.New("inventory not found")
+	ErrInvalidInput      = errors.New("invalid input")
+	ErrDuplicateSlug     = errors.New("slug already exists")
+	ErrDuplicateBarcode  = errors.New("barcode already in use")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrInvalidReason     = errors.New("invalid or missing adjustment reason")
+	ErrTagNotFound       = errors.New("tag not found")
+	ErrDuplicateTag      = errors.New("tag already exists")
+	ErrStocktakeNotFound = errors.New("stocktake session not found")
+	ErrStocktakeClosed   = errors.New("stocktake session is already closed")
+	ErrInUse             = errors.New("inventory is still used by product recipes")
+	ErrConflict          = errors.New("inventory was changed since it was read")
 )
 
 type InventoryRepository interface {
 	Create(ctx context.Context, inv *Inventory) error
+	Import(ctx context.Context, client database.SQLClient, items []*Inventory) ([]bool, error)
 	GetByID(ctx context.Context, id int) (*Inventory, error)
 	GetBySlug(ctx context.Context, slug string) (*Inventory, error)
+	GetBySlugs(ctx context.Context, slugs []string) ([]*Inventory, error)
+	GetByBarcode(ctx context.Context, barcode string) (*Inventory, error)
 	Update(ctx context.Context, inv *Inventory) error
-	Delete(ctx context.Context, id int) error
-	List(ctx context.Context, opts ListOptions) ([]*Inventory, error)
+	Delete(ctx context.Context, id int) error // Soft delete
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, id int) error // Hard delete, only from the trash
+	TrashedBefore(ctx context.Context, cutoff time.Time) ([]int, error)
+	List(ctx context.Context, opts ListOptions) ([]*Inventory, int, error)
+	GetSummary(ctx context.Context) (*Summary, error)
 	UpdateStock(ctx context.Context, id int, delta int64) error
-	Search(ctx context.Context, query string) ([]*Inventory, error)
+	AdjustStock(ctx context.Context, m *StockMovement) error
+	GetMovements(ctx context.Context, id int, limit int) ([]*StockMovement, error)
+	Search(ctx context.Context, query string, tags []string) ([]*Inventory, error)
+	BulkUpsert(ctx context.Context, items []*Inventory) ([]bool, error)
+
+	// Snapshots
+	SaveSnapshot(ctx context.Context, day time.Time) (int, error)
+	GetHistory(ctx context.Context, id int, start, end time.Time) ([]*StockSnapshot, error)
+	GetSnapshotsOn(ctx context.Context, day time.Time) ([]*StockSnapshot, error)
+
+	// Supplier prices
+	UpsertSupplierPrice(ctx context.Context, sp *SupplierPrice) error
+	DeleteSupplierPrice(ctx context.Context, inventoryId int, supplier string) error
+	GetSupplierPrices(ctx context.Context, inventoryId int) ([]*SupplierPrice, error)
+	ListSupplierPrices(ctx context.Context, belowThresholdOnly bool) ([]*SupplierPrice, error)
+
+	// Reservations
+	Reserve(ctx context.Context, res *Reservation) error
+	ReleaseForOrder(ctx context.Context, client database.SQLClient, orderId int) (int64, error)
+	ReleaseExpired(ctx context.Context) (int64, error)
+
+	// Sales
+	ConsumeForOrder(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) ([]string, error)
+	RestockForOrder(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) error
+	GetOrderUsage(ctx context.Context, items []string) ([]*OrderUsage, error)
+
+	// Stocktakes
+	CreateStocktake(ctx context.Context, session *StocktakeSession) error
+	GetStocktake(ctx context.Context, id int) (*StocktakeSession, error)
+	ListStocktakes(ctx context.Context, start, end time.Time) ([]*StocktakeSession, error)
+	RecordCount(ctx context.Context, sessionId, inventoryId int, counted int64) error
+	CloseStocktake(ctx context.Context, sessionId int, userId int) error
+	GetStocktakeCounts(ctx context.Context, sessionIds []int) ([]*StocktakeCount, error)
+
+	// Managed tags
+	CreateTag(ctx context.Context, tag *ManagedTag) error
+	GetTag(ctx context.Context, id int) (*ManagedTag, error)
+	ListTags(ctx context.Context, kind string) ([]*ManagedTag, error)
+	RenameTag(ctx context.Context, id int, newName string) error
+	DeleteTag(ctx context.Context, id int) error
+
+	// Cross-entity lookups
+	GetProductsUsing(ctx context.Context, slug string) ([]*ProductRef, error)
+	SyncProductAvailability(ctx context.Context, slugs []string, reenable bool) ([]*AvailabilityEvent, error)
+
+	// Analytics
+	GetParLevels(ctx context.Context) (map[string][]*Inventory, error)
+	GetConsumption(ctx context.Context, start, end time.Time) (map[string]int64, error)
+	GetOutflowByReason(ctx context.Context, start, end time.Time) (map[string]map[string]int64, error)
+	GetAverageStock(ctx context.Context, start, end time.Time) (map[string]float64, error)
 }
 
 type ListOptions struct {
-	Tag    string
-	Label  string
-	Limit  int
-	Offset int
+	Deleted        bool     // list soft-deleted items (the trash) instead of active ones
+	Tags           []string // items must carry all of these (JSONB containment)
+	Label          string
+	StockMin       *int64 // pointer so 0 is a valid bound
+	StockMax       *int64
+	BelowThreshold bool // only items with stock < min_stock
+	Limit          int
+	Offset         int
+	After          string // Cursor from the page before, in place of Offset
+	Period         database.Period
+	SortBy         string // name, stock, slug, id, created_at, updated_at
+	SortOrder      string // asc, desc
+	Total          bool   // Also count every match, for List to return
+}
+
+// inventoryColumns is the SELECT list matched by scanInventory.
+// barcode is nullable (unique only when set), so it is coalesced to "".
+// reserved is computed from live (unexpired) reservations.
+// Soft-deleted items are excluded everywhere except the trash listing.
+func (r *inventoryRepository) inventoryColumns() string {
+	return `id, slug, name, "desc", label, tags, stock, min_stock, max_stock, unit_cost,
+	(SELECT COALESCE(SUM(res.quantity), 0) FROM inventory_reservations res
+	 WHERE res.inventory_id = inventory.id AND res.expires_at > NOW()),
+	COALESCE(barcode, ''), custom,
+	` + r.dialect.Epoch("created_at") + `, ` + r.dialect.Epoch("updated_at") + `,
+	COALESCE(` + r.dialect.Epoch("deleted_at") + `, 0), revision`
+}
+
+// stocktakeColumns is the SELECT list matched by scanStocktake.
+const stocktakeColumns = `id, status, note, COALESCE(user_id, 0), created_at, closed_at`
+
+// tagColumns is the SELECT list for managed tags, including a usage count.
+func (r *inventoryRepository) tagColumns() string {
+	return `t.id, t.kind, t.name, (
+	SELECT COUNT(*) FROM inventory i
+	WHERE CASE WHEN t.kind = 'label' THEN i.label = t.name ELSE ` + r.dialect.JSONHasElement("i.tags", "t.name") + ` END
+	  AND i.store_id = t.store_id AND i.deleted_at IS NULL
+)`
 }
 
 type inventoryRepository struct {
-	db *sql.DB
+	db      *database.DB
+	dialect database.Dialect
+
+	// allowNegativeStock disables the non-negative guard on stock updates.
+	// Some shops prefer to sell first and reconcile counts later.
+	allowNegativeStock bool
 }
 
-func NewInventoryRepository(db *sql.DB) InventoryRepository {
-	return &inventoryRepository{db: db}
+func NewInventoryRepository(db *sql.DB, allowNegativeStock bool) InventoryRepository {
+	return &inventoryRepository{db: database.Observe(db, "inventory"), dialect: database.DialectOf(db), allowNegativeStock: allowNegativeStock}
 }
 
 // CREATE
@@ -46,94 +154,146 @@ func (r *inventoryRepository) Create(ctx context.Context, inv *Inventory) error
 		return ErrInvalidInput
 	}
 
-	customJSON, err := json.Marshal(inv.Custom)
+	customJSON, err := database.MarshalCustom("inventory", inv.Custom)
 	if err != nil {
 		return fmt.Errorf("failed to marshal custom data: %w", err)
 	}
 
+	tagsJSON, err := marshalTags(inv.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
 	query := `
-		INSERT INTO inventory (slug, name, desc, tag, label, stock, custom)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id
+		INSERT INTO inventory (slug, name, "desc", label, tags, stock, min_stock, max_stock, unit_cost, barcode, custom, store_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, $12, $13, $13)
 	`
+	now := time.Now()
+	args := []any{
+		inv.Slug, inv.Name, inv.Desc, inv.Label, tagsJSON,
+		inv.Stock, inv.MinStock, inv.MaxStock, inv.UnitCost, inv.Barcode, customJSON, database.StoreOf(ctx), now,
+	}
 
-	err = r.db.QueryRowContext(
-		ctx, query,
-		inv.Slug, inv.Name, inv.Desc, inv.Tag, inv.Label, inv.Stock, customJSON,
-	).Scan(&inv.Id)
+	err = r.dialect.InsertReturning(ctx, r.db, "inventory", query, "id, revision", args, &inv.Id, &inv.Revision)
 
 	if err != nil {
-		if isDuplicateKeyError(err) {
-			return ErrDuplicateSlug
+		if database.IsUniqueViolation(err) {
+			return duplicateError(err)
 		}
 		return fmt.Errorf("failed to create inventory: %w", err)
 	}
 
+	inv.Created, inv.Updated = now.Unix(), now.Unix()
 	return nil
 }
 
 // READ BY ID
-func (r *inventoryRepository) GetByID(ctx context.Context, id int) (*Inventory, error) {
-	query := `
-		SELECT id, slug, name, desc, tag, label, stock, custom
-		FROM inventory
-		WHERE id = $1
-	`
-
-	inv := &Inventory{}
-	var customJSON []byte
-
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&inv.Id, &inv.Slug, &inv.Name, &inv.Desc,
-		&inv.Tag, &inv.Label, &inv.Stock, &customJSON,
-	)
+// Import inserts items read back from a backup as they were, ids, stock,
+// revisions and timestamps included, in one bulk insert; it records no
+// stock movements. It reports per item whether it went in; false means one
+// with its id, slug or barcode is already there, and it was left out.
+func (r *inventoryRepository) Import(ctx context.Context, client database.SQLClient, items []*Inventory) ([]bool, error) {
+	client = r.db.On(client)
 
-	if err == sql.ErrNoRows {
-		return nil, ErrNotFound
+	ids := make([]int, len(items))
+	slugs := make([]string, len(items))
+	var barcodes []string
+	for i, inv := range items {
+		ids[i], slugs[i] = inv.Id, inv.Slug
+		if inv.Barcode != "" {
+			barcodes = append(barcodes, inv.Barcode)
+		}
 	}
+	// Ids are shared by every store, slugs and barcodes only clash within one
+	query := `SELECT id, CASE WHEN store_id = $4 THEN slug ELSE '' END, CASE WHEN store_id = $4 THEN COALESCE(barcode, '') ELSE '' END
+		FROM inventory WHERE ` + r.dialect.AnyOf("id", "$1") +
+		` OR ((` + r.dialect.AnyOf("slug", "$2") + ` OR ` + r.dialect.AnyOf("barcode", "$3") + `) AND store_id = $4)`
+	store := database.StoreOf(ctx)
+	taken, err := database.Select(ctx, client, scanKeys, query, r.dialect.Array(ids), r.dialect.Array(slugs), r.dialect.Array(barcodes), store)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get inventory: %w", err)
+		return nil, fmt.Errorf("failed to check inventory: %w", err)
+	}
+	takenIds, takenKeys := map[int]bool{}, map[string]bool{}
+	for _, inv := range taken {
+		takenIds[inv.Id], takenKeys["slug:"+inv.Slug], takenKeys["barcode:"+inv.Barcode] = true, true, true
 	}
 
-	if len(customJSON) > 0 {
-		if err := json.Unmarshal(customJSON, &inv.Custom); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal custom data: %w", err)
+	imported := make([]bool, len(items))
+	var rows [][]any
+	for i, inv := range items {
+		if takenIds[inv.Id] || takenKeys["slug:"+inv.Slug] || (inv.Barcode != "" && takenKeys["barcode:"+inv.Barcode]) {
+			continue
+		}
+
+		customJSON, err := database.MarshalCustom("inventory", inv.Custom)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal custom data: %w", err)
+		}
+
+		tagsJSON, err := marshalTags(inv.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal tags: %w", err)
+		}
+
+		var barcode any
+		if inv.Barcode != "" {
+			barcode = inv.Barcode
 		}
+		rows = append(rows, []any{
+			inv.Id, inv.Slug, inv.Name, inv.Desc, inv.Label, string(tagsJSON),
+			inv.Stock, inv.MinStock, inv.MaxStock, inv.UnitCost, barcode, string(customJSON), max(inv.Revision, 1), store,
+			database.FromUnix(inv.Created), database.FromUnix(inv.Updated), database.FromUnix(inv.Deleted),
+		})
+		imported[i] = true
 	}
 
-	return inv, nil
+	columns := []string{"id", "slug", "name", `"desc"`, "label", "tags", "stock", "min_stock", "max_stock", "unit_cost", "barcode", "custom", "revision", "store_id", "created_at", "updated_at", "deleted_at"}
+	if err := r.dialect.BulkInsert(ctx, client, "inventory", columns, rows); err != nil {
+		return nil, fmt.Errorf("failed to import inventory: %w", err)
+	}
+	if err := r.dialect.ResetSequence(ctx, client, "inventory"); err != nil {
+		return nil, fmt.Errorf("failed to reset inventory ids: %w", err)
+	}
+	return imported, nil
+}
+
+// scanKeys reads just the keys of an item, for clash checks.
+func scanKeys(s database.Scanner) (*Inventory, error) {
+	var inv Inventory
+	err := s.Scan(&inv.Id, &inv.Slug, &inv.Barcode)
+	return &inv, err
+}
+
+func (r *inventoryRepository) GetByID(ctx context.Context, id int) (*Inventory, error) {
+	return r.getOne(ctx, "id = $1 AND deleted_at IS NULL", id)
 }
 
 // READ BY SLUG
 func (r *inventoryRepository) GetBySlug(ctx context.Context, slug string) (*Inventory, error) {
+	return r.getOne(ctx, "slug = $1 AND deleted_at IS NULL", slug)
+}
+
+// READ BY SLUGS
+// Fetches many items in one round trip. Unknown or deleted slugs are simply
+// absent from the result, which is ordered by slug.
+func (r *inventoryRepository) GetBySlugs(ctx context.Context, slugs []string) ([]*Inventory, error) {
 	query := `
-		SELECT id, slug, name, desc, tag, label, stock, custom
+		SELECT ` + r.inventoryColumns() + `
 		FROM inventory
-		WHERE slug = $1
+		WHERE ` + r.dialect.AnyOf("slug", "$1") + ` AND store_id = $2 AND deleted_at IS NULL
+		ORDER BY slug
 	`
 
-	inv := &Inventory{}
-	var customJSON []byte
-
-	err := r.db.QueryRowContext(ctx, query, slug).Scan(
-		&inv.Id, &inv.Slug, &inv.Name, &inv.Desc,
-		&inv.Tag, &inv.Label, &inv.Stock, &customJSON,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, ErrNotFound
-	}
+	items, err := database.Select(ctx, r.db, r.scanInventory, query, r.dialect.Array(slugs), database.StoreOf(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get inventory: %w", err)
-	}
-
-	if len(customJSON) > 0 {
-		if err := json.Unmarshal(customJSON, &inv.Custom); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal custom data: %w", err)
-		}
+		return nil, fmt.Errorf("failed to get inventory by slugs: %w", err)
 	}
+	return items, nil
+}
 
-	return inv, nil
+// READ BY BARCODE
+func (r *inventoryRepository) GetByBarcode(ctx context.Context, barcode string) (*Inventory, error) {
+	return r.getOne(ctx, "barcode = $1 AND deleted_at IS NULL", barcode)
 }
 
 // UPDATE
@@ -142,199 +302,1653 @@ func (r *inventoryRepository) Update(ctx context.Context, inv *Inventory) error
 		return ErrInvalidInput
 	}
 
-	customJSON, err := json.Marshal(inv.Custom)
+	customJSON, err := database.MarshalCustom("inventory", inv.Custom)
 	if err != nil {
 		return fmt.Errorf("failed to marshal custom data: %w", err)
 	}
 
+	tagsJSON, err := marshalTags(inv.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
 	query := `
 		UPDATE inventory
-		SET slug = $1, name = $2, desc = $3, tag = $4, label = $5, stock = $6, custom = $7
-		WHERE id = $8
+		SET slug = $1, name = $2, "desc" = $3, label = $4, tags = $5, stock = $6,
+		    min_stock = $7, max_stock = $8, unit_cost = $9, barcode = NULLIF($10, ''), custom = $11,
+		    revision = revision + 1, updated_at = NOW()
+		WHERE id = $12 AND revision = $13 AND store_id = $14 AND deleted_at IS NULL
 	`
 
-	result, err := r.db.ExecContext(
-		ctx, query,
-		inv.Slug, inv.Name, inv.Desc, inv.Tag, inv.Label, inv.Stock, customJSON, inv.Id,
+	ok, err := database.ExecAffected(
+		ctx, r.db, query,
+		inv.Slug, inv.Name, inv.Desc, inv.Label, tagsJSON,
+		inv.Stock, inv.MinStock, inv.MaxStock, inv.UnitCost, inv.Barcode, customJSON, inv.Id, inv.Revision, database.StoreOf(ctx),
 	)
 
 	if err != nil {
-		if isDuplicateKeyError(err) {
-			return ErrDuplicateSlug
+		if database.IsUniqueViolation(err) {
+			return duplicateError(err)
 		}
 		return fmt.Errorf("failed to update inventory: %w", err)
 	}
+	if !ok {
+		if _, err := r.GetByID(ctx, inv.Id); err != nil {
+			return err
+		}
+		return ErrConflict
+	}
+	inv.Revision++
+
+	return nil
+}
 
-	rows, err := result.RowsAffected()
+// DELETE (soft)
+// Recipes and movement history keep pointing at the item; it just disappears
+// from listings and lookups until restored or purged.
+func (r *inventoryRepository) Delete(ctx context.Context, id int) error {
+	ok, err := database.SoftDelete(ctx, r.db, "inventory", id, "revision = revision + 1")
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to delete inventory: %w", err)
 	}
-
-	if rows == 0 {
+	if !ok {
 		return ErrNotFound
 	}
-
 	return nil
 }
 
-// DELETE
-func (r *inventoryRepository) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM inventory WHERE id = $1`
-
-	result, err := r.db.ExecContext(ctx, query, id)
+// RESTORE
+func (r *inventoryRepository) Restore(ctx context.Context, id int) error {
+	ok, err := database.Restore(ctx, r.db, "inventory", id, "revision = revision + 1")
 	if err != nil {
-		return fmt.Errorf("failed to delete inventory: %w", err)
+		return fmt.Errorf("failed to restore inventory: %w", err)
+	}
+	if !ok {
+		return ErrNotFound
 	}
+	return nil
+}
 
-	rows, err := result.RowsAffected()
+// TRASHED BEFORE
+// Ids of items deleted before the cutoff, for the purge job.
+func (r *inventoryRepository) TrashedBefore(ctx context.Context, cutoff time.Time) ([]int, error) {
+	ids, err := database.TrashedBefore(ctx, r.db, "inventory", cutoff)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return nil, fmt.Errorf("failed to list trashed inventory: %w", err)
 	}
+	return ids, nil
+}
 
-	if rows == 0 {
+// PURGE
+// Permanently removes a soft-deleted item together with its history.
+// Refused while any product recipe still uses the item's slug, including
+// products in the trash, which could otherwise be restored without it.
+func (r *inventoryRepository) Purge(ctx context.Context, id int) error {
+	var slug string
+	store := database.StoreOf(ctx)
+	err := r.db.QueryRowContext(ctx, `SELECT slug FROM inventory WHERE id = $1 AND store_id = $2 AND deleted_at IS NOT NULL`, id, store).Scan(&slug)
+	if err == sql.ErrNoRows {
 		return ErrNotFound
 	}
+	if err != nil {
+		return fmt.Errorf("failed to get inventory: %w", err)
+	}
+
+	query := `
+		DELETE FROM inventory
+		WHERE id = $1 AND store_id = $3 AND deleted_at IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM products WHERE store_id = $3 AND ` + r.dialect.JSONHasKey("recipe", "$2") + `)
+	`
+
+	ok, err := database.ExecAffected(ctx, r.db, query, id, slug, store)
+	if err != nil {
+		return fmt.Errorf("failed to purge inventory: %w", err)
+	}
+	if !ok {
+		return ErrInUse
+	}
 
 	return nil
 }
 
 // READ ALL
-func (r *inventoryRepository) List(ctx context.Context, opts ListOptions) ([]*Inventory, error) {
-	query := `
-		SELECT id, slug, name, desc, tag, label, stock, custom
-		FROM inventory
-		WHERE 1=1
-	`
-	args := []any{}
-	argPos := 1
-
-	if opts.Tag != "" {
-		query += fmt.Sprintf(" AND tag = $%d", argPos)
-		args = append(args, opts.Tag)
-		argPos++
+// One page of the items matching opts, and with opts.Total how many match
+// in all.
+func (r *inventoryRepository) List(ctx context.Context, opts ListOptions) ([]*Inventory, int, error) {
+	f := r.buildListFilter(ctx, opts)
+	k := inventoryKeyset(opts.SortBy, opts.SortOrder)
+	cursor := f.Outer()
+	if err := k.After(r.dialect, &cursor, opts.After); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidInput, err)
 	}
 
-	if opts.Label != "" {
-		query += fmt.Sprintf(" AND label = $%d", argPos)
-		args = append(args, opts.Label)
-		argPos++
+	items, total, err := database.SelectListing(ctx, r.db, r.scanInventory, database.Listing{
+		Columns: r.inventoryColumns(), Table: "inventory", Where: "1=1", Filter: f, Cursor: cursor,
+		OrderBy: k.OrderBy(r.dialect), Limit: opts.Limit, Offset: opts.Offset, Total: opts.Total,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list inventory: %w", err)
 	}
+	return items, total, nil
+}
 
-	query += " ORDER BY id"
+// inventoryKeyset is the order List sorts items in.
+func inventoryKeyset(sortBy, sortOrder string) database.Keyset {
+	return database.Keyset{
+		Column:    database.SortColumn(sortBy, "id", "name", "stock", "slug", "created_at", "updated_at"),
+		Direction: database.SortDirection(sortOrder, "ASC"),
+	}
+}
 
-	if opts.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argPos)
-		args = append(args, opts.Limit)
-		argPos++
+// nextCursor is the cursor for the page after one ending with inv, listed
+// in the given order.
+func nextCursor(inv *Inventory, sortBy, sortOrder string) string {
+	k := inventoryKeyset(sortBy, sortOrder)
+	keys := map[string]any{
+		"id": inv.Id, "name": inv.Name, "stock": inv.Stock, "slug": inv.Slug, "created_at": inv.Created, "updated_at": inv.Updated,
 	}
+	return k.Cursor(keys[k.Column], inv.Id)
+}
 
-	if opts.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argPos)
-		args = append(args, opts.Offset)
+// SUMMARY
+func (r *inventoryRepository) GetSummary(ctx context.Context) (*Summary, error) {
+	summary := &Summary{ByTag: make(map[string]int)}
+
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(stock), 0), COALESCE(SUM(stock * unit_cost), 0)
+		FROM inventory
+		WHERE store_id = $1 AND deleted_at IS NULL
+	`
+	store := database.StoreOf(ctx)
+	err := r.db.QueryRowContext(ctx, query, store).Scan(&summary.TotalSKUs, &summary.TotalUnits, &summary.TotalValue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inventory totals: %w", err)
 	}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	tagQuery := `
+		SELECT COALESCE(t.value, ''), COUNT(*)
+		FROM inventory i
+		LEFT JOIN ` + r.dialect.JSONElements("i.tags", "t") + ` ON TRUE
+		WHERE i.store_id = $1 AND i.deleted_at IS NULL
+		GROUP BY 1
+	`
+	rows, err := r.db.QueryContext(ctx, tagQuery, store)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list inventory: %w", err)
+		return nil, fmt.Errorf("failed to count inventory by tag: %w", err)
 	}
 	defer rows.Close()
 
-	var items []*Inventory
 	for rows.Next() {
-		inv := &Inventory{}
-		var customJSON []byte
-
-		err := rows.Scan(
-			&inv.Id, &inv.Slug, &inv.Name, &inv.Desc,
-			&inv.Tag, &inv.Label, &inv.Stock, &customJSON,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan inventory: %w", err)
+		var tag string
+		var count int
+		if err := rows.Scan(&tag, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag count: %w", err)
 		}
-
-		if len(customJSON) > 0 {
-			if err := json.Unmarshal(customJSON, &inv.Custom); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal custom data: %w", err)
-			}
-		}
-
-		items = append(items, inv)
+		summary.ByTag[tag] = count
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	return items, nil
+	return summary, nil
 }
 
 // UPDATE STOCK
 func (r *inventoryRepository) UpdateStock(ctx context.Context, id int, delta int64) error {
-	query := `
-		UPDATE inventory
-		SET stock = stock + $1
-		WHERE id = $2
-		RETURNING stock
-	`
-
-	var newStock int64
-	err := r.db.QueryRowContext(ctx, query, delta, id).Scan(&newStock)
+	_, err := r.updateStock(ctx, r.db, id, delta)
+	return err
+}
 
-	if err == sql.ErrNoRows {
-		return ErrNotFound
+// ADJUST STOCK (with movement record)
+// Applies the delta and writes the movement row in one transaction so the
+// ledger can never drift from the actual stock level.
+func (r *inventoryRepository) AdjustStock(ctx context.Context, m *StockMovement) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
+
+	newStock, err := r.updateStock(ctx, tx, m.InventoryId, m.Delta)
 	if err != nil {
-		return fmt.Errorf("failed to update stock: %w", err)
+		return err
+	}
+	m.StockAfter = newStock
+
+	// Receiving at a known cost re-weights the unit cost (weighted average)
+	if m.Reason == ReasonReceived && m.UnitCost > 0 && m.Delta > 0 {
+		prior := r.dialect.Greatest("stock - $2", "0")
+		costQuery := `
+			UPDATE inventory
+			SET unit_cost = (` + prior + ` * unit_cost + $2 * $1) / (` + prior + ` + $2)
+			WHERE id = $3
+		`
+		if _, err := tx.ExecContext(ctx, costQuery, m.UnitCost, m.Delta, m.InventoryId); err != nil {
+			return fmt.Errorf("failed to update unit cost: %w", err)
+		}
+	}
+
+	if err := r.insertMovement(ctx, tx, m); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
+// MOVEMENTS
+func (r *inventoryRepository) GetMovements(ctx context.Context, id int, limit int) ([]*StockMovement, error) {
+	query := `
+		SELECT id, inventory_id, delta, stock_after, reason, note, unit_cost, COALESCE(user_id, 0), created_at
+		FROM inventory_movements
+		WHERE inventory_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`
+
+	movements, err := database.Select(ctx, r.db, scanMovement, query, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock movements: %w", err)
+	}
+	return movements, nil
+}
+
 // SEARCH
-func (r *inventoryRepository) Search(ctx context.Context, query string) ([]*Inventory, error) {
+// Matches name, description or any tag; tags, when given, must all be present.
+func (r *inventoryRepository) Search(ctx context.Context, query string, tags []string) ([]*Inventory, error) {
+	d := r.dialect
 	searchQuery := `
-		SELECT id, slug, name, desc, tag, label, stock, custom
+		SELECT ` + r.inventoryColumns() + `
 		FROM inventory
-		WHERE name ILIKE $1 OR desc ILIKE $1 OR tag ILIKE $1
+		WHERE (` + d.ILike("name", "$1") + ` OR ` + d.ILike(`"desc"`, "$1") + `
+		       OR EXISTS (SELECT 1 FROM ` + d.JSONElements("tags", "t") + ` WHERE ` + d.ILike("t.value", "$1") + `))
+		  AND ` + d.JSONContains("tags", "$2") + ` AND store_id = $3 AND deleted_at IS NULL
 		ORDER BY name
 	`
 
+	tagsJSON, err := marshalTags(tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
 	searchPattern := "%" + query + "%"
-	rows, err := r.db.QueryContext(ctx, searchQuery, searchPattern)
+	items, err := database.Select(ctx, r.db, r.scanInventory, searchQuery, searchPattern, tagsJSON, database.StoreOf(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to search inventory: %w", err)
 	}
-	defer rows.Close()
+	return items, nil
+}
 
-	var items []*Inventory
-	for rows.Next() {
-		inv := &Inventory{}
-		var customJSON []byte
+// BULK UPSERT
+// Inserts or updates items by slug inside a single transaction, so an import
+// either lands completely or not at all. The rows are bulk-loaded into a
+// staging table and merged with one statement. The returned slice reports,
+// per item, whether the row was newly created (true) or an existing row was
+// updated (false).
+func (r *inventoryRepository) BulkUpsert(ctx context.Context, items []*Inventory) ([]bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-		err := rows.Scan(
-			&inv.Id, &inv.Slug, &inv.Name, &inv.Desc,
-			&inv.Tag, &inv.Label, &inv.Stock, &customJSON,
-		)
+	columns := []string{"slug", "name", "tags", "stock", "min_stock", "max_stock", "store_id"}
+	if err := r.dialect.StageTable(ctx, tx, "inventory_import", "inventory", strings.Join(columns, ", ")); err != nil {
+		return nil, fmt.Errorf("failed to stage import: %w", err)
+	}
+
+	store := database.StoreOf(ctx)
+	rows := make([][]any, len(items))
+	for i, inv := range items {
+		tagsJSON, err := marshalTags(inv.Tags)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan inventory: %w", err)
+			return nil, fmt.Errorf("failed to marshal tags: %w", err)
 		}
+		rows[i] = []any{inv.Slug, inv.Name, string(tagsJSON), inv.Stock, inv.MinStock, inv.MaxStock, store}
+	}
+	if err := r.dialect.BulkInsert(ctx, tx, "inventory_import", columns, rows); err != nil {
+		return nil, fmt.Errorf("failed to load import: %w", err)
+	}
 
-		if len(customJSON) > 0 {
-			if err := json.Unmarshal(customJSON, &inv.Custom); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal custom data: %w", err)
+	// WHERE TRUE keeps SQLite from reading ON CONFLICT as part of the SELECT
+	merge := `
+		INSERT INTO inventory (slug, name, "desc", label, tags, stock, min_stock, max_stock, store_id, created_at, updated_at)
+		SELECT slug, name, '', '', tags, stock, min_stock, max_stock, store_id, NOW(), NOW() FROM inventory_import WHERE TRUE
+	` + r.dialect.OnConflict("slug, store_id",
+		"name = EXCLUDED.name", "tags = EXCLUDED.tags", "stock = EXCLUDED.stock",
+		"min_stock = EXCLUDED.min_stock", "max_stock = EXCLUDED.max_stock",
+		"deleted_at = NULL", // Re-importing a deleted item restores it
+		"revision = inventory.revision + 1", "updated_at = NOW()",
+	)
+
+	// xmax = 0 only holds for freshly inserted tuples. Elsewhere the slugs
+	// are looked up first; SQLite's transaction holds the write lock, and
+	// MySQL locks the gaps, so nothing can insert them in between.
+	fresh := make(map[string]bool, len(items))
+	if r.dialect == database.Postgres {
+		rows, err := tx.QueryContext(ctx, merge+" RETURNING slug, (xmax = 0)")
+		if err != nil {
+			return nil, fmt.Errorf("failed to upsert inventory: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var slug string
+			var created bool
+			if err := rows.Scan(&slug, &created); err != nil {
+				return nil, fmt.Errorf("failed to upsert inventory: %w", err)
 			}
+			fresh[slug] = created
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to upsert inventory: %w", err)
+		}
+	} else {
+		existing, err := database.Select(ctx, tx, database.Value[string],
+			`SELECT i.slug FROM inventory i JOIN inventory_import s ON s.slug = i.slug AND s.store_id = i.store_id`+r.dialect.ForUpdate())
+		if err != nil {
+			return nil, fmt.Errorf("failed to check inventory: %w", err)
+		}
+		for _, inv := range items {
+			fresh[inv.Slug] = true
 		}
+		for _, slug := range existing {
+			fresh[slug] = false
+		}
+		if _, err := tx.ExecContext(ctx, merge); err != nil {
+			return nil, fmt.Errorf("failed to upsert inventory: %w", err)
+		}
+	}
 
-		items = append(items, inv)
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+	created := make([]bool, len(items))
+	for i, inv := range items {
+		created[i] = fresh[inv.Slug]
 	}
+	return created, nil
+}
 
-	return items, nil
+// SAVE SNAPSHOT
+// Records the current stock of every item for the given day. Running it
+// twice on the same day overwrites that day's row, so the job is idempotent.
+// MySQL counts an overwritten row whose stock changed twice.
+func (r *inventoryRepository) SaveSnapshot(ctx context.Context, day time.Time) (int, error) {
+	query := `
+		INSERT INTO inventory_snapshots (inventory_id, taken_on, stock)
+		SELECT id, $1, stock FROM inventory WHERE store_id = $2 AND deleted_at IS NULL
+	` + r.dialect.OnConflict("inventory_id, taken_on", "stock = EXCLUDED.stock")
+
+	n, err := database.ExecCount(ctx, r.db, query, day, database.StoreOf(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return n, nil
+}
+
+// HISTORY
+func (r *inventoryRepository) GetHistory(ctx context.Context, id int, start, end time.Time) ([]*StockSnapshot, error) {
+	query := `
+		SELECT s.inventory_id, i.slug, s.taken_on, s.stock
+		FROM inventory_snapshots s
+		JOIN inventory i ON i.id = s.inventory_id
+		WHERE s.inventory_id = $1 AND s.taken_on >= $2 AND s.taken_on <= $3
+		ORDER BY s.taken_on
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, id, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock history: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanSnapshots(rows)
+}
+
+// SNAPSHOTS ON DAY
+func (r *inventoryRepository) GetSnapshotsOn(ctx context.Context, day time.Time) ([]*StockSnapshot, error) {
+	query := `
+		SELECT s.inventory_id, i.slug, s.taken_on, s.stock
+		FROM inventory_snapshots s
+		JOIN inventory i ON i.id = s.inventory_id
+		WHERE s.taken_on = $1 AND i.store_id = $2
+		ORDER BY i.slug
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, day, database.StoreOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanSnapshots(rows)
+}
+
+// UPSERT SUPPLIER PRICE
+// One row per (item, supplier); posting the same supplier again replaces the quote.
+func (r *inventoryRepository) UpsertSupplierPrice(ctx context.Context, sp *SupplierPrice) error {
+	query := `
+		INSERT INTO inventory_supplier_prices (inventory_id, supplier, unit_price, lead_time_days)
+		VALUES ($1, $2, $3, $4)
+	` + r.dialect.OnConflict("inventory_id, supplier",
+		"unit_price = EXCLUDED.unit_price", "lead_time_days = EXCLUDED.lead_time_days")
+	args := []any{sp.InventoryId, sp.Supplier, sp.UnitPrice, sp.LeadTimeDays}
+
+	err := r.dialect.InsertReturning(ctx, r.db, "inventory_supplier_prices", query, "id", args, &sp.Id)
+	if err != nil {
+		return fmt.Errorf("failed to save supplier price: %w", err)
+	}
+
+	return nil
+}
+
+// DELETE SUPPLIER PRICE
+func (r *inventoryRepository) DeleteSupplierPrice(ctx context.Context, inventoryId int, supplier string) error {
+	query := `
+		DELETE FROM inventory_supplier_prices
+		WHERE inventory_id = $1 AND supplier = $2
+		  AND inventory_id IN (SELECT id FROM inventory WHERE store_id = $3)
+	`
+
+	ok, err := database.ExecAffected(ctx, r.db, query, inventoryId, supplier, database.StoreOf(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete supplier price: %w", err)
+	}
+	if !ok {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// SUPPLIER PRICES FOR ONE ITEM
+func (r *inventoryRepository) GetSupplierPrices(ctx context.Context, inventoryId int) ([]*SupplierPrice, error) {
+	query := `
+		SELECT sp.id, sp.inventory_id, i.slug, sp.supplier, sp.unit_price, sp.lead_time_days
+		FROM inventory_supplier_prices sp
+		JOIN inventory i ON i.id = sp.inventory_id
+		WHERE sp.inventory_id = $1
+		ORDER BY sp.unit_price, sp.lead_time_days
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, inventoryId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplier prices: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanSupplierPrices(rows)
+}
+
+// SUPPLIER PRICES FOR ALL ITEMS
+func (r *inventoryRepository) ListSupplierPrices(ctx context.Context, belowThresholdOnly bool) ([]*SupplierPrice, error) {
+	query := `
+		SELECT sp.id, sp.inventory_id, i.slug, sp.supplier, sp.unit_price, sp.lead_time_days
+		FROM inventory_supplier_prices sp
+		JOIN inventory i ON i.id = sp.inventory_id
+		WHERE i.store_id = $1
+	`
+	if belowThresholdOnly {
+		query += " AND i.stock < i.min_stock"
+	}
+	query += " ORDER BY i.slug, sp.unit_price, sp.lead_time_days"
+
+	rows, err := r.db.QueryContext(ctx, query, database.StoreOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list supplier prices: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanSupplierPrices(rows)
+}
+
+// RESERVE
+// Holds stock for an order. The insert only happens if enough unreserved
+// stock is left, checked in the same statement to stay race-free.
+func (r *inventoryRepository) Reserve(ctx context.Context, res *Reservation) error {
+	query := `
+		INSERT INTO inventory_reservations (inventory_id, order_id, quantity, expires_at)
+		SELECT i.id, $2, $3, $4
+		FROM inventory i
+		WHERE i.id = $1 AND i.store_id = $5 AND i.deleted_at IS NULL AND i.stock - (
+			SELECT COALESCE(SUM(quantity), 0) FROM inventory_reservations
+			WHERE inventory_id = i.id AND expires_at > NOW()
+		) >= $3
+	`
+	args := []any{res.InventoryId, res.OrderId, res.Quantity, time.Unix(res.ExpiresAt, 0), database.StoreOf(ctx)}
+
+	var createdAt time.Time
+	err := r.dialect.InsertReturning(ctx, r.db, "inventory_reservations", query, "id, created_at", args, &res.Id, &createdAt)
+
+	if err == sql.ErrNoRows {
+		if _, getErr := r.GetByID(ctx, res.InventoryId); getErr != nil {
+			return getErr
+		}
+		return ErrInsufficientStock
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reserve stock: %w", err)
+	}
+	res.Created = createdAt.Unix()
+
+	return nil
 }
 
-func isDuplicateKeyError(err error) bool {
-	return false
+// RELEASE FOR ORDER
+func (r *inventoryRepository) ReleaseForOrder(ctx context.Context, client database.SQLClient, orderId int) (int64, error) {
+	client = r.db.On(client)
+	query := `DELETE FROM inventory_reservations WHERE order_id = $1`
+
+	result, err := client.ExecContext(ctx, query, orderId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to release reservations: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// CONSUME FOR ORDER
+// Takes what the sold items use out of stock, one movement per ingredient,
+// through the caller's transaction. Ingredients without enough stock are
+// skipped and returned instead, so the caller can report them all and roll
+// back.
+func (r *inventoryRepository) ConsumeForOrder(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) ([]string, error) {
+	client = r.db.On(client)
+	usage, err := r.orderUsage(ctx, client, items)
+	if err != nil {
+		return nil, err
+	}
+
+	var short []string
+	for _, u := range usage {
+		m := &StockMovement{
+			InventoryId: u.InventoryId,
+			Delta:       -u.Quantity,
+			Reason:      ReasonSale,
+			Note:        fmt.Sprintf("order #%d", orderId),
+			UserId:      userId,
+		}
+		m.StockAfter, err = r.updateStock(ctx, client, u.InventoryId, m.Delta)
+		if err == ErrInsufficientStock {
+			short = append(short, u.Slug)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := r.insertMovement(ctx, client, m); err != nil {
+			return nil, err
+		}
+	}
+
+	return short, nil
+}
+
+// RESTOCK FOR ORDER
+// Puts back what ConsumeForOrder took for a voided order. Quantities come
+// from the recipes as they are now, so a recipe edited since the sale
+// returns the new amounts.
+func (r *inventoryRepository) RestockForOrder(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) error {
+	client = r.db.On(client)
+	usage, err := r.orderUsage(ctx, client, items)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range usage {
+		m := &StockMovement{
+			InventoryId: u.InventoryId,
+			Delta:       u.Quantity,
+			Reason:      ReasonSale,
+			Note:        fmt.Sprintf("void order #%d", orderId),
+			UserId:      userId,
+		}
+		m.StockAfter, err = r.updateStock(ctx, client, u.InventoryId, m.Delta)
+		if err != nil {
+			return err
+		}
+		if err := r.insertMovement(ctx, client, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GET ORDER USAGE
+func (r *inventoryRepository) GetOrderUsage(ctx context.Context, items []string) ([]*OrderUsage, error) {
+	return r.orderUsage(ctx, r.db, items)
+}
+
+// RELEASE EXPIRED
+func (r *inventoryRepository) ReleaseExpired(ctx context.Context) (int64, error) {
+	query := `
+		DELETE FROM inventory_reservations
+		WHERE expires_at <= NOW() AND inventory_id IN (SELECT id FROM inventory WHERE store_id = $1)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, database.StoreOf(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to release expired reservations: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// CREATE STOCKTAKE
+func (r *inventoryRepository) CreateStocktake(ctx context.Context, session *StocktakeSession) error {
+	query := `
+		INSERT INTO stocktake_sessions (note, user_id, store_id)
+		VALUES ($1, NULLIF($2, 0), $3)
+	`
+	args := []any{session.Note, session.UserId, database.StoreOf(ctx)}
+
+	var createdAt time.Time
+	err := r.dialect.InsertReturning(ctx, r.db, "stocktake_sessions", query, "id, status, created_at", args, &session.Id, &session.Status, &createdAt)
+	if err != nil {
+		return fmt.Errorf("failed to create stocktake: %w", err)
+	}
+	session.Created = createdAt.Unix()
+
+	return nil
+}
+
+// GET STOCKTAKE
+func (r *inventoryRepository) GetStocktake(ctx context.Context, id int) (*StocktakeSession, error) {
+	query := `SELECT ` + stocktakeColumns + ` FROM stocktake_sessions WHERE id = $1 AND store_id = $2`
+
+	return database.Get(ctx, r.db, scanStocktake, ErrStocktakeNotFound, query, id, database.StoreOf(ctx))
+}
+
+// LIST STOCKTAKES
+// Closed sessions whose close time falls between start and end, oldest first.
+func (r *inventoryRepository) ListStocktakes(ctx context.Context, start, end time.Time) ([]*StocktakeSession, error) {
+	query := `
+		SELECT ` + stocktakeColumns + `
+		FROM stocktake_sessions
+		WHERE status = 'closed' AND closed_at >= $1 AND closed_at <= $2 AND store_id = $3
+		ORDER BY closed_at
+	`
+
+	sessions, err := database.Select(ctx, r.db, scanStocktake, query, start, end, database.StoreOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stocktakes: %w", err)
+	}
+	return sessions, nil
+}
+
+// RECORD COUNT
+// Stores (or overwrites) the counted quantity of an item, only while the
+// session is open and for an item of the session's store.
+func (r *inventoryRepository) RecordCount(ctx context.Context, sessionId, inventoryId int, counted int64) error {
+	query := `
+		INSERT INTO stocktake_counts (session_id, inventory_id, counted)
+		SELECT s.id, i.id, $3
+		FROM stocktake_sessions s
+		JOIN inventory i ON i.id = $2 AND i.store_id = s.store_id
+		WHERE s.id = $1 AND s.store_id = $4 AND s.status = 'open'
+	` + r.dialect.OnConflict("session_id, inventory_id", "counted = EXCLUDED.counted")
+
+	ok, err := database.ExecAffected(ctx, r.db, query, sessionId, inventoryId, counted, database.StoreOf(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to record count: %w", err)
+	}
+	if !ok {
+		// The session does not exist or is no longer open, or the item doesn't
+		session, err := r.GetStocktake(ctx, sessionId)
+		if err != nil {
+			return err
+		}
+		if session.Status != StocktakeOpen {
+			return ErrStocktakeClosed
+		}
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// CLOSE STOCKTAKE
+// Freezes the expected (system) stock and unit cost next to every count,
+// corrects stock to the counted value with a 'stocktake' movement, and marks
+// the session closed. All of it happens in one transaction with the counted
+// items locked, so sales landing mid-close cannot skew the variance.
+func (r *inventoryRepository) CloseStocktake(ctx context.Context, sessionId int, userId int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx, `SELECT status FROM stocktake_sessions WHERE id = $1 AND store_id = $2`+r.dialect.ForUpdate(), sessionId, database.StoreOf(ctx)).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrStocktakeNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get stocktake: %w", err)
+	}
+	if status != StocktakeOpen {
+		return ErrStocktakeClosed
+	}
+
+	query := `
+		SELECT c.inventory_id, c.counted, i.stock, i.unit_cost
+		FROM stocktake_counts c
+		JOIN inventory i ON i.id = c.inventory_id
+		WHERE c.session_id = $1
+		ORDER BY c.inventory_id
+	` + r.dialect.ForUpdate("i")
+
+	rows, err := tx.QueryContext(ctx, query, sessionId)
+	if err != nil {
+		return fmt.Errorf("failed to get stocktake counts: %w", err)
+	}
+
+	type countRow struct {
+		inventoryId                 int
+		counted, expected, unitCost int64
+	}
+	var counts []countRow
+	for rows.Next() {
+		var c countRow
+		if err := rows.Scan(&c.inventoryId, &c.counted, &c.expected, &c.unitCost); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan stocktake count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	note := fmt.Sprintf("stocktake #%d", sessionId)
+	for _, c := range counts {
+		_, err := tx.ExecContext(ctx,
+			`UPDATE stocktake_counts SET expected = $1, unit_cost = $2 WHERE session_id = $3 AND inventory_id = $4`,
+			c.expected, c.unitCost, sessionId, c.inventoryId,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save expected stock: %w", err)
+		}
+
+		delta := c.counted - c.expected
+		if delta == 0 {
+			continue
+		}
+
+		newStock, err := r.updateStock(ctx, tx, c.inventoryId, delta)
+		if err != nil {
+			return err
+		}
+
+		m := &StockMovement{
+			InventoryId: c.inventoryId,
+			Delta:       delta,
+			StockAfter:  newStock,
+			Reason:      ReasonStocktake,
+			Note:        note,
+			UserId:      userId,
+		}
+		if err := r.insertMovement(ctx, tx, m); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE stocktake_sessions SET status = 'closed', closed_at = NOW() WHERE id = $1`, sessionId)
+	if err != nil {
+		return fmt.Errorf("failed to close stocktake: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// STOCKTAKE COUNTS
+// Counts for the given sessions, with item details for reporting.
+func (r *inventoryRepository) GetStocktakeCounts(ctx context.Context, sessionIds []int) ([]*StocktakeCount, error) {
+	query := `
+		SELECT c.session_id, c.inventory_id, i.slug, i.name, i.tags,
+		       c.counted, COALESCE(c.expected, 0), c.unit_cost
+		FROM stocktake_counts c
+		JOIN inventory i ON i.id = c.inventory_id
+		WHERE ` + r.dialect.AnyOf("c.session_id", "$1") + `
+		ORDER BY c.session_id, i.slug
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, r.dialect.Array(sessionIds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stocktake counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []*StocktakeCount
+	for rows.Next() {
+		c := &StocktakeCount{}
+		var tagsJSON []byte
+		err := rows.Scan(
+			&c.SessionId, &c.InventoryId, &c.Slug, &c.Name, &tagsJSON,
+			&c.Counted, &c.Expected, &c.UnitCost,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stocktake count: %w", err)
+		}
+		if err := json.Unmarshal(tagsJSON, &c.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+		}
+		c.Variance = c.Counted - c.Expected
+		counts = append(counts, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return counts, nil
+}
+
+// CREATE TAG
+func (r *inventoryRepository) CreateTag(ctx context.Context, tag *ManagedTag) error {
+	query := `
+		INSERT INTO inventory_tags (kind, name, store_id)
+		VALUES ($1, $2, $3)
+	`
+
+	args := []any{tag.Kind, tag.Name, database.StoreOf(ctx)}
+	err := r.dialect.InsertReturning(ctx, r.db, "inventory_tags", query, "id", args, &tag.Id)
+	if database.IsUniqueViolation(err) {
+		return ErrDuplicateTag
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	return nil
+}
+
+// GET TAG
+func (r *inventoryRepository) GetTag(ctx context.Context, id int) (*ManagedTag, error) {
+	query := `SELECT ` + r.tagColumns() + ` FROM inventory_tags t WHERE t.id = $1 AND t.store_id = $2`
+
+	return database.Get(ctx, r.db, scanTag, ErrTagNotFound, query, id, database.StoreOf(ctx))
+}
+
+// LIST TAGS
+func (r *inventoryRepository) ListTags(ctx context.Context, kind string) ([]*ManagedTag, error) {
+	query := `SELECT ` + r.tagColumns() + ` FROM inventory_tags t WHERE t.store_id = $1`
+	args := []any{database.StoreOf(ctx)}
+	if kind != "" {
+		query += " AND t.kind = $2"
+		args = append(args, kind)
+	}
+	query += " ORDER BY t.kind, t.name"
+
+	tags, err := database.Select(ctx, r.db, scanTag, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	return tags, nil
+}
+
+// RENAME TAG
+// Renames the managed tag and rewrites every item that uses it in one
+// transaction, so no item is left pointing at the old name.
+func (r *inventoryRepository) RenameTag(ctx context.Context, id int, newName string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var kind, oldName string
+	store := database.StoreOf(ctx)
+	err = tx.QueryRowContext(ctx, `SELECT kind, name FROM inventory_tags WHERE id = $1 AND store_id = $2`+r.dialect.ForUpdate(), id, store).Scan(&kind, &oldName)
+	if err == sql.ErrNoRows {
+		return ErrTagNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get tag: %w", err)
+	}
+
+	if oldName == newName {
+		return nil
+	}
+
+	var taken bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM inventory_tags WHERE kind = $1 AND name = $2 AND store_id = $3)`, kind, newName, store).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("failed to check tag: %w", err)
+	}
+	if taken {
+		return ErrDuplicateTag
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE inventory_tags SET name = $1 WHERE id = $2`, newName, id); err != nil {
+		return fmt.Errorf("failed to rename tag: %w", err)
+	}
+
+	var cascade []string
+	switch kind {
+	case TagKindLabel:
+		cascade = []string{`UPDATE inventory SET label = $2, revision = revision + 1, updated_at = NOW() WHERE label = $1 AND store_id = $3`}
+	default:
+		cascade = []string{
+			`UPDATE inventory
+			 SET tags = (
+				SELECT ` + r.dialect.JSONAgg("CASE WHEN t.value = $1 THEN $2 ELSE t.value END") + `
+				FROM ` + r.dialect.JSONElements("tags", "t") + `
+			 ), revision = revision + 1, updated_at = NOW()
+			 WHERE store_id = $3 AND ` + r.dialect.JSONHasElement("tags", "$1"),
+		}
+	}
+	for _, q := range cascade {
+		if _, err := tx.ExecContext(ctx, q, oldName, newName, store); err != nil {
+			return fmt.Errorf("failed to cascade tag rename: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DELETE TAG
+// Removes the managed tag and strips it from every item in one transaction.
+func (r *inventoryRepository) DeleteTag(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var kind, name string
+	store := database.StoreOf(ctx)
+	err = tx.QueryRowContext(ctx, `SELECT kind, name FROM inventory_tags WHERE id = $1 AND store_id = $2`+r.dialect.ForUpdate(), id, store).Scan(&kind, &name)
+	if err == sql.ErrNoRows {
+		return ErrTagNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM inventory_tags WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+
+	var cascade []string
+	switch kind {
+	case TagKindLabel:
+		cascade = []string{`UPDATE inventory SET label = '', revision = revision + 1, updated_at = NOW() WHERE label = $1 AND store_id = $2`}
+	default:
+		cascade = []string{
+			`UPDATE inventory SET tags = ` + r.dialect.JSONWithout("tags", "$1") + `, revision = revision + 1, updated_at = NOW()` +
+				` WHERE store_id = $2 AND ` + r.dialect.JSONHasElement("tags", "$1"),
+		}
+	}
+	for _, q := range cascade {
+		if _, err := tx.ExecContext(ctx, q, name, store); err != nil {
+			return fmt.Errorf("failed to cascade tag delete: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// PRODUCTS USING ITEM
+// Finds products whose recipe has the item's slug as a key, leaving out
+// products in the trash.
+func (r *inventoryRepository) GetProductsUsing(ctx context.Context, slug string) ([]*ProductRef, error) {
+	d := r.dialect
+	query := `
+		SELECT id, slug, name, avail,
+		       (SELECT ` + d.CastInt(d.JSONEachValue("recipe", "rc")) + ` FROM ` + d.JSONEach("recipe", "rc") + ` WHERE rc.key = $1)
+		FROM products
+		WHERE store_id = $2 AND deleted_at IS NULL AND ` + d.JSONType("recipe") + ` = 'object' AND ` + d.JSONHasKey("recipe", "$1") + `
+		ORDER BY name
+	`
+
+	products, err := database.Select(ctx, r.db, scanProductRef, query, slug, database.StoreOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products using inventory: %w", err)
+	}
+	return products, nil
+}
+
+// SYNC PRODUCT AVAILABILITY
+// Re-evaluates every product whose recipe uses one of the given inventory
+// slugs. A product that can no longer be made (an ingredient is at zero or
+// below what one portion needs) is switched off and flagged auto_86. With
+// reenable set, flagged products that can be made again are switched back on.
+// Products switched off by hand are never touched.
+func (r *inventoryRepository) SyncProductAvailability(ctx context.Context, slugs []string, reenable bool) ([]*AvailabilityEvent, error) {
+	d := r.dialect
+	affected := `
+		SELECT p.id AS product_id, NOT EXISTS (
+			SELECT 1
+			FROM ` + d.JSONEach("p.recipe", "rc") + `
+			LEFT JOIN inventory i ON i.slug = rc.key AND i.store_id = p.store_id AND i.deleted_at IS NULL
+			WHERE COALESCE(i.stock, 0) <= 0 OR COALESCE(i.stock, 0) < ` + d.CastInt(d.JSONEachValue("p.recipe", "rc")) + `
+		) AS makeable
+		FROM products p
+		WHERE p.store_id = $3 AND p.deleted_at IS NULL AND ` + d.JSONType("p.recipe") + ` = 'object' AND ` + d.JSONHasAnyKey("p.recipe", "$1")
+	if d == database.MySQL {
+		return r.syncProductAvailabilityMySQL(ctx, affected, slugs, reenable)
+	}
+
+	// RETURNING names the columns unqualified; SQLite rejects the alias there
+	query := `
+		WITH affected AS (` + affected + `)
+		UPDATE products AS p
+		SET avail = a.makeable, auto_86 = NOT a.makeable, revision = p.revision + 1, updated_at = NOW()
+		FROM affected a
+		WHERE p.id = a.product_id
+		  AND ((p.avail AND NOT a.makeable) OR ($2 AND p.auto_86 AND NOT p.avail AND a.makeable))
+		RETURNING id, slug, avail, (
+			SELECT rc.key FROM ` + d.JSONEach("recipe", "rc") + `
+			WHERE ` + d.AnyOf("rc.key", "$1") + ` LIMIT 1
+		)
+	`
+
+	events, err := database.Select(ctx, r.db, scanAvailabilityEvent, query, d.Array(slugs), reenable, database.StoreOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync product availability: %w", err)
+	}
+	return events, nil
+}
+
+// MySQL has no UPDATE ... RETURNING, so the changes are read first and then
+// applied one product at a time, in a transaction with the products locked.
+func (r *inventoryRepository) syncProductAvailabilityMySQL(ctx context.Context, affected string, slugs []string, reenable bool) ([]*AvailabilityEvent, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	d := r.dialect
+	query := `
+		SELECT p.id, p.slug, a.makeable, (
+			SELECT rc.key FROM ` + d.JSONEach("p.recipe", "rc") + `
+			WHERE ` + d.AnyOf("rc.key", "$1") + ` LIMIT 1
+		)
+		FROM products p
+		JOIN (` + affected + `) a ON a.product_id = p.id
+		WHERE (p.avail AND NOT a.makeable) OR ($2 AND p.auto_86 AND NOT p.avail AND a.makeable)
+	` + d.ForUpdate()
+
+	events, err := database.Select(ctx, tx, scanAvailabilityEvent, query, d.Array(slugs), reenable, database.StoreOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync product availability: %w", err)
+	}
+
+	for _, e := range events {
+		_, err := tx.ExecContext(ctx, `UPDATE products SET avail = $1, auto_86 = NOT $1, revision = revision + 1, updated_at = NOW() WHERE id = $2`, e.Avail, e.ProductId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sync product availability: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return events, nil
+}
+
+// PAR LEVELS
+// Buckets every item by comparing stock to its par levels in a single query.
+// Returns bucket name (see Par* constants) -> items, most urgent first.
+func (r *inventoryRepository) GetParLevels(ctx context.Context) (map[string][]*Inventory, error) {
+	query := `
+		SELECT ` + r.inventoryColumns() + `, CASE
+			WHEN stock <= 0 OR stock * 2 < min_stock THEN '` + ParCritical + `'
+			WHEN stock < min_stock THEN '` + ParLow + `'
+			WHEN max_stock > 0 AND stock > max_stock THEN '` + ParOverstocked + `'
+			ELSE '` + ParOk + `'
+		END AS bucket
+		FROM inventory
+		WHERE store_id = $1 AND deleted_at IS NULL
+		ORDER BY stock - min_stock, name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, database.StoreOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get par levels: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := map[string][]*Inventory{
+		ParCritical:    {},
+		ParLow:         {},
+		ParOk:          {},
+		ParOverstocked: {},
+	}
+	for rows.Next() {
+		var bucket string
+		inv, err := r.scanInventory(withExtra(rows, &bucket))
+		if err != nil {
+			return nil, err
+		}
+		buckets[bucket] = append(buckets[bucket], inv)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return buckets, nil
+}
+
+// CONSUMPTION
+// Derives how much of each inventory slug was used between start and end by
+// walking orders -> sold product slugs -> product recipes. Bundles are
+// expanded one level so a "morning package" counts its coffee and croissant.
+// Returns inventory slug -> total quantity consumed.
+func (r *inventoryRepository) GetConsumption(ctx context.Context, start, end time.Time) (map[string]int64, error) {
+	d := r.dialect
+	query := `
+		WITH sold AS (
+			SELECT item.value AS slug
+			FROM orders o
+			CROSS JOIN ` + d.JSONElements("o.items", "item") + `
+			WHERE o.created_at >= $1 AND o.created_at <= $2 AND o.status <> 'void' AND o.store_id = $3
+		), expanded AS (
+			SELECT slug FROM sold
+			UNION ALL
+			SELECT b.value
+			FROM sold s
+			JOIN products p ON p.slug = s.slug AND p.store_id = $3 AND ` + d.JSONType("p.items") + ` = 'array'
+			CROSS JOIN ` + d.JSONElements("p.items", "b") + `
+		)
+		SELECT r.key, SUM(` + d.CastInt(d.JSONEachValue("p.recipe", "r")) + `)
+		FROM expanded e
+		JOIN products p ON p.slug = e.slug AND p.store_id = $3 AND ` + d.JSONType("p.recipe") + ` = 'object'
+		CROSS JOIN ` + d.JSONEach("p.recipe", "r") + `
+		GROUP BY r.key
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, start, end, database.StoreOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consumption: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make(map[string]int64)
+	for rows.Next() {
+		var slug string
+		var qty int64
+		if err := rows.Scan(&slug, &qty); err != nil {
+			return nil, fmt.Errorf("failed to scan consumption: %w", err)
+		}
+		usage[slug] = qty
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return usage, nil
+}
+
+// OUTFLOW BY REASON
+// Sums recorded stock decreases between start and end. Sales are netted
+// against the restocks of voided orders, which carry the same reason.
+// Returns inventory slug -> reason -> quantity removed (as a positive number).
+func (r *inventoryRepository) GetOutflowByReason(ctx context.Context, start, end time.Time) (map[string]map[string]int64, error) {
+	query := `
+		SELECT i.slug, m.reason, -SUM(m.delta)
+		FROM inventory_movements m
+		JOIN inventory i ON i.id = m.inventory_id
+		WHERE (m.delta < 0 OR m.reason = $3) AND m.created_at >= $1 AND m.created_at <= $2 AND i.store_id = $4
+		GROUP BY i.slug, m.reason
+		HAVING SUM(m.delta) <> 0
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, start, end, ReasonSale, database.StoreOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock outflow: %w", err)
+	}
+	defer rows.Close()
+
+	outflow := make(map[string]map[string]int64)
+	for rows.Next() {
+		var slug, reason string
+		var qty int64
+		if err := rows.Scan(&slug, &reason, &qty); err != nil {
+			return nil, fmt.Errorf("failed to scan stock outflow: %w", err)
+		}
+		if outflow[slug] == nil {
+			outflow[slug] = make(map[string]int64)
+		}
+		outflow[slug][reason] = qty
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return outflow, nil
+}
+
+// AVERAGE STOCK
+// Averages the daily snapshots between start and end.
+// Returns inventory slug -> average stock. Items without snapshots are absent.
+func (r *inventoryRepository) GetAverageStock(ctx context.Context, start, end time.Time) (map[string]float64, error) {
+	query := `
+		SELECT i.slug, ` + r.dialect.CastFloat("AVG(s.stock)") + `
+		FROM inventory_snapshots s
+		JOIN inventory i ON i.id = s.inventory_id
+		WHERE s.taken_on >= $1 AND s.taken_on <= $2 AND i.store_id = $3
+		GROUP BY i.slug
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, start, end, database.StoreOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get average stock: %w", err)
+	}
+	defer rows.Close()
+
+	avg := make(map[string]float64)
+	for rows.Next() {
+		var slug string
+		var v float64
+		if err := rows.Scan(&slug, &v); err != nil {
+			return nil, fmt.Errorf("failed to scan average stock: %w", err)
+		}
+		avg[slug] = v
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return avg, nil
+}
+
+// Helper methods
+
+// buildListFilter returns the " AND ..." clauses shared by List and Count
+// together with their positional arguments, starting at $1.
+func (r *inventoryRepository) buildListFilter(ctx context.Context, opts ListOptions) database.Filter {
+	f := database.Filter{Where: " AND " + database.Live}
+	if opts.Deleted {
+		f.Where = " AND " + database.Trashed
+	}
+	f.And("store_id = " + f.Arg(database.StoreOf(ctx)))
+
+	if len(opts.Tags) > 0 {
+		tagsJSON, _ := json.Marshal(opts.Tags) // []string never fails
+		f.And(r.dialect.JSONContains("tags", f.Arg(tagsJSON)))
+	}
+	if opts.Label != "" {
+		f.And("label = " + f.Arg(opts.Label))
+	}
+	if opts.StockMin != nil {
+		f.And("stock >= " + f.Arg(*opts.StockMin))
+	}
+	if opts.StockMax != nil {
+		f.And("stock <= " + f.Arg(*opts.StockMax))
+	}
+	if opts.BelowThreshold {
+		f.And("stock < min_stock")
+	}
+	f.Period(opts.Period)
+
+	return f
+}
+
+func (r *inventoryRepository) getOne(ctx context.Context, where string, arg any) (*Inventory, error) {
+	query := `SELECT ` + r.inventoryColumns() + ` FROM inventory WHERE ` + where + ` AND store_id = $2`
+
+	return database.Get(ctx, r.db, r.scanInventory, ErrNotFound, query, arg, database.StoreOf(ctx))
+}
+
+func (r *inventoryRepository) scanInventory(scanner database.Scanner) (*Inventory, error) {
+	inv := &Inventory{}
+	var tagsJSON, customJSON []byte
+
+	err := scanner.Scan(
+		&inv.Id, &inv.Slug, &inv.Name, &inv.Desc,
+		&inv.Label, &tagsJSON, &inv.Stock, &inv.MinStock, &inv.MaxStock, &inv.UnitCost,
+		&inv.Reserved, &inv.Barcode, &customJSON, &inv.Created, &inv.Updated, &inv.Deleted, &inv.Revision,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan inventory: %w", err)
+	}
+
+	if len(tagsJSON) > 0 {
+		if err := json.Unmarshal(tagsJSON, &inv.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+		}
+	}
+
+	if len(customJSON) > 0 {
+		if err := database.UnmarshalCustom("inventory", customJSON, &inv.Custom); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal custom data: %w", err)
+		}
+	}
+
+	return inv, nil
+}
+
+// extraScanner lets scanInventory read rows that carry extra computed
+// columns after the standard inventoryColumns.
+type extraScanner struct {
+	rows  *sql.Rows
+	extra []any
+}
+
+func withExtra(rows *sql.Rows, extra ...any) extraScanner {
+	return extraScanner{rows: rows, extra: extra}
+}
+
+func (e extraScanner) Scan(dest ...any) error {
+	return e.rows.Scan(append(dest, e.extra...)...)
+}
+
+func scanStocktake(scanner database.Scanner) (*StocktakeSession, error) {
+	s := &StocktakeSession{}
+	var createdAt time.Time
+	var closedAt sql.NullTime
+	if err := scanner.Scan(&s.Id, &s.Status, &s.Note, &s.UserId, &createdAt, &closedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan stocktake: %w", err)
+	}
+	s.Created = createdAt.Unix()
+	if closedAt.Valid {
+		s.Closed = closedAt.Time.Unix()
+	}
+	return s, nil
+}
+
+func scanMovement(scanner database.Scanner) (*StockMovement, error) {
+	m := &StockMovement{}
+	var createdAt time.Time
+	err := scanner.Scan(
+		&m.Id, &m.InventoryId, &m.Delta, &m.StockAfter,
+		&m.Reason, &m.Note, &m.UnitCost, &m.UserId, &createdAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan stock movement: %w", err)
+	}
+	m.Created = createdAt.Unix()
+	return m, nil
+}
+
+// scanTag reads a row of tagColumns.
+func scanTag(scanner database.Scanner) (*ManagedTag, error) {
+	tag := &ManagedTag{}
+	if err := scanner.Scan(&tag.Id, &tag.Kind, &tag.Name, &tag.Items); err != nil {
+		return nil, fmt.Errorf("failed to scan tag: %w", err)
+	}
+	return tag, nil
+}
+
+func scanProductRef(scanner database.Scanner) (*ProductRef, error) {
+	p := &ProductRef{}
+	if err := scanner.Scan(&p.Id, &p.Slug, &p.Name, &p.Avail, &p.Quantity); err != nil {
+		return nil, fmt.Errorf("failed to scan product: %w", err)
+	}
+	return p, nil
+}
+
+// scanAvailabilityEvent reads a product id, slug, new availability and the
+// ingredient that caused the change, stamped with the current time.
+func scanAvailabilityEvent(scanner database.Scanner) (*AvailabilityEvent, error) {
+	e := &AvailabilityEvent{At: time.Now().Unix()}
+	if err := scanner.Scan(&e.ProductId, &e.ProductSlug, &e.Avail, &e.Cause); err != nil {
+		return nil, fmt.Errorf("failed to scan availability change: %w", err)
+	}
+	return e, nil
+}
+
+// marshalTags stores nil as an empty array so containment queries work.
+func marshalTags(tags []string) ([]byte, error) {
+	if tags == nil {
+		tags = []string{}
+	}
+	return json.Marshal(tags)
+}
+
+// insertMovement writes a ledger row for a stock change that has already
+// been applied through the same client.
+func (r *inventoryRepository) insertMovement(ctx context.Context, client database.SQLClient, m *StockMovement) error {
+	query := `
+		INSERT INTO inventory_movements (inventory_id, delta, stock_after, reason, note, unit_cost, user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0))
+	`
+	args := []any{m.InventoryId, m.Delta, m.StockAfter, m.Reason, m.Note, m.UnitCost, m.UserId}
+
+	var createdAt time.Time
+	err := r.dialect.InsertReturning(ctx, client, "inventory_movements", query, "id, created_at", args, &m.Id, &createdAt)
+	if err != nil {
+		return fmt.Errorf("failed to record stock movement: %w", err)
+	}
+	m.Created = createdAt.Unix()
+
+	return nil
+}
+
+// orderUsage totals what the given product slugs take out of stock, per
+// live inventory item, ordered by id so concurrent orders lock rows in the
+// same order. As in GetConsumption, a bundle counts its own recipe and its
+// components' recipes, one level deep. Recipe entries naming no live item
+// are left out.
+func (r *inventoryRepository) orderUsage(ctx context.Context, client database.SQLClient, items []string) ([]*OrderUsage, error) {
+	products, err := r.productRecipes(ctx, client, items)
+	if err != nil {
+		return nil, err
+	}
+
+	var components []string
+	for _, p := range products {
+		for _, c := range p.items {
+			if _, ok := products[c]; !ok {
+				components = append(components, c)
+			}
+		}
+	}
+	if len(components) > 0 {
+		more, err := r.productRecipes(ctx, client, components)
+		if err != nil {
+			return nil, err
+		}
+		for slug, p := range more {
+			products[slug] = p
+		}
+	}
+
+	totals := make(map[string]int64)
+	add := func(recipe map[string]int) {
+		for slug, qty := range recipe {
+			totals[slug] += int64(qty)
+		}
+	}
+	for _, slug := range items {
+		p, ok := products[slug]
+		if !ok {
+			continue
+		}
+		add(p.recipe)
+		for _, c := range p.items {
+			if component, ok := products[c]; ok {
+				add(component.recipe)
+			}
+		}
+	}
+	if len(totals) == 0 {
+		return nil, nil
+	}
+
+	slugs := make([]string, 0, len(totals))
+	for slug := range totals {
+		slugs = append(slugs, slug)
+	}
+	query := `
+		SELECT id, slug FROM inventory
+		WHERE ` + r.dialect.AnyOf("slug", "$1") + ` AND store_id = $2 AND deleted_at IS NULL
+		ORDER BY id
+	`
+
+	rows, err := client.QueryContext(ctx, query, r.dialect.Array(slugs), database.StoreOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []*OrderUsage
+	for rows.Next() {
+		u := &OrderUsage{}
+		if err := rows.Scan(&u.InventoryId, &u.Slug); err != nil {
+			return nil, fmt.Errorf("failed to scan order usage: %w", err)
+		}
+		if u.Quantity = totals[u.Slug]; u.Quantity > 0 {
+			usage = append(usage, u)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return usage, nil
+}
+
+// productRecipe is the part of a product orderUsage needs.
+type productRecipe struct {
+	items  []string
+	recipe map[string]int
+}
+
+// productRecipes loads the bundle items and recipe of each product by slug.
+// Unknown slugs are absent from the result.
+func (r *inventoryRepository) productRecipes(ctx context.Context, client database.SQLClient, slugs []string) (map[string]productRecipe, error) {
+	query := `SELECT slug, items, recipe FROM products WHERE ` + r.dialect.AnyOf("slug", "$1") + ` AND store_id = $2`
+
+	rows, err := client.QueryContext(ctx, query, r.dialect.Array(slugs), database.StoreOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product recipes: %w", err)
+	}
+	defer rows.Close()
+
+	products := make(map[string]productRecipe)
+	for rows.Next() {
+		var slug string
+		var itemsJSON, recipeJSON []byte
+		if err := rows.Scan(&slug, &itemsJSON, &recipeJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan product recipe: %w", err)
+		}
+
+		var p productRecipe
+		if len(itemsJSON) > 0 {
+			if err := json.Unmarshal(itemsJSON, &p.items); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal product items: %w", err)
+			}
+		}
+		if len(recipeJSON) > 0 {
+			if err := json.Unmarshal(recipeJSON, &p.recipe); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal product recipe: %w", err)
+			}
+		}
+		products[slug] = p
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return products, nil
+}
+
+// updateStock applies delta to a single item through the given client, which
+// may be the pool or a transaction. The guard lives in the WHERE clause so the
+// check and the write happen atomically; two concurrent decrements can never
+// both pass a stale read.
+func (r *inventoryRepository) updateStock(ctx context.Context, client database.SQLClient, id int, delta int64) (int64, error) {
+	query := `
+		UPDATE inventory
+		SET stock = stock + $1, revision = revision + 1, updated_at = NOW()
+		WHERE id = $2 AND store_id = $3 AND deleted_at IS NULL
+	`
+	if !r.allowNegativeStock {
+		query += " AND stock + $1 >= 0"
+	}
+
+	var newStock int64
+	store := database.StoreOf(ctx)
+	err := r.dialect.UpdateReturning(ctx, client, query, "stock", []any{delta, id, store}, &newStock)
+
+	if err == sql.ErrNoRows {
+		// No row updated: either the item doesn't exist or the guard rejected it
+		var exists bool
+		existsQuery := `SELECT EXISTS(SELECT 1 FROM inventory WHERE id = $1 AND store_id = $2 AND deleted_at IS NULL)`
+		if err := client.QueryRowContext(ctx, existsQuery, id, store).Scan(&exists); err != nil {
+			return 0, fmt.Errorf("failed to check inventory: %w", err)
+		}
+		if !exists {
+			return 0, ErrNotFound
+		}
+		return 0, ErrInsufficientStock
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to update stock: %w", err)
+	}
+
+	return newStock, nil
+}
+
+func (r *inventoryRepository) scanSupplierPrices(rows *sql.Rows) ([]*SupplierPrice, error) {
+	var prices []*SupplierPrice
+	for rows.Next() {
+		sp := &SupplierPrice{}
+		err := rows.Scan(&sp.Id, &sp.InventoryId, &sp.Slug, &sp.Supplier, &sp.UnitPrice, &sp.LeadTimeDays)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan supplier price: %w", err)
+		}
+		prices = append(prices, sp)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return prices, nil
+}
+
+func (r *inventoryRepository) scanSnapshots(rows *sql.Rows) ([]*StockSnapshot, error) {
+	var snapshots []*StockSnapshot
+	for rows.Next() {
+		snap := &StockSnapshot{}
+		if err := rows.Scan(&snap.InventoryId, &snap.Slug, &snap.TakenOn, &snap.Stock); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+		snapshots = append(snapshots, snap)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return snapshots, nil
+}
+
+// duplicateError maps a unique violation on inventory to the matching error.
+func duplicateError(err error) error {
+	if database.Constraint(err) == "inventory_barcode_key" {
+		return ErrDuplicateBarcode
+	}
+	return ErrDuplicateSlug
 }
@@ -2,31 +2,296 @@ package inventory
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/audit"
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/metrics"
+	"github.com/iteranya/practicing-go/internal/utils"
 )
 
+var stockAlerts = metrics.NewCounter("inventory_stock_alerts_total",
+	"Items that dropped below their reorder threshold (low_stock) or ran out (out_of_stock).", "kind")
+
 type InventoryService interface {
 	CreateInventory(ctx context.Context, input Inventory) (*Inventory, error)
 	GetInventory(ctx context.Context, idOrSlug any) (*Inventory, error)
+	GetByBarcode(ctx context.Context, barcode string) (*Inventory, error)
+	GetBySlugs(ctx context.Context, slugs []string) ([]*Inventory, error)
 	UpdateInventory(ctx context.Context, id int, input Inventory) error
 	DeleteInventory(ctx context.Context, id int) error
-	ListInventory(ctx context.Context, params ListParams) ([]*Inventory, error)
-	AdjustStock(ctx context.Context, id int, delta int64) error
+	RestoreInventory(ctx context.Context, id int) error
+	PurgeInventory(ctx context.Context, id int) error
+	PurgeDeleted(ctx context.Context, before time.Time) (int, error)
+	MissingIngredients(ctx context.Context, slugs []string) ([]string, error)
+	ListInventory(ctx context.Context, params ListParams) ([]*Inventory, int, error)
+	GetSummary(ctx context.Context) (*Summary, error)
+	AdjustStock(ctx context.Context, id int, adj StockAdjustment) (*AdjustmentResult, error)
+	GetMovements(ctx context.Context, id int, limit int) ([]*StockMovement, error)
+	ImportCSV(ctx context.Context, r io.Reader) (*ImportReport, error)
+	ExportCSV(ctx context.Context, params ListParams, w io.Writer) error
+
+	// Snapshots
+	TakeSnapshot(ctx context.Context) (int, error)
+	GetStockHistory(ctx context.Context, id int, start, end time.Time) ([]*StockSnapshot, error)
+	GetStockOn(ctx context.Context, day time.Time) ([]*StockSnapshot, error)
+
+	// Supplier prices
+	SetSupplierPrice(ctx context.Context, id int, sp SupplierPrice) (*SupplierPrice, error)
+	RemoveSupplierPrice(ctx context.Context, id int, supplier string) error
+	GetSupplierPrices(ctx context.Context, id int) ([]*SupplierPrice, error)
+	CompareSuppliers(ctx context.Context, belowThresholdOnly bool) ([]*SupplierComparison, error)
+
+	// Reservations
+	Reserve(ctx context.Context, id int, orderId int, quantity int64, ttl time.Duration) (*Reservation, error)
+	ReleaseForOrder(ctx context.Context, client database.SQLClient, orderId int) (int64, error)
+	ReleaseExpired(ctx context.Context) (int64, error)
+
+	// Sales
+	ConsumeForOrder(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) ([]string, error)
+	RestockForOrder(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) error
+	OrderStockChanged(ctx context.Context, items []string, sold bool)
+
+	// Stocktakes
+	OpenStocktake(ctx context.Context, note string, userId int) (*StocktakeSession, error)
+	RecordCount(ctx context.Context, sessionId, inventoryId int, counted int64) error
+	CloseStocktake(ctx context.Context, sessionId int, userId int) (*StocktakeReport, error)
+	GetStocktakeReport(ctx context.Context, sessionId int) (*StocktakeReport, error)
+	GetShrinkageReport(ctx context.Context, start, end time.Time) (*ShrinkageReport, error)
+
+	// Managed tags
+	CreateTag(ctx context.Context, kind, name string) (*ManagedTag, error)
+	ListTags(ctx context.Context, kind string) ([]*ManagedTag, error)
+	RenameTag(ctx context.Context, id int, name string) (*ManagedTag, error)
+	DeleteTag(ctx context.Context, id int) error
+
+	// Cross-entity lookups
+	GetProductsUsing(ctx context.Context, id int) ([]*ProductRef, error)
+	OnAvailabilityChange(fn func(AvailabilityEvent))
+	OnStockAlert(fn func(StockAlert))
+
+	// Analytics
+	GetParDashboard(ctx context.Context) (*ParDashboard, error)
+	ForecastConsumption(ctx context.Context, days int) ([]*Forecast, error)
+	GetConsumptionReport(ctx context.Context, start, end time.Time) ([]*ConsumptionLine, error)
+	GetTurnoverReport(ctx context.Context, start, end time.Time) ([]*TurnoverLine, error)
 }
 
 type ListParams struct {
-	Tag   string
-	Label string
-	Query string // Use this to toggle between List() and Search()
-	Limit int
-	Page  int
+	Deleted        bool     // List the trash instead of active items
+	Tags           []string // Item must have all of these
+	Label          string
+	Query          string // Use this to toggle between List() and Search()
+	StockMin       *int64
+	StockMax       *int64
+	BelowThreshold bool
+	Period         database.Period
+	SortBy         string
+	SortOrder      string
+	Limit          int
+	Page           int
+	After          string // Cursor from the page before, in place of Page
+}
+
+// StockAdjustment is a manual stock change. Reason is mandatory.
+type StockAdjustment struct {
+	Delta    int64
+	Reason   string
+	Note     string
+	UnitCost int64 // Optional, only used when Reason is ReasonReceived
+	UserId   int   // Set by the handler from the auth context
+}
+
+// AdjustmentResult is what a stock adjustment reports back: the ledger row,
+// the new balance and whether the change crossed the reorder threshold.
+type AdjustmentResult struct {
+	Movement *StockMovement `json:"movement"`
+	Stock    int64          `json:"stock"`
+	MinStock int64          `json:"min_stock"`
+	BelowMin bool           `json:"below_min"`
+	Crossed  string         `json:"crossed,omitempty"` // CrossedDown, CrossedUp or empty
+}
+
+// Threshold crossings reported by AdjustmentResult.Crossed
+const (
+	CrossedDown = "down" // Stock fell below min_stock with this adjustment
+	CrossedUp   = "up"   // Stock recovered to min_stock or above
+)
+
+// Forecast projects how long the current stock of an item will last
+// at the average daily consumption rate of the lookback window.
+type Forecast struct {
+	InventoryId   int      `json:"inventory_id"`
+	Slug          string   `json:"slug"`
+	Name          string   `json:"name"`
+	Stock         int64    `json:"stock"`
+	Consumed      int64    `json:"consumed"`       // Total used in the window
+	DailyUsage    float64  `json:"daily_usage"`    // Consumed / days
+	DaysRemaining *float64 `json:"days_remaining"` // nil when nothing was consumed
+}
+
+// SupplierComparison picks the best quotes for one item.
+type SupplierComparison struct {
+	InventoryId int            `json:"inventory_id"`
+	Slug        string         `json:"slug"`
+	Cheapest    *SupplierPrice `json:"cheapest"`
+	Fastest     *SupplierPrice `json:"fastest"`
+	Options     int            `json:"options"` // Number of suppliers quoting this item
+}
+
+// ConsumptionLine compares what recipes say an item should have used with
+// what the stock ledger actually recorded leaving.
+//
+//	Variance = RecordedOut - Explained - Theoretical
+//
+// A positive variance is unexplained loss (shrinkage); a negative one means
+// less left the shelf than recipes predict (over-portioned recipes or a
+// stocktake still pending).
+type ConsumptionLine struct {
+	InventoryId int              `json:"inventory_id"`
+	Slug        string           `json:"slug"`
+	Name        string           `json:"name"`
+	Theoretical int64            `json:"theoretical"`  // From orders x recipes
+	RecordedOut int64            `json:"recorded_out"` // All recorded decreases
+	Explained   int64            `json:"explained"`    // Waste, damage, returns, transfers
+	ByReason    map[string]int64 `json:"by_reason"`
+	Variance    int64            `json:"variance"`
+}
+
+// TurnoverLine relates how much of an item was used in a period to how much
+// was sitting on the shelf on average.
+//
+//	Turnover    = Consumed / AverageStock
+//	DaysOfCover = AverageStock / (Consumed / Days)
+//
+// Low turnover (high days of cover) marks slow-moving stock that is tying
+// up cash. DaysOfCover is nil when nothing was consumed.
+type TurnoverLine struct {
+	InventoryId  int      `json:"inventory_id"`
+	Slug         string   `json:"slug"`
+	Name         string   `json:"name"`
+	Consumed     int64    `json:"consumed"`
+	AverageStock float64  `json:"average_stock"`
+	Turnover     float64  `json:"turnover"`
+	DaysOfCover  *float64 `json:"days_of_cover"`
+	StockValue   int64    `json:"stock_value" perm:"inventory:cost"` // Current stock * unit cost
+}
+
+// StocktakeTotals values the variance of one or more closed stocktakes.
+// Shrinkage only counts missing units; NetValue also nets off overages.
+// ByTag is shrinkage value per tag (untagged items under ""); an item with
+// several tags counts towards each of them.
+type StocktakeTotals struct {
+	Items          int              `json:"items"`
+	ShrinkageUnits int64            `json:"shrinkage_units"`
+	ShrinkageValue int64            `json:"shrinkage_value" perm:"inventory:cost"`
+	NetValue       int64            `json:"net_value" perm:"inventory:cost"`
+	ByTag          map[string]int64 `json:"by_tag" perm:"inventory:cost"`
+}
+
+// StocktakeReport is the expected-vs-counted breakdown of one session.
+type StocktakeReport struct {
+	Session *StocktakeSession `json:"session"`
+	Totals  StocktakeTotals   `json:"totals"`
+	Lines   []*StocktakeCount `json:"lines,omitempty"`
+}
+
+// ShrinkageReport totals every stocktake closed within a period.
+type ShrinkageReport struct {
+	Sessions []*StocktakeReport `json:"sessions"` // Without lines
+	Totals   StocktakeTotals    `json:"totals"`
+}
+
+// ParDashboard groups items by how their stock compares to par levels.
+type ParDashboard struct {
+	Counts      map[string]int `json:"counts"`
+	Critical    []*Inventory   `json:"critical"`
+	Low         []*Inventory   `json:"low"`
+	Ok          []*Inventory   `json:"ok"`
+	Overstocked []*Inventory   `json:"overstocked"`
 }
 
 type inventoryService struct {
-	repo InventoryRepository
+	repo              InventoryRepository
+	autoReenable      bool
+	availabilityHooks []func(AvailabilityEvent)
+	stockAlertHooks   []func(StockAlert)
 }
 
-func NewInventoryService(repo InventoryRepository) InventoryService {
-	return &inventoryService{repo: repo}
+// NewInventoryService wires the service. With autoReenable set, products that
+// were automatically 86'd come back on once their ingredients are restocked.
+func NewInventoryService(repo InventoryRepository, autoReenable bool) InventoryService {
+	return &inventoryService{repo: repo, autoReenable: autoReenable}
+}
+
+// OnAvailabilityChange registers fn to be called for every product that is
+// automatically switched off or on. Register hooks at startup only; the list
+// is not guarded for concurrent modification.
+func (s *inventoryService) OnAvailabilityChange(fn func(AvailabilityEvent)) {
+	s.availabilityHooks = append(s.availabilityHooks, fn)
+}
+
+// OnStockAlert registers fn to be called whenever an item drops below its
+// reorder threshold or runs out. Same registration rules as OnAvailabilityChange.
+func (s *inventoryService) OnStockAlert(fn func(StockAlert)) {
+	s.stockAlertHooks = append(s.stockAlertHooks, fn)
+}
+
+// checkStockAlert raises an alert if going from before to the item's current
+// stock crossed into out-of-stock or below min_stock.
+func (s *inventoryService) checkStockAlert(ctx context.Context, inv *Inventory, before int64) {
+	var kind string
+	switch {
+	case inv.Stock <= 0 && before > 0:
+		kind = AlertOutOfStock
+	case inv.Stock < inv.MinStock && before >= inv.MinStock:
+		kind = AlertLowStock
+	default:
+		return
+	}
+
+	alert := StockAlert{
+		Kind:        kind,
+		InventoryId: inv.Id,
+		Slug:        inv.Slug,
+		Name:        inv.Name,
+		Stock:       inv.Stock,
+		MinStock:    inv.MinStock,
+		Store:       database.StoreOf(ctx),
+		At:          time.Now().Unix(),
+	}
+	stockAlerts.Inc(kind)
+	for _, fn := range s.stockAlertHooks {
+		fn(alert)
+	}
+}
+
+// syncAvailability re-evaluates products that use the given inventory slugs
+// after their stock changed. The stock change is already committed, so a
+// failure here is logged rather than returned.
+func (s *inventoryService) syncAvailability(ctx context.Context, slugs []string) {
+	if len(slugs) == 0 {
+		return
+	}
+
+	events, err := s.repo.SyncProductAvailability(ctx, slugs, s.autoReenable)
+	if err != nil {
+		log.Printf("inventory: product availability sync failed for %v: %v", slugs, err)
+		return
+	}
+
+	for _, e := range events {
+		for _, fn := range s.availabilityHooks {
+			fn(*e)
+		}
+	}
 }
 
 func (s *inventoryService) CreateInventory(ctx context.Context, input Inventory) (*Inventory, error) {
@@ -40,11 +305,19 @@ func (s *inventoryService) CreateInventory(ctx context.Context, input Inventory)
 		return nil, ErrInvalidInput
 	}
 
+	if input.MinStock < 0 || input.MaxStock < 0 || input.UnitCost < 0 {
+		return nil, ErrInvalidInput
+	}
+	if input.MaxStock > 0 && input.MaxStock < input.MinStock {
+		return nil, ErrInvalidInput
+	}
+
 	err := s.repo.Create(ctx, &input)
 	if err != nil {
 		return nil, err
 	}
 
+	audit.Record(ctx, audit.Inventory, input.Id, audit.Create, audit.Diff(nil, &input))
 	return &input, nil
 }
 
@@ -59,10 +332,32 @@ func (s *inventoryService) GetInventory(ctx context.Context, idOrSlug any) (*Inv
 	}
 }
 
+func (s *inventoryService) GetByBarcode(ctx context.Context, barcode string) (*Inventory, error) {
+	if barcode == "" {
+		return nil, ErrInvalidInput
+	}
+	return s.repo.GetByBarcode(ctx, barcode)
+}
+
+// GetBySlugs loads several items in a single query (e.g. every ingredient
+// of a recipe). Missing slugs are left out rather than reported as errors.
+func (s *inventoryService) GetBySlugs(ctx context.Context, slugs []string) ([]*Inventory, error) {
+	if len(slugs) == 0 {
+		return nil, nil
+	}
+	return s.repo.GetBySlugs(ctx, slugs)
+}
+
+// UpdateInventory replaces the item. The input carries the revision it was
+// based on; if the item changed in between, stock movements included, it
+// fails with ErrConflict rather than overwrite the change.
 func (s *inventoryService) UpdateInventory(ctx context.Context, id int, input Inventory) error {
 	if id == 0 {
 		return ErrInvalidInput
 	}
+	if input.Revision == 0 {
+		return fmt.Errorf("%w: revision is required", ErrInvalidInput)
+	}
 
 	// Fetch existing to ensure it exists
 	existing, err := s.repo.GetByID(ctx, id)
@@ -75,38 +370,849 @@ func (s *inventoryService) UpdateInventory(ctx context.Context, id int, input In
 	// excluding ID.
 	input.Id = existing.Id
 
-	return s.repo.Update(ctx, &input)
+	if err := s.repo.Update(ctx, &input); err != nil {
+		return err
+	}
+
+	audit.Record(ctx, audit.Inventory, id, audit.Update, audit.Diff(existing, &input))
+	return nil
 }
 
+// DeleteInventory soft-deletes the item; see RestoreInventory and PurgeInventory.
 func (s *inventoryService) DeleteInventory(ctx context.Context, id int) error {
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	audit.Record(ctx, audit.Inventory, id, audit.Delete, nil)
+	return nil
 }
 
-func (s *inventoryService) ListInventory(ctx context.Context, params ListParams) ([]*Inventory, error) {
+func (s *inventoryService) RestoreInventory(ctx context.Context, id int) error {
+	if err := s.repo.Restore(ctx, id); err != nil {
+		return err
+	}
+	audit.Record(ctx, audit.Inventory, id, audit.Restore, nil)
+	return nil
+}
+
+// PurgeInventory permanently removes an item that is already in the trash.
+func (s *inventoryService) PurgeInventory(ctx context.Context, id int) error {
+	if err := s.repo.Purge(ctx, id); err != nil {
+		return err
+	}
+	audit.Record(ctx, audit.Inventory, id, audit.Purge, nil)
+	return nil
+}
+
+// PurgeDeleted purges the items deleted before the given time and returns
+// how many went. Items a recipe still uses stay in the trash.
+func (s *inventoryService) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	ids, err := s.repo.TrashedBefore(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		err := s.repo.Purge(ctx, id)
+		if errors.Is(err, ErrInUse) || errors.Is(err, ErrNotFound) {
+			continue // still needed, or restored in the meantime
+		}
+		if err != nil {
+			return purged, err
+		}
+		audit.Record(ctx, audit.Inventory, id, audit.Purge, nil)
+		purged++
+	}
+	return purged, nil
+}
+
+// MissingIngredients returns the slugs that do not match an active item,
+// so products cannot reference deleted or unknown stock in their recipes.
+func (s *inventoryService) MissingIngredients(ctx context.Context, slugs []string) ([]string, error) {
+	if len(slugs) == 0 {
+		return nil, nil
+	}
+
+	items, err := s.repo.GetBySlugs(ctx, slugs)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]struct{}, len(items))
+	for _, inv := range items {
+		found[inv.Slug] = struct{}{}
+	}
+
+	var missing []string
+	for _, slug := range slugs {
+		if _, ok := found[slug]; !ok {
+			missing = append(missing, slug)
+		}
+	}
+
+	return missing, nil
+}
+
+// ListInventory returns one page of the items matching params, and how many
+// match in all. A text search (Query) isn't paged, and returns every match
+// as its total.
+func (s *inventoryService) ListInventory(ctx context.Context, params ListParams) ([]*Inventory, int, error) {
 	// If a search query is provided, use the Search method
 	if params.Query != "" {
-		return s.repo.Search(ctx, params.Query)
+		items, err := s.repo.Search(ctx, params.Query, params.Tags)
+		return items, len(items), err
 	}
 
 	// Calculate offset
 	offset := 0
-	if params.Page > 1 {
+	if params.Page > 1 && params.After == "" {
 		offset = (params.Page - 1) * params.Limit
 	}
 
-	repoOpts := ListOptions{
-		Tag:    params.Tag,
-		Label:  params.Label,
-		Limit:  params.Limit,
-		Offset: offset,
-	}
+	repoOpts := s.toListOptions(params)
+	repoOpts.Limit = params.Limit
+	repoOpts.Offset = offset
+	repoOpts.After = params.After
+	repoOpts.Total = true
 
 	return s.repo.List(ctx, repoOpts)
 }
 
-func (s *inventoryService) AdjustStock(ctx context.Context, id int, delta int64) error {
-	if delta == 0 {
-		return nil // No op
+func (s *inventoryService) GetSummary(ctx context.Context) (*Summary, error) {
+	return s.repo.GetSummary(ctx)
+}
+
+func (s *inventoryService) toListOptions(params ListParams) ListOptions {
+	return ListOptions{
+		Deleted:        params.Deleted,
+		Tags:           params.Tags,
+		Label:          params.Label,
+		StockMin:       params.StockMin,
+		StockMax:       params.StockMax,
+		BelowThreshold: params.BelowThreshold,
+		Period:         params.Period,
+		SortBy:         params.SortBy,
+		SortOrder:      params.SortOrder,
+	}
+}
+
+func (s *inventoryService) AdjustStock(ctx context.Context, id int, adj StockAdjustment) (*AdjustmentResult, error) {
+	if adj.Delta == 0 {
+		return nil, ErrInvalidInput
+	}
+	if !IsValidReason(adj.Reason) {
+		return nil, ErrInvalidReason
+	}
+	if adj.UnitCost < 0 {
+		return nil, ErrInvalidInput
+	}
+
+	m := &StockMovement{
+		InventoryId: id,
+		Delta:       adj.Delta,
+		Reason:      adj.Reason,
+		Note:        adj.Note,
+		UnitCost:    adj.UnitCost,
+		UserId:      adj.UserId,
+	}
+	if err := s.repo.AdjustStock(ctx, m); err != nil {
+		return nil, err
+	}
+
+	result := &AdjustmentResult{Movement: m, Stock: m.StockAfter}
+
+	inv, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return result, nil // the adjustment itself went through
+	}
+	s.syncAvailability(ctx, []string{inv.Slug})
+
+	inv.Stock = m.StockAfter // as of this adjustment, not any later one
+	s.checkStockAlert(ctx, inv, m.StockAfter-m.Delta)
+
+	result.MinStock = inv.MinStock
+	result.BelowMin = m.StockAfter < inv.MinStock
+	before := m.StockAfter - m.Delta
+	switch {
+	case before >= inv.MinStock && m.StockAfter < inv.MinStock:
+		result.Crossed = CrossedDown
+	case before < inv.MinStock && m.StockAfter >= inv.MinStock:
+		result.Crossed = CrossedUp
+	}
+
+	return result, nil
+}
+
+func (s *inventoryService) GetMovements(ctx context.Context, id int, limit int) ([]*StockMovement, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetMovements(ctx, id, limit)
+}
+
+// ImportCSV validates every row up front, then upserts all valid rows in a
+// single transaction. Invalid rows are skipped and reported; they never
+// block the valid ones.
+func (s *inventoryService) ImportCSV(ctx context.Context, r io.Reader) (*ImportReport, error) {
+	rows, err := parseImportCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{Rows: make([]ImportRowResult, len(rows))}
+
+	var valid []*Inventory
+	var validIdx []int
+	seen := make(map[string]int) // slug -> first line it appeared on
+
+	for i, row := range rows {
+		result := ImportRowResult{Row: row.line}
+		if row.item != nil {
+			result.Slug = row.item.Slug
+		}
+
+		if row.err == nil {
+			if first, dup := seen[row.item.Slug]; dup {
+				row.err = fmt.Errorf("duplicate slug, first seen on row %d", first)
+			} else {
+				seen[row.item.Slug] = row.line
+			}
+		}
+
+		if row.err != nil {
+			result.Status = ImportStatusError
+			result.Error = row.err.Error()
+			report.Failed++
+		} else {
+			valid = append(valid, row.item)
+			validIdx = append(validIdx, i)
+		}
+		report.Rows[i] = result
+	}
+
+	if len(valid) == 0 {
+		return report, nil
+	}
+
+	slugs := make([]string, len(valid))
+	for j, item := range valid {
+		slugs[j] = item.Slug
+	}
+	before, err := s.repo.GetBySlugs(ctx, slugs)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.BulkUpsert(ctx, valid)
+	if err != nil {
+		return nil, err
+	}
+	s.auditImport(ctx, before, slugs)
+
+	for j, i := range validIdx {
+		if created[j] {
+			report.Rows[i].Status = ImportStatusCreated
+			report.Created++
+		} else {
+			report.Rows[i].Status = ImportStatusUpdated
+			report.Updated++
+		}
+	}
+
+	return report, nil
+}
+
+// auditImport records the items an import created or updated, comparing
+// them with what was there before.
+func (s *inventoryService) auditImport(ctx context.Context, before []*Inventory, slugs []string) {
+	after, err := s.repo.GetBySlugs(ctx, slugs)
+	if err != nil {
+		log.Printf("inventory: failed to read imported items for the audit log: %v", err)
+		return
+	}
+
+	existing := make(map[string]*Inventory, len(before))
+	for _, inv := range before {
+		existing[inv.Slug] = inv
+	}
+	for _, inv := range after {
+		if old, ok := existing[inv.Slug]; ok {
+			audit.Record(ctx, audit.Inventory, inv.Id, audit.Update, audit.Diff(old, inv))
+		} else {
+			audit.Record(ctx, audit.Inventory, inv.Id, audit.Create, audit.Diff(nil, inv))
+		}
+	}
+}
+
+// ExportCSV writes every item matching the list filters as CSV.
+// Pagination in params is ignored; an export is always the full result set.
+func (s *inventoryService) ExportCSV(ctx context.Context, params ListParams, w io.Writer) error {
+	params.Limit = 0
+	params.Page = 1
+	params.After = ""
+
+	items, _, err := s.ListInventory(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	return writeExportCSV(w, items, utils.Allowed(ctx, utils.PermInventoryCost))
+}
+
+// TakeSnapshot records today's stock level for every item.
+func (s *inventoryService) TakeSnapshot(ctx context.Context) (int, error) {
+	return s.repo.SaveSnapshot(ctx, truncateToDay(time.Now()))
+}
+
+func (s *inventoryService) GetStockHistory(ctx context.Context, id int, start, end time.Time) ([]*StockSnapshot, error) {
+	if id == 0 || end.Before(start) {
+		return nil, ErrInvalidInput
+	}
+
+	// Make sure a missing item is a 404 rather than an empty chart
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetHistory(ctx, id, truncateToDay(start), truncateToDay(end))
+}
+
+func (s *inventoryService) GetStockOn(ctx context.Context, day time.Time) ([]*StockSnapshot, error) {
+	return s.repo.GetSnapshotsOn(ctx, truncateToDay(day))
+}
+
+func truncateToDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// --- Supplier Prices ---
+
+func (s *inventoryService) SetSupplierPrice(ctx context.Context, id int, sp SupplierPrice) (*SupplierPrice, error) {
+	if sp.Supplier == "" || sp.UnitPrice < 0 || sp.LeadTimeDays < 0 {
+		return nil, ErrInvalidInput
+	}
+
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	sp.InventoryId = existing.Id
+	sp.Slug = existing.Slug
+	if err := s.repo.UpsertSupplierPrice(ctx, &sp); err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.Inventory, id, "supplier_price_set", map[string]any{
+		"supplier": sp.Supplier, "unit_price": sp.UnitPrice, "lead_time_days": sp.LeadTimeDays,
+	})
+	return &sp, nil
+}
+
+func (s *inventoryService) RemoveSupplierPrice(ctx context.Context, id int, supplier string) error {
+	if err := s.repo.DeleteSupplierPrice(ctx, id, supplier); err != nil {
+		return err
+	}
+	audit.Record(ctx, audit.Inventory, id, "supplier_price_remove", map[string]any{"supplier": supplier})
+	return nil
+}
+
+func (s *inventoryService) GetSupplierPrices(ctx context.Context, id int) ([]*SupplierPrice, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetSupplierPrices(ctx, id)
+}
+
+// CompareSuppliers returns, for every item with at least one quote, the
+// cheapest and the fastest supplier. Ties on price are broken by lead time
+// and vice versa.
+func (s *inventoryService) CompareSuppliers(ctx context.Context, belowThresholdOnly bool) ([]*SupplierComparison, error) {
+	prices, err := s.repo.ListSupplierPrices(ctx, belowThresholdOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*SupplierComparison
+	byItem := make(map[int]*SupplierComparison)
+
+	for _, sp := range prices {
+		c, ok := byItem[sp.InventoryId]
+		if !ok {
+			c = &SupplierComparison{InventoryId: sp.InventoryId, Slug: sp.Slug}
+			byItem[sp.InventoryId] = c
+			result = append(result, c)
+		}
+		c.Options++
+
+		if c.Cheapest == nil || sp.UnitPrice < c.Cheapest.UnitPrice ||
+			(sp.UnitPrice == c.Cheapest.UnitPrice && sp.LeadTimeDays < c.Cheapest.LeadTimeDays) {
+			c.Cheapest = sp
+		}
+		if c.Fastest == nil || sp.LeadTimeDays < c.Fastest.LeadTimeDays ||
+			(sp.LeadTimeDays == c.Fastest.LeadTimeDays && sp.UnitPrice < c.Fastest.UnitPrice) {
+			c.Fastest = sp
+		}
+	}
+
+	return result, nil
+}
+
+// --- Reservations ---
+
+func (s *inventoryService) Reserve(ctx context.Context, id int, orderId int, quantity int64, ttl time.Duration) (*Reservation, error) {
+	if orderId == 0 || quantity <= 0 || ttl <= 0 {
+		return nil, ErrInvalidInput
+	}
+
+	res := &Reservation{
+		InventoryId: id,
+		OrderId:     orderId,
+		Quantity:    quantity,
+		ExpiresAt:   time.Now().Add(ttl).Unix(),
+	}
+	if err := s.repo.Reserve(ctx, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ReleaseForOrder drops every reservation held by the order. The client is
+// the caller's transaction so the release commits or rolls back with it.
+func (s *inventoryService) ReleaseForOrder(ctx context.Context, client database.SQLClient, orderId int) (int64, error) {
+	return s.repo.ReleaseForOrder(ctx, client, orderId)
+}
+
+// ConsumeForOrder takes what the sold product slugs use out of stock and
+// returns the inventory slugs that ran short. The client is the caller's
+// transaction, which should roll back if anything ran short.
+func (s *inventoryService) ConsumeForOrder(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) ([]string, error) {
+	return s.repo.ConsumeForOrder(ctx, client, orderId, items, userId)
+}
+
+// RestockForOrder puts back what a voided order's items used, through the
+// caller's transaction.
+func (s *inventoryService) RestockForOrder(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) error {
+	return s.repo.RestockForOrder(ctx, client, orderId, items, userId)
+}
+
+// OrderStockChanged follows up on a committed sale or void: products using
+// the ingredients are re-evaluated, and for a sale, items it pushed below
+// their minimum raise alerts. Failures are logged, as the stock change
+// itself already went through.
+func (s *inventoryService) OrderStockChanged(ctx context.Context, items []string, sold bool) {
+	usage, err := s.repo.GetOrderUsage(ctx, items)
+	if err != nil {
+		log.Printf("inventory: order stock follow-up failed for %v: %v", items, err)
+		return
+	}
+
+	slugs := make([]string, len(usage))
+	for i, u := range usage {
+		slugs[i] = u.Slug
+	}
+	s.syncAvailability(ctx, slugs)
+
+	if !sold {
+		return
+	}
+	for _, u := range usage {
+		inv, err := s.repo.GetByID(ctx, u.InventoryId)
+		if err != nil {
+			continue
+		}
+		s.checkStockAlert(ctx, inv, inv.Stock+u.Quantity)
+	}
+}
+
+// ReleaseExpired drops reservations past their expiry.
+func (s *inventoryService) ReleaseExpired(ctx context.Context) (int64, error) {
+	return s.repo.ReleaseExpired(ctx)
+}
+
+// GetProductsUsing lists the products whose recipe consumes this item,
+// i.e. what goes unavailable when it runs out.
+// --- Stocktakes ---
+
+func (s *inventoryService) OpenStocktake(ctx context.Context, note string, userId int) (*StocktakeSession, error) {
+	session := &StocktakeSession{Note: strings.TrimSpace(note), UserId: userId}
+	if err := s.repo.CreateStocktake(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *inventoryService) RecordCount(ctx context.Context, sessionId, inventoryId int, counted int64) error {
+	if inventoryId == 0 || counted < 0 {
+		return ErrInvalidInput
+	}
+	return s.repo.RecordCount(ctx, sessionId, inventoryId, counted)
+}
+
+// CloseStocktake applies the counts to stock and returns the final report.
+func (s *inventoryService) CloseStocktake(ctx context.Context, sessionId int, userId int) (*StocktakeReport, error) {
+	if err := s.repo.CloseStocktake(ctx, sessionId, userId); err != nil {
+		return nil, err
+	}
+
+	report, err := s.GetStocktakeReport(ctx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	expected := make(map[string]int64)
+	for _, line := range report.Lines {
+		if line.Variance != 0 {
+			changed = append(changed, line.Slug)
+			expected[line.Slug] = line.Expected
+		}
+	}
+	s.syncAvailability(ctx, changed)
+
+	if len(changed) > 0 {
+		if items, err := s.repo.GetBySlugs(ctx, changed); err == nil {
+			for _, inv := range items {
+				s.checkStockAlert(ctx, inv, expected[inv.Slug])
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// GetStocktakeReport lists every count in the session. While the session is
+// still open, expected and variance are zero.
+func (s *inventoryService) GetStocktakeReport(ctx context.Context, sessionId int) (*StocktakeReport, error) {
+	session, err := s.repo.GetStocktake(ctx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := s.repo.GetStocktakeCounts(ctx, []int{sessionId})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &StocktakeReport{Session: session, Lines: lines}
+	if session.Status == StocktakeClosed {
+		report.Totals = stocktakeTotals(lines)
+	} else {
+		report.Totals = StocktakeTotals{Items: len(lines), ByTag: map[string]int64{}}
+	}
+
+	return report, nil
+}
+
+// GetShrinkageReport values the variance of every session closed between
+// start and end, per session and overall.
+func (s *inventoryService) GetShrinkageReport(ctx context.Context, start, end time.Time) (*ShrinkageReport, error) {
+	if end.Before(start) {
+		return nil, ErrInvalidInput
+	}
+
+	sessions, err := s.repo.ListStocktakes(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.Id
+	}
+
+	lines, err := s.repo.GetStocktakeCounts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	bySession := make(map[int][]*StocktakeCount)
+	for _, line := range lines {
+		bySession[line.SessionId] = append(bySession[line.SessionId], line)
+	}
+
+	report := &ShrinkageReport{
+		Sessions: make([]*StocktakeReport, 0, len(sessions)),
+		Totals:   stocktakeTotals(lines),
+	}
+	for _, session := range sessions {
+		report.Sessions = append(report.Sessions, &StocktakeReport{
+			Session: session,
+			Totals:  stocktakeTotals(bySession[session.Id]),
+		})
+	}
+
+	return report, nil
+}
+
+func stocktakeTotals(lines []*StocktakeCount) StocktakeTotals {
+	totals := StocktakeTotals{Items: len(lines), ByTag: make(map[string]int64)}
+
+	for _, line := range lines {
+		value := line.Variance * line.UnitCost
+		totals.NetValue += value
+		if line.Variance >= 0 {
+			continue
+		}
+
+		totals.ShrinkageUnits -= line.Variance
+		totals.ShrinkageValue -= value
+		if len(line.Tags) == 0 {
+			totals.ByTag[""] -= value
+		}
+		for _, tag := range line.Tags {
+			totals.ByTag[tag] -= value
+		}
+	}
+
+	return totals
+}
+
+// --- Managed Tags ---
+
+func (s *inventoryService) CreateTag(ctx context.Context, kind, name string) (*ManagedTag, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || (kind != TagKindTag && kind != TagKindLabel) {
+		return nil, ErrInvalidInput
+	}
+
+	tag := &ManagedTag{Kind: kind, Name: name}
+	if err := s.repo.CreateTag(ctx, tag); err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.Tag, tag.Id, audit.Create, map[string]any{"kind": kind, "name": name})
+	return tag, nil
+}
+
+func (s *inventoryService) ListTags(ctx context.Context, kind string) ([]*ManagedTag, error) {
+	if kind != "" && kind != TagKindTag && kind != TagKindLabel {
+		return nil, ErrInvalidInput
+	}
+	return s.repo.ListTags(ctx, kind)
+}
+
+// RenameTag renames a managed tag; every item using the old name is updated.
+func (s *inventoryService) RenameTag(ctx context.Context, id int, name string) (*ManagedTag, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrInvalidInput
+	}
+
+	before, err := s.repo.GetTag(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.RenameTag(ctx, id, name); err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.Tag, id, audit.Update, map[string]any{"name": map[string]any{"from": before.Name, "to": name}})
+	return s.repo.GetTag(ctx, id)
+}
+
+// DeleteTag removes a managed tag and strips it from every item.
+func (s *inventoryService) DeleteTag(ctx context.Context, id int) error {
+	if err := s.repo.DeleteTag(ctx, id); err != nil {
+		return err
+	}
+	audit.Record(ctx, audit.Tag, id, audit.Delete, nil)
+	return nil
+}
+
+func (s *inventoryService) GetProductsUsing(ctx context.Context, id int) ([]*ProductRef, error) {
+	inv, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	return s.repo.UpdateStock(ctx, id, delta)
+	return s.repo.GetProductsUsing(ctx, inv.Slug)
+}
+
+// --- Analytics ---
+
+func (s *inventoryService) GetParDashboard(ctx context.Context) (*ParDashboard, error) {
+	buckets, err := s.repo.GetParLevels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(buckets))
+	for name, items := range buckets {
+		counts[name] = len(items)
+	}
+
+	return &ParDashboard{
+		Counts:      counts,
+		Critical:    buckets[ParCritical],
+		Low:         buckets[ParLow],
+		Ok:          buckets[ParOk],
+		Overstocked: buckets[ParOverstocked],
+	}, nil
+}
+
+// ForecastConsumption looks back the given number of days, computes each
+// ingredient's average daily usage from recipes of sold products, and
+// projects days of stock remaining. Results are sorted soonest-to-run-out first.
+func (s *inventoryService) ForecastConsumption(ctx context.Context, days int) ([]*Forecast, error) {
+	if days <= 0 {
+		return nil, ErrInvalidInput
+	}
+
+	end := time.Now()
+	start := end.AddDate(0, 0, -days)
+
+	usage, err := s.repo.GetConsumption(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	items, _, err := s.repo.List(ctx, ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	forecasts := make([]*Forecast, 0, len(items))
+	for _, inv := range items {
+		f := &Forecast{
+			InventoryId: inv.Id,
+			Slug:        inv.Slug,
+			Name:        inv.Name,
+			Stock:       inv.Stock,
+			Consumed:    usage[inv.Slug],
+		}
+		f.DailyUsage = float64(f.Consumed) / float64(days)
+		if f.DailyUsage > 0 {
+			remaining := float64(max(inv.Stock, 0)) / f.DailyUsage
+			f.DaysRemaining = &remaining
+		}
+		forecasts = append(forecasts, f)
+	}
+
+	// Items that will run out first on top; items with no usage last
+	sort.SliceStable(forecasts, func(i, j int) bool {
+		a, b := forecasts[i].DaysRemaining, forecasts[j].DaysRemaining
+		switch {
+		case a == nil:
+			return false
+		case b == nil:
+			return true
+		default:
+			return *a < *b
+		}
+	})
+
+	return forecasts, nil
+}
+
+// GetConsumptionReport replays orders in the range through product recipes
+// and lines the result up against recorded stock movements per item.
+// Items with neither theoretical nor recorded usage are omitted.
+func (s *inventoryService) GetConsumptionReport(ctx context.Context, start, end time.Time) ([]*ConsumptionLine, error) {
+	if end.Before(start) {
+		return nil, ErrInvalidInput
+	}
+
+	usage, err := s.repo.GetConsumption(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	outflow, err := s.repo.GetOutflowByReason(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	items, _, err := s.repo.List(ctx, ListOptions{SortBy: "slug"})
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []*ConsumptionLine
+	for _, inv := range items {
+		byReason := outflow[inv.Slug]
+		if usage[inv.Slug] == 0 && len(byReason) == 0 {
+			continue
+		}
+
+		line := &ConsumptionLine{
+			InventoryId: inv.Id,
+			Slug:        inv.Slug,
+			Name:        inv.Name,
+			Theoretical: usage[inv.Slug],
+			ByReason:    byReason,
+		}
+		for reason, qty := range byReason {
+			line.RecordedOut += qty
+			if _, ok := explainedLossReasons[reason]; ok {
+				line.Explained += qty
+			}
+		}
+		line.Variance = line.RecordedOut - line.Explained - line.Theoretical
+
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// GetTurnoverReport computes turnover for every item over the range, slowest
+// movers first. Consumption comes from orders x recipes (as in the
+// consumption report); average stock from daily snapshots, falling back to
+// the current stock for items that have not been snapshotted yet.
+func (s *inventoryService) GetTurnoverReport(ctx context.Context, start, end time.Time) ([]*TurnoverLine, error) {
+	if end.Before(start) {
+		return nil, ErrInvalidInput
+	}
+
+	usage, err := s.repo.GetConsumption(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	avgStock, err := s.repo.GetAverageStock(ctx, truncateToDay(start), truncateToDay(end))
+	if err != nil {
+		return nil, err
+	}
+
+	items, _, err := s.repo.List(ctx, ListOptions{SortBy: "slug"})
+	if err != nil {
+		return nil, err
+	}
+
+	days := math.Max(end.Sub(start).Hours()/24, 1)
+
+	lines := make([]*TurnoverLine, 0, len(items))
+	for _, inv := range items {
+		avg, ok := avgStock[inv.Slug]
+		if !ok {
+			avg = float64(inv.Stock)
+		}
+
+		line := &TurnoverLine{
+			InventoryId:  inv.Id,
+			Slug:         inv.Slug,
+			Name:         inv.Name,
+			Consumed:     usage[inv.Slug],
+			AverageStock: avg,
+			StockValue:   inv.Stock * inv.UnitCost,
+		}
+		if avg > 0 {
+			line.Turnover = float64(line.Consumed) / avg
+		}
+		if line.Consumed > 0 {
+			cover := avg / (float64(line.Consumed) / days)
+			line.DaysOfCover = &cover
+		}
+
+		lines = append(lines, line)
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Turnover < lines[j].Turnover
+	})
+
+	return lines, nil
 }
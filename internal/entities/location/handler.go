@@ -0,0 +1,145 @@
+package location
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/iteranya/practicing-go/internal/utils"
+)
+
+type LocationHandler struct {
+	service LocationService
+}
+
+func NewLocationHandler(service LocationService) *LocationHandler {
+	return &LocationHandler{service: service}
+}
+
+func (h *LocationHandler) Routes() []utils.Route {
+	return []utils.Route{
+		{Pattern: "GET /locations", Perm: utils.PermLocationRead, Handler: h.HandleList},
+		{Pattern: "GET /locations/{id}", Perm: utils.PermLocationRead, Handler: h.HandleGet}, // supports id or slug
+		{Pattern: "POST /locations", Perm: utils.PermLocationCreate, Handler: h.HandleCreate},
+		{Pattern: "PUT /locations/{id}", Perm: utils.PermLocationUpdate, Handler: h.HandleUpdate},
+		{Pattern: "DELETE /locations/{id}", Perm: utils.PermLocationDelete, Handler: h.HandleDelete},
+	}
+}
+
+// CREATE
+func (h *LocationHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	var input Location
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.service.CreateLocation(r.Context(), input)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, created)
+}
+
+// GET (ID or Slug)
+func (h *LocationHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	param := r.PathValue("id")
+
+	var result *Location
+	var err error
+
+	if id, convErr := strconv.Atoi(param); convErr == nil {
+		result, err = h.service.GetLocation(r.Context(), id)
+	} else {
+		result, err = h.service.GetLocation(r.Context(), param)
+	}
+
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, result)
+}
+
+// LIST
+func (h *LocationHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	locations, err := h.service.ListLocations(r.Context())
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, locations)
+}
+
+// UPDATE
+func (h *LocationHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var input Location
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UpdateLocation(r.Context(), id, input); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+}
+
+// DELETE
+func (h *LocationHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteLocation(r.Context(), id); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// --- Helpers ---
+
+func (h *LocationHandler) respondWithJSON(w http.ResponseWriter, code int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if payload != nil {
+		json.NewEncoder(w).Encode(payload)
+	}
+}
+
+func (h *LocationHandler) respondWithError(w http.ResponseWriter, err error) {
+	var statusCode int
+	switch {
+	case errors.Is(err, ErrLocationNotFound):
+		statusCode = http.StatusNotFound
+	case errors.Is(err, ErrInvalidLocationInput):
+		statusCode = http.StatusBadRequest
+	case errors.Is(err, ErrDuplicateLocationSlug), errors.Is(err, ErrLocationInUse):
+		statusCode = http.StatusConflict
+	default:
+		statusCode = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,12 @@
+package location
+
+// Location is a store or other site orders are rung up at. Users are
+// assigned to locations to limit which orders they can see.
+type Location struct {
+	Id      int
+	Slug    string
+	Name    string
+	Address string
+	Created int64 // Unix timestamps
+	Updated int64
+}
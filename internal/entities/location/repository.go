@@ -0,0 +1,163 @@
+package location
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
+)
+
+var (
+	ErrLocationNotFound      = errors.New("location not found")
+	ErrDuplicateLocationSlug = errors.New("location slug already exists")
+	ErrInvalidLocationInput  = errors.New("invalid location input")
+	ErrLocationInUse         = errors.New("location still has orders")
+)
+
+type LocationRepository interface {
+	Create(ctx context.Context, loc *Location) error
+	Import(ctx context.Context, client database.SQLClient, loc *Location) (bool, error)
+	GetByID(ctx context.Context, id int) (*Location, error)
+	GetBySlug(ctx context.Context, slug string) (*Location, error)
+	Update(ctx context.Context, loc *Location) error
+	Delete(ctx context.Context, id int) error
+	List(ctx context.Context) ([]*Location, error)
+}
+
+type locationRepository struct {
+	db      *database.DB
+	dialect database.Dialect
+}
+
+func NewLocationRepository(db *sql.DB) LocationRepository {
+	return &locationRepository{db: database.Observe(db, "locations"), dialect: database.DialectOf(db)}
+}
+
+// locationColumns is the SELECT list matched by scanLocation.
+func (r *locationRepository) locationColumns() string {
+	return `id, slug, name, address, ` + r.dialect.Epoch("created_at") + `, ` + r.dialect.Epoch("updated_at")
+}
+
+func scanLocation(scanner database.Scanner) (*Location, error) {
+	loc := &Location{}
+	if err := scanner.Scan(&loc.Id, &loc.Slug, &loc.Name, &loc.Address, &loc.Created, &loc.Updated); err != nil {
+		return nil, fmt.Errorf("failed to scan location: %w", err)
+	}
+	return loc, nil
+}
+
+func (r *locationRepository) Create(ctx context.Context, loc *Location) error {
+	if loc.Slug == "" || loc.Name == "" {
+		return ErrInvalidLocationInput
+	}
+
+	query := `
+		INSERT INTO locations (slug, name, address, store_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+	`
+
+	now := time.Now()
+	args := []any{loc.Slug, loc.Name, loc.Address, database.StoreOf(ctx), now}
+	err := r.dialect.InsertReturning(ctx, r.db, "locations", query, "id", args, &loc.Id)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return ErrDuplicateLocationSlug
+		}
+		return fmt.Errorf("failed to create location: %w", err)
+	}
+
+	loc.Created, loc.Updated = now.Unix(), now.Unix()
+	return nil
+}
+
+// Import inserts a location read back from a backup as it was, id and
+// timestamps included. It reports false, writing nothing, if a location
+// with its id or slug is already there.
+func (r *locationRepository) Import(ctx context.Context, client database.SQLClient, loc *Location) (bool, error) {
+	client = r.db.On(client)
+	exists, err := database.Exists(ctx, client, "locations", "id = $1 OR (slug = $2 AND store_id = $3)", loc.Id, loc.Slug, database.StoreOf(ctx))
+	if err != nil || exists {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO locations (id, slug, name, address, store_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	args := []any{loc.Id, loc.Slug, loc.Name, loc.Address, database.StoreOf(ctx), database.FromUnix(loc.Created), database.FromUnix(loc.Updated)}
+	if _, err := client.ExecContext(ctx, query, args...); err != nil {
+		return false, fmt.Errorf("failed to import location: %w", err)
+	}
+	if err := r.dialect.ResetSequence(ctx, client, "locations"); err != nil {
+		return false, fmt.Errorf("failed to reset location ids: %w", err)
+	}
+	return true, nil
+}
+
+func (r *locationRepository) GetByID(ctx context.Context, id int) (*Location, error) {
+	query := `SELECT ` + r.locationColumns() + ` FROM locations WHERE id = $1 AND store_id = $2`
+
+	return database.Get(ctx, r.db, scanLocation, ErrLocationNotFound, query, id, database.StoreOf(ctx))
+}
+
+func (r *locationRepository) GetBySlug(ctx context.Context, slug string) (*Location, error) {
+	query := `SELECT ` + r.locationColumns() + ` FROM locations WHERE slug = $1 AND store_id = $2`
+
+	return database.Get(ctx, r.db, scanLocation, ErrLocationNotFound, query, slug, database.StoreOf(ctx))
+}
+
+func (r *locationRepository) Update(ctx context.Context, loc *Location) error {
+	if loc.Id == 0 {
+		return ErrInvalidLocationInput
+	}
+
+	query := `UPDATE locations SET slug = $1, name = $2, address = $3, updated_at = NOW() WHERE id = $4 AND store_id = $5`
+
+	ok, err := database.ExecAffected(ctx, r.db, query, loc.Slug, loc.Name, loc.Address, loc.Id, database.StoreOf(ctx))
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return ErrDuplicateLocationSlug
+		}
+		return fmt.Errorf("failed to update location: %w", err)
+	}
+	if !ok {
+		return ErrLocationNotFound
+	}
+
+	return nil
+}
+
+// Delete removes a location and its user assignments. Locations that orders
+// were rung up at are kept for reporting (ON DELETE RESTRICT).
+func (r *locationRepository) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM locations WHERE id = $1 AND store_id = $2`
+
+	ok, err := database.ExecAffected(ctx, r.db, query, id, database.StoreOf(ctx))
+	if err != nil {
+		if database.IsForeignKeyViolation(err) {
+			return ErrLocationInUse
+		}
+		return fmt.Errorf("failed to delete location: %w", err)
+	}
+	if !ok {
+		return ErrLocationNotFound
+	}
+
+	return nil
+}
+
+func (r *locationRepository) List(ctx context.Context) ([]*Location, error) {
+	query := `SELECT ` + r.locationColumns() + ` FROM locations WHERE store_id = $1 ORDER BY name ASC`
+
+	locations, err := database.Select(ctx, r.db, scanLocation, query, database.StoreOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list locations: %w", err)
+	}
+	if locations == nil {
+		locations = []*Location{}
+	}
+	return locations, nil
+}
@@ -0,0 +1,91 @@
+package location
+
+import (
+	"context"
+	"strings"
+
+	"github.com/iteranya/practicing-go/internal/audit"
+)
+
+type LocationService interface {
+	CreateLocation(ctx context.Context, loc Location) (*Location, error)
+	GetLocation(ctx context.Context, idOrSlug any) (*Location, error)
+	UpdateLocation(ctx context.Context, id int, loc Location) error
+	DeleteLocation(ctx context.Context, id int) error
+	ListLocations(ctx context.Context) ([]*Location, error)
+}
+
+type locationService struct {
+	repo LocationRepository
+}
+
+func NewLocationService(repo LocationRepository) LocationService {
+	return &locationService{repo: repo}
+}
+
+func (s *locationService) CreateLocation(ctx context.Context, loc Location) (*Location, error) {
+	loc.Slug = strings.ToLower(strings.TrimSpace(loc.Slug))
+	if loc.Slug == "" || strings.TrimSpace(loc.Name) == "" {
+		return nil, ErrInvalidLocationInput
+	}
+
+	if err := s.repo.Create(ctx, &loc); err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.Location, loc.Id, audit.Create, audit.Diff(nil, &loc))
+	return &loc, nil
+}
+
+func (s *locationService) GetLocation(ctx context.Context, idOrSlug any) (*Location, error) {
+	switch v := idOrSlug.(type) {
+	case int:
+		return s.repo.GetByID(ctx, v)
+	case string:
+		return s.repo.GetBySlug(ctx, v)
+	default:
+		return nil, ErrInvalidLocationInput
+	}
+}
+
+func (s *locationService) UpdateLocation(ctx context.Context, id int, loc Location) error {
+	if id == 0 {
+		return ErrInvalidLocationInput
+	}
+
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	before := *existing
+
+	// Empty fields keep their current value
+	if slug := strings.ToLower(strings.TrimSpace(loc.Slug)); slug != "" {
+		existing.Slug = slug
+	}
+	if loc.Name != "" {
+		existing.Name = loc.Name
+	}
+	if loc.Address != "" {
+		existing.Address = loc.Address
+	}
+
+	if err := s.repo.Update(ctx, existing); err != nil {
+		return err
+	}
+
+	audit.Record(ctx, audit.Location, id, audit.Update, audit.Diff(&before, existing))
+	return nil
+}
+
+func (s *locationService) DeleteLocation(ctx context.Context, id int) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	audit.Record(ctx, audit.Location, id, audit.Delete, nil)
+	return nil
+}
+
+func (s *locationService) ListLocations(ctx context.Context) ([]*Location, error) {
+	return s.repo.List(ctx)
+}
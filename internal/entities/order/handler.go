@@ -3,32 +3,41 @@ package order
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
+
+	"github.com/iteranya/practicing-go/internal/utils"
 )
 
 type OrderHandler struct {
 	service OrderService
+	events  *EventHub
 }
 
-func NewOrderHandler(service OrderService) *OrderHandler {
-	return &OrderHandler{service: service}
+func NewOrderHandler(service OrderService, events *EventHub) *OrderHandler {
+	return &OrderHandler{service: service, events: events}
 }
 
-func (h *OrderHandler) RegisterRoutes(mux *http.ServeMux) {
-	// Standard CRUD
-	mux.HandleFunc("POST /orders", h.HandleCreate)
-	mux.HandleFunc("GET /orders", h.HandleList)
-	mux.HandleFunc("GET /orders/{id}", h.HandleGet)
-
-	// Specific Actions
-	mux.HandleFunc("PATCH /orders/{id}/pay", h.HandlePayment)
-	mux.HandleFunc("GET /orders/clerk/{id}", h.HandleClerkHistory)
-
-	// Analytics
-	mux.HandleFunc("GET /orders/metrics", h.HandleMetrics)
-	mux.HandleFunc("GET /orders/metrics/clerk/{id}", h.HandleClerkMetrics)
+func (h *OrderHandler) Routes() []utils.Route {
+	return []utils.Route{
+		// Standard CRUD
+		{Pattern: "POST /orders", Perm: utils.PermOrderCreate, Handler: h.HandleCreate},
+		{Pattern: "GET /orders", Perm: utils.PermOrderRead, Handler: h.HandleList},
+		{Pattern: "GET /orders/{id}", Perm: utils.PermOrderRead, Handler: h.HandleGet},
+		{Pattern: "GET /orders/events", Perm: utils.PermOrderRead, Handler: h.HandleEvents}, // SSE
+
+		// Specific Actions
+		{Pattern: "PATCH /orders/{id}/pay", Perm: utils.PermOrderUpdate, Handler: h.HandlePayment},
+		{Pattern: "POST /orders/{id}/void", Perm: utils.PermOrderDelete, Handler: h.HandleVoid},
+		{Pattern: "GET /orders/clerk/{id}", Perm: utils.PermOrderRead, Handler: h.HandleClerkHistory},
+
+		// Analytics
+		{Pattern: "GET /orders/metrics", Perm: utils.PermOrderRead, Handler: h.HandleMetrics},
+		{Pattern: "GET /orders/metrics/clerk/{id}", Perm: utils.PermOrderRead, Handler: h.HandleClerkMetrics},
+	}
 }
 
 // CREATE
@@ -80,6 +89,7 @@ func (h *OrderHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 	}
 
 	clerkId, _ := strconv.Atoi(query.Get("clerk_id"))
+	locationId, _ := strconv.Atoi(query.Get("location_id"))
 
 	// Parse Dates
 	var start, end *time.Time
@@ -96,13 +106,19 @@ func (h *OrderHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 	maxTotal, _ := strconv.ParseInt(query.Get("max_total"), 10, 64)
 
 	params := OrderServiceListParams{
-		ClerkId:   clerkId,
-		StartDate: start,
-		EndDate:   end,
-		MinTotal:  minTotal,
-		MaxTotal:  maxTotal,
-		Limit:     limit,
-		Page:      page,
+		Archived:   archived(r),
+		ClerkId:    clerkId,
+		LocationId: locationId,
+		StartDate:  start,
+		EndDate:    end,
+		MinTotal:   minTotal,
+		MaxTotal:   maxTotal,
+		Period:     utils.PeriodFromQuery(query),
+		SortBy:     query.Get("sort"),
+		SortOrder:  query.Get("order"),
+		Limit:      limit,
+		Page:       page,
+		After:      query.Get("after"), // X-Next-Cursor of the page before
 	}
 
 	orders, err := h.service.ListOrders(r.Context(), params)
@@ -111,6 +127,10 @@ func (h *OrderHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A full page may have more after it
+	if len(orders) == limit {
+		w.Header().Set("X-Next-Cursor", nextCursor(orders[len(orders)-1], params.SortBy, params.SortOrder))
+	}
 	h.respondWithJSON(w, http.StatusOK, orders)
 }
 
@@ -123,16 +143,17 @@ func (h *OrderHandler) HandlePayment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Expecting JSON: {"paid": 50000}
+	// Expecting JSON: {"paid": 50000, "revision": 1}
 	var body struct {
-		Paid int64 `json:"paid"`
+		Paid     int64 `json:"paid"`
+		Revision int   `json:"revision"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
 		return
 	}
 
-	err = h.service.ProcessPayment(r.Context(), id, body.Paid)
+	err = h.service.ProcessPayment(r.Context(), id, body.Paid, body.Revision)
 	if err != nil {
 		h.respondWithError(w, err)
 		return
@@ -141,6 +162,23 @@ func (h *OrderHandler) HandlePayment(w http.ResponseWriter, r *http.Request) {
 	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "payment updated"})
 }
 
+// VOID
+func (h *OrderHandler) HandleVoid(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.VoidOrder(r.Context(), id); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "order voided"})
+}
+
 // CLERK HISTORY
 func (h *OrderHandler) HandleClerkHistory(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
@@ -159,11 +197,63 @@ func (h *OrderHandler) HandleClerkHistory(w http.ResponseWriter, r *http.Request
 	h.respondWithJSON(w, http.StatusOK, orders)
 }
 
+// EVENTS (Server-Sent Events)
+// Streams orders being created, paid and voided, from this instance and any
+// others sharing the change feed. Each is an SSE event named after its kind
+// with the JSON event as data; the caller sees only the orders they could
+// list.
+func (h *OrderHandler) HandleEvents(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	// The server's WriteTimeout would otherwise cut the stream off
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	utils.LiftRequestTimeout(r.Context())
+
+	events, cancel := h.events.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case event := <-events:
+			if !eventVisible(r.Context(), event) {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Kind, data); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
 // METRICS (GLOBAL)
 func (h *OrderHandler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
 	start, end := h.parseDateRange(r)
 
-	stats, err := h.service.GetSalesStats(r.Context(), start, end)
+	stats, err := h.service.GetSalesStats(r.Context(), start, end, archived(r))
 	if err != nil {
 		h.respondWithError(w, err)
 		return
@@ -183,7 +273,7 @@ func (h *OrderHandler) HandleClerkMetrics(w http.ResponseWriter, r *http.Request
 
 	start, end := h.parseDateRange(r)
 
-	total, err := h.service.GetClerkPerformance(r.Context(), id, start, end)
+	total, err := h.service.GetClerkPerformance(r.Context(), id, start, end, archived(r))
 	if err != nil {
 		h.respondWithError(w, err)
 		return
@@ -198,6 +288,13 @@ func (h *OrderHandler) HandleClerkMetrics(w http.ResponseWriter, r *http.Request
 
 // --- Helpers ---
 
+// archived reports whether the request opted into archived orders with
+// ?archived=true.
+func archived(r *http.Request) bool {
+	b, _ := strconv.ParseBool(r.URL.Query().Get("archived"))
+	return b
+}
+
 func (h *OrderHandler) parseDateRange(r *http.Request) (time.Time, time.Time) {
 	query := r.URL.Query()
 	now := time.Now()
@@ -237,6 +334,10 @@ func (h *OrderHandler) respondWithError(w http.ResponseWriter, err error) {
 		statusCode = http.StatusBadRequest
 	case errors.Is(err, ErrInvalidPayment):
 		statusCode = http.StatusBadRequest
+	case errors.Is(err, ErrOrderVoided), errors.Is(err, ErrOutOfStock), errors.Is(err, ErrOrderConflict):
+		statusCode = http.StatusConflict
+	case errors.Is(err, ErrLocationForbidden), errors.Is(err, utils.ErrNotOwner), errors.Is(err, ErrVoidWindowClosed):
+		statusCode = http.StatusForbidden
 	default:
 		statusCode = http.StatusInternalServerError
 	}
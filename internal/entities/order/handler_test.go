@@ -0,0 +1,110 @@
+package order_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/iteranya/practicing-go/internal/entities/order"
+	"github.com/iteranya/practicing-go/internal/testutil/mock"
+	"github.com/iteranya/practicing-go/internal/utils"
+)
+
+// serve sends a request to the handler's routes the way the server mounts
+// them, minus the permission checks.
+func serve(svc order.OrderService, method, path, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	for _, rt := range order.NewOrderHandler(svc, nil).Routes() {
+		mux.HandleFunc(rt.Pattern, rt.Handler)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestHandleCreate(t *testing.T) {
+	svc := &mock.OrderService{
+		CreateOrderFunc: func(ctx context.Context, o order.Order) (*order.Order, error) {
+			o.Id, o.Change = 7, o.Paid-o.Total
+			return &o, nil
+		},
+	}
+	w := serve(svc, "POST", "/orders", `{"items": ["espresso"], "total": 2500, "paid": 5000}`)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	calls := svc.CreateOrderCalls()
+	if len(calls) != 1 {
+		t.Fatalf("CreateOrder called %d times, want once", len(calls))
+	}
+	if in := calls[0].OrderMoqParam; !slices.Equal(in.Items, []string{"espresso"}) || in.Total != 2500 || in.Paid != 5000 {
+		t.Errorf("CreateOrder got %+v", in)
+	}
+	var created order.Order
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Id != 7 || created.Change != 2500 {
+		t.Errorf("answered %+v, want the created order", created)
+	}
+}
+
+func TestHandleCreateErrors(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{order.ErrInvalidOrderInput, http.StatusBadRequest},
+		{order.ErrOutOfStock, http.StatusConflict},
+		{order.ErrLocationForbidden, http.StatusForbidden},
+		{utils.ErrNotOwner, http.StatusForbidden},
+		{errors.New("connection reset"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			svc := &mock.OrderService{
+				CreateOrderFunc: func(ctx context.Context, o order.Order) (*order.Order, error) {
+					return nil, tt.err
+				},
+			}
+			if w := serve(svc, "POST", "/orders", `{"items": ["espresso"]}`); w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleCreateBadJSON(t *testing.T) {
+	// No CreateOrderFunc: calling the service would panic
+	w := serve(&mock.OrderService{}, "POST", "/orders", `{"items": `)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandlePayment(t *testing.T) {
+	svc := &mock.OrderService{
+		ProcessPaymentFunc: func(ctx context.Context, id int, amountPaid int64, revision int) error {
+			return order.ErrOrderConflict
+		},
+	}
+	w := serve(svc, "PATCH", "/orders/12/pay", `{"paid": 3000, "revision": 2}`)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	calls := svc.ProcessPaymentCalls()
+	if len(calls) != 1 || calls[0].ID != 12 || calls[0].AmountPaid != 3000 || calls[0].Revision != 2 {
+		t.Errorf("ProcessPayment calls = %+v, want order 12 paid 3000 at revision 2", calls)
+	}
+
+	if w := serve(&mock.OrderService{}, "PATCH", "/orders/twelve/pay", `{"paid": 3000}`); w.Code != http.StatusBadRequest {
+		t.Errorf("status for a bad id = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -1,12 +1,70 @@
 package order
 
+import "github.com/iteranya/practicing-go/internal/utils"
+
 type Order struct {
-	Id      int
-	Items   []string // Slug of Products Bought
-	ClerkId int      // User ID of the Cashier
-	Total   int64    // Total Price
-	Paid    int64    // Paid
-	Change  int64    // Change
-	Created int64    // Created
-	Custom  map[string]any
+	Id         int
+	Items      []string // Slug of Products Bought
+	ClerkId    int      // User ID of the Cashier
+	LocationId int      // Store it was rung up at, 0 for orders from before locations
+	Total      int64    // Total Price
+	Paid       int64    // Paid
+	Change     int64    // Change
+	Status     string   // open, void
+	Created    int64    // Unix timestamps
+	Updated    int64
+	Custom     map[string]any
+
+	// Revision goes up with every change. Payments send the one they read
+	// and fail with ErrOrderConflict if it has moved on since.
+	Revision int
+}
+
+// OwnerID makes orders utils.Owned by the clerk who rang them up.
+func (o *Order) OwnerID() int {
+	return o.ClerkId
+}
+
+// LocationID makes orders utils.Located at the store they were rung up at.
+func (o *Order) LocationID() int {
+	return o.LocationId
+}
+
+// Order statuses
+const (
+	StatusOpen = "open"
+	StatusVoid = "void"
+)
+
+// Audit log actions of orders, besides audit.Create.
+const (
+	ActionPayment = "payment"
+	ActionVoid    = "void"
+)
+
+// OrderEvent is raised whenever an order is rung up, paid or voided, for
+// live views such as the /orders/events stream.
+type OrderEvent struct {
+	Kind       string `json:"kind"` // EventCreated, EventPaid or EventVoided
+	OrderId    int    `json:"order_id"`
+	ClerkId    int    `json:"clerk_id"`
+	LocationId int    `json:"location_id"`
+	Total      int64  `json:"total"`
+	Paid       int64  `json:"paid"`
+	Status     string `json:"status"`
+	Store      int    `json:"store"` // Only streamed to callers in this store
+	At         int64  `json:"at"`    // Unix timestamp
+}
+
+const (
+	EventCreated = "created"
+	EventPaid    = "paid"
+	EventVoided  = "voided"
+)
+
+// EventHub fans order events out to live subscribers (the SSE stream).
+type EventHub = utils.Hub[OrderEvent]
+
+func NewEventHub() *EventHub {
+	return utils.NewHub[OrderEvent]()
 }
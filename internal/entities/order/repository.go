@@ -6,52 +6,80 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
 )
 
 var (
 	ErrOrderNotFound     = errors.New("order not found")
 	ErrInvalidOrderInput = errors.New("invalid order input")
 	ErrInvalidPayment    = errors.New("invalid payment amount")
+	ErrOrderVoided       = errors.New("order is void")
+	ErrLocationForbidden = errors.New("not assigned to this location")
+	ErrOutOfStock        = errors.New("not enough stock")
+	ErrOrderConflict     = errors.New("order was changed since it was read")
 )
 
 type OrderRepository interface {
-	Create(ctx context.Context, order *Order) error
+	Create(ctx context.Context, client database.SQLClient, order *Order) error
+	Import(ctx context.Context, client database.SQLClient, orders []*Order) ([]bool, error)
 	GetByID(ctx context.Context, id int) (*Order, error)
 	Update(ctx context.Context, order *Order) error
 	Delete(ctx context.Context, id int) error
-	List(ctx context.Context, opts OrderListOptions) ([]*Order, error)
-	GetByClerk(ctx context.Context, clerkId int) ([]*Order, error)
+	List(ctx context.Context, opts OrderListOptions) ([]*Order, int, error)
 	GetByDateRange(ctx context.Context, start, end time.Time) ([]*Order, error)
-	UpdatePayment(ctx context.Context, id int, paid int64) error
-	GetTotalSales(ctx context.Context, start, end time.Time) (int64, error)
-	GetClerkSales(ctx context.Context, clerkId int, start, end time.Time) (int64, error)
-	GetAverageOrderValue(ctx context.Context, start, end time.Time) (float64, error)
+	UpdatePayment(ctx context.Context, id int, paid int64, revision int) error
+	SetStatus(ctx context.Context, client database.SQLClient, id int, status string) error
+	GetTotalSales(ctx context.Context, start, end time.Time, clerkId int, locationIds []int, archived bool) (int64, error)
+	GetClerkSales(ctx context.Context, clerkId int, start, end time.Time, locationIds []int, archived bool) (int64, error)
+	GetAverageOrderValue(ctx context.Context, start, end time.Time, clerkId int, locationIds []int, archived bool) (float64, error)
 	Count(ctx context.Context) (int, error)
 	GetRecentOrders(ctx context.Context, limit int) ([]*Order, error)
+	Archive(ctx context.Context, client database.SQLClient, before time.Time, limit int) (int, error)
 }
 
 type OrderListOptions struct {
-	ClerkId   int
-	MinTotal  int64
-	MaxTotal  int64
-	StartDate *time.Time
-	EndDate   *time.Time
-	Limit     int
-	Offset    int
-	SortBy    string // id, total, created_at
-	SortOrder string // asc, desc
+	Archived    bool // List the archive instead
+	ClerkId     int
+	LocationIds []int // nil means every location; 0 in it is orders without one
+	MinTotal    int64
+	MaxTotal    int64
+	StartDate   *time.Time
+	EndDate     *time.Time
+	Period      database.Period
+	Limit       int
+	Offset      int
+	After       string // Cursor from the page before, in place of Offset
+	SortBy      string // created_at (default), updated_at, total, id
+	SortOrder   string // desc (default), asc
+	Total       bool   // Also count every match, for List to return
 }
 
+// orderColumns is the SELECT list matched by scanOrder.
+const orderColumns = `id, items, clerk_id, COALESCE(location_id, 0), total, paid, "change", status, custom, created_at, updated_at, revision`
+
+// archiveColumns are copied as they are from orders to orders_archive.
+const archiveColumns = `id, items, clerk_id, location_id, total, paid, "change", status, custom, store_id, created_at, updated_at, revision`
+
+// reportColumns are what the aggregates read, from orders alone or with
+// the archive (see ordersFrom).
+const reportColumns = `clerk_id, location_id, total, status, store_id, created_at`
+
 type orderRepository struct {
-	db *sql.DB
+	db      *database.DB
+	dialect database.Dialect
 }
 
 func NewOrderRepository(db *sql.DB) OrderRepository {
-	return &orderRepository{db: db}
+	return &orderRepository{db: database.Observe(db, "orders"), dialect: database.DialectOf(db)}
 }
 
-func (r *orderRepository) Create(ctx context.Context, order *Order) error {
+// Create runs through the given client so the order can be inserted in the
+// same transaction that takes its items out of stock.
+func (r *orderRepository) Create(ctx context.Context, client database.SQLClient, order *Order) error {
+	client = r.db.On(client)
 	if len(order.Items) == 0 || order.ClerkId == 0 {
 		return ErrInvalidOrderInput
 	}
@@ -59,6 +87,9 @@ func (r *orderRepository) Create(ctx context.Context, order *Order) error {
 	if order.Paid < 0 || order.Total < 0 {
 		return ErrInvalidPayment
 	}
+	if err := r.checkStore(ctx, client, order.ClerkId, order.LocationId); err != nil {
+		return err
+	}
 
 	// Calculate change if not already set
 	if order.Change == 0 && order.Paid > 0 {
@@ -70,62 +101,118 @@ func (r *orderRepository) Create(ctx context.Context, order *Order) error {
 		return fmt.Errorf("failed to marshal items: %w", err)
 	}
 
-	customJSON, err := json.Marshal(order.Custom)
+	customJSON, err := database.MarshalCustom("order", order.Custom)
 	if err != nil {
 		return fmt.Errorf("failed to marshal custom data: %w", err)
 	}
 
 	query := `
-		INSERT INTO orders (items, clerk_id, total, paid, change, custom, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id
+		INSERT INTO orders (items, clerk_id, location_id, total, paid, "change", status, custom, store_id, created_at, updated_at)
+		VALUES ($1, $2, NULLIF($3, 0), $4, $5, $6, $7, $8, $9, $10, $10)
 	`
+	now := time.Now()
+	args := []any{
+		itemsJSON, order.ClerkId, order.LocationId, order.Total, order.Paid, order.Change, order.Status, customJSON, database.StoreOf(ctx), now,
+	}
 
-	err = r.db.QueryRowContext(
-		ctx, query,
-		itemsJSON, order.ClerkId, order.Total, order.Paid, order.Change, customJSON, time.Now(),
-	).Scan(&order.Id)
+	err = r.dialect.InsertReturning(ctx, client, "orders", query, "id, revision", args, &order.Id, &order.Revision)
 
 	if err != nil {
 		return fmt.Errorf("failed to create order: %w", err)
 	}
 
+	order.Created, order.Updated = now.Unix(), now.Unix()
 	return nil
 }
 
-func (r *orderRepository) GetByID(ctx context.Context, id int) (*Order, error) {
-	// 1. Add created_at to the SELECT query
-	query := `
-        SELECT id, items, clerk_id, total, paid, change, custom, created_at
-        FROM orders
-        WHERE id = $1
-    `
+// Import inserts orders read back from a backup as they were, ids,
+// revisions and timestamps included, in one bulk insert; stock is left
+// alone. It reports per order whether it went in; false means one with its
+// id is already there, and it was left out. Their clerks and locations must
+// be there first.
+func (r *orderRepository) Import(ctx context.Context, client database.SQLClient, orders []*Order) ([]bool, error) {
+	client = r.db.On(client)
 
-	order := &Order{}
-	var itemsJSON, customJSON []byte
-	var createdAt time.Time // 2. Create a temp variable for the timestamp
-
-	// 3. Scan into the temp variable
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&order.Id, &itemsJSON, &order.ClerkId,
-		&order.Total, &order.Paid, &order.Change, &customJSON, &createdAt,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, ErrOrderNotFound
+	ids := make([]int, len(orders))
+	for i, o := range orders {
+		ids[i] = o.Id
 	}
+	taken, err := database.Select(ctx, client, database.Value[int], `SELECT id FROM orders WHERE `+r.dialect.AnyOf("id", "$1"), r.dialect.Array(ids))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get order: %w", err)
+		return nil, fmt.Errorf("failed to check orders: %w", err)
+	}
+	takenIds := make(map[int]bool, len(taken))
+	for _, id := range taken {
+		takenIds[id] = true
 	}
 
-	// 4. Convert time.Time to int64 (Unix timestamp)
-	order.Created = createdAt.Unix()
+	store := database.StoreOf(ctx)
+	imported := make([]bool, len(orders))
+	var rows [][]any
+	for i, order := range orders {
+		if takenIds[order.Id] {
+			continue
+		}
 
-	if err := r.unmarshalOrderData(order, itemsJSON, customJSON); err != nil {
-		return nil, err
+		itemsJSON, err := json.Marshal(order.Items)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal items: %w", err)
+		}
+
+		customJSON, err := database.MarshalCustom("order", order.Custom)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal custom data: %w", err)
+		}
+
+		var locationId any
+		if order.LocationId != 0 {
+			locationId = order.LocationId
+		}
+		rows = append(rows, []any{
+			order.Id, string(itemsJSON), order.ClerkId, locationId, order.Total, order.Paid, order.Change, order.Status,
+			string(customJSON), max(order.Revision, 1), store, database.FromUnix(order.Created), database.FromUnix(order.Updated),
+		})
+		imported[i] = true
 	}
 
-	return order, nil
+	columns := []string{"id", "items", "clerk_id", "location_id", "total", "paid", `"change"`, "status", "custom", "revision", "store_id", "created_at", "updated_at"}
+	if err := r.dialect.BulkInsert(ctx, client, "orders", columns, rows); err != nil {
+		return nil, fmt.Errorf("failed to import orders: %w", err)
+	}
+	if err := r.dialect.ResetSequence(ctx, client, "orders"); err != nil {
+		return nil, fmt.Errorf("failed to reset order ids: %w", err)
+	}
+	return imported, nil
+}
+
+// checkStore refuses a clerk or location (if any) from another store, which
+// the foreign keys alone would let through.
+func (r *orderRepository) checkStore(ctx context.Context, client database.SQLClient, clerkId, locationId int) error {
+	store := database.StoreOf(ctx)
+	ok, err := database.Exists(ctx, client, "users", "id = $1 AND store_id = $2", clerkId, store)
+	if err != nil {
+		return fmt.Errorf("failed to check clerk: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: unknown clerk", ErrInvalidOrderInput)
+	}
+	if locationId == 0 {
+		return nil
+	}
+	ok, err = database.Exists(ctx, client, "locations", "id = $1 AND store_id = $2", locationId, store)
+	if err != nil {
+		return fmt.Errorf("failed to check location: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: unknown location", ErrInvalidOrderInput)
+	}
+	return nil
+}
+
+func (r *orderRepository) GetByID(ctx context.Context, id int) (*Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1 AND store_id = $2`
+
+	return database.Get(ctx, r.db, r.scanOrder, ErrOrderNotFound, query, id, database.StoreOf(ctx))
 }
 
 func (r *orderRepository) Update(ctx context.Context, order *Order) error {
@@ -138,215 +225,141 @@ func (r *orderRepository) Update(ctx context.Context, order *Order) error {
 		return fmt.Errorf("failed to marshal items: %w", err)
 	}
 
-	customJSON, err := json.Marshal(order.Custom)
+	customJSON, err := database.MarshalCustom("order", order.Custom)
 	if err != nil {
 		return fmt.Errorf("failed to marshal custom data: %w", err)
 	}
 
+	if err := r.checkStore(ctx, r.db, order.ClerkId, 0); err != nil {
+		return err
+	}
+
 	query := `
 		UPDATE orders
-		SET items = $1, clerk_id = $2, total = $3, paid = $4, change = $5, custom = $6
-		WHERE id = $7
+		SET items = $1, clerk_id = $2, total = $3, paid = $4, "change" = $5, custom = $6,
+		    revision = revision + 1, updated_at = NOW()
+		WHERE id = $7 AND revision = $8 AND store_id = $9
 	`
 
-	result, err := r.db.ExecContext(
-		ctx, query,
-		itemsJSON, order.ClerkId, order.Total, order.Paid, order.Change, customJSON, order.Id,
+	ok, err := database.ExecAffected(
+		ctx, r.db, query,
+		itemsJSON, order.ClerkId, order.Total, order.Paid, order.Change, customJSON, order.Id, order.Revision, database.StoreOf(ctx),
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to update order: %w", err)
 	}
-
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	if rows == 0 {
-		return ErrOrderNotFound
+	if !ok {
+		return r.missingOrConflict(ctx, order.Id)
 	}
+	order.Revision++
 
 	return nil
 }
 
 func (r *orderRepository) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM orders WHERE id = $1`
+	query := `DELETE FROM orders WHERE id = $1 AND store_id = $2`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	ok, err := database.ExecAffected(ctx, r.db, query, id, database.StoreOf(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to delete order: %w", err)
 	}
-
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	if rows == 0 {
+	if !ok {
 		return ErrOrderNotFound
 	}
 
 	return nil
 }
 
-func (r *orderRepository) List(ctx context.Context, opts OrderListOptions) ([]*Order, error) {
-	query := `
-		SELECT id, items, clerk_id, total, paid, change, custom, created_at
-		FROM orders
-		WHERE 1=1
-	`
-	args := []any{}
-	argPos := 1
+// List returns one page of the orders matching opts, and with opts.Total
+// how many match in all.
+func (r *orderRepository) List(ctx context.Context, opts OrderListOptions) ([]*Order, int, error) {
+	table := "orders"
+	if opts.Archived {
+		table = "orders_archive"
+	}
 
+	var f database.Filter
+	f.And("store_id = " + f.Arg(database.StoreOf(ctx)))
 	if opts.ClerkId > 0 {
-		query += fmt.Sprintf(" AND clerk_id = $%d", argPos)
-		args = append(args, opts.ClerkId)
-		argPos++
+		f.And("clerk_id = " + f.Arg(opts.ClerkId))
+	}
+	if opts.LocationIds != nil {
+		f.And(r.atLocations(f.Arg(r.dialect.Array(opts.LocationIds)), opts.LocationIds))
 	}
-
 	if opts.MinTotal > 0 {
-		query += fmt.Sprintf(" AND total >= $%d", argPos)
-		args = append(args, opts.MinTotal)
-		argPos++
+		f.And("total >= " + f.Arg(opts.MinTotal))
 	}
-
 	if opts.MaxTotal > 0 {
-		query += fmt.Sprintf(" AND total <= $%d", argPos)
-		args = append(args, opts.MaxTotal)
-		argPos++
+		f.And("total <= " + f.Arg(opts.MaxTotal))
 	}
-
 	if opts.StartDate != nil {
-		query += fmt.Sprintf(" AND created_at >= $%d", argPos)
-		args = append(args, *opts.StartDate)
-		argPos++
+		f.And("created_at >= " + f.Arg(*opts.StartDate))
 	}
-
 	if opts.EndDate != nil {
-		query += fmt.Sprintf(" AND created_at <= $%d", argPos)
-		args = append(args, *opts.EndDate)
-		argPos++
-	}
-
-	// Sorting
-	sortBy := "id"
-	if opts.SortBy != "" {
-		switch opts.SortBy {
-		case "total", "created_at", "id":
-			sortBy = opts.SortBy
-		}
+		f.And("created_at <= " + f.Arg(*opts.EndDate))
 	}
+	f.Period(opts.Period)
 
-	sortOrder := "DESC" // Most recent first by default
-	if opts.SortOrder == "asc" {
-		sortOrder = "ASC"
+	k := orderKeyset(opts.SortBy, opts.SortOrder)
+	cursor := f.Outer()
+	if err := k.After(r.dialect, &cursor, opts.After); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidOrderInput, err)
 	}
 
-	query += fmt.Sprintf(" ORDER BY %s %s", sortBy, sortOrder)
-
-	if opts.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argPos)
-		args = append(args, opts.Limit)
-		argPos++
-	}
-
-	if opts.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argPos)
-		args = append(args, opts.Offset)
-	}
-
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	orders, total, err := database.SelectListing(ctx, r.db, r.scanOrder, database.Listing{
+		Columns: orderColumns, Table: table, Where: "1=1", Filter: f, Cursor: cursor,
+		OrderBy: k.OrderBy(r.dialect), Limit: opts.Limit, Offset: opts.Offset, Total: opts.Total,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to list orders: %w", err)
-	}
-	defer rows.Close()
-
-	var orders []*Order
-	for rows.Next() {
-		order, err := r.scanOrder(rows)
-		if err != nil {
-			return nil, err
-		}
-		orders = append(orders, order)
+		return nil, 0, fmt.Errorf("failed to list orders: %w", err)
 	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
-	return orders, nil
+	return orders, total, nil
 }
 
-func (r *orderRepository) GetByClerk(ctx context.Context, clerkId int) ([]*Order, error) {
-	query := `
-		SELECT id, items, clerk_id, total, paid, change, custom, created_at
-		FROM orders
-		WHERE clerk_id = $1
-		ORDER BY created_at DESC
-	`
-
-	rows, err := r.db.QueryContext(ctx, query, clerkId)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get orders by clerk: %w", err)
-	}
-	defer rows.Close()
-
-	var orders []*Order
-	for rows.Next() {
-		order, err := r.scanOrder(rows)
-		if err != nil {
-			return nil, err
-		}
-		orders = append(orders, order)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+// orderKeyset is the order List sorts orders in: most recent first unless
+// asked otherwise.
+func orderKeyset(sortBy, sortOrder string) database.Keyset {
+	return database.Keyset{
+		Column:    database.SortColumn(sortBy, "created_at", "id", "total", "created_at", "updated_at"),
+		Direction: database.SortDirection(sortOrder, "DESC"),
 	}
+}
 
-	return orders, nil
+// nextCursor is the cursor for the page after one ending with o, listed
+// in the given order.
+func nextCursor(o *Order, sortBy, sortOrder string) string {
+	k := orderKeyset(sortBy, sortOrder)
+	keys := map[string]any{"id": o.Id, "total": o.Total, "created_at": o.Created, "updated_at": o.Updated}
+	return k.Cursor(keys[k.Column], o.Id)
 }
 
 func (r *orderRepository) GetByDateRange(ctx context.Context, start, end time.Time) ([]*Order, error) {
 	query := `
-		SELECT id, items, clerk_id, total, paid, change, custom, created_at
+		SELECT ` + orderColumns + `
 		FROM orders
-		WHERE created_at >= $1 AND created_at <= $2
+		WHERE created_at >= $1 AND created_at <= $2 AND store_id = $3
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, start, end)
+	orders, err := database.Select(ctx, r.db, r.scanOrder, query, start, end, database.StoreOf(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get orders by date range: %w", err)
 	}
-	defer rows.Close()
-
-	var orders []*Order
-	for rows.Next() {
-		order, err := r.scanOrder(rows)
-		if err != nil {
-			return nil, err
-		}
-		orders = append(orders, order)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
 	return orders, nil
 }
 
-func (r *orderRepository) UpdatePayment(ctx context.Context, id int, paid int64) error {
+// UpdatePayment records what was paid, provided the order is still at the
+// given revision.
+func (r *orderRepository) UpdatePayment(ctx context.Context, id int, paid int64, revision int) error {
 	if paid < 0 {
 		return ErrInvalidPayment
 	}
 
 	// Get current order to calculate new change
-	query := `SELECT total FROM orders WHERE id = $1`
+	query := `SELECT total FROM orders WHERE id = $1 AND store_id = $2`
 	var total int64
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&total)
+	err := r.db.QueryRowContext(ctx, query, id, database.StoreOf(ctx)).Scan(&total)
 	if err == sql.ErrNoRows {
 		return ErrOrderNotFound
 	}
@@ -356,33 +369,51 @@ func (r *orderRepository) UpdatePayment(ctx context.Context, id int, paid int64)
 
 	change := paid - total
 
-	updateQuery := `UPDATE orders SET paid = $1, change = $2 WHERE id = $3`
-	result, err := r.db.ExecContext(ctx, updateQuery, paid, change, id)
+	updateQuery := `UPDATE orders SET paid = $1, "change" = $2, revision = revision + 1, updated_at = NOW() WHERE id = $3 AND revision = $4`
+	ok, err := database.ExecAffected(ctx, r.db, updateQuery, paid, change, id, revision)
 	if err != nil {
 		return fmt.Errorf("failed to update payment: %w", err)
 	}
+	if !ok {
+		return r.missingOrConflict(ctx, id)
+	}
 
-	rows, err := result.RowsAffected()
+	return nil
+}
+
+// SetStatus runs through the given client so callers can change an order's
+// status inside a wider transaction (e.g. voiding + releasing stock).
+func (r *orderRepository) SetStatus(ctx context.Context, client database.SQLClient, id int, status string) error {
+	client = r.db.On(client)
+	query := `UPDATE orders SET status = $1, revision = revision + 1, updated_at = NOW() WHERE id = $2 AND store_id = $3`
+
+	ok, err := database.ExecAffected(ctx, client, query, status, id, database.StoreOf(ctx))
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to set order status: %w", err)
 	}
-
-	if rows == 0 {
+	if !ok {
 		return ErrOrderNotFound
 	}
 
 	return nil
 }
 
-func (r *orderRepository) GetTotalSales(ctx context.Context, start, end time.Time) (int64, error) {
+// GetTotalSales sums non-void orders in the range. The aggregates take a
+// clerkId of 0 for every clerk and a nil locationIds for every location,
+// like OrderListOptions, and count
+// archived orders too if asked. Like everything else, they keep to the
+// store of ctx.
+func (r *orderRepository) GetTotalSales(ctx context.Context, start, end time.Time, clerkId int, locationIds []int, archived bool) (int64, error) {
 	query := `
 		SELECT COALESCE(SUM(total), 0)
-		FROM orders
+		FROM ` + ordersFrom(archived) + `
 		WHERE created_at >= $1 AND created_at <= $2
+		  AND status <> 'void'
 	`
+	query, args := r.reportFilter(ctx, query, []any{start, end}, clerkId, locationIds)
 
 	var total int64
-	err := r.db.QueryRowContext(ctx, query, start, end).Scan(&total)
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&total)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get total sales: %w", err)
 	}
@@ -390,15 +421,17 @@ func (r *orderRepository) GetTotalSales(ctx context.Context, start, end time.Tim
 	return total, nil
 }
 
-func (r *orderRepository) GetClerkSales(ctx context.Context, clerkId int, start, end time.Time) (int64, error) {
+func (r *orderRepository) GetClerkSales(ctx context.Context, clerkId int, start, end time.Time, locationIds []int, archived bool) (int64, error) {
 	query := `
 		SELECT COALESCE(SUM(total), 0)
-		FROM orders
+		FROM ` + ordersFrom(archived) + `
 		WHERE clerk_id = $1 AND created_at >= $2 AND created_at <= $3
+		  AND status <> 'void'
 	`
+	query, args := r.reportFilter(ctx, query, []any{clerkId, start, end}, 0, locationIds)
 
 	var total int64
-	err := r.db.QueryRowContext(ctx, query, clerkId, start, end).Scan(&total)
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&total)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get clerk sales: %w", err)
 	}
@@ -406,15 +439,17 @@ func (r *orderRepository) GetClerkSales(ctx context.Context, clerkId int, start,
 	return total, nil
 }
 
-func (r *orderRepository) GetAverageOrderValue(ctx context.Context, start, end time.Time) (float64, error) {
+func (r *orderRepository) GetAverageOrderValue(ctx context.Context, start, end time.Time, clerkId int, locationIds []int, archived bool) (float64, error) {
 	query := `
 		SELECT COALESCE(AVG(total), 0)
-		FROM orders
+		FROM ` + ordersFrom(archived) + `
 		WHERE created_at >= $1 AND created_at <= $2
+		  AND status <> 'void'
 	`
+	query, args := r.reportFilter(ctx, query, []any{start, end}, clerkId, locationIds)
 
 	var avg float64
-	err := r.db.QueryRowContext(ctx, query, start, end).Scan(&avg)
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&avg)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get average order value: %w", err)
 	}
@@ -423,10 +458,10 @@ func (r *orderRepository) GetAverageOrderValue(ctx context.Context, start, end t
 }
 
 func (r *orderRepository) Count(ctx context.Context) (int, error) {
-	query := `SELECT COUNT(*) FROM orders`
+	query := `SELECT COUNT(*) FROM orders WHERE store_id = $1`
 
 	var count int
-	err := r.db.QueryRowContext(ctx, query).Scan(&count)
+	err := r.db.QueryRowContext(ctx, query, database.StoreOf(ctx)).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count orders: %w", err)
 	}
@@ -436,47 +471,106 @@ func (r *orderRepository) Count(ctx context.Context) (int, error) {
 
 func (r *orderRepository) GetRecentOrders(ctx context.Context, limit int) ([]*Order, error) {
 	query := `
-		SELECT id, items, clerk_id, total, paid, change, custom, created_at
+		SELECT ` + orderColumns + `
 		FROM orders
+		WHERE store_id = $2
 		ORDER BY created_at DESC
 		LIMIT $1
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, limit)
+	orders, err := database.Select(ctx, r.db, r.scanOrder, query, limit, database.StoreOf(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get recent orders: %w", err)
 	}
-	defer rows.Close()
+	return orders, nil
+}
 
-	var orders []*Order
-	for rows.Next() {
-		order, err := r.scanOrder(rows)
-		if err != nil {
-			return nil, err
-		}
-		orders = append(orders, order)
+// Archive moves up to limit orders rung up before the given time, oldest
+// first, into orders_archive, and returns how many it moved. Stock still
+// held for them is let go with them.
+func (r *orderRepository) Archive(ctx context.Context, client database.SQLClient, before time.Time, limit int) (int, error) {
+	client = r.db.On(client)
+	query := `SELECT id FROM orders WHERE created_at < $1 AND store_id = $3 ORDER BY id LIMIT $2` + r.dialect.ForUpdate()
+	ids, err := database.Select(ctx, client, database.Value[int], query, before, limit, database.StoreOf(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find orders to archive: %w", err)
 	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+	if len(ids) == 0 {
+		return 0, nil
 	}
 
-	return orders, nil
+	in := r.dialect.AnyOf("id", "$1")
+	query = `INSERT INTO orders_archive (` + archiveColumns + `) SELECT ` + archiveColumns + ` FROM orders WHERE ` + in
+	if _, err := client.ExecContext(ctx, query, r.dialect.Array(ids)); err != nil {
+		return 0, fmt.Errorf("failed to archive orders: %w", err)
+	}
+	if _, err := client.ExecContext(ctx, `DELETE FROM orders WHERE `+in, r.dialect.Array(ids)); err != nil {
+		return 0, fmt.Errorf("failed to remove archived orders: %w", err)
+	}
+	return len(ids), nil
 }
 
 // Helper methods
 
-func (r *orderRepository) scanOrder(scanner interface {
-	Scan(dest ...any) error
-}) (*Order, error) {
+// ordersFrom is the FROM clause of an aggregate: orders, or orders and the
+// archive together.
+func ordersFrom(archived bool) string {
+	if !archived {
+		return "orders"
+	}
+	return "(SELECT " + reportColumns + " FROM orders UNION ALL SELECT " + reportColumns + " FROM orders_archive) AS all_orders"
+}
+
+// reportFilter narrows an aggregate query to the store of ctx, to clerkId
+// and to locationIds, bound after args. A clerkId of 0 leaves every clerk
+// in, nil locationIds every location.
+func (r *orderRepository) reportFilter(ctx context.Context, query string, args []any, clerkId int, locationIds []int) (string, []any) {
+	args = append(args, database.StoreOf(ctx))
+	query += fmt.Sprintf(" AND store_id = $%d", len(args))
+	if clerkId != 0 {
+		args = append(args, clerkId)
+		query += fmt.Sprintf(" AND clerk_id = $%d", len(args))
+	}
+	if locationIds == nil {
+		return query, args
+	}
+	query += " AND " + r.atLocations(fmt.Sprintf("$%d", len(args)+1), locationIds)
+	return query, append(args, r.dialect.Array(locationIds))
+}
+
+// atLocations matches orders at one of locationIds, bound to placeholder;
+// a 0 among them matches the orders without a location.
+func (r *orderRepository) atLocations(placeholder string, locationIds []int) string {
+	cond := r.dialect.AnyOf("location_id", placeholder)
+	if slices.Contains(locationIds, 0) {
+		cond = "(location_id IS NULL OR " + cond + ")"
+	}
+	return cond
+}
+
+// missingOrConflict explains an update that matched no row: either the
+// order is gone or its revision moved on.
+func (r *orderRepository) missingOrConflict(ctx context.Context, id int) error {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM orders WHERE id = $1 AND store_id = $2)`
+	if err := r.db.QueryRowContext(ctx, query, id, database.StoreOf(ctx)).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check order: %w", err)
+	}
+	if !exists {
+		return ErrOrderNotFound
+	}
+	return ErrOrderConflict
+}
+
+func (r *orderRepository) scanOrder(scanner database.Scanner) (*Order, error) {
 	order := &Order{}
 	var itemsJSON, customJSON []byte
-	var createdAt time.Time // Temp variable
+	var createdAt, updatedAt time.Time // Temp variables
 
-	// Scan created_at
+	// Scan created_at and updated_at
 	err := scanner.Scan(
-		&order.Id, &itemsJSON, &order.ClerkId,
-		&order.Total, &order.Paid, &order.Change, &customJSON, &createdAt,
+		&order.Id, &itemsJSON, &order.ClerkId, &order.LocationId,
+		&order.Total, &order.Paid, &order.Change, &order.Status, &customJSON, &createdAt, &updatedAt, &order.Revision,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan order: %w", err)
@@ -484,6 +578,7 @@ func (r *orderRepository) scanOrder(scanner interface {
 
 	// Convert to int64
 	order.Created = createdAt.Unix()
+	order.Updated = updatedAt.Unix()
 
 	if err := r.unmarshalOrderData(order, itemsJSON, customJSON); err != nil {
 		return nil, err
@@ -500,7 +595,7 @@ func (r *orderRepository) unmarshalOrderData(order *Order, itemsJSON, customJSON
 	}
 
 	if len(customJSON) > 0 {
-		if err := json.Unmarshal(customJSON, &order.Custom); err != nil {
+		if err := database.UnmarshalCustom("order", customJSON, &order.Custom); err != nil {
 			return fmt.Errorf("failed to unmarshal custom data: %w", err)
 		}
 	}
@@ -0,0 +1,77 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/utils"
+)
+
+var ErrVoidWindowClosed = errors.New("order is too old to void")
+
+var voidWindow = 15 * time.Minute
+
+// SetVoidWindow sets how long after ringing an order up its clerk may still
+// void it. Holders of order:void_any aren't limited.
+func SetVoidWindow(d time.Duration) {
+	voidWindow = d
+}
+
+// voidRules let clerks void their own recent orders (a mistyped sale) while
+// anything older or someone else's needs order:void_any.
+var voidRules = []utils.Rule[*Order]{
+	utils.UnlessAt(utils.PermOrderVoidAny, utils.OwnerOnly[*Order]),
+	utils.UnlessAt(utils.PermOrderVoidAny, withinVoidWindow),
+}
+
+func withinVoidWindow(_ utils.Subject, o *Order) error {
+	if time.Since(time.Unix(o.Created, 0)) > voidWindow {
+		return ErrVoidWindowClosed
+	}
+	return nil
+}
+
+// salesRules keep clerks to their own orders and sales figures; comparing
+// colleagues needs order:sales_all.
+var salesRules = []utils.Rule[int]{
+	utils.Unless(utils.PermOrderSalesAll, ownSales),
+}
+
+// clerkRules have orders rung up under the caller's own name; crediting a
+// colleague with a sale needs order:sales_all or order:void_any.
+var clerkRules = []utils.Rule[int]{
+	utils.Unless(utils.PermOrderSalesAll, utils.Unless(utils.PermOrderVoidAny, ownSales)),
+}
+
+func ownSales(s utils.Subject, clerkId int) error {
+	if clerkId != s.UserID {
+		return utils.ErrNotOwner
+	}
+	return nil
+}
+
+// ownSalesOnly is the clerk a listing must be limited to: the caller when
+// they may only see their own sales, 0 (anyone) otherwise.
+func ownSalesOnly(ctx context.Context) int {
+	s, ok := utils.SubjectFrom(ctx)
+	if !ok || s.Can(utils.PermOrderSalesAll) {
+		return 0
+	}
+	return s.UserID
+}
+
+// eventVisible reports whether the caller may see an event on the live
+// stream: the order must be in their store, at one of their locations and,
+// without order:sales_all, their own.
+func eventVisible(ctx context.Context, e OrderEvent) bool {
+	if e.Store != database.StoreOf(ctx) {
+		return false
+	}
+	if scope, ok := utils.LocationScopeFrom(ctx); ok && !scope.Allows(e.LocationId) {
+		return false
+	}
+	clerk := ownSalesOnly(ctx)
+	return clerk == 0 || clerk == e.ClerkId
+}
@@ -2,7 +2,22 @@ package order
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/iteranya/practicing-go/internal/audit"
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/metrics"
+	"github.com/iteranya/practicing-go/internal/utils"
+)
+
+var (
+	ordersCreated   = metrics.NewCounter("orders_created_total", "Orders rung up.")
+	paymentFailures = metrics.NewCounter("order_payment_failures_total",
+		"Payments refused, by reason: invalid, void, conflict, not_found, forbidden or error.", "reason")
 )
 
 type OrderService interface {
@@ -10,22 +25,38 @@ type OrderService interface {
 	GetOrder(ctx context.Context, id int) (*Order, error)
 	ListOrders(ctx context.Context, params OrderServiceListParams) ([]*Order, error)
 	GetOrdersByClerk(ctx context.Context, clerkId int) ([]*Order, error)
-	ProcessPayment(ctx context.Context, id int, amountPaid int64) error
+	ProcessPayment(ctx context.Context, id int, amountPaid int64, revision int) error
+	VoidOrder(ctx context.Context, id int) error
+
+	// OnOrderEvent registers fn to be called after an order is rung up, paid
+	// or voided. Register hooks at startup only; the list is not guarded
+	// for concurrent modification.
+	OnOrderEvent(fn func(OrderEvent))
+
+	// Analytics, over archived orders too if asked
+	GetSalesStats(ctx context.Context, start, end time.Time, archived bool) (SalesStats, error)
+	GetClerkPerformance(ctx context.Context, clerkId int, start, end time.Time, archived bool) (int64, error)
 
-	// Analytics
-	GetSalesStats(ctx context.Context, start, end time.Time) (SalesStats, error)
-	GetClerkPerformance(ctx context.Context, clerkId int, start, end time.Time) (int64, error)
+	// ArchiveBefore moves orders rung up before the given time out of the
+	// orders table, and returns how many it moved.
+	ArchiveBefore(ctx context.Context, before time.Time) (int, error)
 }
 
 // OrderServiceListParams maps incoming request params to repo options
 type OrderServiceListParams struct {
-	ClerkId   int
-	StartDate *time.Time
-	EndDate   *time.Time
-	MinTotal  int64
-	MaxTotal  int64
-	Limit     int
-	Page      int
+	Archived   bool // List archived orders instead
+	ClerkId    int
+	LocationId int // Narrows the list to one store, within the caller's scope
+	StartDate  *time.Time
+	EndDate    *time.Time
+	MinTotal   int64
+	MaxTotal   int64
+	Period     database.Period
+	SortBy     string // created_at (default), updated_at, total, id
+	SortOrder  string // desc (default), asc
+	Limit      int
+	Page       int
+	After      string // Cursor from the page before, in place of Page
 }
 
 type SalesStats struct {
@@ -34,22 +65,63 @@ type SalesStats struct {
 	OrderCount        int     `json:"order_count"`
 }
 
+// Stock is the inventory side of an order, implemented by the inventory
+// service. The client lets each call join the caller's transaction.
+type Stock interface {
+	// ConsumeForOrder takes what the items use out of stock and returns
+	// the inventory that ran short; the caller rolls back if any did.
+	ConsumeForOrder(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) ([]string, error)
+	// RestockForOrder puts back what a voided order's items used.
+	RestockForOrder(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) error
+	// ReleaseForOrder gives back stock held for an order.
+	ReleaseForOrder(ctx context.Context, client database.SQLClient, orderId int) (int64, error)
+	// OrderStockChanged follows up once a sale or void has committed.
+	OrderStockChanged(ctx context.Context, items []string, sold bool)
+}
+
 type orderService struct {
-	repo OrderRepository
+	repo  OrderRepository
+	txm   database.TxManager
+	stock Stock
+
+	eventHooks []func(OrderEvent)
 }
 
-func NewOrderService(repo OrderRepository) OrderService {
-	return &orderService{repo: repo}
+func NewOrderService(repo OrderRepository, txm database.TxManager, stock Stock) OrderService {
+	return &orderService{repo: repo, txm: txm, stock: stock}
 }
 
+// CreateOrder rings up an order. Callers limited to certain stores can only
+// create orders there, and must say which; with a single store the location
+// may be left out. The clerk defaults to the caller; naming someone else
+// needs order:sales_all or order:void_any.
+// The order and the stock its items use are written in one transaction, so
+// an order that can't be filled leaves no trace.
 func (s *orderService) CreateOrder(ctx context.Context, order Order) (*Order, error) {
 	// Basic Validation
 	if len(order.Items) == 0 {
 		return nil, ErrInvalidOrderInput
 	}
+	if subject, ok := utils.SubjectFrom(ctx); ok && order.ClerkId == 0 {
+		order.ClerkId = subject.UserID
+	}
 	if order.ClerkId == 0 {
 		return nil, ErrInvalidOrderInput
 	}
+	if err := utils.Check(ctx, order.ClerkId, clerkRules...); err != nil {
+		return nil, err
+	}
+
+	if scope, ok := utils.LocationScopeFrom(ctx); ok && !scope.All {
+		if order.LocationId == 0 && len(scope.IDs) == 1 {
+			order.LocationId = scope.IDs[0]
+		}
+		if order.LocationId == 0 || !scope.Allows(order.LocationId) {
+			return nil, ErrLocationForbidden
+		}
+	}
+
+	order.Status = StatusOpen
 
 	// Logic: Calculate Change only if Paid is sufficient
 	if order.Paid >= order.Total {
@@ -61,64 +133,245 @@ func (s *orderService) CreateOrder(ctx context.Context, order Order) (*Order, er
 		order.Change = order.Paid - order.Total
 	}
 
-	// Logic: Ensure Created time is set (Repo sets it via SQL NOW(),
-	// but we might want it in the struct that comes back)
-	// The Repo Create method uses RETURNING id, but relies on SQL for timestamp.
+	// The repository fills in Id, Revision and the Created/Updated times.
 
-	err := s.repo.Create(ctx, &order)
+	err := s.txm.Run(ctx, func(ctx context.Context, tx database.SQLClient) error {
+		if err := s.repo.Create(ctx, tx, &order); err != nil {
+			return err
+		}
+		short, err := s.stock.ConsumeForOrder(ctx, tx, order.Id, order.Items, order.ClerkId)
+		if err != nil {
+			return err
+		}
+		if len(short) > 0 {
+			return fmt.Errorf("%w: %s", ErrOutOfStock, strings.Join(short, ", "))
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	s.stock.OrderStockChanged(ctx, order.Items, true)
+	ordersCreated.Inc()
+	s.emit(ctx, EventCreated, &order)
+	audit.Record(ctx, audit.Order, order.Id, audit.Create, audit.Diff(nil, &order))
 
-	// Since the DB handles the timestamp, we usually re-fetch or just return the ID.
-	// We'll return the input object with the new ID.
+	// Return the input object, now carrying its ID and timestamps.
 	return &order, nil
 }
 
+// GetOrder hides orders from stores outside the caller's scope as not found,
+// and so, without order:sales_all, other clerks' orders.
 func (s *orderService) GetOrder(ctx context.Context, id int) (*Order, error) {
-	return s.repo.GetByID(ctx, id)
+	order, err := s.scopedOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if clerk := ownSalesOnly(ctx); clerk != 0 && clerk != order.ClerkId {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+// scopedOrder reads an order for a change, hiding only those from stores
+// outside the caller's scope; whose order it is, the change's own rules
+// decide.
+func (s *orderService) scopedOrder(ctx context.Context, id int) (*Order, error) {
+	order, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if scope, ok := utils.LocationScopeFrom(ctx); ok && !scope.Allows(order.LocationId) {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
 }
 
 func (s *orderService) ListOrders(ctx context.Context, params OrderServiceListParams) ([]*Order, error) {
 	offset := 0
-	if params.Page > 1 {
+	if params.Page > 1 && params.After == "" {
 		offset = (params.Page - 1) * params.Limit
 	}
 
+	locations := scopedLocations(ctx)
+	if params.LocationId != 0 {
+		if locations != nil && !slices.Contains(locations, params.LocationId) {
+			return []*Order{}, nil
+		}
+		locations = []int{params.LocationId}
+	}
+
+	if params.ClerkId == 0 {
+		params.ClerkId = ownSalesOnly(ctx)
+	}
+	if err := utils.Check(ctx, params.ClerkId, salesRules...); err != nil {
+		return nil, err
+	}
+
 	repoOpts := OrderListOptions{
-		ClerkId:   params.ClerkId,
-		StartDate: params.StartDate,
-		EndDate:   params.EndDate,
-		MinTotal:  params.MinTotal,
-		MaxTotal:  params.MaxTotal,
-		Limit:     params.Limit,
-		Offset:    offset,
-		SortBy:    "created_at",
-		SortOrder: "desc",
+		Archived:    params.Archived,
+		ClerkId:     params.ClerkId,
+		LocationIds: locations,
+		StartDate:   params.StartDate,
+		EndDate:     params.EndDate,
+		MinTotal:    params.MinTotal,
+		MaxTotal:    params.MaxTotal,
+		Period:      params.Period,
+		Limit:       params.Limit,
+		Offset:      offset,
+		After:       params.After,
+		SortBy:      params.SortBy,
+		SortOrder:   params.SortOrder,
 	}
 
-	return s.repo.List(ctx, repoOpts)
+	orders, _, err := s.repo.List(ctx, repoOpts)
+	return orders, err
 }
 
 func (s *orderService) GetOrdersByClerk(ctx context.Context, clerkId int) ([]*Order, error) {
 	if clerkId == 0 {
 		return nil, ErrInvalidOrderInput
 	}
-	return s.repo.GetByClerk(ctx, clerkId)
+	if err := utils.Check(ctx, clerkId, salesRules...); err != nil {
+		return nil, err
+	}
+	orders, _, err := s.repo.List(ctx, OrderListOptions{
+		ClerkId:     clerkId,
+		LocationIds: scopedLocations(ctx),
+		SortBy:      "created_at",
+		SortOrder:   "desc",
+	})
+	return orders, err
 }
 
-func (s *orderService) ProcessPayment(ctx context.Context, id int, amountPaid int64) error {
+// ProcessPayment records a payment against the revision of the order the
+// caller read, so two tills can't both settle it unaware of each other.
+// Clerks take payment on their own orders; anyone else's needs
+// order:pay_any.
+func (s *orderService) ProcessPayment(ctx context.Context, id int, amountPaid int64, revision int) (err error) {
+	defer func() {
+		if err != nil {
+			paymentFailures.Inc(paymentFailure(err))
+		}
+	}()
+	if revision == 0 {
+		return fmt.Errorf("%w: revision is required", ErrInvalidPayment)
+	}
+
+	// Make sure the order is within the caller's locations
+	existing, err := s.scopedOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := utils.CheckOwner(ctx, existing, utils.PermOrderPayAny); err != nil {
+		return err
+	}
+
 	// This updates the Paid amount and recalculates Change in the Repo
-	return s.repo.UpdatePayment(ctx, id, amountPaid)
+	if err := s.repo.UpdatePayment(ctx, id, amountPaid, revision); err != nil {
+		return err
+	}
+	before := *existing
+	existing.Paid, existing.Change = amountPaid, amountPaid-existing.Total
+	s.emit(ctx, EventPaid, existing)
+	audit.Record(ctx, audit.Order, id, ActionPayment, audit.Diff(&before, existing))
+	return nil
 }
 
-func (s *orderService) GetSalesStats(ctx context.Context, start, end time.Time) (SalesStats, error) {
-	total, err := s.repo.GetTotalSales(ctx, start, end)
+// paymentFailure is the reason label of a refused payment.
+func paymentFailure(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidPayment):
+		return "invalid"
+	case errors.Is(err, ErrOrderVoided):
+		return "void"
+	case errors.Is(err, ErrOrderConflict):
+		return "conflict"
+	case errors.Is(err, ErrOrderNotFound):
+		return "not_found"
+	case errors.Is(err, utils.ErrNotOwner):
+		return "forbidden"
+	}
+	return "error"
+}
+
+// VoidOrder marks the order void, puts back the stock its items used and
+// releases any stock reserved for it. All of it happens in one transaction
+// so a failed restock or release leaves the order open.
+func (s *orderService) VoidOrder(ctx context.Context, id int) error {
+	existing, err := s.scopedOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing.Status == StatusVoid {
+		return ErrOrderVoided
+	}
+	if err := utils.Check(ctx, existing, voidRules...); err != nil {
+		return err
+	}
+
+	var userId int
+	if subject, ok := utils.SubjectFrom(ctx); ok {
+		userId = subject.UserID
+	}
+
+	err = s.txm.Run(ctx, func(ctx context.Context, tx database.SQLClient) error {
+		if err := s.repo.SetStatus(ctx, tx, id, StatusVoid); err != nil {
+			return err
+		}
+		if err := s.stock.RestockForOrder(ctx, tx, id, existing.Items, userId); err != nil {
+			return err
+		}
+		_, err := s.stock.ReleaseForOrder(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.stock.OrderStockChanged(ctx, existing.Items, false)
+	before := *existing
+	existing.Status = StatusVoid
+	s.emit(ctx, EventVoided, existing)
+	audit.Record(ctx, audit.Order, id, ActionVoid, audit.Diff(&before, existing))
+
+	return nil
+}
+
+func (s *orderService) OnOrderEvent(fn func(OrderEvent)) {
+	s.eventHooks = append(s.eventHooks, fn)
+}
+
+// emit tells the hooks what just happened to o.
+func (s *orderService) emit(ctx context.Context, kind string, o *Order) {
+	event := OrderEvent{
+		Kind:       kind,
+		OrderId:    o.Id,
+		ClerkId:    o.ClerkId,
+		LocationId: o.LocationId,
+		Total:      o.Total,
+		Paid:       o.Paid,
+		Status:     o.Status,
+		Store:      database.StoreOf(ctx),
+		At:         time.Now().Unix(),
+	}
+	for _, fn := range s.eventHooks {
+		fn(event)
+	}
+}
+
+// GetSalesStats covers the caller's own sales only, unless they may see
+// everyone's.
+func (s *orderService) GetSalesStats(ctx context.Context, start, end time.Time, archived bool) (SalesStats, error) {
+	locations := scopedLocations(ctx)
+	clerk := ownSalesOnly(ctx)
+
+	total, err := s.repo.GetTotalSales(ctx, start, end, clerk, locations, archived)
 	if err != nil {
 		return SalesStats{}, err
 	}
 
-	avg, err := s.repo.GetAverageOrderValue(ctx, start, end)
+	avg, err := s.repo.GetAverageOrderValue(ctx, start, end, clerk, locations, archived)
 	if err != nil {
 		return SalesStats{}, err
 	}
@@ -133,6 +386,43 @@ func (s *orderService) GetSalesStats(ctx context.Context, start, end time.Time)
 	}, nil
 }
 
-func (s *orderService) GetClerkPerformance(ctx context.Context, clerkId int, start, end time.Time) (int64, error) {
-	return s.repo.GetClerkSales(ctx, clerkId, start, end)
+func (s *orderService) GetClerkPerformance(ctx context.Context, clerkId int, start, end time.Time, archived bool) (int64, error) {
+	if err := utils.Check(ctx, clerkId, salesRules...); err != nil {
+		return 0, err
+	}
+	return s.repo.GetClerkSales(ctx, clerkId, start, end, scopedLocations(ctx), archived)
+}
+
+// archiveBatch is how many orders ArchiveBefore moves per transaction, so
+// a first run over years of orders doesn't hold one huge transaction.
+const archiveBatch = 500
+
+func (s *orderService) ArchiveBefore(ctx context.Context, before time.Time) (int, error) {
+	archived := 0
+	for {
+		var n int
+		err := s.txm.Run(ctx, func(ctx context.Context, client database.SQLClient) error {
+			var err error
+			n, err = s.repo.Archive(ctx, client, before, archiveBatch)
+			return err
+		})
+		if err != nil {
+			return archived, err
+		}
+		archived += n
+		if n < archiveBatch {
+			return archived, nil
+		}
+	}
+}
+
+// scopedLocations returns the stores the caller is limited to, with 0 for
+// the orders that have none, or nil when they may see every store (or no
+// scope was set, as for internal jobs).
+func scopedLocations(ctx context.Context) []int {
+	scope, ok := utils.LocationScopeFrom(ctx)
+	if !ok || scope.All {
+		return nil
+	}
+	return append([]int{0}, scope.IDs...)
 }
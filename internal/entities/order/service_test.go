@@ -0,0 +1,227 @@
+package order_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/entities/inventory"
+	"github.com/iteranya/practicing-go/internal/entities/order"
+	"github.com/iteranya/practicing-go/internal/entities/role"
+	"github.com/iteranya/practicing-go/internal/entities/user"
+	"github.com/iteranya/practicing-go/internal/testutil"
+	"github.com/iteranya/practicing-go/internal/utils"
+)
+
+type env struct {
+	db     *testutil.DB
+	users  user.UserService
+	orders order.OrderService
+}
+
+func newEnv(t *testing.T) (context.Context, env) {
+	t.Helper()
+	ctx := context.Background()
+	db := testutil.NewDB()
+	testutil.NewRole("cashier", utils.PermOrderCreate, utils.PermOrderRead).Insert(t, ctx, db)
+	testutil.NewRole("manager", utils.PermOrderCreate, utils.PermOrderRead, utils.PermOrderSalesAll).Insert(t, ctx, db)
+	testutil.NewProduct("coffee").Price(300).Insert(t, ctx, db)
+
+	users := user.NewUserService(db.Users(), user.LockoutPolicy{}, role.NewRoleService(db.Roles()), nil, nil)
+	stock := inventory.NewInventoryService(db.Inventory(false), true)
+	return ctx, env{db: db, users: users, orders: order.NewOrderService(db.Orders(), db.TxManager(), stock)}
+}
+
+// as is ctx the way the middleware hands it to the order service: the user,
+// their locations and their permissions.
+func (e env) as(t *testing.T, ctx context.Context, u *user.User) context.Context {
+	t.Helper()
+	scope, err := e.users.GetLocationScope(ctx, u.Id)
+	if err != nil {
+		t.Fatalf("GetLocationScope: %v", err)
+	}
+	subject, err := e.users.GetSubject(ctx, u.Id)
+	if err != nil {
+		t.Fatalf("GetSubject: %v", err)
+	}
+	ctx = context.WithValue(ctx, utils.UserIDKey, u.Id)
+	ctx = context.WithValue(ctx, utils.LocationKey, scope)
+	return context.WithValue(ctx, utils.SubjectKey, subject)
+}
+
+func TestCreateOrderWithoutLocations(t *testing.T) {
+	ctx, e := newEnv(t)
+	clerk := testutil.NewUser("clerk").Role("cashier").Insert(t, ctx, e.db)
+	clerkCtx := e.as(t, ctx, clerk)
+
+	created, err := e.orders.CreateOrder(clerkCtx, order.Order{ClerkId: clerk.Id, Items: []string{"coffee"}, Total: 300})
+	if err != nil {
+		t.Fatalf("CreateOrder in a store without locations: %v", err)
+	}
+
+	orders, err := e.orders.ListOrders(clerkCtx, order.OrderServiceListParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListOrders: %v", err)
+	}
+	if len(orders) != 1 || orders[0].Id != created.Id {
+		t.Fatalf("ListOrders = %v, want the order just created", orders)
+	}
+	if _, err := e.orders.GetOrder(clerkCtx, created.Id); err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+}
+
+func TestLocationScope(t *testing.T) {
+	ctx, e := newEnv(t)
+	clerk := testutil.NewUser("clerk").Role("cashier").Insert(t, ctx, e.db)
+	older := testutil.NewOrder(clerk.Id, "coffee").Total(300).Insert(t, ctx, e.db)
+
+	north := testutil.NewLocation(t, ctx, e.db, "north")
+	south := testutil.NewLocation(t, ctx, e.db, "south")
+	if err := e.users.SetLocations(ctx, clerk.Id, []int{north.Id}); err != nil {
+		t.Fatalf("SetLocations: %v", err)
+	}
+	atSouth := testutil.NewOrder(clerk.Id, "coffee").Total(300).Location(south.Id).Insert(t, ctx, e.db)
+	clerkCtx := e.as(t, ctx, clerk)
+
+	// The one assigned location is the default
+	created, err := e.orders.CreateOrder(clerkCtx, order.Order{ClerkId: clerk.Id, Items: []string{"coffee"}, Total: 300})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if created.LocationId != north.Id {
+		t.Errorf("LocationId = %d, want %d", created.LocationId, north.Id)
+	}
+
+	_, err = e.orders.CreateOrder(clerkCtx, order.Order{ClerkId: clerk.Id, Items: []string{"coffee"}, Total: 300, LocationId: south.Id})
+	if !errors.Is(err, order.ErrLocationForbidden) {
+		t.Errorf("CreateOrder at an unassigned location: err = %v, want ErrLocationForbidden", err)
+	}
+
+	orders, err := e.orders.ListOrders(clerkCtx, order.OrderServiceListParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListOrders: %v", err)
+	}
+	seen := map[int]bool{}
+	for _, o := range orders {
+		seen[o.Id] = true
+	}
+	if !seen[created.Id] || !seen[older.Id] || seen[atSouth.Id] {
+		t.Errorf("ListOrders saw %v, want %d and %d (from before locations) but not %d", seen, created.Id, older.Id, atSouth.Id)
+	}
+
+	if _, err := e.orders.GetOrder(clerkCtx, older.Id); err != nil {
+		t.Errorf("GetOrder of an order without a location: %v", err)
+	}
+	if _, err := e.orders.GetOrder(clerkCtx, atSouth.Id); !errors.Is(err, order.ErrOrderNotFound) {
+		t.Errorf("GetOrder at an unassigned location: err = %v, want ErrOrderNotFound", err)
+	}
+}
+
+func TestUnassignedClerkCannotCreate(t *testing.T) {
+	ctx, e := newEnv(t)
+	testutil.NewLocation(t, ctx, e.db, "north")
+	clerk := testutil.NewUser("clerk").Role("cashier").Insert(t, ctx, e.db)
+
+	_, err := e.orders.CreateOrder(e.as(t, ctx, clerk), order.Order{ClerkId: clerk.Id, Items: []string{"coffee"}, Total: 300})
+	if !errors.Is(err, order.ErrLocationForbidden) {
+		t.Errorf("err = %v, want ErrLocationForbidden", err)
+	}
+}
+
+func TestCreateOrderClerk(t *testing.T) {
+	ctx, e := newEnv(t)
+	clerk := testutil.NewUser("clerk").Role("cashier").Insert(t, ctx, e.db)
+	colleague := testutil.NewUser("colleague").Role("cashier").Insert(t, ctx, e.db)
+	manager := testutil.NewUser("manager").Role("manager").Insert(t, ctx, e.db)
+
+	tests := []struct {
+		name    string
+		caller  *user.User
+		clerkId int
+		want    int
+		wantErr error
+	}{
+		{"defaults to the caller", clerk, 0, clerk.Id, nil},
+		{"own name", clerk, clerk.Id, clerk.Id, nil},
+		{"colleague's name", clerk, colleague.Id, 0, utils.ErrNotOwner},
+		{"manager attributes a sale", manager, colleague.Id, colleague.Id, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created, err := e.orders.CreateOrder(e.as(t, ctx, tt.caller), order.Order{ClerkId: tt.clerkId, Items: []string{"coffee"}, Total: 300})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && created.ClerkId != tt.want {
+				t.Errorf("ClerkId = %d, want %d", created.ClerkId, tt.want)
+			}
+		})
+	}
+}
+
+func TestOwnSalesOnly(t *testing.T) {
+	ctx, e := newEnv(t)
+	clerk := testutil.NewUser("clerk").Role("cashier").Insert(t, ctx, e.db)
+	colleague := testutil.NewUser("colleague").Role("cashier").Insert(t, ctx, e.db)
+	manager := testutil.NewUser("manager").Role("manager").Insert(t, ctx, e.db)
+	own := testutil.NewOrder(clerk.Id, "coffee").Total(300).Insert(t, ctx, e.db)
+	theirs := testutil.NewOrder(colleague.Id, "coffee").Total(900).Insert(t, ctx, e.db)
+	clerkCtx, managerCtx := e.as(t, ctx, clerk), e.as(t, ctx, manager)
+
+	if _, err := e.orders.GetOrder(clerkCtx, own.Id); err != nil {
+		t.Errorf("GetOrder of their own order: %v", err)
+	}
+	if _, err := e.orders.GetOrder(clerkCtx, theirs.Id); !errors.Is(err, order.ErrOrderNotFound) {
+		t.Errorf("GetOrder of a colleague's order: err = %v, want ErrOrderNotFound", err)
+	}
+	if _, err := e.orders.GetOrder(managerCtx, theirs.Id); err != nil {
+		t.Errorf("GetOrder with order:sales_all: %v", err)
+	}
+
+	start, end := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	for _, tt := range []struct {
+		name string
+		ctx  context.Context
+		want int64
+	}{
+		{"cashier", clerkCtx, 300},
+		{"manager", managerCtx, 1200},
+	} {
+		stats, err := e.orders.GetSalesStats(tt.ctx, start, end, false)
+		if err != nil {
+			t.Fatalf("GetSalesStats as %s: %v", tt.name, err)
+		}
+		if stats.TotalRevenue != tt.want {
+			t.Errorf("GetSalesStats as %s: TotalRevenue = %d, want %d", tt.name, stats.TotalRevenue, tt.want)
+		}
+	}
+}
+
+func TestProcessPaymentOwner(t *testing.T) {
+	ctx, e := newEnv(t)
+	testutil.NewRole("supervisor", utils.PermOrderRead, utils.PermOrderUpdate, utils.PermOrderPayAny).Insert(t, ctx, e.db)
+	clerk := testutil.NewUser("clerk").Role("cashier").Insert(t, ctx, e.db)
+	colleague := testutil.NewUser("colleague").Role("cashier").Insert(t, ctx, e.db)
+	supervisor := testutil.NewUser("supervisor").Role("supervisor").Insert(t, ctx, e.db)
+
+	tests := []struct {
+		name    string
+		caller  *user.User
+		wantErr error
+	}{
+		{"own order", clerk, nil},
+		{"colleague's order", colleague, utils.ErrNotOwner},
+		{"with order:pay_any", supervisor, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := testutil.NewOrder(clerk.Id, "coffee").Total(300).Insert(t, ctx, e.db)
+			err := e.orders.ProcessPayment(e.as(t, ctx, tt.caller), o.Id, 300, o.Revision)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
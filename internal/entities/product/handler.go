@@ -5,6 +5,8 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+
+	"github.com/iteranya/practicing-go/internal/utils"
 )
 
 type ProductHandler struct {
@@ -15,21 +17,25 @@ func NewProductHandler(service ProductService) *ProductHandler {
 	return &ProductHandler{service: service}
 }
 
-func (h *ProductHandler) RegisterRoutes(mux *http.ServeMux) {
-	// Standard CRUD
-	mux.HandleFunc("POST /products", h.HandleCreate)
-	mux.HandleFunc("GET /products", h.HandleList)
-	mux.HandleFunc("GET /products/{id}", h.HandleGet) // supports id or slug
-	mux.HandleFunc("PUT /products/{id}", h.HandleUpdate)
-	mux.HandleFunc("DELETE /products/{id}", h.HandleDelete)
-
-	// Specific updates
-	mux.HandleFunc("PATCH /products/{id}/avail", h.HandleToggleAvailability)
-	mux.HandleFunc("PATCH /products/{id}/price", h.HandleUpdatePrice)
-
-	// Specialized filters
-	mux.HandleFunc("GET /products/bundles", h.HandleGetBundles)
-	mux.HandleFunc("GET /products/recipes", h.HandleGetRecipes)
+func (h *ProductHandler) Routes() []utils.Route {
+	return []utils.Route{
+		// Standard CRUD
+		{Pattern: "POST /products", Perm: utils.PermProductCreate, Handler: h.HandleCreate},
+		{Pattern: "GET /products", Perm: utils.PermProductRead, Handler: h.HandleList},
+		{Pattern: "GET /products/{id}", Perm: utils.PermProductRead, Handler: h.HandleGet}, // supports id or slug
+		{Pattern: "PUT /products/{id}", Perm: utils.PermProductUpdate, Handler: h.HandleUpdate},
+		{Pattern: "DELETE /products/{id}", Perm: utils.PermProductDelete, Handler: h.HandleDelete}, // soft delete, see ?deleted=true
+		{Pattern: "POST /products/{id}/restore", Perm: utils.PermProductDelete, Handler: h.HandleRestore},
+		{Pattern: "DELETE /products/{id}/purge", Perm: utils.PermProductDelete, Handler: h.HandlePurge},
+
+		// Specific updates
+		{Pattern: "PATCH /products/{id}/avail", Perm: utils.PermProductUpdate, Handler: h.HandleToggleAvailability},
+		{Pattern: "PATCH /products/{id}/price", Perm: utils.PermProductUpdate, Handler: h.HandleUpdatePrice},
+
+		// Specialized filters
+		{Pattern: "GET /products/bundles", Perm: utils.PermProductRead, Handler: h.HandleGetBundles},
+		{Pattern: "GET /products/recipes", Perm: utils.PermProductRead, Handler: h.HandleGetRecipes},
+	}
 }
 
 // CREATE
@@ -94,16 +100,21 @@ func (h *ProductHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	deleted, _ := strconv.ParseBool(query.Get("deleted"))
+
 	params := ProductServiceListParams{
+		Deleted:  deleted,
 		Tag:      query.Get("tag"),
 		Label:    query.Get("label"),
 		Query:    query.Get("q"),
-		SortBy:   query.Get("sort"), // price, name
+		Period:   utils.PeriodFromQuery(query),
+		SortBy:   query.Get("sort"), // price, name, created_at, updated_at
 		Avail:    avail,
 		MinPrice: minPrice,
 		MaxPrice: maxPrice,
 		Limit:    limit,
 		Page:     page,
+		After:    query.Get("after"), // X-Next-Cursor of the page before
 	}
 
 	products, err := h.service.ListProducts(r.Context(), params)
@@ -112,6 +123,10 @@ func (h *ProductHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A full page may have more after it; search results are never paged
+	if len(products) == limit && (params.Query == "" || params.Deleted) {
+		w.Header().Set("X-Next-Cursor", nextCursor(products[len(products)-1], params.SortBy, ""))
+	}
 	h.respondWithJSON(w, http.StatusOK, products)
 }
 
@@ -155,6 +170,40 @@ func (h *ProductHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
+// RESTORE (undo a soft delete)
+func (h *ProductHandler) HandleRestore(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RestoreProduct(r.Context(), id); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "restored"})
+}
+
+// PURGE (permanent, only for products in the trash no bundle lists)
+func (h *ProductHandler) HandlePurge(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.PurgeProduct(r.Context(), id); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "purged"})
+}
+
 // TOGGLE AVAILABILITY
 func (h *ProductHandler) HandleToggleAvailability(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
@@ -244,8 +293,10 @@ func (h *ProductHandler) respondWithError(w http.ResponseWriter, err error) {
 		statusCode = http.StatusNotFound
 	case errors.Is(err, ErrInvalidProductInput):
 		statusCode = http.StatusBadRequest
-	case errors.Is(err, ErrDuplicateProductSlug):
+	case errors.Is(err, ErrDuplicateProductSlug), errors.Is(err, ErrProductConflict), errors.Is(err, ErrProductInUse):
 		statusCode = http.StatusConflict
+	case errors.Is(err, ErrUnknownIngredient):
+		statusCode = http.StatusBadRequest
 	default:
 		statusCode = http.StatusInternalServerError
 	}
@@ -12,4 +12,12 @@ type Product struct { // This can be a single product, or a package of product
 	Items  *[]string       // This is an array of slug that this uses. Optional (Say, like, a morning package, has coffee and croissant)
 	Recipe *map[string]int // This is the slug of stock in inventory and how much it uses. Optional (Say, 5 grams coffee, 200 ml milk)
 	Custom map[string]any
+
+	// Revision goes up with every change. Updates send the one they read
+	// and fail with ErrProductConflict if it has moved on since.
+	Revision int
+
+	Created int64 // Unix timestamps
+	Updated int64
+	Deleted int64 // Unix timestamp of the soft delete, 0 while active
 }
@@ -6,21 +6,31 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
 )
 
 var (
 	ErrProductNotFound      = errors.New("product not found")
 	ErrInvalidProductInput  = errors.New("invalid product input")
 	ErrDuplicateProductSlug = errors.New("product slug already exists")
+	ErrUnknownIngredient    = errors.New("recipe uses unknown inventory")
+	ErrProductConflict      = errors.New("product was changed since it was read")
+	ErrProductInUse         = errors.New("product is still part of a bundle")
 )
 
 type ProductRepository interface {
 	Create(ctx context.Context, product *Product) error
+	Import(ctx context.Context, client database.SQLClient, products []*Product) ([]bool, error)
 	GetByID(ctx context.Context, id int) (*Product, error)
 	GetBySlug(ctx context.Context, slug string) (*Product, error)
 	Update(ctx context.Context, product *Product) error
-	Delete(ctx context.Context, id int) error
-	List(ctx context.Context, opts ProductListOptions) ([]*Product, error)
+	Delete(ctx context.Context, id int) error // Soft delete
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, id int) error // Hard delete, only from the trash
+	TrashedBefore(ctx context.Context, cutoff time.Time) ([]int, error)
+	List(ctx context.Context, opts ProductListOptions) ([]*Product, int, error)
 	SetAvailability(ctx context.Context, id int, avail bool) error
 	GetAvailable(ctx context.Context) ([]*Product, error)
 	GetByTag(ctx context.Context, tag string) ([]*Product, error)
@@ -33,6 +43,7 @@ type ProductRepository interface {
 }
 
 type ProductListOptions struct {
+	Deleted   bool // list the trash instead of live products
 	Tag       string
 	Label     string
 	Avail     *bool // pointer so we can distinguish between false and not set
@@ -40,16 +51,20 @@ type ProductListOptions struct {
 	MaxPrice  int64
 	Limit     int
 	Offset    int
-	SortBy    string // name, price, slug
+	After     string // Cursor from the page before, in place of Offset
+	Period    database.Period
+	SortBy    string // name, price, slug, created_at, updated_at
 	SortOrder string // asc, desc
+	Total     bool   // Also count every match, for List to return
 }
 
 type productRepository struct {
-	db *sql.DB
+	db      *database.DB
+	dialect database.Dialect
 }
 
 func NewProductRepository(db *sql.DB) ProductRepository {
-	return &productRepository{db: db}
+	return &productRepository{db: database.Observe(db, "products"), dialect: database.DialectOf(db)}
 }
 
 func (r *productRepository) Create(ctx context.Context, product *Product) error {
@@ -67,91 +82,117 @@ func (r *productRepository) Create(ctx context.Context, product *Product) error
 		return fmt.Errorf("failed to marshal recipe: %w", err)
 	}
 
-	customJSON, err := json.Marshal(product.Custom)
+	customJSON, err := database.MarshalCustom("product", product.Custom)
 	if err != nil {
 		return fmt.Errorf("failed to marshal custom data: %w", err)
 	}
 
 	query := `
-		INSERT INTO products (slug, name, desc, tag, label, price, avail, items, recipe, custom)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		RETURNING id
+		INSERT INTO products (slug, name, "desc", tag, label, price, avail, items, recipe, custom, store_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $12)
 	`
-
-	err = r.db.QueryRowContext(
-		ctx, query,
+	now := time.Now()
+	args := []any{
 		product.Slug, product.Name, product.Desc, product.Tag, product.Label,
-		product.Price, product.Avail, itemsJSON, recipeJSON, customJSON,
-	).Scan(&product.Id)
+		product.Price, product.Avail, itemsJSON, recipeJSON, customJSON, database.StoreOf(ctx), now,
+	}
+
+	err = r.dialect.InsertReturning(ctx, r.db, "products", query, "id, revision", args, &product.Id, &product.Revision)
 
 	if err != nil {
-		if isDuplicateKeyError(err) {
+		if database.IsUniqueViolation(err) {
 			return ErrDuplicateProductSlug
 		}
 		return fmt.Errorf("failed to create product: %w", err)
 	}
+	product.Created, product.Updated = now.Unix(), now.Unix()
 
 	return nil
 }
 
-func (r *productRepository) GetByID(ctx context.Context, id int) (*Product, error) {
-	query := `
-		SELECT id, slug, name, desc, tag, label, price, avail, items, recipe, custom
-		FROM products
-		WHERE id = $1
-	`
+// Import inserts products read back from a backup as they were, ids,
+// revisions and timestamps included, in one bulk insert. It reports per
+// product whether it went in; false means one with its id or slug is
+// already there, and it was left out.
+func (r *productRepository) Import(ctx context.Context, client database.SQLClient, products []*Product) ([]bool, error) {
+	client = r.db.On(client)
+
+	ids := make([]int, len(products))
+	slugs := make([]string, len(products))
+	for i, p := range products {
+		ids[i], slugs[i] = p.Id, p.Slug
+	}
+	// Ids are shared by every store, slugs only clash within one
+	query := `SELECT id, CASE WHEN store_id = $3 THEN slug ELSE '' END FROM products
+		WHERE ` + r.dialect.AnyOf("id", "$1") + ` OR (` + r.dialect.AnyOf("slug", "$2") + ` AND store_id = $3)`
+	store := database.StoreOf(ctx)
+	taken, err := database.Select(ctx, client, scanIdSlug, query, r.dialect.Array(ids), r.dialect.Array(slugs), store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check products: %w", err)
+	}
+	takenIds, takenSlugs := map[int]bool{}, map[string]bool{}
+	for _, p := range taken {
+		takenIds[p.Id], takenSlugs[p.Slug] = true, true
+	}
 
-	product := &Product{}
-	var itemsJSON, recipeJSON, customJSON []byte
+	imported := make([]bool, len(products))
+	var rows [][]any
+	for i, product := range products {
+		if takenIds[product.Id] || takenSlugs[product.Slug] {
+			continue
+		}
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&product.Id, &product.Slug, &product.Name, &product.Desc,
-		&product.Tag, &product.Label, &product.Price, &product.Avail,
-		&itemsJSON, &recipeJSON, &customJSON,
-	)
+		itemsJSON, err := r.marshalNullableSlice(product.Items)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal items: %w", err)
+		}
 
-	if err == sql.ErrNoRows {
-		return nil, ErrProductNotFound
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to get product: %w", err)
-	}
+		recipeJSON, err := r.marshalNullableMap(product.Recipe)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal recipe: %w", err)
+		}
 
-	if err := r.unmarshalProductData(product, itemsJSON, recipeJSON, customJSON); err != nil {
-		return nil, err
+		customJSON, err := database.MarshalCustom("product", product.Custom)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal custom data: %w", err)
+		}
+
+		rows = append(rows, []any{
+			product.Id, product.Slug, product.Name, product.Desc, product.Tag, product.Label,
+			product.Price, product.Avail, database.JSONText(itemsJSON), database.JSONText(recipeJSON), string(customJSON), max(product.Revision, 1), store,
+			database.FromUnix(product.Created), database.FromUnix(product.Updated), database.FromUnix(product.Deleted),
+		})
+		imported[i] = true
 	}
 
-	return product, nil
+	columns := []string{"id", "slug", "name", `"desc"`, "tag", "label", "price", "avail", "items", "recipe", "custom", "revision", "store_id", "created_at", "updated_at", "deleted_at"}
+	if err := r.dialect.BulkInsert(ctx, client, "products", columns, rows); err != nil {
+		return nil, fmt.Errorf("failed to import products: %w", err)
+	}
+	if err := r.dialect.ResetSequence(ctx, client, "products"); err != nil {
+		return nil, fmt.Errorf("failed to reset product ids: %w", err)
+	}
+	return imported, nil
 }
 
-func (r *productRepository) GetBySlug(ctx context.Context, slug string) (*Product, error) {
+func (r *productRepository) GetByID(ctx context.Context, id int) (*Product, error) {
 	query := `
-		SELECT id, slug, name, desc, tag, label, price, avail, items, recipe, custom
+		SELECT ` + r.productColumns() + `
 		FROM products
-		WHERE slug = $1
+		WHERE id = $1 AND store_id = $2 AND deleted_at IS NULL
 	`
 
-	product := &Product{}
-	var itemsJSON, recipeJSON, customJSON []byte
-
-	err := r.db.QueryRowContext(ctx, query, slug).Scan(
-		&product.Id, &product.Slug, &product.Name, &product.Desc,
-		&product.Tag, &product.Label, &product.Price, &product.Avail,
-		&itemsJSON, &recipeJSON, &customJSON,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, ErrProductNotFound
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to get product: %w", err)
-	}
+	return database.Get(ctx, r.db, r.scanProduct, ErrProductNotFound, query, id, database.StoreOf(ctx))
+}
 
-	if err := r.unmarshalProductData(product, itemsJSON, recipeJSON, customJSON); err != nil {
-		return nil, err
-	}
+func (r *productRepository) GetBySlug(ctx context.Context, slug string) (*Product, error) {
+	query := `
+		SELECT ` + r.productColumns() + `
+		FROM products
+		WHERE slug = $1 AND store_id = $2 AND deleted_at IS NULL
+	`
 
-	return product, nil
+	return database.Get(ctx, r.db, r.scanProduct, ErrProductNotFound, query, slug, database.StoreOf(ctx))
 }
 
 func (r *productRepository) Update(ctx context.Context, product *Product) error {
@@ -169,165 +210,177 @@ func (r *productRepository) Update(ctx context.Context, product *Product) error
 		return fmt.Errorf("failed to marshal recipe: %w", err)
 	}
 
-	customJSON, err := json.Marshal(product.Custom)
+	customJSON, err := database.MarshalCustom("product", product.Custom)
 	if err != nil {
 		return fmt.Errorf("failed to marshal custom data: %w", err)
 	}
 
 	query := `
 		UPDATE products
-		SET slug = $1, name = $2, desc = $3, tag = $4, label = $5,
-		    price = $6, avail = $7, items = $8, recipe = $9, custom = $10
-		WHERE id = $11
+		SET slug = $1, name = $2, "desc" = $3, tag = $4, label = $5,
+		    price = $6, avail = $7, items = $8, recipe = $9, custom = $10,
+		    auto_86 = auto_86 AND NOT avail AND NOT $7, -- keep the auto flag only while still unavailable
+		    revision = revision + 1, updated_at = NOW()
+		WHERE id = $11 AND revision = $12 AND store_id = $13 AND deleted_at IS NULL
 	`
 
-	result, err := r.db.ExecContext(
-		ctx, query,
+	ok, err := database.ExecAffected(
+		ctx, r.db, query,
 		product.Slug, product.Name, product.Desc, product.Tag, product.Label,
-		product.Price, product.Avail, itemsJSON, recipeJSON, customJSON, product.Id,
+		product.Price, product.Avail, itemsJSON, recipeJSON, customJSON, product.Id, product.Revision, database.StoreOf(ctx),
 	)
 
 	if err != nil {
-		if isDuplicateKeyError(err) {
+		if database.IsUniqueViolation(err) {
 			return ErrDuplicateProductSlug
 		}
 		return fmt.Errorf("failed to update product: %w", err)
 	}
+	if !ok {
+		return r.missingOrConflict(ctx, product.Id)
+	}
+	product.Revision++
+
+	return nil
+}
 
-	rows, err := result.RowsAffected()
+// Delete moves the product to the trash. Orders keep naming it by slug, and
+// it disappears from lookups and listings until restored or purged.
+func (r *productRepository) Delete(ctx context.Context, id int) error {
+	ok, err := database.SoftDelete(ctx, r.db, "products", id, "revision = revision + 1")
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to delete product: %w", err)
 	}
-
-	if rows == 0 {
+	if !ok {
 		return ErrProductNotFound
 	}
-
 	return nil
 }
 
-func (r *productRepository) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM products WHERE id = $1`
-
-	result, err := r.db.ExecContext(ctx, query, id)
+func (r *productRepository) Restore(ctx context.Context, id int) error {
+	ok, err := database.Restore(ctx, r.db, "products", id, "revision = revision + 1")
 	if err != nil {
-		return fmt.Errorf("failed to delete product: %w", err)
+		return fmt.Errorf("failed to restore product: %w", err)
 	}
+	if !ok {
+		return ErrProductNotFound
+	}
+	return nil
+}
 
-	rows, err := result.RowsAffected()
+// Purge permanently removes a product that is in the trash. It is refused
+// while a bundle, trashed or not, still lists the product.
+func (r *productRepository) Purge(ctx context.Context, id int) error {
+	var slug string
+	store := database.StoreOf(ctx)
+	err := r.db.QueryRowContext(ctx, `SELECT slug FROM products WHERE id = $1 AND store_id = $2 AND deleted_at IS NOT NULL`, id, store).Scan(&slug)
+	if err == sql.ErrNoRows {
+		return ErrProductNotFound
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to get product: %w", err)
 	}
 
-	if rows == 0 {
+	var inBundle bool
+	query := `SELECT EXISTS(SELECT 1 FROM products WHERE id <> $1 AND store_id = $3 AND ` + r.dialect.JSONType("items") + ` = 'array' AND ` + r.dialect.JSONHasElement("items", "$2") + `)`
+	if err := r.db.QueryRowContext(ctx, query, id, slug, store).Scan(&inBundle); err != nil {
+		return fmt.Errorf("failed to check bundles: %w", err)
+	}
+	if inBundle {
+		return ErrProductInUse
+	}
+
+	ok, err := database.ExecAffected(ctx, r.db, `DELETE FROM products WHERE id = $1 AND store_id = $2 AND deleted_at IS NOT NULL`, id, store)
+	if err != nil {
+		return fmt.Errorf("failed to purge product: %w", err)
+	}
+	if !ok {
 		return ErrProductNotFound
 	}
 
 	return nil
 }
 
-func (r *productRepository) List(ctx context.Context, opts ProductListOptions) ([]*Product, error) {
-	query := `
-		SELECT id, slug, name, desc, tag, label, price, avail, items, recipe, custom
-		FROM products
-		WHERE 1=1
-	`
-	args := []any{}
-	argPos := 1
+// TrashedBefore returns the ids of products deleted before the cutoff, for
+// the purge job.
+func (r *productRepository) TrashedBefore(ctx context.Context, cutoff time.Time) ([]int, error) {
+	ids, err := database.TrashedBefore(ctx, r.db, "products", cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trashed products: %w", err)
+	}
+	return ids, nil
+}
 
-	if opts.Tag != "" {
-		query += fmt.Sprintf(" AND tag = $%d", argPos)
-		args = append(args, opts.Tag)
-		argPos++
+// List returns one page of the products matching opts, and with
+// opts.Total how many match in all.
+func (r *productRepository) List(ctx context.Context, opts ProductListOptions) ([]*Product, int, error) {
+	where := database.Live
+	if opts.Deleted {
+		where = database.Trashed
 	}
 
+	var f database.Filter
+	f.And("store_id = " + f.Arg(database.StoreOf(ctx)))
+	if opts.Tag != "" {
+		f.And("tag = " + f.Arg(opts.Tag))
+	}
 	if opts.Label != "" {
-		query += fmt.Sprintf(" AND label = $%d", argPos)
-		args = append(args, opts.Label)
-		argPos++
+		f.And("label = " + f.Arg(opts.Label))
 	}
-
 	if opts.Avail != nil {
-		query += fmt.Sprintf(" AND avail = $%d", argPos)
-		args = append(args, *opts.Avail)
-		argPos++
+		f.And("avail = " + f.Arg(*opts.Avail))
 	}
-
 	if opts.MinPrice > 0 {
-		query += fmt.Sprintf(" AND price >= $%d", argPos)
-		args = append(args, opts.MinPrice)
-		argPos++
+		f.And("price >= " + f.Arg(opts.MinPrice))
 	}
-
 	if opts.MaxPrice > 0 {
-		query += fmt.Sprintf(" AND price <= $%d", argPos)
-		args = append(args, opts.MaxPrice)
-		argPos++
-	}
-
-	// Sorting
-	sortBy := "id"
-	if opts.SortBy != "" {
-		switch opts.SortBy {
-		case "name", "price", "slug":
-			sortBy = opts.SortBy
-		}
-	}
-
-	sortOrder := "ASC"
-	if opts.SortOrder == "desc" {
-		sortOrder = "DESC"
-	}
-
-	query += fmt.Sprintf(" ORDER BY %s %s", sortBy, sortOrder)
-
-	if opts.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argPos)
-		args = append(args, opts.Limit)
-		argPos++
+		f.And("price <= " + f.Arg(opts.MaxPrice))
 	}
+	f.Period(opts.Period)
 
-	if opts.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argPos)
-		args = append(args, opts.Offset)
+	k := productKeyset(opts.SortBy, opts.SortOrder)
+	cursor := f.Outer()
+	if err := k.After(r.dialect, &cursor, opts.After); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidProductInput, err)
 	}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	products, total, err := database.SelectListing(ctx, r.db, r.scanProduct, database.Listing{
+		Columns: r.productColumns(), Table: "products", Where: where, Filter: f, Cursor: cursor,
+		OrderBy: k.OrderBy(r.dialect), Limit: opts.Limit, Offset: opts.Offset, Total: opts.Total,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to list products: %w", err)
+		return nil, 0, fmt.Errorf("failed to list products: %w", err)
 	}
-	defer rows.Close()
+	return products, total, nil
+}
 
-	var products []*Product
-	for rows.Next() {
-		product, err := r.scanProduct(rows)
-		if err != nil {
-			return nil, err
-		}
-		products = append(products, product)
+// productKeyset is the order List sorts products in.
+func productKeyset(sortBy, sortOrder string) database.Keyset {
+	return database.Keyset{
+		Column:    database.SortColumn(sortBy, "id", "name", "price", "slug", "created_at", "updated_at"),
+		Direction: database.SortDirection(sortOrder, "ASC"),
 	}
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+// nextCursor is the cursor for the page after one ending with p, listed
+// in the given order.
+func nextCursor(p *Product, sortBy, sortOrder string) string {
+	k := productKeyset(sortBy, sortOrder)
+	keys := map[string]any{
+		"id": p.Id, "name": p.Name, "price": p.Price, "slug": p.Slug, "created_at": p.Created, "updated_at": p.Updated,
 	}
-
-	return products, nil
+	return k.Cursor(keys[k.Column], p.Id)
 }
 
 func (r *productRepository) SetAvailability(ctx context.Context, id int, avail bool) error {
-	query := `UPDATE products SET avail = $1 WHERE id = $2`
+	// A manual toggle overrides any automatic (stock-driven) decision
+	query := `UPDATE products SET avail = $1, auto_86 = FALSE, revision = revision + 1, updated_at = NOW() WHERE id = $2 AND store_id = $3 AND deleted_at IS NULL`
 
-	result, err := r.db.ExecContext(ctx, query, avail, id)
+	ok, err := database.ExecAffected(ctx, r.db, query, avail, id, database.StoreOf(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to set availability: %w", err)
 	}
-
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	if rows == 0 {
+	if !ok {
 		return ErrProductNotFound
 	}
 
@@ -336,199 +389,104 @@ func (r *productRepository) SetAvailability(ctx context.Context, id int, avail b
 
 func (r *productRepository) GetAvailable(ctx context.Context) ([]*Product, error) {
 	query := `
-		SELECT id, slug, name, desc, tag, label, price, avail, items, recipe, custom
+		SELECT ` + r.productColumns() + `
 		FROM products
-		WHERE avail = true
+		WHERE avail = true AND store_id = $1 AND deleted_at IS NULL
 		ORDER BY name
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	products, err := database.Select(ctx, r.db, r.scanProduct, query, database.StoreOf(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get available products: %w", err)
 	}
-	defer rows.Close()
-
-	var products []*Product
-	for rows.Next() {
-		product, err := r.scanProduct(rows)
-		if err != nil {
-			return nil, err
-		}
-		products = append(products, product)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
 	return products, nil
 }
 
 func (r *productRepository) GetByTag(ctx context.Context, tag string) ([]*Product, error) {
 	query := `
-		SELECT id, slug, name, desc, tag, label, price, avail, items, recipe, custom
+		SELECT ` + r.productColumns() + `
 		FROM products
-		WHERE tag = $1
+		WHERE tag = $1 AND store_id = $2 AND deleted_at IS NULL
 		ORDER BY name
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, tag)
+	products, err := database.Select(ctx, r.db, r.scanProduct, query, tag, database.StoreOf(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get products by tag: %w", err)
 	}
-	defer rows.Close()
-
-	var products []*Product
-	for rows.Next() {
-		product, err := r.scanProduct(rows)
-		if err != nil {
-			return nil, err
-		}
-		products = append(products, product)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
 	return products, nil
 }
 
 func (r *productRepository) GetByLabel(ctx context.Context, label string) ([]*Product, error) {
 	query := `
-		SELECT id, slug, name, desc, tag, label, price, avail, items, recipe, custom
+		SELECT ` + r.productColumns() + `
 		FROM products
-		WHERE label = $1
+		WHERE label = $1 AND store_id = $2 AND deleted_at IS NULL
 		ORDER BY name
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, label)
+	products, err := database.Select(ctx, r.db, r.scanProduct, query, label, database.StoreOf(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get products by label: %w", err)
 	}
-	defer rows.Close()
-
-	var products []*Product
-	for rows.Next() {
-		product, err := r.scanProduct(rows)
-		if err != nil {
-			return nil, err
-		}
-		products = append(products, product)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
 	return products, nil
 }
 
 func (r *productRepository) GetBundles(ctx context.Context) ([]*Product, error) {
 	query := `
-		SELECT id, slug, name, desc, tag, label, price, avail, items, recipe, custom
+		SELECT ` + r.productColumns() + `
 		FROM products
-		WHERE items IS NOT NULL
+		WHERE items IS NOT NULL AND store_id = $1 AND deleted_at IS NULL
 		ORDER BY name
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	products, err := database.Select(ctx, r.db, r.scanProduct, query, database.StoreOf(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get bundles: %w", err)
 	}
-	defer rows.Close()
-
-	var products []*Product
-	for rows.Next() {
-		product, err := r.scanProduct(rows)
-		if err != nil {
-			return nil, err
-		}
-		products = append(products, product)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
 	return products, nil
 }
 
 func (r *productRepository) GetWithRecipe(ctx context.Context) ([]*Product, error) {
 	query := `
-		SELECT id, slug, name, desc, tag, label, price, avail, items, recipe, custom
+		SELECT ` + r.productColumns() + `
 		FROM products
-		WHERE recipe IS NOT NULL
+		WHERE recipe IS NOT NULL AND store_id = $1 AND deleted_at IS NULL
 		ORDER BY name
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	products, err := database.Select(ctx, r.db, r.scanProduct, query, database.StoreOf(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get products with recipe: %w", err)
 	}
-	defer rows.Close()
-
-	var products []*Product
-	for rows.Next() {
-		product, err := r.scanProduct(rows)
-		if err != nil {
-			return nil, err
-		}
-		products = append(products, product)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
 	return products, nil
 }
 
 func (r *productRepository) Search(ctx context.Context, query string) ([]*Product, error) {
-	searchQuery := `
-		SELECT id, slug, name, desc, tag, label, price, avail, items, recipe, custom
+	d := r.dialect
+	searchQuery := fmt.Sprintf(`
+		SELECT %s
 		FROM products
-		WHERE name ILIKE $1 OR desc ILIKE $1 OR tag ILIKE $1
+		WHERE (%s OR %s OR %s) AND store_id = $2 AND deleted_at IS NULL
 		ORDER BY name
-	`
+	`, r.productColumns(), d.ILike("name", "$1"), d.ILike(`"desc"`, "$1"), d.ILike("tag", "$1"))
 
 	searchPattern := "%" + query + "%"
-	rows, err := r.db.QueryContext(ctx, searchQuery, searchPattern)
+	products, err := database.Select(ctx, r.db, r.scanProduct, searchQuery, searchPattern, database.StoreOf(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to search products: %w", err)
 	}
-	defer rows.Close()
-
-	var products []*Product
-	for rows.Next() {
-		product, err := r.scanProduct(rows)
-		if err != nil {
-			return nil, err
-		}
-		products = append(products, product)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
 	return products, nil
 }
 
 func (r *productRepository) UpdatePrice(ctx context.Context, id int, price int64) error {
-	query := `UPDATE products SET price = $1 WHERE id = $2`
+	query := `UPDATE products SET price = $1, revision = revision + 1, updated_at = NOW() WHERE id = $2 AND store_id = $3 AND deleted_at IS NULL`
 
-	result, err := r.db.ExecContext(ctx, query, price, id)
+	ok, err := database.ExecAffected(ctx, r.db, query, price, id, database.StoreOf(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to update price: %w", err)
 	}
-
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	if rows == 0 {
+	if !ok {
 		return ErrProductNotFound
 	}
 
@@ -537,46 +495,50 @@ func (r *productRepository) UpdatePrice(ctx context.Context, id int, price int64
 
 func (r *productRepository) GetByPriceRange(ctx context.Context, minPrice, maxPrice int64) ([]*Product, error) {
 	query := `
-		SELECT id, slug, name, desc, tag, label, price, avail, items, recipe, custom
+		SELECT ` + r.productColumns() + `
 		FROM products
-		WHERE price >= $1 AND price <= $2
+		WHERE price >= $1 AND price <= $2 AND store_id = $3 AND deleted_at IS NULL
 		ORDER BY price
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, minPrice, maxPrice)
+	products, err := database.Select(ctx, r.db, r.scanProduct, query, minPrice, maxPrice, database.StoreOf(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get products by price range: %w", err)
 	}
-	defer rows.Close()
-
-	var products []*Product
-	for rows.Next() {
-		product, err := r.scanProduct(rows)
-		if err != nil {
-			return nil, err
-		}
-		products = append(products, product)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
 	return products, nil
 }
 
 // Helper methods
 
-func (r *productRepository) scanProduct(scanner interface {
-	Scan(dest ...any) error
-}) (*Product, error) {
+// productColumns is the SELECT list matched by scanProduct.
+func (r *productRepository) productColumns() string {
+	return `id, slug, name, "desc", tag, label, price, avail, items, recipe, custom, revision,
+	` + r.dialect.Epoch("created_at") + `, ` + r.dialect.Epoch("updated_at") + `, COALESCE(` + r.dialect.Epoch("deleted_at") + `, 0)`
+}
+
+// missingOrConflict explains an update that matched no row: either the
+// product is gone (or in the trash) or its revision moved on.
+func (r *productRepository) missingOrConflict(ctx context.Context, id int) error {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM products WHERE id = $1 AND store_id = $2 AND deleted_at IS NULL)`
+	if err := r.db.QueryRowContext(ctx, query, id, database.StoreOf(ctx)).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check product: %w", err)
+	}
+	if !exists {
+		return ErrProductNotFound
+	}
+	return ErrProductConflict
+}
+
+func (r *productRepository) scanProduct(scanner database.Scanner) (*Product, error) {
 	product := &Product{}
 	var itemsJSON, recipeJSON, customJSON []byte
 
 	err := scanner.Scan(
 		&product.Id, &product.Slug, &product.Name, &product.Desc,
 		&product.Tag, &product.Label, &product.Price, &product.Avail,
-		&itemsJSON, &recipeJSON, &customJSON,
+		&itemsJSON, &recipeJSON, &customJSON, &product.Revision,
+		&product.Created, &product.Updated, &product.Deleted,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan product: %w", err)
@@ -607,7 +569,7 @@ func (r *productRepository) unmarshalProductData(product *Product, itemsJSON, re
 	}
 
 	if len(customJSON) > 0 {
-		if err := json.Unmarshal(customJSON, &product.Custom); err != nil {
+		if err := database.UnmarshalCustom("product", customJSON, &product.Custom); err != nil {
 			return fmt.Errorf("failed to unmarshal custom data: %w", err)
 		}
 	}
@@ -615,6 +577,13 @@ func (r *productRepository) unmarshalProductData(product *Product, itemsJSON, re
 	return nil
 }
 
+// scanIdSlug reads just the keys of a product, for clash checks.
+func scanIdSlug(s database.Scanner) (*Product, error) {
+	var p Product
+	err := s.Scan(&p.Id, &p.Slug)
+	return &p, err
+}
+
 func (r *productRepository) marshalNullableSlice(items *[]string) ([]byte, error) {
 	if items == nil {
 		return nil, nil
@@ -628,13 +597,3 @@ func (r *productRepository) marshalNullableMap(recipe *map[string]int) ([]byte,
 	}
 	return json.Marshal(recipe)
 }
-
-// Helper function to check for duplicate key violations
-// This is PostgreSQL-specific; adjust for your database
-func isDuplicateKeyError(err error) bool {
-	// You might want to import "github.com/lib/pq" and check:
-	// if pqErr, ok := err.(*pq.Error); ok {
-	//     return pqErr.Code == "23505"
-	// }
-	return false
-}
@@ -2,6 +2,13 @@ package product
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/audit"
+	"github.com/iteranya/practicing-go/internal/database"
 )
 
 type ProductService interface {
@@ -9,6 +16,9 @@ type ProductService interface {
 	GetProduct(ctx context.Context, idOrSlug any) (*Product, error)
 	UpdateProduct(ctx context.Context, id int, product Product) error
 	DeleteProduct(ctx context.Context, id int) error
+	RestoreProduct(ctx context.Context, id int) error
+	PurgeProduct(ctx context.Context, id int) error
+	PurgeDeleted(ctx context.Context, before time.Time) (int, error)
 	ListProducts(ctx context.Context, params ProductServiceListParams) ([]*Product, error)
 
 	// Specific Actions
@@ -21,6 +31,7 @@ type ProductService interface {
 }
 
 type ProductServiceListParams struct {
+	Deleted  bool // List the trash instead of live products
 	Tag      string
 	Label    string
 	Query    string // For search
@@ -29,15 +40,24 @@ type ProductServiceListParams struct {
 	MaxPrice int64
 	Limit    int
 	Page     int
+	After    string          // Cursor from the page before, in place of Page
+	Period   database.Period // Created and updated time ranges
 	SortBy   string
 }
 
+// IngredientChecker reports which recipe slugs do not match an active
+// inventory item. It is implemented by the inventory service.
+type IngredientChecker interface {
+	MissingIngredients(ctx context.Context, slugs []string) ([]string, error)
+}
+
 type productService struct {
-	repo ProductRepository
+	repo        ProductRepository
+	ingredients IngredientChecker
 }
 
-func NewProductService(repo ProductRepository) ProductService {
-	return &productService{repo: repo}
+func NewProductService(repo ProductRepository, ingredients IngredientChecker) ProductService {
+	return &productService{repo: repo, ingredients: ingredients}
 }
 
 func (s *productService) CreateProduct(ctx context.Context, product Product) (*Product, error) {
@@ -51,12 +71,16 @@ func (s *productService) CreateProduct(ctx context.Context, product Product) (*P
 
 	// Validate Recipe/Items logic if necessary (e.g. can't be both bundle and recipe?)
 	// For now, we allow flexibility.
+	if err := s.validateRecipe(ctx, product.Recipe); err != nil {
+		return nil, err
+	}
 
 	err := s.repo.Create(ctx, &product)
 	if err != nil {
 		return nil, err
 	}
 
+	audit.Record(ctx, audit.Product, product.Id, audit.Create, audit.Diff(nil, &product))
 	return &product, nil
 }
 
@@ -71,24 +95,89 @@ func (s *productService) GetProduct(ctx context.Context, idOrSlug any) (*Product
 	}
 }
 
+// UpdateProduct replaces the product. The input carries the revision it
+// was based on; if someone else saved in between, it fails with
+// ErrProductConflict rather than overwrite their change.
 func (s *productService) UpdateProduct(ctx context.Context, id int, product Product) error {
 	if id == 0 {
 		return ErrInvalidProductInput
 	}
+	if product.Revision == 0 {
+		return fmt.Errorf("%w: revision is required", ErrInvalidProductInput)
+	}
 
 	// Ensure ID is set on the struct
 	product.Id = id
 
-	return s.repo.Update(ctx, &product)
+	if err := s.validateRecipe(ctx, product.Recipe); err != nil {
+		return err
+	}
+
+	before, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Update(ctx, &product); err != nil {
+		return err
+	}
+
+	audit.Record(ctx, audit.Product, id, audit.Update, audit.Diff(before, &product))
+	return nil
 }
 
+// DeleteProduct moves the product to the trash; see RestoreProduct and
+// PurgeProduct.
 func (s *productService) DeleteProduct(ctx context.Context, id int) error {
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	audit.Record(ctx, audit.Product, id, audit.Delete, nil)
+	return nil
+}
+
+func (s *productService) RestoreProduct(ctx context.Context, id int) error {
+	if err := s.repo.Restore(ctx, id); err != nil {
+		return err
+	}
+	audit.Record(ctx, audit.Product, id, audit.Restore, nil)
+	return nil
+}
+
+// PurgeProduct permanently removes a product that is already in the trash.
+func (s *productService) PurgeProduct(ctx context.Context, id int) error {
+	if err := s.repo.Purge(ctx, id); err != nil {
+		return err
+	}
+	audit.Record(ctx, audit.Product, id, audit.Purge, nil)
+	return nil
+}
+
+// PurgeDeleted purges the products deleted before the given time and
+// returns how many went. Products a bundle still lists stay in the trash.
+func (s *productService) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	ids, err := s.repo.TrashedBefore(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		err := s.repo.Purge(ctx, id)
+		if errors.Is(err, ErrProductInUse) || errors.Is(err, ErrProductNotFound) {
+			continue // still needed, or restored in the meantime
+		}
+		if err != nil {
+			return purged, err
+		}
+		audit.Record(ctx, audit.Product, id, audit.Purge, nil)
+		purged++
+	}
+	return purged, nil
 }
 
 func (s *productService) ListProducts(ctx context.Context, params ProductServiceListParams) ([]*Product, error) {
-	// 1. Handle textual search
-	if params.Query != "" {
+	// 1. Handle textual search (live products only)
+	if params.Query != "" && !params.Deleted {
 		return s.repo.Search(ctx, params.Query)
 	}
 
@@ -101,33 +190,59 @@ func (s *productService) ListProducts(ctx context.Context, params ProductService
 	*/
 
 	offset := 0
-	if params.Page > 1 {
+	if params.Page > 1 && params.After == "" {
 		offset = (params.Page - 1) * params.Limit
 	}
 
 	repoOpts := ProductListOptions{
+		Deleted:  params.Deleted,
 		Tag:      params.Tag,
 		Label:    params.Label,
 		Avail:    params.Avail,
 		MinPrice: params.MinPrice,
 		MaxPrice: params.MaxPrice,
+		Period:   params.Period,
 		SortBy:   params.SortBy,
 		Limit:    params.Limit,
 		Offset:   offset,
+		After:    params.After,
 	}
 
-	return s.repo.List(ctx, repoOpts)
+	products, _, err := s.repo.List(ctx, repoOpts)
+	return products, err
 }
 
 func (s *productService) SetAvailability(ctx context.Context, id int, available bool) error {
-	return s.repo.SetAvailability(ctx, id, available)
+	before, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.SetAvailability(ctx, id, available); err != nil {
+		return err
+	}
+
+	after := *before
+	after.Avail = available
+	audit.Record(ctx, audit.Product, id, audit.Update, audit.Diff(before, &after))
+	return nil
 }
 
 func (s *productService) UpdatePrice(ctx context.Context, id int, newPrice int64) error {
 	if newPrice < 0 {
 		return ErrInvalidProductInput
 	}
-	return s.repo.UpdatePrice(ctx, id, newPrice)
+	before, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePrice(ctx, id, newPrice); err != nil {
+		return err
+	}
+
+	after := *before
+	after.Price = newPrice
+	audit.Record(ctx, audit.Product, id, audit.Update, audit.Diff(before, &after))
+	return nil
 }
 
 func (s *productService) GetBundles(ctx context.Context) ([]*Product, error) {
@@ -137,3 +252,25 @@ func (s *productService) GetBundles(ctx context.Context) ([]*Product, error) {
 func (s *productService) GetProductsWithRecipes(ctx context.Context) ([]*Product, error) {
 	return s.repo.GetWithRecipe(ctx)
 }
+
+// validateRecipe rejects recipes that use unknown or deleted inventory.
+func (s *productService) validateRecipe(ctx context.Context, recipe *map[string]int) error {
+	if recipe == nil || len(*recipe) == 0 {
+		return nil
+	}
+
+	slugs := make([]string, 0, len(*recipe))
+	for slug := range *recipe {
+		slugs = append(slugs, slug)
+	}
+
+	missing, err := s.ingredients.MissingIngredients(ctx, slugs)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownIngredient, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+
+	"github.com/iteranya/practicing-go/internal/utils"
 )
 
 type RoleHandler struct {
@@ -15,18 +17,29 @@ func NewRoleHandler(service RoleService) *RoleHandler {
 	return &RoleHandler{service: service}
 }
 
-func (h *RoleHandler) RegisterRoutes(mux *http.ServeMux) {
-	// Standard CRUD
-	mux.HandleFunc("POST /roles", h.HandleCreate)
-	mux.HandleFunc("GET /roles", h.HandleList)
-	mux.HandleFunc("GET /roles/{id}", h.HandleGet) // supports id or slug
-	mux.HandleFunc("PUT /roles/{id}", h.HandleUpdate)
-	mux.HandleFunc("DELETE /roles/{id}", h.HandleDelete)
-
-	// Permission Management
-	mux.HandleFunc("PUT /roles/{id}/permissions", h.HandleSetPermissions)      // Replace all
-	mux.HandleFunc("POST /roles/{id}/permissions", h.HandleAddPermission)      // Add one
-	mux.HandleFunc("DELETE /roles/{id}/permissions", h.HandleRemovePermission) // Remove one
+func (h *RoleHandler) Routes() []utils.Route {
+	return []utils.Route{
+		// Standard CRUD
+		{Pattern: "POST /roles", Perm: utils.PermRoleCreate, Handler: h.HandleCreate},
+		{Pattern: "GET /roles", Perm: utils.PermRoleRead, Handler: h.HandleList},
+		{Pattern: "GET /roles/usage", Perm: utils.PermRoleRead, Handler: h.HandleUsage},
+		{Pattern: "GET /roles/{id}", Perm: utils.PermRoleRead, Handler: h.HandleGet}, // supports id or slug
+		{Pattern: "PUT /roles/{id}", Perm: utils.PermRoleUpdate, Handler: h.HandleUpdate},
+		{Pattern: "DELETE /roles/{id}", Perm: utils.PermRoleDelete, Handler: h.HandleDelete}, // soft delete, see ?deleted=true
+		{Pattern: "POST /roles/{id}/restore", Perm: utils.PermRoleDelete, Handler: h.HandleRestore},
+		{Pattern: "DELETE /roles/{id}/purge", Perm: utils.PermRoleDelete, Handler: h.HandlePurge},
+		{Pattern: "POST /roles/{id}/clone", Perm: utils.PermRoleCreate, Handler: h.HandleClone},
+		{Pattern: "GET /roles/{id}/audit", Perm: utils.PermRoleRead, Handler: h.HandleAudit},
+
+		// Permission Management
+		{Pattern: "PUT /roles/{id}/permissions", Perm: utils.PermRoleUpdate, Handler: h.HandleSetPermissions},      // Replace all
+		{Pattern: "POST /roles/{id}/permissions", Perm: utils.PermRoleUpdate, Handler: h.HandleAddPermission},      // Add one
+		{Pattern: "DELETE /roles/{id}/permissions", Perm: utils.PermRoleUpdate, Handler: h.HandleRemovePermission}, // Remove one
+
+		// Built-in templates
+		{Pattern: "GET /role-templates", Perm: utils.PermRoleRead, Handler: h.HandleListTemplates},
+		{Pattern: "POST /role-templates/{name}", Perm: utils.PermRoleCreate, Handler: h.HandleCreateFromTemplate},
+	}
 }
 
 // CREATE
@@ -69,7 +82,12 @@ func (h *RoleHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
 
 // LIST
 func (h *RoleHandler) HandleList(w http.ResponseWriter, r *http.Request) {
-	roles, err := h.service.ListRoles(r.Context())
+	list := h.service.ListRoles
+	if deleted, _ := strconv.ParseBool(r.URL.Query().Get("deleted")); deleted {
+		list = h.service.ListDeletedRoles
+	}
+
+	roles, err := list(r.Context())
 	if err != nil {
 		h.respondWithError(w, err)
 		return
@@ -78,6 +96,17 @@ func (h *RoleHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 	h.respondWithJSON(w, http.StatusOK, roles)
 }
 
+// USAGE (holders per role, permissions nobody has)
+func (h *RoleHandler) HandleUsage(w http.ResponseWriter, r *http.Request) {
+	report, err := h.service.GetUsage(r.Context())
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, report)
+}
+
 // UPDATE
 func (h *RoleHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
@@ -101,6 +130,30 @@ func (h *RoleHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
 	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "updated"})
 }
 
+// CLONE
+// Body carries the new Slug and Name; permissions and parent are copied.
+func (h *RoleHandler) HandleClone(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var input Role
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.service.CloneRole(r.Context(), id, input.Slug, input.Name)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, created)
+}
+
 // DELETE
 func (h *RoleHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
@@ -110,12 +163,78 @@ func (h *RoleHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.DeleteRole(r.Context(), id); err != nil {
+	// ?reassign=<slug> moves the role's users there instead of refusing
+	moved, err := h.service.DeleteRole(r.Context(), id, r.URL.Query().Get("reassign"))
+	var inUse *RoleInUseError
+	if errors.As(err, &inUse) {
+		h.respondWithJSON(w, http.StatusConflict, map[string]any{"error": ErrRoleInUse.Error(), "users": inUse.Users})
+		return
+	}
+	if err != nil {
 		h.respondWithError(w, err)
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+	h.respondWithJSON(w, http.StatusOK, map[string]any{"status": "deleted", "reassigned": moved})
+}
+
+// RESTORE (undo a soft delete)
+func (h *RoleHandler) HandleRestore(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RestoreRole(r.Context(), id); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "restored"})
+}
+
+// PURGE (permanent, only for roles in the trash)
+func (h *RoleHandler) HandlePurge(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.PurgeRole(r.Context(), id); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "purged"})
+}
+
+// AUDIT (change history, newest first)
+func (h *RoleHandler) HandleAudit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit <= 0 {
+		limit = 50
+	}
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+
+	entries, err := h.service.ListAudit(r.Context(), id, AuditListParams{Limit: limit, Page: page})
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, entries)
 }
 
 // SET PERMISSIONS (Replace entire list)
@@ -206,14 +325,30 @@ func (h *RoleHandler) respondWithJSON(w http.ResponseWriter, code int, payload a
 	}
 }
 
+// LIST TEMPLATES
+func (h *RoleHandler) HandleListTemplates(w http.ResponseWriter, r *http.Request) {
+	h.respondWithJSON(w, http.StatusOK, h.service.ListTemplates())
+}
+
+// CREATE FROM TEMPLATE
+func (h *RoleHandler) HandleCreateFromTemplate(w http.ResponseWriter, r *http.Request) {
+	created, err := h.service.CreateFromTemplate(r.Context(), r.PathValue("name"))
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, created)
+}
+
 func (h *RoleHandler) respondWithError(w http.ResponseWriter, err error) {
 	var statusCode int
 	switch {
-	case errors.Is(err, ErrRoleNotFound):
+	case errors.Is(err, ErrRoleNotFound), errors.Is(err, ErrUnknownTemplate):
 		statusCode = http.StatusNotFound
-	case errors.Is(err, ErrInvalidRoleInput):
+	case errors.Is(err, ErrInvalidRoleInput), errors.Is(err, ErrRoleCycle):
 		statusCode = http.StatusBadRequest
-	case errors.Is(err, ErrDuplicateRoleSlug):
+	case errors.Is(err, ErrDuplicateRoleSlug), errors.Is(err, ErrRoleInUse), errors.Is(err, ErrSystemRole):
 		statusCode = http.StatusConflict
 	default:
 		statusCode = http.StatusInternalServerError
@@ -1,8 +1,51 @@
 package role
 
+import "time"
+
 type Role struct {
 	Id          int
 	Slug        string
 	Name        string
 	Permissions []string
+	Parent      string // Slug of the role to inherit permissions from, "" for none
+	System      bool   // Seeded role that must stay usable, see checkSystem
+	Created     int64  // Unix timestamps
+	Updated     int64
+	Deleted     int64 // Unix timestamp of the soft delete, 0 while active
+}
+
+// AuditEntry is one change in a role's audit trail, read from the shared
+// audit log (see audit.Entry).
+type AuditEntry struct {
+	Id        int            `json:"id"`
+	RoleId    int            `json:"role_id"`
+	ActorId   *int           `json:"actor_id,omitempty"` // Nil for changes made at startup
+	Action    string         `json:"action"`
+	Detail    map[string]any `json:"detail,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+const (
+	AuditCreate           = "create"
+	AuditUpdate           = "update"
+	AuditDelete           = "delete"
+	AuditRestore          = "restore"
+	AuditPurge            = "purge"
+	AuditPermissionAdd    = "permission_add"
+	AuditPermissionRemove = "permission_remove"
+)
+
+// Usage is how many users hold a role, counting temporary grants still in
+// effect, and what the role effectively allows.
+type Usage struct {
+	Slug        string   `json:"slug"`
+	Name        string   `json:"name"`
+	Users       int      `json:"users"`
+	Permissions []string `json:"permissions"` // Resolved, including inherited ones
+}
+
+// UsageReport lists every role's usage and the permissions no user has.
+type UsageReport struct {
+	Roles             []Usage  `json:"roles"`
+	UnusedPermissions []string `json:"unused_permissions"`
 }
@@ -6,29 +6,59 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
 )
 
 var (
 	ErrRoleNotFound      = errors.New("role not found")
 	ErrDuplicateRoleSlug = errors.New("role slug already exists")
 	ErrInvalidRoleInput  = errors.New("invalid role input")
+	ErrRoleCycle         = errors.New("role cannot inherit from itself or its own descendants")
+	ErrRoleInUse         = errors.New("role is assigned to users")
+	ErrSystemRole        = errors.New("system roles cannot be deleted, renamed or lose critical permissions")
 )
 
+// RoleInUseError is returned when deleting a role users still have. It
+// matches ErrRoleInUse with errors.Is.
+type RoleInUseError struct {
+	Users int
+}
+
+func (e *RoleInUseError) Error() string {
+	return fmt.Sprintf("%s (%d)", ErrRoleInUse, e.Users)
+}
+
+func (e *RoleInUseError) Is(target error) bool {
+	return target == ErrRoleInUse
+}
+
 type RoleRepository interface {
 	Create(ctx context.Context, role *Role) error
+	Import(ctx context.Context, client database.SQLClient, role *Role) (bool, error)
 	GetByID(ctx context.Context, id int) (*Role, error)
 	GetBySlug(ctx context.Context, slug string) (*Role, error)
 	Update(ctx context.Context, role *Role) error
-	Delete(ctx context.Context, id int) error
+	Delete(ctx context.Context, id int) error // Soft delete
+	DeleteAndReassign(ctx context.Context, id int, toSlug string) (moved int, err error)
+	Restore(ctx context.Context, id int) error
+	Purge(ctx context.Context, id int) error // Hard delete, only from the trash
+	TrashedBefore(ctx context.Context, cutoff time.Time) ([]int, error)
+	CountUsers(ctx context.Context, slug string) (int, error)
+	CountUsersByRole(ctx context.Context) (map[string]int, error)
+	RevokeSessions(ctx context.Context, slugs []string) (int, error)
 	List(ctx context.Context) ([]*Role, error)
+	ListDeleted(ctx context.Context) ([]*Role, error)
 }
 
 type roleRepository struct {
-	db *sql.DB
+	db      *database.DB
+	dialect database.Dialect
 }
 
 func NewRoleRepository(db *sql.DB) RoleRepository {
-	return &roleRepository{db: db}
+	return &roleRepository{db: database.Observe(db, "roles"), dialect: database.DialectOf(db)}
 }
 
 func (r *roleRepository) Create(ctx context.Context, role *Role) error {
@@ -47,85 +77,79 @@ func (r *roleRepository) Create(ctx context.Context, role *Role) error {
 	}
 
 	query := `
-        INSERT INTO roles (slug, name, permissions)
-        VALUES ($1, $2, $3)
-        RETURNING id
+        INSERT INTO roles (slug, name, permissions, parent, "system", store_id, created_at, updated_at)
+        VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $7)
     `
+	now := time.Now()
+	args := []any{role.Slug, role.Name, permsJSON, role.Parent, role.System, database.StoreOf(ctx), now}
 
-	err = r.db.QueryRowContext(
-		ctx, query,
-		role.Slug, role.Name, permsJSON,
-	).Scan(&role.Id)
+	err = r.dialect.InsertReturning(ctx, r.db, "roles", query, "id", args, &role.Id)
 
 	if err != nil {
 		// Note: Adapt this check based on your specific DB driver error
-		if isDuplicateKeyError(err) {
+		if database.IsUniqueViolation(err) {
 			return ErrDuplicateRoleSlug
 		}
 		return fmt.Errorf("failed to create role: %w", err)
 	}
 
+	role.Created, role.Updated = now.Unix(), now.Unix()
 	return nil
 }
 
-func (r *roleRepository) GetByID(ctx context.Context, id int) (*Role, error) {
-	query := `
-        SELECT id, slug, name, permissions
-        FROM roles
-        WHERE id = $1
-    `
-
-	role := &Role{}
-	var permsJSON []byte
-
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&role.Id, &role.Slug, &role.Name, &permsJSON,
-	)
+// Import inserts a role read back from a backup as it was, id and
+// timestamps included. It reports false, writing nothing, if a role with
+// its id or slug is already there. The parent must be there first.
+func (r *roleRepository) Import(ctx context.Context, client database.SQLClient, role *Role) (bool, error) {
+	client = r.db.On(client)
+	exists, err := database.Exists(ctx, client, "roles", "id = $1 OR (slug = $2 AND store_id = $3)", role.Id, role.Slug, database.StoreOf(ctx))
+	if err != nil || exists {
+		return false, err
+	}
 
-	if err == sql.ErrNoRows {
-		return nil, ErrRoleNotFound
+	if role.Permissions == nil {
+		role.Permissions = []string{}
 	}
+	permsJSON, err := json.Marshal(role.Permissions)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get role: %w", err)
+		return false, fmt.Errorf("failed to marshal permissions: %w", err)
 	}
 
-	if len(permsJSON) > 0 {
-		if err := json.Unmarshal(permsJSON, &role.Permissions); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal permissions: %w", err)
-		}
+	query := `
+        INSERT INTO roles (id, slug, name, permissions, parent, "system", store_id, created_at, updated_at, deleted_at)
+        VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10)
+    `
+	args := []any{
+		role.Id, role.Slug, role.Name, permsJSON, role.Parent, role.System, database.StoreOf(ctx),
+		database.FromUnix(role.Created), database.FromUnix(role.Updated), database.FromUnix(role.Deleted),
 	}
-
-	return role, nil
+	if _, err := client.ExecContext(ctx, query, args...); err != nil {
+		return false, fmt.Errorf("failed to import role: %w", err)
+	}
+	if err := r.dialect.ResetSequence(ctx, client, "roles"); err != nil {
+		return false, fmt.Errorf("failed to reset role ids: %w", err)
+	}
+	return true, nil
 }
 
-func (r *roleRepository) GetBySlug(ctx context.Context, slug string) (*Role, error) {
+func (r *roleRepository) GetByID(ctx context.Context, id int) (*Role, error) {
 	query := `
-        SELECT id, slug, name, permissions
+        SELECT ` + r.roleColumns() + `
         FROM roles
-        WHERE slug = $1
+        WHERE id = $1 AND store_id = $2 AND deleted_at IS NULL
     `
 
-	role := &Role{}
-	var permsJSON []byte
-
-	err := r.db.QueryRowContext(ctx, query, slug).Scan(
-		&role.Id, &role.Slug, &role.Name, &permsJSON,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, ErrRoleNotFound
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to get role: %w", err)
-	}
+	return database.Get(ctx, r.db, r.scanRole, ErrRoleNotFound, query, id, database.StoreOf(ctx))
+}
 
-	if len(permsJSON) > 0 {
-		if err := json.Unmarshal(permsJSON, &role.Permissions); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal permissions: %w", err)
-		}
-	}
+func (r *roleRepository) GetBySlug(ctx context.Context, slug string) (*Role, error) {
+	query := `
+        SELECT ` + r.roleColumns() + `
+        FROM roles
+        WHERE slug = $1 AND store_id = $2 AND deleted_at IS NULL
+    `
 
-	return role, nil
+	return database.Get(ctx, r.db, r.scanRole, ErrRoleNotFound, query, slug, database.StoreOf(ctx))
 }
 
 func (r *roleRepository) Update(ctx context.Context, role *Role) error {
@@ -138,97 +162,284 @@ func (r *roleRepository) Update(ctx context.Context, role *Role) error {
 		return fmt.Errorf("failed to marshal permissions: %w", err)
 	}
 
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	slug, err := r.lockSlug(ctx, tx, role.Id)
+	if err != nil {
+		return err
+	}
+
 	query := `
         UPDATE roles
-        SET slug = $1, name = $2, permissions = $3
-        WHERE id = $4
+        SET slug = $1, name = $2, permissions = $3, parent = NULLIF($4, ''), updated_at = NOW()
+        WHERE id = $5
     `
 
-	result, err := r.db.ExecContext(
+	_, err = tx.ExecContext(
 		ctx, query,
-		role.Slug, role.Name, permsJSON, role.Id,
+		role.Slug, role.Name, permsJSON, role.Parent, role.Id,
 	)
 
 	if err != nil {
-		if isDuplicateKeyError(err) {
+		if database.IsUniqueViolation(err) {
 			return ErrDuplicateRoleSlug
 		}
 		return fmt.Errorf("failed to update role: %w", err)
 	}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+	if slug != role.Slug {
+		if err := r.reparent(ctx, tx, slug, role.Slug); err != nil {
+			return err
+		}
 	}
 
-	if rows == 0 {
-		return ErrRoleNotFound
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
 func (r *roleRepository) Delete(ctx context.Context, id int) error {
-	// Optional: Check if any users are assigned this role before deleting
-	// or rely on Foreign Key constraints in the DB (ON DELETE RESTRICT)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	query := `DELETE FROM roles WHERE id = $1`
+	slug, err := r.lockSlug(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if err := r.trash(ctx, tx, id, slug); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	return nil
+}
+
+// lockSlug returns the current slug of a live role, locking its row.
+func (r *roleRepository) lockSlug(ctx context.Context, tx *database.Tx, id int) (string, error) {
+	var slug string
+	err := tx.QueryRowContext(ctx, `SELECT slug FROM roles WHERE id = $1 AND store_id = $2 AND deleted_at IS NULL`+r.dialect.ForUpdate(), id, database.StoreOf(ctx)).Scan(&slug)
+	if err == sql.ErrNoRows {
+		return "", ErrRoleNotFound
+	}
 	if err != nil {
+		return "", fmt.Errorf("failed to get role: %w", err)
+	}
+	return slug, nil
+}
+
+// trash moves a role to the trash. Its children stop inheriting from it,
+// as they did when roles were deleted outright; restoring the role doesn't
+// reattach them.
+func (r *roleRepository) trash(ctx context.Context, tx *database.Tx, id int, slug string) error {
+	if _, err := database.SoftDelete(ctx, tx, "roles", id); err != nil {
 		return fmt.Errorf("failed to delete role: %w", err)
 	}
+	if _, err := tx.ExecContext(ctx, `UPDATE roles SET parent = NULL, updated_at = NOW() WHERE parent = $1 AND store_id = $2`, slug, database.StoreOf(ctx)); err != nil {
+		return fmt.Errorf("failed to update child roles: %w", err)
+	}
+	return nil
+}
 
-	rows, err := result.RowsAffected()
+// Restore takes a role out of the trash.
+func (r *roleRepository) Restore(ctx context.Context, id int) error {
+	ok, err := database.Restore(ctx, r.db, "roles", id)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to restore role: %w", err)
 	}
+	if !ok {
+		return ErrRoleNotFound
+	}
+	return nil
+}
 
-	if rows == 0 {
+// Purge permanently removes a role that is in the trash. Its audit trail
+// stays.
+func (r *roleRepository) Purge(ctx context.Context, id int) error {
+	ok, err := database.ExecAffected(ctx, r.db, `DELETE FROM roles WHERE id = $1 AND store_id = $2 AND deleted_at IS NOT NULL`, id, database.StoreOf(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to purge role: %w", err)
+	}
+	if !ok {
 		return ErrRoleNotFound
 	}
 
 	return nil
 }
 
-func (r *roleRepository) List(ctx context.Context) ([]*Role, error) {
+// TrashedBefore returns the ids of roles deleted before the cutoff, for the
+// purge job.
+func (r *roleRepository) TrashedBefore(ctx context.Context, cutoff time.Time) ([]int, error) {
+	ids, err := database.TrashedBefore(ctx, r.db, "roles", cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trashed roles: %w", err)
+	}
+	return ids, nil
+}
+
+// reparent points the children of a renamed role at its new slug. The
+// foreign key on roles.parent does this everywhere but MySQL, which can't
+// cascade from a table to itself on update.
+func (r *roleRepository) reparent(ctx context.Context, tx *database.Tx, from, to string) error {
+	if r.dialect != database.MySQL {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE roles SET parent = NULLIF($1, ''), updated_at = NOW() WHERE parent = $2 AND store_id = $3`, to, from, database.StoreOf(ctx)); err != nil {
+		return fmt.Errorf("failed to update child roles: %w", err)
+	}
+	return nil
+}
+
+// DeleteAndReassign moves every user of the role to toSlug and deletes the
+// role in one transaction, so nobody is left with a role that doesn't exist.
+// The moved users' access tokens are revoked since they carry the old role.
+func (r *roleRepository) DeleteAndReassign(ctx context.Context, id int, toSlug string) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	slug, err := r.lockSlug(ctx, tx, id)
+	if err != nil {
+		return 0, err
+	}
+	if err := r.trash(ctx, tx, id, slug); err != nil {
+		return 0, err
+	}
+
+	query := `UPDATE users SET role = $1, token_version = token_version + 1, revision = revision + 1, updated_at = NOW() WHERE role = $2 AND store_id = $3`
+	moved, err := database.ExecCount(ctx, tx, query, toSlug, slug, database.StoreOf(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to reassign role users: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return moved, nil
+}
+
+// CountUsers counts the accounts (deleted ones aside) that have the role.
+func (r *roleRepository) CountUsers(ctx context.Context, slug string) (int, error) {
+	query := `SELECT COUNT(*) FROM users WHERE role = $1 AND store_id = $2 AND deleted_at IS NULL`
+
+	var n int
+	if err := r.db.QueryRowContext(ctx, query, slug, database.StoreOf(ctx)).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count role users: %w", err)
+	}
+	return n, nil
+}
+
+// CountUsersByRole counts the holders of every role in use. Temporary grants
+// still in effect count towards their role as well as the regular one.
+func (r *roleRepository) CountUsersByRole(ctx context.Context) (map[string]int, error) {
 	query := `
-        SELECT id, slug, name, permissions
-        FROM roles
-        ORDER BY name ASC
+        SELECT slug, COUNT(*) FROM (
+            SELECT role AS slug FROM users WHERE store_id = $1 AND deleted_at IS NULL
+            UNION ALL
+            SELECT temp_role FROM users
+            WHERE store_id = $1 AND deleted_at IS NULL AND temp_role IS NOT NULL AND temp_role_expires_at > NOW()
+        ) holders
+        GROUP BY slug
     `
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, database.StoreOf(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("failed to list roles: %w", err)
+		return nil, fmt.Errorf("failed to count role users: %w", err)
 	}
 	defer rows.Close()
 
-	var roles []*Role
+	counts := map[string]int{}
 	for rows.Next() {
-		role := &Role{}
-		var permsJSON []byte
-
-		err := rows.Scan(&role.Id, &role.Slug, &role.Name, &permsJSON)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan role: %w", err)
+		var slug string
+		var n int
+		if err := rows.Scan(&slug, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan role count: %w", err)
 		}
-
-		if len(permsJSON) > 0 {
-			if err := json.Unmarshal(permsJSON, &role.Permissions); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal permissions: %w", err)
-			}
-		}
-
-		roles = append(roles, role)
+		counts[slug] = n
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
+	return counts, nil
+}
+
+// RevokeSessions bumps the token version of everyone holding one of the
+// roles, regular or temporary, so their access tokens stop being accepted.
+func (r *roleRepository) RevokeSessions(ctx context.Context, slugs []string) (int, error) {
+	query := fmt.Sprintf(`
+        UPDATE users SET token_version = token_version + 1
+        WHERE (%s OR %s) AND store_id = $2
+    `, r.dialect.AnyOf("role", "$1"), r.dialect.AnyOf("temp_role", "$1"))
+
+	n, err := database.ExecCount(ctx, r.db, query, r.dialect.Array(slugs), database.StoreOf(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke role sessions: %w", err)
+	}
+	return n, nil
+}
+
+// List returns the live roles; everything that resolves permissions goes
+// through it, so a role in the trash grants nothing.
+func (r *roleRepository) List(ctx context.Context) ([]*Role, error) {
+	return r.list(ctx, database.Live)
+}
+
+// ListDeleted returns the roles in the trash.
+func (r *roleRepository) ListDeleted(ctx context.Context) ([]*Role, error) {
+	return r.list(ctx, database.Trashed)
+}
+
+func (r *roleRepository) list(ctx context.Context, where string) ([]*Role, error) {
+	query := `
+        SELECT ` + r.roleColumns() + `
+        FROM roles
+        WHERE store_id = $1 AND ` + where + `
+        ORDER BY name ASC
+    `
+
+	roles, err := database.Select(ctx, r.db, r.scanRole, query, database.StoreOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
 	return roles, nil
 }
-func isDuplicateKeyError(_ error) bool {
-	return false
+
+// roleColumns is the SELECT list matched by scanRole.
+func (r *roleRepository) roleColumns() string {
+	return `id, slug, name, permissions, COALESCE(parent, ''), "system",
+	` + r.dialect.Epoch("created_at") + `, ` + r.dialect.Epoch("updated_at") + `, COALESCE(` + r.dialect.Epoch("deleted_at") + `, 0)`
+}
+
+func (r *roleRepository) scanRole(scanner database.Scanner) (*Role, error) {
+	role := &Role{}
+	var permsJSON []byte
+
+	err := scanner.Scan(&role.Id, &role.Slug, &role.Name, &permsJSON, &role.Parent, &role.System, &role.Created, &role.Updated, &role.Deleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan role: %w", err)
+	}
+
+	if len(permsJSON) > 0 {
+		if err := json.Unmarshal(permsJSON, &role.Permissions); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal permissions: %w", err)
+		}
+	}
+
+	return role, nil
 }
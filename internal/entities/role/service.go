@@ -2,7 +2,14 @@ package role
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 	"slices"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/audit"
+	"github.com/iteranya/practicing-go/internal/utils"
 )
 
 type RoleService interface {
@@ -10,20 +17,39 @@ type RoleService interface {
 	CreateRole(ctx context.Context, role Role) (*Role, error)
 	GetRole(ctx context.Context, idOrSlug any) (*Role, error)
 	UpdateRole(ctx context.Context, id int, role Role) error
-	DeleteRole(ctx context.Context, id int) error
+	DeleteRole(ctx context.Context, id int, reassignTo string) (moved int, err error)
+	RestoreRole(ctx context.Context, id int) error
+	PurgeRole(ctx context.Context, id int) error
+	PurgeDeleted(ctx context.Context, before time.Time) (int, error)
 	ListRoles(ctx context.Context) ([]*Role, error)
+	ListDeletedRoles(ctx context.Context) ([]*Role, error)
+	CloneRole(ctx context.Context, id int, slug, name string) (*Role, error)
+	GetUsage(ctx context.Context) (*UsageReport, error)
 
 	// Permission Granular Management
 	UpdatePermissions(ctx context.Context, id int, permissions []string) error
 	AddPermission(ctx context.Context, id int, permission string) error
 	RemovePermission(ctx context.Context, id int, permission string) error
 
+	// Audit trail, newest first
+	ListAudit(ctx context.Context, id int, params AuditListParams) ([]*AuditEntry, error)
+
+	// Templates
+	ListTemplates() []Role
+	CreateFromTemplate(ctx context.Context, name string) (*Role, error)
+	SeedDefaults(ctx context.Context) (int, error)
+
 	// Auth Helper
 	// Fetches all roles and converts them to a map of Slug -> Permissions
 	// Used by the Authorization Middleware to check User access against DB rules.
 	GetPolicyMap(ctx context.Context) (map[string][]string, error)
 }
 
+type AuditListParams struct {
+	Limit int
+	Page  int
+}
+
 type roleService struct {
 	repo RoleRepository
 }
@@ -34,7 +60,14 @@ func NewRoleService(repo RoleRepository) RoleService {
 
 // --- CRUD ---
 
+// CreateRole creates an ordinary role; only the built-in templates make
+// system roles.
 func (s *roleService) CreateRole(ctx context.Context, role Role) (*Role, error) {
+	role.System = false
+	return s.create(ctx, role)
+}
+
+func (s *roleService) create(ctx context.Context, role Role) (*Role, error) {
 	if role.Slug == "" || role.Name == "" {
 		return nil, ErrInvalidRoleInput
 	}
@@ -42,15 +75,41 @@ func (s *roleService) CreateRole(ctx context.Context, role Role) (*Role, error)
 	if role.Permissions == nil {
 		role.Permissions = []string{}
 	}
+	if err := checkPermissions(role.Permissions); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkParent(ctx, role.Slug, role.Parent); err != nil {
+		return nil, err
+	}
 
 	err := s.repo.Create(ctx, &role)
 	if err != nil {
 		return nil, err
 	}
 
+	s.audit(ctx, role.Id, AuditCreate, map[string]any{
+		"slug": role.Slug, "name": role.Name, "permissions": role.Permissions, "parent": role.Parent,
+	})
 	return &role, nil
 }
 
+// CloneRole creates a new role with the same permissions and parent as an
+// existing one, as a starting point for a variant of it.
+func (s *roleService) CloneRole(ctx context.Context, id int, slug, name string) (*Role, error) {
+	src, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.CreateRole(ctx, Role{
+		Slug:        slug,
+		Name:        name,
+		Permissions: slices.Clone(src.Permissions),
+		Parent:      src.Parent,
+	})
+}
+
 func (s *roleService) GetRole(ctx context.Context, idOrSlug any) (*Role, error) {
 	switch v := idOrSlug.(type) {
 	case int:
@@ -74,36 +133,189 @@ func (s *roleService) UpdateRole(ctx context.Context, id int, role Role) error {
 	}
 
 	role.Id = existing.Id
+	role.System = existing.System
 
 	// If permissions are nil in update, preserve existing ones
 	if role.Permissions == nil {
 		role.Permissions = existing.Permissions
+	} else if err := checkPermissions(role.Permissions); err != nil {
+		return err
+	}
+
+	if err := s.checkSystem(ctx, existing, &role); err != nil {
+		return err
+	}
+
+	// Children refer to the parent by slug, so check under the old slug too
+	if err := s.checkParent(ctx, existing.Slug, role.Parent); err != nil {
+		return err
 	}
 
-	return s.repo.Update(ctx, &role)
+	if err := s.repo.Update(ctx, &role); err != nil {
+		return err
+	}
+	s.audit(ctx, id, AuditUpdate, changes(existing, &role))
+	return s.revokeSessions(ctx, existing.Slug, role.Slug)
 }
 
-func (s *roleService) DeleteRole(ctx context.Context, id int) error {
-	return s.repo.Delete(ctx, id)
+// DeleteRole moves the role to the trash; see RestoreRole and PurgeRole. It
+// refuses while users still have the role, since they'd be left with no
+// permissions at all. With reassignTo set, those users are moved to that
+// role first; the number moved is returned.
+func (s *roleService) DeleteRole(ctx context.Context, id int, reassignTo string) (int, error) {
+	role, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.checkSystem(ctx, role, nil); err != nil {
+		return 0, err
+	}
+
+	if reassignTo != "" {
+		if reassignTo == role.Slug {
+			return 0, fmt.Errorf("%w: cannot reassign users to the role being deleted", ErrInvalidRoleInput)
+		}
+		if _, err := s.repo.GetBySlug(ctx, reassignTo); err != nil {
+			if errors.Is(err, ErrRoleNotFound) {
+				return 0, fmt.Errorf("%w: unknown role %q", ErrInvalidRoleInput, reassignTo)
+			}
+			return 0, err
+		}
+		moved, err := s.repo.DeleteAndReassign(ctx, id, reassignTo)
+		if err != nil {
+			return 0, err
+		}
+		s.audit(ctx, id, AuditDelete, map[string]any{"slug": role.Slug, "reassigned_to": reassignTo, "users": moved})
+		return moved, nil
+	}
+
+	users, err := s.repo.CountUsers(ctx, role.Slug)
+	if err != nil {
+		return 0, err
+	}
+	if users > 0 {
+		return 0, &RoleInUseError{Users: users}
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return 0, err
+	}
+	s.audit(ctx, id, AuditDelete, map[string]any{"slug": role.Slug})
+	return 0, nil
+}
+
+// RestoreRole takes a role out of the trash. Roles that inherited from it
+// were detached when it was deleted and stay that way.
+func (s *roleService) RestoreRole(ctx context.Context, id int) error {
+	if err := s.repo.Restore(ctx, id); err != nil {
+		return err
+	}
+	s.audit(ctx, id, AuditRestore, nil)
+	return nil
+}
+
+// PurgeRole permanently removes a role that is already in the trash.
+func (s *roleService) PurgeRole(ctx context.Context, id int) error {
+	if err := s.repo.Purge(ctx, id); err != nil {
+		return err
+	}
+	s.audit(ctx, id, AuditPurge, nil)
+	return nil
+}
+
+// PurgeDeleted purges the roles deleted before the given time and returns
+// how many went.
+func (s *roleService) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	ids, err := s.repo.TrashedBefore(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		err := s.repo.Purge(ctx, id)
+		if errors.Is(err, ErrRoleNotFound) {
+			continue // restored in the meantime
+		}
+		if err != nil {
+			return purged, err
+		}
+		s.audit(ctx, id, AuditPurge, nil)
+		purged++
+	}
+	return purged, nil
 }
 
 func (s *roleService) ListRoles(ctx context.Context) ([]*Role, error) {
 	return s.repo.List(ctx)
 }
 
+func (s *roleService) ListDeletedRoles(ctx context.Context) ([]*Role, error) {
+	return s.repo.ListDeleted(ctx)
+}
+
+// GetUsage reports how many users hold each role and which permissions no
+// user has at all, to help prune roles and spot over-broad ones.
+func (s *roleService) GetUsage(ctx context.Context) (*UsageReport, error) {
+	roles, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.repo.CountUsersByRole(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bySlug := make(map[string]*Role, len(roles))
+	for _, r := range roles {
+		bySlug[r.Slug] = r
+	}
+
+	report := &UsageReport{Roles: make([]Usage, 0, len(roles)), UnusedPermissions: []string{}}
+	var held [][]string
+	for _, r := range roles {
+		perms := resolvePermissions(r, bySlug)
+		report.Roles = append(report.Roles, Usage{Slug: r.Slug, Name: r.Name, Users: counts[r.Slug], Permissions: perms})
+		if counts[r.Slug] > 0 {
+			held = append(held, perms)
+		}
+	}
+
+	for _, p := range utils.GetAllPermissions() {
+		if !slices.ContainsFunc(held, func(perms []string) bool { return utils.HasPermission(perms, p) }) {
+			report.UnusedPermissions = append(report.UnusedPermissions, p)
+		}
+	}
+	return report, nil
+}
+
 // --- Permission Management ---
 
 func (s *roleService) UpdatePermissions(ctx context.Context, id int, permissions []string) error {
+	if err := checkPermissions(permissions); err != nil {
+		return err
+	}
 	role, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
+	before := *role
 	role.Permissions = permissions
-	return s.repo.Update(ctx, role)
+	if err := s.checkSystem(ctx, &before, role); err != nil {
+		return err
+	}
+	if err := s.repo.Update(ctx, role); err != nil {
+		return err
+	}
+	s.audit(ctx, id, AuditUpdate, changes(&before, role))
+	return s.revokeSessions(ctx, role.Slug)
 }
 
 func (s *roleService) AddPermission(ctx context.Context, id int, permission string) error {
+	if err := checkPermissions([]string{permission}); err != nil {
+		return err
+	}
 	role, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return err
@@ -111,8 +323,16 @@ func (s *roleService) AddPermission(ctx context.Context, id int, permission stri
 
 	// Check if already exists to avoid duplicates
 	if !slices.Contains(role.Permissions, permission) {
+		before := *role
 		role.Permissions = append(role.Permissions, permission)
-		return s.repo.Update(ctx, role)
+		if err := s.checkSystem(ctx, &before, role); err != nil {
+			return err // A deny such as "!role:*" can strip permissions too
+		}
+		if err := s.repo.Update(ctx, role); err != nil {
+			return err
+		}
+		s.audit(ctx, id, AuditPermissionAdd, map[string]any{"permission": permission})
+		return s.revokeSessions(ctx, role.Slug)
 	}
 
 	return nil // Already exists, no-op works fine
@@ -134,15 +354,228 @@ func (s *roleService) RemovePermission(ctx context.Context, id int, permission s
 
 	// Only update if something actually changed
 	if len(newPerms) != len(role.Permissions) {
+		before := *role
 		role.Permissions = newPerms
-		return s.repo.Update(ctx, role)
+		if err := s.checkSystem(ctx, &before, role); err != nil {
+			return err
+		}
+		if err := s.repo.Update(ctx, role); err != nil {
+			return err
+		}
+		s.audit(ctx, id, AuditPermissionRemove, map[string]any{"permission": permission})
+		return s.revokeSessions(ctx, role.Slug)
+	}
+
+	return nil
+}
+
+// --- Audit Trail ---
+
+// ListAudit doesn't require the role to still exist, so the history of a
+// deleted role can be read too. It reads the shared audit log.
+func (s *roleService) ListAudit(ctx context.Context, id int, params AuditListParams) ([]*AuditEntry, error) {
+	offset := 0
+	if params.Page > 1 {
+		offset = (params.Page - 1) * params.Limit
+	}
+
+	logged, err := audit.List(ctx, audit.Filter{EntityType: audit.Role, EntityId: id, Limit: params.Limit, Offset: offset})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*AuditEntry, 0, len(logged))
+	for _, e := range logged {
+		entries = append(entries, &AuditEntry{
+			Id: e.Id, RoleId: e.EntityId, ActorId: e.ActorId, Action: e.Action, Detail: e.Diff, CreatedAt: e.CreatedAt,
+		})
+	}
+	return entries, nil
+}
+
+// audit records a change to a role in the audit log.
+func (s *roleService) audit(ctx context.Context, roleId int, action string, detail map[string]any) {
+	audit.Record(ctx, audit.Role, roleId, action, detail)
+}
+
+// changes describes what an update changed: each changed field as
+// {"from", "to"}, and permissions as the lists added and removed.
+func changes(before, after *Role) map[string]any {
+	out := map[string]any{}
+	for _, f := range []struct{ name, from, to string }{
+		{"slug", before.Slug, after.Slug},
+		{"name", before.Name, after.Name},
+		{"parent", before.Parent, after.Parent},
+	} {
+		if f.from != f.to {
+			out[f.name] = map[string]string{"from": f.from, "to": f.to}
+		}
+	}
+
+	var added, removed []string
+	for _, p := range after.Permissions {
+		if !slices.Contains(before.Permissions, p) {
+			added = append(added, p)
+		}
+	}
+	for _, p := range before.Permissions {
+		if !slices.Contains(after.Permissions, p) {
+			removed = append(removed, p)
+		}
+	}
+	if len(added) > 0 {
+		out["permissions_added"] = added
+	}
+	if len(removed) > 0 {
+		out["permissions_removed"] = removed
+	}
+	return out
+}
+
+// revokeSessions logs out everyone whose permissions just changed: holders of
+// the roles and of every role inheriting from them.
+func (s *roleService) revokeSessions(ctx context.Context, slugs ...string) error {
+	roles, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	affected := slices.Clone(slugs)
+	for changed := true; changed; {
+		changed = false
+		for _, r := range roles {
+			if r.Parent != "" && slices.Contains(affected, r.Parent) && !slices.Contains(affected, r.Slug) {
+				affected = append(affected, r.Slug)
+				changed = true
+			}
+		}
+	}
+
+	n, err := s.repo.RevokeSessions(ctx, affected)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("role: %q changed, revoked sessions of %d users", slugs[0], n)
+	}
+	return nil
+}
+
+// checkPermissions refuses entries that name no known permission, so a typo
+// can't slip in as a grant or deny that silently does nothing.
+func checkPermissions(perms []string) error {
+	for _, p := range perms {
+		if !utils.IsValidPermission(p) {
+			return fmt.Errorf("%w: unknown permission %q", ErrInvalidRoleInput, p)
+		}
 	}
+	return nil
+}
+
+// criticalPermissions are what a system role needs so that someone can
+// always manage users and roles; without them the install is locked out.
+var criticalPermissions = []string{utils.UserAdmin, utils.RoleAdmin}
+
+// checkSystem refuses changes that would break a system role: deleting it
+// (after == nil), renaming it, or taking away any critical permission it
+// has, directly or through its parent. The last goes for changes to its
+// ancestors too, so a system role can't be locked out by editing or
+// deleting a role it inherits from.
+func (s *roleService) checkSystem(ctx context.Context, before, after *Role) error {
+	if before.System && (after == nil || after.Slug != before.Slug) {
+		return ErrSystemRole
+	}
+
+	roles, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	bySlug := make(map[string]*Role, len(roles))
+	for _, r := range roles {
+		bySlug[r.Slug] = r
+	}
+	bySlug[before.Slug] = before
+	next := rolesAfter(bySlug, before, after)
 
+	for _, r := range bySlug {
+		if !r.System {
+			continue
+		}
+		had := resolvePermissions(r, bySlug)
+		has := resolvePermissions(next[r.Slug], next)
+
+		for _, p := range utils.GetAllPermissions() {
+			if !slices.ContainsFunc(criticalPermissions, func(c string) bool { return utils.HasPermission([]string{c}, p) }) {
+				continue
+			}
+			if utils.HasPermission(had, p) && !utils.HasPermission(has, p) {
+				return fmt.Errorf("%w: %s would lose %s", ErrSystemRole, r.Slug, p)
+			}
+		}
+	}
+	return nil
+}
+
+// rolesAfter is bySlug as it will be once before becomes after, or is
+// deleted with a nil after: like the repository, it points the children of
+// a renamed role at the new slug and leaves those of a deleted one without
+// a parent.
+func rolesAfter(bySlug map[string]*Role, before, after *Role) map[string]*Role {
+	next := make(map[string]*Role, len(bySlug))
+	for slug, r := range bySlug {
+		if slug == before.Slug {
+			continue
+		}
+		if r.Parent == before.Slug {
+			child := *r
+			child.Parent = ""
+			if after != nil {
+				child.Parent = after.Slug
+			}
+			r = &child
+		}
+		next[slug] = r
+	}
+	if after != nil {
+		next[after.Slug] = after
+	}
+	return next
+}
+
+// checkParent makes sure the parent exists and that slug isn't one of its
+// ancestors, which would make the hierarchy loop.
+func (s *roleService) checkParent(ctx context.Context, slug, parent string) error {
+	if parent == "" {
+		return nil
+	}
+	if parent == slug {
+		return ErrRoleCycle
+	}
+
+	roles, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	parents := make(map[string]string, len(roles))
+	for _, r := range roles {
+		parents[r.Slug] = r.Parent
+	}
+
+	if _, ok := parents[parent]; !ok {
+		return fmt.Errorf("%w: unknown parent role %q", ErrInvalidRoleInput, parent)
+	}
+	for p, seen := parent, map[string]bool{}; p != "" && !seen[p]; p = parents[p] {
+		if p == slug {
+			return ErrRoleCycle
+		}
+		seen[p] = true
+	}
 	return nil
 }
 
 // --- Auth Helper ---
 
+// GetPolicyMap maps each role slug to its permissions, including everything
+// inherited from its ancestors.
 func (s *roleService) GetPolicyMap(ctx context.Context) (map[string][]string, error) {
 	roles, err := s.repo.List(ctx)
 	if err != nil {
@@ -151,11 +584,39 @@ func (s *roleService) GetPolicyMap(ctx context.Context) (map[string][]string, er
 		return nil, err
 	}
 
+	bySlug := make(map[string]*Role, len(roles))
+	for _, r := range roles {
+		bySlug[r.Slug] = r
+	}
+
 	policy := make(map[string][]string)
 	for _, r := range roles {
 		// Map the Role Slug (stored in User) to the Permission List (stored in Role)
-		policy[r.Slug] = r.Permissions
+		policy[r.Slug] = resolvePermissions(r, bySlug)
 	}
 
 	return policy, nil
 }
+
+// resolvePermissions walks up the parent chain collecting permissions. Writes
+// reject cycles, but one edited into the database directly stops the walk
+// instead of looping forever.
+func resolvePermissions(r *Role, bySlug map[string]*Role) []string {
+	perms := []string{}
+	seen := map[string]bool{}
+
+	for cur := r; cur != nil; cur = bySlug[cur.Parent] {
+		if seen[cur.Slug] {
+			log.Printf("role: inheritance cycle at %q while resolving %q", cur.Slug, r.Slug)
+			break
+		}
+		seen[cur.Slug] = true
+
+		for _, p := range cur.Permissions {
+			if !slices.Contains(perms, p) {
+				perms = append(perms, p)
+			}
+		}
+	}
+	return perms
+}
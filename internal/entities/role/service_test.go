@@ -0,0 +1,80 @@
+package role_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/iteranya/practicing-go/internal/entities/role"
+	"github.com/iteranya/practicing-go/internal/testutil"
+)
+
+func TestPermissionValidation(t *testing.T) {
+	ctx := context.Background()
+	svc := role.NewRoleService(testutil.NewDB().Roles())
+
+	created, err := svc.CreateRole(ctx, role.Role{
+		Slug: "shift-lead", Name: "Shift lead",
+		Permissions: []string{"order:*", "!order:delete", "inventory:update@north"},
+	})
+	if err != nil {
+		t.Fatalf("CreateRole with wildcard, deny and scoped entries: %v", err)
+	}
+
+	typo := []string{"order:read", "!order:delte"}
+	if _, err := svc.CreateRole(ctx, role.Role{Slug: "typo", Name: "Typo", Permissions: typo}); !errors.Is(err, role.ErrInvalidRoleInput) {
+		t.Errorf("CreateRole: err = %v, want ErrInvalidRoleInput", err)
+	}
+	if err := svc.UpdateRole(ctx, created.Id, role.Role{Slug: created.Slug, Name: created.Name, Permissions: typo}); !errors.Is(err, role.ErrInvalidRoleInput) {
+		t.Errorf("UpdateRole: err = %v, want ErrInvalidRoleInput", err)
+	}
+	if err := svc.UpdatePermissions(ctx, created.Id, typo); !errors.Is(err, role.ErrInvalidRoleInput) {
+		t.Errorf("UpdatePermissions: err = %v, want ErrInvalidRoleInput", err)
+	}
+	if err := svc.AddPermission(ctx, created.Id, "widget:*"); !errors.Is(err, role.ErrInvalidRoleInput) {
+		t.Errorf("AddPermission: err = %v, want ErrInvalidRoleInput", err)
+	}
+
+	got, err := svc.GetRole(ctx, created.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Permissions) != 3 {
+		t.Errorf("Permissions = %q, want them unchanged", got.Permissions)
+	}
+}
+
+func TestSystemRoleAncestors(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.NewDB()
+	base := testutil.NewRole("base", "user:*", "role:*").Insert(t, ctx, db)
+	testutil.NewRole("admin", "order:*").Parent("base").System().Insert(t, ctx, db)
+	svc := role.NewRoleService(db.Roles())
+
+	if err := svc.AddPermission(ctx, base.Id, "product:read"); err != nil {
+		t.Errorf("AddPermission to a system role's parent: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func() error
+	}{
+		{"remove a permission", func() error { return svc.RemovePermission(ctx, base.Id, "role:*") }},
+		{"replace the permissions", func() error { return svc.UpdatePermissions(ctx, base.Id, []string{"user:*"}) }},
+		{"deny one", func() error { return svc.AddPermission(ctx, base.Id, "!user:delete") }},
+		{"delete", func() error { _, err := svc.DeleteRole(ctx, base.Id, ""); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.change(); !errors.Is(err, role.ErrSystemRole) {
+				t.Errorf("err = %v, want ErrSystemRole", err)
+			}
+		})
+	}
+
+	// Renaming the parent keeps the system role's permissions, as its
+	// children follow it
+	if err := svc.UpdateRole(ctx, base.Id, role.Role{Slug: "core", Name: "Core"}); err != nil {
+		t.Errorf("UpdateRole renaming the parent: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package role
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/iteranya/practicing-go/internal/utils"
+)
+
+var ErrUnknownTemplate = errors.New("unknown role template")
+
+// templates are the built-in starting points. They're created on first
+// startup and can be re-created (e.g. after deleting one) from
+// POST /role-templates/{name}. Once created they're ordinary roles.
+var templates = []Role{
+	{
+		Slug:   "admin",
+		Name:   "Administrator",
+		System: true,
+		Permissions: []string{
+			utils.InventoryAdmin, utils.OrderAdmin, utils.ProductAdmin,
+			utils.UserAdmin, utils.RoleAdmin, utils.LocationAdmin,
+		},
+	},
+	{
+		Slug: "manager",
+		Name: "Manager",
+		Permissions: []string{
+			utils.InventoryAdmin, utils.OrderAdmin, utils.ProductAdmin,
+			utils.PermUserRead, utils.PermRoleRead, utils.PermLocationRead,
+		},
+	},
+	{
+		Slug: "cashier",
+		Name: "Cashier",
+		Permissions: []string{
+			utils.PermOrderCreate, utils.PermOrderRead, utils.PermOrderUpdate,
+			utils.PermOrderDelete, // Own orders only, shortly after; see order.SetVoidWindow
+			utils.PermProductRead, utils.PermInventoryRead, utils.PermLocationRead,
+		},
+	},
+	{
+		Slug: "stockist",
+		Name: "Stockist",
+		Permissions: []string{
+			utils.InventoryAdmin,
+			utils.PermProductRead, utils.PermLocationRead,
+		},
+	},
+}
+
+// ListTemplates returns the built-in role templates.
+func (s *roleService) ListTemplates() []Role {
+	out := make([]Role, len(templates))
+	for i, t := range templates {
+		t.Permissions = slices.Clone(t.Permissions)
+		out[i] = t
+	}
+	return out
+}
+
+// CreateFromTemplate creates the role named by a template. It fails with
+// ErrDuplicateRoleSlug if a role with that slug already exists.
+func (s *roleService) CreateFromTemplate(ctx context.Context, name string) (*Role, error) {
+	for _, t := range s.ListTemplates() {
+		if t.Slug == name {
+			return s.create(ctx, t)
+		}
+	}
+	return nil, ErrUnknownTemplate
+}
+
+// SeedDefaults creates every template when there are no roles yet, so a fresh
+// install has an admin role to give the first user. It returns how many roles
+// were created.
+func (s *roleService) SeedDefaults(ctx context.Context) (int, error) {
+	existing, err := s.repo.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(existing) > 0 {
+		return 0, nil
+	}
+
+	for _, t := range s.ListTemplates() {
+		if _, err := s.create(ctx, t); err != nil {
+			return 0, err
+		}
+	}
+	return len(templates), nil
+}
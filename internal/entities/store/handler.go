@@ -0,0 +1,65 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/utils"
+)
+
+type StoreHandler struct {
+	service StoreService
+}
+
+func NewStoreHandler(service StoreService) *StoreHandler {
+	return &StoreHandler{service: service}
+}
+
+// Stores are created with -seed -store; the API only tells callers which one
+// they are in.
+func (h *StoreHandler) Routes() []utils.Route {
+	return []utils.Route{
+		{Pattern: "GET /store", Handler: h.HandleCurrent},
+	}
+}
+
+// CURRENT
+func (h *StoreHandler) HandleCurrent(w http.ResponseWriter, r *http.Request) {
+	st, err := h.service.GetStore(r.Context(), database.StoreOf(r.Context()))
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, st)
+}
+
+// --- Helpers ---
+
+func (h *StoreHandler) respondWithJSON(w http.ResponseWriter, code int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if payload != nil {
+		json.NewEncoder(w).Encode(payload)
+	}
+}
+
+func (h *StoreHandler) respondWithError(w http.ResponseWriter, err error) {
+	var statusCode int
+	switch {
+	case errors.Is(err, ErrStoreNotFound):
+		statusCode = http.StatusNotFound
+	case errors.Is(err, ErrInvalidStoreInput):
+		statusCode = http.StatusBadRequest
+	case errors.Is(err, ErrDuplicateStoreSlug):
+		statusCode = http.StatusConflict
+	default:
+		statusCode = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,10 @@
+package store
+
+// Store is a shop with its own catalog, stock, staff and orders, sharing the
+// deployment with any others. See database.StoreOf.
+type Store struct {
+	Id      int
+	Slug    string // What clients send as X-Store
+	Name    string
+	Created int64 // Unix timestamp
+}
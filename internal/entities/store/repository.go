@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
+)
+
+var (
+	ErrStoreNotFound      = errors.New("store not found")
+	ErrDuplicateStoreSlug = errors.New("store slug already exists")
+	ErrInvalidStoreInput  = errors.New("invalid store input")
+)
+
+type StoreRepository interface {
+	Create(ctx context.Context, s *Store) error
+	GetByID(ctx context.Context, id int) (*Store, error)
+	GetBySlug(ctx context.Context, slug string) (*Store, error)
+	List(ctx context.Context) ([]*Store, error)
+}
+
+type storeRepository struct {
+	db      *database.DB
+	dialect database.Dialect
+}
+
+func NewStoreRepository(db *sql.DB) StoreRepository {
+	return &storeRepository{db: database.Observe(db, "stores"), dialect: database.DialectOf(db)}
+}
+
+// storeColumns is the SELECT list matched by scanStore.
+func (r *storeRepository) storeColumns() string {
+	return `id, slug, name, ` + r.dialect.Epoch("created_at")
+}
+
+func scanStore(scanner database.Scanner) (*Store, error) {
+	s := &Store{}
+	if err := scanner.Scan(&s.Id, &s.Slug, &s.Name, &s.Created); err != nil {
+		return nil, fmt.Errorf("failed to scan store: %w", err)
+	}
+	return s, nil
+}
+
+func (r *storeRepository) Create(ctx context.Context, s *Store) error {
+	if s.Slug == "" || s.Name == "" {
+		return ErrInvalidStoreInput
+	}
+
+	query := `INSERT INTO stores (slug, name, created_at, updated_at) VALUES ($1, $2, $3, $3)`
+
+	now := time.Now()
+	err := r.dialect.InsertReturning(ctx, r.db, "stores", query, "id", []any{s.Slug, s.Name, now}, &s.Id)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return ErrDuplicateStoreSlug
+		}
+		return fmt.Errorf("failed to create store: %w", err)
+	}
+
+	s.Created = now.Unix()
+	return nil
+}
+
+func (r *storeRepository) GetByID(ctx context.Context, id int) (*Store, error) {
+	query := `SELECT ` + r.storeColumns() + ` FROM stores WHERE id = $1`
+
+	return database.Get(ctx, r.db, scanStore, ErrStoreNotFound, query, id)
+}
+
+func (r *storeRepository) GetBySlug(ctx context.Context, slug string) (*Store, error) {
+	query := `SELECT ` + r.storeColumns() + ` FROM stores WHERE slug = $1`
+
+	return database.Get(ctx, r.db, scanStore, ErrStoreNotFound, query, slug)
+}
+
+func (r *storeRepository) List(ctx context.Context) ([]*Store, error) {
+	query := `SELECT ` + r.storeColumns() + ` FROM stores ORDER BY id ASC`
+
+	stores, err := database.Select(ctx, r.db, scanStore, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stores: %w", err)
+	}
+	return stores, nil
+}
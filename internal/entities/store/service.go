@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"strings"
+
+	"github.com/iteranya/practicing-go/internal/audit"
+	"github.com/iteranya/practicing-go/internal/database"
+)
+
+type StoreService interface {
+	CreateStore(ctx context.Context, s Store) (*Store, error)
+	GetStore(ctx context.Context, idOrSlug any) (*Store, error)
+	ListStores(ctx context.Context) ([]*Store, error)
+
+	// Resolve returns ctx acting for the store with the given slug, or for
+	// the default store if slug is empty.
+	Resolve(ctx context.Context, slug string) (context.Context, error)
+}
+
+type storeService struct {
+	repo StoreRepository
+}
+
+func NewStoreService(repo StoreRepository) StoreService {
+	return &storeService{repo: repo}
+}
+
+func (s *storeService) CreateStore(ctx context.Context, st Store) (*Store, error) {
+	st.Slug = strings.ToLower(strings.TrimSpace(st.Slug))
+	if st.Slug == "" || strings.TrimSpace(st.Name) == "" {
+		return nil, ErrInvalidStoreInput
+	}
+
+	if err := s.repo.Create(ctx, &st); err != nil {
+		return nil, err
+	}
+
+	// Logged in the new store, where its admins will look
+	audit.Record(database.WithStore(ctx, st.Id), audit.Store, st.Id, audit.Create, audit.Diff(nil, &st))
+	return &st, nil
+}
+
+func (s *storeService) GetStore(ctx context.Context, idOrSlug any) (*Store, error) {
+	switch v := idOrSlug.(type) {
+	case int:
+		return s.repo.GetByID(ctx, v)
+	case string:
+		return s.repo.GetBySlug(ctx, strings.ToLower(v))
+	default:
+		return nil, ErrInvalidStoreInput
+	}
+}
+
+func (s *storeService) ListStores(ctx context.Context) ([]*Store, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *storeService) Resolve(ctx context.Context, slug string) (context.Context, error) {
+	if slug == "" {
+		return database.WithStore(ctx, database.DefaultStore), nil
+	}
+	st, err := s.GetStore(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	return database.WithStore(ctx, st.Id), nil
+}
@@ -1,28 +1,105 @@
 package user
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
-	"fmt"
+	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 
 	// Replace this with your actual module path
+	"github.com/iteranya/practicing-go/internal/database"
 	"github.com/iteranya/practicing-go/internal/utils"
 )
 
 var (
-	// In production, ensure this is set via environment variable
-	jwtSecret = []byte(getEnv("JWT_SECRET", "super-secret-dev-key"))
-	tokenTTL  = 24 * time.Hour
+	// Until main installs a provider (SetTokenProvider), tokens are HS256
+	// with JWT_SECRET. In production, set JWT_SECRET or configure JWT_KEYS_DIR.
+	tokenProvider TokenProvider = defaultKeySet()
+	tokens                      = DefaultTokenConfig()
 )
 
+// TokenProvider signs access token claims and verifies tokens back into
+// claims, including the signature and expiry. *KeySet (JWTs) is the built-in
+// provider; another, e.g. backed by a KMS, can be swapped in with
+// SetTokenProvider. The issuer is checked by ValidateToken.
+type TokenProvider interface {
+	Sign(claims *Claims) (string, error)
+	Verify(token string) (*Claims, error)
+}
+
+// TokenConfig holds the issuer and lifetimes of the tokens we hand out.
+type TokenConfig struct {
+	Issuer     string
+	AccessTTL  time.Duration // Interactive logins; short-lived, renewed via refresh token
+	RefreshTTL time.Duration // Refresh tokens rotate on every use
+	PINTTL     time.Duration // Roughly one shift; PIN tokens can't be refreshed
+	APITTL     time.Duration // Integration tokens, issued by an admin
+	DeviceTTL  time.Duration // Remember-me tokens; sliding, renewed on each use
+	MagicTTL   time.Duration // Emailed login links, single use
+}
+
+func DefaultTokenConfig() TokenConfig {
+	return TokenConfig{
+		Issuer:     "inventory-system",
+		AccessTTL:  15 * time.Minute,
+		RefreshTTL: 30 * 24 * time.Hour,
+		PINTTL:     8 * time.Hour,
+		APITTL:     90 * 24 * time.Hour,
+		DeviceTTL:  180 * 24 * time.Hour,
+		MagicTTL:   15 * time.Minute,
+	}
+}
+
+// SetTokenConfig replaces the token issuer and lifetimes. Tokens signed with
+// a different issuer stop validating.
+func SetTokenConfig(cfg TokenConfig) {
+	tokens = cfg
+}
+
+// Token scopes. An empty scope is a full-access token from a password login.
+const (
+	ScopePIN = "pin" // Register use only: selling, not managing
+	ScopeAPI = "api" // Integrations: full access, long-lived, no refresh token
+)
+
+// pinScopeRoutes lists what a PIN token may reach, as path prefix -> allowed
+// methods ("*" for any). Anything not listed is refused.
+var pinScopeRoutes = map[string][]string{
+	"/orders":       {"*"},
+	"/products":     {http.MethodGet},
+	"/inventory":    {http.MethodGet},
+	"/logout":       {http.MethodPost},
+	"/me":           {http.MethodGet},
+	"/me/clock-in":  {http.MethodPost},
+	"/me/clock-out": {http.MethodPost},
+	"/me/policy":    {http.MethodPost},
+}
+
+// TokenPair is what a successful login or refresh hands back to the client.
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // Access token lifetime in seconds
+}
+
 // Claims defines the payload inside our signed JWT
 type Claims struct {
-	UserID int    `json:"user_id"`
-	Role   string `json:"role"`
+	UserID  int    `json:"user_id"`
+	Role    string `json:"role"`
+	Version int    `json:"ver"`             // Must match the user's current token version
+	Scope   string `json:"scope,omitempty"` // Empty for full access, see Scope* constants
+	Store   int    `json:"store,omitempty"` // The user's store; only tokens from before stores lack it
+	// MustChangePassword is copied from the user when the token is issued.
+	// Changing the password revokes the token, so it never goes stale.
+	MustChangePassword bool `json:"pwd,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -30,20 +107,37 @@ type Claims struct {
 // DOMAIN METHODS (Attached to the User Struct)
 // ---------------------------------------------------------
 
-// SetPassword hashes the raw password using bcrypt and updates the user's Hash field.
+// SetPassword hashes the raw password with the configured hasher and updates the user's Hash field.
 func (u *User) SetPassword(rawPassword string) error {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(rawPassword), bcrypt.DefaultCost)
+	hash, err := passwords.Hash(rawPassword)
 	if err != nil {
 		return err
 	}
-	u.Hash = string(bytes)
+	u.Hash = hash
 	return nil
 }
 
-// CheckPassword compares the provided raw password with the user's stored hash.
+// CheckPassword compares the provided raw password with the user's stored
+// hash, which may still be a legacy bcrypt one.
 func (u *User) CheckPassword(rawPassword string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Hash), []byte(rawPassword))
-	return err == nil
+	return passwords.Verify(u.Hash, rawPassword)
+}
+
+// HashPIN hashes a numeric PIN with bcrypt.
+func HashPIN(rawPIN string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(rawPIN), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+// CheckPIN compares a raw PIN with its stored hash. An empty hash (no PIN set) never matches.
+func CheckPIN(hash, rawPIN string) bool {
+	if hash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(rawPIN)) == nil
 }
 
 // Can checks if this user is allowed to perform a specific action.
@@ -54,8 +148,8 @@ func (u *User) CheckPassword(rawPassword string) bool {
 // 3. We check if 'requiredPerm' exists in that list.
 func (u *User) Can(requiredPerm string, policy map[string][]string) bool {
 	// 1. Retrieve the permissions array for this user's role
-	myPerms, exists := policy[u.Role]
-	if !exists {
+	myPerms := u.Permissions(policy)
+	if myPerms == nil {
 		return false // Role doesn't exist in the system policy = Deny Access
 	}
 
@@ -63,47 +157,150 @@ func (u *User) Can(requiredPerm string, policy map[string][]string) bool {
 	return utils.HasPermission(myPerms, requiredPerm)
 }
 
+// EffectiveRole is the role permissions are checked against: the temporary
+// role while it lasts, the regular one otherwise.
+func (u *User) EffectiveRole() string {
+	if u.TempRole != "" && u.TempRoleExpiresAt != nil && time.Now().Before(*u.TempRoleExpiresAt) {
+		return u.TempRole
+	}
+	return u.Role
+}
+
+// Permissions returns the policy entry for the user's effective role, or nil
+// when the role doesn't exist. A temporary role deleted before it expired
+// falls back to the regular role.
+func (u *User) Permissions(policy map[string][]string) []string {
+	if perms, ok := policy[u.EffectiveRole()]; ok {
+		return perms
+	}
+	return policy[u.Role]
+}
+
 // ---------------------------------------------------------
 // STATIC HELPERS (JWT Token Management)
 // ---------------------------------------------------------
 
 // GenerateToken creates a signed JWT for a specific user instance.
 func GenerateToken(u *User) (string, error) {
-	claims := Claims{
-		UserID: u.Id,
-		Role:   u.Role,
+	return generateToken(u, "", tokens.AccessTTL)
+}
+
+// GeneratePINToken creates a register-only token (see ScopePIN).
+func GeneratePINToken(u *User) (string, error) {
+	return generateToken(u, ScopePIN, tokens.PINTTL)
+}
+
+// GenerateAPIToken creates a long-lived integration token (see ScopeAPI).
+func GenerateAPIToken(u *User) (string, error) {
+	return generateToken(u, ScopeAPI, tokens.APITTL)
+}
+
+func generateToken(u *User, scope string, ttl time.Duration) (string, error) {
+	claims := &Claims{
+		UserID:  u.Id,
+		Role:    u.Role,
+		Version: u.Version,
+		Scope:   scope,
+		Store:   u.StoreId,
+
+		MustChangePassword: u.MustChangePassword,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenTTL)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "inventory-system",
+			Issuer:    tokens.Issuer,
 		},
 	}
 
-	// Sign the token with HS256 algorithm and our secret key
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return tokenProvider.Sign(claims)
+}
+
+// SetTokenProvider replaces what signs and verifies access tokens, typically
+// a KeySet loaded from JWT_KEYS_DIR.
+func SetTokenProvider(p TokenProvider) {
+	tokenProvider = p
+}
+
+func defaultKeySet() *KeySet {
+	ks := NewKeySet()
+	ks.AddHMAC("", []byte(getEnv("JWT_SECRET", "super-secret-dev-key")))
+	return ks
 }
 
 // ValidateToken parses a raw token string, verifies the signature, and returns the claims.
 // This is primarily used by the AuthMiddleware in main.go.
 func ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validating the algorithm is crucial to prevent downgrade attacks
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return jwtSecret, nil
-	})
-
+	claims, err := tokenProvider.Verify(tokenString)
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	if claims.Issuer != tokens.Issuer {
+		return nil, errors.New("invalid token issuer")
+	}
+	return claims, nil
+}
+
+// AllowsRequest reports whether the token's scope covers the request.
+// Full-access tokens allow everything; role permissions are checked separately.
+func (c *Claims) AllowsRequest(method, path string) bool {
+	if c.Scope != ScopePIN {
+		return c.Scope == "" || c.Scope == ScopeAPI
+	}
+
+	for prefix, methods := range pinScopeRoutes {
+		if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			continue
+		}
+		for _, m := range methods {
+			if m == "*" || m == method {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// passwordChangeRoutes are all a user may reach until they replace a password
+// an admin set: changing it, reading the profile, and logging out.
+var passwordChangeRoutes = map[string]string{
+	"/me/password": http.MethodPatch,
+	"/me":          http.MethodGet,
+	"/logout":      http.MethodPost,
+}
+
+// StoreID is the store the token acts for.
+func (c *Claims) StoreID() int {
+	if c.Store == 0 {
+		return database.DefaultStore
 	}
+	return c.Store
+}
+
+// NeedsPasswordChange reports whether the request is refused until the user
+// changes their password. Integration tokens aren't people and are exempt.
+func (c *Claims) NeedsPasswordChange(method, path string) bool {
+	if !c.MustChangePassword || c.Scope == ScopeAPI {
+		return false
+	}
+	return passwordChangeRoutes[path] != method
+}
+
+// NewRefreshToken returns a random opaque token for the client and the hash
+// to store. Only the hash is persisted, so a database leak can't be replayed.
+func NewRefreshToken() (raw string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	raw = base64.RawURLEncoding.EncodeToString(b)
+	return raw, HashRefreshToken(raw), nil
+}
 
-	return nil, errors.New("invalid token")
+// HashRefreshToken derives the stored form of a refresh token. A plain SHA-256
+// is enough here because the token itself is 256 bits of randomness.
+func HashRefreshToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
 }
 
 // ---------------------------------------------------------
@@ -0,0 +1,65 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log"
+)
+
+// Authenticator verifies a username and password against a credential
+// backend. It returns ErrInvalidCredentials for a wrong pair; any other error
+// means the backend itself failed. On success the returned identity is used
+// to provision a local account if there isn't one yet.
+type Authenticator interface {
+	Authenticate(ctx context.Context, username, password string) (*ExternalIdentity, error)
+}
+
+// LocalAuthenticator checks the bcrypt hash stored in the users table.
+type LocalAuthenticator struct {
+	repo UserRepository
+}
+
+func NewLocalAuthenticator(repo UserRepository) *LocalAuthenticator {
+	return &LocalAuthenticator{repo: repo}
+}
+
+func (a *LocalAuthenticator) Authenticate(ctx context.Context, username, password string) (*ExternalIdentity, error) {
+	u, err := a.repo.GetByUsername(ctx, username)
+	if err != nil || !u.CheckPassword(password) {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Upgrade bcrypt (or outdated argon2id) hashes while we have the password
+	if passwords.NeedsRehash(u.Hash) {
+		if err := u.SetPassword(password); err == nil {
+			if err := a.repo.RehashPassword(ctx, u.Id, u.Hash); err != nil {
+				log.Printf("user: failed to rehash password for user %d: %v", u.Id, err)
+			}
+		}
+	}
+
+	return &ExternalIdentity{Provider: "local", Email: u.Email, Name: u.DisplayName}, nil
+}
+
+// ChainAuthenticator tries each backend in order and accepts the first
+// success, e.g. the directory first and local accounts as a fallback for
+// admins when it's unreachable.
+type ChainAuthenticator []Authenticator
+
+func (c ChainAuthenticator) Authenticate(ctx context.Context, username, password string) (*ExternalIdentity, error) {
+	var errs []error
+	for _, a := range c {
+		ident, err := a.Authenticate(ctx, username, password)
+		if err == nil {
+			return ident, nil
+		}
+		if !errors.Is(err, ErrInvalidCredentials) {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return nil, ErrInvalidCredentials
+}
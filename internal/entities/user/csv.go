@@ -0,0 +1,47 @@
+package user
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// exportColumns is the header written by writeExportCSV, meant for HR to
+// reconcile against their staff records.
+var exportColumns = []string{"id", "username", "display_name", "email", "role", "active", "last_login_at", "created_at"}
+
+// writeExportCSV writes users as CSV. Timestamps are RFC 3339 in UTC;
+// last_login_at is empty for users who never logged in.
+func writeExportCSV(w io.Writer, users []*User) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(exportColumns); err != nil {
+		return fmt.Errorf("failed to write csv header: %w", err)
+	}
+
+	for _, u := range users {
+		lastLogin := ""
+		if u.LastLoginAt != nil {
+			lastLogin = u.LastLoginAt.UTC().Format(time.RFC3339)
+		}
+
+		record := []string{
+			strconv.Itoa(u.Id),
+			u.Username,
+			u.DisplayName,
+			u.Email,
+			u.Role,
+			strconv.FormatBool(u.Active),
+			lastLogin,
+			u.CreatedAt.UTC().Format(time.RFC3339),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write csv row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -1,10 +1,14 @@
 package user
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/utils"
 )
 
 type UserHandler struct {
@@ -15,18 +19,52 @@ func NewUserHandler(service UserService) *UserHandler {
 	return &UserHandler{service: service}
 }
 
-func (h *UserHandler) RegisterRoutes(mux *http.ServeMux) {
-	// Standard CRUD
-	mux.HandleFunc("POST /users", h.HandleCreate)
-	mux.HandleFunc("GET /users", h.HandleList)
-	mux.HandleFunc("GET /users/{id}", h.HandleGet) // supports id or username
-	mux.HandleFunc("PUT /users/{id}", h.HandleUpdate)
-	mux.HandleFunc("DELETE /users/{id}", h.HandleDelete)
+func (h *UserHandler) Routes() []utils.Route {
+	return []utils.Route{
+		// Standard CRUD
+		{Pattern: "POST /users", Perm: utils.PermUserCreate, Handler: h.HandleCreate},
+		{Pattern: "GET /users", Perm: utils.PermUserRead, Handler: h.HandleList},
+		{Pattern: "GET /users/{id}", Perm: utils.PermUserRead, Handler: h.HandleGet}, // supports id or username
+		{Pattern: "PUT /users/{id}", Perm: utils.PermUserUpdate, Handler: h.HandleUpdate},
+		{Pattern: "DELETE /users/{id}", Perm: utils.PermUserDelete, Handler: h.HandleDelete}, // soft delete, see ?deleted=true
+		{Pattern: "POST /users/{id}/restore", Perm: utils.PermUserDelete, Handler: h.HandleRestore},
+		{Pattern: "DELETE /users/{id}/purge", Perm: utils.PermUserDelete, Handler: h.HandlePurge}, // anonymizes
+		{Pattern: "GET /users/export", Perm: utils.PermUserRead, Handler: h.HandleExport},         // Same filters as GET /users
+		{Pattern: "GET /timesheets", Perm: utils.PermUserRead, Handler: h.HandleHoursReport},      // ?start_date=&end_date=&user_id=
+
+		// Security & State
+		{Pattern: "PUT /users/{id}/pin", Perm: utils.PermUserUpdate, Handler: h.HandleSetPIN},
+		{Pattern: "PATCH /users/{id}/password", Perm: utils.PermUserUpdate, Handler: h.HandleChangePassword}, // Admin reset
+		{Pattern: "PATCH /users/{id}/active", Perm: utils.PermUserUpdate, Handler: h.HandleToggleActive},
+		{Pattern: "PATCH /users/{id}/settings", Perm: utils.PermUserUpdate, Handler: h.HandleUpdateSettings},
+		{Pattern: "DELETE /users/{id}/lock", Perm: utils.PermUserUpdate, Handler: h.HandleUnlock},
+		{Pattern: "PUT /users/{id}/temp-role", Perm: utils.PermUserUpdate, Handler: h.HandleGrantTempRole},
+		{Pattern: "DELETE /users/{id}/temp-role", Perm: utils.PermUserUpdate, Handler: h.HandleRevokeTempRole},
+		{Pattern: "POST /users/{id}/api-token", Perm: utils.PermUserAPIToken, Handler: h.HandleIssueAPIToken}, // Acts as that user, so not mere user:update
+		{Pattern: "GET /users/{id}/activity", Perm: utils.PermUserRead, Handler: h.HandleActivity},
+		{Pattern: "GET /users/{id}/login-attempts", Perm: utils.PermUserRead, Handler: h.HandleLoginAttempts}, // {id} may be a username
+		{Pattern: "GET /users/{id}/username-history", Perm: utils.PermUserRead, Handler: h.HandleUsernameHistory},
+		{Pattern: "GET /users/{id}/locations", Perm: utils.PermUserRead, Handler: h.HandleGetLocations},
+		{Pattern: "PUT /users/{id}/locations", Perm: utils.PermUserUpdate, Handler: h.HandleSetLocations},
+		{Pattern: "PUT /users/{id}/avatar", Perm: utils.PermUserUpdate, Handler: h.HandleSetAvatar},
+		{Pattern: "DELETE /users/{id}/avatar", Perm: utils.PermUserUpdate, Handler: h.HandleRemoveAvatar},
+
+		// Session
+		{Pattern: "GET /me", Perm: "", Handler: h.HandleMe},
+		{Pattern: "PATCH /me/password", Perm: "", Handler: h.HandleChangeOwnPassword},
+		{Pattern: "POST /me/policy", Perm: "", Handler: h.HandleAcceptPolicy},
+		{Pattern: "POST /logout", Perm: "", Handler: h.HandleLogout},
+
+		// Timekeeping (the caller's own clock)
+		{Pattern: "GET /me/clock", Perm: "", Handler: h.HandleClockStatus},
+		{Pattern: "POST /me/clock-in", Perm: "", Handler: h.HandleClockIn},
+		{Pattern: "POST /me/clock-out", Perm: "", Handler: h.HandleClockOut},
 
-	// Security & State
-	mux.HandleFunc("PATCH /users/{id}/password", h.HandleChangePassword)
-	mux.HandleFunc("PATCH /users/{id}/active", h.HandleToggleActive)
-	mux.HandleFunc("PATCH /users/{id}/settings", h.HandleUpdateSettings)
+		// Trusted devices (the caller's own)
+		{Pattern: "POST /devices", Perm: "", Handler: h.HandleRegisterDevice},
+		{Pattern: "GET /devices", Perm: "", Handler: h.HandleListDevices},
+		{Pattern: "DELETE /devices/{id}", Perm: "", Handler: h.HandleRevokeDevice},
+	}
 }
 
 // CREATE
@@ -43,7 +81,7 @@ func (h *UserHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusCreated, created)
+	h.respondWithJSON(w, http.StatusCreated, NewUserResponse(created))
 }
 
 // GET (ID or Username)
@@ -64,11 +102,47 @@ func (h *UserHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, result)
+	h.respondWithJSON(w, http.StatusOK, NewUserResponse(result))
 }
 
 // LIST
 func (h *UserHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	params := h.parseListParams(r)
+	page, err := h.service.ListUsers(r.Context(), params)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	// A full page may have more after it
+	if n := len(page.Users); n > 0 && n == page.Limit {
+		w.Header().Set("X-Next-Cursor", nextCursor(page.Users[n-1], params.SortBy, params.SortOrder))
+	}
+	h.respondWithJSON(w, http.StatusOK, map[string]any{
+		"data":  NewUserResponses(page.Users),
+		"total": page.Total,
+		"page":  page.Page,
+		"limit": page.Limit,
+	})
+}
+
+// EXPORT (CSV)
+// Accepts the same filters as LIST; pagination is ignored.
+func (h *UserHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	// Buffer first so a failed query still gets a proper JSON error response
+	var buf bytes.Buffer
+	if err := h.service.ExportCSV(r.Context(), h.parseListParams(r), &buf); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="users.csv"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func (h *UserHandler) parseListParams(r *http.Request) UserServiceListParams {
 	query := r.URL.Query()
 
 	limit, _ := strconv.Atoi(query.Get("limit"))
@@ -88,21 +162,20 @@ func (h *UserHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	params := UserServiceListParams{
-		Role:   query.Get("role"),
-		Query:  query.Get("q"),
-		Active: active,
-		Limit:  limit,
-		Page:   page,
-	}
+	deleted, _ := strconv.ParseBool(query.Get("deleted"))
 
-	users, err := h.service.ListUsers(r.Context(), params)
-	if err != nil {
-		h.respondWithError(w, err)
-		return
+	return UserServiceListParams{
+		Deleted:   deleted,
+		Role:      query.Get("role"),
+		Query:     query.Get("q"),
+		Active:    active,
+		Period:    utils.PeriodFromQuery(query),
+		Limit:     limit,
+		Page:      page,
+		After:     query.Get("after"), // X-Next-Cursor of the page before
+		SortBy:    query.Get("sort"),
+		SortOrder: query.Get("order"),
 	}
-
-	h.respondWithJSON(w, http.StatusOK, users)
 }
 
 // UPDATE
@@ -145,6 +218,40 @@ func (h *UserHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
+// RESTORE (undo a soft delete)
+func (h *UserHandler) HandleRestore(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RestoreUser(r.Context(), id); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "restored"})
+}
+
+// PURGE (anonymize for good, whether or not the account is in the trash)
+func (h *UserHandler) HandlePurge(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.AnonymizeUser(r.Context(), id); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "purged"})
+}
+
 // CHANGE PASSWORD
 func (h *UserHandler) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
@@ -170,6 +277,31 @@ func (h *UserHandler) HandleChangePassword(w http.ResponseWriter, r *http.Reques
 	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "password updated"})
 }
 
+// CHANGE OWN PASSWORD (requires the current password)
+func (h *UserHandler) HandleChangeOwnPassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "User context missing", http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ChangeOwnPassword(r.Context(), userID, body.CurrentPassword, body.NewPassword); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "password updated"})
+}
+
 // TOGGLE ACTIVE
 func (h *UserHandler) HandleToggleActive(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
@@ -204,26 +336,302 @@ func (h *UserHandler) HandleUpdateSettings(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	var body map[string]any
+	// Only the keys present are changed, null clears a key
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	patch, err := ParseSettingsPatch(body)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	settings, err := h.service.UpdateSettings(r.Context(), id, patch)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, settings)
+}
+
+// UNLOCK (clear a login lockout)
+func (h *UserHandler) HandleUnlock(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Unlock(r.Context(), id); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "unlocked"})
+}
+
+// GRANT TEMPORARY ROLE (e.g. acting manager until Monday)
+func (h *UserHandler) HandleGrantTempRole(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Role      string    `json:"role"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.service.UpdateSettings(r.Context(), id, body); err != nil {
+	if err := h.service.GrantTempRole(r.Context(), id, body.Role, body.ExpiresAt); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "temporary role granted"})
+}
+
+// REVOKE TEMPORARY ROLE
+func (h *UserHandler) HandleRevokeTempRole(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RevokeTempRole(r.Context(), id); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "temporary role revoked"})
+}
+
+// ISSUE API TOKEN (for integrations; shown once, not stored)
+func (h *UserHandler) HandleIssueAPIToken(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.service.IssueAPIToken(r.Context(), id)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, map[string]any{
+		"token":      tokens.AccessToken,
+		"expires_in": tokens.ExpiresIn,
+	})
+}
+
+// maxAvatarSize caps avatar uploads, including multipart overhead.
+const maxAvatarSize = 2 << 20
+
+// SET AVATAR (multipart form, file field "avatar")
+func (h *UserHandler) HandleSetAvatar(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarSize)
+	file, _, err := r.FormFile("avatar")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Avatar too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Missing avatar file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	url, err := h.service.SetAvatar(r.Context(), id, file)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"avatar_url": url})
+}
+
+// REMOVE AVATAR
+func (h *UserHandler) HandleRemoveAvatar(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RemoveAvatar(r.Context(), id); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "avatar removed"})
+}
+
+// ACTIVITY (security audit trail, newest first)
+func (h *UserHandler) HandleActivity(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit <= 0 {
+		limit = 50
+	}
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+
+	activity, err := h.service.ListActivity(r.Context(), id, ActivityListParams{Limit: limit, Page: page})
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, activity)
+}
+
+// USERNAME HISTORY (previous names, newest first)
+func (h *UserHandler) HandleUsernameHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.service.GetUsernameHistory(r.Context(), id)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, history)
+}
+
+// GET LOCATIONS (assigned store IDs)
+func (h *UserHandler) HandleGetLocations(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	ids, err := h.service.GetLocations(r.Context(), id)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]any{"location_ids": ids})
+}
+
+// SET LOCATIONS (replace all assignments)
+func (h *UserHandler) HandleSetLocations(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	// Expecting JSON: {"location_ids": [1, 2]}
+	var body struct {
+		LocationIds []int `json:"location_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.SetLocations(r.Context(), id, body.LocationIds); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "locations updated"})
+}
+
+// ACCEPT STAFF POLICY (the caller's own)
+func (h *UserHandler) HandleAcceptPolicy(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "User context missing", http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	status, err := h.service.AcceptPolicy(r.Context(), userID, body.Version)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, status)
+}
+
+// LOGIN ATTEMPTS (security review; {id} may also be a username)
+func (h *UserHandler) HandleLoginAttempts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit <= 0 {
+		limit = 50
+	}
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+
+	params := LoginAttemptListParams{Limit: limit, Page: page}
+	param := r.PathValue("id")
+	if id, err := strconv.Atoi(param); err == nil {
+		params.UserId = id
+	} else {
+		params.Username = param
+	}
+
+	attempts, err := h.service.ListLoginAttempts(r.Context(), params)
+	if err != nil {
 		h.respondWithError(w, err)
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "settings updated"})
+	h.respondWithJSON(w, http.StatusOK, attempts)
 }
 
 // --- Login ---
 
 func (h *UserHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	var body struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+		Username     string `json:"username"`
+		Password     string `json:"password"`
+		CaptchaToken string `json:"captcha_token"` // Only needed after repeated failures
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -232,27 +640,367 @@ func (h *UserHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call the Service
-	token, u, err := h.service.Login(r.Context(), body.Username, body.Password)
+	tokens, u, err := h.service.Login(r.Context(), body.Username, body.Password, body.CaptchaToken)
+	h.respondWithLogin(w, tokens, u, err)
+}
+
+// PIN LOGIN (public: quick cashier switching on a shared register)
+func (h *UserHandler) HandlePINLogin(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Username string `json:"username"`
+		PIN      string `json:"pin"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, u, err := h.service.PINLogin(r.Context(), body.Username, body.PIN)
+	h.respondWithLogin(w, tokens, u, err)
+}
+
+// SET PIN (empty pin removes it)
+func (h *UserHandler) HandleSetPIN(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		PIN string `json:"pin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.SetPIN(r.Context(), id, body.PIN); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "pin updated"})
+}
+
+// ME (the caller's profile and effective permissions)
+func (h *UserHandler) HandleMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "User context missing", http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := h.service.GetProfile(r.Context(), userID)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, profile)
+}
+
+// LOGOUT (invalidates all of the caller's tokens)
+func (h *UserHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "User context missing", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.service.Logout(r.Context(), userID); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "logged out"})
+}
+
+// REFRESH (public: the access token may already have expired)
+func (h *UserHandler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.service.Refresh(r.Context(), body.RefreshToken)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, tokens)
+}
+
+// CLOCK STATUS
+func (h *UserHandler) HandleClockStatus(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "User context missing", http.StatusUnauthorized)
+		return
+	}
+
+	entry, err := h.service.ClockStatus(r.Context(), userID)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]any{
+		"clocked_in": entry != nil,
+		"entry":      entry,
+	})
+}
+
+// CLOCK IN
+func (h *UserHandler) HandleClockIn(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "User context missing", http.StatusUnauthorized)
+		return
+	}
+
+	entry, err := h.service.ClockIn(r.Context(), userID)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, entry)
+}
+
+// CLOCK OUT
+func (h *UserHandler) HandleClockOut(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "User context missing", http.StatusUnauthorized)
+		return
+	}
+
+	entry, err := h.service.ClockOut(r.Context(), userID)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, entry)
+}
+
+// HOURS REPORT (default: last 7 days, all users)
+func (h *UserHandler) HandleHoursReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	now := time.Now()
+
+	start := now.AddDate(0, 0, -7)
+	end := now
+	if v := query.Get("start_date"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid start_date, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		start = t
+	}
+	if v := query.Get("end_date"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid end_date, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		end = t.AddDate(0, 0, 1) // Inclusive: up to the end of that day
+	}
+	userID, _ := strconv.Atoi(query.Get("user_id"))
+
+	lines, err := h.service.GetHoursReport(r.Context(), start, end, userID)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]any{
+		"start": start,
+		"end":   end,
+		"users": lines,
+	})
+}
+
+// DEVICE LOGIN (public: a trusted register signing back in)
+func (h *UserHandler) HandleDeviceLogin(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		DeviceToken string `json:"device_token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, u, err := h.service.DeviceLogin(r.Context(), body.DeviceToken)
+	if errors.Is(err, ErrInvalidDevice) {
+		h.respondWithError(w, err)
+		return
+	}
+	h.respondWithLogin(w, tokens, u, err)
+}
+
+// REQUEST MAGIC LINK (always 202, so it can't be used to probe for accounts)
+func (h *UserHandler) HandleRequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Email string `json:"email"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err := h.service.RequestMagicLink(r.Context(), body.Email)
+	if errors.Is(err, ErrMagicLinkDisabled) || errors.Is(err, ErrInvalidUserInput) {
+		h.respondWithError(w, err)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to send login link", http.StatusInternalServerError)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusAccepted, map[string]string{"status": "sent"})
+}
+
+// MAGIC LINK LOGIN (exchanges the emailed token for a token pair)
+func (h *UserHandler) HandleMagicLinkLogin(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Token string `json:"token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, u, err := h.service.MagicLinkLogin(r.Context(), body.Token)
+	if errors.Is(err, ErrInvalidMagicLink) {
+		h.respondWithError(w, err)
+		return
+	}
+	h.respondWithLogin(w, tokens, u, err)
+}
+
+// REGISTER DEVICE (returns the remember-me token once)
+func (h *UserHandler) HandleRegisterDevice(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "User context missing", http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	device, token, err := h.service.RegisterDevice(r.Context(), userID, body.Name)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, map[string]any{
+		"device":       device,
+		"device_token": token,
+	})
+}
+
+// LIST DEVICES
+func (h *UserHandler) HandleListDevices(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "User context missing", http.StatusUnauthorized)
+		return
+	}
+
+	devices, err := h.service.ListDevices(r.Context(), userID)
+	if err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, devices)
+}
+
+// REVOKE DEVICE
+func (h *UserHandler) HandleRevokeDevice(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "User context missing", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.RevokeDevice(r.Context(), userID, id); err != nil {
+		h.respondWithError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, map[string]string{"status": "revoked"})
+}
+
+// --- Helpers ---
+
+// respondWithLogin writes the outcome of a password or PIN login.
+func (h *UserHandler) respondWithLogin(w http.ResponseWriter, tokens *TokenPair, u *User, err error) {
+	var locked *LockedError
+	if errors.As(err, &locked) {
+		retry := int(time.Until(locked.Until).Seconds()) + 1
+		w.Header().Set("Retry-After", strconv.Itoa(retry))
+		h.respondWithJSON(w, http.StatusLocked, map[string]string{"error": locked.Error()})
+		return
+	}
+	if errors.Is(err, ErrCaptchaRequired) {
+		// Machine-readable so the client knows to show the challenge and retry
+		h.respondWithJSON(w, http.StatusUnauthorized, map[string]any{
+			"error":            err.Error(),
+			"captcha_required": true,
+		})
+		return
+	}
 	if err != nil {
 		// Log the error internally if you have a logger, but return generic msg to user
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	// Return Token and User Info
-	h.respondWithJSON(w, http.StatusOK, map[string]any{
-		"token": token,
+	// Return Tokens and User Info
+	resp := map[string]any{
+		"token":      tokens.AccessToken,
+		"expires_in": tokens.ExpiresIn,
 		"user": map[string]any{
 			"id":           u.Id,
 			"username":     u.Username,
 			"display_name": u.DisplayName,
 			"role":         u.Role,
 		},
-	})
+	}
+	if tokens.RefreshToken != "" { // PIN logins don't get one
+		resp["refresh_token"] = tokens.RefreshToken
+	}
+	h.respondWithJSON(w, http.StatusOK, resp)
 }
 
-// --- Helpers ---
-
 func (h *UserHandler) respondWithJSON(w http.ResponseWriter, code int, payload any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
@@ -268,8 +1016,22 @@ func (h *UserHandler) respondWithError(w http.ResponseWriter, err error) {
 		statusCode = http.StatusNotFound
 	case errors.Is(err, ErrInvalidUserInput):
 		statusCode = http.StatusBadRequest
-	case errors.Is(err, ErrDuplicateUsername):
+	case errors.Is(err, ErrDuplicateUsername), errors.Is(err, ErrDuplicateEmail), errors.Is(err, ErrUserConflict), errors.Is(err, ErrUserAnonymized):
 		statusCode = http.StatusConflict
+	case errors.Is(err, ErrInvalidPIN):
+		statusCode = http.StatusBadRequest
+	case errors.Is(err, ErrInvalidRefresh), errors.Is(err, ErrInvalidDevice), errors.Is(err, ErrInvalidMagicLink):
+		statusCode = http.StatusUnauthorized
+	case errors.Is(err, ErrDeviceNotFound), errors.Is(err, ErrMagicLinkDisabled):
+		statusCode = http.StatusNotFound
+	case errors.Is(err, ErrAlreadyClockedIn), errors.Is(err, ErrNotClockedIn), errors.Is(err, ErrPolicyOutdated):
+		statusCode = http.StatusConflict
+	case errors.Is(err, ErrPasswordTooShort), errors.Is(err, ErrPasswordReused):
+		statusCode = http.StatusBadRequest
+	case errors.Is(err, ErrInvalidImage), errors.Is(err, ErrInvalidSetting):
+		statusCode = http.StatusBadRequest
+	case errors.Is(err, ErrWrongPassword), errors.Is(err, ErrPolicyNotAccepted):
+		statusCode = http.StatusForbidden
 	default:
 		statusCode = http.StatusInternalServerError
 	}
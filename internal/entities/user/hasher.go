@@ -0,0 +1,143 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var errMalformedHash = errors.New("malformed password hash")
+
+// PasswordHasher turns passwords into stored hashes and checks them. Swap in
+// another implementation with SetPasswordHasher; existing hashes it doesn't
+// like are replaced on the user's next login (see NeedsRehash).
+type PasswordHasher interface {
+	Hash(raw string) (string, error)
+	Verify(hash, raw string) bool
+	NeedsRehash(hash string) bool
+}
+
+// passwords is the hasher in use, argon2id with the default parameters
+// until main installs another.
+var passwords PasswordHasher = NewArgon2Hasher(DefaultArgon2Params())
+
+// SetPasswordHasher replaces the hasher used for every password.
+func SetPasswordHasher(h PasswordHasher) {
+	passwords = h
+}
+
+// Argon2Params tunes argon2id. Raising them makes new hashes slower to crack;
+// existing hashes are upgraded on the user's next successful login.
+type Argon2Params struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follows the OWASP baseline (64 MiB, 3 passes).
+func DefaultArgon2Params() Argon2Params {
+	return Argon2Params{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// Argon2Hasher is the built-in PasswordHasher. It also verifies the bcrypt
+// hashes of older accounts, which it then reports as needing a rehash.
+type Argon2Hasher struct {
+	params Argon2Params
+}
+
+func NewArgon2Hasher(p Argon2Params) *Argon2Hasher {
+	return &Argon2Hasher{params: p}
+}
+
+// Hash returns an argon2id hash in the PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func (h *Argon2Hasher) Hash(raw string) (string, error) {
+	p := h.params
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(raw), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify checks raw against an argon2id or legacy bcrypt hash.
+func (h *Argon2Hasher) Verify(hash, raw string) bool {
+	if isBcrypt(hash) {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(raw)) == nil
+	}
+
+	p, salt, key, err := decodeArgon2(hash)
+	if err != nil {
+		return false
+	}
+	got := argon2.IDKey([]byte(raw), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(got, key) == 1
+}
+
+// NeedsRehash reports whether hash should be replaced on the next login:
+// bcrypt hashes, and argon2id hashes made with weaker parameters.
+func (h *Argon2Hasher) NeedsRehash(hash string) bool {
+	if isBcrypt(hash) {
+		return true
+	}
+
+	p, _, key, err := decodeArgon2(hash)
+	if err != nil {
+		return false
+	}
+	cur := h.params
+	return p.Memory < cur.Memory || p.Iterations < cur.Iterations ||
+		p.Parallelism != cur.Parallelism || uint32(len(key)) < cur.KeyLength
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2")
+}
+
+func decodeArgon2(hash string) (Argon2Params, []byte, []byte, error) {
+	var p Argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, errMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, errMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, errMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errMalformedHash
+	}
+
+	return p, salt, key, nil
+}
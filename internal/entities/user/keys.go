@@ -0,0 +1,185 @@
+package user
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKey     = errors.New("unknown signing key")
+	ErrNoSigningKey   = errors.New("no active signing key")
+	ErrUnsupportedKey = errors.New("unsupported key type, use RSA or Ed25519")
+)
+
+// SigningKey is one entry of a KeySet. Keys with only a public half can still
+// verify tokens they signed before being retired.
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	sign   any // Private key or HMAC secret, nil for verify-only keys
+	verify any
+}
+
+// KeySet holds every key access tokens may be signed with, indexed by the
+// "kid" header. New tokens use the active key; rotating means adding a new
+// key, making it active, and removing the old one once its tokens expired.
+// Keys may change while tokens are being signed, e.g. when the JWT secret is
+// refreshed from a secret store.
+type KeySet struct {
+	mu     sync.RWMutex
+	active string
+	keys   map[string]*SigningKey
+}
+
+func NewKeySet() *KeySet {
+	return &KeySet{keys: make(map[string]*SigningKey)}
+}
+
+// AddHMAC adds a shared-secret HS256 key. Tokens issued before key rotation
+// existed carry no kid, so the legacy JWT_SECRET key uses kid "".
+func (ks *KeySet) AddHMAC(kid string, secret []byte) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[kid] = &SigningKey{ID: kid, Method: jwt.SigningMethodHS256, sign: secret, verify: secret}
+}
+
+// AddPEM adds an RSA (RS256) or Ed25519 (EdDSA) key. A private key can sign
+// and verify; a public key only verifies.
+func (ks *KeySet) AddPEM(kid string, data []byte) error {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return fmt.Errorf("key %q: no PEM block found", kid)
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return fmt.Errorf("key %q: unexpected PEM block %q", kid, block.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("key %q: %w", kid, err)
+	}
+
+	k := &SigningKey{ID: kid}
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		k.Method, k.sign, k.verify = jwt.SigningMethodRS256, key, &key.PublicKey
+	case *rsa.PublicKey:
+		k.Method, k.verify = jwt.SigningMethodRS256, key
+	case ed25519.PrivateKey:
+		k.Method, k.sign, k.verify = jwt.SigningMethodEdDSA, key, key.Public()
+	case ed25519.PublicKey:
+		k.Method, k.verify = jwt.SigningMethodEdDSA, key
+	default:
+		return fmt.Errorf("key %q: %w", kid, ErrUnsupportedKey)
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[kid] = k
+	return nil
+}
+
+// SetActive picks the key new tokens are signed with.
+func (ks *KeySet) SetActive(kid string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	k, ok := ks.keys[kid]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	if k.sign == nil {
+		return fmt.Errorf("key %q is verify-only", kid)
+	}
+	ks.active = kid
+	return nil
+}
+
+// LoadKeySet reads every *.pem file in dir, using the file name (without
+// extension) as the kid.
+func LoadKeySet(dir, activeKid string) (*KeySet, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	ks := NewKeySet()
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key: %w", err)
+		}
+		kid := strings.TrimSuffix(filepath.Base(f), ".pem")
+		if err := ks.AddPEM(kid, data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ks.SetActive(activeKid); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Sign signs the claims with the active key, implementing TokenProvider.
+func (ks *KeySet) Sign(claims *Claims) (string, error) {
+	ks.mu.RLock()
+	k, ok := ks.keys[ks.active]
+	ks.mu.RUnlock()
+	if !ok || k.sign == nil {
+		return "", ErrNoSigningKey
+	}
+
+	token := jwt.NewWithClaims(k.Method, claims)
+	if k.ID != "" {
+		token.Header["kid"] = k.ID
+	}
+	return token.SignedString(k.sign)
+}
+
+// Verify checks the signature and expiry, implementing TokenProvider. The
+// key set checks the algorithm against the key, preventing downgrade attacks.
+func (ks *KeySet) Verify(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, ks.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		return claims, nil
+	}
+	return nil, errors.New("invalid token")
+}
+
+// keyFunc finds the verification key by kid and refuses tokens whose alg
+// doesn't match it, which blocks algorithm confusion attacks.
+func (ks *KeySet) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	ks.mu.RLock()
+	k, ok := ks.keys[kid]
+	ks.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	if token.Method.Alg() != k.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return k.verify, nil
+}
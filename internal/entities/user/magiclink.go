@@ -0,0 +1,120 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/mail"
+)
+
+var (
+	ErrMagicLinkDisabled = errors.New("magic link login is not enabled")
+	ErrInvalidMagicLink  = errors.New("invalid or expired login link")
+)
+
+// MagicLinkConfig enables passwordless login by email. URL is the client page
+// that receives the link; the token is appended as ?token= and the page
+// exchanges it via POST /auth/magic-link/verify. Links last TokenConfig.MagicTTL.
+type MagicLinkConfig struct {
+	Sender mail.Sender // Nil disables magic links
+	URL    string
+}
+
+var magicLinks MagicLinkConfig
+
+// SetMagicLinkConfig enables (or, with a nil Sender, disables) magic links.
+func SetMagicLinkConfig(cfg MagicLinkConfig) {
+	magicLinks = cfg
+}
+
+// RequestMagicLink emails a single-use login link to the account with that
+// email. Unknown or inactive addresses are silently ignored so the endpoint
+// doesn't reveal who has an account.
+func (s *userService) RequestMagicLink(ctx context.Context, email string) error {
+	if magicLinks.Sender == nil {
+		return ErrMagicLinkDisabled
+	}
+	if email == "" {
+		return ErrInvalidUserInput
+	}
+
+	u, err := s.repo.GetByEmail(ctx, email)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !u.Active {
+		return nil
+	}
+
+	// Same scheme as refresh tokens: random, and only the hash is stored
+	raw, hash, err := NewRefreshToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(tokens.MagicTTL)
+	if err := s.repo.CreateMagicLink(ctx, u.Id, hash, expiresAt); err != nil {
+		return err
+	}
+
+	link, err := url.Parse(magicLinks.URL)
+	if err != nil {
+		return fmt.Errorf("invalid magic link URL: %w", err)
+	}
+	q := link.Query()
+	q.Set("token", raw)
+	link.RawQuery = q.Encode()
+
+	msg := mail.Message{
+		To:      u.Email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf("Hi %s,\n\nUse this link to log in. It works once and expires in %s.\n\n%s\n\n"+
+			"If you didn't ask for it, you can ignore this email.\n",
+			u.DisplayName, tokens.MagicTTL, link),
+	}
+	if err := magicLinks.Sender.Send(ctx, msg); err != nil {
+		log.Printf("user: failed to send magic link to user %d: %v", u.Id, err)
+		return err
+	}
+	return nil
+}
+
+// MagicLinkLogin exchanges a link token for a normal token pair. The token is
+// consumed even if the login is then refused (locked out, deactivated).
+func (s *userService) MagicLinkLogin(ctx context.Context, token string) (pair *TokenPair, u *User, err error) {
+	var username string
+	defer func() { s.recordLoginAttempt(ctx, LoginMethodMagicLink, username, u, err) }()
+
+	if token == "" {
+		return nil, nil, ErrInvalidMagicLink
+	}
+
+	userId, err := s.repo.UseMagicLink(ctx, HashRefreshToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	existing, err := s.repo.GetByID(ctx, userId)
+	if err != nil {
+		return nil, nil, ErrInvalidMagicLink
+	}
+	username = existing.Username
+
+	u, err = s.authenticate(ctx, existing.Username, func(*User) error { return nil })
+	if err != nil {
+		return nil, nil, err
+	}
+	s.logActivity(ctx, u.Id, ActivityLogin, map[string]any{"method": "magic_link"})
+
+	pair, err = s.issueTokens(ctx, u)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, u, nil
+}
@@ -1,12 +1,201 @@
 package user
 
+import "time"
+
 type User struct {
 	Id          int
 	Username    string
 	DisplayName string
-	Hash        string
+	Email       string // Optional, used to match single sign-on identities
+	Hash        string `json:"-"` // Never serialized, handlers respond with UserResponse
 	Role        string // Slug of Role
 	Active      bool
-	Setting     map[string]any
+	Version     int        `json:"-"` // Token version, access tokens carrying an older one are rejected
+	Revision    int        // Goes up with every change to the account, see UserInput.Revision
+	LastLoginAt *time.Time // Nil if the user never logged in
+	LastLoginIP string
+	AvatarURL   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time // Moves with Revision
+	Setting     Settings
 	Custom      map[string]any
+
+	// MustChangePassword holds the user to changing their password before
+	// anything else, set on accounts an admin created or reset
+	MustChangePassword bool
+
+	// TempRole replaces Role until TempRoleExpiresAt, for temporary
+	// elevation such as covering for a manager. See EffectiveRole.
+	TempRole          string
+	TempRoleExpiresAt *time.Time
+
+	DeletedAt *time.Time // Set while in the trash, and for good once anonymized
+
+	StoreId int `json:"-"` // The store the account belongs to, and signs in to
+}
+
+// UserResponse is the API representation of a User. Handlers never encode
+// User directly so credentials and internal fields can't leak.
+type UserResponse struct {
+	Id          int            `json:"id"`
+	Username    string         `json:"username"`
+	DisplayName string         `json:"display_name"`
+	Email       string         `json:"email,omitempty"`
+	Role        string         `json:"role"`
+	Active      bool           `json:"active"`
+	AvatarURL   string         `json:"avatar_url,omitempty"`
+	LastLoginAt *time.Time     `json:"last_login_at"`
+	LastLoginIP string         `json:"last_login_ip,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	Setting     Settings       `json:"setting"`
+	Custom      map[string]any `json:"custom"`
+	Revision    int            `json:"revision"`
+
+	MustChangePassword bool `json:"must_change_password"`
+
+	TempRole          string     `json:"temp_role,omitempty"` // Only while it is in effect
+	TempRoleExpiresAt *time.Time `json:"temp_role_expires_at,omitempty"`
+
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func NewUserResponse(u *User) UserResponse {
+	resp := UserResponse{
+		Id:          u.Id,
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		Email:       u.Email,
+		Role:        u.Role,
+		Active:      u.Active,
+		AvatarURL:   u.AvatarURL,
+		LastLoginAt: u.LastLoginAt,
+		LastLoginIP: u.LastLoginIP,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		Setting:     u.Setting,
+		Custom:      u.Custom,
+		Revision:    u.Revision,
+
+		MustChangePassword: u.MustChangePassword,
+
+		DeletedAt: u.DeletedAt,
+	}
+	if role := u.EffectiveRole(); role != u.Role {
+		resp.TempRole = role
+		resp.TempRoleExpiresAt = u.TempRoleExpiresAt
+	}
+	return resp
+}
+
+func NewUserResponses(users []*User) []UserResponse {
+	out := make([]UserResponse, 0, len(users))
+	for _, u := range users {
+		out = append(out, NewUserResponse(u))
+	}
+	return out
 }
+
+// Device is a trusted device holding a remember-me token. The token itself
+// is only shown once, when the device is registered.
+type Device struct {
+	Id         int        `json:"id"`
+	UserId     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+}
+
+// TimeEntry is one shift on the clock. ClockOut is nil while it's running.
+type TimeEntry struct {
+	Id       int        `json:"id"`
+	UserId   int        `json:"user_id"`
+	ClockIn  time.Time  `json:"clock_in"`
+	ClockOut *time.Time `json:"clock_out"`
+}
+
+// HoursLine is one user's attendance over a report period. Shifts crossing
+// the period edges only count the part inside it.
+type HoursLine struct {
+	UserId      int     `json:"user_id"`
+	Username    string  `json:"username"`
+	DisplayName string  `json:"display_name"`
+	Shifts      int     `json:"shifts"`
+	Seconds     int64   `json:"seconds"`
+	Hours       float64 `json:"hours"`
+}
+
+// LoginAttempt is one sign-in try, recorded whatever the outcome.
+type LoginAttempt struct {
+	Id        int       `json:"id"`
+	UserId    *int      `json:"user_id"` // Nil if the username matched nobody
+	Username  string    `json:"username"`
+	Method    string    `json:"method"`
+	Outcome   string    `json:"outcome"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Login methods
+const (
+	LoginMethodPassword  = "password"
+	LoginMethodPIN       = "pin"
+	LoginMethodDevice    = "device"
+	LoginMethodOIDC      = "oidc"
+	LoginMethodMagicLink = "magic_link"
+)
+
+// Login outcomes
+const (
+	LoginSuccess            = "success"
+	LoginInvalidCredentials = "invalid_credentials"
+	LoginLocked             = "locked"
+	LoginInactive           = "inactive"
+	LoginCaptchaRequired    = "captcha_required"
+	LoginError              = "error" // A backend failed, not the caller's fault
+)
+
+// UsernameChange is a name the user went by until ChangedAt.
+type UsernameChange struct {
+	Username  string    `json:"username"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// Activity is one entry in a user's security audit trail.
+type Activity struct {
+	Id        int            `json:"id"`
+	UserId    int            `json:"user_id"`            // The account acted upon
+	ActorId   *int           `json:"actor_id,omitempty"` // Who did it, nil if unknown
+	Action    string         `json:"action"`
+	IP        string         `json:"ip,omitempty"`
+	Detail    map[string]any `json:"detail,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+const (
+	ActivityLogin          = "login"
+	ActivityPasswordChange = "password_change"
+	ActivityRoleChange     = "role_change"
+	ActivityDeactivate     = "deactivate"
+	ActivityReactivate     = "reactivate"
+	ActivityDelete         = "delete"
+	ActivityRestore        = "restore"
+	ActivityAnonymize      = "anonymize"
+	ActivityAPIToken       = "api_token"
+	ActivityUsernameChange = "username_change"
+	ActivityTempRole       = "temp_role"
+)
+
+// Audit log actions of users, besides the ones every entity shares.
+const (
+	ActionAnonymize      = "anonymize"
+	ActionPasswordChange = "password_change"
+	ActionPINSet         = "pin_set"
+	ActionPINRemove      = "pin_remove"
+	ActionDeviceRegister = "device_register"
+	ActionDeviceRevoke   = "device_revoke"
+	ActionUnlock         = "unlock"
+	ActionPolicyAccept   = "policy_accept"
+)
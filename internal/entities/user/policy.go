@@ -0,0 +1,72 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPolicyNotAccepted = errors.New("the current staff policy must be accepted first")
+	ErrPolicyOutdated    = errors.New("that is not the current staff policy version")
+)
+
+// policyVersion is the staff policy everyone must have accepted, e.g.
+// "2024-03". Empty disables the requirement.
+var policyVersion string
+
+// SetPolicyVersion sets the current staff policy. Publishing a new version
+// makes everyone accept again before their next write.
+func SetPolicyVersion(v string) {
+	policyVersion = strings.TrimSpace(v)
+}
+
+// PolicyAcceptance records a user agreeing to one policy version.
+type PolicyAcceptance struct {
+	Version    string    `json:"version"`
+	AcceptedAt time.Time `json:"accepted_at"`
+}
+
+// PolicyStatus is shown on the user's profile.
+type PolicyStatus struct {
+	CurrentVersion  string     `json:"current_version"`
+	AcceptedVersion string     `json:"accepted_version,omitempty"`
+	AcceptedAt      *time.Time `json:"accepted_at,omitempty"`
+	Accepted        bool       `json:"accepted"` // The current version is accepted
+}
+
+func newPolicyStatus(latest *PolicyAcceptance) *PolicyStatus {
+	status := &PolicyStatus{CurrentVersion: policyVersion}
+	if latest != nil {
+		status.AcceptedVersion = latest.Version
+		status.AcceptedAt = &latest.AcceptedAt
+		status.Accepted = latest.Version == policyVersion
+	}
+	return status
+}
+
+// policyExemptRoutes stay usable before accepting, so people can read the
+// profile, accept and log out.
+var policyExemptRoutes = []string{"/me", "/logout"}
+
+// NeedsPolicy reports whether the request has to wait for the current staff
+// policy to be accepted. Only writes are held back, and integration tokens
+// aren't people so they never are.
+func (c *Claims) NeedsPolicy(method, path string) bool {
+	if policyVersion == "" || c.Scope == ScopeAPI {
+		return false
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+
+	for _, prefix := range policyExemptRoutes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return false
+		}
+	}
+	return true
+}
@@ -6,45 +6,152 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
 )
 
 var (
 	ErrUserNotFound       = errors.New("user not found")
 	ErrInvalidUserInput   = errors.New("invalid user input")
 	ErrDuplicateUsername  = errors.New("username already exists")
+	ErrDuplicateEmail     = errors.New("email already in use")
 	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidRefresh     = errors.New("invalid or expired refresh token")
+	ErrInvalidDevice      = errors.New("invalid or expired device token")
+	ErrDeviceNotFound     = errors.New("device not found")
+	ErrAlreadyClockedIn   = errors.New("already clocked in")
+	ErrNotClockedIn       = errors.New("not clocked in")
+	ErrTokenRevoked       = errors.New("token has been revoked")
+	ErrAccountLocked      = errors.New("account is temporarily locked")
+	ErrInvalidPIN         = errors.New("pin must be 4 to 8 digits")
+	ErrUserConflict       = errors.New("user was changed since it was read")
+	ErrUserAnonymized     = errors.New("anonymized users cannot be restored")
 )
 
+// LockedError is returned by Login while the account is locked out.
+// It matches ErrAccountLocked with errors.Is.
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s until %s", ErrAccountLocked, e.Until.UTC().Format(time.RFC3339))
+}
+
+func (e *LockedError) Is(target error) bool {
+	return target == ErrAccountLocked
+}
+
 type UserRepository interface {
 	Create(ctx context.Context, user *User) error
+	Import(ctx context.Context, client database.SQLClient, user *User, anonymized bool, locationIds []int) (bool, error)
 	GetByID(ctx context.Context, id int) (*User, error)
 	GetByUsername(ctx context.Context, username string) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetByFormerUsername(ctx context.Context, username string) (*User, error)
+	UsernameTaken(ctx context.Context, username string, exceptId int) (bool, error)
+	GetUsernameHistory(ctx context.Context, id int) ([]UsernameChange, error)
 	Update(ctx context.Context, user *User) error
-	Delete(ctx context.Context, id int) error
-	List(ctx context.Context, opts UserListOptions) ([]*User, error)
-	UpdatePassword(ctx context.Context, id int, hash string) error
-	UpdateSettings(ctx context.Context, id int, settings map[string]any) error
+	Trash(ctx context.Context, id int) error
+	Restore(ctx context.Context, id int) error
+	Anonymize(ctx context.Context, id int) error // Purges from the trash, or straight away
+	TrashedBefore(ctx context.Context, cutoff time.Time) ([]int, error)
+	SetAvatar(ctx context.Context, id int, url string) (previous string, err error)
+	List(ctx context.Context, opts UserListOptions) ([]*User, int, error)
+	UpdatePassword(ctx context.Context, id int, hash string, mustChange bool) error
+	RehashPassword(ctx context.Context, id int, hash string) error
+	UpdateSettings(ctx context.Context, id int, patch SettingsPatch) (Settings, error)
 	SetActive(ctx context.Context, id int, active bool) error
 	GetByRole(ctx context.Context, role string) ([]*User, error)
-	Search(ctx context.Context, query string) ([]*User, error)
 	Count(ctx context.Context) (int, error)
+
+	// PIN
+	SetPinHash(ctx context.Context, id int, hash string) error
+	GetPinHash(ctx context.Context, id int) (string, error)
+
+	// Lockout
+	GetLockedUntil(ctx context.Context, id int) (time.Time, error)
+	GetFailedLogins(ctx context.Context, id int) (int, error)
+	RecordFailedLogin(ctx context.Context, id int, maxAttempts int, cooldown time.Duration) (time.Time, error)
+	ClearFailedLogins(ctx context.Context, id int) error
+
+	// Temporary roles
+	SetTempRole(ctx context.Context, id int, role string, expiresAt *time.Time) error
+	ClearExpiredTempRoles(ctx context.Context) (int, error)
+	RecordLogin(ctx context.Context, id int, ip string) error
+	RecordLoginAttempt(ctx context.Context, a *LoginAttempt) error
+	ListLoginAttempts(ctx context.Context, userId int, username string, limit, offset int) ([]*LoginAttempt, error)
+
+	// Locations
+	GetLocationIDs(ctx context.Context, userId int) ([]int, error)
+	HasLocations(ctx context.Context) (bool, error)
+	GetLocationSlugs(ctx context.Context, userId int, all bool) (map[int]string, error)
+	SetLocations(ctx context.Context, userId int, locationIds []int) error
+
+	// Staff policy
+	AcceptPolicy(ctx context.Context, userId int, version string) (*PolicyAcceptance, error)
+	GetPolicyAcceptance(ctx context.Context, userId int) (*PolicyAcceptance, error)
+
+	// Sessions
+	GetTokenVersion(ctx context.Context, id int) (version int, active bool, err error)
+	BumpTokenVersion(ctx context.Context, id int) error
+	RevokeRefreshTokens(ctx context.Context, userId int) error
+
+	// Audit
+	LogActivity(ctx context.Context, a *Activity) error
+	ListActivity(ctx context.Context, userId, limit, offset int) ([]*Activity, error)
+
+	// Devices
+	CreateDevice(ctx context.Context, d *Device, hash string) error
+	ListDevices(ctx context.Context, userId int) ([]*Device, error)
+	UseDevice(ctx context.Context, hash string, expiresAt time.Time) (userId int, err error)
+	RevokeDevice(ctx context.Context, userId, deviceId int) error
+	RevokeDevices(ctx context.Context, userId int) error
+
+	// Timekeeping
+	ClockIn(ctx context.Context, userId int) (*TimeEntry, error)
+	ClockOut(ctx context.Context, userId int) (*TimeEntry, error)
+	GetOpenTimeEntry(ctx context.Context, userId int) (*TimeEntry, error)
+	GetHoursReport(ctx context.Context, start, end time.Time, userId int) ([]HoursLine, error)
+
+	// Magic links
+	CreateMagicLink(ctx context.Context, userId int, hash string, expiresAt time.Time) error
+	UseMagicLink(ctx context.Context, hash string) (userId int, err error)
+
+	// Refresh tokens
+	CreateRefreshToken(ctx context.Context, userId int, hash string, expiresAt time.Time) error
+	RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (int, error)
 }
 
+// userColumns is the select list matching scanUser.
+const userColumns = `id, username, display_name, hash, role, active, setting, custom,
+		       last_login_at, COALESCE(last_login_ip, ''), COALESCE(avatar_url, ''), COALESCE(email, ''), created_at, updated_at,
+		       must_change_password, COALESCE(temp_role, ''), temp_role_expires_at, revision, deleted_at, store_id`
+
 type UserListOptions struct {
-	Role      string
-	Active    *bool // pointer so we can distinguish between false and not set
-	Limit     int
-	Offset    int
-	SortBy    string // username, display_name, id
-	SortOrder string // asc, desc
+	Deleted    bool   // List the trash (deleted, not yet anonymized) instead
+	Anonymized bool   // With Deleted, list the anonymized users instead of the trash
+	Query      string // Matches username or display name
+	Role       string
+	Active     *bool // pointer so we can distinguish between false and not set
+	Period     database.Period
+	Limit      int
+	Offset     int
+	After      string // Cursor from the page before, in place of Offset
+	SortBy     string // username (default), display_name, id, created_at, updated_at, last_login_at
+	SortOrder  string // asc (default), desc
+	Total      bool   // Also count every match, for List to return
 }
 
 type userRepository struct {
-	db *sql.DB
+	db      *database.DB
+	dialect database.Dialect
 }
 
 func NewUserRepository(db *sql.DB) UserRepository {
-	return &userRepository{db: db}
+	return &userRepository{db: database.Observe(db, "users"), dialect: database.DialectOf(db)}
 }
 
 func (r *userRepository) Create(ctx context.Context, user *User) error {
@@ -57,25 +164,27 @@ func (r *userRepository) Create(ctx context.Context, user *User) error {
 		return fmt.Errorf("failed to marshal settings: %w", err)
 	}
 
-	customJSON, err := json.Marshal(user.Custom)
+	customJSON, err := database.MarshalCustom("user", user.Custom)
 	if err != nil {
 		return fmt.Errorf("failed to marshal custom data: %w", err)
 	}
 
 	query := `
-		INSERT INTO users (username, display_name, hash, role, active, setting, custom)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id
+		INSERT INTO users (username, display_name, hash, role, active, setting, custom, email, must_change_password, store_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, $11, $11)
 	`
+	user.StoreId = database.StoreOf(ctx)
+	args := []any{
+		user.Username, user.DisplayName, user.Hash, user.Role, user.Active, settingJSON, customJSON, user.Email,
+		user.MustChangePassword, user.StoreId, time.Now(),
+	}
 
-	err = r.db.QueryRowContext(
-		ctx, query,
-		user.Username, user.DisplayName, user.Hash, user.Role, user.Active, settingJSON, customJSON,
-	).Scan(&user.Id)
+	err = r.dialect.InsertReturning(ctx, r.db, "users", query, "id, created_at, updated_at, revision", args,
+		&user.Id, &user.CreatedAt, &user.UpdatedAt, &user.Revision)
 
 	if err != nil {
-		if isDuplicateKeyError(err) {
-			return ErrDuplicateUsername
+		if database.IsUniqueViolation(err) {
+			return duplicateError(err)
 		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
@@ -83,64 +192,158 @@ func (r *userRepository) Create(ctx context.Context, user *User) error {
 	return nil
 }
 
-func (r *userRepository) GetByID(ctx context.Context, id int) (*User, error) {
-	query := `
-		SELECT id, username, display_name, hash, role, active, setting, custom
-		FROM users
-		WHERE id = $1
-	`
+// Import inserts a user read back from a backup as they were, id, revision
+// and timestamps included, along with their store assignments. Users that
+// were anonymized stay so. It reports false, writing nothing, if a user
+// with their id, username or email is already there. Their locations must
+// be there first.
+func (r *userRepository) Import(ctx context.Context, client database.SQLClient, user *User, anonymized bool, locationIds []int) (bool, error) {
+	client = r.db.On(client)
+	user.StoreId = database.StoreOf(ctx)
+	exists, err := database.Exists(ctx, client, "users", "id = $1 OR ((LOWER(username) = LOWER($2) OR email = NULLIF($3, '')) AND store_id = $4)",
+		user.Id, user.Username, user.Email, user.StoreId)
+	if err != nil || exists {
+		return false, err
+	}
 
-	user := &User{}
-	var settingJSON, customJSON []byte
+	settingJSON, err := json.Marshal(user.Setting)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal settings: %w", err)
+	}
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.Id, &user.Username, &user.DisplayName, &user.Hash,
-		&user.Role, &user.Active, &settingJSON, &customJSON,
-	)
+	customJSON, err := database.MarshalCustom("user", user.Custom)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal custom data: %w", err)
+	}
 
-	if err == sql.ErrNoRows {
-		return nil, ErrUserNotFound
+	var anonymizedAt *time.Time
+	if anonymized {
+		anonymizedAt = user.DeletedAt
 	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
+
+	query := `
+		INSERT INTO users (id, username, display_name, hash, role, active, setting, custom, email, must_change_password,
+		                   last_login_at, last_login_ip, avatar_url, temp_role, temp_role_expires_at,
+		                   revision, created_at, updated_at, deleted_at, anonymized_at, store_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, $11, NULLIF($12, ''), NULLIF($13, ''), NULLIF($14, ''), $15,
+		        $16, $17, $18, $19, $20, $21)
+	`
+	args := []any{
+		user.Id, user.Username, user.DisplayName, user.Hash, user.Role, user.Active, settingJSON, customJSON, user.Email,
+		user.MustChangePassword, user.LastLoginAt, user.LastLoginIP, user.AvatarURL, user.TempRole, user.TempRoleExpiresAt,
+		max(user.Revision, 1), user.CreatedAt, user.UpdatedAt, user.DeletedAt, anonymizedAt, user.StoreId,
+	}
+	if _, err := client.ExecContext(ctx, query, args...); err != nil {
+		return false, fmt.Errorf("failed to import user: %w", err)
 	}
 
-	if err := r.unmarshalUserData(user, settingJSON, customJSON); err != nil {
-		return nil, err
+	add := `INSERT INTO user_locations (user_id, location_id) VALUES ($1, $2)`
+	for _, locationId := range locationIds {
+		if _, err := client.ExecContext(ctx, add, user.Id, locationId); err != nil {
+			return false, fmt.Errorf("failed to import user locations: %w", err)
+		}
 	}
 
-	return user, nil
+	if err := r.dialect.ResetSequence(ctx, client, "users"); err != nil {
+		return false, fmt.Errorf("failed to reset user ids: %w", err)
+	}
+	return true, nil
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int) (*User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id = $1 AND store_id = $2
+	`
+
+	return database.Get(ctx, r.db, r.scanUser, ErrUserNotFound, query, id, database.StoreOf(ctx))
 }
 
+// GetByUsername matches case-insensitively, like the unique index.
 func (r *userRepository) GetByUsername(ctx context.Context, username string) (*User, error) {
 	query := `
-		SELECT id, username, display_name, hash, role, active, setting, custom
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE username = $1
+		WHERE LOWER(username) = LOWER($1) AND store_id = $2
 	`
 
-	user := &User{}
-	var settingJSON, customJSON []byte
+	return database.Get(ctx, r.db, r.scanUser, ErrUserNotFound, query, username, database.StoreOf(ctx))
+}
 
-	err := r.db.QueryRowContext(ctx, query, username).Scan(
-		&user.Id, &user.Username, &user.DisplayName, &user.Hash,
-		&user.Role, &user.Active, &settingJSON, &customJSON,
-	)
+// GetByEmail matches case-insensitively; emails are stored lowercased.
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE email = LOWER($1) AND store_id = $2 AND deleted_at IS NULL
+	`
 
-	if err == sql.ErrNoRows {
-		return nil, ErrUserNotFound
+	return database.Get(ctx, r.db, r.scanUser, ErrUserNotFound, query, email, database.StoreOf(ctx))
+}
+
+// GetByFormerUsername finds whoever most recently gave up the username.
+func (r *userRepository) GetByFormerUsername(ctx context.Context, username string) (*User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE id = (
+			SELECT h.user_id FROM username_history h
+			JOIN users u ON u.id = h.user_id
+			WHERE LOWER(h.username) = LOWER($1) AND u.store_id = $2
+			ORDER BY h.changed_at DESC, h.id DESC
+			LIMIT 1
+		)
+	`
+
+	return database.Get(ctx, r.db, r.scanUser, ErrUserNotFound, query, username, database.StoreOf(ctx))
+}
+
+// UsernameTaken reports whether another account (not exceptId) already uses
+// the username in any letter case.
+func (r *userRepository) UsernameTaken(ctx context.Context, username string, exceptId int) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(username) = LOWER($1) AND id <> $2 AND store_id = $3)`
+
+	var taken bool
+	if err := r.db.QueryRowContext(ctx, query, username, exceptId, database.StoreOf(ctx)).Scan(&taken); err != nil {
+		return false, fmt.Errorf("failed to check username: %w", err)
 	}
+	return taken, nil
+}
+
+// GetUsernameHistory lists the user's previous usernames, newest first.
+func (r *userRepository) GetUsernameHistory(ctx context.Context, id int) ([]UsernameChange, error) {
+	query := `
+		SELECT username, changed_at
+		FROM username_history
+		WHERE user_id = $1
+		ORDER BY changed_at DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return nil, fmt.Errorf("failed to get username history: %w", err)
 	}
+	defer rows.Close()
 
-	if err := r.unmarshalUserData(user, settingJSON, customJSON); err != nil {
-		return nil, err
+	history := []UsernameChange{}
+	for rows.Next() {
+		var c UsernameChange
+		if err := rows.Scan(&c.Username, &c.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan username history: %w", err)
+		}
+		history = append(history, c)
 	}
 
-	return user, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return history, nil
 }
 
+// Update saves the user. A changed username (including a change of case) is
+// recorded in username_history in the same transaction.
 func (r *userRepository) Update(ctx context.Context, user *User) error {
 	if user.Id == 0 {
 		return ErrInvalidUserInput
@@ -151,192 +354,402 @@ func (r *userRepository) Update(ctx context.Context, user *User) error {
 		return fmt.Errorf("failed to marshal settings: %w", err)
 	}
 
-	customJSON, err := json.Marshal(user.Custom)
+	customJSON, err := database.MarshalCustom("user", user.Custom)
 	if err != nil {
 		return fmt.Errorf("failed to marshal custom data: %w", err)
 	}
 
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var oldUsername string
+	var revision int
+	err = tx.QueryRowContext(ctx, `SELECT username, revision FROM users WHERE id = $1 AND store_id = $2 AND deleted_at IS NULL`+r.dialect.ForUpdate(), user.Id, database.StoreOf(ctx)).Scan(&oldUsername, &revision)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if revision != user.Revision {
+		return ErrUserConflict
+	}
+
+	if oldUsername != user.Username {
+		_, err := tx.ExecContext(ctx, `INSERT INTO username_history (user_id, username) VALUES ($1, $2)`, user.Id, oldUsername)
+		if err != nil {
+			return fmt.Errorf("failed to record username history: %w", err)
+		}
+	}
+
 	query := `
 		UPDATE users
 		SET username = $1, display_name = $2, hash = $3, role = $4, 
-		    active = $5, setting = $6, custom = $7
-		WHERE id = $8
+		    active = $5, setting = $6, custom = $7, email = NULLIF($8, ''),
+		    revision = revision + 1, updated_at = NOW()
+		WHERE id = $9
 	`
 
-	result, err := r.db.ExecContext(
+	_, err = tx.ExecContext(
 		ctx, query,
 		user.Username, user.DisplayName, user.Hash, user.Role,
-		user.Active, settingJSON, customJSON, user.Id,
+		user.Active, settingJSON, customJSON, user.Email, user.Id,
 	)
 
 	if err != nil {
-		if isDuplicateKeyError(err) {
-			return ErrDuplicateUsername
+		if database.IsUniqueViolation(err) {
+			return duplicateError(err)
 		}
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 
-	rows, err := result.RowsAffected()
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	user.Revision++
+
+	return nil
+}
+
+// SetAvatar stores the avatar URL (empty clears it) and returns the one it
+// replaced so the caller can remove the old file.
+func (r *userRepository) SetAvatar(ctx context.Context, id int, url string) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT COALESCE(avatar_url, '') FROM users WHERE id = $1 AND store_id = $2 AND deleted_at IS NULL` + r.dialect.ForUpdate()
+
+	var previous string
+	err = tx.QueryRowContext(ctx, query, id, database.StoreOf(ctx)).Scan(&previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrUserNotFound
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return "", fmt.Errorf("failed to get avatar: %w", err)
 	}
 
-	if rows == 0 {
-		return ErrUserNotFound
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET avatar_url = NULLIF($1, '') WHERE id = $2`, url, id); err != nil {
+		return "", fmt.Errorf("failed to set avatar: %w", err)
 	}
 
-	return nil
-}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
 
-func (r *userRepository) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM users WHERE id = $1`
+	return previous, nil
+}
 
-	result, err := r.db.ExecContext(ctx, query, id)
+// Trash deletes the account, reversibly: it is deactivated and hidden from
+// listings until restored, or anonymized when purged. Its access tokens stop
+// working at once.
+func (r *userRepository) Trash(ctx context.Context, id int) error {
+	ok, err := database.SoftDelete(ctx, r.db, "users", id,
+		"active = FALSE", "token_version = token_version + 1", "revision = revision + 1")
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
+	if !ok {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Restore takes an account out of the trash. It stays deactivated until
+// someone reactivates it; anonymized accounts can't be restored.
+func (r *userRepository) Restore(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	rows, err := result.RowsAffected()
+	var anonymized bool
+	err = tx.QueryRowContext(ctx, `SELECT anonymized_at IS NOT NULL FROM users WHERE id = $1 AND store_id = $2`+r.dialect.ForUpdate(), id, database.StoreOf(ctx)).Scan(&anonymized)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if anonymized {
+		return ErrUserAnonymized
 	}
 
-	if rows == 0 {
+	ok, err := database.Restore(ctx, tx, "users", id, "revision = revision + 1")
+	if err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+	if !ok {
 		return ErrUserNotFound
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
-func (r *userRepository) List(ctx context.Context, opts UserListOptions) ([]*User, error) {
+// TrashedBefore returns the ids of accounts deleted before the cutoff and
+// not anonymized yet, for the purge job.
+func (r *userRepository) TrashedBefore(ctx context.Context, cutoff time.Time) ([]int, error) {
+	ids, err := database.TrashedBefore(ctx, r.db, "users", cutoff, "anonymized_at IS NULL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trashed users: %w", err)
+	}
+	return ids, nil
+}
+
+// Anonymize is the permanent delete: the row stays so orders keep a valid
+// clerk_id, but everything personal is scrubbed and the account can never
+// log in again. Sessions and the IPs in the user's audit trail go with it.
+// Accounts can be anonymized from the trash or straight away.
+func (r *userRepository) Anonymize(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
-		SELECT id, username, display_name, hash, role, active, setting, custom
-		FROM users
-		WHERE 1=1
+		UPDATE users
+		SET username = 'deleted-' || id,
+		    display_name = 'Deleted user',
+		    hash = '',
+		    pin_hash = NULL,
+		    active = FALSE,
+		    token_version = token_version + 1,
+		    last_login_ip = NULL,
+		    avatar_url = NULL,
+		    email = NULL,
+		    setting = NULL,
+		    custom = NULL,
+		    revision = revision + 1, updated_at = NOW(),
+		    deleted_at = COALESCE(deleted_at, NOW()),
+		    anonymized_at = NOW()
+		WHERE id = $1 AND store_id = $2 AND anonymized_at IS NULL
 	`
-	args := []any{}
-	argPos := 1
 
-	if opts.Role != "" {
-		query += fmt.Sprintf(" AND role = $%d", argPos)
-		args = append(args, opts.Role)
-		argPos++
+	ok, err := database.ExecAffected(ctx, tx, query, id, database.StoreOf(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to anonymize user: %w", err)
 	}
-
-	if opts.Active != nil {
-		query += fmt.Sprintf(" AND active = $%d", argPos)
-		args = append(args, *opts.Active)
-		argPos++
+	if !ok {
+		return ErrUserNotFound
 	}
 
-	// Sorting
-	sortBy := "id"
-	if opts.SortBy != "" {
-		switch opts.SortBy {
-		case "username", "display_name", "id":
-			sortBy = opts.SortBy
+	cleanup := []string{
+		`DELETE FROM refresh_tokens WHERE user_id = $1`,
+		`DELETE FROM user_devices WHERE user_id = $1`,
+		`UPDATE user_activity SET ip = NULL WHERE user_id = $1`,
+		`UPDATE login_attempts SET username = 'deleted-' || user_id, ip = NULL, user_agent = NULL WHERE user_id = $1`,
+		`DELETE FROM username_history WHERE user_id = $1`,
+		`DELETE FROM magic_links WHERE user_id = $1`,
+	}
+	for _, q := range cleanup {
+		if _, err := tx.ExecContext(ctx, q, id); err != nil {
+			return fmt.Errorf("failed to anonymize user: %w", err)
 		}
 	}
 
-	sortOrder := "ASC"
-	if opts.SortOrder == "desc" {
-		sortOrder = "DESC"
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	query += fmt.Sprintf(" ORDER BY %s %s", sortBy, sortOrder)
+	return nil
+}
 
-	if opts.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argPos)
-		args = append(args, opts.Limit)
-		argPos++
+// List returns one page of users matching opts, and with opts.Total how
+// many match in all so clients can paginate.
+func (r *userRepository) List(ctx context.Context, opts UserListOptions) ([]*User, int, error) {
+	where := database.Live
+	if opts.Deleted {
+		where = database.Trashed + " AND anonymized_at IS NULL"
+		if opts.Anonymized {
+			where = "anonymized_at IS NOT NULL"
+		}
 	}
 
-	if opts.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argPos)
-		args = append(args, opts.Offset)
+	var f database.Filter
+	f.And("store_id = " + f.Arg(database.StoreOf(ctx)))
+	if opts.Query != "" {
+		param := f.Arg("%" + opts.Query + "%")
+		f.And("(" + r.dialect.ILike("username", param) + " OR " + r.dialect.ILike("display_name", param) + ")")
+	}
+	if opts.Role != "" {
+		f.And("role = " + f.Arg(opts.Role))
+	}
+	if opts.Active != nil {
+		f.And("active = " + f.Arg(*opts.Active))
+	}
+	f.Period(opts.Period)
+
+	// The cursor is kept out of f so the total counts every match, not just
+	// those after it
+	k := userKeyset(opts.SortBy, opts.SortOrder)
+	cursor := f.Outer()
+	if err := k.After(r.dialect, &cursor, opts.After); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidUserInput, err)
 	}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	users, total, err := database.SelectListing(ctx, r.db, r.scanUser, database.Listing{
+		Columns: userColumns, Table: "users", Where: where, Filter: f, Cursor: cursor,
+		OrderBy: k.OrderBy(r.dialect), Limit: opts.Limit, Offset: opts.Offset, Total: opts.Total,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to list users: %w", err)
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
 	}
-	defer rows.Close()
+	if users == nil {
+		users = []*User{}
+	}
+	return users, total, nil
+}
 
-	var users []*User
-	for rows.Next() {
-		user, err := r.scanUser(rows)
-		if err != nil {
-			return nil, err
+// userKeyset is the order List sorts users in: by username unless asked
+// otherwise, with those who never logged in last.
+func userKeyset(sortBy, sortOrder string) database.Keyset {
+	column := database.SortColumn(sortBy, "username", "id", "username", "display_name", "created_at", "updated_at", "last_login_at")
+	return database.Keyset{
+		Column:    column,
+		Direction: database.SortDirection(sortOrder, "ASC"),
+		Nullable:  column == "last_login_at",
+	}
+}
+
+// nextCursor is the cursor for the page after one ending with u, listed
+// in the given order.
+func nextCursor(u *User, sortBy, sortOrder string) string {
+	k := userKeyset(sortBy, sortOrder)
+	var key any
+	switch k.Column {
+	case "id":
+		key = u.Id
+	case "username":
+		key = u.Username
+	case "display_name":
+		key = u.DisplayName
+	case "created_at":
+		key = u.CreatedAt.Unix()
+	case "updated_at":
+		key = u.UpdatedAt.Unix()
+	case "last_login_at":
+		if u.LastLoginAt != nil {
+			key = u.LastLoginAt.Unix()
 		}
-		users = append(users, user)
 	}
+	return k.Cursor(key, u.Id)
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+func (r *userRepository) RehashPassword(ctx context.Context, id int, hash string) error {
+	query := `UPDATE users SET hash = $1 WHERE id = $2 AND store_id = $3`
+
+	if _, err := r.db.ExecContext(ctx, query, hash, id, database.StoreOf(ctx)); err != nil {
+		return fmt.Errorf("failed to rehash password: %w", err)
 	}
 
-	return users, nil
+	return nil
 }
 
-func (r *userRepository) UpdatePassword(ctx context.Context, id int, hash string) error {
-	query := `UPDATE users SET hash = $1 WHERE id = $2`
+// UpdatePassword replaces the hash. mustChange makes the user pick their own
+// password on the next login, for passwords someone else chose.
+func (r *userRepository) UpdatePassword(ctx context.Context, id int, hash string, mustChange bool) error {
+	// A new password logs out every existing session
+	query := `
+		UPDATE users
+		SET hash = $1, must_change_password = $2, token_version = token_version + 1, revision = revision + 1, updated_at = NOW()
+		WHERE id = $3 AND store_id = $4
+	`
 
-	result, err := r.db.ExecContext(ctx, query, hash, id)
+	ok, err := database.ExecAffected(ctx, r.db, query, hash, mustChange, id, database.StoreOf(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
-
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	if rows == 0 {
+	if !ok {
 		return ErrUserNotFound
 	}
 
 	return nil
 }
 
-func (r *userRepository) UpdateSettings(ctx context.Context, id int, settings map[string]any) error {
-	settingJSON, err := json.Marshal(settings)
+// UpdateSettings merges patch into the stored settings in one statement so
+// concurrent updates to different keys don't overwrite each other.
+func (r *userRepository) UpdateSettings(ctx context.Context, id int, patch SettingsPatch) (Settings, error) {
+	var settings Settings
+
+	setJSON, err := json.Marshal(patch.Set)
 	if err != nil {
-		return fmt.Errorf("failed to marshal settings: %w", err)
+		return settings, fmt.Errorf("failed to marshal settings: %w", err)
 	}
 
-	query := `UPDATE users SET setting = $1 WHERE id = $2`
+	query := `
+		UPDATE users SET setting = (COALESCE(setting, '{}'::jsonb) || $1::jsonb) - $2::text[], revision = revision + 1, updated_at = NOW()
+		WHERE id = $3 AND store_id = $4
+	`
 
-	result, err := r.db.ExecContext(ctx, query, settingJSON, id)
-	if err != nil {
-		return fmt.Errorf("failed to update settings: %w", err)
+	// A nil slice would be sent as NULL, and jsonb - NULL wipes the column
+	unset := patch.Unset
+	if unset == nil {
+		unset = []string{}
+	}
+	store := database.StoreOf(ctx)
+	args := []any{setJSON, r.dialect.Array(unset), id, store}
+
+	if r.dialect != database.Postgres {
+		// A JSON merge patch, where null removes a key
+		merge := map[string]any{}
+		for k, v := range patch.Set {
+			merge[k] = v
+		}
+		for _, k := range patch.Unset {
+			merge[k] = nil
+		}
+		mergeJSON, err := json.Marshal(merge)
+		if err != nil {
+			return settings, fmt.Errorf("failed to marshal settings: %w", err)
+		}
+		mergePatch := "json_patch"
+		if r.dialect == database.MySQL {
+			mergePatch = "JSON_MERGE_PATCH"
+		}
+		query = `UPDATE users SET setting = ` + mergePatch + `(COALESCE(setting, '{}'), $1), revision = revision + 1, updated_at = NOW() WHERE id = $2 AND store_id = $3`
+		args = []any{string(mergeJSON), id, store}
 	}
 
-	rows, err := result.RowsAffected()
+	var settingJSON []byte
+	err = r.dialect.UpdateReturning(ctx, r.db, query, "setting", args, &settingJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		return settings, ErrUserNotFound
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return settings, fmt.Errorf("failed to update settings: %w", err)
 	}
 
-	if rows == 0 {
-		return ErrUserNotFound
+	if err := json.Unmarshal(settingJSON, &settings); err != nil {
+		return settings, fmt.Errorf("failed to unmarshal settings: %w", err)
 	}
 
-	return nil
+	return settings, nil
 }
 
 func (r *userRepository) SetActive(ctx context.Context, id int, active bool) error {
-	query := `UPDATE users SET active = $1 WHERE id = $2`
+	// Deactivating also invalidates tokens already issued to the account
+	query := `
+		UPDATE users
+		SET active = $1, token_version = token_version + CASE WHEN $1 THEN 0 ELSE 1 END, revision = revision + 1, updated_at = NOW()
+		WHERE id = $2 AND store_id = $3 AND deleted_at IS NULL
+	`
 
-	result, err := r.db.ExecContext(ctx, query, active, id)
+	ok, err := database.ExecAffected(ctx, r.db, query, active, id, database.StoreOf(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to set active status: %w", err)
 	}
-
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	if rows == 0 {
+	if !ok {
 		return ErrUserNotFound
 	}
 
@@ -345,70 +758,24 @@ func (r *userRepository) SetActive(ctx context.Context, id int, active bool) err
 
 func (r *userRepository) GetByRole(ctx context.Context, role string) ([]*User, error) {
 	query := `
-		SELECT id, username, display_name, hash, role, active, setting, custom
+		SELECT ` + userColumns + `
 		FROM users
-		WHERE role = $1
+		WHERE role = $1 AND store_id = $2 AND deleted_at IS NULL
 		ORDER BY username
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, role)
+	users, err := database.Select(ctx, r.db, r.scanUser, query, role, database.StoreOf(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get users by role: %w", err)
 	}
-	defer rows.Close()
-
-	var users []*User
-	for rows.Next() {
-		user, err := r.scanUser(rows)
-		if err != nil {
-			return nil, err
-		}
-		users = append(users, user)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
-	return users, nil
-}
-
-func (r *userRepository) Search(ctx context.Context, query string) ([]*User, error) {
-	searchQuery := `
-		SELECT id, username, display_name, hash, role, active, setting, custom
-		FROM users
-		WHERE username ILIKE $1 OR display_name ILIKE $1
-		ORDER BY username
-	`
-
-	searchPattern := "%" + query + "%"
-	rows, err := r.db.QueryContext(ctx, searchQuery, searchPattern)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search users: %w", err)
-	}
-	defer rows.Close()
-
-	var users []*User
-	for rows.Next() {
-		user, err := r.scanUser(rows)
-		if err != nil {
-			return nil, err
-		}
-		users = append(users, user)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-
 	return users, nil
 }
 
 func (r *userRepository) Count(ctx context.Context) (int, error) {
-	query := `SELECT COUNT(*) FROM users`
+	query := `SELECT COUNT(*) FROM users WHERE store_id = $1`
 
 	var count int
-	err := r.db.QueryRowContext(ctx, query).Scan(&count)
+	err := r.db.QueryRowContext(ctx, query, database.StoreOf(ctx)).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count users: %w", err)
 	}
@@ -418,43 +785,798 @@ func (r *userRepository) Count(ctx context.Context) (int, error) {
 
 // Helper methods
 
-func (r *userRepository) scanUser(scanner interface {
-	Scan(dest ...any) error
-}) (*User, error) {
-	user := &User{}
-	var settingJSON, customJSON []byte
+// SetPinHash stores (or, with an empty hash, removes) the user's PIN.
+func (r *userRepository) SetPinHash(ctx context.Context, id int, hash string) error {
+	query := `UPDATE users SET pin_hash = NULLIF($1, '') WHERE id = $2 AND store_id = $3`
 
-	err := scanner.Scan(
-		&user.Id, &user.Username, &user.DisplayName, &user.Hash,
-		&user.Role, &user.Active, &settingJSON, &customJSON,
-	)
+	ok, err := database.ExecAffected(ctx, r.db, query, hash, id, database.StoreOf(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("failed to scan user: %w", err)
+		return fmt.Errorf("failed to set pin: %w", err)
+	}
+	if !ok {
+		return ErrUserNotFound
 	}
 
-	if err := r.unmarshalUserData(user, settingJSON, customJSON); err != nil {
-		return nil, err
+	return nil
+}
+
+func (r *userRepository) GetPinHash(ctx context.Context, id int) (string, error) {
+	var hash sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT pin_hash FROM users WHERE id = $1 AND store_id = $2`, id, database.StoreOf(ctx)).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", ErrUserNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get pin: %w", err)
 	}
 
-	return user, nil
+	return hash.String, nil
 }
 
-func (r *userRepository) unmarshalUserData(user *User, settingJSON, customJSON []byte) error {
-	if len(settingJSON) > 0 {
-		if err := json.Unmarshal(settingJSON, &user.Setting); err != nil {
-			return fmt.Errorf("failed to unmarshal settings: %w", err)
-		}
+// GetLockedUntil returns when the lockout ends, or the zero time if the
+// account is not locked.
+func (r *userRepository) GetLockedUntil(ctx context.Context, id int) (time.Time, error) {
+	var until sql.NullTime
+	err := r.db.QueryRowContext(ctx, `SELECT locked_until FROM users WHERE id = $1 AND store_id = $2 AND locked_until > NOW()`, id, database.StoreOf(ctx)).Scan(&until)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get lockout: %w", err)
 	}
 
-	if len(customJSON) > 0 {
-		if err := json.Unmarshal(customJSON, &user.Custom); err != nil {
-			return fmt.Errorf("failed to unmarshal custom data: %w", err)
-		}
+	return until.Time, nil
+}
+
+// GetFailedLogins returns the consecutive failures since the last success
+// or lockout.
+func (r *userRepository) GetFailedLogins(ctx context.Context, id int) (int, error) {
+	var failed int
+	err := r.db.QueryRowContext(ctx, `SELECT failed_logins FROM users WHERE id = $1 AND store_id = $2`, id, database.StoreOf(ctx)).Scan(&failed)
+	if err == sql.ErrNoRows {
+		return 0, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get failed logins: %w", err)
 	}
 
-	return nil
+	return failed, nil
 }
 
-func isDuplicateKeyError(_ error) bool {
-	return false
+// RecordFailedLogin counts a failed attempt. Reaching maxAttempts locks the
+// account for cooldown and starts the count over; a maxAttempts of zero
+// only counts. Returns the lockout end,
+// or the zero time if this attempt did not lock the account.
+func (r *userRepository) RecordFailedLogin(ctx context.Context, id int, maxAttempts int, cooldown time.Duration) (time.Time, error) {
+	// locked_until goes first: MySQL assigns left to right, and later
+	// assignments see the new values
+	query := `
+		UPDATE users
+		SET locked_until = CASE WHEN $2 > 0 AND failed_logins + 1 >= $2 THEN $3 ELSE locked_until END,
+		    failed_logins = CASE WHEN $2 > 0 AND failed_logins + 1 >= $2 THEN 0 ELSE failed_logins + 1 END
+		WHERE id = $1 AND store_id = $4
+	`
+	returning := `CASE WHEN locked_until > NOW() THEN locked_until END`
+
+	var until sql.NullTime
+	args := []any{id, maxAttempts, time.Now().Add(cooldown), database.StoreOf(ctx)}
+	err := r.dialect.UpdateReturning(ctx, r.db, query, returning, args, &until)
+	if err == sql.ErrNoRows {
+		return time.Time{}, ErrUserNotFound
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to record failed login: %w", err)
+	}
+
+	return until.Time, nil
+}
+
+// RecordLogin stamps a successful login with the time and client address.
+func (r *userRepository) RecordLogin(ctx context.Context, id int, ip string) error {
+	query := `UPDATE users SET last_login_at = NOW(), last_login_ip = NULLIF($1, '') WHERE id = $2 AND store_id = $3`
+
+	if _, err := r.db.ExecContext(ctx, query, ip, id, database.StoreOf(ctx)); err != nil {
+		return fmt.Errorf("failed to record login: %w", err)
+	}
+
+	return nil
+}
+
+// HasLocations reports whether the store of ctx has any locations set up.
+func (r *userRepository) HasLocations(ctx context.Context) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM locations WHERE store_id = $1)`
+	if err := r.db.QueryRowContext(ctx, query, database.StoreOf(ctx)).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check locations: %w", err)
+	}
+	return exists, nil
+}
+
+// GetLocationIDs returns the locations the user is assigned to.
+func (r *userRepository) GetLocationIDs(ctx context.Context, userId int) ([]int, error) {
+	query := `SELECT location_id FROM user_locations WHERE user_id = $1 ORDER BY location_id`
+
+	rows, err := r.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user locations: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan location: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return ids, nil
+}
+
+// GetLocationSlugs maps the user's locations, or every location when all is
+// set, from id to slug.
+func (r *userRepository) GetLocationSlugs(ctx context.Context, userId int, all bool) (map[int]string, error) {
+	query := `
+		SELECT l.id, l.slug
+		FROM locations l
+		WHERE l.store_id = $3 AND ($2 OR l.id IN (SELECT location_id FROM user_locations WHERE user_id = $1))
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userId, all, database.StoreOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user locations: %w", err)
+	}
+	defer rows.Close()
+
+	slugs := map[int]string{}
+	for rows.Next() {
+		var id int
+		var slug string
+		if err := rows.Scan(&id, &slug); err != nil {
+			return nil, fmt.Errorf("failed to scan location: %w", err)
+		}
+		slugs[id] = slug
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return slugs, nil
+}
+
+// SetLocations replaces the user's assignments in one transaction: rows not
+// in locationIds are removed and new ones added.
+func (r *userRepository) SetLocations(ctx context.Context, userId int, locationIds []int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	store := database.StoreOf(ctx)
+	err = tx.QueryRowContext(ctx, `SELECT TRUE FROM users WHERE id = $1 AND store_id = $2`, userId, store).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if locationIds == nil {
+		locationIds = []int{}
+	}
+	remove := `DELETE FROM user_locations WHERE user_id = $1 AND NOT ` + r.dialect.AnyOf("location_id", "$2")
+	if _, err := tx.ExecContext(ctx, remove, userId, r.dialect.Array(locationIds)); err != nil {
+		return fmt.Errorf("failed to set user locations: %w", err)
+	}
+
+	add := `INSERT INTO user_locations (user_id, location_id) VALUES ($1, $2) ` + r.dialect.OnConflict("user_id, location_id")
+	for _, locationId := range locationIds {
+		ours, err := database.Exists(ctx, tx, "locations", "id = $1 AND store_id = $2", locationId, store)
+		if err != nil {
+			return fmt.Errorf("failed to check location: %w", err)
+		}
+		if !ours {
+			return fmt.Errorf("%w: unknown location", ErrInvalidUserInput)
+		}
+		if _, err := tx.ExecContext(ctx, add, userId, locationId); err != nil {
+			if database.IsForeignKeyViolation(err) {
+				return fmt.Errorf("%w: unknown location", ErrInvalidUserInput)
+			}
+			return fmt.Errorf("failed to set user locations: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// AcceptPolicy records the user agreeing to version. Accepting again keeps
+// the original timestamp.
+func (r *userRepository) AcceptPolicy(ctx context.Context, userId int, version string) (*PolicyAcceptance, error) {
+	query := `INSERT INTO policy_acceptances (user_id, version) VALUES ($1, $2) ` +
+		r.dialect.OnConflict("user_id, version", "accepted_at = policy_acceptances.accepted_at")
+
+	a := &PolicyAcceptance{}
+	var err error
+	if r.dialect == database.MySQL {
+		// No RETURNING, and the table has no id to read it back by
+		_, err = r.db.ExecContext(ctx, query, userId, version)
+		if err == nil {
+			err = r.db.QueryRowContext(ctx, `SELECT version, accepted_at FROM policy_acceptances WHERE user_id = $1 AND version = $2`, userId, version).
+				Scan(&a.Version, &a.AcceptedAt)
+		}
+	} else {
+		err = r.db.QueryRowContext(ctx, query+" RETURNING version, accepted_at", userId, version).Scan(&a.Version, &a.AcceptedAt)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept policy: %w", err)
+	}
+
+	return a, nil
+}
+
+// GetPolicyAcceptance returns the user's most recent acceptance, or nil if
+// they never accepted any version.
+func (r *userRepository) GetPolicyAcceptance(ctx context.Context, userId int) (*PolicyAcceptance, error) {
+	query := `
+		SELECT version, accepted_at FROM policy_acceptances
+		WHERE user_id = $1
+		ORDER BY accepted_at DESC
+		LIMIT 1
+	`
+
+	a := &PolicyAcceptance{}
+	err := r.db.QueryRowContext(ctx, query, userId).Scan(&a.Version, &a.AcceptedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get policy acceptance: %w", err)
+	}
+
+	return a, nil
+}
+
+// RecordLoginAttempt stores a sign-in attempt. Without a UserId the account
+// is looked up by username, so attempts on unknown names stay unattached.
+func (r *userRepository) RecordLoginAttempt(ctx context.Context, a *LoginAttempt) error {
+	query := `
+		INSERT INTO login_attempts (user_id, username, method, outcome, ip, user_agent, store_id)
+		VALUES (COALESCE($1, (SELECT id FROM users WHERE LOWER(username) = LOWER($2) AND store_id = $7)), $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7)
+	`
+	args := []any{a.UserId, a.Username, a.Method, a.Outcome, a.IP, a.UserAgent, database.StoreOf(ctx)}
+
+	var userId sql.NullInt64
+	err := r.dialect.InsertReturning(ctx, r.db, "login_attempts", query, "id, user_id, created_at", args, &a.Id, &userId, &a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record login attempt: %w", err)
+	}
+	if userId.Valid {
+		id := int(userId.Int64)
+		a.UserId = &id
+	}
+
+	return nil
+}
+
+// ListLoginAttempts returns attempts newest first, either on an account
+// (userId) or on a username as typed, which also finds unknown names.
+func (r *userRepository) ListLoginAttempts(ctx context.Context, userId int, username string, limit, offset int) ([]*LoginAttempt, error) {
+	query := `
+		SELECT id, user_id, username, method, outcome, COALESCE(ip, ''), COALESCE(user_agent, ''), created_at
+		FROM login_attempts
+		WHERE (($1 > 0 AND user_id = $1) OR ($1 = 0 AND LOWER(username) = LOWER($2))) AND store_id = $5
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	attempts, err := database.Select(ctx, r.db, scanLoginAttempt, query, userId, username, limit, offset, database.StoreOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list login attempts: %w", err)
+	}
+	if attempts == nil {
+		attempts = []*LoginAttempt{}
+	}
+	return attempts, nil
+}
+
+// LogActivity appends an entry to the audit trail.
+func (r *userRepository) LogActivity(ctx context.Context, a *Activity) error {
+	detailJSON, err := json.Marshal(a.Detail)
+	if err != nil {
+		return fmt.Errorf("failed to marshal activity detail: %w", err)
+	}
+
+	query := `
+		INSERT INTO user_activity (user_id, actor_id, action, ip, detail)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+	`
+	args := []any{a.UserId, a.ActorId, a.Action, a.IP, detailJSON}
+
+	err = r.dialect.InsertReturning(ctx, r.db, "user_activity", query, "id, created_at", args, &a.Id, &a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to log activity: %w", err)
+	}
+
+	return nil
+}
+
+// ListActivity returns a user's audit trail, newest first.
+func (r *userRepository) ListActivity(ctx context.Context, userId, limit, offset int) ([]*Activity, error) {
+	query := `
+		SELECT id, user_id, actor_id, action, COALESCE(ip, ''), detail, created_at
+		FROM user_activity
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	activity, err := database.Select(ctx, r.db, scanActivity, query, userId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activity: %w", err)
+	}
+	if activity == nil {
+		activity = []*Activity{}
+	}
+	return activity, nil
+}
+
+// ClearFailedLogins resets the counter and lifts any lockout.
+func (r *userRepository) ClearFailedLogins(ctx context.Context, id int) error {
+	query := `UPDATE users SET failed_logins = 0, locked_until = NULL WHERE id = $1 AND store_id = $2`
+
+	ok, err := database.ExecAffected(ctx, r.db, query, id, database.StoreOf(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to clear failed logins: %w", err)
+	}
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetTempRole sets (or with an empty role, clears) the user's temporary role.
+func (r *userRepository) SetTempRole(ctx context.Context, id int, role string, expiresAt *time.Time) error {
+	query := `UPDATE users SET temp_role = NULLIF($1, ''), temp_role_expires_at = $2 WHERE id = $3 AND store_id = $4`
+
+	ok, err := database.ExecAffected(ctx, r.db, query, role, expiresAt, id, database.StoreOf(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to set temporary role: %w", err)
+	}
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// ClearExpiredTempRoles removes temporary roles that have run out. They stop
+// counting at expiry regardless; this only tidies the rows.
+func (r *userRepository) ClearExpiredTempRoles(ctx context.Context) (int, error) {
+	query := `
+		UPDATE users SET temp_role = NULL, temp_role_expires_at = NULL
+		WHERE temp_role IS NOT NULL AND temp_role_expires_at <= NOW() AND store_id = $1
+	`
+
+	n, err := database.ExecCount(ctx, r.db, query, database.StoreOf(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear temporary roles: %w", err)
+	}
+	return n, nil
+}
+
+func (r *userRepository) GetTokenVersion(ctx context.Context, id int) (int, bool, error) {
+	var version int
+	var active bool
+	err := r.db.QueryRowContext(ctx, `SELECT token_version, active FROM users WHERE id = $1 AND store_id = $2`, id, database.StoreOf(ctx)).Scan(&version, &active)
+	if err == sql.ErrNoRows {
+		return 0, false, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get token version: %w", err)
+	}
+
+	return version, active, nil
+}
+
+func (r *userRepository) BumpTokenVersion(ctx context.Context, id int) error {
+	query := `UPDATE users SET token_version = token_version + 1 WHERE id = $1 AND store_id = $2`
+
+	ok, err := database.ExecAffected(ctx, r.db, query, id, database.StoreOf(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to bump token version: %w", err)
+	}
+	if !ok {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) RevokeRefreshTokens(ctx context.Context, userId int) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+
+	if _, err := r.db.ExecContext(ctx, query, userId); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	return nil
+}
+
+func (r *userRepository) CreateDevice(ctx context.Context, d *Device, hash string) error {
+	query := `
+		INSERT INTO user_devices (user_id, name, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	args := []any{d.UserId, d.Name, hash, d.ExpiresAt}
+
+	err := r.dialect.InsertReturning(ctx, r.db, "user_devices", query, "id, created_at", args, &d.Id, &d.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create device: %w", err)
+	}
+
+	return nil
+}
+
+// ListDevices returns the user's active (unrevoked, unexpired) devices.
+func (r *userRepository) ListDevices(ctx context.Context, userId int) ([]*Device, error) {
+	query := `
+		SELECT id, user_id, name, created_at, last_used_at, expires_at
+		FROM user_devices
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at
+	`
+
+	devices, err := database.Select(ctx, r.db, scanDevice, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+	if devices == nil {
+		devices = []*Device{}
+	}
+	return devices, nil
+}
+
+// UseDevice validates a device token, slides its expiry forward and returns
+// the owner's ID. Like magic links and refresh tokens, it only works in its
+// owner's store.
+func (r *userRepository) UseDevice(ctx context.Context, hash string, expiresAt time.Time) (int, error) {
+	query := `
+		UPDATE user_devices
+		SET last_used_at = NOW(), expires_at = $2
+		WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		  AND user_id IN (SELECT id FROM users WHERE store_id = $3)
+	`
+
+	var userId int
+	err := r.dialect.UpdateReturning(ctx, r.db, query, "user_id", []any{hash, expiresAt, database.StoreOf(ctx)}, &userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrInvalidDevice
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to use device: %w", err)
+	}
+
+	return userId, nil
+}
+
+func (r *userRepository) CreateMagicLink(ctx context.Context, userId int, hash string, expiresAt time.Time) error {
+	query := `INSERT INTO magic_links (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+
+	if _, err := r.db.ExecContext(ctx, query, userId, hash, expiresAt); err != nil {
+		return fmt.Errorf("failed to create magic link: %w", err)
+	}
+	return nil
+}
+
+// UseMagicLink marks the link used and returns its owner. A link that was
+// already used or has expired returns ErrInvalidMagicLink.
+func (r *userRepository) UseMagicLink(ctx context.Context, hash string) (int, error) {
+	query := `
+		UPDATE magic_links
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		  AND user_id IN (SELECT id FROM users WHERE store_id = $2)
+	`
+
+	var userId int
+	err := r.dialect.UpdateReturning(ctx, r.db, query, "user_id", []any{hash, database.StoreOf(ctx)}, &userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrInvalidMagicLink
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to use magic link: %w", err)
+	}
+
+	return userId, nil
+}
+
+func (r *userRepository) RevokeDevice(ctx context.Context, userId, deviceId int) error {
+	query := `UPDATE user_devices SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	ok, err := database.ExecAffected(ctx, r.db, query, deviceId, userId)
+	if err != nil {
+		return fmt.Errorf("failed to revoke device: %w", err)
+	}
+	if !ok {
+		return ErrDeviceNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) RevokeDevices(ctx context.Context, userId int) error {
+	query := `UPDATE user_devices SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+
+	if _, err := r.db.ExecContext(ctx, query, userId); err != nil {
+		return fmt.Errorf("failed to revoke devices: %w", err)
+	}
+
+	return nil
+}
+
+func (r *userRepository) ClockIn(ctx context.Context, userId int) (*TimeEntry, error) {
+	query := `INSERT INTO time_entries (user_id) VALUES ($1)`
+
+	e := &TimeEntry{}
+	err := r.dialect.InsertReturning(ctx, r.db, "time_entries", query, "id, user_id, clock_in", []any{userId}, &e.Id, &e.UserId, &e.ClockIn)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return nil, ErrAlreadyClockedIn
+		}
+		return nil, fmt.Errorf("failed to clock in: %w", err)
+	}
+
+	return e, nil
+}
+
+func (r *userRepository) ClockOut(ctx context.Context, userId int) (*TimeEntry, error) {
+	query := `
+		UPDATE time_entries SET clock_out = NOW()
+		WHERE user_id = $1 AND clock_out IS NULL
+	`
+
+	e := &TimeEntry{}
+	var out time.Time
+	err := r.dialect.UpdateReturning(ctx, r.db, query, "id, user_id, clock_in, clock_out", []any{userId}, &e.Id, &e.UserId, &e.ClockIn, &out)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotClockedIn
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to clock out: %w", err)
+	}
+	e.ClockOut = &out
+
+	return e, nil
+}
+
+// GetOpenTimeEntry returns the running shift, or nil when clocked out.
+func (r *userRepository) GetOpenTimeEntry(ctx context.Context, userId int) (*TimeEntry, error) {
+	query := `SELECT id, user_id, clock_in FROM time_entries WHERE user_id = $1 AND clock_out IS NULL`
+
+	e := &TimeEntry{}
+	err := r.db.QueryRowContext(ctx, query, userId).Scan(&e.Id, &e.UserId, &e.ClockIn)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time entry: %w", err)
+	}
+
+	return e, nil
+}
+
+// GetHoursReport sums time on the clock per user within [start, end).
+// Running shifts count up to now. userId 0 means everyone.
+func (r *userRepository) GetHoursReport(ctx context.Context, start, end time.Time, userId int) ([]HoursLine, error) {
+	d := r.dialect
+	onClock := d.SecondsBetween(d.Greatest("t.clock_in", "$1"), d.Least("COALESCE(t.clock_out, NOW())", "$2"))
+	query := `
+		SELECT u.id, u.username, COALESCE(u.display_name, ''), COUNT(t.id),
+		       ` + d.CastInt(`COALESCE(SUM(`+onClock+`), 0)`) + `
+		FROM time_entries t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.clock_in < $2 AND COALESCE(t.clock_out, NOW()) > $1
+		  AND ($3 = 0 OR t.user_id = $3) AND u.store_id = $4
+		GROUP BY u.id, u.username, u.display_name
+		ORDER BY u.username
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, start, end, userId, database.StoreOf(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hours report: %w", err)
+	}
+	defer rows.Close()
+
+	lines := []HoursLine{}
+	for rows.Next() {
+		var l HoursLine
+		if err := rows.Scan(&l.UserId, &l.Username, &l.DisplayName, &l.Shifts, &l.Seconds); err != nil {
+			return nil, fmt.Errorf("failed to scan hours line: %w", err)
+		}
+		l.Hours = math.Round(float64(l.Seconds)/36) / 100
+		lines = append(lines, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return lines, nil
+}
+
+func (r *userRepository) CreateRefreshToken(ctx context.Context, userId int, hash string, expiresAt time.Time) error {
+	query := `INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+
+	if _, err := r.db.ExecContext(ctx, query, userId, hash, expiresAt); err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// RotateRefreshToken swaps a valid refresh token for a new one and returns the
+// owner's ID. Presenting a token that was already rotated means it leaked (the
+// legitimate client only holds the newest one), so every token of that user is
+// revoked and they must log in again.
+func (r *userRepository) RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userId int
+	var expires time.Time
+	var revoked sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT user_id, expires_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1 AND user_id IN (SELECT id FROM users WHERE store_id = $2)`+r.dialect.ForUpdate(), oldHash, database.StoreOf(ctx)).Scan(&userId, &expires, &revoked)
+	if err == sql.ErrNoRows {
+		return 0, ErrInvalidRefresh
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	if revoked.Valid {
+		_, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userId)
+		if err != nil {
+			return 0, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return 0, ErrInvalidRefresh
+	}
+
+	if time.Now().After(expires) {
+		return 0, ErrInvalidRefresh
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = NOW() WHERE token_hash = $1`, oldHash); err != nil {
+		return 0, fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		userId, newHash, expiresAt,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return userId, nil
+}
+
+func (r *userRepository) scanUser(scanner database.Scanner) (*User, error) {
+	user := &User{}
+	var settingJSON, customJSON []byte
+	var lastLogin sql.NullTime
+
+	err := scanner.Scan(
+		&user.Id, &user.Username, &user.DisplayName, &user.Hash,
+		&user.Role, &user.Active, &settingJSON, &customJSON,
+		&lastLogin, &user.LastLoginIP, &user.AvatarURL, &user.Email, &user.CreatedAt, &user.UpdatedAt,
+		&user.MustChangePassword, &user.TempRole, &user.TempRoleExpiresAt, &user.Revision, &user.DeletedAt, &user.StoreId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan user: %w", err)
+	}
+
+	if lastLogin.Valid {
+		user.LastLoginAt = &lastLogin.Time
+	}
+
+	if err := r.unmarshalUserData(user, settingJSON, customJSON); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) unmarshalUserData(user *User, settingJSON, customJSON []byte) error {
+	if len(settingJSON) > 0 {
+		if err := json.Unmarshal(settingJSON, &user.Setting); err != nil {
+			return fmt.Errorf("failed to unmarshal settings: %w", err)
+		}
+	}
+
+	if len(customJSON) > 0 {
+		if err := database.UnmarshalCustom("user", customJSON, &user.Custom); err != nil {
+			return fmt.Errorf("failed to unmarshal custom data: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// duplicateError maps a unique violation on users to the matching error.
+func duplicateError(err error) error {
+	if database.Constraint(err) == "users_email_key" {
+		return ErrDuplicateEmail
+	}
+	return ErrDuplicateUsername
+}
+
+func scanLoginAttempt(scanner database.Scanner) (*LoginAttempt, error) {
+	a := &LoginAttempt{}
+	var uid sql.NullInt64
+	if err := scanner.Scan(&a.Id, &uid, &a.Username, &a.Method, &a.Outcome, &a.IP, &a.UserAgent, &a.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan login attempt: %w", err)
+	}
+	if uid.Valid {
+		id := int(uid.Int64)
+		a.UserId = &id
+	}
+	return a, nil
+}
+
+func scanActivity(scanner database.Scanner) (*Activity, error) {
+	a := &Activity{}
+	var actorId sql.NullInt64
+	var detailJSON []byte
+
+	if err := scanner.Scan(&a.Id, &a.UserId, &actorId, &a.Action, &a.IP, &detailJSON, &a.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan activity: %w", err)
+	}
+	if actorId.Valid {
+		id := int(actorId.Int64)
+		a.ActorId = &id
+	}
+	if len(detailJSON) > 0 {
+		if err := json.Unmarshal(detailJSON, &a.Detail); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal activity detail: %w", err)
+		}
+	}
+	return a, nil
+}
+
+func scanDevice(scanner database.Scanner) (*Device, error) {
+	d := &Device{}
+	var lastUsed sql.NullTime
+	if err := scanner.Scan(&d.Id, &d.UserId, &d.Name, &d.CreatedAt, &lastUsed, &d.ExpiresAt); err != nil {
+		return nil, fmt.Errorf("failed to scan device: %w", err)
+	}
+	if lastUsed.Valid {
+		d.LastUsedAt = &lastUsed.Time
+	}
+	return d, nil
 }
@@ -1,29 +1,114 @@
 package user
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/audit"
+	"github.com/iteranya/practicing-go/internal/captcha"
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/storage"
+	"github.com/iteranya/practicing-go/internal/utils"
 )
 
 var (
-	ErrPasswordTooShort = errors.New("password must be at least 6 characters")
+	ErrPasswordTooShort       = errors.New("password must be at least 6 characters")
+	ErrWrongPassword          = errors.New("current password is incorrect")
+	ErrPasswordReused         = errors.New("new password must differ from the current one")
+	ErrPasswordChangeRequired = errors.New("password must be changed before continuing")
+	ErrInvalidImage           = errors.New("avatar must be a png, jpeg, gif or webp image")
+	ErrCaptchaRequired        = errors.New("captcha required")
+	ErrInactiveUser           = errors.New("user account is inactive")
 )
 
+// defaultRole is given to users created without an explicit role.
+const defaultRole = "staff"
+
 type UserService interface {
 	// Authentication
 	RegisterUser(ctx context.Context, input UserInput) (*User, error)
-	Login(ctx context.Context, username, password string) (string, *User, error)
+	Login(ctx context.Context, username, password, captchaToken string) (*TokenPair, *User, error)
+	PINLogin(ctx context.Context, username, pin string) (*TokenPair, *User, error)
+	ExternalLogin(ctx context.Context, ident ExternalIdentity, provisionRole string) (*TokenPair, *User, error)
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, error)
+	Logout(ctx context.Context, userId int) error
+	ValidateSession(ctx context.Context, claims *Claims) error
+	IssueAPIToken(ctx context.Context, id int) (*TokenPair, error)
+
+	// Trusted devices
+	RegisterDevice(ctx context.Context, userId int, name string) (*Device, string, error)
+	DeviceLogin(ctx context.Context, deviceToken string) (*TokenPair, *User, error)
+	RequestMagicLink(ctx context.Context, email string) error
+	MagicLinkLogin(ctx context.Context, token string) (*TokenPair, *User, error)
+	ListDevices(ctx context.Context, userId int) ([]*Device, error)
+	RevokeDevice(ctx context.Context, userId, deviceId int) error
+	Unlock(ctx context.Context, id int) error
 
 	// User Management
 	GetUser(ctx context.Context, idOrUsername any) (*User, error)
+	GetUsernameHistory(ctx context.Context, id int) ([]UsernameChange, error)
+	GetProfile(ctx context.Context, id int) (*Profile, error)
 	UpdateUser(ctx context.Context, id int, input UserInput) error
 	DeleteUser(ctx context.Context, id int) error
-	ListUsers(ctx context.Context, params UserServiceListParams) ([]*User, error)
+	RestoreUser(ctx context.Context, id int) error
+	AnonymizeUser(ctx context.Context, id int) error
+	PurgeDeleted(ctx context.Context, before time.Time) (int, error)
+	ListUsers(ctx context.Context, params UserServiceListParams) (*UserPage, error)
+	ExportCSV(ctx context.Context, params UserServiceListParams, w io.Writer) error
 
 	// Specific Actions
 	ChangePassword(ctx context.Context, id int, newPassword string) error
-	UpdateSettings(ctx context.Context, id int, settings map[string]any) error
+	ChangeOwnPassword(ctx context.Context, id int, currentPassword, newPassword string) error
+	SetPIN(ctx context.Context, id int, pin string) error
+	UpdateSettings(ctx context.Context, id int, patch SettingsPatch) (Settings, error)
 	ToggleActive(ctx context.Context, id int, active bool) error
+	SetAvatar(ctx context.Context, id int, image io.Reader) (string, error)
+	RemoveAvatar(ctx context.Context, id int) error
+
+	// Temporary roles
+	GrantTempRole(ctx context.Context, id int, role string, expiresAt time.Time) error
+	RevokeTempRole(ctx context.Context, id int) error
+	ClearExpiredTempRoles(ctx context.Context) (int, error)
+
+	// Timekeeping
+	ClockIn(ctx context.Context, userId int) (*TimeEntry, error)
+	ClockOut(ctx context.Context, userId int) (*TimeEntry, error)
+	ClockStatus(ctx context.Context, userId int) (*TimeEntry, error)
+	GetHoursReport(ctx context.Context, start, end time.Time, userId int) ([]HoursLine, error)
+
+	// Audit
+	ListActivity(ctx context.Context, id int, params ActivityListParams) ([]*Activity, error)
+	ListLoginAttempts(ctx context.Context, params LoginAttemptListParams) ([]*LoginAttempt, error)
+
+	// Locations
+	GetLocations(ctx context.Context, id int) ([]int, error)
+	SetLocations(ctx context.Context, id int, locationIds []int) error
+	GetLocationScope(ctx context.Context, id int) (utils.LocationScope, error)
+	GetSubject(ctx context.Context, id int) (utils.Subject, error)
+
+	// Staff policy
+	AcceptPolicy(ctx context.Context, userId int, version string) (*PolicyStatus, error)
+	CheckPolicyAccepted(ctx context.Context, userId int) error
+}
+
+type ActivityListParams struct {
+	Limit int
+	Page  int
+}
+
+type LoginAttemptListParams struct {
+	UserId   int
+	Username string // Used when UserId is zero, matches attempts on unknown names too
+	Limit    int
+	Page     int
 }
 
 // UserInput separates the API request shape from the Database Model
@@ -31,25 +116,92 @@ type UserInput struct {
 	Username    string         `json:"username"`
 	Password    string         `json:"password"` // Raw password, only used on Create
 	DisplayName string         `json:"display_name"`
+	Email       string         `json:"email"`
 	Role        string         `json:"role"`
-	Setting     map[string]any `json:"setting"`
+	Setting     *Settings      `json:"setting"` // Replaces all settings, PATCH /users/{id}/settings updates single keys
 	Custom      map[string]any `json:"custom"`
+
+	// Revision is the one the caller read, required on update. If the user
+	// changed since, the update fails with ErrUserConflict.
+	Revision int `json:"revision"`
 }
 
 type UserServiceListParams struct {
-	Role   string
-	Query  string // Username or Display Name search
-	Active *bool
-	Limit  int
-	Page   int
+	Deleted   bool // List the trash instead of live accounts
+	Role      string
+	Query     string // Username or Display Name search
+	Active    *bool
+	Period    database.Period
+	Limit     int
+	Page      int
+	After     string // Cursor from the page before, in place of Page
+	SortBy    string // username (default), display_name, id, created_at, updated_at, last_login_at
+	SortOrder string // asc (default), desc
+}
+
+// UserPage is one page of a user listing. Total counts every match, not
+// just the ones on this page.
+type UserPage struct {
+	Users []*User
+	Total int
+	Page  int
+	Limit int
+}
+
+// LockoutPolicy controls brute-force protection on login. After MaxAttempts
+// consecutive failures the account is locked for Cooldown. A MaxAttempts of
+// zero disables lockout.
+//
+// With a Captcha verifier set, password logins for an account with
+// CaptchaAfter or more consecutive failures must also carry a solved
+// challenge. Pick CaptchaAfter below MaxAttempts so people see the challenge
+// before they get locked out.
+type LockoutPolicy struct {
+	MaxAttempts  int
+	Cooldown     time.Duration
+	Captcha      captcha.Verifier
+	CaptchaAfter int
+}
+
+// countsFailures reports whether failed logins need to be tracked at all.
+func (p LockoutPolicy) countsFailures() bool {
+	return p.MaxAttempts > 0 || p.Captcha != nil
+}
+
+// PolicySource provides the role slug -> permissions map. It is implemented
+// by the role service.
+type PolicySource interface {
+	GetPolicyMap(ctx context.Context) (map[string][]string, error)
+}
+
+// Profile is the authenticated user's own view of their account, with the
+// role's permissions already resolved (wildcards expanded).
+type Profile struct {
+	Id          int           `json:"id"`
+	Username    string        `json:"username"`
+	DisplayName string        `json:"display_name"`
+	Role        string        `json:"role"`
+	AvatarURL   string        `json:"avatar_url,omitempty"`
+	Permissions []string      `json:"permissions"`
+	Setting     Settings      `json:"setting"`
+	Policy      *PolicyStatus `json:"policy,omitempty"` // Nil when no staff policy is configured
 }
 
 type userService struct {
-	repo UserRepository
+	repo     UserRepository
+	lockout  LockoutPolicy
+	policies PolicySource
+	files    storage.Storage
+	auth     Authenticator
 }
 
-func NewUserService(repo UserRepository) UserService {
-	return &userService{repo: repo}
+// NewUserService wires the user service. auth verifies passwords on login;
+// nil means the local password hashes (NewLocalAuthenticator).
+func NewUserService(repo UserRepository, lockout LockoutPolicy, policies PolicySource, files storage.Storage, auth Authenticator) UserService {
+	if auth == nil {
+		auth = NewLocalAuthenticator(repo)
+	}
+	return &userService{repo: repo, lockout: lockout, policies: policies, files: files, auth: auth}
 }
 
 // RegisterUser handles creation and hashing of the password
@@ -63,17 +215,33 @@ func (s *userService) RegisterUser(ctx context.Context, input UserInput) (*User,
 	}
 
 	if input.Role == "" {
-		input.Role = "staff"
+		input.Role = defaultRole
+	}
+
+	if err := s.checkUsernameFree(ctx, input.Username, 0); err != nil {
+		return nil, err
+	}
+
+	var settings Settings
+	if input.Setting != nil {
+		if err := input.Setting.Validate(); err != nil {
+			return nil, err
+		}
+		settings = *input.Setting
 	}
 
 	// Create the domain entity
 	newUser := &User{
 		Username:    input.Username,
 		DisplayName: input.DisplayName,
+		Email:       strings.ToLower(strings.TrimSpace(input.Email)),
 		Role:        input.Role,
 		Active:      true, // Active by default on register
-		Setting:     input.Setting,
+		Setting:     settings,
 		Custom:      input.Custom,
+
+		// The admin chose this password, so the user replaces it first thing
+		MustChangePassword: true,
 	}
 
 	// Use domain logic from auth.go to hash password
@@ -86,35 +254,533 @@ func (s *userService) RegisterUser(ctx context.Context, input UserInput) (*User,
 		return nil, err
 	}
 
+	audit.Record(ctx, audit.User, newUser.Id, audit.Create, audit.Diff(nil, newUser))
 	return newUser, nil
 }
 
-// Login verifies credentials and returns a JWT token + User Info
-func (s *userService) Login(ctx context.Context, username, password string) (string, *User, error) {
+// Login verifies credentials and returns an access/refresh token pair + User Info
+func (s *userService) Login(ctx context.Context, username, password, captchaToken string) (pair *TokenPair, u *User, err error) {
+	defer func() { s.recordLoginAttempt(ctx, LoginMethodPassword, username, u, err) }()
+
+	// Directory users may sign in before they have a local account
+	existing, err := s.repo.GetByUsername(ctx, username)
+	if errors.Is(err, ErrUserNotFound) {
+		if err := s.provisionFromBackend(ctx, username, password); err != nil {
+			return nil, nil, err
+		}
+	} else if err == nil {
+		if err := s.checkCaptcha(ctx, existing.Id, captchaToken); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	u, err = s.authenticate(ctx, username, func(u *User) error {
+		_, err := s.auth.Authenticate(ctx, u.Username, password)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	s.logActivity(ctx, u.Id, ActivityLogin, map[string]any{"method": "password"})
+
+	pair, err = s.issueTokens(ctx, u)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, u, nil
+}
+
+// checkCaptcha demands a solved challenge once the account has had
+// CaptchaAfter failed logins in a row. It runs before the password check so
+// guessing can't continue without solving one each time.
+func (s *userService) checkCaptcha(ctx context.Context, id int, token string) error {
+	if s.lockout.Captcha == nil {
+		return nil
+	}
+
+	failed, err := s.repo.GetFailedLogins(ctx, id)
+	if err != nil {
+		return err
+	}
+	if failed < s.lockout.CaptchaAfter {
+		return nil
+	}
+
+	if token == "" {
+		return ErrCaptchaRequired
+	}
+	ip, _ := ctx.Value(utils.ClientIPKey).(string)
+	if err := s.lockout.Captcha.Verify(ctx, token, ip); err != nil {
+		if errors.Is(err, captcha.ErrInvalidToken) {
+			return ErrCaptchaRequired
+		}
+		return err
+	}
+	return nil
+}
+
+// IssueAPIToken mints a long-lived token for an integration acting as this
+// user. Like any access token it dies with a logout or password change.
+func (s *userService) IssueAPIToken(ctx context.Context, id int) (*TokenPair, error) {
+	u, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !u.Active {
+		return nil, ErrInactiveUser
+	}
+
+	if u.Version, _, err = s.repo.GetTokenVersion(ctx, u.Id); err != nil {
+		return nil, err
+	}
+
+	access, err := GenerateAPIToken(u)
+	if err != nil {
+		return nil, err
+	}
+	s.logActivity(ctx, id, ActivityAPIToken, nil)
+
+	return &TokenPair{AccessToken: access, ExpiresIn: int(tokens.APITTL.Seconds())}, nil
+}
+
+// provisionFromBackend creates the local account for a username the
+// authenticator vouches for but we haven't seen yet. It runs before the lockout
+// bookkeeping in authenticate, which needs a local row.
+func (s *userService) provisionFromBackend(ctx context.Context, username, password string) error {
+	ident, err := s.auth.Authenticate(ctx, username, password)
+	if err != nil {
+		return ErrInvalidCredentials
+	}
+
+	u := &User{
+		Username:    username,
+		DisplayName: ident.Name,
+		Email:       ident.Email,
+		Role:        defaultRole,
+		Active:      true,
+	}
+	if err := s.setRandomPassword(u); err != nil {
+		return err
+	}
+
+	err = s.repo.Create(ctx, u)
+	if errors.Is(err, ErrDuplicateUsername) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	audit.Record(ctx, audit.User, u.Id, audit.Create, audit.Diff(nil, u))
+	return nil
+}
+
+// ExternalIdentity is a user vouched for by a single sign-on provider.
+type ExternalIdentity struct {
+	Provider string
+	Email    string
+	Name     string
+}
+
+// ExternalLogin signs in the local user whose email matches the identity,
+// creating one with provisionRole on first sign-in. Lockout and deactivation
+// still apply.
+func (s *userService) ExternalLogin(ctx context.Context, ident ExternalIdentity, provisionRole string) (pair *TokenPair, u *User, err error) {
+	defer func() { s.recordLoginAttempt(ctx, LoginMethodOIDC, ident.Email, u, err) }()
+
+	existing, err := s.repo.GetByEmail(ctx, ident.Email)
+	if errors.Is(err, ErrUserNotFound) {
+		existing, err = s.provisionExternal(ctx, ident, provisionRole)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u, err = s.authenticate(ctx, existing.Username, func(*User) error { return nil })
+	if err != nil {
+		return nil, nil, err
+	}
+	s.logActivity(ctx, u.Id, ActivityLogin, map[string]any{"method": "oidc", "provider": ident.Provider})
+
+	pair, err = s.issueTokens(ctx, u)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, u, nil
+}
+
+// provisionExternal creates a local account for a first-time SSO user. The
+// username comes from the email's local part, suffixed if already taken, and
+// the password is random: they sign in through the provider.
+func (s *userService) provisionExternal(ctx context.Context, ident ExternalIdentity, role string) (*User, error) {
+	u := &User{
+		DisplayName: ident.Name,
+		Email:       ident.Email,
+		Role:        role,
+		Active:      true,
+	}
+	if err := s.setRandomPassword(u); err != nil {
+		return nil, err
+	}
+
+	var err error
+	base, _, _ := strings.Cut(ident.Email, "@")
+	for i := 1; i <= 5; i++ {
+		u.Username = base
+		if i > 1 {
+			u.Username = fmt.Sprintf("%s%d", base, i)
+		}
+
+		err = s.repo.Create(ctx, u)
+		if !errors.Is(err, ErrDuplicateUsername) {
+			break
+		}
+	}
+	if errors.Is(err, ErrDuplicateUsername) {
+		u.Username = ident.Email
+		err = s.repo.Create(ctx, u)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	audit.Record(ctx, audit.User, u.Id, audit.Create, audit.Diff(nil, u))
+	return u, nil
+}
+
+// setRandomPassword gives externally authenticated accounts an unguessable
+// local password, so they can only sign in through their provider.
+func (s *userService) setRandomPassword(u *User) error {
+	raw, _, err := NewRefreshToken()
+	if err != nil {
+		return err
+	}
+	return u.SetPassword(raw)
+}
+
+// issueTokens creates a full-access token pair for an authenticated user.
+func (s *userService) issueTokens(ctx context.Context, u *User) (*TokenPair, error) {
+	access, err := GenerateToken(u)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, hash, err := NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateRefreshToken(ctx, u.Id, hash, time.Now().Add(tokens.RefreshTTL)); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    int(tokens.AccessTTL.Seconds()),
+	}, nil
+}
+
+// PINLogin verifies a user's PIN and returns a register-only access token
+// (see ScopePIN). No refresh token is issued; clerks simply switch again.
+func (s *userService) PINLogin(ctx context.Context, username, pin string) (pair *TokenPair, u *User, err error) {
+	defer func() { s.recordLoginAttempt(ctx, LoginMethodPIN, username, u, err) }()
+
+	u, err = s.authenticate(ctx, username, func(u *User) error {
+		hash, err := s.repo.GetPinHash(ctx, u.Id)
+		if err != nil || !CheckPIN(hash, pin) {
+			return ErrInvalidCredentials
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	s.logActivity(ctx, u.Id, ActivityLogin, map[string]any{"method": "pin"})
+
+	access, err := GeneratePINToken(u)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &TokenPair{AccessToken: access, ExpiresIn: int(tokens.PINTTL.Seconds())}, u, nil
+}
+
+// authenticate runs the checks shared by every login method: the user must
+// exist, not be locked out, be active and pass check. A check returning
+// ErrInvalidCredentials counts towards the lockout; other errors (a backend
+// being down) are returned as is. On success the user's token version is loaded.
+func (s *userService) authenticate(ctx context.Context, username string, check func(*User) error) (*User, error) {
 	// 1. Find User
 	u, err := s.repo.GetByUsername(ctx, username)
 	if err != nil {
 		// Mask specific DB errors for security, just say invalid creds
-		return "", nil, ErrInvalidCredentials
+		return nil, ErrInvalidCredentials
+	}
+
+	// 2. Check Lockout (before the secret, so a locked account leaks nothing)
+	if s.lockout.MaxAttempts > 0 {
+		until, err := s.repo.GetLockedUntil(ctx, u.Id)
+		if err != nil {
+			return nil, err
+		}
+		if !until.IsZero() {
+			return nil, &LockedError{Until: until}
+		}
+	}
+
+	// 3. Check Active Status
+	if !u.Active {
+		return nil, ErrInactiveUser
+	}
+
+	// 4. Check Password or PIN
+	if err := check(u); err != nil {
+		if !errors.Is(err, ErrInvalidCredentials) {
+			return nil, err
+		}
+		if s.lockout.countsFailures() {
+			until, err := s.repo.RecordFailedLogin(ctx, u.Id, s.lockout.MaxAttempts, s.lockout.Cooldown)
+			if err != nil {
+				return nil, err
+			}
+			if !until.IsZero() {
+				return nil, &LockedError{Until: until}
+			}
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if s.lockout.countsFailures() {
+		if err := s.repo.ClearFailedLogins(ctx, u.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	ip, _ := ctx.Value(utils.ClientIPKey).(string)
+	if err := s.repo.RecordLogin(ctx, u.Id, ip); err != nil {
+		return nil, err
+	}
+
+	if u.Version, _, err = s.repo.GetTokenVersion(ctx, u.Id); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// SetPIN sets the user's quick-switch PIN; an empty PIN removes it.
+func (s *userService) SetPIN(ctx context.Context, id int, pin string) error {
+	if pin == "" {
+		if err := s.repo.SetPinHash(ctx, id, ""); err != nil {
+			return err
+		}
+		audit.Record(ctx, audit.User, id, ActionPINRemove, nil)
+		return nil
 	}
 
-	// 2. Check Active Status
+	if len(pin) < 4 || len(pin) > 8 {
+		return ErrInvalidPIN
+	}
+	for _, c := range pin {
+		if c < '0' || c > '9' {
+			return ErrInvalidPIN
+		}
+	}
+
+	hash, err := HashPIN(pin)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.SetPinHash(ctx, id, hash); err != nil {
+		return err
+	}
+	audit.Record(ctx, audit.User, id, ActionPINSet, nil)
+	return nil
+}
+
+// Refresh exchanges a refresh token for a new pair. The old refresh token is
+// revoked in the process (rotation), so each one works exactly once.
+func (s *userService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefresh
+	}
+
+	refresh, hash, err := NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := s.repo.RotateRefreshToken(ctx, HashRefreshToken(refreshToken), hash, time.Now().Add(tokens.RefreshTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-read the user so role changes and deactivation take effect
+	u, err := s.repo.GetByID(ctx, userId)
+	if err != nil {
+		return nil, ErrInvalidRefresh
+	}
 	if !u.Active {
-		return "", nil, errors.New("user account is inactive")
+		return nil, ErrInvalidRefresh
+	}
+	if u.Version, _, err = s.repo.GetTokenVersion(ctx, u.Id); err != nil {
+		return nil, err
+	}
+
+	access, err := GenerateToken(u)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    int(tokens.AccessTTL.Seconds()),
+	}, nil
+}
+
+// RegisterDevice marks the caller's current device as trusted and returns its
+// remember-me token, shown only this once.
+func (s *userService) RegisterDevice(ctx context.Context, userId int, name string) (*Device, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", ErrInvalidUserInput
+	}
+
+	raw, hash, err := NewRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	d := &Device{UserId: userId, Name: name, ExpiresAt: time.Now().Add(tokens.DeviceTTL)}
+	if err := s.repo.CreateDevice(ctx, d, hash); err != nil {
+		return nil, "", err
+	}
+
+	audit.Record(ctx, audit.User, userId, ActionDeviceRegister, map[string]any{"device": d.Id, "name": d.Name})
+	return d, raw, nil
+}
+
+// DeviceLogin exchanges a remember-me token for a fresh access token. The
+// device token stays the same; its expiry slides forward on each use.
+func (s *userService) DeviceLogin(ctx context.Context, deviceToken string) (pair *TokenPair, u *User, err error) {
+	var username string
+	defer func() { s.recordLoginAttempt(ctx, LoginMethodDevice, username, u, err) }()
+
+	if deviceToken == "" {
+		return nil, nil, ErrInvalidDevice
+	}
+
+	userId, err := s.repo.UseDevice(ctx, HashRefreshToken(deviceToken), time.Now().Add(tokens.DeviceTTL))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	existing, err := s.repo.GetByID(ctx, userId)
+	if err != nil {
+		return nil, nil, ErrInvalidDevice
+	}
+	username = existing.Username
+
+	u, err = s.authenticate(ctx, existing.Username, func(*User) error { return nil })
+	if err != nil {
+		return nil, nil, err
 	}
+	s.logActivity(ctx, u.Id, ActivityLogin, map[string]any{"method": "device"})
 
-	// 3. Check Password (domain logic)
-	if !u.CheckPassword(password) {
-		return "", nil, ErrInvalidCredentials
+	access, err := GenerateToken(u)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// 4. Generate Token (domain logic)
-	token, err := GenerateToken(u)
+	return &TokenPair{AccessToken: access, ExpiresIn: int(tokens.AccessTTL.Seconds())}, u, nil
+}
+
+func (s *userService) ListDevices(ctx context.Context, userId int) ([]*Device, error) {
+	return s.repo.ListDevices(ctx, userId)
+}
+
+func (s *userService) RevokeDevice(ctx context.Context, userId, deviceId int) error {
+	if err := s.repo.RevokeDevice(ctx, userId, deviceId); err != nil {
+		return err
+	}
+	audit.Record(ctx, audit.User, userId, ActionDeviceRevoke, map[string]any{"device": deviceId})
+	return nil
+}
+
+// Logout invalidates every access token issued to the user so far (by bumping
+// the token version) and revokes their refresh tokens.
+func (s *userService) Logout(ctx context.Context, userId int) error {
+	if err := s.repo.BumpTokenVersion(ctx, userId); err != nil {
+		return err
+	}
+	return s.repo.RevokeRefreshTokens(ctx, userId)
+}
+
+// ValidateSession is the stateful half of authentication: the JWT signature
+// is checked by ValidateToken, this checks the token wasn't revoked since.
+func (s *userService) ValidateSession(ctx context.Context, claims *Claims) error {
+	version, active, err := s.repo.GetTokenVersion(ctx, claims.UserID)
 	if err != nil {
-		return "", nil, err
+		return ErrTokenRevoked
+	}
+	if !active || version != claims.Version {
+		return ErrTokenRevoked
 	}
+	return nil
+}
 
-	return token, u, nil
+// Unlock lets an admin lift a lockout before the cooldown runs out.
+func (s *userService) Unlock(ctx context.Context, id int) error {
+	if err := s.repo.ClearFailedLogins(ctx, id); err != nil {
+		return err
+	}
+	audit.Record(ctx, audit.User, id, ActionUnlock, nil)
+	return nil
+}
+
+// GrantTempRole gives the user another role until expiresAt, after which
+// their regular role applies again without anyone having to undo it.
+func (s *userService) GrantTempRole(ctx context.Context, id int, role string, expiresAt time.Time) error {
+	if role == "" || !expiresAt.After(time.Now()) {
+		return ErrInvalidUserInput
+	}
+
+	policy, err := s.policies.GetPolicyMap(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := policy[role]; !ok {
+		return fmt.Errorf("%w: unknown role %q", ErrInvalidUserInput, role)
+	}
+
+	if err := s.repo.SetTempRole(ctx, id, role, &expiresAt); err != nil {
+		return err
+	}
+	if err := s.repo.BumpTokenVersion(ctx, id); err != nil {
+		return err
+	}
+	s.logActivity(ctx, id, ActivityTempRole, map[string]any{"role": role, "expires_at": expiresAt})
+	audit.Record(ctx, audit.User, id, audit.Update, map[string]any{
+		"temp_role": map[string]any{"to": role}, "temp_role_expires_at": map[string]any{"to": expiresAt},
+	})
+	return nil
+}
+
+// RevokeTempRole ends a temporary role early.
+func (s *userService) RevokeTempRole(ctx context.Context, id int) error {
+	if err := s.repo.SetTempRole(ctx, id, "", nil); err != nil {
+		return err
+	}
+	if err := s.repo.BumpTokenVersion(ctx, id); err != nil {
+		return err
+	}
+	s.logActivity(ctx, id, ActivityTempRole, map[string]any{"revoked": true})
+	audit.Record(ctx, audit.User, id, audit.Update, map[string]any{"temp_role": map[string]any{"to": ""}})
+	return nil
+}
+
+func (s *userService) ClearExpiredTempRoles(ctx context.Context) (int, error) {
+	return s.repo.ClearExpiredTempRoles(ctx)
 }
 
 func (s *userService) GetUser(ctx context.Context, idOrUsername any) (*User, error) {
@@ -122,16 +788,183 @@ func (s *userService) GetUser(ctx context.Context, idOrUsername any) (*User, err
 	case int:
 		return s.repo.GetByID(ctx, v)
 	case string:
-		return s.repo.GetByUsername(ctx, v)
+		// Fall back to former usernames so old receipts still resolve
+		u, err := s.repo.GetByUsername(ctx, v)
+		if errors.Is(err, ErrUserNotFound) {
+			return s.repo.GetByFormerUsername(ctx, v)
+		}
+		return u, err
 	default:
 		return nil, ErrInvalidUserInput
 	}
 }
 
+// GetUsernameHistory lists the names the user went by before, newest first.
+func (s *userService) GetUsernameHistory(ctx context.Context, id int) ([]UsernameChange, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetUsernameHistory(ctx, id)
+}
+
+// GetProfile returns the user together with their effective permissions.
+func (s *userService) GetProfile(ctx context.Context, id int) (*Profile, error) {
+	u, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := s.GetSubject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	perms := []string{}
+	for _, p := range utils.GetAllPermissions() {
+		if subject.Can(p) {
+			perms = append(perms, p)
+		}
+	}
+
+	profile := &Profile{
+		Id:          u.Id,
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		Role:        u.Role,
+		AvatarURL:   u.AvatarURL,
+		Permissions: perms,
+		Setting:     u.Setting,
+	}
+
+	if policyVersion != "" {
+		latest, err := s.repo.GetPolicyAcceptance(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		profile.Policy = newPolicyStatus(latest)
+	}
+
+	return profile, nil
+}
+
+func (s *userService) GetLocations(ctx context.Context, id int) ([]int, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetLocationIDs(ctx, id)
+}
+
+// SetLocations replaces the stores the user is assigned to.
+func (s *userService) SetLocations(ctx context.Context, id int, locationIds []int) error {
+	if id == 0 {
+		return ErrInvalidUserInput
+	}
+	before, err := s.repo.GetLocationIDs(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.SetLocations(ctx, id, locationIds); err != nil {
+		return err
+	}
+	audit.Record(ctx, audit.User, id, audit.Update, map[string]any{"locations": map[string]any{"from": before, "to": locationIds}})
+	return nil
+}
+
+// GetLocationScope resolves which stores the user may work with: all of
+// them with location:all, otherwise only their assigned ones. A store with
+// no locations set up isn't split into any, so everyone works with all of it.
+func (s *userService) GetLocationScope(ctx context.Context, id int) (utils.LocationScope, error) {
+	u, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return utils.LocationScope{}, err
+	}
+
+	policy, err := s.policies.GetPolicyMap(ctx)
+	if err != nil {
+		return utils.LocationScope{}, err
+	}
+	if u.Can(utils.PermLocationAll, policy) {
+		return utils.LocationScope{All: true}, nil
+	}
+	located, err := s.repo.HasLocations(ctx)
+	if err != nil {
+		return utils.LocationScope{}, err
+	}
+	if !located {
+		return utils.LocationScope{All: true}, nil
+	}
+
+	ids, err := s.repo.GetLocationIDs(ctx, id)
+	if err != nil {
+		return utils.LocationScope{}, err
+	}
+	return utils.LocationScope{IDs: ids}, nil
+}
+
+// GetSubject resolves what the user may do: their role's permissions and,
+// when some of those are scoped to a location, the locations they work at
+// (every one with location:all).
+func (s *userService) GetSubject(ctx context.Context, id int) (utils.Subject, error) {
+	u, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return utils.Subject{}, err
+	}
+
+	policy, err := s.policies.GetPolicyMap(ctx)
+	if err != nil {
+		return utils.Subject{}, err
+	}
+
+	subject := utils.Subject{UserID: u.Id, Permissions: u.Permissions(policy)}
+	if slices.ContainsFunc(subject.Permissions, func(p string) bool { return strings.Contains(p, utils.ScopeSeparator) }) {
+		all := utils.HasPermission(subject.Permissions, utils.PermLocationAll)
+		if subject.Locations, err = s.repo.GetLocationSlugs(ctx, id, all); err != nil {
+			return utils.Subject{}, err
+		}
+	}
+	return subject, nil
+}
+
+// AcceptPolicy records the user agreeing to the current staff policy. The
+// version must match so nobody accepts a text they weren't shown.
+func (s *userService) AcceptPolicy(ctx context.Context, userId int, version string) (*PolicyStatus, error) {
+	if policyVersion == "" || version != policyVersion {
+		return nil, ErrPolicyOutdated
+	}
+
+	accepted, err := s.repo.AcceptPolicy(ctx, userId, version)
+	if err != nil {
+		return nil, err
+	}
+	audit.Record(ctx, audit.User, userId, ActionPolicyAccept, map[string]any{"version": version})
+
+	return newPolicyStatus(accepted), nil
+}
+
+// CheckPolicyAccepted returns ErrPolicyNotAccepted until the user has
+// accepted the current staff policy.
+func (s *userService) CheckPolicyAccepted(ctx context.Context, userId int) error {
+	if policyVersion == "" {
+		return nil
+	}
+
+	latest, err := s.repo.GetPolicyAcceptance(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if latest == nil || latest.Version != policyVersion {
+		return ErrPolicyNotAccepted
+	}
+	return nil
+}
+
 func (s *userService) UpdateUser(ctx context.Context, id int, input UserInput) error {
 	if id == 0 {
 		return ErrInvalidUserInput
 	}
+	if input.Revision == 0 {
+		return fmt.Errorf("%w: revision is required", ErrInvalidUserInput)
+	}
 
 	// Fetch existing to preserve fields like Hash, Active if not provided
 	existing, err := s.repo.GetByID(ctx, id)
@@ -140,52 +973,183 @@ func (s *userService) UpdateUser(ctx context.Context, id int, input UserInput) e
 	}
 
 	// Update fields
-	if input.Username != "" {
+	before := *existing
+	previousUsername := existing.Username
+	if input.Username != "" && input.Username != existing.Username {
+		if err := s.checkUsernameFree(ctx, input.Username, id); err != nil {
+			return err
+		}
 		existing.Username = input.Username
 	}
 	if input.DisplayName != "" {
 		existing.DisplayName = input.DisplayName
 	}
+	if input.Email != "" {
+		existing.Email = strings.ToLower(strings.TrimSpace(input.Email))
+	}
+	roleChanged := input.Role != "" && input.Role != existing.Role
+	previousRole := existing.Role
 	if input.Role != "" {
 		existing.Role = input.Role
 	}
 	if input.Setting != nil {
-		existing.Setting = input.Setting
+		if err := input.Setting.Validate(); err != nil {
+			return err
+		}
+		existing.Setting = *input.Setting
 	}
 	if input.Custom != nil {
 		existing.Custom = input.Custom
 	}
 	// Note: We deliberately do NOT update Password here. Use ChangePassword.
+	existing.Revision = input.Revision
+
+	if err := s.repo.Update(ctx, existing); err != nil {
+		return err
+	}
 
-	return s.repo.Update(ctx, existing)
+	if roleChanged {
+		// Tokens carry the role, so make the user pick up the new one
+		if err := s.repo.BumpTokenVersion(ctx, id); err != nil {
+			return err
+		}
+		s.logActivity(ctx, id, ActivityRoleChange, map[string]any{"from": previousRole, "to": existing.Role})
+	}
+	if existing.Username != previousUsername {
+		s.logActivity(ctx, id, ActivityUsernameChange, map[string]any{"from": previousUsername, "to": existing.Username})
+	}
+	audit.Record(ctx, audit.User, id, audit.Update, audit.Diff(&before, existing))
+	return nil
 }
 
+// checkUsernameFree rejects a username another account already has in any
+// letter case. The unique index still catches a concurrent duplicate.
+func (s *userService) checkUsernameFree(ctx context.Context, username string, exceptId int) error {
+	taken, err := s.repo.UsernameTaken(ctx, username, exceptId)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrDuplicateUsername
+	}
+	return nil
+}
+
+// DeleteUser moves the account to the trash and signs it out everywhere;
+// see RestoreUser and AnonymizeUser.
 func (s *userService) DeleteUser(ctx context.Context, id int) error {
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Trash(ctx, id); err != nil {
+		return err
+	}
+
+	s.logActivity(ctx, id, ActivityDelete, nil)
+	audit.Record(ctx, audit.User, id, audit.Delete, nil)
+	if err := s.repo.RevokeDevices(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.RevokeRefreshTokens(ctx, id)
 }
 
-func (s *userService) ListUsers(ctx context.Context, params UserServiceListParams) ([]*User, error) {
-	if params.Query != "" {
-		return s.repo.Search(ctx, params.Query)
+func (s *userService) RestoreUser(ctx context.Context, id int) error {
+	if err := s.repo.Restore(ctx, id); err != nil {
+		return err
+	}
+	s.logActivity(ctx, id, ActivityRestore, nil)
+	audit.Record(ctx, audit.User, id, audit.Restore, nil)
+	return nil
+}
+
+// AnonymizeUser is the permanent delete. The row stays, which orders
+// reference for reporting, but nothing personal is left in it.
+func (s *userService) AnonymizeUser(ctx context.Context, id int) error {
+	u, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Anonymize(ctx, id); err != nil {
+		return err
+	}
+	s.removeFile(ctx, u.AvatarURL)
+
+	s.logActivity(ctx, id, ActivityAnonymize, nil)
+	audit.Record(ctx, audit.User, id, ActionAnonymize, nil)
+	return nil
+}
+
+// PurgeDeleted anonymizes the accounts deleted before the given time and
+// returns how many there were.
+func (s *userService) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	ids, err := s.repo.TrashedBefore(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		err := s.AnonymizeUser(ctx, id)
+		if errors.Is(err, ErrUserNotFound) {
+			continue // anonymized in the meantime
+		}
+		if err != nil {
+			return purged, err
+		}
+		purged++
 	}
+	return purged, nil
+}
 
+func (s *userService) ListUsers(ctx context.Context, params UserServiceListParams) (*UserPage, error) {
 	offset := 0
-	if params.Page > 1 {
+	if params.Page > 1 && params.After == "" {
 		offset = (params.Page - 1) * params.Limit
 	}
 
 	repoOpts := UserListOptions{
-		Role:   params.Role,
-		Active: params.Active,
-		Limit:  params.Limit,
-		Offset: offset,
-		SortBy: "username",
+		Deleted:   params.Deleted,
+		Query:     params.Query,
+		Role:      params.Role,
+		Active:    params.Active,
+		Period:    params.Period,
+		Limit:     params.Limit,
+		Offset:    offset,
+		After:     params.After,
+		SortBy:    params.SortBy,
+		SortOrder: params.SortOrder,
+		Total:     true,
+	}
+
+	users, total, err := s.repo.List(ctx, repoOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserPage{Users: users, Total: total, Page: params.Page, Limit: params.Limit}, nil
+}
+
+// ExportCSV writes every user matching the list filters as CSV.
+// Pagination in params is ignored; an export is always the full result set.
+func (s *userService) ExportCSV(ctx context.Context, params UserServiceListParams, w io.Writer) error {
+	params.Limit = 0
+	params.Page = 1
+	params.After = ""
+
+	page, err := s.ListUsers(ctx, params)
+	if err != nil {
+		return err
 	}
 
-	return s.repo.List(ctx, repoOpts)
+	return writeExportCSV(w, page.Users)
 }
 
+// ChangePassword is the admin reset. Unless admins reset their own, the user
+// has to pick a new password on their next login.
 func (s *userService) ChangePassword(ctx context.Context, id int, newPassword string) error {
+	actor, _ := ctx.Value(utils.UserIDKey).(int)
+	return s.setPassword(ctx, id, newPassword, actor != id)
+}
+
+func (s *userService) setPassword(ctx context.Context, id int, newPassword string, mustChange bool) error {
 	if len(newPassword) < 6 {
 		return ErrPasswordTooShort
 	}
@@ -197,14 +1161,246 @@ func (s *userService) ChangePassword(ctx context.Context, id int, newPassword st
 		return err
 	}
 
-	// Push the new hash to the repository
-	return s.repo.UpdatePassword(ctx, id, tempUser.Hash)
+	// Push the new hash to the repository (this also bumps the token version)
+	if err := s.repo.UpdatePassword(ctx, id, tempUser.Hash, mustChange); err != nil {
+		return err
+	}
+	s.logActivity(ctx, id, ActivityPasswordChange, nil)
+	audit.Record(ctx, audit.User, id, ActionPasswordChange, nil)
+
+	if err := s.repo.RevokeDevices(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.RevokeRefreshTokens(ctx, id)
+}
+
+// ChangeOwnPassword is the self-service variant: the caller must prove they
+// know the current password before it is replaced.
+func (s *userService) ChangeOwnPassword(ctx context.Context, id int, currentPassword, newPassword string) error {
+	u, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !u.CheckPassword(currentPassword) {
+		return ErrWrongPassword
+	}
+	// A forced change has to actually replace the temporary password
+	if u.MustChangePassword && u.CheckPassword(newPassword) {
+		return ErrPasswordReused
+	}
+
+	return s.setPassword(ctx, id, newPassword, false)
 }
 
-func (s *userService) UpdateSettings(ctx context.Context, id int, settings map[string]any) error {
-	return s.repo.UpdateSettings(ctx, id, settings)
+func (s *userService) UpdateSettings(ctx context.Context, id int, patch SettingsPatch) (Settings, error) {
+	before, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return Settings{}, err
+	}
+	settings, err := s.repo.UpdateSettings(ctx, id, patch)
+	if err != nil {
+		return settings, err
+	}
+
+	after := *before
+	after.Setting = settings
+	audit.Record(ctx, audit.User, id, audit.Update, audit.Diff(before, &after))
+	return settings, nil
 }
 
 func (s *userService) ToggleActive(ctx context.Context, id int, active bool) error {
-	return s.repo.SetActive(ctx, id, active)
+	if err := s.repo.SetActive(ctx, id, active); err != nil {
+		return err
+	}
+	audit.Record(ctx, audit.User, id, audit.Update, map[string]any{"active": map[string]any{"to": active}})
+
+	if active {
+		s.logActivity(ctx, id, ActivityReactivate, nil)
+		return nil
+	}
+
+	s.logActivity(ctx, id, ActivityDeactivate, nil)
+	if err := s.repo.RevokeDevices(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.RevokeRefreshTokens(ctx, id)
+}
+
+// avatarTypes maps the accepted image types to the extension they're stored with.
+var avatarTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// SetAvatar stores a new avatar image and returns its URL. The type is
+// sniffed from the content, not trusted from the client.
+func (s *userService) SetAvatar(ctx context.Context, id int, image io.Reader) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(image, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", ErrInvalidImage
+	}
+	head = head[:n]
+
+	ext, ok := avatarTypes[http.DetectContentType(head)]
+	if !ok {
+		return "", ErrInvalidImage
+	}
+
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return "", err
+	}
+
+	// A fresh key per upload so clients and caches never see a stale image
+	key := fmt.Sprintf("avatars/%d-%d%s", id, time.Now().UnixNano(), ext)
+	url, err := s.files.Save(ctx, key, io.MultiReader(bytes.NewReader(head), image))
+	if err != nil {
+		return "", err
+	}
+
+	previous, err := s.repo.SetAvatar(ctx, id, url)
+	if err != nil {
+		s.removeFile(ctx, url)
+		return "", err
+	}
+	s.removeFile(ctx, previous)
+	audit.Record(ctx, audit.User, id, audit.Update, map[string]any{"avatar_url": map[string]any{"from": previous, "to": url}})
+
+	return url, nil
+}
+
+func (s *userService) RemoveAvatar(ctx context.Context, id int) error {
+	previous, err := s.repo.SetAvatar(ctx, id, "")
+	if err != nil {
+		return err
+	}
+	s.removeFile(ctx, previous)
+	audit.Record(ctx, audit.User, id, audit.Update, map[string]any{"avatar_url": map[string]any{"from": previous, "to": ""}})
+	return nil
+}
+
+// removeFile deletes a stored file by URL. It's cleanup, so failures are
+// only logged.
+func (s *userService) removeFile(ctx context.Context, url string) {
+	key := s.files.Key(url)
+	if key == "" {
+		return
+	}
+	if err := s.files.Delete(ctx, key); err != nil {
+		log.Printf("user: failed to remove %s: %v", key, err)
+	}
+}
+
+func (s *userService) ClockIn(ctx context.Context, userId int) (*TimeEntry, error) {
+	return s.repo.ClockIn(ctx, userId)
+}
+
+func (s *userService) ClockOut(ctx context.Context, userId int) (*TimeEntry, error) {
+	return s.repo.ClockOut(ctx, userId)
+}
+
+// ClockStatus returns the running shift, nil if the user is clocked out.
+func (s *userService) ClockStatus(ctx context.Context, userId int) (*TimeEntry, error) {
+	return s.repo.GetOpenTimeEntry(ctx, userId)
+}
+
+func (s *userService) GetHoursReport(ctx context.Context, start, end time.Time, userId int) ([]HoursLine, error) {
+	if !end.After(start) {
+		return nil, ErrInvalidUserInput
+	}
+	return s.repo.GetHoursReport(ctx, start, end, userId)
+}
+
+func (s *userService) ListActivity(ctx context.Context, id int, params ActivityListParams) ([]*Activity, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	offset := 0
+	if params.Page > 1 {
+		offset = (params.Page - 1) * params.Limit
+	}
+
+	return s.repo.ListActivity(ctx, id, params.Limit, offset)
+}
+
+// recordLoginAttempt logs a sign-in attempt with its outcome. Like
+// logActivity it is best-effort and never fails the login.
+func (s *userService) recordLoginAttempt(ctx context.Context, method, username string, u *User, err error) {
+	a := &LoginAttempt{Username: username, Method: method, Outcome: loginOutcome(err)}
+	if u != nil {
+		a.UserId = &u.Id
+		a.Username = u.Username
+	}
+	a.IP, _ = ctx.Value(utils.ClientIPKey).(string)
+	a.UserAgent, _ = ctx.Value(utils.UserAgentKey).(string)
+	if len(a.UserAgent) > maxUserAgentLength {
+		a.UserAgent = a.UserAgent[:maxUserAgentLength]
+	}
+
+	if err := s.repo.RecordLoginAttempt(ctx, a); err != nil {
+		log.Printf("user: failed to record login attempt for %q: %v", username, err)
+	}
+}
+
+const maxUserAgentLength = 512
+
+func loginOutcome(err error) string {
+	var locked *LockedError
+	switch {
+	case err == nil:
+		return LoginSuccess
+	case errors.As(err, &locked):
+		return LoginLocked
+	case errors.Is(err, ErrCaptchaRequired):
+		return LoginCaptchaRequired
+	case errors.Is(err, ErrInactiveUser):
+		return LoginInactive
+	case errors.Is(err, ErrInvalidCredentials), errors.Is(err, ErrInvalidDevice), errors.Is(err, ErrInvalidMagicLink):
+		return LoginInvalidCredentials
+	default:
+		return LoginError
+	}
+}
+
+// ListLoginAttempts returns recent sign-in attempts on an account, or on a
+// username as typed when params.UserId is zero.
+func (s *userService) ListLoginAttempts(ctx context.Context, params LoginAttemptListParams) ([]*LoginAttempt, error) {
+	if params.UserId == 0 && params.Username == "" {
+		return nil, ErrInvalidUserInput
+	}
+	if params.UserId != 0 {
+		if _, err := s.repo.GetByID(ctx, params.UserId); err != nil {
+			return nil, err
+		}
+	}
+
+	offset := 0
+	if params.Page > 1 {
+		offset = (params.Page - 1) * params.Limit
+	}
+
+	return s.repo.ListLoginAttempts(ctx, params.UserId, params.Username, params.Limit, offset)
+}
+
+// logActivity records an audit entry for the target user. The actor and IP
+// come from the request context; on login the actor is the user themselves.
+// Failures are logged rather than returned so auditing never blocks the
+// action itself.
+func (s *userService) logActivity(ctx context.Context, userId int, action string, detail map[string]any) {
+	a := &Activity{UserId: userId, Action: action, Detail: detail}
+
+	if actor, ok := ctx.Value(utils.UserIDKey).(int); ok {
+		a.ActorId = &actor
+	} else if action == ActivityLogin {
+		a.ActorId = &userId
+	}
+	a.IP, _ = ctx.Value(utils.ClientIPKey).(string)
+
+	if err := s.repo.LogActivity(ctx, a); err != nil {
+		log.Printf("user: failed to log %s for user %d: %v", action, userId, err)
+	}
 }
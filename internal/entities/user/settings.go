@@ -0,0 +1,116 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidSetting = errors.New("invalid setting")
+
+// Settings are a user's own preferences. Empty fields fall back to the
+// client's defaults.
+type Settings struct {
+	Locale            string `json:"locale,omitempty"`             // BCP 47 tag, e.g. "en-US"
+	Theme             string `json:"theme,omitempty"`              // light, dark or system
+	DefaultPrinter    string `json:"default_printer,omitempty"`    // Printer name as the client sees it
+	ReceiptPreference string `json:"receipt_preference,omitempty"` // print, email, none or ask
+}
+
+const (
+	SettingLocale            = "locale"
+	SettingTheme             = "theme"
+	SettingDefaultPrinter    = "default_printer"
+	SettingReceiptPreference = "receipt_preference"
+)
+
+var (
+	settingKeys        = []string{SettingLocale, SettingTheme, SettingDefaultPrinter, SettingReceiptPreference}
+	themes             = []string{"light", "dark", "system"}
+	receiptPreferences = []string{"print", "email", "none", "ask"}
+	localePattern      = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z][a-z]{3})?(-([A-Z]{2}|[0-9]{3}))?$`)
+)
+
+const maxPrinterNameLength = 128
+
+// Validate checks every non-empty field.
+func (s Settings) Validate() error {
+	for key, v := range map[string]string{
+		SettingLocale:            s.Locale,
+		SettingTheme:             s.Theme,
+		SettingDefaultPrinter:    s.DefaultPrinter,
+		SettingReceiptPreference: s.ReceiptPreference,
+	} {
+		if v == "" {
+			continue
+		}
+		if err := validateSetting(key, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateSetting(key, value string) error {
+	var ok bool
+	switch key {
+	case SettingLocale:
+		ok = localePattern.MatchString(value)
+	case SettingTheme:
+		ok = slices.Contains(themes, value)
+	case SettingReceiptPreference:
+		ok = slices.Contains(receiptPreferences, value)
+	case SettingDefaultPrinter:
+		ok = len(value) <= maxPrinterNameLength && !strings.ContainsFunc(value, unicode.IsControl)
+	default:
+		return fmt.Errorf("%w: unknown key %q", ErrInvalidSetting, key)
+	}
+
+	if !ok {
+		return fmt.Errorf("%w: bad value for %q", ErrInvalidSetting, key)
+	}
+	return nil
+}
+
+// SettingsPatch is a partial update: keys in Set are overwritten, keys in
+// Unset are cleared, and everything else is left alone.
+type SettingsPatch struct {
+	Set   map[string]string
+	Unset []string
+}
+
+// ParseSettingsPatch validates a PATCH body key by key. A null or empty
+// value clears the key; unknown keys are rejected rather than stored.
+func ParseSettingsPatch(body map[string]json.RawMessage) (SettingsPatch, error) {
+	patch := SettingsPatch{Set: make(map[string]string)}
+	if len(body) == 0 {
+		return patch, fmt.Errorf("%w: no settings given", ErrInvalidSetting)
+	}
+
+	for key, raw := range body {
+		if !slices.Contains(settingKeys, key) {
+			return patch, fmt.Errorf("%w: unknown key %q", ErrInvalidSetting, key)
+		}
+
+		var value *string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return patch, fmt.Errorf("%w: %q must be a string", ErrInvalidSetting, key)
+		}
+		if value == nil || strings.TrimSpace(*value) == "" {
+			patch.Unset = append(patch.Unset, key)
+			continue
+		}
+
+		v := strings.TrimSpace(*value)
+		if err := validateSetting(key, v); err != nil {
+			return patch, err
+		}
+		patch.Set[key] = v
+	}
+
+	return patch, nil
+}
@@ -0,0 +1,137 @@
+package user
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/oidc"
+)
+
+// ssoCookie carries "state.nonce" between the redirect to the provider and
+// the callback, so no server-side session store is needed.
+const (
+	ssoCookie    = "sso_state"
+	ssoCookieTTL = 10 * time.Minute
+)
+
+// SSOHandler runs the OpenID Connect sign-in flow for the configured
+// providers. Accounts are matched by email and provisioned with
+// provisionRole on first sign-in.
+type SSOHandler struct {
+	service       UserService
+	users         *UserHandler // for the shared login response
+	providers     map[string]*oidc.Provider
+	provisionRole string
+}
+
+func NewSSOHandler(service UserService, provisionRole string, providers ...*oidc.Provider) *SSOHandler {
+	h := &SSOHandler{
+		service:       service,
+		users:         NewUserHandler(service),
+		providers:     make(map[string]*oidc.Provider, len(providers)),
+		provisionRole: provisionRole,
+	}
+	for _, p := range providers {
+		h.providers[p.Name()] = p
+	}
+	return h
+}
+
+// RegisterRoutes mounts the public SSO endpoints under prefix (e.g. "/api/v1").
+func (h *SSOHandler) RegisterRoutes(mux *http.ServeMux, prefix string) {
+	mux.HandleFunc("GET "+prefix+"/auth/oidc/{provider}/login", h.HandleLogin)
+	mux.HandleFunc("GET "+prefix+"/auth/oidc/{provider}/callback", h.HandleCallback)
+}
+
+// LOGIN (redirects the browser to the provider)
+func (h *SSOHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.providers[r.PathValue("provider")]
+	if !ok {
+		http.Error(w, "Unknown provider", http.StatusNotFound)
+		return
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	authURL, err := provider.AuthURL(r.Context(), state, nonce)
+	if err != nil {
+		log.Printf("sso: %v", err)
+		http.Error(w, "Identity provider unavailable", http.StatusBadGateway)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoCookie,
+		Value:    state + "." + nonce,
+		Path:     strings.TrimSuffix(r.URL.Path, "/login"),
+		MaxAge:   int(ssoCookieTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// CALLBACK (provider redirects back here with the code)
+func (h *SSOHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("provider")
+	provider, ok := h.providers[name]
+	if !ok {
+		http.Error(w, "Unknown provider", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	if e := query.Get("error"); e != "" {
+		http.Error(w, "Sign-in was cancelled or refused: "+e, http.StatusUnauthorized)
+		return
+	}
+
+	cookie, err := r.Cookie(ssoCookie)
+	if err != nil {
+		http.Error(w, "Sign-in session expired, try again", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: ssoCookie, Path: strings.TrimSuffix(r.URL.Path, "/callback"), MaxAge: -1})
+
+	state, nonce, _ := strings.Cut(cookie.Value, ".")
+	if state == "" || query.Get("state") != state {
+		http.Error(w, "Invalid sign-in state", http.StatusBadRequest)
+		return
+	}
+
+	ident, err := provider.Exchange(r.Context(), query.Get("code"), nonce)
+	if err != nil {
+		log.Printf("sso: %s: %v", name, err)
+		http.Error(w, "Sign-in failed", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, u, err := h.service.ExternalLogin(r.Context(), ExternalIdentity{
+		Provider: name,
+		Email:    ident.Email,
+		Name:     ident.Name,
+	}, h.provisionRole)
+	h.users.respondWithLogin(w, tokens, u, err)
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,87 @@
+//go:build integration
+
+package integration_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/iteranya/practicing-go/internal/entities/inventory"
+	"github.com/iteranya/practicing-go/internal/entities/order"
+	"github.com/iteranya/practicing-go/internal/entities/product"
+	"github.com/iteranya/practicing-go/internal/integration"
+)
+
+// TestLogin goes through the login and the checks in front of every route.
+func TestLogin(t *testing.T) {
+	ctx := seed(t, false)
+
+	t.Run("anonymous", func(t *testing.T) {
+		anon := env.Anonymous()
+		creds := map[string]string{"username": integration.AdminUsername, "password": "wrong"}
+		expect(t, ctx, anon, http.StatusUnauthorized, "POST", "/login", creds, nil)
+		expect(t, ctx, anon, http.StatusUnauthorized, "GET", "/products", nil, nil)
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		var me struct {
+			Username string `json:"username"`
+			Role     string `json:"role"`
+		}
+		expect(t, ctx, login(t, ctx), http.StatusOK, "GET", "/me", nil, &me)
+		if me.Username != integration.AdminUsername || me.Role != "admin" {
+			t.Errorf("me: got %s (%s)", me.Username, me.Role)
+		}
+	})
+}
+
+// TestProducts takes a product through the API from creation to the trash.
+func TestProducts(t *testing.T) {
+	ctx := seed(t, false)
+	admin := login(t, ctx)
+
+	var tea product.Product
+	expect(t, ctx, admin, http.StatusCreated, "POST", "/products", product.Product{Slug: "tea", Name: "Tea", Price: 100, Avail: true}, &tea)
+	expect(t, ctx, admin, http.StatusConflict, "POST", "/products", product.Product{Slug: "tea", Name: "Tea"}, nil)
+
+	path := fmt.Sprintf("/products/%d", tea.Id)
+	t.Run("price", func(t *testing.T) {
+		expect(t, ctx, admin, http.StatusOK, "PATCH", path+"/price", map[string]int64{"price": 150}, nil)
+		var got product.Product
+		expect(t, ctx, admin, http.StatusOK, "GET", "/products/tea", nil, &got)
+		if got.Price != 150 {
+			t.Errorf("price: got %d, want 150", got.Price)
+		}
+	})
+
+	t.Run("trash", func(t *testing.T) {
+		expect(t, ctx, admin, http.StatusOK, "DELETE", path, nil, nil)
+		expect(t, ctx, admin, http.StatusNotFound, "GET", path, nil, nil)
+		var trash []product.Product
+		expect(t, ctx, admin, http.StatusOK, "GET", "/products?deleted=true", nil, &trash)
+		if len(trash) != 1 || trash[0].Slug != "tea" {
+			t.Errorf("trash: got %d products", len(trash))
+		}
+	})
+}
+
+// TestOrders sells a demo product and checks its recipe came off the stock.
+func TestOrders(t *testing.T) {
+	ctx := seed(t, true)
+	admin := login(t, ctx)
+
+	var before, after inventory.Inventory
+	expect(t, ctx, admin, http.StatusOK, "GET", "/inventory/coffee-beans", nil, &before)
+
+	var o order.Order
+	expect(t, ctx, admin, http.StatusCreated, "POST", "/orders", order.Order{Items: []string{"espresso"}, Total: 2500, Paid: 5000}, &o)
+	if o.Change != 2500 {
+		t.Errorf("change: got %d, want 2500", o.Change)
+	}
+
+	expect(t, ctx, admin, http.StatusOK, "GET", "/inventory/coffee-beans", nil, &after)
+	if after.Stock != before.Stock-18 {
+		t.Errorf("coffee beans: got %d, want %d", after.Stock, before.Stock-18)
+	}
+}
@@ -0,0 +1,90 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Client calls the API of a served Env, as the user it logged in as.
+type Client struct {
+	url   string
+	token string
+}
+
+// Login logs in and returns a client carrying the access token.
+func (e *Env) Login(ctx context.Context, username, password string) (*Client, error) {
+	c := &Client{url: e.URL}
+	var tokens struct {
+		Token string `json:"token"`
+	}
+	creds := map[string]string{"username": username, "password": password}
+	if err := c.Expect(ctx, http.StatusOK, "POST", "/login", creds, &tokens); err != nil {
+		return nil, fmt.Errorf("login as %s: %w", username, err)
+	}
+	c.token = tokens.Token
+	return c, nil
+}
+
+// Anonymous returns a client without a token.
+func (e *Env) Anonymous() *Client {
+	return &Client{url: e.URL}
+}
+
+// Do sends body (if any) as JSON to path and decodes a successful answer
+// into out (if any). It returns the status, and the body of answers that
+// aren't 2xx as the error.
+func (c *Client) Do(ctx context.Context, method, path string, body, out any) (int, error) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, r)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, fmt.Errorf("%s %s: %d %s", method, path, resp.StatusCode, bytes.TrimSpace(data))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("%s %s: %w", method, path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// Expect is Do failing unless the answer has the given status.
+func (c *Client) Expect(ctx context.Context, status int, method, path string, body, out any) error {
+	got, err := c.Do(ctx, method, path, body, out)
+	if got == status {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%s %s: got %d, want %d", method, path, got, status)
+}
@@ -0,0 +1,231 @@
+//go:build integration
+
+// Package integration runs the server end to end against a real Postgres:
+// Start brings one up in a container with testcontainers and migrates it,
+// Serve starts the server binary on it, and Seed and Truncate give each
+// test a freshly seeded database. It needs Docker, and is only built with
+// the integration tag; its tests check the repositories and the API:
+//
+//	go test -tags integration ./internal/integration
+//
+// INTEGRATION_DB_DSN points it at an existing Postgres instead of a
+// container, e.g. a CI service; Truncate empties it, so never a real one.
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/migrations"
+)
+
+const (
+	image = "postgres:16-alpine"
+
+	// The account Seed creates, already past its first password change
+	AdminUsername = "admin"
+	AdminPassword = "Integration-pass-1!"
+
+	jwtSecret = "integration-secret"
+)
+
+// Env is a migrated database, and the server once Serve has started it.
+type Env struct {
+	DSN string
+	DB  *sql.DB
+	URL string // Base of the API, e.g. http://127.0.0.1:41234/api/v1, set by Serve
+
+	container *postgres.PostgresContainer
+	dir       string // Holds the server binary and its uploads
+	server    *exec.Cmd
+}
+
+// Start starts Postgres, migrates it and builds the server. Close releases
+// everything it started.
+func Start(ctx context.Context) (_ *Env, err error) {
+	e := &Env{DSN: os.Getenv("INTEGRATION_DB_DSN")}
+	defer func() {
+		if err != nil {
+			e.Close()
+		}
+	}()
+
+	if e.DSN == "" {
+		e.container, err = postgres.Run(ctx, image,
+			postgres.WithDatabase("pos_db"),
+			postgres.WithUsername("pos"),
+			postgres.WithPassword("pos"),
+			postgres.BasicWaitStrategies(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start postgres: %w", err)
+		}
+		if e.DSN, err = e.container.ConnectionString(ctx, "sslmode=disable"); err != nil {
+			return nil, fmt.Errorf("failed to get postgres address: %w", err)
+		}
+	}
+
+	e.DB, err = database.NewDatabase(database.Config{Driver: "postgres", DSN: e.DSN, MaxOpenConns: 5, MaxIdleConns: 5})
+	if err != nil {
+		return nil, err
+	}
+	migrator, err := migrations.NewMigrator(e.DB)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		return nil, fmt.Errorf("failed to migrate: %w", err)
+	}
+
+	if e.dir, err = os.MkdirTemp("", "pos-integration-"); err != nil {
+		return nil, err
+	}
+	if err := e.build(ctx); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Close stops the server and the container, if they are running.
+func (e *Env) Close() {
+	if e.server != nil {
+		e.server.Process.Kill()
+		e.server.Wait()
+	}
+	if e.DB != nil {
+		e.DB.Close()
+	}
+	if e.container != nil {
+		testcontainers.TerminateContainer(e.container)
+	}
+	if e.dir != "" {
+		os.RemoveAll(e.dir)
+	}
+}
+
+// Serve starts the server on a free port and waits until it answers.
+func (e *Env) Serve(ctx context.Context) error {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	e.server = exec.Command(e.bin())
+	e.server.Env = append(e.env(), "PORT="+addr)
+	e.server.Stdout, e.server.Stderr = os.Stderr, os.Stderr
+	if err := e.server.Start(); err != nil {
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+	e.URL = "http://" + addr + "/api/v1"
+
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		resp, err := http.Get(e.URL + "/health")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return errors.New("server didn't come up")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// Truncate empties every table but the migrations, leaving the default
+// store as migrating left it.
+func (e *Env) Truncate(ctx context.Context) error {
+	tables, err := database.Select(ctx, e.DB, func(s database.Scanner) (string, error) {
+		var name string
+		return name, s.Scan(&name)
+	}, `
+		SELECT tablename FROM pg_tables
+		WHERE schemaname = current_schema() AND tablename NOT IN ('schema_migrations', 'stores')
+		ORDER BY tablename`)
+	if err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+	if len(tables) > 0 {
+		if _, err := e.DB.ExecContext(ctx, `TRUNCATE `+strings.Join(tables, ", ")+` RESTART IDENTITY CASCADE`); err != nil {
+			return fmt.Errorf("failed to truncate: %w", err)
+		}
+	}
+	_, err = e.DB.ExecContext(ctx, `
+		DELETE FROM stores WHERE id <> 1;
+		SELECT setval('stores_id_seq', 1)`)
+	if err != nil {
+		return fmt.Errorf("failed to reset stores: %w", err)
+	}
+	return nil
+}
+
+// Seed runs the server's -seed, with -demo if asked, and lets the admin
+// in without changing the password first.
+func (e *Env) Seed(ctx context.Context, demo bool) error {
+	args := []string{"-seed"}
+	if demo {
+		args = append(args, "-demo")
+	}
+	cmd := exec.CommandContext(ctx, e.bin(), args...)
+	cmd.Env = append(e.env(), "SEED_ADMIN_USERNAME="+AdminUsername, "SEED_ADMIN_PASSWORD="+AdminPassword)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("seeding failed: %w\n%s", err, out)
+	}
+	_, err := e.DB.ExecContext(ctx, `UPDATE users SET must_change_password = FALSE WHERE username = $1`, AdminUsername)
+	return err
+}
+
+func (e *Env) bin() string { return filepath.Join(e.dir, "server") }
+
+// build compiles ./cmd/server of the module this runs in.
+func (e *Env) build(ctx context.Context) error {
+	gomod, err := exec.CommandContext(ctx, "go", "env", "GOMOD").Output()
+	if err != nil {
+		return fmt.Errorf("failed to find the module: %w", err)
+	}
+	cmd := exec.CommandContext(ctx, "go", "build", "-o", e.bin(), "./cmd/server")
+	cmd.Dir = filepath.Dir(strings.TrimSpace(string(gomod)))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to build the server: %w\n%s", err, out)
+	}
+	return nil
+}
+
+// env is the environment the server runs with: the database, and the rest
+// of the defaults kept out of the tests' way.
+func (e *Env) env() []string {
+	return append(os.Environ(),
+		"CONFIG_FILE=",
+		"DB_DRIVER=postgres",
+		"DB_DSN="+e.DSN,
+		"JWT_SECRET="+jwtSecret,
+		"UPLOAD_DIR="+filepath.Join(e.dir, "uploads"),
+		"METRICS_ADDR=",
+		"LOGIN_RATE_PER_IP=1000",
+		"LOGIN_RATE_PER_USER=1000",
+		"ARGON2_MEMORY_KIB=8192",
+		"ARGON2_ITERATIONS=1",
+	)
+}
@@ -0,0 +1,67 @@
+//go:build integration
+
+package integration_test
+
+import (
+	"context"
+	"log"
+	"testing"
+
+	"github.com/iteranya/practicing-go/internal/integration"
+)
+
+// env is shared by every test: one Postgres and one server, started by
+// TestMain.
+var env *integration.Env
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+	var err error
+	if env, err = integration.Start(ctx); err != nil {
+		log.Fatalf("Fatal: %v", err)
+	}
+	defer env.Close()
+	if err := env.Serve(ctx); err != nil {
+		env.Close()
+		log.Fatalf("Fatal: %v", err)
+	}
+	// An INTEGRATION_DB_DSN database may hold a previous run's rows
+	if err := env.Truncate(ctx); err != nil {
+		env.Close()
+		log.Fatalf("Fatal: %v", err)
+	}
+	m.Run()
+}
+
+// seed gives t a freshly seeded database, with the demo catalog if asked,
+// and empties it again once t is done.
+func seed(t *testing.T, demo bool) context.Context {
+	t.Helper()
+	t.Cleanup(func() {
+		if err := env.Truncate(context.Background()); err != nil {
+			t.Errorf("Truncate: %v", err)
+		}
+	})
+	if err := env.Seed(t.Context(), demo); err != nil {
+		t.Fatal(err)
+	}
+	return t.Context()
+}
+
+// login logs in as the seeded admin.
+func login(t *testing.T, ctx context.Context) *integration.Client {
+	t.Helper()
+	admin, err := env.Login(ctx, integration.AdminUsername, integration.AdminPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return admin
+}
+
+// expect fails t unless the call answers status.
+func expect(t *testing.T, ctx context.Context, c *integration.Client, status int, method, path string, body, out any) {
+	t.Helper()
+	if err := c.Expect(ctx, status, method, path, body, out); err != nil {
+		t.Fatal(err)
+	}
+}
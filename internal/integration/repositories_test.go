@@ -0,0 +1,110 @@
+//go:build integration
+
+package integration_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/entities/order"
+	"github.com/iteranya/practicing-go/internal/entities/product"
+	"github.com/iteranya/practicing-go/internal/entities/store"
+	"github.com/iteranya/practicing-go/internal/entities/user"
+)
+
+// TestRepositories runs the SQL repositories against Postgres directly.
+func TestRepositories(t *testing.T) {
+	ctx := seed(t, false)
+	products := product.NewProductRepository(env.DB)
+	users := user.NewUserRepository(env.DB)
+	orders := order.NewOrderRepository(env.DB)
+
+	t.Run("products", func(t *testing.T) {
+		for _, slug := range []string{"tea", "coffee", "cocoa"} {
+			if err := products.Create(ctx, &product.Product{Slug: slug, Name: slug, Price: 100, Avail: true}); err != nil {
+				t.Fatalf("create product %s: %v", slug, err)
+			}
+		}
+		page, count, err := products.List(ctx, product.ProductListOptions{SortBy: "slug", Limit: 2, Total: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 2 || page[0].Slug != "cocoa" || page[1].Slug != "coffee" {
+			t.Errorf("products by slug: got %v", slugs(page))
+		}
+		if count != 3 {
+			t.Errorf("products total: got %d, want 3", count)
+		}
+		// A page past the last still counts every product
+		if _, count, err := products.List(ctx, product.ProductListOptions{Limit: 2, Offset: 4, Total: true}); err != nil || count != 3 {
+			t.Errorf("products total past the last page: got %d, %v", count, err)
+		}
+
+		cocoa, err := products.GetBySlug(ctx, "cocoa")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := products.Delete(ctx, cocoa.Id); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := products.GetByID(ctx, cocoa.Id); !errors.Is(err, product.ErrProductNotFound) {
+			t.Errorf("deleted product: got %v, want ErrProductNotFound", err)
+		}
+	})
+
+	clerk := &user.User{Username: "Dana", DisplayName: "Dana", Hash: "!", Role: "cashier", Active: true}
+	t.Run("users", func(t *testing.T) {
+		if err := users.Create(ctx, clerk); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := users.GetByUsername(ctx, "dana"); err != nil {
+			t.Errorf("username in another case: %v", err)
+		}
+		err := users.Create(ctx, &user.User{Username: "DANA", Hash: "!", Role: "cashier"})
+		if !errors.Is(err, user.ErrDuplicateUsername) {
+			t.Errorf("duplicate username: got %v, want ErrDuplicateUsername", err)
+		}
+	})
+
+	t.Run("orders", func(t *testing.T) {
+		if clerk.Id == 0 {
+			t.Skip("no clerk to ring the order up")
+		}
+		if err := orders.Create(ctx, env.DB, &order.Order{Items: []string{"tea"}, ClerkId: clerk.Id, Total: 1000, Paid: 1000, Status: order.StatusOpen}); err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now()
+		total, err := orders.GetTotalSales(ctx, now.Add(-time.Hour), now.Add(time.Hour), 0, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 1000 {
+			t.Errorf("total sales: got %d, want 1000", total)
+		}
+	})
+
+	t.Run("stores", func(t *testing.T) {
+		// Another store sees none of the default one's rows
+		north := &store.Store{Slug: "north", Name: "North"}
+		if err := store.NewStoreRepository(env.DB).Create(ctx, north); err != nil {
+			t.Fatal(err)
+		}
+		northCtx := database.WithStore(ctx, north.Id)
+		if _, err := products.GetBySlug(northCtx, "tea"); !errors.Is(err, product.ErrProductNotFound) {
+			t.Errorf("product of another store: got %v, want ErrProductNotFound", err)
+		}
+		if _, err := users.GetByUsername(northCtx, "dana"); !errors.Is(err, user.ErrUserNotFound) {
+			t.Errorf("user of another store: got %v, want ErrUserNotFound", err)
+		}
+	})
+}
+
+func slugs(products []*product.Product) []string {
+	var s []string
+	for _, p := range products {
+		s = append(s, p.Slug)
+	}
+	return s
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	httpRequests = NewCounter("http_requests_total",
+		"HTTP requests served, by route, method and status code.", "route", "method", "status")
+	httpDuration = NewHistogram("http_request_duration_seconds",
+		"How long HTTP requests took to serve, by route and method.", DefaultBuckets, "route", "method")
+)
+
+type routeKey struct{}
+
+// Middleware records every request by route, method and status code, and
+// how long it took. Routes are the patterns of the muxes wrapped in Routes
+// that matched; a request none matched is "unmatched", so raw paths, which
+// are unbounded, never become labels.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := new(string)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), routeKey{}, route)))
+
+		if *route == "" {
+			*route = "unmatched"
+		}
+		httpRequests.Inc(*route, r.Method, strconv.Itoa(rec.status))
+		httpDuration.Observe(time.Since(start).Seconds(), *route, r.Method)
+	})
+}
+
+// Routes labels requests on their way to mux, through next, with the path
+// of the pattern they match in it, after prefix where mux sits behind
+// http.StripPrefix. Labelling ahead of the middleware in front of mux
+// counts the requests it turns away under their routes too. Nested muxes
+// are each labelled, and the innermost match wins.
+func Routes(prefix string, mux *http.ServeMux) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route, ok := r.Context().Value(routeKey{}).(*string); ok {
+				if _, pattern := mux.Handler(r); pattern != "" {
+					if _, path, ok := strings.Cut(pattern, " "); ok {
+						pattern = path // Without the method, which has a label of its own
+					}
+					*route = prefix + pattern
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// statusRecorder notes the status code of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if !s.wroteHeader {
+		s.status, s.wroteHeader = code, true
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the connection, for streams.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,251 @@
+// Package metrics counts what the server does, for Prometheus to scrape
+// from GET /metrics on the metrics listener (METRICS_ADDR). Packages
+// declare the metrics they record next to the code recording them:
+//
+//	var ordersCreated = metrics.NewCounter("orders_created_total", "Orders rung up.")
+//	...
+//	ordersCreated.Inc()
+//
+// Middleware records every HTTP request. Values read when scraped rather
+// than counted, like the connection pool's, are added with Collect.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of latency histograms.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// metric is anything in the exposition.
+type metric interface {
+	write(e *Exposition)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]metric{}
+	collectors []func(e *Exposition)
+)
+
+func register(name string, m metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; ok {
+		panic("metrics: " + name + " registered twice")
+	}
+	registry[name] = m
+}
+
+// Collect registers fn to add metrics to every exposition as it is written,
+// for values read when scraped rather than counted as they happen.
+func Collect(fn func(e *Exposition)) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	collectors = append(collectors, fn)
+}
+
+// series is one combination of label values of a metric.
+type series struct {
+	values []string
+	value  float64  // Counters
+	counts []uint64 // Histograms, per bucket and then above them all
+	sum    float64  // Histograms
+}
+
+// family is a metric with its series by label values.
+type family struct {
+	name, help, kind string
+	labels           []string
+	buckets          []float64 // Histograms only
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+func (f *family) get(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{values: slices.Clone(values)}
+		if f.kind == "histogram" {
+			s.counts = make([]uint64, len(f.buckets)+1)
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// sorted returns the series in a stable order, for the exposition.
+func (f *family) sorted() []*series {
+	out := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return slices.Compare(out[i].values, out[j].values) < 0
+	})
+	return out
+}
+
+// Counter counts events, by the values of its labels.
+type Counter struct {
+	family
+}
+
+// NewCounter registers a counter; by convention its name ends in _total.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{family{name: name, help: help, kind: "counter", labels: labels, series: map[string]*series{}}}
+	if len(labels) == 0 {
+		c.get(nil) // Exposed as 0 until the first event
+	}
+	register(name, c)
+	return c
+}
+
+// Inc counts one event with the given label values, one per label.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add counts n events.
+func (c *Counter) Add(n float64, values ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.get(values).value += n
+}
+
+func (c *Counter) write(e *Exposition) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.Metric(c.name, c.help, c.kind)
+	for _, s := range c.sorted() {
+		e.Sample(c.name, s.value, pairs(c.labels, s.values)...)
+	}
+}
+
+// Histogram counts observations, such as latencies, into buckets.
+type Histogram struct {
+	family
+}
+
+// NewHistogram registers a histogram with the given upper bounds, in
+// ascending order.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{family{name: name, help: help, kind: "histogram", labels: labels, buckets: buckets, series: map[string]*series{}}}
+	if len(labels) == 0 {
+		h.get(nil)
+	}
+	register(name, h)
+	return h
+}
+
+// Observe records v with the given label values.
+func (h *Histogram) Observe(v float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.get(values)
+	s.counts[sort.SearchFloat64s(h.buckets, v)]++
+	s.sum += v
+}
+
+func (h *Histogram) write(e *Exposition) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e.Metric(h.name, h.help, h.kind)
+	for _, s := range h.sorted() {
+		labels := pairs(h.labels, s.values)
+		var count uint64
+		for i, n := range s.counts {
+			count += n
+			le := "+Inf"
+			if i < len(h.buckets) {
+				le = formatFloat(h.buckets[i])
+			}
+			e.Sample(h.name+"_bucket", float64(count), append(labels, "le", le)...)
+		}
+		e.Sample(h.name+"_sum", s.sum, labels...)
+		e.Sample(h.name+"_count", float64(count), labels...)
+	}
+}
+
+// pairs interleaves label names with their values.
+func pairs(labels, values []string) []string {
+	out := make([]string, 0, 2*len(labels))
+	for i, l := range labels {
+		out = append(out, l, values[i])
+	}
+	return out
+}
+
+// Exposition writes metrics in the Prometheus text format.
+type Exposition struct {
+	w *bufio.Writer
+}
+
+// Metric starts a metric: its help text and type (counter, gauge or
+// histogram). Its samples follow.
+func (e *Exposition) Metric(name, help, kind string) {
+	fmt.Fprintf(e.w, "# HELP %s %s\n# TYPE %s %s\n", name, strings.ReplaceAll(help, "\n", " "), name, kind)
+}
+
+// Sample writes one value, with labels given as name and value pairs.
+func (e *Exposition) Sample(name string, value float64, labels ...string) {
+	e.w.WriteString(name)
+	for i := 0; i+1 < len(labels); i += 2 {
+		sep := ","
+		if i == 0 {
+			sep = "{"
+		}
+		e.w.WriteString(sep + labels[i] + `="` + labelEscaper.Replace(labels[i+1]) + `"`)
+	}
+	if len(labels) > 1 {
+		e.w.WriteString("}")
+	}
+	e.w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Handler serves every registered metric, sorted by name, then those from
+// Collect.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registryMu.Lock()
+		names := make([]string, 0, len(registry))
+		for name := range registry {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		metrics := make([]metric, len(names))
+		for i, name := range names {
+			metrics[i] = registry[name]
+		}
+		collect := slices.Clone(collectors)
+		registryMu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		e := &Exposition{w: bufio.NewWriter(w)}
+		for _, m := range metrics {
+			m.write(e)
+		}
+		for _, fn := range collect {
+			fn(e)
+		}
+		e.w.Flush()
+	})
+}
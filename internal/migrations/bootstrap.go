@@ -0,0 +1,269 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
+)
+
+// Schema is the whole schema of a dialect as of one migration version,
+// split into the tables and indexes it creates.
+type Schema struct {
+	Version int
+	Objects []SchemaObject
+}
+
+// SchemaObject is a table or an index of a Schema. Statements creates it;
+// for a table, statements after its CREATE TABLE that fill it in follow,
+// e.g. the default store's row.
+type SchemaObject struct {
+	Table      string
+	Index      string // Empty for the table itself
+	Statements []string
+}
+
+// BootstrapResult is what Bootstrap did to the database.
+type BootstrapResult struct {
+	Loaded  int      // Version of the schema loaded into an empty database, 0 if it wasn't empty
+	Applied []int    // Migrations applied after the schema or, if none was loaded, instead of it
+	Created []string // Tables and indexes that were missing, as table or table.index
+}
+
+var (
+	schemaVersionRe = regexp.MustCompile(`(?m)^-- schema_version: (\d+)$`)
+	createTableRe   = regexp.MustCompile(`^CREATE TABLE (\w+)`)
+	createIndexRe   = regexp.MustCompile(`^CREATE (?:UNIQUE )?INDEX (\w+) ON (\w+)`)
+)
+
+// LoadSchema reads the embedded schema of a dialect, sql/schema/<dialect>.sql.
+func LoadSchema(dialect database.Dialect) (*Schema, error) {
+	name := path.Join("sql", "schema", dialect.String()+".sql")
+	body, err := fs.ReadFile(files, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	match := schemaVersionRe.FindSubmatch(body)
+	if match == nil {
+		return nil, fmt.Errorf("%s has no schema_version line", name)
+	}
+	version, _ := strconv.Atoi(string(match[1]))
+
+	s := &Schema{Version: version}
+	for _, stmt := range splitStatements(string(body)) {
+		if m := createTableRe.FindStringSubmatch(stmt); m != nil {
+			s.Objects = append(s.Objects, SchemaObject{Table: m[1], Statements: []string{stmt}})
+			continue
+		}
+		if m := createIndexRe.FindStringSubmatch(stmt); m != nil {
+			s.Objects = append(s.Objects, SchemaObject{Table: m[2], Index: m[1], Statements: []string{stmt}})
+			continue
+		}
+		last := len(s.Objects) - 1
+		if last < 0 || s.Objects[last].Index != "" {
+			return nil, fmt.Errorf("%s: statement %q doesn't follow a CREATE TABLE", name, firstLine(stmt))
+		}
+		s.Objects[last].Statements = append(s.Objects[last].Statements, stmt)
+	}
+	return s, nil
+}
+
+// Bootstrap gets a database to the current schema, however it finds it,
+// for quick starts against an empty one. An empty database gets the
+// embedded schema in one go rather than every migration in turn, and the
+// migrations it covers are recorded as applied; any newer ones are applied
+// after it. Otherwise pending migrations are applied as by Up, and then
+// the tables and indexes of the schema still missing, e.g. dropped by
+// hand, are created. Tables that are there are never altered.
+func (m *Migrator) Bootstrap(ctx context.Context) (*BootstrapResult, error) {
+	schema, err := LoadSchema(m.dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &BootstrapResult{}
+	err = m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		existing, err := m.objects(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		empty := len(done) == 0
+		for _, obj := range schema.Objects {
+			if obj.Index == "" && existing[obj.key()] {
+				empty = false
+			}
+		}
+
+		var covered []Migration
+		if empty {
+			for _, mig := range m.migrations {
+				if mig.Version <= schema.Version {
+					covered = append(covered, mig)
+					done[mig.Version] = time.Now()
+				}
+			}
+			res.Loaded = schema.Version
+		}
+
+		// A database that wasn't empty is brought up to the schema first,
+		// so what is still missing afterwards is missing from the schema
+		if !empty {
+			if res.Applied, err = m.up(ctx, conn, done); err != nil {
+				return err
+			}
+			if existing, err = m.objects(ctx, conn); err != nil {
+				return err
+			}
+		}
+
+		var missing []SchemaObject
+		for _, obj := range schema.Objects {
+			if !existing[obj.key()] {
+				missing = append(missing, obj)
+			}
+		}
+		if err := m.create(ctx, conn, missing, covered); err != nil {
+			return err
+		}
+		if empty {
+			res.Applied, err = m.up(ctx, conn, done)
+			return err
+		}
+		for _, obj := range missing {
+			res.Created = append(res.Created, obj.key())
+		}
+		return nil
+	})
+	return res, err
+}
+
+func (o SchemaObject) key() string {
+	if o.Index == "" {
+		return o.Table
+	}
+	return o.Table + "." + o.Index
+}
+
+// objects lists the tables and indexes in the database, keyed as
+// SchemaObject.key. MySQL names indexes per table, hence the table in
+// every index's key.
+func (m *Migrator) objects(ctx context.Context, conn *sql.Conn) (map[string]bool, error) {
+	query := `
+		SELECT tablename, '' FROM pg_tables WHERE schemaname = current_schema()
+		UNION ALL
+		SELECT tablename, indexname FROM pg_indexes WHERE schemaname = current_schema()
+	`
+	if m.dialect == database.SQLite {
+		query = `
+			SELECT tbl_name, CASE type WHEN 'index' THEN name ELSE '' END
+			FROM sqlite_master WHERE type IN ('table', 'index')
+		`
+	}
+	if m.dialect == database.MySQL {
+		query = `
+			SELECT table_name, '' FROM information_schema.tables WHERE table_schema = DATABASE()
+			UNION ALL
+			SELECT DISTINCT table_name, index_name FROM information_schema.statistics WHERE table_schema = DATABASE()
+		`
+	}
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect schema: %w", err)
+	}
+	defer rows.Close()
+
+	existing := map[string]bool{}
+	for rows.Next() {
+		var obj SchemaObject
+		if err := rows.Scan(&obj.Table, &obj.Index); err != nil {
+			return nil, fmt.Errorf("failed to scan schema object: %w", err)
+		}
+		existing[obj.key()] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return existing, nil
+}
+
+// create runs the statements of objs and records migrations as applied,
+// in one transaction. Like migrations on MySQL, it may leave part of its
+// work behind there if it fails.
+func (m *Migrator) create(ctx context.Context, conn *sql.Conn, objs []SchemaObject, migrations []Migration) error {
+	if len(objs) == 0 && len(migrations) == 0 {
+		return nil
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, obj := range objs {
+		for _, stmt := range obj.Statements {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("failed to create %s: %w", obj.key(), err)
+			}
+		}
+	}
+	for _, mig := range migrations {
+		_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name)
+		if err != nil {
+			return fmt.Errorf("failed to record migration: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// splitStatements splits a script into its statements, without comments.
+// A statement ends with the line that ends in a semicolon.
+func splitStatements(script string) []string {
+	var stmts []string
+	var cur []string
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimRight(stripComment(line), " \t\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		cur = append(cur, line)
+		if strings.HasSuffix(line, ";") {
+			stmts = append(stmts, strings.TrimSuffix(strings.Join(cur, "\n"), ";"))
+			cur = nil
+		}
+	}
+	return stmts
+}
+
+// stripComment cuts a -- comment off a line, unless it is in a string.
+func stripComment(line string) string {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\'':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(line[i:], "--"):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,356 @@
+// Package migrations keeps the database schema up to date. Each change is a
+// pair of files, NNNN_name.up.sql and NNNN_name.down.sql, written once per
+// dialect in sql/postgres, sql/sqlite and sql/mysql and embedded in the
+// binary; applied versions are recorded in schema_migrations. The schema
+// they add up to is kept whole in sql/schema too, for Bootstrap.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
+)
+
+//go:embed sql/postgres/*.sql sql/sqlite/*.sql sql/mysql/*.sql sql/schema/*.sql
+var files embed.FS
+
+// lockKey is the Postgres advisory lock held while migrating, so instances
+// starting together don't apply the same migration twice. MySQL names its
+// locks, and uses lockName.
+const (
+	lockKey  = 72_011_001
+	lockName = "schema_migrations"
+)
+
+var ErrNoDownMigration = errors.New("migration has no down file")
+
+// Migration is one schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string // Empty if the change can't be undone
+}
+
+// Status is a migration and whether it has been applied.
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"` // Nil while pending
+}
+
+// Load reads the embedded migrations for a dialect, ordered by version.
+func Load(dialect database.Dialect) ([]Migration, error) {
+	dir := path.Join("sql", dialect.String())
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(e.Name(), ".sql"), ".")
+		versionStr, name, found := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionStr)
+		if !ok || !found || err != nil || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("bad migration file name %q, want NNNN_name.up.sql or .down.sql", e.Name())
+		}
+
+		body, err := fs.ReadFile(files, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", e.Name(), err)
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d has two names, %q and %q", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies and reverts the embedded migrations.
+type Migrator struct {
+	db         *sql.DB
+	dialect    database.Dialect
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	dialect := database.DialectOf(db)
+	migrations, err := Load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Up applies every pending migration in order, each in its own transaction,
+// and returns the versions applied.
+func (m *Migrator) Up(ctx context.Context) ([]int, error) {
+	var applied []int
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		applied, err = m.up(ctx, conn, done)
+		return err
+	})
+	return applied, err
+}
+
+// up applies the migrations missing from done, on a locked connection.
+func (m *Migrator) up(ctx context.Context, conn *sql.Conn, done map[int]time.Time) ([]int, error) {
+	if err := m.baseline(ctx, conn, done); err != nil {
+		return nil, err
+	}
+
+	var applied []int
+	for _, mig := range m.migrations {
+		if _, ok := done[mig.Version]; ok {
+			continue
+		}
+		record := `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`
+		if err := m.inTx(ctx, conn, mig.Up, record, mig.Version, mig.Name); err != nil {
+			return applied, fmt.Errorf("migration %d_%s failed: %w", mig.Version, mig.Name, err)
+		}
+		applied = append(applied, mig.Version)
+	}
+	return applied, nil
+}
+
+// Down reverts the most recently applied migrations, steps of them, and
+// returns the versions reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]int, error) {
+	var reverted []int
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := done[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("%w: %d_%s", ErrNoDownMigration, mig.Version, mig.Name)
+			}
+			record := `DELETE FROM schema_migrations WHERE version = $1`
+			if err := m.inTx(ctx, conn, mig.Down, record, mig.Version); err != nil {
+				return fmt.Errorf("reverting %d_%s failed: %w", mig.Version, mig.Name, err)
+			}
+			reverted = append(reverted, mig.Version)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration with when it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var status []Status
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			s := Status{Version: mig.Version, Name: mig.Name}
+			if at, ok := done[mig.Version]; ok {
+				s.AppliedAt = &at
+			}
+			status = append(status, s)
+		}
+		return nil
+	})
+	return status, err
+}
+
+// locked runs fn on one connection holding the migration lock. SQLite
+// needs none: each migration's transaction locks the whole file. It does
+// need foreign keys off, outside any transaction, for a migration to
+// rebuild a table others point at (see inTx). MySQL
+// commits DDL as it goes, so there a failed migration may leave part of its
+// changes behind.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if m.dialect == database.SQLite {
+		if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+			return fmt.Errorf("failed to turn off foreign keys: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), `PRAGMA foreign_keys = ON`)
+		return fn(conn)
+	}
+
+	if m.dialect == database.MySQL {
+		var got sql.NullInt64
+		if err := conn.QueryRowContext(ctx, `SELECT GET_LOCK($1, 60)`, lockName).Scan(&got); err != nil {
+			return fmt.Errorf("failed to take migration lock: %w", err)
+		}
+		if got.Int64 != 1 {
+			return fmt.Errorf("failed to take migration lock: timed out")
+		}
+		defer conn.ExecContext(context.Background(), `SELECT RELEASE_LOCK($1)`, lockName)
+		return fn(conn)
+	}
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	return fn(conn)
+}
+
+// applied creates the bookkeeping table if needed and returns when each
+// applied version was applied.
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	create := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`
+	if m.dialect == database.SQLite {
+		create = `
+			CREATE TABLE IF NOT EXISTS schema_migrations (
+				version INTEGER PRIMARY KEY,
+				name TEXT NOT NULL,
+				applied_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+			)
+		`
+	}
+	if m.dialect == database.MySQL {
+		create = `
+			CREATE TABLE IF NOT EXISTS schema_migrations (
+				version INT PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				applied_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+			)
+		`
+	}
+	if _, err := conn.ExecContext(ctx, create); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	done := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		done[version] = at
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return done, nil
+}
+
+// baseline handles databases created from the old db/schema.sql, before
+// migrations existed: their tables are already there, so the initial
+// migration is recorded as applied instead of run. That schema was
+// Postgres-only, so other databases never need it.
+func (m *Migrator) baseline(ctx context.Context, conn *sql.Conn, done map[int]time.Time) error {
+	if len(done) > 0 || len(m.migrations) == 0 || m.dialect != database.Postgres {
+		return nil
+	}
+
+	var exists bool
+	if err := conn.QueryRowContext(ctx, `SELECT to_regclass('users') IS NOT NULL`).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to inspect schema: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+
+	first := m.migrations[0]
+	var at time.Time
+	err := conn.QueryRowContext(ctx,
+		`INSERT INTO schema_migrations (version, name) VALUES ($1, $2) RETURNING applied_at`,
+		first.Version, first.Name,
+	).Scan(&at)
+	if err != nil {
+		return fmt.Errorf("failed to record baseline: %w", err)
+	}
+	done[first.Version] = at
+	return nil
+}
+
+// inTx runs a migration script and its bookkeeping statement atomically.
+// On SQLite, whose foreign keys are off meanwhile, it checks them all
+// before committing.
+func (m *Migrator) inTx(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+	if m.dialect == database.SQLite {
+		var table string
+		var rowid sql.NullInt64
+		var parent string
+		var fk int
+		err := tx.QueryRowContext(ctx, `PRAGMA foreign_key_check`).Scan(&table, &rowid, &parent, &fk)
+		if err == nil {
+			return fmt.Errorf("a row of %s points at a missing row of %s", table, parent)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to check foreign keys: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,5 @@
+-- Row revisions for optimistic locking, as in postgres/0002_revisions.up.sql.
+ALTER TABLE products ADD COLUMN revision INT NOT NULL DEFAULT 1;
+ALTER TABLE inventory ADD COLUMN revision INT NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN revision INT NOT NULL DEFAULT 1;
+ALTER TABLE orders ADD COLUMN revision INT NOT NULL DEFAULT 1;
@@ -0,0 +1,10 @@
+ALTER TABLE orders DROP COLUMN updated_at;
+ALTER TABLE users DROP COLUMN updated_at;
+ALTER TABLE locations DROP COLUMN updated_at;
+ALTER TABLE locations DROP COLUMN created_at;
+ALTER TABLE roles DROP COLUMN updated_at;
+ALTER TABLE roles DROP COLUMN created_at;
+ALTER TABLE inventory DROP COLUMN updated_at;
+ALTER TABLE inventory DROP COLUMN created_at;
+ALTER TABLE products DROP COLUMN updated_at;
+ALTER TABLE products DROP COLUMN created_at;
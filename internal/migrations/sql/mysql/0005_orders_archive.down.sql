@@ -0,0 +1,4 @@
+-- Archived orders go back to orders.
+INSERT INTO orders (id, items, clerk_id, location_id, total, paid, "change", status, custom, created_at, updated_at, revision)
+SELECT id, items, clerk_id, location_id, total, paid, "change", status, custom, created_at, updated_at, revision FROM orders_archive;
+DROP TABLE orders_archive;
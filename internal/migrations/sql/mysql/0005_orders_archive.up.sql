@@ -0,0 +1,21 @@
+-- Archived orders, as in postgres/0005_orders_archive.up.sql.
+CREATE TABLE orders_archive (
+    id INT PRIMARY KEY, -- From orders, never reused
+    items JSON NOT NULL,
+    clerk_id INT NOT NULL,
+    location_id INT,
+    total BIGINT NOT NULL,
+    paid BIGINT NOT NULL,
+    "change" BIGINT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    custom JSON,
+    created_at DATETIME(6) NOT NULL,
+    updated_at DATETIME(6) NOT NULL,
+    revision INT NOT NULL,
+    archived_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_orders_archive_clerk_id (clerk_id),
+    INDEX idx_orders_archive_location_id (location_id),
+    INDEX idx_orders_archive_created_at (created_at),
+    FOREIGN KEY (clerk_id) REFERENCES users(id),
+    FOREIGN KEY (location_id) REFERENCES locations(id)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
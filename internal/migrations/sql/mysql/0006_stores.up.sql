@@ -0,0 +1,35 @@
+-- Stores, as in postgres/0006_stores.up.sql.
+CREATE TABLE stores (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    slug VARCHAR(255) NOT NULL, -- Named by the X-Store header
+    name TEXT NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT stores_slug_key UNIQUE (slug)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+INSERT INTO stores (id, slug, name) VALUES (1, 'default', 'Default');
+
+ALTER TABLE users ADD COLUMN store_id INT NOT NULL DEFAULT 1, ADD FOREIGN KEY (store_id) REFERENCES stores(id);
+ALTER TABLE login_attempts ADD COLUMN store_id INT NOT NULL DEFAULT 1, ADD FOREIGN KEY (store_id) REFERENCES stores(id);
+ALTER TABLE roles ADD COLUMN store_id INT NOT NULL DEFAULT 1, ADD FOREIGN KEY (store_id) REFERENCES stores(id);
+ALTER TABLE locations ADD COLUMN store_id INT NOT NULL DEFAULT 1, ADD FOREIGN KEY (store_id) REFERENCES stores(id);
+ALTER TABLE inventory ADD COLUMN store_id INT NOT NULL DEFAULT 1, ADD FOREIGN KEY (store_id) REFERENCES stores(id);
+ALTER TABLE inventory_tags ADD COLUMN store_id INT NOT NULL DEFAULT 1, ADD FOREIGN KEY (store_id) REFERENCES stores(id);
+ALTER TABLE stocktake_sessions ADD COLUMN store_id INT NOT NULL DEFAULT 1, ADD FOREIGN KEY (store_id) REFERENCES stores(id);
+ALTER TABLE products ADD COLUMN store_id INT NOT NULL DEFAULT 1, ADD FOREIGN KEY (store_id) REFERENCES stores(id);
+ALTER TABLE orders ADD COLUMN store_id INT NOT NULL DEFAULT 1, ADD FOREIGN KEY (store_id) REFERENCES stores(id);
+ALTER TABLE orders_archive ADD COLUMN store_id INT NOT NULL DEFAULT 1, ADD FOREIGN KEY (store_id) REFERENCES stores(id);
+
+-- Unique within a store; roles.parent has no foreign key here either
+ALTER TABLE users DROP INDEX users_username_key, ADD CONSTRAINT users_username_key UNIQUE (username_lower, store_id);
+ALTER TABLE users DROP INDEX users_email_key, ADD CONSTRAINT users_email_key UNIQUE (email, store_id);
+ALTER TABLE roles DROP INDEX roles_slug_key, ADD CONSTRAINT roles_slug_key UNIQUE (slug, store_id);
+ALTER TABLE locations DROP INDEX locations_slug_key, ADD CONSTRAINT locations_slug_key UNIQUE (slug, store_id);
+ALTER TABLE inventory DROP INDEX inventory_slug_key, ADD CONSTRAINT inventory_slug_key UNIQUE (slug, store_id);
+ALTER TABLE inventory DROP INDEX inventory_barcode_key, ADD CONSTRAINT inventory_barcode_key UNIQUE (barcode, store_id);
+ALTER TABLE inventory_tags DROP INDEX inventory_tags_kind_name_key, ADD CONSTRAINT inventory_tags_kind_name_key UNIQUE (kind, name, store_id);
+ALTER TABLE products DROP INDEX products_slug_key, ADD CONSTRAINT products_slug_key UNIQUE (slug, store_id);
+
+CREATE INDEX idx_orders_store_id ON orders(store_id, created_at);
+CREATE INDEX idx_orders_archive_store_id ON orders_archive(store_id, created_at);
@@ -0,0 +1,16 @@
+-- Role entries go back to role_audit; the rest of the log is lost.
+CREATE TABLE role_audit (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    role_id INT NOT NULL,
+    actor_id INT,
+    action VARCHAR(64) NOT NULL,
+    detail JSON,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_role_audit_role (role_id, created_at DESC),
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+INSERT INTO role_audit (role_id, actor_id, action, detail, created_at)
+SELECT entity_id, actor_id, action, diff, created_at FROM audit_log WHERE entity_type = 'role' ORDER BY id;
+
+DROP TABLE audit_log;
@@ -0,0 +1,23 @@
+-- One audit log for every entity, as in postgres/0007_audit_log.up.sql.
+CREATE TABLE audit_log (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    store_id INT NOT NULL DEFAULT 1,
+    actor_id INT, -- NULL for startup jobs
+    entity_type VARCHAR(32) NOT NULL, -- product, inventory, order, user, role, location, store or tag
+    entity_id INT NOT NULL,
+    action VARCHAR(64) NOT NULL, -- create, update, delete, restore, purge, or one of the entity's own
+    diff JSON,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_audit_log_entity (store_id, entity_type, entity_id, created_at DESC),
+    INDEX idx_audit_log_actor (store_id, actor_id, created_at DESC),
+    INDEX idx_audit_log_created (store_id, created_at DESC),
+    FOREIGN KEY (store_id) REFERENCES stores(id),
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+INSERT INTO audit_log (store_id, actor_id, entity_type, entity_id, action, diff, created_at)
+SELECT COALESCE(r.store_id, 1), a.actor_id, 'role', a.role_id, a.action, a.detail, a.created_at
+FROM role_audit a LEFT JOIN roles r ON r.id = a.role_id
+ORDER BY a.id;
+
+DROP TABLE role_audit;
@@ -0,0 +1,10 @@
+-- Drops everything 0001_initial.up.sql created, newest tables first.
+DROP TABLE IF EXISTS
+    role_audit, roles,
+    inventory_reservations, orders, user_locations, locations,
+    products,
+    stocktake_counts, stocktake_sessions, inventory_supplier_prices,
+    inventory_movements, inventory_snapshots, inventory_tags, inventory,
+    refresh_tokens, magic_links, login_attempts, policy_acceptances,
+    user_activity, time_entries, username_history, user_devices, users
+CASCADE;
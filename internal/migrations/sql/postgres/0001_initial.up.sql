@@ -0,0 +1,333 @@
+-- Initial schema. Later changes go in new numbered migrations next to it.
+
+-- Enable uuid extension if you plan to use UUIDs later,
+-- though your code currently uses SERIAL (int) IDs.
+-- CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+-- ==========================================
+-- 1. USERS
+-- ==========================================
+CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    username TEXT NOT NULL, -- Unique regardless of case, see users_username_key
+    display_name TEXT,
+    email TEXT UNIQUE, -- Lowercased; single sign-on identities are matched on it
+    hash TEXT NOT NULL,
+    pin_hash TEXT, -- Optional bcrypt hash of a numeric PIN for quick register switching
+    role TEXT NOT NULL, -- e.g., 'admin', 'clerk'
+    temp_role TEXT, -- Used instead of role until temp_role_expires_at, e.g. acting manager
+    temp_role_expires_at TIMESTAMPTZ,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    token_version INTEGER NOT NULL DEFAULT 0, -- Bumped to invalidate every issued access token
+    must_change_password BOOLEAN NOT NULL DEFAULT FALSE, -- Set when an admin chose the password
+    failed_logins INTEGER NOT NULL DEFAULT 0, -- Consecutive failures since the last success or lockout
+    locked_until TIMESTAMPTZ, -- Login refused until then
+    last_login_at TIMESTAMPTZ,
+    last_login_ip TEXT,
+    avatar_url TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMPTZ, -- Set when anonymized; the row is kept for order history
+    setting JSONB, -- Stores user.Settings (locale, theme, default_printer, receipt_preference)
+    custom JSONB   -- Stores map[string]any
+);
+
+-- "Bob" and "bob" are the same account; logins match case-insensitively too
+CREATE UNIQUE INDEX users_username_key ON users(LOWER(username));
+
+-- Index for searching users
+CREATE INDEX idx_users_role ON users(role);
+CREATE INDEX idx_users_active ON users(active);
+
+-- Remember-me tokens for trusted devices (registers). Like refresh tokens
+-- only the SHA-256 hash is kept; each use pushes expires_at forward.
+CREATE TABLE user_devices (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL, -- e.g. "Front register"
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_user_devices_user ON user_devices(user_id);
+
+-- Names a user went by before being renamed, so receipts and audit entries
+-- printed under an old username can still be traced to the account.
+CREATE TABLE username_history (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    username TEXT NOT NULL, -- The previous name
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW() -- When it stopped being used
+);
+
+CREATE INDEX idx_username_history_user ON username_history(user_id, changed_at DESC);
+CREATE INDEX idx_username_history_username ON username_history(LOWER(username));
+
+-- Timekeeping: one row per shift, clock_out is NULL while clocked in.
+CREATE TABLE time_entries (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    clock_in TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    clock_out TIMESTAMPTZ,
+    CHECK (clock_out IS NULL OR clock_out >= clock_in)
+);
+
+CREATE INDEX idx_time_entries_user ON time_entries(user_id, clock_in);
+-- At most one open shift per user
+CREATE UNIQUE INDEX idx_time_entries_open ON time_entries(user_id) WHERE clock_out IS NULL;
+
+-- Security audit trail: logins, password and role changes, deactivation.
+CREATE TABLE user_activity (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE, -- Target account
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL, -- Who performed it
+    action TEXT NOT NULL, -- login, password_change, role_change, deactivate, reactivate, anonymize
+    ip TEXT,
+    detail JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_activity_user ON user_activity(user_id, created_at DESC);
+
+-- Staff policy versions each user agreed to
+CREATE TABLE policy_acceptances (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    version TEXT NOT NULL,
+    accepted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, version)
+);
+
+-- Every sign-in attempt, successful or not, for security review. Attempts on
+-- unknown usernames are kept too, with user_id NULL.
+CREATE TABLE login_attempts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    username TEXT NOT NULL, -- As typed, empty for device tokens that matched nobody
+    method TEXT NOT NULL, -- password, pin, device, oidc, magic_link
+    outcome TEXT NOT NULL, -- success, invalid_credentials, locked, inactive, captcha_required, error
+    ip TEXT,
+    user_agent TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_login_attempts_user ON login_attempts(user_id, created_at DESC);
+CREATE INDEX idx_login_attempts_username ON login_attempts(LOWER(username), created_at DESC);
+
+-- Single-use passwordless login links sent by email, stored hashed.
+CREATE TABLE magic_links (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ, -- Set on the first (and only) use
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_magic_links_user_id ON magic_links(user_id);
+
+-- Long-lived refresh tokens, stored as SHA-256 hashes. Each one is single-use:
+-- refreshing revokes it and issues a replacement.
+CREATE TABLE refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ, -- Set when rotated or revoked
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+
+-- ==========================================
+-- 2. INVENTORY
+-- ==========================================
+CREATE TABLE inventory (
+    id SERIAL PRIMARY KEY,
+    slug TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    "desc" TEXT, -- "desc" is a reserved keyword in SQL, so it must be quoted
+    label TEXT,
+    tags JSONB NOT NULL DEFAULT '[]', -- Array of strings
+    stock BIGINT NOT NULL DEFAULT 0,
+    min_stock BIGINT NOT NULL DEFAULT 0, -- Reorder threshold
+    max_stock BIGINT NOT NULL DEFAULT 0, -- Upper par level, 0 = no ceiling
+    unit_cost BIGINT NOT NULL DEFAULT 0, -- Weighted average cost per unit
+    barcode TEXT UNIQUE, -- NULL when unset so multiple items can lack one
+    custom JSONB,
+    deleted_at TIMESTAMPTZ -- Soft delete; NULL while the item is active
+);
+
+-- Indexes for filtering and searching
+CREATE INDEX idx_inventory_label ON inventory(label);
+CREATE INDEX idx_inventory_active ON inventory(id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_inventory_tags ON inventory USING GIN (tags);
+
+-- Curated tags and labels for inventory items
+CREATE TABLE inventory_tags (
+    id SERIAL PRIMARY KEY,
+    kind TEXT NOT NULL, -- 'tag' or 'label'
+    name TEXT NOT NULL,
+    UNIQUE (kind, name)
+);
+-- Optional: GIN index if you plan to query inside the JSONB custom field
+-- CREATE INDEX idx_inventory_custom ON inventory USING GIN (custom);
+
+-- Daily stock levels, one row per item per day (upserted by the snapshot job)
+CREATE TABLE inventory_snapshots (
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    taken_on DATE NOT NULL,
+    stock BIGINT NOT NULL,
+    PRIMARY KEY (inventory_id, taken_on)
+);
+
+CREATE INDEX idx_inventory_snapshots_taken_on ON inventory_snapshots(taken_on);
+
+-- Stock ledger: every manual adjustment with its reason and author
+CREATE TABLE inventory_movements (
+    id SERIAL PRIMARY KEY,
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    delta BIGINT NOT NULL,
+    stock_after BIGINT NOT NULL,
+    reason TEXT NOT NULL, -- e.g., 'received', 'waste', 'stocktake'
+    note TEXT NOT NULL DEFAULT '',
+    unit_cost BIGINT NOT NULL DEFAULT 0, -- Cost per unit on receiving
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Supplier quotes per item, used for price comparison and reordering
+CREATE TABLE inventory_supplier_prices (
+    id SERIAL PRIMARY KEY,
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    supplier TEXT NOT NULL,
+    unit_price BIGINT NOT NULL DEFAULT 0,
+    lead_time_days INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (inventory_id, supplier)
+);
+
+CREATE INDEX idx_inventory_movements_inventory_id ON inventory_movements(inventory_id, created_at);
+
+-- Stocktake (physical count) sessions. Closing a session records the
+-- expected stock next to each count and corrects stock to the counted value.
+CREATE TABLE stocktake_sessions (
+    id SERIAL PRIMARY KEY,
+    status TEXT NOT NULL DEFAULT 'open', -- 'open' or 'closed'
+    note TEXT NOT NULL DEFAULT '',
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    closed_at TIMESTAMPTZ
+);
+
+CREATE TABLE stocktake_counts (
+    session_id INTEGER NOT NULL REFERENCES stocktake_sessions(id) ON DELETE CASCADE,
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    counted BIGINT NOT NULL,
+    expected BIGINT, -- System stock at close, NULL while the session is open
+    unit_cost BIGINT NOT NULL DEFAULT 0, -- Unit cost at close, to value the variance
+    PRIMARY KEY (session_id, inventory_id)
+);
+
+-- ==========================================
+-- 3. PRODUCTS
+-- ==========================================
+CREATE TABLE products (
+    id SERIAL PRIMARY KEY,
+    slug TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    "desc" TEXT,
+    tag TEXT,
+    label TEXT,
+    price BIGINT NOT NULL DEFAULT 0,
+    avail BOOLEAN NOT NULL DEFAULT TRUE,
+    auto_86 BOOLEAN NOT NULL DEFAULT FALSE, -- Avail was switched off by stock depletion, not by hand
+    items JSONB,  -- Array of strings (slugs) for bundles
+    recipe JSONB, -- Map of string:int for inventory usage
+    custom JSONB
+);
+
+-- Indexes for filtering
+CREATE INDEX idx_products_tag ON products(tag);
+CREATE INDEX idx_products_label ON products(label);
+CREATE INDEX idx_products_price ON products(price);
+CREATE INDEX idx_products_avail ON products(avail);
+
+-- ==========================================
+-- 4. ORDERS
+-- ==========================================
+-- Stores; clerks only see orders from the locations they're assigned to
+CREATE TABLE locations (
+    id SERIAL PRIMARY KEY,
+    slug TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    address TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE user_locations (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    location_id INTEGER NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, location_id)
+);
+
+CREATE TABLE orders (
+    id SERIAL PRIMARY KEY,
+    items JSONB NOT NULL, -- Stores []string (product slugs)
+    clerk_id INTEGER NOT NULL REFERENCES users(id), -- Users are anonymized, never deleted
+    location_id INTEGER REFERENCES locations(id), -- Store it was rung up at, NULL for older orders
+    total BIGINT NOT NULL DEFAULT 0,
+    paid BIGINT NOT NULL DEFAULT 0,
+    change BIGINT NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'open', -- 'open' or 'void'
+    custom JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Indexes for reporting and history
+CREATE INDEX idx_orders_clerk_id ON orders(clerk_id);
+CREATE INDEX idx_orders_location_id ON orders(location_id);
+CREATE INDEX idx_orders_created_at ON orders(created_at);
+CREATE INDEX idx_orders_total ON orders(total);
+CREATE INDEX idx_orders_status ON orders(status);
+
+-- Stock held for an order until it is voided or the hold expires
+CREATE TABLE inventory_reservations (
+    id SERIAL PRIMARY KEY,
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    quantity BIGINT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_inventory_reservations_inventory_id ON inventory_reservations(inventory_id);
+CREATE INDEX idx_inventory_reservations_order_id ON inventory_reservations(order_id);
+CREATE INDEX idx_inventory_reservations_expires_at ON inventory_reservations(expires_at);
+
+-- ==========================================
+-- 5. ROLES
+-- ==========================================
+CREATE TABLE roles (
+    id SERIAL PRIMARY KEY,
+    slug TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    permissions JSONB, -- Stores []string
+    -- Slug of the role whose permissions this one inherits, e.g. manager -> cashier
+    parent TEXT REFERENCES roles(slug) ON UPDATE CASCADE ON DELETE SET NULL,
+    system BOOLEAN NOT NULL DEFAULT FALSE -- Seeded; can't be deleted or lose critical permissions
+);
+
+CREATE INDEX idx_roles_slug ON roles(slug);
+
+-- Who changed which role and how. role_id has no foreign key so the history
+-- outlives a deleted role.
+CREATE TABLE role_audit (
+    id SERIAL PRIMARY KEY,
+    role_id INTEGER NOT NULL,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL, -- NULL for startup seeding
+    action TEXT NOT NULL, -- create, update, delete, permission_add, permission_remove
+    detail JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_role_audit_role ON role_audit(role_id, created_at DESC);
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- Row revisions for optimistic locking. Every write to an editable row
+-- bumps revision; updates name the revision they were based on and fail
+-- with a conflict if it has moved on.
+ALTER TABLE products ADD COLUMN revision INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE inventory ADD COLUMN revision INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN revision INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE orders ADD COLUMN revision INTEGER NOT NULL DEFAULT 1;
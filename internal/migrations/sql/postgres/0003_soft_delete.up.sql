@@ -0,0 +1,11 @@
+-- Products and roles go to a trash instead of being deleted outright, as
+-- inventory already did (see database.SoftDelete). Users already had
+-- deleted_at, set when anonymized; deleting now only trashes the account,
+-- and anonymized_at marks the ones that were anonymized for good.
+ALTER TABLE products ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE roles ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN anonymized_at TIMESTAMPTZ;
+
+UPDATE users SET anonymized_at = deleted_at WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX idx_products_active ON products(id) WHERE deleted_at IS NULL;
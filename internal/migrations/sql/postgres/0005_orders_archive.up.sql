@@ -0,0 +1,22 @@
+-- Orders older than ORDER_RETENTION, moved out of orders by the archive job
+-- so the hot table stays small. Rows keep their id and every column, plus
+-- when they were moved; reports that opt in read both tables.
+CREATE TABLE orders_archive (
+    id INTEGER PRIMARY KEY, -- From orders, never reused
+    items JSONB NOT NULL,
+    clerk_id INTEGER NOT NULL REFERENCES users(id),
+    location_id INTEGER REFERENCES locations(id),
+    total BIGINT NOT NULL,
+    paid BIGINT NOT NULL,
+    change BIGINT NOT NULL,
+    status TEXT NOT NULL,
+    custom JSONB,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    revision INTEGER NOT NULL,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_orders_archive_clerk_id ON orders_archive(clerk_id);
+CREATE INDEX idx_orders_archive_location_id ON orders_archive(location_id);
+CREATE INDEX idx_orders_archive_created_at ON orders_archive(created_at);
@@ -0,0 +1,51 @@
+-- Stores: one deployment can serve several shops, each seeing only its
+-- own data. Entity tables get a store_id, and what was there before
+-- belongs to the default store. Rows hanging off an entity (movements,
+-- devices, audit entries) go through it and need none.
+--
+-- There is no down migration: once a second store exists its rows can't
+-- be folded back into one.
+CREATE TABLE stores (
+    id SERIAL PRIMARY KEY,
+    slug TEXT NOT NULL UNIQUE, -- Named by the X-Store header
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO stores (id, slug, name) VALUES (1, 'default', 'Default');
+SELECT setval('stores_id_seq', 1);
+
+ALTER TABLE users ADD COLUMN store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id);
+ALTER TABLE login_attempts ADD COLUMN store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id);
+ALTER TABLE roles ADD COLUMN store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id);
+ALTER TABLE locations ADD COLUMN store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id);
+ALTER TABLE inventory ADD COLUMN store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id);
+ALTER TABLE inventory_tags ADD COLUMN store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id);
+ALTER TABLE stocktake_sessions ADD COLUMN store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id);
+ALTER TABLE products ADD COLUMN store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id);
+ALTER TABLE orders ADD COLUMN store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id);
+ALTER TABLE orders_archive ADD COLUMN store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id);
+
+-- Names and codes are unique within a store. The store comes second so
+-- the indexes still serve lookups by the name alone.
+DROP INDEX users_username_key;
+CREATE UNIQUE INDEX users_username_key ON users(LOWER(username), store_id);
+ALTER TABLE users DROP CONSTRAINT users_email_key, ADD CONSTRAINT users_email_key UNIQUE (email, store_id);
+ALTER TABLE roles DROP CONSTRAINT roles_parent_fkey;
+ALTER TABLE roles DROP CONSTRAINT roles_slug_key, ADD CONSTRAINT roles_slug_key UNIQUE (slug, store_id);
+-- The role repository clears children before a role is purged, so only
+-- renames need following
+ALTER TABLE roles ADD CONSTRAINT roles_parent_fkey FOREIGN KEY (parent, store_id) REFERENCES roles(slug, store_id) ON UPDATE CASCADE;
+ALTER TABLE locations DROP CONSTRAINT locations_slug_key, ADD CONSTRAINT locations_slug_key UNIQUE (slug, store_id);
+ALTER TABLE inventory DROP CONSTRAINT inventory_slug_key, ADD CONSTRAINT inventory_slug_key UNIQUE (slug, store_id);
+ALTER TABLE inventory DROP CONSTRAINT inventory_barcode_key, ADD CONSTRAINT inventory_barcode_key UNIQUE (barcode, store_id);
+ALTER TABLE inventory_tags DROP CONSTRAINT inventory_tags_kind_name_key, ADD CONSTRAINT inventory_tags_kind_name_key UNIQUE (kind, name, store_id);
+ALTER TABLE products DROP CONSTRAINT products_slug_key, ADD CONSTRAINT products_slug_key UNIQUE (slug, store_id);
+
+CREATE INDEX idx_users_store_id ON users(store_id);
+CREATE INDEX idx_roles_store_id ON roles(store_id);
+CREATE INDEX idx_inventory_store_id ON inventory(store_id);
+CREATE INDEX idx_products_store_id ON products(store_id);
+CREATE INDEX idx_orders_store_id ON orders(store_id, created_at);
+CREATE INDEX idx_orders_archive_store_id ON orders_archive(store_id, created_at);
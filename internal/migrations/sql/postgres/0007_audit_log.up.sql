@@ -0,0 +1,24 @@
+-- One audit log for every entity, in place of tables kept per entity. The
+-- role audit trail moves into it. entity_id has no foreign key so the
+-- history outlives a purged entity, hence the store_id of its own.
+CREATE TABLE audit_log (
+    id SERIAL PRIMARY KEY,
+    store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id),
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL, -- NULL for startup jobs
+    entity_type TEXT NOT NULL, -- product, inventory, order, user, role, location, store or tag
+    entity_id INTEGER NOT NULL,
+    action TEXT NOT NULL, -- create, update, delete, restore, purge, or one of the entity's own
+    diff JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_entity ON audit_log(store_id, entity_type, entity_id, created_at DESC);
+CREATE INDEX idx_audit_log_actor ON audit_log(store_id, actor_id, created_at DESC);
+CREATE INDEX idx_audit_log_created ON audit_log(store_id, created_at DESC);
+
+INSERT INTO audit_log (store_id, actor_id, entity_type, entity_id, action, diff, created_at)
+SELECT COALESCE(r.store_id, 1), a.actor_id, 'role', a.role_id, a.action, a.detail, a.created_at
+FROM role_audit a LEFT JOIN roles r ON r.id = a.role_id
+ORDER BY a.id;
+
+DROP TABLE role_audit;
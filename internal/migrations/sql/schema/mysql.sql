@@ -0,0 +1,434 @@
+-- The schema the migrations build, up to the version below, in one go: the
+-- MySQL/MariaDB version of postgres.sql, which says how it is used. Most
+-- indexes are declared with their table, as in the migrations; only the
+-- ones created on their own are filled in on a database that lacks them.
+--
+-- schema_version: 7
+
+-- ==========================================
+-- 0. STORES
+-- ==========================================
+CREATE TABLE stores (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    slug VARCHAR(255) NOT NULL, -- Named by the X-Store header
+    name TEXT NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT stores_slug_key UNIQUE (slug)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+INSERT INTO stores (id, slug, name) VALUES (1, 'default', 'Default');
+
+-- ==========================================
+-- 1. USERS
+-- ==========================================
+CREATE TABLE users (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    username VARCHAR(255) NOT NULL, -- Unique regardless of case within a store, see users_username_key
+    username_lower VARCHAR(255) AS (LOWER(username)) STORED,
+    display_name TEXT,
+    email VARCHAR(255), -- Lowercased; single sign-on identities are matched on it
+    hash TEXT NOT NULL,
+    pin_hash TEXT, -- Optional bcrypt hash of a numeric PIN for quick register switching
+    role VARCHAR(255) NOT NULL, -- e.g., 'admin', 'clerk'
+    temp_role VARCHAR(255), -- Used instead of role until temp_role_expires_at, e.g. acting manager
+    temp_role_expires_at DATETIME(6),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    token_version INT NOT NULL DEFAULT 0, -- Bumped to invalidate every issued access token
+    must_change_password BOOLEAN NOT NULL DEFAULT FALSE, -- Set when an admin chose the password
+    failed_logins INT NOT NULL DEFAULT 0, -- Consecutive failures since the last success or lockout
+    locked_until DATETIME(6), -- Login refused until then
+    last_login_at DATETIME(6),
+    last_login_ip TEXT,
+    avatar_url TEXT,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    deleted_at DATETIME(6), -- Set when deleted; the row is kept for order history
+    setting JSON, -- Stores user.Settings (locale, theme, default_printer, receipt_preference)
+    custom JSON,  -- Stores map[string]any
+    revision INT NOT NULL DEFAULT 1,
+    anonymized_at DATETIME(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    store_id INT NOT NULL DEFAULT 1,
+    -- "Bob" and "bob" are the same account; logins match case-insensitively too
+    CONSTRAINT users_username_key UNIQUE (username_lower, store_id),
+    CONSTRAINT users_email_key UNIQUE (email, store_id),
+    INDEX idx_users_role (role),
+    INDEX idx_users_active (active),
+    FOREIGN KEY (store_id) REFERENCES stores(id)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- Remember-me tokens for trusted devices (registers). Like refresh tokens
+-- only the SHA-256 hash is kept; each use pushes expires_at forward.
+CREATE TABLE user_devices (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    name TEXT NOT NULL, -- e.g. "Front register"
+    token_hash VARCHAR(255) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    last_used_at DATETIME(6),
+    expires_at DATETIME(6) NOT NULL,
+    revoked_at DATETIME(6),
+    CONSTRAINT user_devices_token_hash_key UNIQUE (token_hash),
+    INDEX idx_user_devices_user (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- Names a user went by before being renamed, so receipts and audit entries
+-- printed under an old username can still be traced to the account.
+CREATE TABLE username_history (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    username VARCHAR(255) NOT NULL, -- The previous name
+    changed_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6), -- When it stopped being used
+    INDEX idx_username_history_user (user_id, changed_at DESC),
+    INDEX idx_username_history_username (username),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- Timekeeping: one row per shift, clock_out is NULL while clocked in.
+CREATE TABLE time_entries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    clock_in DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    clock_out DATETIME(6),
+    -- Set only while clocked in, so its unique key allows one open shift per
+    -- user. MySQL refuses ON DELETE CASCADE on the base column of a stored
+    -- generated column; users are never deleted, so it isn't missed.
+    open_user_id INT AS (CASE WHEN clock_out IS NULL THEN user_id END) STORED,
+    CHECK (clock_out IS NULL OR clock_out >= clock_in),
+    INDEX idx_time_entries_user (user_id, clock_in),
+    CONSTRAINT idx_time_entries_open UNIQUE (open_user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- Security audit trail: logins, password and role changes, deactivation.
+CREATE TABLE user_activity (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL, -- Target account
+    actor_id INT, -- Who performed it
+    action VARCHAR(64) NOT NULL, -- login, password_change, role_change, deactivate, reactivate, anonymize
+    ip TEXT,
+    detail JSON,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_user_activity_user (user_id, created_at DESC),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- Staff policy versions each user agreed to
+CREATE TABLE policy_acceptances (
+    user_id INT NOT NULL,
+    version VARCHAR(255) NOT NULL,
+    accepted_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    PRIMARY KEY (user_id, version),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- Every sign-in attempt, successful or not, for security review. Attempts on
+-- unknown usernames are kept too, with user_id NULL.
+CREATE TABLE login_attempts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT,
+    username VARCHAR(255) NOT NULL, -- As typed, empty for device tokens that matched nobody
+    method VARCHAR(32) NOT NULL, -- password, pin, device, oidc, magic_link
+    outcome VARCHAR(32) NOT NULL, -- success, invalid_credentials, locked, inactive, captcha_required, error
+    ip TEXT,
+    user_agent TEXT,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    store_id INT NOT NULL DEFAULT 1,
+    INDEX idx_login_attempts_user (user_id, created_at DESC),
+    INDEX idx_login_attempts_username (username, created_at DESC),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (store_id) REFERENCES stores(id)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- Single-use passwordless login links sent by email, stored hashed.
+CREATE TABLE magic_links (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    token_hash VARCHAR(255) NOT NULL,
+    expires_at DATETIME(6) NOT NULL,
+    used_at DATETIME(6), -- Set on the first (and only) use
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT magic_links_token_hash_key UNIQUE (token_hash),
+    INDEX idx_magic_links_user_id (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- Long-lived refresh tokens, stored as SHA-256 hashes. Each one is single-use:
+-- refreshing revokes it and issues a replacement.
+CREATE TABLE refresh_tokens (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    token_hash VARCHAR(255) NOT NULL,
+    expires_at DATETIME(6) NOT NULL,
+    revoked_at DATETIME(6), -- Set when rotated or revoked
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT refresh_tokens_token_hash_key UNIQUE (token_hash),
+    INDEX idx_refresh_tokens_user_id (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- ==========================================
+-- 2. INVENTORY
+-- ==========================================
+CREATE TABLE inventory (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    slug VARCHAR(255) NOT NULL,
+    name TEXT NOT NULL,
+    "desc" TEXT, -- "desc" is a reserved keyword in SQL, so it must be quoted
+    label VARCHAR(255),
+    tags JSON NOT NULL DEFAULT (JSON_ARRAY()), -- Array of strings
+    stock BIGINT NOT NULL DEFAULT 0,
+    min_stock BIGINT NOT NULL DEFAULT 0, -- Reorder threshold
+    max_stock BIGINT NOT NULL DEFAULT 0, -- Upper par level, 0 = no ceiling
+    unit_cost BIGINT NOT NULL DEFAULT 0, -- Weighted average cost per unit
+    barcode VARCHAR(255), -- NULL when unset so multiple items can lack one
+    custom JSON,
+    deleted_at DATETIME(6), -- Soft delete; NULL while the item is active
+    revision INT NOT NULL DEFAULT 1,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    store_id INT NOT NULL DEFAULT 1,
+    CONSTRAINT inventory_slug_key UNIQUE (slug, store_id),
+    CONSTRAINT inventory_barcode_key UNIQUE (barcode, store_id),
+    INDEX idx_inventory_label (label),
+    INDEX idx_inventory_deleted_at (deleted_at),
+    FOREIGN KEY (store_id) REFERENCES stores(id)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- Curated tags and labels for inventory items
+CREATE TABLE inventory_tags (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL, -- 'tag' or 'label'
+    name VARCHAR(255) NOT NULL,
+    store_id INT NOT NULL DEFAULT 1,
+    CONSTRAINT inventory_tags_kind_name_key UNIQUE (kind, name, store_id),
+    FOREIGN KEY (store_id) REFERENCES stores(id)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- Daily stock levels, one row per item per day (upserted by the snapshot job)
+CREATE TABLE inventory_snapshots (
+    inventory_id INT NOT NULL,
+    taken_on DATE NOT NULL,
+    stock BIGINT NOT NULL,
+    PRIMARY KEY (inventory_id, taken_on),
+    INDEX idx_inventory_snapshots_taken_on (taken_on),
+    FOREIGN KEY (inventory_id) REFERENCES inventory(id) ON DELETE CASCADE
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- Stock ledger: every manual adjustment with its reason and author
+CREATE TABLE inventory_movements (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    inventory_id INT NOT NULL,
+    delta BIGINT NOT NULL,
+    stock_after BIGINT NOT NULL,
+    reason VARCHAR(64) NOT NULL, -- e.g., 'received', 'waste', 'stocktake'
+    note TEXT NOT NULL DEFAULT (''),
+    unit_cost BIGINT NOT NULL DEFAULT 0, -- Cost per unit on receiving
+    user_id INT,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_inventory_movements_inventory_id (inventory_id, created_at),
+    FOREIGN KEY (inventory_id) REFERENCES inventory(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- Supplier quotes per item, used for price comparison and reordering
+CREATE TABLE inventory_supplier_prices (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    inventory_id INT NOT NULL,
+    supplier VARCHAR(255) NOT NULL,
+    unit_price BIGINT NOT NULL DEFAULT 0,
+    lead_time_days INT NOT NULL DEFAULT 0,
+    CONSTRAINT inventory_supplier_prices_inventory_id_supplier_key UNIQUE (inventory_id, supplier),
+    FOREIGN KEY (inventory_id) REFERENCES inventory(id) ON DELETE CASCADE
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- Stocktake (physical count) sessions. Closing a session records the
+-- expected stock next to each count and corrects stock to the counted value.
+CREATE TABLE stocktake_sessions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    status VARCHAR(16) NOT NULL DEFAULT 'open', -- 'open' or 'closed'
+    note TEXT NOT NULL DEFAULT (''),
+    user_id INT,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    closed_at DATETIME(6),
+    store_id INT NOT NULL DEFAULT 1,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (store_id) REFERENCES stores(id)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE stocktake_counts (
+    session_id INT NOT NULL,
+    inventory_id INT NOT NULL,
+    counted BIGINT NOT NULL,
+    expected BIGINT, -- System stock at close, NULL while the session is open
+    unit_cost BIGINT NOT NULL DEFAULT 0, -- Unit cost at close, to value the variance
+    PRIMARY KEY (session_id, inventory_id),
+    FOREIGN KEY (session_id) REFERENCES stocktake_sessions(id) ON DELETE CASCADE,
+    FOREIGN KEY (inventory_id) REFERENCES inventory(id) ON DELETE CASCADE
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- ==========================================
+-- 3. PRODUCTS
+-- ==========================================
+CREATE TABLE products (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    slug VARCHAR(255) NOT NULL,
+    name TEXT NOT NULL,
+    "desc" TEXT,
+    tag VARCHAR(255),
+    label VARCHAR(255),
+    price BIGINT NOT NULL DEFAULT 0,
+    avail BOOLEAN NOT NULL DEFAULT TRUE,
+    auto_86 BOOLEAN NOT NULL DEFAULT FALSE, -- Avail was switched off by stock depletion, not by hand
+    items JSON,  -- Array of strings (slugs) for bundles
+    recipe JSON, -- Map of string:int for inventory usage
+    custom JSON,
+    revision INT NOT NULL DEFAULT 1,
+    deleted_at DATETIME(6),
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    store_id INT NOT NULL DEFAULT 1,
+    CONSTRAINT products_slug_key UNIQUE (slug, store_id),
+    INDEX idx_products_tag (tag),
+    INDEX idx_products_label (label),
+    INDEX idx_products_price (price),
+    INDEX idx_products_avail (avail),
+    FOREIGN KEY (store_id) REFERENCES stores(id)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE INDEX idx_products_deleted_at ON products(deleted_at);
+
+-- ==========================================
+-- 4. ORDERS
+-- ==========================================
+-- Stores; clerks only see orders from the locations they're assigned to
+CREATE TABLE locations (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    slug VARCHAR(255) NOT NULL,
+    name TEXT NOT NULL,
+    address TEXT NOT NULL DEFAULT (''),
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    store_id INT NOT NULL DEFAULT 1,
+    CONSTRAINT locations_slug_key UNIQUE (slug, store_id),
+    FOREIGN KEY (store_id) REFERENCES stores(id)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE user_locations (
+    user_id INT NOT NULL,
+    location_id INT NOT NULL,
+    PRIMARY KEY (user_id, location_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (location_id) REFERENCES locations(id) ON DELETE CASCADE
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE TABLE orders (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    items JSON NOT NULL, -- Stores []string (product slugs)
+    clerk_id INT NOT NULL, -- Users are anonymized, never deleted
+    location_id INT, -- Store it was rung up at, NULL for older orders
+    total BIGINT NOT NULL DEFAULT 0,
+    paid BIGINT NOT NULL DEFAULT 0,
+    "change" BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(16) NOT NULL DEFAULT 'open', -- 'open' or 'void'
+    custom JSON,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    revision INT NOT NULL DEFAULT 1,
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    store_id INT NOT NULL DEFAULT 1,
+    INDEX idx_orders_clerk_id (clerk_id),
+    INDEX idx_orders_location_id (location_id),
+    INDEX idx_orders_created_at (created_at),
+    INDEX idx_orders_total (total),
+    INDEX idx_orders_status (status),
+    FOREIGN KEY (clerk_id) REFERENCES users(id),
+    FOREIGN KEY (location_id) REFERENCES locations(id),
+    FOREIGN KEY (store_id) REFERENCES stores(id)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE INDEX idx_orders_store_id ON orders(store_id, created_at);
+
+-- Archived orders, as in postgres.sql
+CREATE TABLE orders_archive (
+    id INT PRIMARY KEY, -- From orders, never reused
+    items JSON NOT NULL,
+    clerk_id INT NOT NULL,
+    location_id INT,
+    total BIGINT NOT NULL,
+    paid BIGINT NOT NULL,
+    "change" BIGINT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    custom JSON,
+    created_at DATETIME(6) NOT NULL,
+    updated_at DATETIME(6) NOT NULL,
+    revision INT NOT NULL,
+    archived_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    store_id INT NOT NULL DEFAULT 1,
+    INDEX idx_orders_archive_clerk_id (clerk_id),
+    INDEX idx_orders_archive_location_id (location_id),
+    INDEX idx_orders_archive_created_at (created_at),
+    FOREIGN KEY (clerk_id) REFERENCES users(id),
+    FOREIGN KEY (location_id) REFERENCES locations(id),
+    FOREIGN KEY (store_id) REFERENCES stores(id)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+CREATE INDEX idx_orders_archive_store_id ON orders_archive(store_id, created_at);
+
+-- Stock held for an order until it is voided or the hold expires
+CREATE TABLE inventory_reservations (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    inventory_id INT NOT NULL,
+    order_id INT NOT NULL,
+    quantity BIGINT NOT NULL,
+    expires_at DATETIME(6) NOT NULL,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_inventory_reservations_inventory_id (inventory_id),
+    INDEX idx_inventory_reservations_order_id (order_id),
+    INDEX idx_inventory_reservations_expires_at (expires_at),
+    FOREIGN KEY (inventory_id) REFERENCES inventory(id) ON DELETE CASCADE,
+    FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- ==========================================
+-- 5. ROLES
+-- ==========================================
+CREATE TABLE roles (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    slug VARCHAR(255) NOT NULL,
+    name TEXT NOT NULL,
+    permissions JSON, -- Stores []string
+    -- Slug of the role whose permissions this one inherits, e.g. manager -> cashier.
+    -- No foreign key: MySQL won't cascade a rename within one table, so the
+    -- role repository updates children itself.
+    parent VARCHAR(255),
+    "system" BOOLEAN NOT NULL DEFAULT FALSE, -- Seeded; can't be deleted or lose critical permissions
+    deleted_at DATETIME(6),
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    store_id INT NOT NULL DEFAULT 1,
+    CONSTRAINT roles_slug_key UNIQUE (slug, store_id),
+    FOREIGN KEY (store_id) REFERENCES stores(id)
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
+
+-- ==========================================
+-- 6. AUDIT LOG
+-- ==========================================
+-- Who changed which entity and how. entity_id has no foreign key so the
+-- history outlives a purged entity, hence the store_id of its own.
+CREATE TABLE audit_log (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    store_id INT NOT NULL DEFAULT 1,
+    actor_id INT, -- NULL for startup jobs
+    entity_type VARCHAR(32) NOT NULL, -- product, inventory, order, user, role, location, store or tag
+    entity_id INT NOT NULL,
+    action VARCHAR(64) NOT NULL, -- create, update, delete, restore, purge, or one of the entity's own
+    diff JSON,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_audit_log_entity (store_id, entity_type, entity_id, created_at DESC),
+    INDEX idx_audit_log_actor (store_id, actor_id, created_at DESC),
+    INDEX idx_audit_log_created (store_id, created_at DESC),
+    FOREIGN KEY (store_id) REFERENCES stores(id),
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin;
@@ -0,0 +1,25 @@
+-- Drops everything 0001_initial.up.sql created, newest tables first.
+-- SQLite has no CASCADE, so the order matters: children before parents.
+DROP TABLE IF EXISTS role_audit;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS inventory_reservations;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS user_locations;
+DROP TABLE IF EXISTS locations;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS stocktake_counts;
+DROP TABLE IF EXISTS stocktake_sessions;
+DROP TABLE IF EXISTS inventory_supplier_prices;
+DROP TABLE IF EXISTS inventory_movements;
+DROP TABLE IF EXISTS inventory_snapshots;
+DROP TABLE IF EXISTS inventory_tags;
+DROP TABLE IF EXISTS inventory;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS magic_links;
+DROP TABLE IF EXISTS login_attempts;
+DROP TABLE IF EXISTS policy_acceptances;
+DROP TABLE IF EXISTS user_activity;
+DROP TABLE IF EXISTS time_entries;
+DROP TABLE IF EXISTS username_history;
+DROP TABLE IF EXISTS user_devices;
+DROP TABLE IF EXISTS users;
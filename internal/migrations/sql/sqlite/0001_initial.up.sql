@@ -0,0 +1,332 @@
+-- Initial schema, the SQLite version of postgres/0001_initial.up.sql. Keep
+-- the two in step: every migration has a file in both directories.
+--
+-- Differences: INTEGER PRIMARY KEY AUTOINCREMENT for SERIAL (so ids of
+-- deleted rows are never reused), INTEGER (64-bit) for BIGINT,
+-- TIMESTAMP for TIMESTAMPTZ (UTC text, read back as time.Time), TEXT holding
+-- JSON for JSONB, and no GIN index.
+
+-- ==========================================
+-- 1. USERS
+-- ==========================================
+CREATE TABLE users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT NOT NULL, -- Unique regardless of case, see users_username_key
+    display_name TEXT,
+    email TEXT UNIQUE, -- Lowercased; single sign-on identities are matched on it
+    hash TEXT NOT NULL,
+    pin_hash TEXT, -- Optional bcrypt hash of a numeric PIN for quick register switching
+    role TEXT NOT NULL, -- e.g., 'admin', 'clerk'
+    temp_role TEXT, -- Used instead of role until temp_role_expires_at, e.g. acting manager
+    temp_role_expires_at TIMESTAMP,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    token_version INTEGER NOT NULL DEFAULT 0, -- Bumped to invalidate every issued access token
+    must_change_password BOOLEAN NOT NULL DEFAULT FALSE, -- Set when an admin chose the password
+    failed_logins INTEGER NOT NULL DEFAULT 0, -- Consecutive failures since the last success or lockout
+    locked_until TIMESTAMP, -- Login refused until then
+    last_login_at TIMESTAMP,
+    last_login_ip TEXT,
+    avatar_url TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    deleted_at TIMESTAMP, -- Set when anonymized; the row is kept for order history
+    setting TEXT, -- Stores user.Settings (locale, theme, default_printer, receipt_preference)
+    custom TEXT   -- Stores map[string]any
+);
+
+-- "Bob" and "bob" are the same account; logins match case-insensitively too
+CREATE UNIQUE INDEX users_username_key ON users(LOWER(username));
+
+-- Index for searching users
+CREATE INDEX idx_users_role ON users(role);
+CREATE INDEX idx_users_active ON users(active);
+
+-- Remember-me tokens for trusted devices (registers). Like refresh tokens
+-- only the SHA-256 hash is kept; each use pushes expires_at forward.
+CREATE TABLE user_devices (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL, -- e.g. "Front register"
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    last_used_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_user_devices_user ON user_devices(user_id);
+
+-- Names a user went by before being renamed, so receipts and audit entries
+-- printed under an old username can still be traced to the account.
+CREATE TABLE username_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    username TEXT NOT NULL, -- The previous name
+    changed_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')) -- When it stopped being used
+);
+
+CREATE INDEX idx_username_history_user ON username_history(user_id, changed_at DESC);
+CREATE INDEX idx_username_history_username ON username_history(LOWER(username));
+
+-- Timekeeping: one row per shift, clock_out is NULL while clocked in.
+CREATE TABLE time_entries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    clock_in TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    clock_out TIMESTAMP,
+    CHECK (clock_out IS NULL OR clock_out >= clock_in)
+);
+
+CREATE INDEX idx_time_entries_user ON time_entries(user_id, clock_in);
+-- At most one open shift per user
+CREATE UNIQUE INDEX idx_time_entries_open ON time_entries(user_id) WHERE clock_out IS NULL;
+
+-- Security audit trail: logins, password and role changes, deactivation.
+CREATE TABLE user_activity (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE, -- Target account
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL, -- Who performed it
+    action TEXT NOT NULL, -- login, password_change, role_change, deactivate, reactivate, anonymize
+    ip TEXT,
+    detail TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX idx_user_activity_user ON user_activity(user_id, created_at DESC);
+
+-- Staff policy versions each user agreed to
+CREATE TABLE policy_acceptances (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    version TEXT NOT NULL,
+    accepted_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    PRIMARY KEY (user_id, version)
+);
+
+-- Every sign-in attempt, successful or not, for security review. Attempts on
+-- unknown usernames are kept too, with user_id NULL.
+CREATE TABLE login_attempts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    username TEXT NOT NULL, -- As typed, empty for device tokens that matched nobody
+    method TEXT NOT NULL, -- password, pin, device, oidc, magic_link
+    outcome TEXT NOT NULL, -- success, invalid_credentials, locked, inactive, captcha_required, error
+    ip TEXT,
+    user_agent TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX idx_login_attempts_user ON login_attempts(user_id, created_at DESC);
+CREATE INDEX idx_login_attempts_username ON login_attempts(LOWER(username), created_at DESC);
+
+-- Single-use passwordless login links sent by email, stored hashed.
+CREATE TABLE magic_links (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP, -- Set on the first (and only) use
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX idx_magic_links_user_id ON magic_links(user_id);
+
+-- Long-lived refresh tokens, stored as SHA-256 hashes. Each one is single-use:
+-- refreshing revokes it and issues a replacement.
+CREATE TABLE refresh_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP, -- Set when rotated or revoked
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+
+-- ==========================================
+-- 2. INVENTORY
+-- ==========================================
+CREATE TABLE inventory (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    "desc" TEXT, -- "desc" is a reserved keyword in SQL, so it must be quoted
+    label TEXT,
+    tags TEXT NOT NULL DEFAULT '[]', -- Array of strings
+    stock INTEGER NOT NULL DEFAULT 0,
+    min_stock INTEGER NOT NULL DEFAULT 0, -- Reorder threshold
+    max_stock INTEGER NOT NULL DEFAULT 0, -- Upper par level, 0 = no ceiling
+    unit_cost INTEGER NOT NULL DEFAULT 0, -- Weighted average cost per unit
+    barcode TEXT UNIQUE, -- NULL when unset so multiple items can lack one
+    custom TEXT,
+    deleted_at TIMESTAMP -- Soft delete; NULL while the item is active
+);
+
+-- Indexes for filtering and searching
+CREATE INDEX idx_inventory_label ON inventory(label);
+CREATE INDEX idx_inventory_active ON inventory(id) WHERE deleted_at IS NULL;
+
+-- Curated tags and labels for inventory items
+CREATE TABLE inventory_tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL, -- 'tag' or 'label'
+    name TEXT NOT NULL,
+    UNIQUE (kind, name)
+);
+
+-- Daily stock levels, one row per item per day (upserted by the snapshot job)
+CREATE TABLE inventory_snapshots (
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    taken_on DATE NOT NULL,
+    stock INTEGER NOT NULL,
+    PRIMARY KEY (inventory_id, taken_on)
+);
+
+CREATE INDEX idx_inventory_snapshots_taken_on ON inventory_snapshots(taken_on);
+
+-- Stock ledger: every manual adjustment with its reason and author
+CREATE TABLE inventory_movements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    delta INTEGER NOT NULL,
+    stock_after INTEGER NOT NULL,
+    reason TEXT NOT NULL, -- e.g., 'received', 'waste', 'stocktake'
+    note TEXT NOT NULL DEFAULT '',
+    unit_cost INTEGER NOT NULL DEFAULT 0, -- Cost per unit on receiving
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+-- Supplier quotes per item, used for price comparison and reordering
+CREATE TABLE inventory_supplier_prices (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    supplier TEXT NOT NULL,
+    unit_price INTEGER NOT NULL DEFAULT 0,
+    lead_time_days INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (inventory_id, supplier)
+);
+
+CREATE INDEX idx_inventory_movements_inventory_id ON inventory_movements(inventory_id, created_at);
+
+-- Stocktake (physical count) sessions. Closing a session records the
+-- expected stock next to each count and corrects stock to the counted value.
+CREATE TABLE stocktake_sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    status TEXT NOT NULL DEFAULT 'open', -- 'open' or 'closed'
+    note TEXT NOT NULL DEFAULT '',
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    closed_at TIMESTAMP
+);
+
+CREATE TABLE stocktake_counts (
+    session_id INTEGER NOT NULL REFERENCES stocktake_sessions(id) ON DELETE CASCADE,
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    counted INTEGER NOT NULL,
+    expected INTEGER, -- System stock at close, NULL while the session is open
+    unit_cost INTEGER NOT NULL DEFAULT 0, -- Unit cost at close, to value the variance
+    PRIMARY KEY (session_id, inventory_id)
+);
+
+-- ==========================================
+-- 3. PRODUCTS
+-- ==========================================
+CREATE TABLE products (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    "desc" TEXT,
+    tag TEXT,
+    label TEXT,
+    price INTEGER NOT NULL DEFAULT 0,
+    avail BOOLEAN NOT NULL DEFAULT TRUE,
+    auto_86 BOOLEAN NOT NULL DEFAULT FALSE, -- Avail was switched off by stock depletion, not by hand
+    items TEXT,  -- Array of strings (slugs) for bundles
+    recipe TEXT, -- Map of string:int for inventory usage
+    custom TEXT
+);
+
+-- Indexes for filtering
+CREATE INDEX idx_products_tag ON products(tag);
+CREATE INDEX idx_products_label ON products(label);
+CREATE INDEX idx_products_price ON products(price);
+CREATE INDEX idx_products_avail ON products(avail);
+
+-- ==========================================
+-- 4. ORDERS
+-- ==========================================
+-- Stores; clerks only see orders from the locations they're assigned to
+CREATE TABLE locations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    address TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE user_locations (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    location_id INTEGER NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, location_id)
+);
+
+CREATE TABLE orders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    items TEXT NOT NULL, -- Stores []string (product slugs)
+    clerk_id INTEGER NOT NULL REFERENCES users(id), -- Users are anonymized, never deleted
+    location_id INTEGER REFERENCES locations(id), -- Store it was rung up at, NULL for older orders
+    total INTEGER NOT NULL DEFAULT 0,
+    paid INTEGER NOT NULL DEFAULT 0,
+    change INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'open', -- 'open' or 'void'
+    custom TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+-- Indexes for reporting and history
+CREATE INDEX idx_orders_clerk_id ON orders(clerk_id);
+CREATE INDEX idx_orders_location_id ON orders(location_id);
+CREATE INDEX idx_orders_created_at ON orders(created_at);
+CREATE INDEX idx_orders_total ON orders(total);
+CREATE INDEX idx_orders_status ON orders(status);
+
+-- Stock held for an order until it is voided or the hold expires
+CREATE TABLE inventory_reservations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id) ON DELETE CASCADE,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX idx_inventory_reservations_inventory_id ON inventory_reservations(inventory_id);
+CREATE INDEX idx_inventory_reservations_order_id ON inventory_reservations(order_id);
+CREATE INDEX idx_inventory_reservations_expires_at ON inventory_reservations(expires_at);
+
+-- ==========================================
+-- 5. ROLES
+-- ==========================================
+CREATE TABLE roles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    permissions TEXT, -- Stores []string
+    -- Slug of the role whose permissions this one inherits, e.g. manager -> cashier
+    parent TEXT REFERENCES roles(slug) ON UPDATE CASCADE ON DELETE SET NULL,
+    system BOOLEAN NOT NULL DEFAULT FALSE -- Seeded; can't be deleted or lose critical permissions
+);
+
+CREATE INDEX idx_roles_slug ON roles(slug);
+
+-- Who changed which role and how. role_id has no foreign key so the history
+-- outlives a deleted role.
+CREATE TABLE role_audit (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    role_id INTEGER NOT NULL,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL, -- NULL for startup seeding
+    action TEXT NOT NULL, -- create, update, delete, permission_add, permission_remove
+    detail TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX idx_role_audit_role ON role_audit(role_id, created_at DESC);
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE orders DROP COLUMN revision;
+ALTER TABLE users DROP COLUMN revision;
+ALTER TABLE inventory DROP COLUMN revision;
+ALTER TABLE products DROP COLUMN revision;
@@ -0,0 +1,5 @@
+-- Trashed products and roles come back; trashed users stay deleted.
+DROP INDEX idx_products_active;
+ALTER TABLE users DROP COLUMN anonymized_at;
+ALTER TABLE roles DROP COLUMN deleted_at;
+ALTER TABLE products DROP COLUMN deleted_at;
@@ -0,0 +1,8 @@
+-- Soft delete for products and roles, as in postgres/0003_soft_delete.up.sql.
+ALTER TABLE products ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE roles ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN anonymized_at TIMESTAMP;
+
+UPDATE users SET anonymized_at = deleted_at WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX idx_products_active ON products(id) WHERE deleted_at IS NULL;
@@ -0,0 +1,20 @@
+-- Archived orders, as in postgres/0005_orders_archive.up.sql.
+CREATE TABLE orders_archive (
+    id INTEGER PRIMARY KEY, -- From orders, never reused
+    items TEXT NOT NULL,
+    clerk_id INTEGER NOT NULL REFERENCES users(id),
+    location_id INTEGER REFERENCES locations(id),
+    total INTEGER NOT NULL,
+    paid INTEGER NOT NULL,
+    change INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    custom TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    revision INTEGER NOT NULL,
+    archived_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX idx_orders_archive_clerk_id ON orders_archive(clerk_id);
+CREATE INDEX idx_orders_archive_location_id ON orders_archive(location_id);
+CREATE INDEX idx_orders_archive_created_at ON orders_archive(created_at);
@@ -0,0 +1,181 @@
+-- Stores, as in postgres/0006_stores.up.sql. SQLite can't change a
+-- table's unique constraints in place, so the tables that have them are
+-- rebuilt: created anew with store_id, filled from the old one, which is
+-- dropped, and renamed. The migrator turns foreign keys off while it runs,
+-- so dropping the old tables leaves the rows pointing at them alone, and
+-- checks them all before committing. Each table keeps its id sequence.
+CREATE TABLE stores (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug TEXT NOT NULL UNIQUE, -- Named by the X-Store header
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+INSERT INTO stores (id, slug, name) VALUES (1, 'default', 'Default');
+
+ALTER TABLE login_attempts ADD COLUMN store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id);
+ALTER TABLE stocktake_sessions ADD COLUMN store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id);
+ALTER TABLE orders ADD COLUMN store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id);
+ALTER TABLE orders_archive ADD COLUMN store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id);
+CREATE INDEX idx_orders_store_id ON orders(store_id, created_at);
+CREATE INDEX idx_orders_archive_store_id ON orders_archive(store_id, created_at);
+
+CREATE TABLE users_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT NOT NULL, -- Unique regardless of case within a store, see users_username_key
+    display_name TEXT,
+    email TEXT, -- Lowercased; single sign-on identities are matched on it
+    hash TEXT NOT NULL,
+    pin_hash TEXT, -- Optional bcrypt hash of a numeric PIN for quick register switching
+    role TEXT NOT NULL, -- e.g., 'admin', 'clerk'
+    temp_role TEXT, -- Used instead of role until temp_role_expires_at, e.g. acting manager
+    temp_role_expires_at TIMESTAMP,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    token_version INTEGER NOT NULL DEFAULT 0, -- Bumped to invalidate every issued access token
+    must_change_password BOOLEAN NOT NULL DEFAULT FALSE, -- Set when an admin chose the password
+    failed_logins INTEGER NOT NULL DEFAULT 0, -- Consecutive failures since the last success or lockout
+    locked_until TIMESTAMP, -- Login refused until then
+    last_login_at TIMESTAMP,
+    last_login_ip TEXT,
+    avatar_url TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00+00:00',
+    deleted_at TIMESTAMP, -- Set when deleted; the row is kept for order history
+    anonymized_at TIMESTAMP,
+    setting TEXT, -- Stores user.Settings (locale, theme, default_printer, receipt_preference)
+    custom TEXT, -- Stores map[string]any
+    revision INTEGER NOT NULL DEFAULT 1,
+    store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id),
+    UNIQUE (email, store_id)
+);
+INSERT INTO users_new (id, username, display_name, email, hash, pin_hash, role, temp_role, temp_role_expires_at, active, token_version, must_change_password, failed_logins, locked_until, last_login_at, last_login_ip, avatar_url, created_at, updated_at, deleted_at, anonymized_at, setting, custom, revision)
+SELECT id, username, display_name, email, hash, pin_hash, role, temp_role, temp_role_expires_at, active, token_version, must_change_password, failed_logins, locked_until, last_login_at, last_login_ip, avatar_url, created_at, updated_at, deleted_at, anonymized_at, setting, custom, revision FROM users;
+DELETE FROM sqlite_sequence WHERE name = 'users_new';
+UPDATE sqlite_sequence SET name = 'users_new' WHERE name = 'users';
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+CREATE UNIQUE INDEX users_username_key ON users(LOWER(username), store_id);
+CREATE INDEX idx_users_role ON users(role);
+CREATE INDEX idx_users_active ON users(active);
+CREATE INDEX idx_users_store_id ON users(store_id);
+
+CREATE TABLE roles_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug TEXT NOT NULL,
+    name TEXT NOT NULL,
+    permissions TEXT, -- Stores []string
+    -- Slug of the role whose permissions this one inherits, e.g. manager -> cashier.
+    -- The role repository clears children before a role is purged.
+    parent TEXT,
+    system BOOLEAN NOT NULL DEFAULT FALSE, -- Seeded; can't be deleted or lose critical permissions
+    created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00+00:00',
+    updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00+00:00',
+    deleted_at TIMESTAMP,
+    store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id),
+    UNIQUE (slug, store_id),
+    FOREIGN KEY (parent, store_id) REFERENCES roles(slug, store_id) ON UPDATE CASCADE
+);
+INSERT INTO roles_new (id, slug, name, permissions, parent, system, created_at, updated_at, deleted_at)
+SELECT id, slug, name, permissions, parent, system, created_at, updated_at, deleted_at FROM roles;
+DELETE FROM sqlite_sequence WHERE name = 'roles_new';
+UPDATE sqlite_sequence SET name = 'roles_new' WHERE name = 'roles';
+DROP TABLE roles;
+ALTER TABLE roles_new RENAME TO roles;
+CREATE INDEX idx_roles_slug ON roles(slug);
+CREATE INDEX idx_roles_store_id ON roles(store_id);
+
+CREATE TABLE locations_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug TEXT NOT NULL,
+    name TEXT NOT NULL,
+    address TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00+00:00',
+    updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00+00:00',
+    store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id),
+    UNIQUE (slug, store_id)
+);
+INSERT INTO locations_new (id, slug, name, address, created_at, updated_at)
+SELECT id, slug, name, address, created_at, updated_at FROM locations;
+DELETE FROM sqlite_sequence WHERE name = 'locations_new';
+UPDATE sqlite_sequence SET name = 'locations_new' WHERE name = 'locations';
+DROP TABLE locations;
+ALTER TABLE locations_new RENAME TO locations;
+
+CREATE TABLE inventory_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug TEXT NOT NULL,
+    name TEXT NOT NULL,
+    "desc" TEXT, -- "desc" is a reserved keyword in SQL, so it must be quoted
+    label TEXT,
+    tags TEXT NOT NULL DEFAULT '[]', -- Array of strings
+    stock INTEGER NOT NULL DEFAULT 0,
+    min_stock INTEGER NOT NULL DEFAULT 0, -- Reorder threshold
+    max_stock INTEGER NOT NULL DEFAULT 0, -- Upper par level, 0 = no ceiling
+    unit_cost INTEGER NOT NULL DEFAULT 0, -- Weighted average cost per unit
+    barcode TEXT, -- NULL when unset so multiple items can lack one
+    custom TEXT,
+    revision INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00+00:00',
+    updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00+00:00',
+    deleted_at TIMESTAMP, -- Soft delete; NULL while the item is active
+    store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id),
+    UNIQUE (slug, store_id),
+    UNIQUE (barcode, store_id)
+);
+INSERT INTO inventory_new (id, slug, name, "desc", label, tags, stock, min_stock, max_stock, unit_cost, barcode, custom, revision, created_at, updated_at, deleted_at)
+SELECT id, slug, name, "desc", label, tags, stock, min_stock, max_stock, unit_cost, barcode, custom, revision, created_at, updated_at, deleted_at FROM inventory;
+DELETE FROM sqlite_sequence WHERE name = 'inventory_new';
+UPDATE sqlite_sequence SET name = 'inventory_new' WHERE name = 'inventory';
+DROP TABLE inventory;
+ALTER TABLE inventory_new RENAME TO inventory;
+CREATE INDEX idx_inventory_label ON inventory(label);
+CREATE INDEX idx_inventory_active ON inventory(id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_inventory_store_id ON inventory(store_id);
+
+CREATE TABLE inventory_tags_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL, -- 'tag' or 'label'
+    name TEXT NOT NULL,
+    store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id),
+    UNIQUE (kind, name, store_id)
+);
+INSERT INTO inventory_tags_new (id, kind, name)
+SELECT id, kind, name FROM inventory_tags;
+DELETE FROM sqlite_sequence WHERE name = 'inventory_tags_new';
+UPDATE sqlite_sequence SET name = 'inventory_tags_new' WHERE name = 'inventory_tags';
+DROP TABLE inventory_tags;
+ALTER TABLE inventory_tags_new RENAME TO inventory_tags;
+
+CREATE TABLE products_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug TEXT NOT NULL,
+    name TEXT NOT NULL,
+    "desc" TEXT,
+    tag TEXT,
+    label TEXT,
+    price INTEGER NOT NULL DEFAULT 0,
+    avail BOOLEAN NOT NULL DEFAULT TRUE,
+    auto_86 BOOLEAN NOT NULL DEFAULT FALSE, -- Avail was switched off by stock depletion, not by hand
+    items TEXT, -- Array of strings (slugs) for bundles
+    recipe TEXT, -- Map of string:int for inventory usage
+    custom TEXT,
+    revision INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00+00:00',
+    updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00+00:00',
+    deleted_at TIMESTAMP,
+    store_id INTEGER NOT NULL DEFAULT 1 REFERENCES stores(id),
+    UNIQUE (slug, store_id)
+);
+INSERT INTO products_new (id, slug, name, "desc", tag, label, price, avail, auto_86, items, recipe, custom, revision, created_at, updated_at, deleted_at)
+SELECT id, slug, name, "desc", tag, label, price, avail, auto_86, items, recipe, custom, revision, created_at, updated_at, deleted_at FROM products;
+DELETE FROM sqlite_sequence WHERE name = 'products_new';
+UPDATE sqlite_sequence SET name = 'products_new' WHERE name = 'products';
+DROP TABLE products;
+ALTER TABLE products_new RENAME TO products;
+CREATE INDEX idx_products_tag ON products(tag);
+CREATE INDEX idx_products_label ON products(label);
+CREATE INDEX idx_products_price ON products(price);
+CREATE INDEX idx_products_avail ON products(avail);
+CREATE INDEX idx_products_active ON products(id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_products_store_id ON products(store_id);
@@ -0,0 +1,16 @@
+-- Role entries go back to role_audit; the rest of the log is lost.
+CREATE TABLE role_audit (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    role_id INTEGER NOT NULL,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL,
+    detail TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX idx_role_audit_role ON role_audit(role_id, created_at DESC);
+
+INSERT INTO role_audit (role_id, actor_id, action, detail, created_at)
+SELECT entity_id, actor_id, action, diff, created_at FROM audit_log WHERE entity_type = 'role' ORDER BY id;
+
+DROP TABLE audit_log;
@@ -0,0 +1,331 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/utils"
+)
+
+// Entry is the per-key state a Store keeps: the window of a Limiter, or the
+// bucket of a TokenBucket.
+type Entry struct {
+	Count        int // Requests in the current window
+	WindowStart  time.Time
+	Strikes      int // Consecutive windows that went over the limit
+	BlockedUntil time.Time
+
+	Tokens   float64 // Left in the bucket as of Refilled
+	Refilled time.Time
+}
+
+// lastSeen is when the key was last counted.
+func (e *Entry) lastSeen() time.Time {
+	if e.Refilled.After(e.WindowStart) {
+		return e.Refilled
+	}
+	return e.WindowStart
+}
+
+// Store persists limiter state. Update must apply fn atomically per key so
+// concurrent requests can't both slip under the limit. A shared store (e.g.
+// Redis) lets several server instances enforce one limit.
+type Store interface {
+	Update(ctx context.Context, key string, fn func(e *Entry)) (Entry, error)
+}
+
+// Policy allows Limit requests per Window. Going over blocks the key for
+// BaseBackoff, doubling with every further strike up to MaxBackoff. A Limit
+// of 0 disables the limiter.
+type Policy struct {
+	Limit       int
+	Window      time.Duration
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Decision is the outcome of one check.
+type Decision struct {
+	Allowed    bool
+	RetryAfter time.Duration
+}
+
+// Allower is anything that counts requests against a key: a Limiter or a
+// TokenBucket.
+type Allower interface {
+	Allow(ctx context.Context, key string) (Decision, error)
+}
+
+type Limiter struct {
+	store  Store
+	policy Policy
+}
+
+func NewLimiter(store Store, policy Policy) *Limiter {
+	return &Limiter{store: store, policy: policy}
+}
+
+// Allow counts a request against key and reports whether it may proceed.
+func (l *Limiter) Allow(ctx context.Context, key string) (Decision, error) {
+	now := time.Now()
+	p := l.policy
+	if p.Limit <= 0 {
+		return Decision{Allowed: true}, nil
+	}
+
+	e, err := l.store.Update(ctx, key, func(e *Entry) {
+		if now.Before(e.BlockedUntil) {
+			return
+		}
+
+		if now.Sub(e.WindowStart) >= p.Window {
+			// A clean window forgives earlier strikes
+			if e.Count <= p.Limit {
+				e.Strikes = 0
+			}
+			e.WindowStart, e.Count = now, 0
+		}
+
+		e.Count++
+		if e.Count > p.Limit {
+			e.Strikes++
+			e.BlockedUntil = now.Add(backoff(p, e.Strikes))
+			e.WindowStart, e.Count = now, 0
+		}
+	})
+	if errors.Is(err, ErrContended) {
+		return Decision{RetryAfter: p.BaseBackoff}, nil
+	}
+	if err != nil {
+		return Decision{}, err
+	}
+
+	if now.Before(e.BlockedUntil) {
+		return Decision{RetryAfter: e.BlockedUntil.Sub(now)}, nil
+	}
+	return Decision{Allowed: true}, nil
+}
+
+func backoff(p Policy, strikes int) time.Duration {
+	d := p.BaseBackoff
+	for i := 1; i < strikes && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxBackoff)
+}
+
+// Bucket allows bursts of up to Burst requests, and one more every Refill
+// after that. A Burst of 0 disables the bucket.
+type Bucket struct {
+	Burst  int
+	Refill time.Duration
+}
+
+// TokenBucket smooths traffic rather than blocking it: a client over its
+// rate waits for the next token, with no backoff.
+type TokenBucket struct {
+	store  Store
+	bucket Bucket
+}
+
+func NewTokenBucket(store Store, bucket Bucket) *TokenBucket {
+	return &TokenBucket{store: store, bucket: bucket}
+}
+
+// Allow takes a token from the bucket of key, if there is one.
+func (t *TokenBucket) Allow(ctx context.Context, key string) (Decision, error) {
+	now := time.Now()
+	b := t.bucket
+	if b.Burst <= 0 || b.Refill <= 0 {
+		return Decision{Allowed: true}, nil
+	}
+
+	var allowed bool
+	var tokens float64
+	var err error
+	if ts, ok := t.store.(tokenStore); ok {
+		allowed, tokens, err = ts.take(ctx, key, b)
+	} else {
+		var e Entry
+		e, err = t.store.Update(ctx, key, func(e *Entry) {
+			// A new key starts with a full bucket
+			if e.Refilled.IsZero() {
+				e.Tokens = float64(b.Burst)
+			} else {
+				e.Tokens = min(float64(b.Burst), e.Tokens+float64(now.Sub(e.Refilled))/float64(b.Refill))
+			}
+			e.Refilled = now
+
+			allowed = e.Tokens >= 1
+			if allowed {
+				e.Tokens--
+			}
+		})
+		tokens = e.Tokens
+	}
+	if errors.Is(err, ErrContended) {
+		return Decision{RetryAfter: b.Refill}, nil
+	}
+	if err != nil {
+		return Decision{}, err
+	}
+
+	if !allowed {
+		return Decision{RetryAfter: time.Duration((1 - tokens) * float64(b.Refill))}, nil
+	}
+	return Decision{Allowed: true}, nil
+}
+
+// tokenStore is a Store that keeps buckets itself, taking tokens
+// atomically without a read-modify-write round trip.
+type tokenStore interface {
+	take(ctx context.Context, key string, b Bucket) (allowed bool, tokens float64, err error)
+}
+
+// KeyFunc derives the rate limit key from a request; "" skips the check.
+type KeyFunc func(r *http.Request) string
+
+// ByIP keys on the client address set by the ClientIP middleware.
+func ByIP(r *http.Request) string {
+	ip, _ := r.Context().Value(utils.ClientIPKey).(string)
+	if ip == "" {
+		return ""
+	}
+	return "ip:" + ip
+}
+
+// ByUser keys on the user the Auth middleware identified, so it only works
+// behind it.
+func ByUser(r *http.Request) string {
+	id, ok := r.Context().Value(utils.UserIDKey).(int)
+	if !ok {
+		return ""
+	}
+	return "user:" + strconv.Itoa(id)
+}
+
+// ByToken keys on the bearer token, so every API token (and every session)
+// has its own limit. Only a hash of the token is kept.
+func ByToken(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return "token:" + hex.EncodeToString(sum[:16])
+}
+
+// ByJSONField keys on a string field of a JSON body (e.g. "username"). The
+// body is restored so the handler can still read it.
+func ByJSONField(field string) KeyFunc {
+	return func(r *http.Request) string {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return ""
+		}
+
+		var fields map[string]any
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return ""
+		}
+		v, _ := fields[field].(string)
+		if v == "" {
+			return ""
+		}
+		return field + ":" + strings.ToLower(v)
+	}
+}
+
+// Rule pairs a limiter with the key it applies to.
+type Rule struct {
+	Limiter Allower
+	Key     KeyFunc
+}
+
+// Middleware rejects requests with 429 once any rule is exhausted. Store
+// errors fail open: a broken limiter shouldn't lock everybody out. A key
+// too contended to update (ErrContended) is refused rather than an error.
+func Middleware(scope string, rules ...Rule) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, rule := range rules {
+				key := rule.Key(r)
+				if key == "" {
+					continue
+				}
+
+				d, err := rule.Limiter.Allow(r.Context(), scope+"|"+key)
+				if err != nil {
+					log.Printf("ratelimit: %v", err)
+					continue
+				}
+				if !d.Allowed {
+					tooManyRequests(w, d.RetryAfter)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	secs := int(retryAfter.Seconds()) + 1
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error":       "too many requests",
+		"retry_after": secs,
+	})
+}
+
+// MemoryStore keeps state in process memory. Entries idle for longer than
+// ttl are swept so the map doesn't grow without bound; a swept bucket comes
+// back full, so ttl must outlast the time a bucket takes to refill.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*Entry
+	ttl     time.Duration
+	swept   time.Time
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*Entry), ttl: ttl, swept: time.Now()}
+}
+
+func (s *MemoryStore) Update(ctx context.Context, key string, fn func(e *Entry)) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.swept) > s.ttl {
+		for k, e := range s.entries {
+			if now.Sub(e.lastSeen()) > s.ttl && now.After(e.BlockedUntil) {
+				delete(s.entries, k)
+			}
+		}
+		s.swept = now
+	}
+
+	e, ok := s.entries[key]
+	if !ok {
+		e = &Entry{}
+		s.entries[key] = e
+	}
+	fn(e)
+	return *e, nil
+}
@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrContended is returned when other instances kept changing a key for
+// as long as Update retried. Limiters count it as a refusal, not a store
+// failure: a flood on one key mustn't be what lets it through.
+var ErrContended = errors.New("ratelimit: key changed during every update attempt")
+
+// takeScript refills and takes from a bucket in one step, on Redis' clock
+// so instances with skewed clocks still agree. KEYS[1] is the bucket; ARGV
+// are the burst, the refill interval in microseconds and the ttl in
+// milliseconds. It answers whether a token was taken and how many are left.
+var takeScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local refill = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local tokens = burst
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'refilled')
+if b[1] and b[2] then
+	tokens = math.min(burst, tonumber(b[1]) + (now - tonumber(b[2])) / refill)
+end
+
+local taken = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	taken = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', string.format('%.17g', tokens), 'refilled', string.format('%d', now))
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return {taken, string.format('%.17g', tokens)}
+`)
+
+// RedisStore keeps state in Redis, so every server instance sharing it
+// enforces one limit. TokenBucket runs as a script, atomic in Redis itself.
+// Update is optimistic instead: it WATCHes the key and retries when another
+// instance changed it in between, giving up with ErrContended. Within one
+// instance updates of a key take turns, so they don't use up each other's
+// retries. Keys expire ttl after they were last counted, which like
+// MemoryStore's ttl must outlast a refill.
+type RedisStore struct {
+	client *redis.Client
+	ttl    time.Duration
+	locks  [64]sync.Mutex // By key hash
+}
+
+// NewRedisStore connects lazily to the Redis at rawURL,
+// redis://[user:password@]host[:port][/db], or rediss:// for TLS.
+func NewRedisStore(rawURL string, ttl time.Duration) (*RedisStore, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url %q: %w", rawURL, err)
+	}
+	return &RedisStore{client: redis.NewClient(opts), ttl: ttl}, nil
+}
+
+// Ping checks that Redis can be reached.
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+func (s *RedisStore) Update(ctx context.Context, key string, fn func(e *Entry)) (Entry, error) {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	lock := &s.locks[h.Sum32()%uint32(len(s.locks))]
+	lock.Lock()
+	defer lock.Unlock()
+
+	key = "ratelimit:" + key
+	var e Entry
+	update := func(tx *redis.Tx) error {
+		e = Entry{}
+		data, err := tx.Get(ctx, key).Bytes()
+		switch {
+		case errors.Is(err, redis.Nil):
+		case err != nil:
+			return err
+		default:
+			if err := json.Unmarshal(data, &e); err != nil {
+				return fmt.Errorf("ratelimit: bad entry for %s: %w", key, err)
+			}
+		}
+
+		fn(&e)
+		enc, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.Set(ctx, key, enc, s.ttl)
+			return nil
+		})
+		return err
+	}
+
+	for range 5 {
+		err := s.client.Watch(ctx, update, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return e, err
+		}
+	}
+	return e, ErrContended
+}
+
+// take takes a token from the bucket of key, reporting whether there was
+// one and how many are left.
+func (s *RedisStore) take(ctx context.Context, key string, b Bucket) (bool, float64, error) {
+	args := []any{b.Burst, b.Refill.Microseconds(), s.ttl.Milliseconds()}
+	res, err := takeScript.Run(ctx, s.client, []string{"ratelimit:bucket:" + key}, args...).Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(res) != 2 {
+		return false, 0, fmt.Errorf("ratelimit: bad bucket reply for %s: %v", key, res)
+	}
+	taken, _ := res[0].(int64)
+	left, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(left, 64)
+	if err != nil {
+		return false, 0, fmt.Errorf("ratelimit: bad bucket for %s: %q", key, left)
+	}
+	return taken == 1, tokens, nil
+}
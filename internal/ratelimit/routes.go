@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+type checkedKey struct{}
+
+// Routes applies rules to the requests of chosen routes, given as
+// http.ServeMux patterns such as "POST /api/v1/orders"; where several
+// patterns match a request, the most specific one wins, as it would in a
+// mux.
+//
+// Its middleware can sit both before and after authentication: each rule
+// is checked by the first one that can key it, so ByIP rules are counted
+// before the credentials are checked and ByUser rules once they have been,
+// but none twice.
+type Routes struct {
+	mux   *http.ServeMux
+	rules map[string][]Rule
+}
+
+func NewRoutes() *Routes {
+	return &Routes{mux: http.NewServeMux(), rules: map[string][]Rule{}}
+}
+
+// Add limits the requests matching pattern with rule. Like
+// http.ServeMux.Handle, it panics on an invalid pattern.
+func (rt *Routes) Add(pattern string, rule Rule) {
+	if _, ok := rt.rules[pattern]; !ok {
+		rt.mux.Handle(pattern, http.NotFoundHandler())
+	}
+	rt.rules[pattern] = append(rt.rules[pattern], rule)
+}
+
+// Middleware rejects requests with 429 once a rule of their route is
+// exhausted, failing open like Middleware. prefix is what an
+// http.StripPrefix in front of it took off the path, so the patterns keep
+// naming full paths.
+func (rt *Routes) Middleware(prefix string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			checked, ok := r.Context().Value(checkedKey{}).(map[string]bool)
+			if !ok {
+				checked = map[string]bool{}
+				r = r.WithContext(context.WithValue(r.Context(), checkedKey{}, checked))
+			}
+
+			match := r
+			if prefix != "" {
+				match = r.WithContext(r.Context())
+				match.URL = &url.URL{Path: prefix + r.URL.Path}
+			}
+			_, pattern := rt.mux.Handler(match)
+
+			for i, rule := range rt.rules[pattern] {
+				scope := fmt.Sprintf("%s#%d", pattern, i)
+				if checked[scope] {
+					continue
+				}
+				key := rule.Key(r)
+				if key == "" {
+					continue
+				}
+				checked[scope] = true
+
+				d, err := rule.Limiter.Allow(r.Context(), scope+"|"+key)
+				if err != nil {
+					log.Printf("ratelimit: %v", err)
+					continue
+				}
+				if !d.Allowed {
+					tooManyRequests(w, d.RetryAfter)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// AWS reads secrets from one AWS Secrets Manager secret holding a JSON
+// object, each secret being a key of it (the console's key/value form).
+// Credentials are found the way the AWS CLI finds them: the environment,
+// the shared config files, then the ECS task, EKS service account (IRSA)
+// or EC2 instance role.
+type AWS struct {
+	secretID string
+	client   *secretsmanager.Client
+}
+
+// NewAWS loads the default AWS configuration for region. endpoint, if set,
+// replaces the regional one.
+func NewAWS(ctx context.Context, region, secretID, endpoint string) (*AWS, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("aws: failed to load configuration: %w", err)
+	}
+	client := secretsmanager.NewFromConfig(cfg, func(o *secretsmanager.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+	return &AWS{secretID: secretID, client: client}, nil
+}
+
+func (a *AWS) Get(ctx context.Context, name string) (string, error) {
+	out, err := a.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(a.secretID)})
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("aws: %w", err)
+	}
+
+	var values map[string]any
+	if err := json.Unmarshal([]byte(aws.ToString(out.SecretString)), &values); err != nil {
+		return "", fmt.Errorf("aws: secret %s is not a JSON object", a.secretID)
+	}
+	return lookup(values, name)
+}
@@ -0,0 +1,161 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+var ErrNotFound = errors.New("secret not found")
+
+// Source looks secrets up by name. Names are those of the environment
+// variables they replace (JWT_SECRET, DB_DSN, SMTP_PASSWORD, ...), so a
+// Vault or AWS secret holds them as keys and Env reads them as before.
+// Get returns ErrNotFound for a name the source doesn't hold.
+type Source interface {
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// Env reads secrets from environment variables. Empty ones count as unset.
+type Env struct{}
+
+func (Env) Get(_ context.Context, name string) (string, error) {
+	if v := os.Getenv(name); v != "" {
+		return v, nil
+	}
+	return "", ErrNotFound
+}
+
+// Chain tries each source in turn, moving on only when one doesn't hold
+// the secret. Any other error stops it, so a store that can't be reached
+// isn't mistaken for an empty one.
+type Chain []Source
+
+func (c Chain) Get(ctx context.Context, name string) (string, error) {
+	for _, src := range c {
+		v, err := src.Get(ctx, name)
+		if !errors.Is(err, ErrNotFound) {
+			return v, err
+		}
+	}
+	return "", ErrNotFound
+}
+
+// Options picks a Source. Vault's token comes from VAULT_TOKEN unless
+// VaultAuth logs in for one, an AppRole's ids from VAULT_ROLE_ID and
+// VAULT_SECRET_ID; AWS finds its credentials as its SDK does.
+type Options struct {
+	Kind           string // env, vault or aws
+	VaultAddr      string
+	VaultMount     string // KV version 2 engine
+	VaultPath      string
+	VaultAuth      string // token (the default), kubernetes or approle
+	VaultAuthMount string // Defaults to the method's name
+	VaultRole      string // The Vault role for kubernetes auth
+	AWSRegion      string
+	AWSSecretID    string
+	AWSEndpoint    string // Defaults to the regional endpoint
+}
+
+// New builds the source opts names. Vault and AWS fall back to the
+// environment for names their secret doesn't hold.
+func New(opts Options) (Source, error) {
+	switch strings.ToLower(opts.Kind) {
+	case "", "env":
+		return Env{}, nil
+	case "vault":
+		if opts.VaultAddr == "" || opts.VaultPath == "" {
+			return nil, errors.New("vault needs an address and a secret path")
+		}
+		if opts.VaultAuth == "" || opts.VaultAuth == "token" {
+			v := NewVault(opts.VaultAddr, os.Getenv("VAULT_TOKEN"), opts.VaultMount, opts.VaultPath)
+			return Chain{v, Env{}}, nil
+		}
+		v, err := NewVaultLogin(opts.VaultAddr, VaultAuth{
+			Method:   opts.VaultAuth,
+			Mount:    opts.VaultAuthMount,
+			Role:     opts.VaultRole,
+			RoleID:   os.Getenv("VAULT_ROLE_ID"),
+			SecretID: os.Getenv("VAULT_SECRET_ID"),
+		}, opts.VaultMount, opts.VaultPath)
+		if err != nil {
+			return nil, err
+		}
+		return Chain{v, Env{}}, nil
+	case "aws":
+		if opts.AWSRegion == "" || opts.AWSSecretID == "" {
+			return nil, errors.New("aws needs a region and a secret id")
+		}
+		a, err := NewAWS(context.Background(), opts.AWSRegion, opts.AWSSecretID, opts.AWSEndpoint)
+		if err != nil {
+			return nil, err
+		}
+		return Chain{a, Env{}}, nil
+	default:
+		return nil, fmt.Errorf("unknown secret source %q, want env, vault or aws", opts.Kind)
+	}
+}
+
+// Secret is one named secret, read once by Fetch and kept current by
+// Refresh for the values that can change under a running server:
+//
+//	jwt, err := secrets.Fetch(ctx, src, "JWT_SECRET")
+//	jwt.OnChange(func(v string) { keys.AddHMAC("", []byte(v)) })
+//	go runEvery(interval, func() { jwt.Refresh(ctx) })
+type Secret struct {
+	src  Source
+	name string
+
+	mu       sync.RWMutex
+	value    string
+	onChange []func(string)
+}
+
+// Fetch reads the named secret from src.
+func Fetch(ctx context.Context, src Source, name string) (*Secret, error) {
+	value, err := src.Get(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return &Secret{src: src, name: name, value: value}, nil
+}
+
+func (s *Secret) Name() string {
+	return s.name
+}
+
+// Value is the secret as last read.
+func (s *Secret) Value() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.value
+}
+
+// OnChange registers fn to be called with the new value whenever Refresh
+// finds the secret changed. Register hooks before refreshing starts.
+func (s *Secret) OnChange(fn func(string)) {
+	s.onChange = append(s.onChange, fn)
+}
+
+// Refresh reads the secret again. A failed read keeps the last value.
+func (s *Secret) Refresh(ctx context.Context) error {
+	value, err := s.src.Get(ctx, s.name)
+	if err != nil {
+		return fmt.Errorf("failed to refresh %s: %w", s.name, err)
+	}
+
+	s.mu.Lock()
+	changed := value != s.value
+	s.value = value
+	s.mu.Unlock()
+
+	if changed {
+		for _, fn := range s.onChange {
+			fn(value)
+		}
+	}
+	return nil
+}
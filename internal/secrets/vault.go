@@ -0,0 +1,214 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Vault reads secrets from one path of a HashiCorp Vault KV version 2
+// engine, each secret being a key of it. It either sends a token it was
+// given as is, renewing it being left to whatever issues it (e.g. Vault
+// Agent), or logs in with a VaultAuth and logs in again before the token
+// expires.
+type Vault struct {
+	addr   string
+	url    string
+	client *http.Client
+	auth   *VaultAuth
+
+	mu      sync.Mutex
+	token   string
+	renewAt time.Time // Zero for a token that doesn't expire
+}
+
+// VaultAuth logs in to Vault with the Kubernetes or AppRole auth method.
+type VaultAuth struct {
+	Method string // kubernetes or approle
+	Mount  string // Defaults to the method's name
+	Role   string // kubernetes: the Vault role bound to the service account
+
+	// JWTPath is the service account token; the default is where
+	// Kubernetes mounts it
+	JWTPath string
+
+	RoleID   string // approle
+	SecretID string // approle
+}
+
+const serviceAccountToken = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+func NewVault(addr, token, mount, path string) *Vault {
+	if mount == "" {
+		mount = "secret"
+	}
+	return &Vault{
+		addr:   strings.TrimSuffix(addr, "/"),
+		url:    strings.TrimSuffix(addr, "/") + "/v1/" + url.PathEscape(mount) + "/data/" + strings.Trim(path, "/"),
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewVaultLogin is NewVault for a token got by logging in with auth.
+func NewVaultLogin(addr string, auth VaultAuth, mount, path string) (*Vault, error) {
+	switch auth.Method {
+	case "kubernetes":
+		if auth.Role == "" {
+			return nil, errors.New("vault kubernetes auth needs a role")
+		}
+		if auth.JWTPath == "" {
+			auth.JWTPath = serviceAccountToken
+		}
+	case "approle":
+		if auth.RoleID == "" {
+			return nil, errors.New("vault approle auth needs a role id")
+		}
+	default:
+		return nil, fmt.Errorf("unknown vault auth method %q, want kubernetes or approle", auth.Method)
+	}
+	if auth.Mount == "" {
+		auth.Mount = auth.Method
+	}
+
+	v := NewVault(addr, "", mount, path)
+	v.auth = &auth
+	return v, nil
+}
+
+func (v *Vault) Get(ctx context.Context, name string) (string, error) {
+	resp, err := v.read(ctx)
+	if err != nil {
+		return "", err
+	}
+	// A token revoked before its time gets one more try with a new one
+	if resp.StatusCode == http.StatusForbidden && v.auth != nil {
+		resp.Body.Close()
+		v.mu.Lock()
+		v.token = ""
+		v.mu.Unlock()
+		if resp, err = v.read(ctx); err != nil {
+			return "", err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault: unexpected status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("vault: failed to decode response: %w", err)
+	}
+	return lookup(body.Data.Data, name)
+}
+
+func (v *Vault) read(ctx context.Context) (*http.Response, error) {
+	token, err := v.currentToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+	return resp, nil
+}
+
+// currentToken is the token to send, logging in first when there is none
+// yet or it is close to expiring.
+func (v *Vault) currentToken(ctx context.Context) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.auth == nil || (v.token != "" && (v.renewAt.IsZero() || time.Now().Before(v.renewAt))) {
+		return v.token, nil
+	}
+
+	creds := map[string]string{}
+	switch v.auth.Method {
+	case "kubernetes":
+		// Read every time: the kubelet rotates it
+		jwt, err := os.ReadFile(v.auth.JWTPath)
+		if err != nil {
+			return "", fmt.Errorf("vault: failed to read service account token: %w", err)
+		}
+		creds["role"], creds["jwt"] = v.auth.Role, strings.TrimSpace(string(jwt))
+	case "approle":
+		creds["role_id"] = v.auth.RoleID
+		if v.auth.SecretID != "" {
+			creds["secret_id"] = v.auth.SecretID
+		}
+	}
+	body, err := json.Marshal(creds)
+	if err != nil {
+		return "", err
+	}
+	loginURL := v.addr + "/v1/auth/" + strings.Trim(v.auth.Mount, "/") + "/login"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, loginURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault: %s login failed with status %d", v.auth.Method, resp.StatusCode)
+	}
+
+	var out struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"` // Seconds; 0 never expires
+		} `json:"auth"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil || out.Auth.ClientToken == "" {
+		return "", fmt.Errorf("vault: %s login returned no token", v.auth.Method)
+	}
+
+	v.token = out.Auth.ClientToken
+	v.renewAt = time.Time{}
+	if lease := time.Duration(out.Auth.LeaseDuration) * time.Second; lease > 0 {
+		// Log in again with a quarter of the lease to spare
+		v.renewAt = time.Now().Add(lease * 3 / 4)
+	}
+	return v.token, nil
+}
+
+// lookup finds a secret among the keys of a Vault or AWS secret.
+func lookup(values map[string]any, name string) (string, error) {
+	v, ok := values[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("secret %s is not a string", name)
+	}
+	return s, nil
+}
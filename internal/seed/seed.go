@@ -0,0 +1,149 @@
+// Package seed fills a fresh database with what it needs to be usable: the
+// built-in roles, an admin account and, optionally, a small demo catalog.
+// Every step checks first, so running it again changes nothing.
+package seed
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/iteranya/practicing-go/internal/entities/inventory"
+	"github.com/iteranya/practicing-go/internal/entities/product"
+	"github.com/iteranya/practicing-go/internal/entities/role"
+	"github.com/iteranya/practicing-go/internal/entities/user"
+)
+
+const adminRole = "admin"
+
+type Options struct {
+	AdminUsername string // Defaults to "admin"
+	AdminPassword string // Generated (and reported) when empty
+	Demo          bool   // Also create demo inventory and products
+}
+
+// Report says what Run created.
+type Report struct {
+	Roles         int
+	AdminUsername string // Set when the admin account was created
+	AdminPassword string // Set when it was generated; shown once
+	Inventory     int
+	Products      int
+}
+
+type Services struct {
+	Roles     role.RoleService
+	Users     user.UserService
+	Inventory inventory.InventoryService
+	Products  product.ProductService
+}
+
+// Run seeds whatever is missing.
+func Run(ctx context.Context, svc Services, opts Options) (*Report, error) {
+	report := &Report{}
+
+	n, err := svc.Roles.SeedDefaults(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("seeding roles: %w", err)
+	}
+	report.Roles = n
+
+	if err := seedAdmin(ctx, svc.Users, opts, report); err != nil {
+		return nil, fmt.Errorf("seeding admin: %w", err)
+	}
+
+	if opts.Demo {
+		if err := seedDemo(ctx, svc, report); err != nil {
+			return nil, fmt.Errorf("seeding demo data: %w", err)
+		}
+	}
+	return report, nil
+}
+
+// seedAdmin creates the admin account unless someone already has the admin
+// role. The password must be changed on first login.
+func seedAdmin(ctx context.Context, users user.UserService, opts Options, report *Report) error {
+	admins, err := users.ListUsers(ctx, user.UserServiceListParams{Role: adminRole, Limit: 1, Page: 1})
+	if err != nil {
+		return err
+	}
+	if admins.Total > 0 {
+		return nil
+	}
+
+	username := opts.AdminUsername
+	if username == "" {
+		username = "admin"
+	}
+	password := opts.AdminPassword
+	if password == "" {
+		b := make([]byte, 12)
+		if _, err := rand.Read(b); err != nil {
+			return fmt.Errorf("failed to generate password: %w", err)
+		}
+		password = base64.RawURLEncoding.EncodeToString(b)
+		report.AdminPassword = password
+	}
+
+	_, err = users.RegisterUser(ctx, user.UserInput{
+		Username:    username,
+		Password:    password,
+		DisplayName: "Administrator",
+		Role:        adminRole,
+	})
+	if err != nil {
+		return err
+	}
+	report.AdminUsername = username
+	return nil
+}
+
+var demoInventory = []inventory.Inventory{
+	{Slug: "coffee-beans", Name: "Coffee Beans (g)", Tags: []string{"coffee"}, Stock: 5000, MinStock: 1000, UnitCost: 3},
+	{Slug: "milk", Name: "Milk (ml)", Tags: []string{"dairy"}, Stock: 10000, MinStock: 2000, UnitCost: 1},
+	{Slug: "croissant-dough", Name: "Croissant Dough", Tags: []string{"bakery"}, Stock: 40, MinStock: 10, UnitCost: 450},
+}
+
+var demoProducts = []product.Product{
+	{Slug: "espresso", Name: "Espresso", Tag: "coffee", Price: 2500, Avail: true,
+		Recipe: &map[string]int{"coffee-beans": 18}},
+	{Slug: "latte", Name: "Caffe Latte", Tag: "coffee", Price: 3500, Avail: true,
+		Recipe: &map[string]int{"coffee-beans": 18, "milk": 200}},
+	{Slug: "croissant", Name: "Butter Croissant", Tag: "bakery", Price: 3000, Avail: true,
+		Recipe: &map[string]int{"croissant-dough": 1}},
+}
+
+// seedDemo creates the demo items and products that don't exist yet,
+// matching by slug.
+func seedDemo(ctx context.Context, svc Services, report *Report) error {
+	for _, item := range demoInventory {
+		_, err := svc.Inventory.GetInventory(ctx, item.Slug)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, inventory.ErrNotFound) {
+			return err
+		}
+		if _, err := svc.Inventory.CreateInventory(ctx, item); err != nil {
+			return err
+		}
+		report.Inventory++
+	}
+
+	for _, p := range demoProducts {
+		_, err := svc.Products.GetProduct(ctx, p.Slug)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, product.ErrProductNotFound) {
+			return err
+		}
+		if _, err := svc.Products.CreateProduct(ctx, p); err != nil {
+			return err
+		}
+		report.Products++
+	}
+	return nil
+}
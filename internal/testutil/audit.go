@@ -0,0 +1,61 @@
+package testutil
+
+import (
+	"cmp"
+	"context"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/audit"
+	"github.com/iteranya/practicing-go/internal/database"
+)
+
+type auditRow struct {
+	audit.Entry
+	store int
+}
+
+func (r auditRow) rowID() int { return r.Id }
+
+// Audit returns the DB as an audit.Log; pass it to audit.SetLog to record
+// the changes services make.
+func (db *DB) Audit() audit.Log {
+	return auditLog{db}
+}
+
+type auditLog struct {
+	db *DB
+}
+
+func (l auditLog) Append(ctx context.Context, e *audit.Entry) error {
+	defer l.db.lock()()
+	e.Id, e.CreatedAt = l.db.nextID("audit_log"), time.Now()
+	l.db.t.audit[e.Id] = auditRow{jsonCopy(*e), database.StoreOf(ctx)}
+	return nil
+}
+
+func (l auditLog) List(ctx context.Context, f audit.Filter) ([]*audit.Entry, error) {
+	defer l.db.lock()()
+	rows := sorted(l.db.t.audit,
+		func(r auditRow) bool {
+			return r.store == database.StoreOf(ctx) &&
+				(f.EntityType == "" || r.EntityType == f.EntityType) &&
+				(f.EntityType == "" || f.EntityId == 0 || r.EntityId == f.EntityId) &&
+				(f.ActorId == 0 || r.ActorId != nil && *r.ActorId == f.ActorId) &&
+				(f.Action == "" || r.Action == f.Action) &&
+				(f.From.IsZero() || !r.CreatedAt.Before(f.From)) &&
+				(f.To.IsZero() || !r.CreatedAt.After(f.To))
+		},
+		func(a, b auditRow) int {
+			return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.Id, a.Id))
+		})
+
+	rows = rows[min(f.Offset, len(rows)):]
+	entries := collect(rows[:min(f.Limit, len(rows))], func(r auditRow) *audit.Entry {
+		e := jsonCopy(r.Entry)
+		return &e
+	})
+	if entries == nil {
+		entries = []*audit.Entry{}
+	}
+	return entries, nil
+}
@@ -0,0 +1,364 @@
+// Package testutil has in-memory versions of the repositories, and builders
+// for the entities they hold, so service tests can run without a database:
+//
+//	db := testutil.NewDB()
+//	inv := inventory.NewInventoryService(db.Inventory(false), true)
+//	products := product.NewProductService(db.Products(), inv)
+//	milk := testutil.NewInventory("milk").Stock(1000).Insert(t, ctx, db)
+//
+// The repositories of one DB share it the way tables share a database: roles
+// count the users holding them, inventory reads product recipes, orders check
+// their clerk. Like the SQL ones they keep to the store of the context and
+// return the same errors. Values go through JSON on the way in and out, as
+// they do through the JSON columns, so numbers in Custom come back as
+// float64.
+package testutil
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
+)
+
+// DB is an in-memory database behind the repositories it hands out. It is
+// safe for concurrent use; each repository call runs on its own, as one
+// statement would.
+type DB struct {
+	mu sync.Mutex
+	t  tables
+}
+
+// tables is everything a DB holds. Rows are stored by value and replaced
+// rather than changed in place, so copying every map is enough to
+// snapshot it (see TxManager).
+type tables struct {
+	seq map[string]int // Last id handed out per table, shared by every store
+
+	stores    map[int]storeRow
+	locations map[int]locationRow
+	products  map[int]productRow
+
+	inventory      map[int]inventoryRow
+	movements      map[int]movementRow
+	snapshots      map[snapshotKey]int64
+	supplierPrices map[int]supplierPriceRow
+	reservations   map[int]reservationRow
+	stocktakes     map[int]stocktakeRow
+	counts         map[countKey]countRow
+	tags           map[int]tagRow
+
+	orders  map[int]orderRow
+	archive map[int]orderRow
+
+	roles map[int]roleRow
+
+	users           map[int]userRow
+	usernameHistory map[int]historyRow
+	userLocations   map[userLocation]bool
+	policies        map[policyKey]time.Time
+	loginAttempts   map[int]loginAttemptRow
+	activity        map[int]activityRow
+	devices         map[int]deviceRow
+	magicLinks      map[string]tokenRow
+	refreshTokens   map[string]tokenRow
+	timeEntries     map[int]timeEntryRow
+
+	audit map[int]auditRow
+}
+
+// NewDB returns an empty database holding only the default store, as a
+// freshly migrated one does.
+func NewDB() *DB {
+	db := &DB{t: tables{}.clone()}
+	db.t.stores[database.DefaultStore] = storeRow{id: database.DefaultStore, slug: "default", name: "Default", created: time.Now()}
+	db.t.seq["stores"] = database.DefaultStore
+	return db
+}
+
+// TxManager returns a transaction manager for the DB. Run rolls everything
+// back if fn fails, by restoring what the DB held when it started; writes
+// made meanwhile outside fn are lost with it, which tests running one
+// transaction at a time never notice. fn gets a nil client, which the
+// repositories here ignore.
+func (db *DB) TxManager() database.TxManager {
+	return txManager{db}
+}
+
+type txManager struct {
+	db *DB
+}
+
+func (m txManager) Run(ctx context.Context, fn func(ctx context.Context, client database.SQLClient) error) (err error) {
+	saved := m.db.snapshot()
+	defer func() {
+		if p := recover(); p != nil {
+			m.db.restore(saved)
+			panic(p)
+		}
+		if err != nil {
+			m.db.restore(saved)
+		}
+	}()
+	return fn(ctx, nil)
+}
+
+func (db *DB) snapshot() tables {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.t.clone()
+}
+
+// clone copies every table, making the missing ones.
+func (t tables) clone() tables {
+	return tables{
+		seq:             copyMap(t.seq),
+		stores:          copyMap(t.stores),
+		locations:       copyMap(t.locations),
+		products:        copyMap(t.products),
+		inventory:       copyMap(t.inventory),
+		movements:       copyMap(t.movements),
+		snapshots:       copyMap(t.snapshots),
+		supplierPrices:  copyMap(t.supplierPrices),
+		reservations:    copyMap(t.reservations),
+		stocktakes:      copyMap(t.stocktakes),
+		counts:          copyMap(t.counts),
+		tags:            copyMap(t.tags),
+		orders:          copyMap(t.orders),
+		archive:         copyMap(t.archive),
+		roles:           copyMap(t.roles),
+		users:           copyMap(t.users),
+		usernameHistory: copyMap(t.usernameHistory),
+		userLocations:   copyMap(t.userLocations),
+		policies:        copyMap(t.policies),
+		loginAttempts:   copyMap(t.loginAttempts),
+		activity:        copyMap(t.activity),
+		devices:         copyMap(t.devices),
+		magicLinks:      copyMap(t.magicLinks),
+		refreshTokens:   copyMap(t.refreshTokens),
+		timeEntries:     copyMap(t.timeEntries),
+		audit:           copyMap(t.audit),
+	}
+}
+
+func copyMap[K comparable, V any](m map[K]V) map[K]V {
+	c := make(map[K]V, len(m))
+	maps.Copy(c, m)
+	return c
+}
+
+func (db *DB) restore(saved tables) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.t = saved
+}
+
+// lock takes the DB for one repository call.
+func (db *DB) lock() func() {
+	db.mu.Lock()
+	return db.mu.Unlock
+}
+
+// nextID hands out the next id of table, as its sequence would.
+func (db *DB) nextID(table string) int {
+	db.t.seq[table]++
+	return db.t.seq[table]
+}
+
+// sawID moves the sequence of table past an id inserted as it was, as
+// ResetSequence does after an import.
+func (db *DB) sawID(table string, id int) {
+	db.t.seq[table] = max(db.t.seq[table], id)
+}
+
+// jsonCopy returns a copy of v made by a trip through JSON, the way values
+// come back out of the database's JSON columns.
+func jsonCopy[T any](v T) T {
+	var out T
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic("testutil: can't copy value: " + err.Error())
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		panic("testutil: can't copy value: " + err.Error())
+	}
+	return out
+}
+
+// unix is a timestamp as the entities carry it, 0 for the zero time.
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// fromUnix is a Unix timestamp read back into a time, the zero time for 0.
+func fromUnix(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// inPeriod checks a row's timestamps against p, like Filter.Period.
+func inPeriod(p database.Period, created, updated time.Time) bool {
+	return (p.CreatedFrom.IsZero() || !created.Before(p.CreatedFrom)) &&
+		(p.CreatedTo.IsZero() || !created.After(p.CreatedTo)) &&
+		(p.UpdatedFrom.IsZero() || !updated.Before(p.UpdatedFrom)) &&
+		(p.UpdatedTo.IsZero() || !updated.After(p.UpdatedTo))
+}
+
+// between checks start <= t <= end, like the reports' date ranges.
+func between(t, start, end time.Time) bool {
+	return !t.Before(start) && !t.After(end)
+}
+
+// like matches ILIKE '%query%'.
+func like(s, query string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(query))
+}
+
+// row is a row of a table with an id primary key.
+type row interface {
+	rowID() int
+}
+
+// sorted returns the rows that pass keep, ordered by order (if any) and then
+// id, as an ORDER BY on the table would.
+func sorted[K comparable, R row](rows map[K]R, keep func(R) bool, order func(a, b R) int) []R {
+	var out []R
+	for _, r := range rows {
+		if keep(r) {
+			out = append(out, r)
+		}
+	}
+	slices.SortFunc(out, func(a, b R) int {
+		if order != nil {
+			if c := order(a, b); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.rowID(), b.rowID())
+	})
+	return out
+}
+
+// page orders rows by k, keyed by key (a string, an int64 or nil for NULL),
+// and returns the page after cursor, like the SQL listings with Keyset.
+func page[R row](rows []R, k database.Keyset, cursor string, limit, offset int, key func(R) any) ([]R, error) {
+	order := func(ka any, ia int, kb any, ib int) int {
+		if (ka == nil) != (kb == nil) { // NULLs come last either way
+			if ka == nil {
+				return 1
+			}
+			return -1
+		}
+		c := compareKeys(ka, kb)
+		if c == 0 {
+			c = cmp.Compare(ia, ib)
+		}
+		if k.Direction == "DESC" {
+			c = -c
+		}
+		return c
+	}
+	slices.SortFunc(rows, func(a, b R) int {
+		return order(key(a), a.rowID(), key(b), b.rowID())
+	})
+
+	if cursor != "" {
+		ck, cid, err := k.Position(cursor)
+		if err != nil {
+			return nil, err
+		}
+		rows = slices.DeleteFunc(rows, func(r R) bool {
+			return order(key(r), r.rowID(), ck, cid) <= 0
+		})
+	}
+
+	rows = rows[min(offset, len(rows)):]
+	if limit > 0 {
+		rows = rows[:min(limit, len(rows))]
+	}
+	return rows, nil
+}
+
+func compareKeys(a, b any) int {
+	switch a := a.(type) {
+	case int64:
+		if b, ok := b.(int64); ok {
+			return cmp.Compare(a, b)
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return cmp.Compare(a, b)
+		}
+	case nil:
+		return 0
+	}
+	panic("testutil: sort keys of different types")
+}
+
+// listTotal is the count a listing returns, with Total set, before it is paged.
+func listTotal[R any](rows []R, count bool) int {
+	if !count {
+		return 0
+	}
+	return len(rows)
+}
+
+// collect turns rows into entities, returning nil for none like
+// database.Select.
+func collect[R any, T any](rows []R, out func(R) T) []T {
+	var all []T
+	for _, r := range rows {
+		all = append(all, out(r))
+	}
+	return all
+}
+
+// always keeps every row.
+func always[R any](R) bool { return true }
+
+// stamps are the store a row belongs to and its timestamps, kept to the
+// nanosecond as the database keeps them; entities only see whole seconds.
+type stamps struct {
+	store            int
+	created, updated time.Time
+	deleted          time.Time // Zero while live, for tables with a trash
+}
+
+func newStamps(ctx context.Context) stamps {
+	now := time.Now()
+	return stamps{store: database.StoreOf(ctx), created: now, updated: now}
+}
+
+func (s stamps) stamp() stamps { return s }
+
+// in reports whether the row belongs to the store of ctx.
+func (s stamps) in(ctx context.Context) bool { return s.store == database.StoreOf(ctx) }
+
+func (s stamps) live() bool { return s.deleted.IsZero() }
+
+// trashedBefore returns the ids of rows in the store of ctx deleted before
+// the cutoff, oldest first, like database.TrashedBefore.
+func trashedBefore[R interface {
+	row
+	stamp() stamps
+}](ctx context.Context, rows map[int]R, cutoff time.Time, and func(R) bool) []int {
+	trashed := sorted(rows,
+		func(r R) bool {
+			s := r.stamp()
+			return s.in(ctx) && !s.live() && s.deleted.Before(cutoff) && (and == nil || and(r))
+		},
+		func(a, b R) int { return a.stamp().deleted.Compare(b.stamp().deleted) })
+	return collect(trashed, R.rowID)
+}
@@ -0,0 +1,1148 @@
+package testutil
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/entities/inventory"
+	"github.com/iteranya/practicing-go/internal/entities/order"
+)
+
+type inventoryRow struct {
+	inventory.Inventory // Created, Updated and Deleted live in stamps, Reserved is computed
+	stamps
+}
+
+func (r inventoryRow) rowID() int { return r.Id }
+
+type movementRow struct {
+	inventory.StockMovement // Created lives in created
+	created                 time.Time
+}
+
+func (r movementRow) rowID() int { return r.Id }
+
+type snapshotKey struct {
+	inventoryId int
+	day         int64 // Unix seconds of the day's midnight
+}
+
+type supplierPriceRow struct {
+	inventory.SupplierPrice // Slug is filled on reads
+}
+
+func (r supplierPriceRow) rowID() int { return r.Id }
+
+type reservationRow struct {
+	inventory.Reservation
+}
+
+type stocktakeRow struct {
+	inventory.StocktakeSession // Created and Closed live in created and closed
+	store                      int
+	created, closed            time.Time
+}
+
+func (r stocktakeRow) rowID() int { return r.Id }
+
+func (r stocktakeRow) out() *inventory.StocktakeSession {
+	s := r.StocktakeSession
+	s.Created, s.Closed = unix(r.created), unix(r.closed)
+	return &s
+}
+
+type countKey struct {
+	sessionId, inventoryId int
+}
+
+type countRow struct {
+	counted, expected, unitCost int64 // expected and unitCost are set on closing
+}
+
+type tagRow struct {
+	inventory.ManagedTag // Items is computed
+	store                int
+}
+
+func (r tagRow) rowID() int { return r.Id }
+
+// Inventory returns the DB as an inventory.InventoryRepository. Like
+// inventory.NewInventoryRepository, it lets stock go below zero only if
+// allowNegativeStock is set.
+func (db *DB) Inventory(allowNegativeStock bool) inventory.InventoryRepository {
+	return inventoryRepository{db: db, allowNegativeStock: allowNegativeStock}
+}
+
+type inventoryRepository struct {
+	db                 *DB
+	allowNegativeStock bool
+}
+
+func (r inventoryRepository) Create(ctx context.Context, inv *inventory.Inventory) error {
+	if inv.Slug == "" || inv.Name == "" {
+		return inventory.ErrInvalidInput
+	}
+	defer r.db.lock()()
+
+	if err := r.db.inventoryKeysTaken(ctx, inv.Slug, inv.Barcode, 0); err != nil {
+		return err
+	}
+	row := inventoryRow{Inventory: jsonCopy(*inv), stamps: newStamps(ctx)}
+	row.Id, row.Revision = r.db.nextID("inventory"), 1
+	if row.Tags == nil {
+		row.Tags = []string{}
+	}
+	row.Reserved, row.Created, row.Updated, row.Deleted = 0, 0, 0, 0
+	r.db.t.inventory[row.Id] = row
+
+	inv.Id, inv.Revision, inv.Created, inv.Updated = row.Id, row.Revision, unix(row.created), unix(row.updated)
+	return nil
+}
+
+func (r inventoryRepository) Import(ctx context.Context, client database.SQLClient, items []*inventory.Inventory) ([]bool, error) {
+	defer r.db.lock()()
+
+	imported := make([]bool, len(items))
+	for i, inv := range items {
+		if _, ok := r.db.t.inventory[inv.Id]; ok || r.db.inventoryKeysTaken(ctx, inv.Slug, inv.Barcode, 0) != nil {
+			continue
+		}
+		row := inventoryRow{
+			Inventory: jsonCopy(*inv),
+			stamps:    stamps{store: database.StoreOf(ctx), created: fromUnix(inv.Created), updated: fromUnix(inv.Updated), deleted: fromUnix(inv.Deleted)},
+		}
+		row.Revision = max(inv.Revision, 1)
+		if row.Tags == nil {
+			row.Tags = []string{}
+		}
+		row.Reserved, row.Created, row.Updated, row.Deleted = 0, 0, 0, 0
+		r.db.t.inventory[inv.Id] = row
+		r.db.sawID("inventory", inv.Id)
+		imported[i] = true
+	}
+	return imported, nil
+}
+
+func (r inventoryRepository) GetByID(ctx context.Context, id int) (*inventory.Inventory, error) {
+	defer r.db.lock()()
+	row, ok := r.db.inventoryItem(ctx, id)
+	if !ok {
+		return nil, inventory.ErrNotFound
+	}
+	return r.db.inventoryOut(row), nil
+}
+
+func (r inventoryRepository) GetBySlug(ctx context.Context, slug string) (*inventory.Inventory, error) {
+	return r.getOne(ctx, func(row inventoryRow) bool { return row.Slug == slug })
+}
+
+func (r inventoryRepository) GetBySlugs(ctx context.Context, slugs []string) ([]*inventory.Inventory, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.inventory,
+		func(row inventoryRow) bool { return row.in(ctx) && row.live() && slices.Contains(slugs, row.Slug) },
+		func(a, b inventoryRow) int { return cmp.Compare(a.Slug, b.Slug) })
+	return collect(rows, r.db.inventoryOut), nil
+}
+
+func (r inventoryRepository) GetByBarcode(ctx context.Context, barcode string) (*inventory.Inventory, error) {
+	return r.getOne(ctx, func(row inventoryRow) bool { return row.Barcode != "" && row.Barcode == barcode })
+}
+
+func (r inventoryRepository) Update(ctx context.Context, inv *inventory.Inventory) error {
+	if inv.Id == 0 {
+		return inventory.ErrInvalidInput
+	}
+	defer r.db.lock()()
+
+	row, ok := r.db.inventoryItem(ctx, inv.Id)
+	if !ok {
+		return inventory.ErrNotFound
+	}
+	if row.Revision != inv.Revision {
+		return inventory.ErrConflict
+	}
+	if err := r.db.inventoryKeysTaken(ctx, inv.Slug, inv.Barcode, inv.Id); err != nil {
+		return err
+	}
+
+	updated := inventoryRow{Inventory: jsonCopy(*inv), stamps: row.stamps}
+	if updated.Tags == nil {
+		updated.Tags = []string{}
+	}
+	updated.Revision++
+	updated.Reserved, updated.Created, updated.Updated, updated.Deleted = 0, 0, 0, 0
+	updated.updated = time.Now()
+	r.db.t.inventory[inv.Id] = updated
+	inv.Revision++
+	return nil
+}
+
+func (r inventoryRepository) Delete(ctx context.Context, id int) error {
+	defer r.db.lock()()
+	row, ok := r.db.inventoryItem(ctx, id)
+	if !ok {
+		return inventory.ErrNotFound
+	}
+	row.deleted, row.updated = time.Now(), time.Now()
+	row.Revision++
+	r.db.t.inventory[id] = row
+	return nil
+}
+
+func (r inventoryRepository) Restore(ctx context.Context, id int) error {
+	defer r.db.lock()()
+	row, ok := r.db.t.inventory[id]
+	if !ok || !row.in(ctx) || row.live() {
+		return inventory.ErrNotFound
+	}
+	row.deleted, row.updated = time.Time{}, time.Now()
+	row.Revision++
+	r.db.t.inventory[id] = row
+	return nil
+}
+
+// Purge is refused while a product recipe, trashed or not, uses the item.
+func (r inventoryRepository) Purge(ctx context.Context, id int) error {
+	defer r.db.lock()()
+	row, ok := r.db.t.inventory[id]
+	if !ok || !row.in(ctx) || row.live() {
+		return inventory.ErrNotFound
+	}
+	for _, p := range r.db.t.products {
+		if p.in(ctx) && p.Recipe != nil {
+			if _, uses := (*p.Recipe)[row.Slug]; uses {
+				return inventory.ErrInUse
+			}
+		}
+	}
+
+	// Its history goes with it
+	delete(r.db.t.inventory, id)
+	for mid, m := range r.db.t.movements {
+		if m.InventoryId == id {
+			delete(r.db.t.movements, mid)
+		}
+	}
+	for k := range r.db.t.snapshots {
+		if k.inventoryId == id {
+			delete(r.db.t.snapshots, k)
+		}
+	}
+	for sid, sp := range r.db.t.supplierPrices {
+		if sp.InventoryId == id {
+			delete(r.db.t.supplierPrices, sid)
+		}
+	}
+	for rid, res := range r.db.t.reservations {
+		if res.InventoryId == id {
+			delete(r.db.t.reservations, rid)
+		}
+	}
+	for k := range r.db.t.counts {
+		if k.inventoryId == id {
+			delete(r.db.t.counts, k)
+		}
+	}
+	return nil
+}
+
+func (r inventoryRepository) TrashedBefore(ctx context.Context, cutoff time.Time) ([]int, error) {
+	defer r.db.lock()()
+	return trashedBefore(ctx, r.db.t.inventory, cutoff, nil), nil
+}
+
+func (r inventoryRepository) List(ctx context.Context, opts inventory.ListOptions) ([]*inventory.Inventory, int, error) {
+	defer r.db.lock()()
+
+	rows := sorted(r.db.t.inventory, listFilter(ctx, opts), nil)
+	total := listTotal(rows, opts.Total)
+	k := database.Keyset{
+		Column:    database.SortColumn(opts.SortBy, "id", "name", "stock", "slug", "created_at", "updated_at"),
+		Direction: database.SortDirection(opts.SortOrder, "ASC"),
+	}
+	rows, err := page(rows, k, opts.After, opts.Limit, opts.Offset, func(row inventoryRow) any {
+		switch k.Column {
+		case "name":
+			return row.Name
+		case "stock":
+			return row.Stock
+		case "slug":
+			return row.Slug
+		case "created_at":
+			return row.created.Unix()
+		case "updated_at":
+			return row.updated.Unix()
+		}
+		return int64(row.Id)
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", inventory.ErrInvalidInput, err)
+	}
+	return collect(rows, r.db.inventoryOut), total, nil
+}
+
+func (r inventoryRepository) GetSummary(ctx context.Context) (*inventory.Summary, error) {
+	defer r.db.lock()()
+	summary := &inventory.Summary{ByTag: make(map[string]int)}
+	for _, row := range r.db.t.inventory {
+		if !row.in(ctx) || !row.live() {
+			continue
+		}
+		summary.TotalSKUs++
+		summary.TotalUnits += row.Stock
+		summary.TotalValue += row.Stock * row.UnitCost
+		if len(row.Tags) == 0 {
+			summary.ByTag[""]++
+		}
+		for _, tag := range row.Tags {
+			summary.ByTag[tag]++
+		}
+	}
+	return summary, nil
+}
+
+func (r inventoryRepository) UpdateStock(ctx context.Context, id int, delta int64) error {
+	defer r.db.lock()()
+	_, err := r.updateStock(ctx, id, delta)
+	return err
+}
+
+func (r inventoryRepository) AdjustStock(ctx context.Context, m *inventory.StockMovement) error {
+	defer r.db.lock()()
+
+	newStock, err := r.updateStock(ctx, m.InventoryId, m.Delta)
+	if err != nil {
+		return err
+	}
+	m.StockAfter = newStock
+
+	// Receiving at a known cost re-weights the unit cost (weighted average)
+	if m.Reason == inventory.ReasonReceived && m.UnitCost > 0 && m.Delta > 0 {
+		row := r.db.t.inventory[m.InventoryId]
+		prior := max(row.Stock-m.Delta, 0)
+		row.UnitCost = (prior*row.UnitCost + m.Delta*m.UnitCost) / (prior + m.Delta)
+		r.db.t.inventory[m.InventoryId] = row
+	}
+
+	r.db.insertMovement(m)
+	return nil
+}
+
+func (r inventoryRepository) GetMovements(ctx context.Context, id int, limit int) ([]*inventory.StockMovement, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.movements,
+		func(m movementRow) bool { return m.InventoryId == id },
+		func(a, b movementRow) int {
+			if c := b.created.Compare(a.created); c != 0 {
+				return c
+			}
+			return cmp.Compare(b.Id, a.Id)
+		})
+	return collect(rows[:min(limit, len(rows))], func(m movementRow) *inventory.StockMovement {
+		out := m.StockMovement
+		out.Created = m.created.Unix()
+		return &out
+	}), nil
+}
+
+func (r inventoryRepository) Search(ctx context.Context, query string, tags []string) ([]*inventory.Inventory, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.inventory,
+		func(row inventoryRow) bool {
+			matched := like(row.Name, query) || like(row.Desc, query) ||
+				slices.ContainsFunc(row.Tags, func(tag string) bool { return like(tag, query) })
+			return row.in(ctx) && row.live() && matched && hasTags(row, tags)
+		},
+		func(a, b inventoryRow) int { return cmp.Compare(a.Name, b.Name) })
+	return collect(rows, r.db.inventoryOut), nil
+}
+
+func (r inventoryRepository) BulkUpsert(ctx context.Context, items []*inventory.Inventory) ([]bool, error) {
+	defer r.db.lock()()
+
+	created := make([]bool, len(items))
+	for i, inv := range items {
+		tags := jsonCopy(inv.Tags)
+		if tags == nil {
+			tags = []string{}
+		}
+		if row := r.db.inventoryBySlug(ctx, inv.Slug, false); row != nil {
+			row.Name, row.Tags, row.Stock, row.MinStock, row.MaxStock = inv.Name, tags, inv.Stock, inv.MinStock, inv.MaxStock
+			row.deleted, row.updated = time.Time{}, time.Now() // Re-importing a deleted item restores it
+			row.Revision++
+			r.db.t.inventory[row.Id] = *row
+			continue
+		}
+
+		row := inventoryRow{stamps: newStamps(ctx)}
+		row.Id, row.Revision = r.db.nextID("inventory"), 1
+		row.Slug, row.Name, row.Tags, row.Stock, row.MinStock, row.MaxStock = inv.Slug, inv.Name, tags, inv.Stock, inv.MinStock, inv.MaxStock
+		r.db.t.inventory[row.Id] = row
+		created[i] = true
+	}
+	return created, nil
+}
+
+func (r inventoryRepository) SaveSnapshot(ctx context.Context, day time.Time) (int, error) {
+	defer r.db.lock()()
+	n := 0
+	for _, row := range r.db.t.inventory {
+		if row.in(ctx) && row.live() {
+			r.db.t.snapshots[snapshotKey{row.Id, day.Unix()}] = row.Stock
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r inventoryRepository) GetHistory(ctx context.Context, id int, start, end time.Time) ([]*inventory.StockSnapshot, error) {
+	defer r.db.lock()()
+	return r.db.snapshotsWhere(func(k snapshotKey, _ inventoryRow) bool {
+		return k.inventoryId == id && k.day >= start.Unix() && k.day <= end.Unix()
+	}, func(a, b *inventory.StockSnapshot) int { return a.TakenOn.Compare(b.TakenOn) }), nil
+}
+
+func (r inventoryRepository) GetSnapshotsOn(ctx context.Context, day time.Time) ([]*inventory.StockSnapshot, error) {
+	defer r.db.lock()()
+	return r.db.snapshotsWhere(func(k snapshotKey, item inventoryRow) bool {
+		return k.day == day.Unix() && item.in(ctx)
+	}, func(a, b *inventory.StockSnapshot) int { return cmp.Compare(a.Slug, b.Slug) }), nil
+}
+
+func (r inventoryRepository) UpsertSupplierPrice(ctx context.Context, sp *inventory.SupplierPrice) error {
+	defer r.db.lock()()
+	if _, ok := r.db.t.inventory[sp.InventoryId]; !ok {
+		return fmt.Errorf("failed to save supplier price: no inventory item %d", sp.InventoryId)
+	}
+	for id, existing := range r.db.t.supplierPrices {
+		if existing.InventoryId == sp.InventoryId && existing.Supplier == sp.Supplier {
+			existing.UnitPrice, existing.LeadTimeDays = sp.UnitPrice, sp.LeadTimeDays
+			r.db.t.supplierPrices[id] = existing
+			sp.Id = id
+			return nil
+		}
+	}
+	sp.Id = r.db.nextID("inventory_supplier_prices")
+	row := supplierPriceRow{*sp}
+	row.Slug = ""
+	r.db.t.supplierPrices[sp.Id] = row
+	return nil
+}
+
+func (r inventoryRepository) DeleteSupplierPrice(ctx context.Context, inventoryId int, supplier string) error {
+	defer r.db.lock()()
+	if item, ok := r.db.t.inventory[inventoryId]; ok && item.in(ctx) {
+		for id, sp := range r.db.t.supplierPrices {
+			if sp.InventoryId == inventoryId && sp.Supplier == supplier {
+				delete(r.db.t.supplierPrices, id)
+				return nil
+			}
+		}
+	}
+	return inventory.ErrNotFound
+}
+
+func (r inventoryRepository) GetSupplierPrices(ctx context.Context, inventoryId int) ([]*inventory.SupplierPrice, error) {
+	defer r.db.lock()()
+	return r.db.supplierPricesWhere(
+		func(sp supplierPriceRow, _ inventoryRow) bool { return sp.InventoryId == inventoryId },
+		false), nil
+}
+
+func (r inventoryRepository) ListSupplierPrices(ctx context.Context, belowThresholdOnly bool) ([]*inventory.SupplierPrice, error) {
+	defer r.db.lock()()
+	return r.db.supplierPricesWhere(
+		func(_ supplierPriceRow, item inventoryRow) bool {
+			return item.in(ctx) && (!belowThresholdOnly || item.Stock < item.MinStock)
+		},
+		true), nil
+}
+
+func (r inventoryRepository) Reserve(ctx context.Context, res *inventory.Reservation) error {
+	defer r.db.lock()()
+	row, ok := r.db.inventoryItem(ctx, res.InventoryId)
+	if !ok {
+		return inventory.ErrNotFound
+	}
+	if row.Stock-r.db.reserved(row.Id) < res.Quantity {
+		return inventory.ErrInsufficientStock
+	}
+	res.Id, res.Created = r.db.nextID("inventory_reservations"), time.Now().Unix()
+	r.db.t.reservations[res.Id] = reservationRow{*res}
+	return nil
+}
+
+func (r inventoryRepository) ReleaseForOrder(ctx context.Context, client database.SQLClient, orderId int) (int64, error) {
+	defer r.db.lock()()
+	var n int64
+	for id, res := range r.db.t.reservations {
+		if res.OrderId == orderId {
+			delete(r.db.t.reservations, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r inventoryRepository) ReleaseExpired(ctx context.Context) (int64, error) {
+	defer r.db.lock()()
+	now := time.Now().Unix()
+	var n int64
+	for id, res := range r.db.t.reservations {
+		if item, ok := r.db.t.inventory[res.InventoryId]; ok && item.in(ctx) && res.ExpiresAt <= now {
+			delete(r.db.t.reservations, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r inventoryRepository) ConsumeForOrder(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) ([]string, error) {
+	defer r.db.lock()()
+
+	var short []string
+	for _, u := range r.db.orderUsage(ctx, items) {
+		m := &inventory.StockMovement{
+			InventoryId: u.InventoryId,
+			Delta:       -u.Quantity,
+			Reason:      inventory.ReasonSale,
+			Note:        fmt.Sprintf("order #%d", orderId),
+			UserId:      userId,
+		}
+		var err error
+		m.StockAfter, err = r.updateStock(ctx, u.InventoryId, m.Delta)
+		if err == inventory.ErrInsufficientStock {
+			short = append(short, u.Slug)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		r.db.insertMovement(m)
+	}
+	return short, nil
+}
+
+func (r inventoryRepository) RestockForOrder(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) error {
+	defer r.db.lock()()
+
+	for _, u := range r.db.orderUsage(ctx, items) {
+		m := &inventory.StockMovement{
+			InventoryId: u.InventoryId,
+			Delta:       u.Quantity,
+			Reason:      inventory.ReasonSale,
+			Note:        fmt.Sprintf("void order #%d", orderId),
+			UserId:      userId,
+		}
+		var err error
+		if m.StockAfter, err = r.updateStock(ctx, u.InventoryId, m.Delta); err != nil {
+			return err
+		}
+		r.db.insertMovement(m)
+	}
+	return nil
+}
+
+func (r inventoryRepository) GetOrderUsage(ctx context.Context, items []string) ([]*inventory.OrderUsage, error) {
+	defer r.db.lock()()
+	return r.db.orderUsage(ctx, items), nil
+}
+
+func (r inventoryRepository) CreateStocktake(ctx context.Context, session *inventory.StocktakeSession) error {
+	defer r.db.lock()()
+	row := stocktakeRow{StocktakeSession: *session, store: database.StoreOf(ctx), created: time.Now()}
+	row.Id, row.Status = r.db.nextID("stocktake_sessions"), inventory.StocktakeOpen
+	r.db.t.stocktakes[row.Id] = row
+	session.Id, session.Status, session.Created = row.Id, row.Status, unix(row.created)
+	return nil
+}
+
+func (r inventoryRepository) GetStocktake(ctx context.Context, id int) (*inventory.StocktakeSession, error) {
+	defer r.db.lock()()
+	row, ok := r.db.t.stocktakes[id]
+	if !ok || row.store != database.StoreOf(ctx) {
+		return nil, inventory.ErrStocktakeNotFound
+	}
+	return row.out(), nil
+}
+
+func (r inventoryRepository) ListStocktakes(ctx context.Context, start, end time.Time) ([]*inventory.StocktakeSession, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.stocktakes,
+		func(row stocktakeRow) bool {
+			return row.store == database.StoreOf(ctx) && row.Status == inventory.StocktakeClosed && between(row.closed, start, end)
+		},
+		func(a, b stocktakeRow) int { return a.closed.Compare(b.closed) })
+	return collect(rows, stocktakeRow.out), nil
+}
+
+func (r inventoryRepository) RecordCount(ctx context.Context, sessionId, inventoryId int, counted int64) error {
+	defer r.db.lock()()
+	session, ok := r.db.t.stocktakes[sessionId]
+	if !ok || session.store != database.StoreOf(ctx) {
+		return inventory.ErrStocktakeNotFound
+	}
+	if session.Status != inventory.StocktakeOpen {
+		return inventory.ErrStocktakeClosed
+	}
+	if item, ok := r.db.t.inventory[inventoryId]; !ok || item.store != session.store {
+		return inventory.ErrNotFound
+	}
+	key := countKey{sessionId, inventoryId}
+	c := r.db.t.counts[key]
+	c.counted = counted
+	r.db.t.counts[key] = c
+	return nil
+}
+
+func (r inventoryRepository) CloseStocktake(ctx context.Context, sessionId int, userId int) error {
+	defer r.db.lock()()
+	session, ok := r.db.t.stocktakes[sessionId]
+	if !ok || session.store != database.StoreOf(ctx) {
+		return inventory.ErrStocktakeNotFound
+	}
+	if session.Status != inventory.StocktakeOpen {
+		return inventory.ErrStocktakeClosed
+	}
+
+	var keys []countKey
+	for k := range r.db.t.counts {
+		if k.sessionId == sessionId {
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, func(a, b countKey) int { return cmp.Compare(a.inventoryId, b.inventoryId) })
+
+	note := fmt.Sprintf("stocktake #%d", sessionId)
+	for _, k := range keys {
+		item := r.db.t.inventory[k.inventoryId]
+		c := r.db.t.counts[k]
+		c.expected, c.unitCost = item.Stock, item.UnitCost
+		r.db.t.counts[k] = c
+
+		delta := c.counted - c.expected
+		if delta == 0 {
+			continue
+		}
+		newStock, err := r.updateStock(ctx, k.inventoryId, delta)
+		if err != nil {
+			return err
+		}
+		r.db.insertMovement(&inventory.StockMovement{
+			InventoryId: k.inventoryId,
+			Delta:       delta,
+			StockAfter:  newStock,
+			Reason:      inventory.ReasonStocktake,
+			Note:        note,
+			UserId:      userId,
+		})
+	}
+
+	session.Status, session.closed = inventory.StocktakeClosed, time.Now()
+	r.db.t.stocktakes[sessionId] = session
+	return nil
+}
+
+func (r inventoryRepository) GetStocktakeCounts(ctx context.Context, sessionIds []int) ([]*inventory.StocktakeCount, error) {
+	defer r.db.lock()()
+	var counts []*inventory.StocktakeCount
+	for k, c := range r.db.t.counts {
+		if !slices.Contains(sessionIds, k.sessionId) {
+			continue
+		}
+		item := r.db.t.inventory[k.inventoryId]
+		counts = append(counts, &inventory.StocktakeCount{
+			SessionId:   k.sessionId,
+			InventoryId: k.inventoryId,
+			Slug:        item.Slug,
+			Name:        item.Name,
+			Tags:        jsonCopy(item.Tags),
+			Counted:     c.counted,
+			Expected:    c.expected,
+			Variance:    c.counted - c.expected,
+			UnitCost:    c.unitCost,
+		})
+	}
+	slices.SortFunc(counts, func(a, b *inventory.StocktakeCount) int {
+		return cmp.Or(cmp.Compare(a.SessionId, b.SessionId), cmp.Compare(a.Slug, b.Slug))
+	})
+	return counts, nil
+}
+
+func (r inventoryRepository) CreateTag(ctx context.Context, tag *inventory.ManagedTag) error {
+	defer r.db.lock()()
+	if r.db.tagTaken(ctx, tag.Kind, tag.Name) {
+		return inventory.ErrDuplicateTag
+	}
+	tag.Id = r.db.nextID("inventory_tags")
+	r.db.t.tags[tag.Id] = tagRow{ManagedTag: inventory.ManagedTag{Id: tag.Id, Kind: tag.Kind, Name: tag.Name}, store: database.StoreOf(ctx)}
+	return nil
+}
+
+func (r inventoryRepository) GetTag(ctx context.Context, id int) (*inventory.ManagedTag, error) {
+	defer r.db.lock()()
+	row, ok := r.db.t.tags[id]
+	if !ok || row.store != database.StoreOf(ctx) {
+		return nil, inventory.ErrTagNotFound
+	}
+	return r.db.tagOut(row), nil
+}
+
+func (r inventoryRepository) ListTags(ctx context.Context, kind string) ([]*inventory.ManagedTag, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.tags,
+		func(row tagRow) bool { return row.store == database.StoreOf(ctx) && (kind == "" || row.Kind == kind) },
+		func(a, b tagRow) int { return cmp.Or(cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Name, b.Name)) })
+	return collect(rows, r.db.tagOut), nil
+}
+
+func (r inventoryRepository) RenameTag(ctx context.Context, id int, newName string) error {
+	defer r.db.lock()()
+	row, ok := r.db.t.tags[id]
+	if !ok || row.store != database.StoreOf(ctx) {
+		return inventory.ErrTagNotFound
+	}
+	if row.Name == newName {
+		return nil
+	}
+	if r.db.tagTaken(ctx, row.Kind, newName) {
+		return inventory.ErrDuplicateTag
+	}
+
+	oldName := row.Name
+	row.Name = newName
+	r.db.t.tags[id] = row
+	r.db.retag(ctx, row.Kind, oldName, func(tags []string) []string {
+		renamed := slices.Clone(tags)
+		for i, t := range renamed {
+			if t == oldName {
+				renamed[i] = newName
+			}
+		}
+		return renamed
+	}, newName)
+	return nil
+}
+
+func (r inventoryRepository) DeleteTag(ctx context.Context, id int) error {
+	defer r.db.lock()()
+	row, ok := r.db.t.tags[id]
+	if !ok || row.store != database.StoreOf(ctx) {
+		return inventory.ErrTagNotFound
+	}
+	delete(r.db.t.tags, id)
+	r.db.retag(ctx, row.Kind, row.Name, func(tags []string) []string {
+		return slices.DeleteFunc(slices.Clone(tags), func(t string) bool { return t == row.Name })
+	}, "")
+	return nil
+}
+
+func (r inventoryRepository) GetProductsUsing(ctx context.Context, slug string) ([]*inventory.ProductRef, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.products,
+		func(p productRow) bool {
+			if !p.in(ctx) || !p.live() || p.Recipe == nil {
+				return false
+			}
+			_, uses := (*p.Recipe)[slug]
+			return uses
+		},
+		func(a, b productRow) int { return cmp.Compare(a.Name, b.Name) })
+	return collect(rows, func(p productRow) *inventory.ProductRef {
+		return &inventory.ProductRef{Id: p.Id, Slug: p.Slug, Name: p.Name, Avail: p.Avail, Quantity: (*p.Recipe)[slug]}
+	}), nil
+}
+
+func (r inventoryRepository) SyncProductAvailability(ctx context.Context, slugs []string, reenable bool) ([]*inventory.AvailabilityEvent, error) {
+	defer r.db.lock()()
+
+	affected := sorted(r.db.t.products, func(p productRow) bool {
+		return p.in(ctx) && p.live() && p.Recipe != nil &&
+			slices.ContainsFunc(slugs, func(s string) bool { _, ok := (*p.Recipe)[s]; return ok })
+	}, nil)
+
+	var events []*inventory.AvailabilityEvent
+	now := time.Now()
+	for _, p := range affected {
+		makeable := true
+		for slug, qty := range *p.Recipe {
+			item := r.db.inventoryBySlug(ctx, slug, true)
+			if item == nil || item.Stock <= 0 || item.Stock < int64(qty) {
+				makeable = false
+			}
+		}
+		if !(p.Avail && !makeable) && !(reenable && p.auto86 && !p.Avail && makeable) {
+			continue
+		}
+
+		p.Avail, p.auto86, p.updated = makeable, !makeable, now
+		p.Revision++
+		r.db.t.products[p.Id] = p
+
+		cause := ""
+		for _, slug := range slices.Sorted(maps.Keys(*p.Recipe)) {
+			if slices.Contains(slugs, slug) {
+				cause = slug
+				break
+			}
+		}
+		events = append(events, &inventory.AvailabilityEvent{ProductId: p.Id, ProductSlug: p.Slug, Avail: p.Avail, Cause: cause, At: now.Unix()})
+	}
+	return events, nil
+}
+
+func (r inventoryRepository) GetParLevels(ctx context.Context) (map[string][]*inventory.Inventory, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.inventory,
+		func(row inventoryRow) bool { return row.in(ctx) && row.live() },
+		func(a, b inventoryRow) int {
+			return cmp.Or(cmp.Compare(a.Stock-a.MinStock, b.Stock-b.MinStock), cmp.Compare(a.Name, b.Name))
+		})
+
+	buckets := map[string][]*inventory.Inventory{
+		inventory.ParCritical:    {},
+		inventory.ParLow:         {},
+		inventory.ParOk:          {},
+		inventory.ParOverstocked: {},
+	}
+	for _, row := range rows {
+		bucket := inventory.ParOk
+		switch {
+		case row.Stock <= 0 || row.Stock*2 < row.MinStock:
+			bucket = inventory.ParCritical
+		case row.Stock < row.MinStock:
+			bucket = inventory.ParLow
+		case row.MaxStock > 0 && row.Stock > row.MaxStock:
+			bucket = inventory.ParOverstocked
+		}
+		buckets[bucket] = append(buckets[bucket], r.db.inventoryOut(row))
+	}
+	return buckets, nil
+}
+
+func (r inventoryRepository) GetConsumption(ctx context.Context, start, end time.Time) (map[string]int64, error) {
+	defer r.db.lock()()
+
+	recipes := r.db.productRecipes(ctx)
+	usage := make(map[string]int64)
+	add := func(slug string) {
+		for ingredient, qty := range recipes[slug].recipe {
+			usage[ingredient] += int64(qty)
+		}
+	}
+	for _, o := range r.db.t.orders {
+		if !o.in(ctx) || o.Status == order.StatusVoid || !between(o.created, start, end) {
+			continue
+		}
+		for _, slug := range o.Items {
+			add(slug)
+			for _, component := range recipes[slug].items {
+				add(component)
+			}
+		}
+	}
+	return usage, nil
+}
+
+func (r inventoryRepository) GetOutflowByReason(ctx context.Context, start, end time.Time) (map[string]map[string]int64, error) {
+	defer r.db.lock()()
+
+	type group struct{ slug, reason string }
+	sums := make(map[group]int64)
+	for _, m := range r.db.t.movements {
+		item, ok := r.db.t.inventory[m.InventoryId]
+		if !ok || !item.in(ctx) || !between(m.created, start, end) || (m.Delta >= 0 && m.Reason != inventory.ReasonSale) {
+			continue
+		}
+		sums[group{item.Slug, m.Reason}] += m.Delta
+	}
+
+	outflow := make(map[string]map[string]int64)
+	for g, sum := range sums {
+		if sum == 0 {
+			continue
+		}
+		if outflow[g.slug] == nil {
+			outflow[g.slug] = make(map[string]int64)
+		}
+		outflow[g.slug][g.reason] = -sum
+	}
+	return outflow, nil
+}
+
+func (r inventoryRepository) GetAverageStock(ctx context.Context, start, end time.Time) (map[string]float64, error) {
+	defer r.db.lock()()
+
+	sums, counts := make(map[string]int64), make(map[string]int)
+	for k, stock := range r.db.t.snapshots {
+		item, ok := r.db.t.inventory[k.inventoryId]
+		if !ok || !item.in(ctx) || k.day < start.Unix() || k.day > end.Unix() {
+			continue
+		}
+		sums[item.Slug] += stock
+		counts[item.Slug]++
+	}
+
+	avg := make(map[string]float64, len(sums))
+	for slug, sum := range sums {
+		avg[slug] = float64(sum) / float64(counts[slug])
+	}
+	return avg, nil
+}
+
+// getOne returns the live item of the store of ctx that passes keep.
+func (r inventoryRepository) getOne(ctx context.Context, keep func(inventoryRow) bool) (*inventory.Inventory, error) {
+	defer r.db.lock()()
+	for _, row := range r.db.t.inventory {
+		if row.in(ctx) && row.live() && keep(row) {
+			return r.db.inventoryOut(row), nil
+		}
+	}
+	return nil, inventory.ErrNotFound
+}
+
+// updateStock applies delta to a live item of the store of ctx and returns
+// the new stock, refusing to go below zero unless allowed to.
+func (r inventoryRepository) updateStock(ctx context.Context, id int, delta int64) (int64, error) {
+	row, ok := r.db.inventoryItem(ctx, id)
+	if !ok {
+		return 0, inventory.ErrNotFound
+	}
+	if !r.allowNegativeStock && row.Stock+delta < 0 {
+		return 0, inventory.ErrInsufficientStock
+	}
+	row.Stock += delta
+	row.updated = time.Now()
+	row.Revision++
+	r.db.t.inventory[id] = row
+	return row.Stock, nil
+}
+
+// inventoryItem returns the live item with the given id in the store of ctx.
+func (db *DB) inventoryItem(ctx context.Context, id int) (inventoryRow, bool) {
+	row, ok := db.t.inventory[id]
+	return row, ok && row.in(ctx) && row.live()
+}
+
+// inventoryBySlug returns the item with the given slug in the store of ctx,
+// if there is one; with live set, only if it isn't in the trash.
+func (db *DB) inventoryBySlug(ctx context.Context, slug string, live bool) *inventoryRow {
+	for _, row := range db.t.inventory {
+		if row.in(ctx) && row.Slug == slug && (row.live() || !live) {
+			return &row
+		}
+	}
+	return nil
+}
+
+// inventoryKeysTaken reports the slug or (non-empty) barcode being used by
+// another item of the store of ctx than exceptId, trashed ones included.
+func (db *DB) inventoryKeysTaken(ctx context.Context, slug, barcode string, exceptId int) error {
+	for _, row := range db.t.inventory {
+		if !row.in(ctx) || row.Id == exceptId {
+			continue
+		}
+		if row.Slug == slug {
+			return inventory.ErrDuplicateSlug
+		}
+		if barcode != "" && row.Barcode == barcode {
+			return inventory.ErrDuplicateBarcode
+		}
+	}
+	return nil
+}
+
+// inventoryOut is an item as the repository returns it, with what its
+// reservations hold.
+func (db *DB) inventoryOut(row inventoryRow) *inventory.Inventory {
+	inv := jsonCopy(row.Inventory)
+	inv.Reserved = db.reserved(row.Id)
+	inv.Created, inv.Updated, inv.Deleted = unix(row.created), unix(row.updated), unix(row.deleted)
+	return &inv
+}
+
+// reserved is the stock held for an item by unexpired reservations.
+func (db *DB) reserved(inventoryId int) int64 {
+	now := time.Now().Unix()
+	var n int64
+	for _, res := range db.t.reservations {
+		if res.InventoryId == inventoryId && res.ExpiresAt > now {
+			n += res.Quantity
+		}
+	}
+	return n
+}
+
+func (db *DB) insertMovement(m *inventory.StockMovement) {
+	row := movementRow{StockMovement: *m, created: time.Now()}
+	row.Id = db.nextID("inventory_movements")
+	db.t.movements[row.Id] = row
+	m.Id, m.Created = row.Id, unix(row.created)
+}
+
+func (db *DB) snapshotsWhere(keep func(snapshotKey, inventoryRow) bool, order func(a, b *inventory.StockSnapshot) int) []*inventory.StockSnapshot {
+	var snapshots []*inventory.StockSnapshot
+	for k, stock := range db.t.snapshots {
+		item, ok := db.t.inventory[k.inventoryId]
+		if ok && keep(k, item) {
+			snapshots = append(snapshots, &inventory.StockSnapshot{
+				InventoryId: k.inventoryId,
+				Slug:        item.Slug,
+				TakenOn:     time.Unix(k.day, 0).UTC(),
+				Stock:       stock,
+			})
+		}
+	}
+	slices.SortFunc(snapshots, func(a, b *inventory.StockSnapshot) int {
+		return cmp.Or(order(a, b), cmp.Compare(a.InventoryId, b.InventoryId))
+	})
+	return snapshots
+}
+
+// supplierPricesWhere returns the quotes that pass keep with their item's
+// slug, by price and lead time, and first by slug if bySlug is set.
+func (db *DB) supplierPricesWhere(keep func(supplierPriceRow, inventoryRow) bool, bySlug bool) []*inventory.SupplierPrice {
+	var prices []*inventory.SupplierPrice
+	for _, sp := range sorted(db.t.supplierPrices, always, nil) {
+		item, ok := db.t.inventory[sp.InventoryId]
+		if ok && keep(sp, item) {
+			out := sp.SupplierPrice
+			out.Slug = item.Slug
+			prices = append(prices, &out)
+		}
+	}
+	slices.SortStableFunc(prices, func(a, b *inventory.SupplierPrice) int {
+		c := cmp.Or(cmp.Compare(a.UnitPrice, b.UnitPrice), cmp.Compare(a.LeadTimeDays, b.LeadTimeDays))
+		if bySlug {
+			c = cmp.Or(cmp.Compare(a.Slug, b.Slug), c)
+		}
+		return c
+	})
+	return prices
+}
+
+// productRecipe is the part of a product orderUsage needs.
+type productRecipe struct {
+	items  []string
+	recipe map[string]int
+}
+
+// productRecipes returns the bundle items and recipe of every product of
+// the store of ctx by slug, trashed ones included as the SQL joins do.
+func (db *DB) productRecipes(ctx context.Context) map[string]productRecipe {
+	recipes := make(map[string]productRecipe)
+	for _, p := range db.t.products {
+		if !p.in(ctx) {
+			continue
+		}
+		var pr productRecipe
+		if p.Items != nil {
+			pr.items = *p.Items
+		}
+		if p.Recipe != nil {
+			pr.recipe = *p.Recipe
+		}
+		recipes[p.Slug] = pr
+	}
+	return recipes
+}
+
+// orderUsage totals what the given product slugs take out of stock, per
+// live item, ordered by id. A bundle counts its own recipe and its
+// components' recipes, one level deep.
+func (db *DB) orderUsage(ctx context.Context, items []string) []*inventory.OrderUsage {
+	recipes := db.productRecipes(ctx)
+	totals := make(map[string]int64)
+	add := func(slug string) {
+		for ingredient, qty := range recipes[slug].recipe {
+			totals[ingredient] += int64(qty)
+		}
+	}
+	for _, slug := range items {
+		add(slug)
+		for _, component := range recipes[slug].items {
+			add(component)
+		}
+	}
+
+	rows := sorted(db.t.inventory, func(row inventoryRow) bool {
+		return row.in(ctx) && row.live() && totals[row.Slug] > 0
+	}, nil)
+	return collect(rows, func(row inventoryRow) *inventory.OrderUsage {
+		return &inventory.OrderUsage{InventoryId: row.Id, Slug: row.Slug, Quantity: totals[row.Slug]}
+	})
+}
+
+// listFilter is the filter List and Count share.
+func listFilter(ctx context.Context, opts inventory.ListOptions) func(inventoryRow) bool {
+	return func(row inventoryRow) bool {
+		return row.in(ctx) && row.live() != opts.Deleted &&
+			hasTags(row, opts.Tags) &&
+			(opts.Label == "" || row.Label == opts.Label) &&
+			(opts.StockMin == nil || row.Stock >= *opts.StockMin) &&
+			(opts.StockMax == nil || row.Stock <= *opts.StockMax) &&
+			(!opts.BelowThreshold || row.Stock < row.MinStock) &&
+			inPeriod(opts.Period, row.created, row.updated)
+	}
+}
+
+// hasTags reports whether the item carries every one of tags.
+func hasTags(row inventoryRow, tags []string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(row.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func (db *DB) tagTaken(ctx context.Context, kind, name string) bool {
+	for _, row := range db.t.tags {
+		if row.store == database.StoreOf(ctx) && row.Kind == kind && row.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// tagOut is a managed tag with the number of live items using it.
+func (db *DB) tagOut(row tagRow) *inventory.ManagedTag {
+	tag := row.ManagedTag
+	for _, item := range db.t.inventory {
+		if item.store != row.store || !item.live() {
+			continue
+		}
+		if row.Kind == inventory.TagKindLabel && item.Label == row.Name ||
+			row.Kind != inventory.TagKindLabel && slices.Contains(item.Tags, row.Name) {
+			tag.Items++
+		}
+	}
+	return &tag
+}
+
+// retag cascades a change to a managed tag to the items of the store of ctx
+// using it: labels named name become label, and tags go through edit.
+func (db *DB) retag(ctx context.Context, kind, name string, edit func([]string) []string, label string) {
+	now := time.Now()
+	for id, item := range db.t.inventory {
+		if !item.in(ctx) {
+			continue
+		}
+		switch {
+		case kind == inventory.TagKindLabel && item.Label == name:
+			item.Label = label
+		case kind != inventory.TagKindLabel && slices.Contains(item.Tags, name):
+			item.Tags = edit(item.Tags)
+		default:
+			continue
+		}
+		item.updated = now
+		item.Revision++
+		db.t.inventory[id] = item
+	}
+}
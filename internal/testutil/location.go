@@ -0,0 +1,150 @@
+package testutil
+
+import (
+	"cmp"
+	"context"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/entities/location"
+)
+
+type locationRow struct {
+	location.Location
+	stamps
+}
+
+func (r locationRow) rowID() int { return r.Id }
+
+func (r locationRow) out() *location.Location {
+	loc := r.Location
+	loc.Created, loc.Updated = unix(r.created), unix(r.updated)
+	return &loc
+}
+
+// Locations returns the DB as a location.LocationRepository.
+func (db *DB) Locations() location.LocationRepository {
+	return locationRepository{db}
+}
+
+type locationRepository struct {
+	db *DB
+}
+
+func (r locationRepository) Create(ctx context.Context, loc *location.Location) error {
+	if loc.Slug == "" || loc.Name == "" {
+		return location.ErrInvalidLocationInput
+	}
+	defer r.db.lock()()
+
+	store := database.StoreOf(ctx)
+	if r.db.locationSlugTaken(store, loc.Slug, 0) {
+		return location.ErrDuplicateLocationSlug
+	}
+	row := locationRow{Location: *loc, stamps: newStamps(ctx)}
+	row.Id = r.db.nextID("locations")
+	r.db.t.locations[row.Id] = row
+
+	loc.Id, loc.Created, loc.Updated = row.Id, unix(row.created), unix(row.updated)
+	return nil
+}
+
+func (r locationRepository) Import(ctx context.Context, client database.SQLClient, loc *location.Location) (bool, error) {
+	defer r.db.lock()()
+
+	store := database.StoreOf(ctx)
+	if _, ok := r.db.t.locations[loc.Id]; ok || r.db.locationSlugTaken(store, loc.Slug, 0) {
+		return false, nil
+	}
+	r.db.t.locations[loc.Id] = locationRow{Location: *loc, stamps: stamps{store: store, created: fromUnix(loc.Created), updated: fromUnix(loc.Updated)}}
+	r.db.sawID("locations", loc.Id)
+	return true, nil
+}
+
+func (r locationRepository) GetByID(ctx context.Context, id int) (*location.Location, error) {
+	defer r.db.lock()()
+	row, ok := r.db.location(ctx, id)
+	if !ok {
+		return nil, location.ErrLocationNotFound
+	}
+	return row.out(), nil
+}
+
+func (r locationRepository) GetBySlug(ctx context.Context, slug string) (*location.Location, error) {
+	defer r.db.lock()()
+	for _, row := range r.db.t.locations {
+		if row.in(ctx) && row.Slug == slug {
+			return row.out(), nil
+		}
+	}
+	return nil, location.ErrLocationNotFound
+}
+
+func (r locationRepository) Update(ctx context.Context, loc *location.Location) error {
+	if loc.Id == 0 {
+		return location.ErrInvalidLocationInput
+	}
+	defer r.db.lock()()
+
+	row, ok := r.db.location(ctx, loc.Id)
+	if !ok {
+		return location.ErrLocationNotFound
+	}
+	if r.db.locationSlugTaken(row.store, loc.Slug, loc.Id) {
+		return location.ErrDuplicateLocationSlug
+	}
+	row.Slug, row.Name, row.Address, row.updated = loc.Slug, loc.Name, loc.Address, time.Now()
+	r.db.t.locations[row.Id] = row
+	return nil
+}
+
+// Delete refuses a location orders were rung up at, as the foreign keys
+// from orders and the archive do, and drops its user assignments.
+func (r locationRepository) Delete(ctx context.Context, id int) error {
+	defer r.db.lock()()
+
+	if _, ok := r.db.location(ctx, id); !ok {
+		return location.ErrLocationNotFound
+	}
+	for _, orders := range []map[int]orderRow{r.db.t.orders, r.db.t.archive} {
+		for _, o := range orders {
+			if o.LocationId == id {
+				return location.ErrLocationInUse
+			}
+		}
+	}
+	delete(r.db.t.locations, id)
+	for ul := range r.db.t.userLocations {
+		if ul.locationId == id {
+			delete(r.db.t.userLocations, ul)
+		}
+	}
+	return nil
+}
+
+func (r locationRepository) List(ctx context.Context) ([]*location.Location, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.locations,
+		func(row locationRow) bool { return row.in(ctx) },
+		func(a, b locationRow) int { return cmp.Compare(a.Name, b.Name) })
+	locations := collect(rows, locationRow.out)
+	if locations == nil {
+		locations = []*location.Location{}
+	}
+	return locations, nil
+}
+
+// location returns the location with the given id in the store of ctx.
+func (db *DB) location(ctx context.Context, id int) (locationRow, bool) {
+	row, ok := db.t.locations[id]
+	return row, ok && row.in(ctx)
+}
+
+func (db *DB) locationSlugTaken(store int, slug string, exceptId int) bool {
+	for _, row := range db.t.locations {
+		if row.store == store && row.Slug == slug && row.Id != exceptId {
+			return true
+		}
+	}
+	return false
+}
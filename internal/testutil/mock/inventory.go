@@ -0,0 +1,2417 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mock
+
+import (
+	"context"
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/entities/inventory"
+	"io"
+	"sync"
+	"time"
+)
+
+// Ensure, that InventoryService does implement inventory.InventoryService.
+// If this is not the case, regenerate this file with moq.
+var _ inventory.InventoryService = &InventoryService{}
+
+// InventoryService is a mock implementation of inventory.InventoryService.
+//
+//	func TestSomethingThatUsesInventoryService(t *testing.T) {
+//
+//		// make and configure a mocked inventory.InventoryService
+//		mockedInventoryService := &InventoryService{
+//			AdjustStockFunc: func(ctx context.Context, id int, adj inventory.StockAdjustment) (*inventory.AdjustmentResult, error) {
+//				panic("mock out the AdjustStock method")
+//			},
+//			CloseStocktakeFunc: func(ctx context.Context, sessionId int, userId int) (*inventory.StocktakeReport, error) {
+//				panic("mock out the CloseStocktake method")
+//			},
+//			CompareSuppliersFunc: func(ctx context.Context, belowThresholdOnly bool) ([]*inventory.SupplierComparison, error) {
+//				panic("mock out the CompareSuppliers method")
+//			},
+//			ConsumeForOrderFunc: func(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) ([]string, error) {
+//				panic("mock out the ConsumeForOrder method")
+//			},
+//			CreateInventoryFunc: func(ctx context.Context, input inventory.Inventory) (*inventory.Inventory, error) {
+//				panic("mock out the CreateInventory method")
+//			},
+//			CreateTagFunc: func(ctx context.Context, kind string, name string) (*inventory.ManagedTag, error) {
+//				panic("mock out the CreateTag method")
+//			},
+//			DeleteInventoryFunc: func(ctx context.Context, id int) error {
+//				panic("mock out the DeleteInventory method")
+//			},
+//			DeleteTagFunc: func(ctx context.Context, id int) error {
+//				panic("mock out the DeleteTag method")
+//			},
+//			ExportCSVFunc: func(ctx context.Context, params inventory.ListParams, w io.Writer) error {
+//				panic("mock out the ExportCSV method")
+//			},
+//			ForecastConsumptionFunc: func(ctx context.Context, days int) ([]*inventory.Forecast, error) {
+//				panic("mock out the ForecastConsumption method")
+//			},
+//			GetByBarcodeFunc: func(ctx context.Context, barcode string) (*inventory.Inventory, error) {
+//				panic("mock out the GetByBarcode method")
+//			},
+//			GetBySlugsFunc: func(ctx context.Context, slugs []string) ([]*inventory.Inventory, error) {
+//				panic("mock out the GetBySlugs method")
+//			},
+//			GetConsumptionReportFunc: func(ctx context.Context, start time.Time, end time.Time) ([]*inventory.ConsumptionLine, error) {
+//				panic("mock out the GetConsumptionReport method")
+//			},
+//			GetInventoryFunc: func(ctx context.Context, idOrSlug any) (*inventory.Inventory, error) {
+//				panic("mock out the GetInventory method")
+//			},
+//			GetMovementsFunc: func(ctx context.Context, id int, limit int) ([]*inventory.StockMovement, error) {
+//				panic("mock out the GetMovements method")
+//			},
+//			GetParDashboardFunc: func(ctx context.Context) (*inventory.ParDashboard, error) {
+//				panic("mock out the GetParDashboard method")
+//			},
+//			GetProductsUsingFunc: func(ctx context.Context, id int) ([]*inventory.ProductRef, error) {
+//				panic("mock out the GetProductsUsing method")
+//			},
+//			GetShrinkageReportFunc: func(ctx context.Context, start time.Time, end time.Time) (*inventory.ShrinkageReport, error) {
+//				panic("mock out the GetShrinkageReport method")
+//			},
+//			GetStockHistoryFunc: func(ctx context.Context, id int, start time.Time, end time.Time) ([]*inventory.StockSnapshot, error) {
+//				panic("mock out the GetStockHistory method")
+//			},
+//			GetStockOnFunc: func(ctx context.Context, day time.Time) ([]*inventory.StockSnapshot, error) {
+//				panic("mock out the GetStockOn method")
+//			},
+//			GetStocktakeReportFunc: func(ctx context.Context, sessionId int) (*inventory.StocktakeReport, error) {
+//				panic("mock out the GetStocktakeReport method")
+//			},
+//			GetSummaryFunc: func(ctx context.Context) (*inventory.Summary, error) {
+//				panic("mock out the GetSummary method")
+//			},
+//			GetSupplierPricesFunc: func(ctx context.Context, id int) ([]*inventory.SupplierPrice, error) {
+//				panic("mock out the GetSupplierPrices method")
+//			},
+//			GetTurnoverReportFunc: func(ctx context.Context, start time.Time, end time.Time) ([]*inventory.TurnoverLine, error) {
+//				panic("mock out the GetTurnoverReport method")
+//			},
+//			ImportCSVFunc: func(ctx context.Context, r io.Reader) (*inventory.ImportReport, error) {
+//				panic("mock out the ImportCSV method")
+//			},
+//			ListInventoryFunc: func(ctx context.Context, params inventory.ListParams) ([]*inventory.Inventory, int, error) {
+//				panic("mock out the ListInventory method")
+//			},
+//			ListTagsFunc: func(ctx context.Context, kind string) ([]*inventory.ManagedTag, error) {
+//				panic("mock out the ListTags method")
+//			},
+//			MissingIngredientsFunc: func(ctx context.Context, slugs []string) ([]string, error) {
+//				panic("mock out the MissingIngredients method")
+//			},
+//			OnAvailabilityChangeFunc: func(fn func(inventory.AvailabilityEvent))  {
+//				panic("mock out the OnAvailabilityChange method")
+//			},
+//			OnStockAlertFunc: func(fn func(inventory.StockAlert))  {
+//				panic("mock out the OnStockAlert method")
+//			},
+//			OpenStocktakeFunc: func(ctx context.Context, note string, userId int) (*inventory.StocktakeSession, error) {
+//				panic("mock out the OpenStocktake method")
+//			},
+//			OrderStockChangedFunc: func(ctx context.Context, items []string, sold bool)  {
+//				panic("mock out the OrderStockChanged method")
+//			},
+//			PurgeDeletedFunc: func(ctx context.Context, before time.Time) (int, error) {
+//				panic("mock out the PurgeDeleted method")
+//			},
+//			PurgeInventoryFunc: func(ctx context.Context, id int) error {
+//				panic("mock out the PurgeInventory method")
+//			},
+//			RecordCountFunc: func(ctx context.Context, sessionId int, inventoryId int, counted int64) error {
+//				panic("mock out the RecordCount method")
+//			},
+//			ReleaseExpiredFunc: func(ctx context.Context) (int64, error) {
+//				panic("mock out the ReleaseExpired method")
+//			},
+//			ReleaseForOrderFunc: func(ctx context.Context, client database.SQLClient, orderId int) (int64, error) {
+//				panic("mock out the ReleaseForOrder method")
+//			},
+//			RemoveSupplierPriceFunc: func(ctx context.Context, id int, supplier string) error {
+//				panic("mock out the RemoveSupplierPrice method")
+//			},
+//			RenameTagFunc: func(ctx context.Context, id int, name string) (*inventory.ManagedTag, error) {
+//				panic("mock out the RenameTag method")
+//			},
+//			ReserveFunc: func(ctx context.Context, id int, orderId int, quantity int64, ttl time.Duration) (*inventory.Reservation, error) {
+//				panic("mock out the Reserve method")
+//			},
+//			RestockForOrderFunc: func(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) error {
+//				panic("mock out the RestockForOrder method")
+//			},
+//			RestoreInventoryFunc: func(ctx context.Context, id int) error {
+//				panic("mock out the RestoreInventory method")
+//			},
+//			SetSupplierPriceFunc: func(ctx context.Context, id int, sp inventory.SupplierPrice) (*inventory.SupplierPrice, error) {
+//				panic("mock out the SetSupplierPrice method")
+//			},
+//			TakeSnapshotFunc: func(ctx context.Context) (int, error) {
+//				panic("mock out the TakeSnapshot method")
+//			},
+//			UpdateInventoryFunc: func(ctx context.Context, id int, input inventory.Inventory) error {
+//				panic("mock out the UpdateInventory method")
+//			},
+//		}
+//
+//		// use mockedInventoryService in code that requires inventory.InventoryService
+//		// and then make assertions.
+//
+//	}
+type InventoryService struct {
+	// AdjustStockFunc mocks the AdjustStock method.
+	AdjustStockFunc func(ctx context.Context, id int, adj inventory.StockAdjustment) (*inventory.AdjustmentResult, error)
+
+	// CloseStocktakeFunc mocks the CloseStocktake method.
+	CloseStocktakeFunc func(ctx context.Context, sessionId int, userId int) (*inventory.StocktakeReport, error)
+
+	// CompareSuppliersFunc mocks the CompareSuppliers method.
+	CompareSuppliersFunc func(ctx context.Context, belowThresholdOnly bool) ([]*inventory.SupplierComparison, error)
+
+	// ConsumeForOrderFunc mocks the ConsumeForOrder method.
+	ConsumeForOrderFunc func(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) ([]string, error)
+
+	// CreateInventoryFunc mocks the CreateInventory method.
+	CreateInventoryFunc func(ctx context.Context, input inventory.Inventory) (*inventory.Inventory, error)
+
+	// CreateTagFunc mocks the CreateTag method.
+	CreateTagFunc func(ctx context.Context, kind string, name string) (*inventory.ManagedTag, error)
+
+	// DeleteInventoryFunc mocks the DeleteInventory method.
+	DeleteInventoryFunc func(ctx context.Context, id int) error
+
+	// DeleteTagFunc mocks the DeleteTag method.
+	DeleteTagFunc func(ctx context.Context, id int) error
+
+	// ExportCSVFunc mocks the ExportCSV method.
+	ExportCSVFunc func(ctx context.Context, params inventory.ListParams, w io.Writer) error
+
+	// ForecastConsumptionFunc mocks the ForecastConsumption method.
+	ForecastConsumptionFunc func(ctx context.Context, days int) ([]*inventory.Forecast, error)
+
+	// GetByBarcodeFunc mocks the GetByBarcode method.
+	GetByBarcodeFunc func(ctx context.Context, barcode string) (*inventory.Inventory, error)
+
+	// GetBySlugsFunc mocks the GetBySlugs method.
+	GetBySlugsFunc func(ctx context.Context, slugs []string) ([]*inventory.Inventory, error)
+
+	// GetConsumptionReportFunc mocks the GetConsumptionReport method.
+	GetConsumptionReportFunc func(ctx context.Context, start time.Time, end time.Time) ([]*inventory.ConsumptionLine, error)
+
+	// GetInventoryFunc mocks the GetInventory method.
+	GetInventoryFunc func(ctx context.Context, idOrSlug any) (*inventory.Inventory, error)
+
+	// GetMovementsFunc mocks the GetMovements method.
+	GetMovementsFunc func(ctx context.Context, id int, limit int) ([]*inventory.StockMovement, error)
+
+	// GetParDashboardFunc mocks the GetParDashboard method.
+	GetParDashboardFunc func(ctx context.Context) (*inventory.ParDashboard, error)
+
+	// GetProductsUsingFunc mocks the GetProductsUsing method.
+	GetProductsUsingFunc func(ctx context.Context, id int) ([]*inventory.ProductRef, error)
+
+	// GetShrinkageReportFunc mocks the GetShrinkageReport method.
+	GetShrinkageReportFunc func(ctx context.Context, start time.Time, end time.Time) (*inventory.ShrinkageReport, error)
+
+	// GetStockHistoryFunc mocks the GetStockHistory method.
+	GetStockHistoryFunc func(ctx context.Context, id int, start time.Time, end time.Time) ([]*inventory.StockSnapshot, error)
+
+	// GetStockOnFunc mocks the GetStockOn method.
+	GetStockOnFunc func(ctx context.Context, day time.Time) ([]*inventory.StockSnapshot, error)
+
+	// GetStocktakeReportFunc mocks the GetStocktakeReport method.
+	GetStocktakeReportFunc func(ctx context.Context, sessionId int) (*inventory.StocktakeReport, error)
+
+	// GetSummaryFunc mocks the GetSummary method.
+	GetSummaryFunc func(ctx context.Context) (*inventory.Summary, error)
+
+	// GetSupplierPricesFunc mocks the GetSupplierPrices method.
+	GetSupplierPricesFunc func(ctx context.Context, id int) ([]*inventory.SupplierPrice, error)
+
+	// GetTurnoverReportFunc mocks the GetTurnoverReport method.
+	GetTurnoverReportFunc func(ctx context.Context, start time.Time, end time.Time) ([]*inventory.TurnoverLine, error)
+
+	// ImportCSVFunc mocks the ImportCSV method.
+	ImportCSVFunc func(ctx context.Context, r io.Reader) (*inventory.ImportReport, error)
+
+	// ListInventoryFunc mocks the ListInventory method.
+	ListInventoryFunc func(ctx context.Context, params inventory.ListParams) ([]*inventory.Inventory, int, error)
+
+	// ListTagsFunc mocks the ListTags method.
+	ListTagsFunc func(ctx context.Context, kind string) ([]*inventory.ManagedTag, error)
+
+	// MissingIngredientsFunc mocks the MissingIngredients method.
+	MissingIngredientsFunc func(ctx context.Context, slugs []string) ([]string, error)
+
+	// OnAvailabilityChangeFunc mocks the OnAvailabilityChange method.
+	OnAvailabilityChangeFunc func(fn func(inventory.AvailabilityEvent))
+
+	// OnStockAlertFunc mocks the OnStockAlert method.
+	OnStockAlertFunc func(fn func(inventory.StockAlert))
+
+	// OpenStocktakeFunc mocks the OpenStocktake method.
+	OpenStocktakeFunc func(ctx context.Context, note string, userId int) (*inventory.StocktakeSession, error)
+
+	// OrderStockChangedFunc mocks the OrderStockChanged method.
+	OrderStockChangedFunc func(ctx context.Context, items []string, sold bool)
+
+	// PurgeDeletedFunc mocks the PurgeDeleted method.
+	PurgeDeletedFunc func(ctx context.Context, before time.Time) (int, error)
+
+	// PurgeInventoryFunc mocks the PurgeInventory method.
+	PurgeInventoryFunc func(ctx context.Context, id int) error
+
+	// RecordCountFunc mocks the RecordCount method.
+	RecordCountFunc func(ctx context.Context, sessionId int, inventoryId int, counted int64) error
+
+	// ReleaseExpiredFunc mocks the ReleaseExpired method.
+	ReleaseExpiredFunc func(ctx context.Context) (int64, error)
+
+	// ReleaseForOrderFunc mocks the ReleaseForOrder method.
+	ReleaseForOrderFunc func(ctx context.Context, client database.SQLClient, orderId int) (int64, error)
+
+	// RemoveSupplierPriceFunc mocks the RemoveSupplierPrice method.
+	RemoveSupplierPriceFunc func(ctx context.Context, id int, supplier string) error
+
+	// RenameTagFunc mocks the RenameTag method.
+	RenameTagFunc func(ctx context.Context, id int, name string) (*inventory.ManagedTag, error)
+
+	// ReserveFunc mocks the Reserve method.
+	ReserveFunc func(ctx context.Context, id int, orderId int, quantity int64, ttl time.Duration) (*inventory.Reservation, error)
+
+	// RestockForOrderFunc mocks the RestockForOrder method.
+	RestockForOrderFunc func(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) error
+
+	// RestoreInventoryFunc mocks the RestoreInventory method.
+	RestoreInventoryFunc func(ctx context.Context, id int) error
+
+	// SetSupplierPriceFunc mocks the SetSupplierPrice method.
+	SetSupplierPriceFunc func(ctx context.Context, id int, sp inventory.SupplierPrice) (*inventory.SupplierPrice, error)
+
+	// TakeSnapshotFunc mocks the TakeSnapshot method.
+	TakeSnapshotFunc func(ctx context.Context) (int, error)
+
+	// UpdateInventoryFunc mocks the UpdateInventory method.
+	UpdateInventoryFunc func(ctx context.Context, id int, input inventory.Inventory) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// AdjustStock holds details about calls to the AdjustStock method.
+		AdjustStock []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Adj is the adj argument value.
+			Adj inventory.StockAdjustment
+		}
+		// CloseStocktake holds details about calls to the CloseStocktake method.
+		CloseStocktake []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// SessionId is the sessionId argument value.
+			SessionId int
+			// UserId is the userId argument value.
+			UserId int
+		}
+		// CompareSuppliers holds details about calls to the CompareSuppliers method.
+		CompareSuppliers []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// BelowThresholdOnly is the belowThresholdOnly argument value.
+			BelowThresholdOnly bool
+		}
+		// ConsumeForOrder holds details about calls to the ConsumeForOrder method.
+		ConsumeForOrder []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Client is the client argument value.
+			Client database.SQLClient
+			// OrderId is the orderId argument value.
+			OrderId int
+			// Items is the items argument value.
+			Items []string
+			// UserId is the userId argument value.
+			UserId int
+		}
+		// CreateInventory holds details about calls to the CreateInventory method.
+		CreateInventory []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Input is the input argument value.
+			Input inventory.Inventory
+		}
+		// CreateTag holds details about calls to the CreateTag method.
+		CreateTag []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Kind is the kind argument value.
+			Kind string
+			// Name is the name argument value.
+			Name string
+		}
+		// DeleteInventory holds details about calls to the DeleteInventory method.
+		DeleteInventory []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// DeleteTag holds details about calls to the DeleteTag method.
+		DeleteTag []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// ExportCSV holds details about calls to the ExportCSV method.
+		ExportCSV []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Params is the params argument value.
+			Params inventory.ListParams
+			// W is the w argument value.
+			W io.Writer
+		}
+		// ForecastConsumption holds details about calls to the ForecastConsumption method.
+		ForecastConsumption []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Days is the days argument value.
+			Days int
+		}
+		// GetByBarcode holds details about calls to the GetByBarcode method.
+		GetByBarcode []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Barcode is the barcode argument value.
+			Barcode string
+		}
+		// GetBySlugs holds details about calls to the GetBySlugs method.
+		GetBySlugs []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Slugs is the slugs argument value.
+			Slugs []string
+		}
+		// GetConsumptionReport holds details about calls to the GetConsumptionReport method.
+		GetConsumptionReport []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Start is the start argument value.
+			Start time.Time
+			// End is the end argument value.
+			End time.Time
+		}
+		// GetInventory holds details about calls to the GetInventory method.
+		GetInventory []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IdOrSlug is the idOrSlug argument value.
+			IdOrSlug any
+		}
+		// GetMovements holds details about calls to the GetMovements method.
+		GetMovements []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// GetParDashboard holds details about calls to the GetParDashboard method.
+		GetParDashboard []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// GetProductsUsing holds details about calls to the GetProductsUsing method.
+		GetProductsUsing []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// GetShrinkageReport holds details about calls to the GetShrinkageReport method.
+		GetShrinkageReport []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Start is the start argument value.
+			Start time.Time
+			// End is the end argument value.
+			End time.Time
+		}
+		// GetStockHistory holds details about calls to the GetStockHistory method.
+		GetStockHistory []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Start is the start argument value.
+			Start time.Time
+			// End is the end argument value.
+			End time.Time
+		}
+		// GetStockOn holds details about calls to the GetStockOn method.
+		GetStockOn []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Day is the day argument value.
+			Day time.Time
+		}
+		// GetStocktakeReport holds details about calls to the GetStocktakeReport method.
+		GetStocktakeReport []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// SessionId is the sessionId argument value.
+			SessionId int
+		}
+		// GetSummary holds details about calls to the GetSummary method.
+		GetSummary []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// GetSupplierPrices holds details about calls to the GetSupplierPrices method.
+		GetSupplierPrices []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// GetTurnoverReport holds details about calls to the GetTurnoverReport method.
+		GetTurnoverReport []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Start is the start argument value.
+			Start time.Time
+			// End is the end argument value.
+			End time.Time
+		}
+		// ImportCSV holds details about calls to the ImportCSV method.
+		ImportCSV []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// R is the r argument value.
+			R io.Reader
+		}
+		// ListInventory holds details about calls to the ListInventory method.
+		ListInventory []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Params is the params argument value.
+			Params inventory.ListParams
+		}
+		// ListTags holds details about calls to the ListTags method.
+		ListTags []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Kind is the kind argument value.
+			Kind string
+		}
+		// MissingIngredients holds details about calls to the MissingIngredients method.
+		MissingIngredients []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Slugs is the slugs argument value.
+			Slugs []string
+		}
+		// OnAvailabilityChange holds details about calls to the OnAvailabilityChange method.
+		OnAvailabilityChange []struct {
+			// Fn is the fn argument value.
+			Fn func(inventory.AvailabilityEvent)
+		}
+		// OnStockAlert holds details about calls to the OnStockAlert method.
+		OnStockAlert []struct {
+			// Fn is the fn argument value.
+			Fn func(inventory.StockAlert)
+		}
+		// OpenStocktake holds details about calls to the OpenStocktake method.
+		OpenStocktake []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Note is the note argument value.
+			Note string
+			// UserId is the userId argument value.
+			UserId int
+		}
+		// OrderStockChanged holds details about calls to the OrderStockChanged method.
+		OrderStockChanged []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Items is the items argument value.
+			Items []string
+			// Sold is the sold argument value.
+			Sold bool
+		}
+		// PurgeDeleted holds details about calls to the PurgeDeleted method.
+		PurgeDeleted []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Before is the before argument value.
+			Before time.Time
+		}
+		// PurgeInventory holds details about calls to the PurgeInventory method.
+		PurgeInventory []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// RecordCount holds details about calls to the RecordCount method.
+		RecordCount []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// SessionId is the sessionId argument value.
+			SessionId int
+			// InventoryId is the inventoryId argument value.
+			InventoryId int
+			// Counted is the counted argument value.
+			Counted int64
+		}
+		// ReleaseExpired holds details about calls to the ReleaseExpired method.
+		ReleaseExpired []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// ReleaseForOrder holds details about calls to the ReleaseForOrder method.
+		ReleaseForOrder []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Client is the client argument value.
+			Client database.SQLClient
+			// OrderId is the orderId argument value.
+			OrderId int
+		}
+		// RemoveSupplierPrice holds details about calls to the RemoveSupplierPrice method.
+		RemoveSupplierPrice []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Supplier is the supplier argument value.
+			Supplier string
+		}
+		// RenameTag holds details about calls to the RenameTag method.
+		RenameTag []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Name is the name argument value.
+			Name string
+		}
+		// Reserve holds details about calls to the Reserve method.
+		Reserve []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// OrderId is the orderId argument value.
+			OrderId int
+			// Quantity is the quantity argument value.
+			Quantity int64
+			// TTL is the ttl argument value.
+			TTL time.Duration
+		}
+		// RestockForOrder holds details about calls to the RestockForOrder method.
+		RestockForOrder []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Client is the client argument value.
+			Client database.SQLClient
+			// OrderId is the orderId argument value.
+			OrderId int
+			// Items is the items argument value.
+			Items []string
+			// UserId is the userId argument value.
+			UserId int
+		}
+		// RestoreInventory holds details about calls to the RestoreInventory method.
+		RestoreInventory []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// SetSupplierPrice holds details about calls to the SetSupplierPrice method.
+		SetSupplierPrice []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Sp is the sp argument value.
+			Sp inventory.SupplierPrice
+		}
+		// TakeSnapshot holds details about calls to the TakeSnapshot method.
+		TakeSnapshot []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// UpdateInventory holds details about calls to the UpdateInventory method.
+		UpdateInventory []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Input is the input argument value.
+			Input inventory.Inventory
+		}
+	}
+	lockAdjustStock          sync.RWMutex
+	lockCloseStocktake       sync.RWMutex
+	lockCompareSuppliers     sync.RWMutex
+	lockConsumeForOrder      sync.RWMutex
+	lockCreateInventory      sync.RWMutex
+	lockCreateTag            sync.RWMutex
+	lockDeleteInventory      sync.RWMutex
+	lockDeleteTag            sync.RWMutex
+	lockExportCSV            sync.RWMutex
+	lockForecastConsumption  sync.RWMutex
+	lockGetByBarcode         sync.RWMutex
+	lockGetBySlugs           sync.RWMutex
+	lockGetConsumptionReport sync.RWMutex
+	lockGetInventory         sync.RWMutex
+	lockGetMovements         sync.RWMutex
+	lockGetParDashboard      sync.RWMutex
+	lockGetProductsUsing     sync.RWMutex
+	lockGetShrinkageReport   sync.RWMutex
+	lockGetStockHistory      sync.RWMutex
+	lockGetStockOn           sync.RWMutex
+	lockGetStocktakeReport   sync.RWMutex
+	lockGetSummary           sync.RWMutex
+	lockGetSupplierPrices    sync.RWMutex
+	lockGetTurnoverReport    sync.RWMutex
+	lockImportCSV            sync.RWMutex
+	lockListInventory        sync.RWMutex
+	lockListTags             sync.RWMutex
+	lockMissingIngredients   sync.RWMutex
+	lockOnAvailabilityChange sync.RWMutex
+	lockOnStockAlert         sync.RWMutex
+	lockOpenStocktake        sync.RWMutex
+	lockOrderStockChanged    sync.RWMutex
+	lockPurgeDeleted         sync.RWMutex
+	lockPurgeInventory       sync.RWMutex
+	lockRecordCount          sync.RWMutex
+	lockReleaseExpired       sync.RWMutex
+	lockReleaseForOrder      sync.RWMutex
+	lockRemoveSupplierPrice  sync.RWMutex
+	lockRenameTag            sync.RWMutex
+	lockReserve              sync.RWMutex
+	lockRestockForOrder      sync.RWMutex
+	lockRestoreInventory     sync.RWMutex
+	lockSetSupplierPrice     sync.RWMutex
+	lockTakeSnapshot         sync.RWMutex
+	lockUpdateInventory      sync.RWMutex
+}
+
+// AdjustStock calls AdjustStockFunc.
+func (mock *InventoryService) AdjustStock(ctx context.Context, id int, adj inventory.StockAdjustment) (*inventory.AdjustmentResult, error) {
+	if mock.AdjustStockFunc == nil {
+		panic("InventoryService.AdjustStockFunc: method is nil but InventoryService.AdjustStock was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+		Adj inventory.StockAdjustment
+	}{
+		Ctx: ctx,
+		ID:  id,
+		Adj: adj,
+	}
+	mock.lockAdjustStock.Lock()
+	mock.calls.AdjustStock = append(mock.calls.AdjustStock, callInfo)
+	mock.lockAdjustStock.Unlock()
+	return mock.AdjustStockFunc(ctx, id, adj)
+}
+
+// AdjustStockCalls gets all the calls that were made to AdjustStock.
+// Check the length with:
+//
+//	len(mockedInventoryService.AdjustStockCalls())
+func (mock *InventoryService) AdjustStockCalls() []struct {
+	Ctx context.Context
+	ID  int
+	Adj inventory.StockAdjustment
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+		Adj inventory.StockAdjustment
+	}
+	mock.lockAdjustStock.RLock()
+	calls = mock.calls.AdjustStock
+	mock.lockAdjustStock.RUnlock()
+	return calls
+}
+
+// CloseStocktake calls CloseStocktakeFunc.
+func (mock *InventoryService) CloseStocktake(ctx context.Context, sessionId int, userId int) (*inventory.StocktakeReport, error) {
+	if mock.CloseStocktakeFunc == nil {
+		panic("InventoryService.CloseStocktakeFunc: method is nil but InventoryService.CloseStocktake was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		SessionId int
+		UserId    int
+	}{
+		Ctx:       ctx,
+		SessionId: sessionId,
+		UserId:    userId,
+	}
+	mock.lockCloseStocktake.Lock()
+	mock.calls.CloseStocktake = append(mock.calls.CloseStocktake, callInfo)
+	mock.lockCloseStocktake.Unlock()
+	return mock.CloseStocktakeFunc(ctx, sessionId, userId)
+}
+
+// CloseStocktakeCalls gets all the calls that were made to CloseStocktake.
+// Check the length with:
+//
+//	len(mockedInventoryService.CloseStocktakeCalls())
+func (mock *InventoryService) CloseStocktakeCalls() []struct {
+	Ctx       context.Context
+	SessionId int
+	UserId    int
+} {
+	var calls []struct {
+		Ctx       context.Context
+		SessionId int
+		UserId    int
+	}
+	mock.lockCloseStocktake.RLock()
+	calls = mock.calls.CloseStocktake
+	mock.lockCloseStocktake.RUnlock()
+	return calls
+}
+
+// CompareSuppliers calls CompareSuppliersFunc.
+func (mock *InventoryService) CompareSuppliers(ctx context.Context, belowThresholdOnly bool) ([]*inventory.SupplierComparison, error) {
+	if mock.CompareSuppliersFunc == nil {
+		panic("InventoryService.CompareSuppliersFunc: method is nil but InventoryService.CompareSuppliers was just called")
+	}
+	callInfo := struct {
+		Ctx                context.Context
+		BelowThresholdOnly bool
+	}{
+		Ctx:                ctx,
+		BelowThresholdOnly: belowThresholdOnly,
+	}
+	mock.lockCompareSuppliers.Lock()
+	mock.calls.CompareSuppliers = append(mock.calls.CompareSuppliers, callInfo)
+	mock.lockCompareSuppliers.Unlock()
+	return mock.CompareSuppliersFunc(ctx, belowThresholdOnly)
+}
+
+// CompareSuppliersCalls gets all the calls that were made to CompareSuppliers.
+// Check the length with:
+//
+//	len(mockedInventoryService.CompareSuppliersCalls())
+func (mock *InventoryService) CompareSuppliersCalls() []struct {
+	Ctx                context.Context
+	BelowThresholdOnly bool
+} {
+	var calls []struct {
+		Ctx                context.Context
+		BelowThresholdOnly bool
+	}
+	mock.lockCompareSuppliers.RLock()
+	calls = mock.calls.CompareSuppliers
+	mock.lockCompareSuppliers.RUnlock()
+	return calls
+}
+
+// ConsumeForOrder calls ConsumeForOrderFunc.
+func (mock *InventoryService) ConsumeForOrder(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) ([]string, error) {
+	if mock.ConsumeForOrderFunc == nil {
+		panic("InventoryService.ConsumeForOrderFunc: method is nil but InventoryService.ConsumeForOrder was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Client  database.SQLClient
+		OrderId int
+		Items   []string
+		UserId  int
+	}{
+		Ctx:     ctx,
+		Client:  client,
+		OrderId: orderId,
+		Items:   items,
+		UserId:  userId,
+	}
+	mock.lockConsumeForOrder.Lock()
+	mock.calls.ConsumeForOrder = append(mock.calls.ConsumeForOrder, callInfo)
+	mock.lockConsumeForOrder.Unlock()
+	return mock.ConsumeForOrderFunc(ctx, client, orderId, items, userId)
+}
+
+// ConsumeForOrderCalls gets all the calls that were made to ConsumeForOrder.
+// Check the length with:
+//
+//	len(mockedInventoryService.ConsumeForOrderCalls())
+func (mock *InventoryService) ConsumeForOrderCalls() []struct {
+	Ctx     context.Context
+	Client  database.SQLClient
+	OrderId int
+	Items   []string
+	UserId  int
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Client  database.SQLClient
+		OrderId int
+		Items   []string
+		UserId  int
+	}
+	mock.lockConsumeForOrder.RLock()
+	calls = mock.calls.ConsumeForOrder
+	mock.lockConsumeForOrder.RUnlock()
+	return calls
+}
+
+// CreateInventory calls CreateInventoryFunc.
+func (mock *InventoryService) CreateInventory(ctx context.Context, input inventory.Inventory) (*inventory.Inventory, error) {
+	if mock.CreateInventoryFunc == nil {
+		panic("InventoryService.CreateInventoryFunc: method is nil but InventoryService.CreateInventory was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Input inventory.Inventory
+	}{
+		Ctx:   ctx,
+		Input: input,
+	}
+	mock.lockCreateInventory.Lock()
+	mock.calls.CreateInventory = append(mock.calls.CreateInventory, callInfo)
+	mock.lockCreateInventory.Unlock()
+	return mock.CreateInventoryFunc(ctx, input)
+}
+
+// CreateInventoryCalls gets all the calls that were made to CreateInventory.
+// Check the length with:
+//
+//	len(mockedInventoryService.CreateInventoryCalls())
+func (mock *InventoryService) CreateInventoryCalls() []struct {
+	Ctx   context.Context
+	Input inventory.Inventory
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Input inventory.Inventory
+	}
+	mock.lockCreateInventory.RLock()
+	calls = mock.calls.CreateInventory
+	mock.lockCreateInventory.RUnlock()
+	return calls
+}
+
+// CreateTag calls CreateTagFunc.
+func (mock *InventoryService) CreateTag(ctx context.Context, kind string, name string) (*inventory.ManagedTag, error) {
+	if mock.CreateTagFunc == nil {
+		panic("InventoryService.CreateTagFunc: method is nil but InventoryService.CreateTag was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Kind string
+		Name string
+	}{
+		Ctx:  ctx,
+		Kind: kind,
+		Name: name,
+	}
+	mock.lockCreateTag.Lock()
+	mock.calls.CreateTag = append(mock.calls.CreateTag, callInfo)
+	mock.lockCreateTag.Unlock()
+	return mock.CreateTagFunc(ctx, kind, name)
+}
+
+// CreateTagCalls gets all the calls that were made to CreateTag.
+// Check the length with:
+//
+//	len(mockedInventoryService.CreateTagCalls())
+func (mock *InventoryService) CreateTagCalls() []struct {
+	Ctx  context.Context
+	Kind string
+	Name string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Kind string
+		Name string
+	}
+	mock.lockCreateTag.RLock()
+	calls = mock.calls.CreateTag
+	mock.lockCreateTag.RUnlock()
+	return calls
+}
+
+// DeleteInventory calls DeleteInventoryFunc.
+func (mock *InventoryService) DeleteInventory(ctx context.Context, id int) error {
+	if mock.DeleteInventoryFunc == nil {
+		panic("InventoryService.DeleteInventoryFunc: method is nil but InventoryService.DeleteInventory was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockDeleteInventory.Lock()
+	mock.calls.DeleteInventory = append(mock.calls.DeleteInventory, callInfo)
+	mock.lockDeleteInventory.Unlock()
+	return mock.DeleteInventoryFunc(ctx, id)
+}
+
+// DeleteInventoryCalls gets all the calls that were made to DeleteInventory.
+// Check the length with:
+//
+//	len(mockedInventoryService.DeleteInventoryCalls())
+func (mock *InventoryService) DeleteInventoryCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockDeleteInventory.RLock()
+	calls = mock.calls.DeleteInventory
+	mock.lockDeleteInventory.RUnlock()
+	return calls
+}
+
+// DeleteTag calls DeleteTagFunc.
+func (mock *InventoryService) DeleteTag(ctx context.Context, id int) error {
+	if mock.DeleteTagFunc == nil {
+		panic("InventoryService.DeleteTagFunc: method is nil but InventoryService.DeleteTag was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockDeleteTag.Lock()
+	mock.calls.DeleteTag = append(mock.calls.DeleteTag, callInfo)
+	mock.lockDeleteTag.Unlock()
+	return mock.DeleteTagFunc(ctx, id)
+}
+
+// DeleteTagCalls gets all the calls that were made to DeleteTag.
+// Check the length with:
+//
+//	len(mockedInventoryService.DeleteTagCalls())
+func (mock *InventoryService) DeleteTagCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockDeleteTag.RLock()
+	calls = mock.calls.DeleteTag
+	mock.lockDeleteTag.RUnlock()
+	return calls
+}
+
+// ExportCSV calls ExportCSVFunc.
+func (mock *InventoryService) ExportCSV(ctx context.Context, params inventory.ListParams, w io.Writer) error {
+	if mock.ExportCSVFunc == nil {
+		panic("InventoryService.ExportCSVFunc: method is nil but InventoryService.ExportCSV was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Params inventory.ListParams
+		W      io.Writer
+	}{
+		Ctx:    ctx,
+		Params: params,
+		W:      w,
+	}
+	mock.lockExportCSV.Lock()
+	mock.calls.ExportCSV = append(mock.calls.ExportCSV, callInfo)
+	mock.lockExportCSV.Unlock()
+	return mock.ExportCSVFunc(ctx, params, w)
+}
+
+// ExportCSVCalls gets all the calls that were made to ExportCSV.
+// Check the length with:
+//
+//	len(mockedInventoryService.ExportCSVCalls())
+func (mock *InventoryService) ExportCSVCalls() []struct {
+	Ctx    context.Context
+	Params inventory.ListParams
+	W      io.Writer
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Params inventory.ListParams
+		W      io.Writer
+	}
+	mock.lockExportCSV.RLock()
+	calls = mock.calls.ExportCSV
+	mock.lockExportCSV.RUnlock()
+	return calls
+}
+
+// ForecastConsumption calls ForecastConsumptionFunc.
+func (mock *InventoryService) ForecastConsumption(ctx context.Context, days int) ([]*inventory.Forecast, error) {
+	if mock.ForecastConsumptionFunc == nil {
+		panic("InventoryService.ForecastConsumptionFunc: method is nil but InventoryService.ForecastConsumption was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Days int
+	}{
+		Ctx:  ctx,
+		Days: days,
+	}
+	mock.lockForecastConsumption.Lock()
+	mock.calls.ForecastConsumption = append(mock.calls.ForecastConsumption, callInfo)
+	mock.lockForecastConsumption.Unlock()
+	return mock.ForecastConsumptionFunc(ctx, days)
+}
+
+// ForecastConsumptionCalls gets all the calls that were made to ForecastConsumption.
+// Check the length with:
+//
+//	len(mockedInventoryService.ForecastConsumptionCalls())
+func (mock *InventoryService) ForecastConsumptionCalls() []struct {
+	Ctx  context.Context
+	Days int
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Days int
+	}
+	mock.lockForecastConsumption.RLock()
+	calls = mock.calls.ForecastConsumption
+	mock.lockForecastConsumption.RUnlock()
+	return calls
+}
+
+// GetByBarcode calls GetByBarcodeFunc.
+func (mock *InventoryService) GetByBarcode(ctx context.Context, barcode string) (*inventory.Inventory, error) {
+	if mock.GetByBarcodeFunc == nil {
+		panic("InventoryService.GetByBarcodeFunc: method is nil but InventoryService.GetByBarcode was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Barcode string
+	}{
+		Ctx:     ctx,
+		Barcode: barcode,
+	}
+	mock.lockGetByBarcode.Lock()
+	mock.calls.GetByBarcode = append(mock.calls.GetByBarcode, callInfo)
+	mock.lockGetByBarcode.Unlock()
+	return mock.GetByBarcodeFunc(ctx, barcode)
+}
+
+// GetByBarcodeCalls gets all the calls that were made to GetByBarcode.
+// Check the length with:
+//
+//	len(mockedInventoryService.GetByBarcodeCalls())
+func (mock *InventoryService) GetByBarcodeCalls() []struct {
+	Ctx     context.Context
+	Barcode string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Barcode string
+	}
+	mock.lockGetByBarcode.RLock()
+	calls = mock.calls.GetByBarcode
+	mock.lockGetByBarcode.RUnlock()
+	return calls
+}
+
+// GetBySlugs calls GetBySlugsFunc.
+func (mock *InventoryService) GetBySlugs(ctx context.Context, slugs []string) ([]*inventory.Inventory, error) {
+	if mock.GetBySlugsFunc == nil {
+		panic("InventoryService.GetBySlugsFunc: method is nil but InventoryService.GetBySlugs was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Slugs []string
+	}{
+		Ctx:   ctx,
+		Slugs: slugs,
+	}
+	mock.lockGetBySlugs.Lock()
+	mock.calls.GetBySlugs = append(mock.calls.GetBySlugs, callInfo)
+	mock.lockGetBySlugs.Unlock()
+	return mock.GetBySlugsFunc(ctx, slugs)
+}
+
+// GetBySlugsCalls gets all the calls that were made to GetBySlugs.
+// Check the length with:
+//
+//	len(mockedInventoryService.GetBySlugsCalls())
+func (mock *InventoryService) GetBySlugsCalls() []struct {
+	Ctx   context.Context
+	Slugs []string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Slugs []string
+	}
+	mock.lockGetBySlugs.RLock()
+	calls = mock.calls.GetBySlugs
+	mock.lockGetBySlugs.RUnlock()
+	return calls
+}
+
+// GetConsumptionReport calls GetConsumptionReportFunc.
+func (mock *InventoryService) GetConsumptionReport(ctx context.Context, start time.Time, end time.Time) ([]*inventory.ConsumptionLine, error) {
+	if mock.GetConsumptionReportFunc == nil {
+		panic("InventoryService.GetConsumptionReportFunc: method is nil but InventoryService.GetConsumptionReport was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Start time.Time
+		End   time.Time
+	}{
+		Ctx:   ctx,
+		Start: start,
+		End:   end,
+	}
+	mock.lockGetConsumptionReport.Lock()
+	mock.calls.GetConsumptionReport = append(mock.calls.GetConsumptionReport, callInfo)
+	mock.lockGetConsumptionReport.Unlock()
+	return mock.GetConsumptionReportFunc(ctx, start, end)
+}
+
+// GetConsumptionReportCalls gets all the calls that were made to GetConsumptionReport.
+// Check the length with:
+//
+//	len(mockedInventoryService.GetConsumptionReportCalls())
+func (mock *InventoryService) GetConsumptionReportCalls() []struct {
+	Ctx   context.Context
+	Start time.Time
+	End   time.Time
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Start time.Time
+		End   time.Time
+	}
+	mock.lockGetConsumptionReport.RLock()
+	calls = mock.calls.GetConsumptionReport
+	mock.lockGetConsumptionReport.RUnlock()
+	return calls
+}
+
+// GetInventory calls GetInventoryFunc.
+func (mock *InventoryService) GetInventory(ctx context.Context, idOrSlug any) (*inventory.Inventory, error) {
+	if mock.GetInventoryFunc == nil {
+		panic("InventoryService.GetInventoryFunc: method is nil but InventoryService.GetInventory was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		IdOrSlug any
+	}{
+		Ctx:      ctx,
+		IdOrSlug: idOrSlug,
+	}
+	mock.lockGetInventory.Lock()
+	mock.calls.GetInventory = append(mock.calls.GetInventory, callInfo)
+	mock.lockGetInventory.Unlock()
+	return mock.GetInventoryFunc(ctx, idOrSlug)
+}
+
+// GetInventoryCalls gets all the calls that were made to GetInventory.
+// Check the length with:
+//
+//	len(mockedInventoryService.GetInventoryCalls())
+func (mock *InventoryService) GetInventoryCalls() []struct {
+	Ctx      context.Context
+	IdOrSlug any
+} {
+	var calls []struct {
+		Ctx      context.Context
+		IdOrSlug any
+	}
+	mock.lockGetInventory.RLock()
+	calls = mock.calls.GetInventory
+	mock.lockGetInventory.RUnlock()
+	return calls
+}
+
+// GetMovements calls GetMovementsFunc.
+func (mock *InventoryService) GetMovements(ctx context.Context, id int, limit int) ([]*inventory.StockMovement, error) {
+	if mock.GetMovementsFunc == nil {
+		panic("InventoryService.GetMovementsFunc: method is nil but InventoryService.GetMovements was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		ID    int
+		Limit int
+	}{
+		Ctx:   ctx,
+		ID:    id,
+		Limit: limit,
+	}
+	mock.lockGetMovements.Lock()
+	mock.calls.GetMovements = append(mock.calls.GetMovements, callInfo)
+	mock.lockGetMovements.Unlock()
+	return mock.GetMovementsFunc(ctx, id, limit)
+}
+
+// GetMovementsCalls gets all the calls that were made to GetMovements.
+// Check the length with:
+//
+//	len(mockedInventoryService.GetMovementsCalls())
+func (mock *InventoryService) GetMovementsCalls() []struct {
+	Ctx   context.Context
+	ID    int
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		ID    int
+		Limit int
+	}
+	mock.lockGetMovements.RLock()
+	calls = mock.calls.GetMovements
+	mock.lockGetMovements.RUnlock()
+	return calls
+}
+
+// GetParDashboard calls GetParDashboardFunc.
+func (mock *InventoryService) GetParDashboard(ctx context.Context) (*inventory.ParDashboard, error) {
+	if mock.GetParDashboardFunc == nil {
+		panic("InventoryService.GetParDashboardFunc: method is nil but InventoryService.GetParDashboard was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetParDashboard.Lock()
+	mock.calls.GetParDashboard = append(mock.calls.GetParDashboard, callInfo)
+	mock.lockGetParDashboard.Unlock()
+	return mock.GetParDashboardFunc(ctx)
+}
+
+// GetParDashboardCalls gets all the calls that were made to GetParDashboard.
+// Check the length with:
+//
+//	len(mockedInventoryService.GetParDashboardCalls())
+func (mock *InventoryService) GetParDashboardCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetParDashboard.RLock()
+	calls = mock.calls.GetParDashboard
+	mock.lockGetParDashboard.RUnlock()
+	return calls
+}
+
+// GetProductsUsing calls GetProductsUsingFunc.
+func (mock *InventoryService) GetProductsUsing(ctx context.Context, id int) ([]*inventory.ProductRef, error) {
+	if mock.GetProductsUsingFunc == nil {
+		panic("InventoryService.GetProductsUsingFunc: method is nil but InventoryService.GetProductsUsing was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetProductsUsing.Lock()
+	mock.calls.GetProductsUsing = append(mock.calls.GetProductsUsing, callInfo)
+	mock.lockGetProductsUsing.Unlock()
+	return mock.GetProductsUsingFunc(ctx, id)
+}
+
+// GetProductsUsingCalls gets all the calls that were made to GetProductsUsing.
+// Check the length with:
+//
+//	len(mockedInventoryService.GetProductsUsingCalls())
+func (mock *InventoryService) GetProductsUsingCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockGetProductsUsing.RLock()
+	calls = mock.calls.GetProductsUsing
+	mock.lockGetProductsUsing.RUnlock()
+	return calls
+}
+
+// GetShrinkageReport calls GetShrinkageReportFunc.
+func (mock *InventoryService) GetShrinkageReport(ctx context.Context, start time.Time, end time.Time) (*inventory.ShrinkageReport, error) {
+	if mock.GetShrinkageReportFunc == nil {
+		panic("InventoryService.GetShrinkageReportFunc: method is nil but InventoryService.GetShrinkageReport was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Start time.Time
+		End   time.Time
+	}{
+		Ctx:   ctx,
+		Start: start,
+		End:   end,
+	}
+	mock.lockGetShrinkageReport.Lock()
+	mock.calls.GetShrinkageReport = append(mock.calls.GetShrinkageReport, callInfo)
+	mock.lockGetShrinkageReport.Unlock()
+	return mock.GetShrinkageReportFunc(ctx, start, end)
+}
+
+// GetShrinkageReportCalls gets all the calls that were made to GetShrinkageReport.
+// Check the length with:
+//
+//	len(mockedInventoryService.GetShrinkageReportCalls())
+func (mock *InventoryService) GetShrinkageReportCalls() []struct {
+	Ctx   context.Context
+	Start time.Time
+	End   time.Time
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Start time.Time
+		End   time.Time
+	}
+	mock.lockGetShrinkageReport.RLock()
+	calls = mock.calls.GetShrinkageReport
+	mock.lockGetShrinkageReport.RUnlock()
+	return calls
+}
+
+// GetStockHistory calls GetStockHistoryFunc.
+func (mock *InventoryService) GetStockHistory(ctx context.Context, id int, start time.Time, end time.Time) ([]*inventory.StockSnapshot, error) {
+	if mock.GetStockHistoryFunc == nil {
+		panic("InventoryService.GetStockHistoryFunc: method is nil but InventoryService.GetStockHistory was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		ID    int
+		Start time.Time
+		End   time.Time
+	}{
+		Ctx:   ctx,
+		ID:    id,
+		Start: start,
+		End:   end,
+	}
+	mock.lockGetStockHistory.Lock()
+	mock.calls.GetStockHistory = append(mock.calls.GetStockHistory, callInfo)
+	mock.lockGetStockHistory.Unlock()
+	return mock.GetStockHistoryFunc(ctx, id, start, end)
+}
+
+// GetStockHistoryCalls gets all the calls that were made to GetStockHistory.
+// Check the length with:
+//
+//	len(mockedInventoryService.GetStockHistoryCalls())
+func (mock *InventoryService) GetStockHistoryCalls() []struct {
+	Ctx   context.Context
+	ID    int
+	Start time.Time
+	End   time.Time
+} {
+	var calls []struct {
+		Ctx   context.Context
+		ID    int
+		Start time.Time
+		End   time.Time
+	}
+	mock.lockGetStockHistory.RLock()
+	calls = mock.calls.GetStockHistory
+	mock.lockGetStockHistory.RUnlock()
+	return calls
+}
+
+// GetStockOn calls GetStockOnFunc.
+func (mock *InventoryService) GetStockOn(ctx context.Context, day time.Time) ([]*inventory.StockSnapshot, error) {
+	if mock.GetStockOnFunc == nil {
+		panic("InventoryService.GetStockOnFunc: method is nil but InventoryService.GetStockOn was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Day time.Time
+	}{
+		Ctx: ctx,
+		Day: day,
+	}
+	mock.lockGetStockOn.Lock()
+	mock.calls.GetStockOn = append(mock.calls.GetStockOn, callInfo)
+	mock.lockGetStockOn.Unlock()
+	return mock.GetStockOnFunc(ctx, day)
+}
+
+// GetStockOnCalls gets all the calls that were made to GetStockOn.
+// Check the length with:
+//
+//	len(mockedInventoryService.GetStockOnCalls())
+func (mock *InventoryService) GetStockOnCalls() []struct {
+	Ctx context.Context
+	Day time.Time
+} {
+	var calls []struct {
+		Ctx context.Context
+		Day time.Time
+	}
+	mock.lockGetStockOn.RLock()
+	calls = mock.calls.GetStockOn
+	mock.lockGetStockOn.RUnlock()
+	return calls
+}
+
+// GetStocktakeReport calls GetStocktakeReportFunc.
+func (mock *InventoryService) GetStocktakeReport(ctx context.Context, sessionId int) (*inventory.StocktakeReport, error) {
+	if mock.GetStocktakeReportFunc == nil {
+		panic("InventoryService.GetStocktakeReportFunc: method is nil but InventoryService.GetStocktakeReport was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		SessionId int
+	}{
+		Ctx:       ctx,
+		SessionId: sessionId,
+	}
+	mock.lockGetStocktakeReport.Lock()
+	mock.calls.GetStocktakeReport = append(mock.calls.GetStocktakeReport, callInfo)
+	mock.lockGetStocktakeReport.Unlock()
+	return mock.GetStocktakeReportFunc(ctx, sessionId)
+}
+
+// GetStocktakeReportCalls gets all the calls that were made to GetStocktakeReport.
+// Check the length with:
+//
+//	len(mockedInventoryService.GetStocktakeReportCalls())
+func (mock *InventoryService) GetStocktakeReportCalls() []struct {
+	Ctx       context.Context
+	SessionId int
+} {
+	var calls []struct {
+		Ctx       context.Context
+		SessionId int
+	}
+	mock.lockGetStocktakeReport.RLock()
+	calls = mock.calls.GetStocktakeReport
+	mock.lockGetStocktakeReport.RUnlock()
+	return calls
+}
+
+// GetSummary calls GetSummaryFunc.
+func (mock *InventoryService) GetSummary(ctx context.Context) (*inventory.Summary, error) {
+	if mock.GetSummaryFunc == nil {
+		panic("InventoryService.GetSummaryFunc: method is nil but InventoryService.GetSummary was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetSummary.Lock()
+	mock.calls.GetSummary = append(mock.calls.GetSummary, callInfo)
+	mock.lockGetSummary.Unlock()
+	return mock.GetSummaryFunc(ctx)
+}
+
+// GetSummaryCalls gets all the calls that were made to GetSummary.
+// Check the length with:
+//
+//	len(mockedInventoryService.GetSummaryCalls())
+func (mock *InventoryService) GetSummaryCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetSummary.RLock()
+	calls = mock.calls.GetSummary
+	mock.lockGetSummary.RUnlock()
+	return calls
+}
+
+// GetSupplierPrices calls GetSupplierPricesFunc.
+func (mock *InventoryService) GetSupplierPrices(ctx context.Context, id int) ([]*inventory.SupplierPrice, error) {
+	if mock.GetSupplierPricesFunc == nil {
+		panic("InventoryService.GetSupplierPricesFunc: method is nil but InventoryService.GetSupplierPrices was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetSupplierPrices.Lock()
+	mock.calls.GetSupplierPrices = append(mock.calls.GetSupplierPrices, callInfo)
+	mock.lockGetSupplierPrices.Unlock()
+	return mock.GetSupplierPricesFunc(ctx, id)
+}
+
+// GetSupplierPricesCalls gets all the calls that were made to GetSupplierPrices.
+// Check the length with:
+//
+//	len(mockedInventoryService.GetSupplierPricesCalls())
+func (mock *InventoryService) GetSupplierPricesCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockGetSupplierPrices.RLock()
+	calls = mock.calls.GetSupplierPrices
+	mock.lockGetSupplierPrices.RUnlock()
+	return calls
+}
+
+// GetTurnoverReport calls GetTurnoverReportFunc.
+func (mock *InventoryService) GetTurnoverReport(ctx context.Context, start time.Time, end time.Time) ([]*inventory.TurnoverLine, error) {
+	if mock.GetTurnoverReportFunc == nil {
+		panic("InventoryService.GetTurnoverReportFunc: method is nil but InventoryService.GetTurnoverReport was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Start time.Time
+		End   time.Time
+	}{
+		Ctx:   ctx,
+		Start: start,
+		End:   end,
+	}
+	mock.lockGetTurnoverReport.Lock()
+	mock.calls.GetTurnoverReport = append(mock.calls.GetTurnoverReport, callInfo)
+	mock.lockGetTurnoverReport.Unlock()
+	return mock.GetTurnoverReportFunc(ctx, start, end)
+}
+
+// GetTurnoverReportCalls gets all the calls that were made to GetTurnoverReport.
+// Check the length with:
+//
+//	len(mockedInventoryService.GetTurnoverReportCalls())
+func (mock *InventoryService) GetTurnoverReportCalls() []struct {
+	Ctx   context.Context
+	Start time.Time
+	End   time.Time
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Start time.Time
+		End   time.Time
+	}
+	mock.lockGetTurnoverReport.RLock()
+	calls = mock.calls.GetTurnoverReport
+	mock.lockGetTurnoverReport.RUnlock()
+	return calls
+}
+
+// ImportCSV calls ImportCSVFunc.
+func (mock *InventoryService) ImportCSV(ctx context.Context, r io.Reader) (*inventory.ImportReport, error) {
+	if mock.ImportCSVFunc == nil {
+		panic("InventoryService.ImportCSVFunc: method is nil but InventoryService.ImportCSV was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		R   io.Reader
+	}{
+		Ctx: ctx,
+		R:   r,
+	}
+	mock.lockImportCSV.Lock()
+	mock.calls.ImportCSV = append(mock.calls.ImportCSV, callInfo)
+	mock.lockImportCSV.Unlock()
+	return mock.ImportCSVFunc(ctx, r)
+}
+
+// ImportCSVCalls gets all the calls that were made to ImportCSV.
+// Check the length with:
+//
+//	len(mockedInventoryService.ImportCSVCalls())
+func (mock *InventoryService) ImportCSVCalls() []struct {
+	Ctx context.Context
+	R   io.Reader
+} {
+	var calls []struct {
+		Ctx context.Context
+		R   io.Reader
+	}
+	mock.lockImportCSV.RLock()
+	calls = mock.calls.ImportCSV
+	mock.lockImportCSV.RUnlock()
+	return calls
+}
+
+// ListInventory calls ListInventoryFunc.
+func (mock *InventoryService) ListInventory(ctx context.Context, params inventory.ListParams) ([]*inventory.Inventory, int, error) {
+	if mock.ListInventoryFunc == nil {
+		panic("InventoryService.ListInventoryFunc: method is nil but InventoryService.ListInventory was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Params inventory.ListParams
+	}{
+		Ctx:    ctx,
+		Params: params,
+	}
+	mock.lockListInventory.Lock()
+	mock.calls.ListInventory = append(mock.calls.ListInventory, callInfo)
+	mock.lockListInventory.Unlock()
+	return mock.ListInventoryFunc(ctx, params)
+}
+
+// ListInventoryCalls gets all the calls that were made to ListInventory.
+// Check the length with:
+//
+//	len(mockedInventoryService.ListInventoryCalls())
+func (mock *InventoryService) ListInventoryCalls() []struct {
+	Ctx    context.Context
+	Params inventory.ListParams
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Params inventory.ListParams
+	}
+	mock.lockListInventory.RLock()
+	calls = mock.calls.ListInventory
+	mock.lockListInventory.RUnlock()
+	return calls
+}
+
+// ListTags calls ListTagsFunc.
+func (mock *InventoryService) ListTags(ctx context.Context, kind string) ([]*inventory.ManagedTag, error) {
+	if mock.ListTagsFunc == nil {
+		panic("InventoryService.ListTagsFunc: method is nil but InventoryService.ListTags was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Kind string
+	}{
+		Ctx:  ctx,
+		Kind: kind,
+	}
+	mock.lockListTags.Lock()
+	mock.calls.ListTags = append(mock.calls.ListTags, callInfo)
+	mock.lockListTags.Unlock()
+	return mock.ListTagsFunc(ctx, kind)
+}
+
+// ListTagsCalls gets all the calls that were made to ListTags.
+// Check the length with:
+//
+//	len(mockedInventoryService.ListTagsCalls())
+func (mock *InventoryService) ListTagsCalls() []struct {
+	Ctx  context.Context
+	Kind string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Kind string
+	}
+	mock.lockListTags.RLock()
+	calls = mock.calls.ListTags
+	mock.lockListTags.RUnlock()
+	return calls
+}
+
+// MissingIngredients calls MissingIngredientsFunc.
+func (mock *InventoryService) MissingIngredients(ctx context.Context, slugs []string) ([]string, error) {
+	if mock.MissingIngredientsFunc == nil {
+		panic("InventoryService.MissingIngredientsFunc: method is nil but InventoryService.MissingIngredients was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Slugs []string
+	}{
+		Ctx:   ctx,
+		Slugs: slugs,
+	}
+	mock.lockMissingIngredients.Lock()
+	mock.calls.MissingIngredients = append(mock.calls.MissingIngredients, callInfo)
+	mock.lockMissingIngredients.Unlock()
+	return mock.MissingIngredientsFunc(ctx, slugs)
+}
+
+// MissingIngredientsCalls gets all the calls that were made to MissingIngredients.
+// Check the length with:
+//
+//	len(mockedInventoryService.MissingIngredientsCalls())
+func (mock *InventoryService) MissingIngredientsCalls() []struct {
+	Ctx   context.Context
+	Slugs []string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Slugs []string
+	}
+	mock.lockMissingIngredients.RLock()
+	calls = mock.calls.MissingIngredients
+	mock.lockMissingIngredients.RUnlock()
+	return calls
+}
+
+// OnAvailabilityChange calls OnAvailabilityChangeFunc.
+func (mock *InventoryService) OnAvailabilityChange(fn func(inventory.AvailabilityEvent)) {
+	if mock.OnAvailabilityChangeFunc == nil {
+		panic("InventoryService.OnAvailabilityChangeFunc: method is nil but InventoryService.OnAvailabilityChange was just called")
+	}
+	callInfo := struct {
+		Fn func(inventory.AvailabilityEvent)
+	}{
+		Fn: fn,
+	}
+	mock.lockOnAvailabilityChange.Lock()
+	mock.calls.OnAvailabilityChange = append(mock.calls.OnAvailabilityChange, callInfo)
+	mock.lockOnAvailabilityChange.Unlock()
+	mock.OnAvailabilityChangeFunc(fn)
+}
+
+// OnAvailabilityChangeCalls gets all the calls that were made to OnAvailabilityChange.
+// Check the length with:
+//
+//	len(mockedInventoryService.OnAvailabilityChangeCalls())
+func (mock *InventoryService) OnAvailabilityChangeCalls() []struct {
+	Fn func(inventory.AvailabilityEvent)
+} {
+	var calls []struct {
+		Fn func(inventory.AvailabilityEvent)
+	}
+	mock.lockOnAvailabilityChange.RLock()
+	calls = mock.calls.OnAvailabilityChange
+	mock.lockOnAvailabilityChange.RUnlock()
+	return calls
+}
+
+// OnStockAlert calls OnStockAlertFunc.
+func (mock *InventoryService) OnStockAlert(fn func(inventory.StockAlert)) {
+	if mock.OnStockAlertFunc == nil {
+		panic("InventoryService.OnStockAlertFunc: method is nil but InventoryService.OnStockAlert was just called")
+	}
+	callInfo := struct {
+		Fn func(inventory.StockAlert)
+	}{
+		Fn: fn,
+	}
+	mock.lockOnStockAlert.Lock()
+	mock.calls.OnStockAlert = append(mock.calls.OnStockAlert, callInfo)
+	mock.lockOnStockAlert.Unlock()
+	mock.OnStockAlertFunc(fn)
+}
+
+// OnStockAlertCalls gets all the calls that were made to OnStockAlert.
+// Check the length with:
+//
+//	len(mockedInventoryService.OnStockAlertCalls())
+func (mock *InventoryService) OnStockAlertCalls() []struct {
+	Fn func(inventory.StockAlert)
+} {
+	var calls []struct {
+		Fn func(inventory.StockAlert)
+	}
+	mock.lockOnStockAlert.RLock()
+	calls = mock.calls.OnStockAlert
+	mock.lockOnStockAlert.RUnlock()
+	return calls
+}
+
+// OpenStocktake calls OpenStocktakeFunc.
+func (mock *InventoryService) OpenStocktake(ctx context.Context, note string, userId int) (*inventory.StocktakeSession, error) {
+	if mock.OpenStocktakeFunc == nil {
+		panic("InventoryService.OpenStocktakeFunc: method is nil but InventoryService.OpenStocktake was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Note   string
+		UserId int
+	}{
+		Ctx:    ctx,
+		Note:   note,
+		UserId: userId,
+	}
+	mock.lockOpenStocktake.Lock()
+	mock.calls.OpenStocktake = append(mock.calls.OpenStocktake, callInfo)
+	mock.lockOpenStocktake.Unlock()
+	return mock.OpenStocktakeFunc(ctx, note, userId)
+}
+
+// OpenStocktakeCalls gets all the calls that were made to OpenStocktake.
+// Check the length with:
+//
+//	len(mockedInventoryService.OpenStocktakeCalls())
+func (mock *InventoryService) OpenStocktakeCalls() []struct {
+	Ctx    context.Context
+	Note   string
+	UserId int
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Note   string
+		UserId int
+	}
+	mock.lockOpenStocktake.RLock()
+	calls = mock.calls.OpenStocktake
+	mock.lockOpenStocktake.RUnlock()
+	return calls
+}
+
+// OrderStockChanged calls OrderStockChangedFunc.
+func (mock *InventoryService) OrderStockChanged(ctx context.Context, items []string, sold bool) {
+	if mock.OrderStockChangedFunc == nil {
+		panic("InventoryService.OrderStockChangedFunc: method is nil but InventoryService.OrderStockChanged was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Items []string
+		Sold  bool
+	}{
+		Ctx:   ctx,
+		Items: items,
+		Sold:  sold,
+	}
+	mock.lockOrderStockChanged.Lock()
+	mock.calls.OrderStockChanged = append(mock.calls.OrderStockChanged, callInfo)
+	mock.lockOrderStockChanged.Unlock()
+	mock.OrderStockChangedFunc(ctx, items, sold)
+}
+
+// OrderStockChangedCalls gets all the calls that were made to OrderStockChanged.
+// Check the length with:
+//
+//	len(mockedInventoryService.OrderStockChangedCalls())
+func (mock *InventoryService) OrderStockChangedCalls() []struct {
+	Ctx   context.Context
+	Items []string
+	Sold  bool
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Items []string
+		Sold  bool
+	}
+	mock.lockOrderStockChanged.RLock()
+	calls = mock.calls.OrderStockChanged
+	mock.lockOrderStockChanged.RUnlock()
+	return calls
+}
+
+// PurgeDeleted calls PurgeDeletedFunc.
+func (mock *InventoryService) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	if mock.PurgeDeletedFunc == nil {
+		panic("InventoryService.PurgeDeletedFunc: method is nil but InventoryService.PurgeDeleted was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Before time.Time
+	}{
+		Ctx:    ctx,
+		Before: before,
+	}
+	mock.lockPurgeDeleted.Lock()
+	mock.calls.PurgeDeleted = append(mock.calls.PurgeDeleted, callInfo)
+	mock.lockPurgeDeleted.Unlock()
+	return mock.PurgeDeletedFunc(ctx, before)
+}
+
+// PurgeDeletedCalls gets all the calls that were made to PurgeDeleted.
+// Check the length with:
+//
+//	len(mockedInventoryService.PurgeDeletedCalls())
+func (mock *InventoryService) PurgeDeletedCalls() []struct {
+	Ctx    context.Context
+	Before time.Time
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Before time.Time
+	}
+	mock.lockPurgeDeleted.RLock()
+	calls = mock.calls.PurgeDeleted
+	mock.lockPurgeDeleted.RUnlock()
+	return calls
+}
+
+// PurgeInventory calls PurgeInventoryFunc.
+func (mock *InventoryService) PurgeInventory(ctx context.Context, id int) error {
+	if mock.PurgeInventoryFunc == nil {
+		panic("InventoryService.PurgeInventoryFunc: method is nil but InventoryService.PurgeInventory was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockPurgeInventory.Lock()
+	mock.calls.PurgeInventory = append(mock.calls.PurgeInventory, callInfo)
+	mock.lockPurgeInventory.Unlock()
+	return mock.PurgeInventoryFunc(ctx, id)
+}
+
+// PurgeInventoryCalls gets all the calls that were made to PurgeInventory.
+// Check the length with:
+//
+//	len(mockedInventoryService.PurgeInventoryCalls())
+func (mock *InventoryService) PurgeInventoryCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockPurgeInventory.RLock()
+	calls = mock.calls.PurgeInventory
+	mock.lockPurgeInventory.RUnlock()
+	return calls
+}
+
+// RecordCount calls RecordCountFunc.
+func (mock *InventoryService) RecordCount(ctx context.Context, sessionId int, inventoryId int, counted int64) error {
+	if mock.RecordCountFunc == nil {
+		panic("InventoryService.RecordCountFunc: method is nil but InventoryService.RecordCount was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		SessionId   int
+		InventoryId int
+		Counted     int64
+	}{
+		Ctx:         ctx,
+		SessionId:   sessionId,
+		InventoryId: inventoryId,
+		Counted:     counted,
+	}
+	mock.lockRecordCount.Lock()
+	mock.calls.RecordCount = append(mock.calls.RecordCount, callInfo)
+	mock.lockRecordCount.Unlock()
+	return mock.RecordCountFunc(ctx, sessionId, inventoryId, counted)
+}
+
+// RecordCountCalls gets all the calls that were made to RecordCount.
+// Check the length with:
+//
+//	len(mockedInventoryService.RecordCountCalls())
+func (mock *InventoryService) RecordCountCalls() []struct {
+	Ctx         context.Context
+	SessionId   int
+	InventoryId int
+	Counted     int64
+} {
+	var calls []struct {
+		Ctx         context.Context
+		SessionId   int
+		InventoryId int
+		Counted     int64
+	}
+	mock.lockRecordCount.RLock()
+	calls = mock.calls.RecordCount
+	mock.lockRecordCount.RUnlock()
+	return calls
+}
+
+// ReleaseExpired calls ReleaseExpiredFunc.
+func (mock *InventoryService) ReleaseExpired(ctx context.Context) (int64, error) {
+	if mock.ReleaseExpiredFunc == nil {
+		panic("InventoryService.ReleaseExpiredFunc: method is nil but InventoryService.ReleaseExpired was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockReleaseExpired.Lock()
+	mock.calls.ReleaseExpired = append(mock.calls.ReleaseExpired, callInfo)
+	mock.lockReleaseExpired.Unlock()
+	return mock.ReleaseExpiredFunc(ctx)
+}
+
+// ReleaseExpiredCalls gets all the calls that were made to ReleaseExpired.
+// Check the length with:
+//
+//	len(mockedInventoryService.ReleaseExpiredCalls())
+func (mock *InventoryService) ReleaseExpiredCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockReleaseExpired.RLock()
+	calls = mock.calls.ReleaseExpired
+	mock.lockReleaseExpired.RUnlock()
+	return calls
+}
+
+// ReleaseForOrder calls ReleaseForOrderFunc.
+func (mock *InventoryService) ReleaseForOrder(ctx context.Context, client database.SQLClient, orderId int) (int64, error) {
+	if mock.ReleaseForOrderFunc == nil {
+		panic("InventoryService.ReleaseForOrderFunc: method is nil but InventoryService.ReleaseForOrder was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Client  database.SQLClient
+		OrderId int
+	}{
+		Ctx:     ctx,
+		Client:  client,
+		OrderId: orderId,
+	}
+	mock.lockReleaseForOrder.Lock()
+	mock.calls.ReleaseForOrder = append(mock.calls.ReleaseForOrder, callInfo)
+	mock.lockReleaseForOrder.Unlock()
+	return mock.ReleaseForOrderFunc(ctx, client, orderId)
+}
+
+// ReleaseForOrderCalls gets all the calls that were made to ReleaseForOrder.
+// Check the length with:
+//
+//	len(mockedInventoryService.ReleaseForOrderCalls())
+func (mock *InventoryService) ReleaseForOrderCalls() []struct {
+	Ctx     context.Context
+	Client  database.SQLClient
+	OrderId int
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Client  database.SQLClient
+		OrderId int
+	}
+	mock.lockReleaseForOrder.RLock()
+	calls = mock.calls.ReleaseForOrder
+	mock.lockReleaseForOrder.RUnlock()
+	return calls
+}
+
+// RemoveSupplierPrice calls RemoveSupplierPriceFunc.
+func (mock *InventoryService) RemoveSupplierPrice(ctx context.Context, id int, supplier string) error {
+	if mock.RemoveSupplierPriceFunc == nil {
+		panic("InventoryService.RemoveSupplierPriceFunc: method is nil but InventoryService.RemoveSupplierPrice was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		ID       int
+		Supplier string
+	}{
+		Ctx:      ctx,
+		ID:       id,
+		Supplier: supplier,
+	}
+	mock.lockRemoveSupplierPrice.Lock()
+	mock.calls.RemoveSupplierPrice = append(mock.calls.RemoveSupplierPrice, callInfo)
+	mock.lockRemoveSupplierPrice.Unlock()
+	return mock.RemoveSupplierPriceFunc(ctx, id, supplier)
+}
+
+// RemoveSupplierPriceCalls gets all the calls that were made to RemoveSupplierPrice.
+// Check the length with:
+//
+//	len(mockedInventoryService.RemoveSupplierPriceCalls())
+func (mock *InventoryService) RemoveSupplierPriceCalls() []struct {
+	Ctx      context.Context
+	ID       int
+	Supplier string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		ID       int
+		Supplier string
+	}
+	mock.lockRemoveSupplierPrice.RLock()
+	calls = mock.calls.RemoveSupplierPrice
+	mock.lockRemoveSupplierPrice.RUnlock()
+	return calls
+}
+
+// RenameTag calls RenameTagFunc.
+func (mock *InventoryService) RenameTag(ctx context.Context, id int, name string) (*inventory.ManagedTag, error) {
+	if mock.RenameTagFunc == nil {
+		panic("InventoryService.RenameTagFunc: method is nil but InventoryService.RenameTag was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		ID   int
+		Name string
+	}{
+		Ctx:  ctx,
+		ID:   id,
+		Name: name,
+	}
+	mock.lockRenameTag.Lock()
+	mock.calls.RenameTag = append(mock.calls.RenameTag, callInfo)
+	mock.lockRenameTag.Unlock()
+	return mock.RenameTagFunc(ctx, id, name)
+}
+
+// RenameTagCalls gets all the calls that were made to RenameTag.
+// Check the length with:
+//
+//	len(mockedInventoryService.RenameTagCalls())
+func (mock *InventoryService) RenameTagCalls() []struct {
+	Ctx  context.Context
+	ID   int
+	Name string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		ID   int
+		Name string
+	}
+	mock.lockRenameTag.RLock()
+	calls = mock.calls.RenameTag
+	mock.lockRenameTag.RUnlock()
+	return calls
+}
+
+// Reserve calls ReserveFunc.
+func (mock *InventoryService) Reserve(ctx context.Context, id int, orderId int, quantity int64, ttl time.Duration) (*inventory.Reservation, error) {
+	if mock.ReserveFunc == nil {
+		panic("InventoryService.ReserveFunc: method is nil but InventoryService.Reserve was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		ID       int
+		OrderId  int
+		Quantity int64
+		TTL      time.Duration
+	}{
+		Ctx:      ctx,
+		ID:       id,
+		OrderId:  orderId,
+		Quantity: quantity,
+		TTL:      ttl,
+	}
+	mock.lockReserve.Lock()
+	mock.calls.Reserve = append(mock.calls.Reserve, callInfo)
+	mock.lockReserve.Unlock()
+	return mock.ReserveFunc(ctx, id, orderId, quantity, ttl)
+}
+
+// ReserveCalls gets all the calls that were made to Reserve.
+// Check the length with:
+//
+//	len(mockedInventoryService.ReserveCalls())
+func (mock *InventoryService) ReserveCalls() []struct {
+	Ctx      context.Context
+	ID       int
+	OrderId  int
+	Quantity int64
+	TTL      time.Duration
+} {
+	var calls []struct {
+		Ctx      context.Context
+		ID       int
+		OrderId  int
+		Quantity int64
+		TTL      time.Duration
+	}
+	mock.lockReserve.RLock()
+	calls = mock.calls.Reserve
+	mock.lockReserve.RUnlock()
+	return calls
+}
+
+// RestockForOrder calls RestockForOrderFunc.
+func (mock *InventoryService) RestockForOrder(ctx context.Context, client database.SQLClient, orderId int, items []string, userId int) error {
+	if mock.RestockForOrderFunc == nil {
+		panic("InventoryService.RestockForOrderFunc: method is nil but InventoryService.RestockForOrder was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Client  database.SQLClient
+		OrderId int
+		Items   []string
+		UserId  int
+	}{
+		Ctx:     ctx,
+		Client:  client,
+		OrderId: orderId,
+		Items:   items,
+		UserId:  userId,
+	}
+	mock.lockRestockForOrder.Lock()
+	mock.calls.RestockForOrder = append(mock.calls.RestockForOrder, callInfo)
+	mock.lockRestockForOrder.Unlock()
+	return mock.RestockForOrderFunc(ctx, client, orderId, items, userId)
+}
+
+// RestockForOrderCalls gets all the calls that were made to RestockForOrder.
+// Check the length with:
+//
+//	len(mockedInventoryService.RestockForOrderCalls())
+func (mock *InventoryService) RestockForOrderCalls() []struct {
+	Ctx     context.Context
+	Client  database.SQLClient
+	OrderId int
+	Items   []string
+	UserId  int
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Client  database.SQLClient
+		OrderId int
+		Items   []string
+		UserId  int
+	}
+	mock.lockRestockForOrder.RLock()
+	calls = mock.calls.RestockForOrder
+	mock.lockRestockForOrder.RUnlock()
+	return calls
+}
+
+// RestoreInventory calls RestoreInventoryFunc.
+func (mock *InventoryService) RestoreInventory(ctx context.Context, id int) error {
+	if mock.RestoreInventoryFunc == nil {
+		panic("InventoryService.RestoreInventoryFunc: method is nil but InventoryService.RestoreInventory was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockRestoreInventory.Lock()
+	mock.calls.RestoreInventory = append(mock.calls.RestoreInventory, callInfo)
+	mock.lockRestoreInventory.Unlock()
+	return mock.RestoreInventoryFunc(ctx, id)
+}
+
+// RestoreInventoryCalls gets all the calls that were made to RestoreInventory.
+// Check the length with:
+//
+//	len(mockedInventoryService.RestoreInventoryCalls())
+func (mock *InventoryService) RestoreInventoryCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockRestoreInventory.RLock()
+	calls = mock.calls.RestoreInventory
+	mock.lockRestoreInventory.RUnlock()
+	return calls
+}
+
+// SetSupplierPrice calls SetSupplierPriceFunc.
+func (mock *InventoryService) SetSupplierPrice(ctx context.Context, id int, sp inventory.SupplierPrice) (*inventory.SupplierPrice, error) {
+	if mock.SetSupplierPriceFunc == nil {
+		panic("InventoryService.SetSupplierPriceFunc: method is nil but InventoryService.SetSupplierPrice was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+		Sp  inventory.SupplierPrice
+	}{
+		Ctx: ctx,
+		ID:  id,
+		Sp:  sp,
+	}
+	mock.lockSetSupplierPrice.Lock()
+	mock.calls.SetSupplierPrice = append(mock.calls.SetSupplierPrice, callInfo)
+	mock.lockSetSupplierPrice.Unlock()
+	return mock.SetSupplierPriceFunc(ctx, id, sp)
+}
+
+// SetSupplierPriceCalls gets all the calls that were made to SetSupplierPrice.
+// Check the length with:
+//
+//	len(mockedInventoryService.SetSupplierPriceCalls())
+func (mock *InventoryService) SetSupplierPriceCalls() []struct {
+	Ctx context.Context
+	ID  int
+	Sp  inventory.SupplierPrice
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+		Sp  inventory.SupplierPrice
+	}
+	mock.lockSetSupplierPrice.RLock()
+	calls = mock.calls.SetSupplierPrice
+	mock.lockSetSupplierPrice.RUnlock()
+	return calls
+}
+
+// TakeSnapshot calls TakeSnapshotFunc.
+func (mock *InventoryService) TakeSnapshot(ctx context.Context) (int, error) {
+	if mock.TakeSnapshotFunc == nil {
+		panic("InventoryService.TakeSnapshotFunc: method is nil but InventoryService.TakeSnapshot was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockTakeSnapshot.Lock()
+	mock.calls.TakeSnapshot = append(mock.calls.TakeSnapshot, callInfo)
+	mock.lockTakeSnapshot.Unlock()
+	return mock.TakeSnapshotFunc(ctx)
+}
+
+// TakeSnapshotCalls gets all the calls that were made to TakeSnapshot.
+// Check the length with:
+//
+//	len(mockedInventoryService.TakeSnapshotCalls())
+func (mock *InventoryService) TakeSnapshotCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockTakeSnapshot.RLock()
+	calls = mock.calls.TakeSnapshot
+	mock.lockTakeSnapshot.RUnlock()
+	return calls
+}
+
+// UpdateInventory calls UpdateInventoryFunc.
+func (mock *InventoryService) UpdateInventory(ctx context.Context, id int, input inventory.Inventory) error {
+	if mock.UpdateInventoryFunc == nil {
+		panic("InventoryService.UpdateInventoryFunc: method is nil but InventoryService.UpdateInventory was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		ID    int
+		Input inventory.Inventory
+	}{
+		Ctx:   ctx,
+		ID:    id,
+		Input: input,
+	}
+	mock.lockUpdateInventory.Lock()
+	mock.calls.UpdateInventory = append(mock.calls.UpdateInventory, callInfo)
+	mock.lockUpdateInventory.Unlock()
+	return mock.UpdateInventoryFunc(ctx, id, input)
+}
+
+// UpdateInventoryCalls gets all the calls that were made to UpdateInventory.
+// Check the length with:
+//
+//	len(mockedInventoryService.UpdateInventoryCalls())
+func (mock *InventoryService) UpdateInventoryCalls() []struct {
+	Ctx   context.Context
+	ID    int
+	Input inventory.Inventory
+} {
+	var calls []struct {
+		Ctx   context.Context
+		ID    int
+		Input inventory.Inventory
+	}
+	mock.lockUpdateInventory.RLock()
+	calls = mock.calls.UpdateInventory
+	mock.lockUpdateInventory.RUnlock()
+	return calls
+}
@@ -0,0 +1,282 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mock
+
+import (
+	"context"
+	"github.com/iteranya/practicing-go/internal/entities/location"
+	"sync"
+)
+
+// Ensure, that LocationService does implement location.LocationService.
+// If this is not the case, regenerate this file with moq.
+var _ location.LocationService = &LocationService{}
+
+// LocationService is a mock implementation of location.LocationService.
+//
+//	func TestSomethingThatUsesLocationService(t *testing.T) {
+//
+//		// make and configure a mocked location.LocationService
+//		mockedLocationService := &LocationService{
+//			CreateLocationFunc: func(ctx context.Context, loc location.Location) (*location.Location, error) {
+//				panic("mock out the CreateLocation method")
+//			},
+//			DeleteLocationFunc: func(ctx context.Context, id int) error {
+//				panic("mock out the DeleteLocation method")
+//			},
+//			GetLocationFunc: func(ctx context.Context, idOrSlug any) (*location.Location, error) {
+//				panic("mock out the GetLocation method")
+//			},
+//			ListLocationsFunc: func(ctx context.Context) ([]*location.Location, error) {
+//				panic("mock out the ListLocations method")
+//			},
+//			UpdateLocationFunc: func(ctx context.Context, id int, loc location.Location) error {
+//				panic("mock out the UpdateLocation method")
+//			},
+//		}
+//
+//		// use mockedLocationService in code that requires location.LocationService
+//		// and then make assertions.
+//
+//	}
+type LocationService struct {
+	// CreateLocationFunc mocks the CreateLocation method.
+	CreateLocationFunc func(ctx context.Context, loc location.Location) (*location.Location, error)
+
+	// DeleteLocationFunc mocks the DeleteLocation method.
+	DeleteLocationFunc func(ctx context.Context, id int) error
+
+	// GetLocationFunc mocks the GetLocation method.
+	GetLocationFunc func(ctx context.Context, idOrSlug any) (*location.Location, error)
+
+	// ListLocationsFunc mocks the ListLocations method.
+	ListLocationsFunc func(ctx context.Context) ([]*location.Location, error)
+
+	// UpdateLocationFunc mocks the UpdateLocation method.
+	UpdateLocationFunc func(ctx context.Context, id int, loc location.Location) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// CreateLocation holds details about calls to the CreateLocation method.
+		CreateLocation []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Loc is the loc argument value.
+			Loc location.Location
+		}
+		// DeleteLocation holds details about calls to the DeleteLocation method.
+		DeleteLocation []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// GetLocation holds details about calls to the GetLocation method.
+		GetLocation []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IdOrSlug is the idOrSlug argument value.
+			IdOrSlug any
+		}
+		// ListLocations holds details about calls to the ListLocations method.
+		ListLocations []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// UpdateLocation holds details about calls to the UpdateLocation method.
+		UpdateLocation []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Loc is the loc argument value.
+			Loc location.Location
+		}
+	}
+	lockCreateLocation sync.RWMutex
+	lockDeleteLocation sync.RWMutex
+	lockGetLocation    sync.RWMutex
+	lockListLocations  sync.RWMutex
+	lockUpdateLocation sync.RWMutex
+}
+
+// CreateLocation calls CreateLocationFunc.
+func (mock *LocationService) CreateLocation(ctx context.Context, loc location.Location) (*location.Location, error) {
+	if mock.CreateLocationFunc == nil {
+		panic("LocationService.CreateLocationFunc: method is nil but LocationService.CreateLocation was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Loc location.Location
+	}{
+		Ctx: ctx,
+		Loc: loc,
+	}
+	mock.lockCreateLocation.Lock()
+	mock.calls.CreateLocation = append(mock.calls.CreateLocation, callInfo)
+	mock.lockCreateLocation.Unlock()
+	return mock.CreateLocationFunc(ctx, loc)
+}
+
+// CreateLocationCalls gets all the calls that were made to CreateLocation.
+// Check the length with:
+//
+//	len(mockedLocationService.CreateLocationCalls())
+func (mock *LocationService) CreateLocationCalls() []struct {
+	Ctx context.Context
+	Loc location.Location
+} {
+	var calls []struct {
+		Ctx context.Context
+		Loc location.Location
+	}
+	mock.lockCreateLocation.RLock()
+	calls = mock.calls.CreateLocation
+	mock.lockCreateLocation.RUnlock()
+	return calls
+}
+
+// DeleteLocation calls DeleteLocationFunc.
+func (mock *LocationService) DeleteLocation(ctx context.Context, id int) error {
+	if mock.DeleteLocationFunc == nil {
+		panic("LocationService.DeleteLocationFunc: method is nil but LocationService.DeleteLocation was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockDeleteLocation.Lock()
+	mock.calls.DeleteLocation = append(mock.calls.DeleteLocation, callInfo)
+	mock.lockDeleteLocation.Unlock()
+	return mock.DeleteLocationFunc(ctx, id)
+}
+
+// DeleteLocationCalls gets all the calls that were made to DeleteLocation.
+// Check the length with:
+//
+//	len(mockedLocationService.DeleteLocationCalls())
+func (mock *LocationService) DeleteLocationCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockDeleteLocation.RLock()
+	calls = mock.calls.DeleteLocation
+	mock.lockDeleteLocation.RUnlock()
+	return calls
+}
+
+// GetLocation calls GetLocationFunc.
+func (mock *LocationService) GetLocation(ctx context.Context, idOrSlug any) (*location.Location, error) {
+	if mock.GetLocationFunc == nil {
+		panic("LocationService.GetLocationFunc: method is nil but LocationService.GetLocation was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		IdOrSlug any
+	}{
+		Ctx:      ctx,
+		IdOrSlug: idOrSlug,
+	}
+	mock.lockGetLocation.Lock()
+	mock.calls.GetLocation = append(mock.calls.GetLocation, callInfo)
+	mock.lockGetLocation.Unlock()
+	return mock.GetLocationFunc(ctx, idOrSlug)
+}
+
+// GetLocationCalls gets all the calls that were made to GetLocation.
+// Check the length with:
+//
+//	len(mockedLocationService.GetLocationCalls())
+func (mock *LocationService) GetLocationCalls() []struct {
+	Ctx      context.Context
+	IdOrSlug any
+} {
+	var calls []struct {
+		Ctx      context.Context
+		IdOrSlug any
+	}
+	mock.lockGetLocation.RLock()
+	calls = mock.calls.GetLocation
+	mock.lockGetLocation.RUnlock()
+	return calls
+}
+
+// ListLocations calls ListLocationsFunc.
+func (mock *LocationService) ListLocations(ctx context.Context) ([]*location.Location, error) {
+	if mock.ListLocationsFunc == nil {
+		panic("LocationService.ListLocationsFunc: method is nil but LocationService.ListLocations was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockListLocations.Lock()
+	mock.calls.ListLocations = append(mock.calls.ListLocations, callInfo)
+	mock.lockListLocations.Unlock()
+	return mock.ListLocationsFunc(ctx)
+}
+
+// ListLocationsCalls gets all the calls that were made to ListLocations.
+// Check the length with:
+//
+//	len(mockedLocationService.ListLocationsCalls())
+func (mock *LocationService) ListLocationsCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockListLocations.RLock()
+	calls = mock.calls.ListLocations
+	mock.lockListLocations.RUnlock()
+	return calls
+}
+
+// UpdateLocation calls UpdateLocationFunc.
+func (mock *LocationService) UpdateLocation(ctx context.Context, id int, loc location.Location) error {
+	if mock.UpdateLocationFunc == nil {
+		panic("LocationService.UpdateLocationFunc: method is nil but LocationService.UpdateLocation was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+		Loc location.Location
+	}{
+		Ctx: ctx,
+		ID:  id,
+		Loc: loc,
+	}
+	mock.lockUpdateLocation.Lock()
+	mock.calls.UpdateLocation = append(mock.calls.UpdateLocation, callInfo)
+	mock.lockUpdateLocation.Unlock()
+	return mock.UpdateLocationFunc(ctx, id, loc)
+}
+
+// UpdateLocationCalls gets all the calls that were made to UpdateLocation.
+// Check the length with:
+//
+//	len(mockedLocationService.UpdateLocationCalls())
+func (mock *LocationService) UpdateLocationCalls() []struct {
+	Ctx context.Context
+	ID  int
+	Loc location.Location
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+		Loc location.Location
+	}
+	mock.lockUpdateLocation.RLock()
+	calls = mock.calls.UpdateLocation
+	mock.lockUpdateLocation.RUnlock()
+	return calls
+}
@@ -0,0 +1,28 @@
+// Package mock has generated mocks of the services, for handler tests that
+// only care what a handler asks of its service and what it makes of the
+// answer. Each mock has a Func field per method; set the ones the test
+// expects to be called, and Calls lists what it was called with:
+//
+//	svc := &mock.ProductService{
+//		GetProductFunc: func(ctx context.Context, idOrSlug any) (*product.Product, error) {
+//			return nil, product.ErrProductNotFound
+//		},
+//	}
+//	h := product.NewProductHandler(svc)
+//	...
+//	if len(svc.GetProductCalls()) != 1 { ... }
+//
+// A method called without its Func set panics, so a test fails loudly on
+// calls it didn't plan for. Regenerate after changing a service interface,
+// with moq (github.com/matryer/moq) on the PATH:
+//
+//	go generate ./internal/testutil/mock
+package mock
+
+//go:generate moq -rm -pkg mock -out inventory.go ../../entities/inventory InventoryService:InventoryService
+//go:generate moq -rm -pkg mock -out location.go ../../entities/location LocationService:LocationService
+//go:generate moq -rm -pkg mock -out order.go ../../entities/order OrderService:OrderService
+//go:generate moq -rm -pkg mock -out product.go ../../entities/product ProductService:ProductService
+//go:generate moq -rm -pkg mock -out role.go ../../entities/role RoleService:RoleService
+//go:generate moq -rm -pkg mock -out store.go ../../entities/store StoreService:StoreService
+//go:generate moq -rm -pkg mock -out user.go ../../entities/user UserService:UserService
@@ -0,0 +1,569 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mock
+
+import (
+	"context"
+	"github.com/iteranya/practicing-go/internal/entities/order"
+	"sync"
+	"time"
+)
+
+// Ensure, that OrderService does implement order.OrderService.
+// If this is not the case, regenerate this file with moq.
+var _ order.OrderService = &OrderService{}
+
+// OrderService is a mock implementation of order.OrderService.
+//
+//	func TestSomethingThatUsesOrderService(t *testing.T) {
+//
+//		// make and configure a mocked order.OrderService
+//		mockedOrderService := &OrderService{
+//			ArchiveBeforeFunc: func(ctx context.Context, before time.Time) (int, error) {
+//				panic("mock out the ArchiveBefore method")
+//			},
+//			CreateOrderFunc: func(ctx context.Context, orderMoqParam order.Order) (*order.Order, error) {
+//				panic("mock out the CreateOrder method")
+//			},
+//			GetClerkPerformanceFunc: func(ctx context.Context, clerkId int, start time.Time, end time.Time, archived bool) (int64, error) {
+//				panic("mock out the GetClerkPerformance method")
+//			},
+//			GetOrderFunc: func(ctx context.Context, id int) (*order.Order, error) {
+//				panic("mock out the GetOrder method")
+//			},
+//			GetOrdersByClerkFunc: func(ctx context.Context, clerkId int) ([]*order.Order, error) {
+//				panic("mock out the GetOrdersByClerk method")
+//			},
+//			GetSalesStatsFunc: func(ctx context.Context, start time.Time, end time.Time, archived bool) (order.SalesStats, error) {
+//				panic("mock out the GetSalesStats method")
+//			},
+//			ListOrdersFunc: func(ctx context.Context, params order.OrderServiceListParams) ([]*order.Order, error) {
+//				panic("mock out the ListOrders method")
+//			},
+//			OnOrderEventFunc: func(fn func(order.OrderEvent))  {
+//				panic("mock out the OnOrderEvent method")
+//			},
+//			ProcessPaymentFunc: func(ctx context.Context, id int, amountPaid int64, revision int) error {
+//				panic("mock out the ProcessPayment method")
+//			},
+//			VoidOrderFunc: func(ctx context.Context, id int) error {
+//				panic("mock out the VoidOrder method")
+//			},
+//		}
+//
+//		// use mockedOrderService in code that requires order.OrderService
+//		// and then make assertions.
+//
+//	}
+type OrderService struct {
+	// ArchiveBeforeFunc mocks the ArchiveBefore method.
+	ArchiveBeforeFunc func(ctx context.Context, before time.Time) (int, error)
+
+	// CreateOrderFunc mocks the CreateOrder method.
+	CreateOrderFunc func(ctx context.Context, orderMoqParam order.Order) (*order.Order, error)
+
+	// GetClerkPerformanceFunc mocks the GetClerkPerformance method.
+	GetClerkPerformanceFunc func(ctx context.Context, clerkId int, start time.Time, end time.Time, archived bool) (int64, error)
+
+	// GetOrderFunc mocks the GetOrder method.
+	GetOrderFunc func(ctx context.Context, id int) (*order.Order, error)
+
+	// GetOrdersByClerkFunc mocks the GetOrdersByClerk method.
+	GetOrdersByClerkFunc func(ctx context.Context, clerkId int) ([]*order.Order, error)
+
+	// GetSalesStatsFunc mocks the GetSalesStats method.
+	GetSalesStatsFunc func(ctx context.Context, start time.Time, end time.Time, archived bool) (order.SalesStats, error)
+
+	// ListOrdersFunc mocks the ListOrders method.
+	ListOrdersFunc func(ctx context.Context, params order.OrderServiceListParams) ([]*order.Order, error)
+
+	// OnOrderEventFunc mocks the OnOrderEvent method.
+	OnOrderEventFunc func(fn func(order.OrderEvent))
+
+	// ProcessPaymentFunc mocks the ProcessPayment method.
+	ProcessPaymentFunc func(ctx context.Context, id int, amountPaid int64, revision int) error
+
+	// VoidOrderFunc mocks the VoidOrder method.
+	VoidOrderFunc func(ctx context.Context, id int) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// ArchiveBefore holds details about calls to the ArchiveBefore method.
+		ArchiveBefore []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Before is the before argument value.
+			Before time.Time
+		}
+		// CreateOrder holds details about calls to the CreateOrder method.
+		CreateOrder []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// OrderMoqParam is the orderMoqParam argument value.
+			OrderMoqParam order.Order
+		}
+		// GetClerkPerformance holds details about calls to the GetClerkPerformance method.
+		GetClerkPerformance []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ClerkId is the clerkId argument value.
+			ClerkId int
+			// Start is the start argument value.
+			Start time.Time
+			// End is the end argument value.
+			End time.Time
+			// Archived is the archived argument value.
+			Archived bool
+		}
+		// GetOrder holds details about calls to the GetOrder method.
+		GetOrder []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// GetOrdersByClerk holds details about calls to the GetOrdersByClerk method.
+		GetOrdersByClerk []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ClerkId is the clerkId argument value.
+			ClerkId int
+		}
+		// GetSalesStats holds details about calls to the GetSalesStats method.
+		GetSalesStats []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Start is the start argument value.
+			Start time.Time
+			// End is the end argument value.
+			End time.Time
+			// Archived is the archived argument value.
+			Archived bool
+		}
+		// ListOrders holds details about calls to the ListOrders method.
+		ListOrders []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Params is the params argument value.
+			Params order.OrderServiceListParams
+		}
+		// OnOrderEvent holds details about calls to the OnOrderEvent method.
+		OnOrderEvent []struct {
+			// Fn is the fn argument value.
+			Fn func(order.OrderEvent)
+		}
+		// ProcessPayment holds details about calls to the ProcessPayment method.
+		ProcessPayment []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// AmountPaid is the amountPaid argument value.
+			AmountPaid int64
+			// Revision is the revision argument value.
+			Revision int
+		}
+		// VoidOrder holds details about calls to the VoidOrder method.
+		VoidOrder []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+	}
+	lockArchiveBefore       sync.RWMutex
+	lockCreateOrder         sync.RWMutex
+	lockGetClerkPerformance sync.RWMutex
+	lockGetOrder            sync.RWMutex
+	lockGetOrdersByClerk    sync.RWMutex
+	lockGetSalesStats       sync.RWMutex
+	lockListOrders          sync.RWMutex
+	lockOnOrderEvent        sync.RWMutex
+	lockProcessPayment      sync.RWMutex
+	lockVoidOrder           sync.RWMutex
+}
+
+// ArchiveBefore calls ArchiveBeforeFunc.
+func (mock *OrderService) ArchiveBefore(ctx context.Context, before time.Time) (int, error) {
+	if mock.ArchiveBeforeFunc == nil {
+		panic("OrderService.ArchiveBeforeFunc: method is nil but OrderService.ArchiveBefore was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Before time.Time
+	}{
+		Ctx:    ctx,
+		Before: before,
+	}
+	mock.lockArchiveBefore.Lock()
+	mock.calls.ArchiveBefore = append(mock.calls.ArchiveBefore, callInfo)
+	mock.lockArchiveBefore.Unlock()
+	return mock.ArchiveBeforeFunc(ctx, before)
+}
+
+// ArchiveBeforeCalls gets all the calls that were made to ArchiveBefore.
+// Check the length with:
+//
+//	len(mockedOrderService.ArchiveBeforeCalls())
+func (mock *OrderService) ArchiveBeforeCalls() []struct {
+	Ctx    context.Context
+	Before time.Time
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Before time.Time
+	}
+	mock.lockArchiveBefore.RLock()
+	calls = mock.calls.ArchiveBefore
+	mock.lockArchiveBefore.RUnlock()
+	return calls
+}
+
+// CreateOrder calls CreateOrderFunc.
+func (mock *OrderService) CreateOrder(ctx context.Context, orderMoqParam order.Order) (*order.Order, error) {
+	if mock.CreateOrderFunc == nil {
+		panic("OrderService.CreateOrderFunc: method is nil but OrderService.CreateOrder was just called")
+	}
+	callInfo := struct {
+		Ctx           context.Context
+		OrderMoqParam order.Order
+	}{
+		Ctx:           ctx,
+		OrderMoqParam: orderMoqParam,
+	}
+	mock.lockCreateOrder.Lock()
+	mock.calls.CreateOrder = append(mock.calls.CreateOrder, callInfo)
+	mock.lockCreateOrder.Unlock()
+	return mock.CreateOrderFunc(ctx, orderMoqParam)
+}
+
+// CreateOrderCalls gets all the calls that were made to CreateOrder.
+// Check the length with:
+//
+//	len(mockedOrderService.CreateOrderCalls())
+func (mock *OrderService) CreateOrderCalls() []struct {
+	Ctx           context.Context
+	OrderMoqParam order.Order
+} {
+	var calls []struct {
+		Ctx           context.Context
+		OrderMoqParam order.Order
+	}
+	mock.lockCreateOrder.RLock()
+	calls = mock.calls.CreateOrder
+	mock.lockCreateOrder.RUnlock()
+	return calls
+}
+
+// GetClerkPerformance calls GetClerkPerformanceFunc.
+func (mock *OrderService) GetClerkPerformance(ctx context.Context, clerkId int, start time.Time, end time.Time, archived bool) (int64, error) {
+	if mock.GetClerkPerformanceFunc == nil {
+		panic("OrderService.GetClerkPerformanceFunc: method is nil but OrderService.GetClerkPerformance was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		ClerkId  int
+		Start    time.Time
+		End      time.Time
+		Archived bool
+	}{
+		Ctx:      ctx,
+		ClerkId:  clerkId,
+		Start:    start,
+		End:      end,
+		Archived: archived,
+	}
+	mock.lockGetClerkPerformance.Lock()
+	mock.calls.GetClerkPerformance = append(mock.calls.GetClerkPerformance, callInfo)
+	mock.lockGetClerkPerformance.Unlock()
+	return mock.GetClerkPerformanceFunc(ctx, clerkId, start, end, archived)
+}
+
+// GetClerkPerformanceCalls gets all the calls that were made to GetClerkPerformance.
+// Check the length with:
+//
+//	len(mockedOrderService.GetClerkPerformanceCalls())
+func (mock *OrderService) GetClerkPerformanceCalls() []struct {
+	Ctx      context.Context
+	ClerkId  int
+	Start    time.Time
+	End      time.Time
+	Archived bool
+} {
+	var calls []struct {
+		Ctx      context.Context
+		ClerkId  int
+		Start    time.Time
+		End      time.Time
+		Archived bool
+	}
+	mock.lockGetClerkPerformance.RLock()
+	calls = mock.calls.GetClerkPerformance
+	mock.lockGetClerkPerformance.RUnlock()
+	return calls
+}
+
+// GetOrder calls GetOrderFunc.
+func (mock *OrderService) GetOrder(ctx context.Context, id int) (*order.Order, error) {
+	if mock.GetOrderFunc == nil {
+		panic("OrderService.GetOrderFunc: method is nil but OrderService.GetOrder was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetOrder.Lock()
+	mock.calls.GetOrder = append(mock.calls.GetOrder, callInfo)
+	mock.lockGetOrder.Unlock()
+	return mock.GetOrderFunc(ctx, id)
+}
+
+// GetOrderCalls gets all the calls that were made to GetOrder.
+// Check the length with:
+//
+//	len(mockedOrderService.GetOrderCalls())
+func (mock *OrderService) GetOrderCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockGetOrder.RLock()
+	calls = mock.calls.GetOrder
+	mock.lockGetOrder.RUnlock()
+	return calls
+}
+
+// GetOrdersByClerk calls GetOrdersByClerkFunc.
+func (mock *OrderService) GetOrdersByClerk(ctx context.Context, clerkId int) ([]*order.Order, error) {
+	if mock.GetOrdersByClerkFunc == nil {
+		panic("OrderService.GetOrdersByClerkFunc: method is nil but OrderService.GetOrdersByClerk was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		ClerkId int
+	}{
+		Ctx:     ctx,
+		ClerkId: clerkId,
+	}
+	mock.lockGetOrdersByClerk.Lock()
+	mock.calls.GetOrdersByClerk = append(mock.calls.GetOrdersByClerk, callInfo)
+	mock.lockGetOrdersByClerk.Unlock()
+	return mock.GetOrdersByClerkFunc(ctx, clerkId)
+}
+
+// GetOrdersByClerkCalls gets all the calls that were made to GetOrdersByClerk.
+// Check the length with:
+//
+//	len(mockedOrderService.GetOrdersByClerkCalls())
+func (mock *OrderService) GetOrdersByClerkCalls() []struct {
+	Ctx     context.Context
+	ClerkId int
+} {
+	var calls []struct {
+		Ctx     context.Context
+		ClerkId int
+	}
+	mock.lockGetOrdersByClerk.RLock()
+	calls = mock.calls.GetOrdersByClerk
+	mock.lockGetOrdersByClerk.RUnlock()
+	return calls
+}
+
+// GetSalesStats calls GetSalesStatsFunc.
+func (mock *OrderService) GetSalesStats(ctx context.Context, start time.Time, end time.Time, archived bool) (order.SalesStats, error) {
+	if mock.GetSalesStatsFunc == nil {
+		panic("OrderService.GetSalesStatsFunc: method is nil but OrderService.GetSalesStats was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Start    time.Time
+		End      time.Time
+		Archived bool
+	}{
+		Ctx:      ctx,
+		Start:    start,
+		End:      end,
+		Archived: archived,
+	}
+	mock.lockGetSalesStats.Lock()
+	mock.calls.GetSalesStats = append(mock.calls.GetSalesStats, callInfo)
+	mock.lockGetSalesStats.Unlock()
+	return mock.GetSalesStatsFunc(ctx, start, end, archived)
+}
+
+// GetSalesStatsCalls gets all the calls that were made to GetSalesStats.
+// Check the length with:
+//
+//	len(mockedOrderService.GetSalesStatsCalls())
+func (mock *OrderService) GetSalesStatsCalls() []struct {
+	Ctx      context.Context
+	Start    time.Time
+	End      time.Time
+	Archived bool
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Start    time.Time
+		End      time.Time
+		Archived bool
+	}
+	mock.lockGetSalesStats.RLock()
+	calls = mock.calls.GetSalesStats
+	mock.lockGetSalesStats.RUnlock()
+	return calls
+}
+
+// ListOrders calls ListOrdersFunc.
+func (mock *OrderService) ListOrders(ctx context.Context, params order.OrderServiceListParams) ([]*order.Order, error) {
+	if mock.ListOrdersFunc == nil {
+		panic("OrderService.ListOrdersFunc: method is nil but OrderService.ListOrders was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Params order.OrderServiceListParams
+	}{
+		Ctx:    ctx,
+		Params: params,
+	}
+	mock.lockListOrders.Lock()
+	mock.calls.ListOrders = append(mock.calls.ListOrders, callInfo)
+	mock.lockListOrders.Unlock()
+	return mock.ListOrdersFunc(ctx, params)
+}
+
+// ListOrdersCalls gets all the calls that were made to ListOrders.
+// Check the length with:
+//
+//	len(mockedOrderService.ListOrdersCalls())
+func (mock *OrderService) ListOrdersCalls() []struct {
+	Ctx    context.Context
+	Params order.OrderServiceListParams
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Params order.OrderServiceListParams
+	}
+	mock.lockListOrders.RLock()
+	calls = mock.calls.ListOrders
+	mock.lockListOrders.RUnlock()
+	return calls
+}
+
+// OnOrderEvent calls OnOrderEventFunc.
+func (mock *OrderService) OnOrderEvent(fn func(order.OrderEvent)) {
+	if mock.OnOrderEventFunc == nil {
+		panic("OrderService.OnOrderEventFunc: method is nil but OrderService.OnOrderEvent was just called")
+	}
+	callInfo := struct {
+		Fn func(order.OrderEvent)
+	}{
+		Fn: fn,
+	}
+	mock.lockOnOrderEvent.Lock()
+	mock.calls.OnOrderEvent = append(mock.calls.OnOrderEvent, callInfo)
+	mock.lockOnOrderEvent.Unlock()
+	mock.OnOrderEventFunc(fn)
+}
+
+// OnOrderEventCalls gets all the calls that were made to OnOrderEvent.
+// Check the length with:
+//
+//	len(mockedOrderService.OnOrderEventCalls())
+func (mock *OrderService) OnOrderEventCalls() []struct {
+	Fn func(order.OrderEvent)
+} {
+	var calls []struct {
+		Fn func(order.OrderEvent)
+	}
+	mock.lockOnOrderEvent.RLock()
+	calls = mock.calls.OnOrderEvent
+	mock.lockOnOrderEvent.RUnlock()
+	return calls
+}
+
+// ProcessPayment calls ProcessPaymentFunc.
+func (mock *OrderService) ProcessPayment(ctx context.Context, id int, amountPaid int64, revision int) error {
+	if mock.ProcessPaymentFunc == nil {
+		panic("OrderService.ProcessPaymentFunc: method is nil but OrderService.ProcessPayment was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		ID         int
+		AmountPaid int64
+		Revision   int
+	}{
+		Ctx:        ctx,
+		ID:         id,
+		AmountPaid: amountPaid,
+		Revision:   revision,
+	}
+	mock.lockProcessPayment.Lock()
+	mock.calls.ProcessPayment = append(mock.calls.ProcessPayment, callInfo)
+	mock.lockProcessPayment.Unlock()
+	return mock.ProcessPaymentFunc(ctx, id, amountPaid, revision)
+}
+
+// ProcessPaymentCalls gets all the calls that were made to ProcessPayment.
+// Check the length with:
+//
+//	len(mockedOrderService.ProcessPaymentCalls())
+func (mock *OrderService) ProcessPaymentCalls() []struct {
+	Ctx        context.Context
+	ID         int
+	AmountPaid int64
+	Revision   int
+} {
+	var calls []struct {
+		Ctx        context.Context
+		ID         int
+		AmountPaid int64
+		Revision   int
+	}
+	mock.lockProcessPayment.RLock()
+	calls = mock.calls.ProcessPayment
+	mock.lockProcessPayment.RUnlock()
+	return calls
+}
+
+// VoidOrder calls VoidOrderFunc.
+func (mock *OrderService) VoidOrder(ctx context.Context, id int) error {
+	if mock.VoidOrderFunc == nil {
+		panic("OrderService.VoidOrderFunc: method is nil but OrderService.VoidOrder was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockVoidOrder.Lock()
+	mock.calls.VoidOrder = append(mock.calls.VoidOrder, callInfo)
+	mock.lockVoidOrder.Unlock()
+	return mock.VoidOrderFunc(ctx, id)
+}
+
+// VoidOrderCalls gets all the calls that were made to VoidOrder.
+// Check the length with:
+//
+//	len(mockedOrderService.VoidOrderCalls())
+func (mock *OrderService) VoidOrderCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockVoidOrder.RLock()
+	calls = mock.calls.VoidOrder
+	mock.lockVoidOrder.RUnlock()
+	return calls
+}
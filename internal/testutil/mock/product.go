@@ -0,0 +1,639 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mock
+
+import (
+	"context"
+	"github.com/iteranya/practicing-go/internal/entities/product"
+	"sync"
+	"time"
+)
+
+// Ensure, that ProductService does implement product.ProductService.
+// If this is not the case, regenerate this file with moq.
+var _ product.ProductService = &ProductService{}
+
+// ProductService is a mock implementation of product.ProductService.
+//
+//	func TestSomethingThatUsesProductService(t *testing.T) {
+//
+//		// make and configure a mocked product.ProductService
+//		mockedProductService := &ProductService{
+//			CreateProductFunc: func(ctx context.Context, productMoqParam product.Product) (*product.Product, error) {
+//				panic("mock out the CreateProduct method")
+//			},
+//			DeleteProductFunc: func(ctx context.Context, id int) error {
+//				panic("mock out the DeleteProduct method")
+//			},
+//			GetBundlesFunc: func(ctx context.Context) ([]*product.Product, error) {
+//				panic("mock out the GetBundles method")
+//			},
+//			GetProductFunc: func(ctx context.Context, idOrSlug any) (*product.Product, error) {
+//				panic("mock out the GetProduct method")
+//			},
+//			GetProductsWithRecipesFunc: func(ctx context.Context) ([]*product.Product, error) {
+//				panic("mock out the GetProductsWithRecipes method")
+//			},
+//			ListProductsFunc: func(ctx context.Context, params product.ProductServiceListParams) ([]*product.Product, error) {
+//				panic("mock out the ListProducts method")
+//			},
+//			PurgeDeletedFunc: func(ctx context.Context, before time.Time) (int, error) {
+//				panic("mock out the PurgeDeleted method")
+//			},
+//			PurgeProductFunc: func(ctx context.Context, id int) error {
+//				panic("mock out the PurgeProduct method")
+//			},
+//			RestoreProductFunc: func(ctx context.Context, id int) error {
+//				panic("mock out the RestoreProduct method")
+//			},
+//			SetAvailabilityFunc: func(ctx context.Context, id int, available bool) error {
+//				panic("mock out the SetAvailability method")
+//			},
+//			UpdatePriceFunc: func(ctx context.Context, id int, newPrice int64) error {
+//				panic("mock out the UpdatePrice method")
+//			},
+//			UpdateProductFunc: func(ctx context.Context, id int, productMoqParam product.Product) error {
+//				panic("mock out the UpdateProduct method")
+//			},
+//		}
+//
+//		// use mockedProductService in code that requires product.ProductService
+//		// and then make assertions.
+//
+//	}
+type ProductService struct {
+	// CreateProductFunc mocks the CreateProduct method.
+	CreateProductFunc func(ctx context.Context, productMoqParam product.Product) (*product.Product, error)
+
+	// DeleteProductFunc mocks the DeleteProduct method.
+	DeleteProductFunc func(ctx context.Context, id int) error
+
+	// GetBundlesFunc mocks the GetBundles method.
+	GetBundlesFunc func(ctx context.Context) ([]*product.Product, error)
+
+	// GetProductFunc mocks the GetProduct method.
+	GetProductFunc func(ctx context.Context, idOrSlug any) (*product.Product, error)
+
+	// GetProductsWithRecipesFunc mocks the GetProductsWithRecipes method.
+	GetProductsWithRecipesFunc func(ctx context.Context) ([]*product.Product, error)
+
+	// ListProductsFunc mocks the ListProducts method.
+	ListProductsFunc func(ctx context.Context, params product.ProductServiceListParams) ([]*product.Product, error)
+
+	// PurgeDeletedFunc mocks the PurgeDeleted method.
+	PurgeDeletedFunc func(ctx context.Context, before time.Time) (int, error)
+
+	// PurgeProductFunc mocks the PurgeProduct method.
+	PurgeProductFunc func(ctx context.Context, id int) error
+
+	// RestoreProductFunc mocks the RestoreProduct method.
+	RestoreProductFunc func(ctx context.Context, id int) error
+
+	// SetAvailabilityFunc mocks the SetAvailability method.
+	SetAvailabilityFunc func(ctx context.Context, id int, available bool) error
+
+	// UpdatePriceFunc mocks the UpdatePrice method.
+	UpdatePriceFunc func(ctx context.Context, id int, newPrice int64) error
+
+	// UpdateProductFunc mocks the UpdateProduct method.
+	UpdateProductFunc func(ctx context.Context, id int, productMoqParam product.Product) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// CreateProduct holds details about calls to the CreateProduct method.
+		CreateProduct []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ProductMoqParam is the productMoqParam argument value.
+			ProductMoqParam product.Product
+		}
+		// DeleteProduct holds details about calls to the DeleteProduct method.
+		DeleteProduct []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// GetBundles holds details about calls to the GetBundles method.
+		GetBundles []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// GetProduct holds details about calls to the GetProduct method.
+		GetProduct []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IdOrSlug is the idOrSlug argument value.
+			IdOrSlug any
+		}
+		// GetProductsWithRecipes holds details about calls to the GetProductsWithRecipes method.
+		GetProductsWithRecipes []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// ListProducts holds details about calls to the ListProducts method.
+		ListProducts []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Params is the params argument value.
+			Params product.ProductServiceListParams
+		}
+		// PurgeDeleted holds details about calls to the PurgeDeleted method.
+		PurgeDeleted []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Before is the before argument value.
+			Before time.Time
+		}
+		// PurgeProduct holds details about calls to the PurgeProduct method.
+		PurgeProduct []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// RestoreProduct holds details about calls to the RestoreProduct method.
+		RestoreProduct []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// SetAvailability holds details about calls to the SetAvailability method.
+		SetAvailability []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Available is the available argument value.
+			Available bool
+		}
+		// UpdatePrice holds details about calls to the UpdatePrice method.
+		UpdatePrice []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// NewPrice is the newPrice argument value.
+			NewPrice int64
+		}
+		// UpdateProduct holds details about calls to the UpdateProduct method.
+		UpdateProduct []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// ProductMoqParam is the productMoqParam argument value.
+			ProductMoqParam product.Product
+		}
+	}
+	lockCreateProduct          sync.RWMutex
+	lockDeleteProduct          sync.RWMutex
+	lockGetBundles             sync.RWMutex
+	lockGetProduct             sync.RWMutex
+	lockGetProductsWithRecipes sync.RWMutex
+	lockListProducts           sync.RWMutex
+	lockPurgeDeleted           sync.RWMutex
+	lockPurgeProduct           sync.RWMutex
+	lockRestoreProduct         sync.RWMutex
+	lockSetAvailability        sync.RWMutex
+	lockUpdatePrice            sync.RWMutex
+	lockUpdateProduct          sync.RWMutex
+}
+
+// CreateProduct calls CreateProductFunc.
+func (mock *ProductService) CreateProduct(ctx context.Context, productMoqParam product.Product) (*product.Product, error) {
+	if mock.CreateProductFunc == nil {
+		panic("ProductService.CreateProductFunc: method is nil but ProductService.CreateProduct was just called")
+	}
+	callInfo := struct {
+		Ctx             context.Context
+		ProductMoqParam product.Product
+	}{
+		Ctx:             ctx,
+		ProductMoqParam: productMoqParam,
+	}
+	mock.lockCreateProduct.Lock()
+	mock.calls.CreateProduct = append(mock.calls.CreateProduct, callInfo)
+	mock.lockCreateProduct.Unlock()
+	return mock.CreateProductFunc(ctx, productMoqParam)
+}
+
+// CreateProductCalls gets all the calls that were made to CreateProduct.
+// Check the length with:
+//
+//	len(mockedProductService.CreateProductCalls())
+func (mock *ProductService) CreateProductCalls() []struct {
+	Ctx             context.Context
+	ProductMoqParam product.Product
+} {
+	var calls []struct {
+		Ctx             context.Context
+		ProductMoqParam product.Product
+	}
+	mock.lockCreateProduct.RLock()
+	calls = mock.calls.CreateProduct
+	mock.lockCreateProduct.RUnlock()
+	return calls
+}
+
+// DeleteProduct calls DeleteProductFunc.
+func (mock *ProductService) DeleteProduct(ctx context.Context, id int) error {
+	if mock.DeleteProductFunc == nil {
+		panic("ProductService.DeleteProductFunc: method is nil but ProductService.DeleteProduct was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockDeleteProduct.Lock()
+	mock.calls.DeleteProduct = append(mock.calls.DeleteProduct, callInfo)
+	mock.lockDeleteProduct.Unlock()
+	return mock.DeleteProductFunc(ctx, id)
+}
+
+// DeleteProductCalls gets all the calls that were made to DeleteProduct.
+// Check the length with:
+//
+//	len(mockedProductService.DeleteProductCalls())
+func (mock *ProductService) DeleteProductCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockDeleteProduct.RLock()
+	calls = mock.calls.DeleteProduct
+	mock.lockDeleteProduct.RUnlock()
+	return calls
+}
+
+// GetBundles calls GetBundlesFunc.
+func (mock *ProductService) GetBundles(ctx context.Context) ([]*product.Product, error) {
+	if mock.GetBundlesFunc == nil {
+		panic("ProductService.GetBundlesFunc: method is nil but ProductService.GetBundles was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetBundles.Lock()
+	mock.calls.GetBundles = append(mock.calls.GetBundles, callInfo)
+	mock.lockGetBundles.Unlock()
+	return mock.GetBundlesFunc(ctx)
+}
+
+// GetBundlesCalls gets all the calls that were made to GetBundles.
+// Check the length with:
+//
+//	len(mockedProductService.GetBundlesCalls())
+func (mock *ProductService) GetBundlesCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetBundles.RLock()
+	calls = mock.calls.GetBundles
+	mock.lockGetBundles.RUnlock()
+	return calls
+}
+
+// GetProduct calls GetProductFunc.
+func (mock *ProductService) GetProduct(ctx context.Context, idOrSlug any) (*product.Product, error) {
+	if mock.GetProductFunc == nil {
+		panic("ProductService.GetProductFunc: method is nil but ProductService.GetProduct was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		IdOrSlug any
+	}{
+		Ctx:      ctx,
+		IdOrSlug: idOrSlug,
+	}
+	mock.lockGetProduct.Lock()
+	mock.calls.GetProduct = append(mock.calls.GetProduct, callInfo)
+	mock.lockGetProduct.Unlock()
+	return mock.GetProductFunc(ctx, idOrSlug)
+}
+
+// GetProductCalls gets all the calls that were made to GetProduct.
+// Check the length with:
+//
+//	len(mockedProductService.GetProductCalls())
+func (mock *ProductService) GetProductCalls() []struct {
+	Ctx      context.Context
+	IdOrSlug any
+} {
+	var calls []struct {
+		Ctx      context.Context
+		IdOrSlug any
+	}
+	mock.lockGetProduct.RLock()
+	calls = mock.calls.GetProduct
+	mock.lockGetProduct.RUnlock()
+	return calls
+}
+
+// GetProductsWithRecipes calls GetProductsWithRecipesFunc.
+func (mock *ProductService) GetProductsWithRecipes(ctx context.Context) ([]*product.Product, error) {
+	if mock.GetProductsWithRecipesFunc == nil {
+		panic("ProductService.GetProductsWithRecipesFunc: method is nil but ProductService.GetProductsWithRecipes was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetProductsWithRecipes.Lock()
+	mock.calls.GetProductsWithRecipes = append(mock.calls.GetProductsWithRecipes, callInfo)
+	mock.lockGetProductsWithRecipes.Unlock()
+	return mock.GetProductsWithRecipesFunc(ctx)
+}
+
+// GetProductsWithRecipesCalls gets all the calls that were made to GetProductsWithRecipes.
+// Check the length with:
+//
+//	len(mockedProductService.GetProductsWithRecipesCalls())
+func (mock *ProductService) GetProductsWithRecipesCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetProductsWithRecipes.RLock()
+	calls = mock.calls.GetProductsWithRecipes
+	mock.lockGetProductsWithRecipes.RUnlock()
+	return calls
+}
+
+// ListProducts calls ListProductsFunc.
+func (mock *ProductService) ListProducts(ctx context.Context, params product.ProductServiceListParams) ([]*product.Product, error) {
+	if mock.ListProductsFunc == nil {
+		panic("ProductService.ListProductsFunc: method is nil but ProductService.ListProducts was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Params product.ProductServiceListParams
+	}{
+		Ctx:    ctx,
+		Params: params,
+	}
+	mock.lockListProducts.Lock()
+	mock.calls.ListProducts = append(mock.calls.ListProducts, callInfo)
+	mock.lockListProducts.Unlock()
+	return mock.ListProductsFunc(ctx, params)
+}
+
+// ListProductsCalls gets all the calls that were made to ListProducts.
+// Check the length with:
+//
+//	len(mockedProductService.ListProductsCalls())
+func (mock *ProductService) ListProductsCalls() []struct {
+	Ctx    context.Context
+	Params product.ProductServiceListParams
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Params product.ProductServiceListParams
+	}
+	mock.lockListProducts.RLock()
+	calls = mock.calls.ListProducts
+	mock.lockListProducts.RUnlock()
+	return calls
+}
+
+// PurgeDeleted calls PurgeDeletedFunc.
+func (mock *ProductService) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	if mock.PurgeDeletedFunc == nil {
+		panic("ProductService.PurgeDeletedFunc: method is nil but ProductService.PurgeDeleted was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Before time.Time
+	}{
+		Ctx:    ctx,
+		Before: before,
+	}
+	mock.lockPurgeDeleted.Lock()
+	mock.calls.PurgeDeleted = append(mock.calls.PurgeDeleted, callInfo)
+	mock.lockPurgeDeleted.Unlock()
+	return mock.PurgeDeletedFunc(ctx, before)
+}
+
+// PurgeDeletedCalls gets all the calls that were made to PurgeDeleted.
+// Check the length with:
+//
+//	len(mockedProductService.PurgeDeletedCalls())
+func (mock *ProductService) PurgeDeletedCalls() []struct {
+	Ctx    context.Context
+	Before time.Time
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Before time.Time
+	}
+	mock.lockPurgeDeleted.RLock()
+	calls = mock.calls.PurgeDeleted
+	mock.lockPurgeDeleted.RUnlock()
+	return calls
+}
+
+// PurgeProduct calls PurgeProductFunc.
+func (mock *ProductService) PurgeProduct(ctx context.Context, id int) error {
+	if mock.PurgeProductFunc == nil {
+		panic("ProductService.PurgeProductFunc: method is nil but ProductService.PurgeProduct was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockPurgeProduct.Lock()
+	mock.calls.PurgeProduct = append(mock.calls.PurgeProduct, callInfo)
+	mock.lockPurgeProduct.Unlock()
+	return mock.PurgeProductFunc(ctx, id)
+}
+
+// PurgeProductCalls gets all the calls that were made to PurgeProduct.
+// Check the length with:
+//
+//	len(mockedProductService.PurgeProductCalls())
+func (mock *ProductService) PurgeProductCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockPurgeProduct.RLock()
+	calls = mock.calls.PurgeProduct
+	mock.lockPurgeProduct.RUnlock()
+	return calls
+}
+
+// RestoreProduct calls RestoreProductFunc.
+func (mock *ProductService) RestoreProduct(ctx context.Context, id int) error {
+	if mock.RestoreProductFunc == nil {
+		panic("ProductService.RestoreProductFunc: method is nil but ProductService.RestoreProduct was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockRestoreProduct.Lock()
+	mock.calls.RestoreProduct = append(mock.calls.RestoreProduct, callInfo)
+	mock.lockRestoreProduct.Unlock()
+	return mock.RestoreProductFunc(ctx, id)
+}
+
+// RestoreProductCalls gets all the calls that were made to RestoreProduct.
+// Check the length with:
+//
+//	len(mockedProductService.RestoreProductCalls())
+func (mock *ProductService) RestoreProductCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockRestoreProduct.RLock()
+	calls = mock.calls.RestoreProduct
+	mock.lockRestoreProduct.RUnlock()
+	return calls
+}
+
+// SetAvailability calls SetAvailabilityFunc.
+func (mock *ProductService) SetAvailability(ctx context.Context, id int, available bool) error {
+	if mock.SetAvailabilityFunc == nil {
+		panic("ProductService.SetAvailabilityFunc: method is nil but ProductService.SetAvailability was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		ID        int
+		Available bool
+	}{
+		Ctx:       ctx,
+		ID:        id,
+		Available: available,
+	}
+	mock.lockSetAvailability.Lock()
+	mock.calls.SetAvailability = append(mock.calls.SetAvailability, callInfo)
+	mock.lockSetAvailability.Unlock()
+	return mock.SetAvailabilityFunc(ctx, id, available)
+}
+
+// SetAvailabilityCalls gets all the calls that were made to SetAvailability.
+// Check the length with:
+//
+//	len(mockedProductService.SetAvailabilityCalls())
+func (mock *ProductService) SetAvailabilityCalls() []struct {
+	Ctx       context.Context
+	ID        int
+	Available bool
+} {
+	var calls []struct {
+		Ctx       context.Context
+		ID        int
+		Available bool
+	}
+	mock.lockSetAvailability.RLock()
+	calls = mock.calls.SetAvailability
+	mock.lockSetAvailability.RUnlock()
+	return calls
+}
+
+// UpdatePrice calls UpdatePriceFunc.
+func (mock *ProductService) UpdatePrice(ctx context.Context, id int, newPrice int64) error {
+	if mock.UpdatePriceFunc == nil {
+		panic("ProductService.UpdatePriceFunc: method is nil but ProductService.UpdatePrice was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		ID       int
+		NewPrice int64
+	}{
+		Ctx:      ctx,
+		ID:       id,
+		NewPrice: newPrice,
+	}
+	mock.lockUpdatePrice.Lock()
+	mock.calls.UpdatePrice = append(mock.calls.UpdatePrice, callInfo)
+	mock.lockUpdatePrice.Unlock()
+	return mock.UpdatePriceFunc(ctx, id, newPrice)
+}
+
+// UpdatePriceCalls gets all the calls that were made to UpdatePrice.
+// Check the length with:
+//
+//	len(mockedProductService.UpdatePriceCalls())
+func (mock *ProductService) UpdatePriceCalls() []struct {
+	Ctx      context.Context
+	ID       int
+	NewPrice int64
+} {
+	var calls []struct {
+		Ctx      context.Context
+		ID       int
+		NewPrice int64
+	}
+	mock.lockUpdatePrice.RLock()
+	calls = mock.calls.UpdatePrice
+	mock.lockUpdatePrice.RUnlock()
+	return calls
+}
+
+// UpdateProduct calls UpdateProductFunc.
+func (mock *ProductService) UpdateProduct(ctx context.Context, id int, productMoqParam product.Product) error {
+	if mock.UpdateProductFunc == nil {
+		panic("ProductService.UpdateProductFunc: method is nil but ProductService.UpdateProduct was just called")
+	}
+	callInfo := struct {
+		Ctx             context.Context
+		ID              int
+		ProductMoqParam product.Product
+	}{
+		Ctx:             ctx,
+		ID:              id,
+		ProductMoqParam: productMoqParam,
+	}
+	mock.lockUpdateProduct.Lock()
+	mock.calls.UpdateProduct = append(mock.calls.UpdateProduct, callInfo)
+	mock.lockUpdateProduct.Unlock()
+	return mock.UpdateProductFunc(ctx, id, productMoqParam)
+}
+
+// UpdateProductCalls gets all the calls that were made to UpdateProduct.
+// Check the length with:
+//
+//	len(mockedProductService.UpdateProductCalls())
+func (mock *ProductService) UpdateProductCalls() []struct {
+	Ctx             context.Context
+	ID              int
+	ProductMoqParam product.Product
+} {
+	var calls []struct {
+		Ctx             context.Context
+		ID              int
+		ProductMoqParam product.Product
+	}
+	mock.lockUpdateProduct.RLock()
+	calls = mock.calls.UpdateProduct
+	mock.lockUpdateProduct.RUnlock()
+	return calls
+}
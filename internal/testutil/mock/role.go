@@ -0,0 +1,988 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mock
+
+import (
+	"context"
+	"github.com/iteranya/practicing-go/internal/entities/role"
+	"sync"
+	"time"
+)
+
+// Ensure, that RoleService does implement role.RoleService.
+// If this is not the case, regenerate this file with moq.
+var _ role.RoleService = &RoleService{}
+
+// RoleService is a mock implementation of role.RoleService.
+//
+//	func TestSomethingThatUsesRoleService(t *testing.T) {
+//
+//		// make and configure a mocked role.RoleService
+//		mockedRoleService := &RoleService{
+//			AddPermissionFunc: func(ctx context.Context, id int, permission string) error {
+//				panic("mock out the AddPermission method")
+//			},
+//			CloneRoleFunc: func(ctx context.Context, id int, slug string, name string) (*role.Role, error) {
+//				panic("mock out the CloneRole method")
+//			},
+//			CreateFromTemplateFunc: func(ctx context.Context, name string) (*role.Role, error) {
+//				panic("mock out the CreateFromTemplate method")
+//			},
+//			CreateRoleFunc: func(ctx context.Context, roleMoqParam role.Role) (*role.Role, error) {
+//				panic("mock out the CreateRole method")
+//			},
+//			DeleteRoleFunc: func(ctx context.Context, id int, reassignTo string) (int, error) {
+//				panic("mock out the DeleteRole method")
+//			},
+//			GetPolicyMapFunc: func(ctx context.Context) (map[string][]string, error) {
+//				panic("mock out the GetPolicyMap method")
+//			},
+//			GetRoleFunc: func(ctx context.Context, idOrSlug any) (*role.Role, error) {
+//				panic("mock out the GetRole method")
+//			},
+//			GetUsageFunc: func(ctx context.Context) (*role.UsageReport, error) {
+//				panic("mock out the GetUsage method")
+//			},
+//			ListAuditFunc: func(ctx context.Context, id int, params role.AuditListParams) ([]*role.AuditEntry, error) {
+//				panic("mock out the ListAudit method")
+//			},
+//			ListDeletedRolesFunc: func(ctx context.Context) ([]*role.Role, error) {
+//				panic("mock out the ListDeletedRoles method")
+//			},
+//			ListRolesFunc: func(ctx context.Context) ([]*role.Role, error) {
+//				panic("mock out the ListRoles method")
+//			},
+//			ListTemplatesFunc: func() []role.Role {
+//				panic("mock out the ListTemplates method")
+//			},
+//			PurgeDeletedFunc: func(ctx context.Context, before time.Time) (int, error) {
+//				panic("mock out the PurgeDeleted method")
+//			},
+//			PurgeRoleFunc: func(ctx context.Context, id int) error {
+//				panic("mock out the PurgeRole method")
+//			},
+//			RemovePermissionFunc: func(ctx context.Context, id int, permission string) error {
+//				panic("mock out the RemovePermission method")
+//			},
+//			RestoreRoleFunc: func(ctx context.Context, id int) error {
+//				panic("mock out the RestoreRole method")
+//			},
+//			SeedDefaultsFunc: func(ctx context.Context) (int, error) {
+//				panic("mock out the SeedDefaults method")
+//			},
+//			UpdatePermissionsFunc: func(ctx context.Context, id int, permissions []string) error {
+//				panic("mock out the UpdatePermissions method")
+//			},
+//			UpdateRoleFunc: func(ctx context.Context, id int, roleMoqParam role.Role) error {
+//				panic("mock out the UpdateRole method")
+//			},
+//		}
+//
+//		// use mockedRoleService in code that requires role.RoleService
+//		// and then make assertions.
+//
+//	}
+type RoleService struct {
+	// AddPermissionFunc mocks the AddPermission method.
+	AddPermissionFunc func(ctx context.Context, id int, permission string) error
+
+	// CloneRoleFunc mocks the CloneRole method.
+	CloneRoleFunc func(ctx context.Context, id int, slug string, name string) (*role.Role, error)
+
+	// CreateFromTemplateFunc mocks the CreateFromTemplate method.
+	CreateFromTemplateFunc func(ctx context.Context, name string) (*role.Role, error)
+
+	// CreateRoleFunc mocks the CreateRole method.
+	CreateRoleFunc func(ctx context.Context, roleMoqParam role.Role) (*role.Role, error)
+
+	// DeleteRoleFunc mocks the DeleteRole method.
+	DeleteRoleFunc func(ctx context.Context, id int, reassignTo string) (int, error)
+
+	// GetPolicyMapFunc mocks the GetPolicyMap method.
+	GetPolicyMapFunc func(ctx context.Context) (map[string][]string, error)
+
+	// GetRoleFunc mocks the GetRole method.
+	GetRoleFunc func(ctx context.Context, idOrSlug any) (*role.Role, error)
+
+	// GetUsageFunc mocks the GetUsage method.
+	GetUsageFunc func(ctx context.Context) (*role.UsageReport, error)
+
+	// ListAuditFunc mocks the ListAudit method.
+	ListAuditFunc func(ctx context.Context, id int, params role.AuditListParams) ([]*role.AuditEntry, error)
+
+	// ListDeletedRolesFunc mocks the ListDeletedRoles method.
+	ListDeletedRolesFunc func(ctx context.Context) ([]*role.Role, error)
+
+	// ListRolesFunc mocks the ListRoles method.
+	ListRolesFunc func(ctx context.Context) ([]*role.Role, error)
+
+	// ListTemplatesFunc mocks the ListTemplates method.
+	ListTemplatesFunc func() []role.Role
+
+	// PurgeDeletedFunc mocks the PurgeDeleted method.
+	PurgeDeletedFunc func(ctx context.Context, before time.Time) (int, error)
+
+	// PurgeRoleFunc mocks the PurgeRole method.
+	PurgeRoleFunc func(ctx context.Context, id int) error
+
+	// RemovePermissionFunc mocks the RemovePermission method.
+	RemovePermissionFunc func(ctx context.Context, id int, permission string) error
+
+	// RestoreRoleFunc mocks the RestoreRole method.
+	RestoreRoleFunc func(ctx context.Context, id int) error
+
+	// SeedDefaultsFunc mocks the SeedDefaults method.
+	SeedDefaultsFunc func(ctx context.Context) (int, error)
+
+	// UpdatePermissionsFunc mocks the UpdatePermissions method.
+	UpdatePermissionsFunc func(ctx context.Context, id int, permissions []string) error
+
+	// UpdateRoleFunc mocks the UpdateRole method.
+	UpdateRoleFunc func(ctx context.Context, id int, roleMoqParam role.Role) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// AddPermission holds details about calls to the AddPermission method.
+		AddPermission []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Permission is the permission argument value.
+			Permission string
+		}
+		// CloneRole holds details about calls to the CloneRole method.
+		CloneRole []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Slug is the slug argument value.
+			Slug string
+			// Name is the name argument value.
+			Name string
+		}
+		// CreateFromTemplate holds details about calls to the CreateFromTemplate method.
+		CreateFromTemplate []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Name is the name argument value.
+			Name string
+		}
+		// CreateRole holds details about calls to the CreateRole method.
+		CreateRole []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// RoleMoqParam is the roleMoqParam argument value.
+			RoleMoqParam role.Role
+		}
+		// DeleteRole holds details about calls to the DeleteRole method.
+		DeleteRole []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// ReassignTo is the reassignTo argument value.
+			ReassignTo string
+		}
+		// GetPolicyMap holds details about calls to the GetPolicyMap method.
+		GetPolicyMap []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// GetRole holds details about calls to the GetRole method.
+		GetRole []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IdOrSlug is the idOrSlug argument value.
+			IdOrSlug any
+		}
+		// GetUsage holds details about calls to the GetUsage method.
+		GetUsage []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// ListAudit holds details about calls to the ListAudit method.
+		ListAudit []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Params is the params argument value.
+			Params role.AuditListParams
+		}
+		// ListDeletedRoles holds details about calls to the ListDeletedRoles method.
+		ListDeletedRoles []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// ListRoles holds details about calls to the ListRoles method.
+		ListRoles []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// ListTemplates holds details about calls to the ListTemplates method.
+		ListTemplates []struct {
+		}
+		// PurgeDeleted holds details about calls to the PurgeDeleted method.
+		PurgeDeleted []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Before is the before argument value.
+			Before time.Time
+		}
+		// PurgeRole holds details about calls to the PurgeRole method.
+		PurgeRole []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// RemovePermission holds details about calls to the RemovePermission method.
+		RemovePermission []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Permission is the permission argument value.
+			Permission string
+		}
+		// RestoreRole holds details about calls to the RestoreRole method.
+		RestoreRole []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// SeedDefaults holds details about calls to the SeedDefaults method.
+		SeedDefaults []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// UpdatePermissions holds details about calls to the UpdatePermissions method.
+		UpdatePermissions []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Permissions is the permissions argument value.
+			Permissions []string
+		}
+		// UpdateRole holds details about calls to the UpdateRole method.
+		UpdateRole []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// RoleMoqParam is the roleMoqParam argument value.
+			RoleMoqParam role.Role
+		}
+	}
+	lockAddPermission      sync.RWMutex
+	lockCloneRole          sync.RWMutex
+	lockCreateFromTemplate sync.RWMutex
+	lockCreateRole         sync.RWMutex
+	lockDeleteRole         sync.RWMutex
+	lockGetPolicyMap       sync.RWMutex
+	lockGetRole            sync.RWMutex
+	lockGetUsage           sync.RWMutex
+	lockListAudit          sync.RWMutex
+	lockListDeletedRoles   sync.RWMutex
+	lockListRoles          sync.RWMutex
+	lockListTemplates      sync.RWMutex
+	lockPurgeDeleted       sync.RWMutex
+	lockPurgeRole          sync.RWMutex
+	lockRemovePermission   sync.RWMutex
+	lockRestoreRole        sync.RWMutex
+	lockSeedDefaults       sync.RWMutex
+	lockUpdatePermissions  sync.RWMutex
+	lockUpdateRole         sync.RWMutex
+}
+
+// AddPermission calls AddPermissionFunc.
+func (mock *RoleService) AddPermission(ctx context.Context, id int, permission string) error {
+	if mock.AddPermissionFunc == nil {
+		panic("RoleService.AddPermissionFunc: method is nil but RoleService.AddPermission was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		ID         int
+		Permission string
+	}{
+		Ctx:        ctx,
+		ID:         id,
+		Permission: permission,
+	}
+	mock.lockAddPermission.Lock()
+	mock.calls.AddPermission = append(mock.calls.AddPermission, callInfo)
+	mock.lockAddPermission.Unlock()
+	return mock.AddPermissionFunc(ctx, id, permission)
+}
+
+// AddPermissionCalls gets all the calls that were made to AddPermission.
+// Check the length with:
+//
+//	len(mockedRoleService.AddPermissionCalls())
+func (mock *RoleService) AddPermissionCalls() []struct {
+	Ctx        context.Context
+	ID         int
+	Permission string
+} {
+	var calls []struct {
+		Ctx        context.Context
+		ID         int
+		Permission string
+	}
+	mock.lockAddPermission.RLock()
+	calls = mock.calls.AddPermission
+	mock.lockAddPermission.RUnlock()
+	return calls
+}
+
+// CloneRole calls CloneRoleFunc.
+func (mock *RoleService) CloneRole(ctx context.Context, id int, slug string, name string) (*role.Role, error) {
+	if mock.CloneRoleFunc == nil {
+		panic("RoleService.CloneRoleFunc: method is nil but RoleService.CloneRole was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		ID   int
+		Slug string
+		Name string
+	}{
+		Ctx:  ctx,
+		ID:   id,
+		Slug: slug,
+		Name: name,
+	}
+	mock.lockCloneRole.Lock()
+	mock.calls.CloneRole = append(mock.calls.CloneRole, callInfo)
+	mock.lockCloneRole.Unlock()
+	return mock.CloneRoleFunc(ctx, id, slug, name)
+}
+
+// CloneRoleCalls gets all the calls that were made to CloneRole.
+// Check the length with:
+//
+//	len(mockedRoleService.CloneRoleCalls())
+func (mock *RoleService) CloneRoleCalls() []struct {
+	Ctx  context.Context
+	ID   int
+	Slug string
+	Name string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		ID   int
+		Slug string
+		Name string
+	}
+	mock.lockCloneRole.RLock()
+	calls = mock.calls.CloneRole
+	mock.lockCloneRole.RUnlock()
+	return calls
+}
+
+// CreateFromTemplate calls CreateFromTemplateFunc.
+func (mock *RoleService) CreateFromTemplate(ctx context.Context, name string) (*role.Role, error) {
+	if mock.CreateFromTemplateFunc == nil {
+		panic("RoleService.CreateFromTemplateFunc: method is nil but RoleService.CreateFromTemplate was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Name string
+	}{
+		Ctx:  ctx,
+		Name: name,
+	}
+	mock.lockCreateFromTemplate.Lock()
+	mock.calls.CreateFromTemplate = append(mock.calls.CreateFromTemplate, callInfo)
+	mock.lockCreateFromTemplate.Unlock()
+	return mock.CreateFromTemplateFunc(ctx, name)
+}
+
+// CreateFromTemplateCalls gets all the calls that were made to CreateFromTemplate.
+// Check the length with:
+//
+//	len(mockedRoleService.CreateFromTemplateCalls())
+func (mock *RoleService) CreateFromTemplateCalls() []struct {
+	Ctx  context.Context
+	Name string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Name string
+	}
+	mock.lockCreateFromTemplate.RLock()
+	calls = mock.calls.CreateFromTemplate
+	mock.lockCreateFromTemplate.RUnlock()
+	return calls
+}
+
+// CreateRole calls CreateRoleFunc.
+func (mock *RoleService) CreateRole(ctx context.Context, roleMoqParam role.Role) (*role.Role, error) {
+	if mock.CreateRoleFunc == nil {
+		panic("RoleService.CreateRoleFunc: method is nil but RoleService.CreateRole was just called")
+	}
+	callInfo := struct {
+		Ctx          context.Context
+		RoleMoqParam role.Role
+	}{
+		Ctx:          ctx,
+		RoleMoqParam: roleMoqParam,
+	}
+	mock.lockCreateRole.Lock()
+	mock.calls.CreateRole = append(mock.calls.CreateRole, callInfo)
+	mock.lockCreateRole.Unlock()
+	return mock.CreateRoleFunc(ctx, roleMoqParam)
+}
+
+// CreateRoleCalls gets all the calls that were made to CreateRole.
+// Check the length with:
+//
+//	len(mockedRoleService.CreateRoleCalls())
+func (mock *RoleService) CreateRoleCalls() []struct {
+	Ctx          context.Context
+	RoleMoqParam role.Role
+} {
+	var calls []struct {
+		Ctx          context.Context
+		RoleMoqParam role.Role
+	}
+	mock.lockCreateRole.RLock()
+	calls = mock.calls.CreateRole
+	mock.lockCreateRole.RUnlock()
+	return calls
+}
+
+// DeleteRole calls DeleteRoleFunc.
+func (mock *RoleService) DeleteRole(ctx context.Context, id int, reassignTo string) (int, error) {
+	if mock.DeleteRoleFunc == nil {
+		panic("RoleService.DeleteRoleFunc: method is nil but RoleService.DeleteRole was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		ID         int
+		ReassignTo string
+	}{
+		Ctx:        ctx,
+		ID:         id,
+		ReassignTo: reassignTo,
+	}
+	mock.lockDeleteRole.Lock()
+	mock.calls.DeleteRole = append(mock.calls.DeleteRole, callInfo)
+	mock.lockDeleteRole.Unlock()
+	return mock.DeleteRoleFunc(ctx, id, reassignTo)
+}
+
+// DeleteRoleCalls gets all the calls that were made to DeleteRole.
+// Check the length with:
+//
+//	len(mockedRoleService.DeleteRoleCalls())
+func (mock *RoleService) DeleteRoleCalls() []struct {
+	Ctx        context.Context
+	ID         int
+	ReassignTo string
+} {
+	var calls []struct {
+		Ctx        context.Context
+		ID         int
+		ReassignTo string
+	}
+	mock.lockDeleteRole.RLock()
+	calls = mock.calls.DeleteRole
+	mock.lockDeleteRole.RUnlock()
+	return calls
+}
+
+// GetPolicyMap calls GetPolicyMapFunc.
+func (mock *RoleService) GetPolicyMap(ctx context.Context) (map[string][]string, error) {
+	if mock.GetPolicyMapFunc == nil {
+		panic("RoleService.GetPolicyMapFunc: method is nil but RoleService.GetPolicyMap was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetPolicyMap.Lock()
+	mock.calls.GetPolicyMap = append(mock.calls.GetPolicyMap, callInfo)
+	mock.lockGetPolicyMap.Unlock()
+	return mock.GetPolicyMapFunc(ctx)
+}
+
+// GetPolicyMapCalls gets all the calls that were made to GetPolicyMap.
+// Check the length with:
+//
+//	len(mockedRoleService.GetPolicyMapCalls())
+func (mock *RoleService) GetPolicyMapCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetPolicyMap.RLock()
+	calls = mock.calls.GetPolicyMap
+	mock.lockGetPolicyMap.RUnlock()
+	return calls
+}
+
+// GetRole calls GetRoleFunc.
+func (mock *RoleService) GetRole(ctx context.Context, idOrSlug any) (*role.Role, error) {
+	if mock.GetRoleFunc == nil {
+		panic("RoleService.GetRoleFunc: method is nil but RoleService.GetRole was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		IdOrSlug any
+	}{
+		Ctx:      ctx,
+		IdOrSlug: idOrSlug,
+	}
+	mock.lockGetRole.Lock()
+	mock.calls.GetRole = append(mock.calls.GetRole, callInfo)
+	mock.lockGetRole.Unlock()
+	return mock.GetRoleFunc(ctx, idOrSlug)
+}
+
+// GetRoleCalls gets all the calls that were made to GetRole.
+// Check the length with:
+//
+//	len(mockedRoleService.GetRoleCalls())
+func (mock *RoleService) GetRoleCalls() []struct {
+	Ctx      context.Context
+	IdOrSlug any
+} {
+	var calls []struct {
+		Ctx      context.Context
+		IdOrSlug any
+	}
+	mock.lockGetRole.RLock()
+	calls = mock.calls.GetRole
+	mock.lockGetRole.RUnlock()
+	return calls
+}
+
+// GetUsage calls GetUsageFunc.
+func (mock *RoleService) GetUsage(ctx context.Context) (*role.UsageReport, error) {
+	if mock.GetUsageFunc == nil {
+		panic("RoleService.GetUsageFunc: method is nil but RoleService.GetUsage was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetUsage.Lock()
+	mock.calls.GetUsage = append(mock.calls.GetUsage, callInfo)
+	mock.lockGetUsage.Unlock()
+	return mock.GetUsageFunc(ctx)
+}
+
+// GetUsageCalls gets all the calls that were made to GetUsage.
+// Check the length with:
+//
+//	len(mockedRoleService.GetUsageCalls())
+func (mock *RoleService) GetUsageCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetUsage.RLock()
+	calls = mock.calls.GetUsage
+	mock.lockGetUsage.RUnlock()
+	return calls
+}
+
+// ListAudit calls ListAuditFunc.
+func (mock *RoleService) ListAudit(ctx context.Context, id int, params role.AuditListParams) ([]*role.AuditEntry, error) {
+	if mock.ListAuditFunc == nil {
+		panic("RoleService.ListAuditFunc: method is nil but RoleService.ListAudit was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		ID     int
+		Params role.AuditListParams
+	}{
+		Ctx:    ctx,
+		ID:     id,
+		Params: params,
+	}
+	mock.lockListAudit.Lock()
+	mock.calls.ListAudit = append(mock.calls.ListAudit, callInfo)
+	mock.lockListAudit.Unlock()
+	return mock.ListAuditFunc(ctx, id, params)
+}
+
+// ListAuditCalls gets all the calls that were made to ListAudit.
+// Check the length with:
+//
+//	len(mockedRoleService.ListAuditCalls())
+func (mock *RoleService) ListAuditCalls() []struct {
+	Ctx    context.Context
+	ID     int
+	Params role.AuditListParams
+} {
+	var calls []struct {
+		Ctx    context.Context
+		ID     int
+		Params role.AuditListParams
+	}
+	mock.lockListAudit.RLock()
+	calls = mock.calls.ListAudit
+	mock.lockListAudit.RUnlock()
+	return calls
+}
+
+// ListDeletedRoles calls ListDeletedRolesFunc.
+func (mock *RoleService) ListDeletedRoles(ctx context.Context) ([]*role.Role, error) {
+	if mock.ListDeletedRolesFunc == nil {
+		panic("RoleService.ListDeletedRolesFunc: method is nil but RoleService.ListDeletedRoles was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockListDeletedRoles.Lock()
+	mock.calls.ListDeletedRoles = append(mock.calls.ListDeletedRoles, callInfo)
+	mock.lockListDeletedRoles.Unlock()
+	return mock.ListDeletedRolesFunc(ctx)
+}
+
+// ListDeletedRolesCalls gets all the calls that were made to ListDeletedRoles.
+// Check the length with:
+//
+//	len(mockedRoleService.ListDeletedRolesCalls())
+func (mock *RoleService) ListDeletedRolesCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockListDeletedRoles.RLock()
+	calls = mock.calls.ListDeletedRoles
+	mock.lockListDeletedRoles.RUnlock()
+	return calls
+}
+
+// ListRoles calls ListRolesFunc.
+func (mock *RoleService) ListRoles(ctx context.Context) ([]*role.Role, error) {
+	if mock.ListRolesFunc == nil {
+		panic("RoleService.ListRolesFunc: method is nil but RoleService.ListRoles was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockListRoles.Lock()
+	mock.calls.ListRoles = append(mock.calls.ListRoles, callInfo)
+	mock.lockListRoles.Unlock()
+	return mock.ListRolesFunc(ctx)
+}
+
+// ListRolesCalls gets all the calls that were made to ListRoles.
+// Check the length with:
+//
+//	len(mockedRoleService.ListRolesCalls())
+func (mock *RoleService) ListRolesCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockListRoles.RLock()
+	calls = mock.calls.ListRoles
+	mock.lockListRoles.RUnlock()
+	return calls
+}
+
+// ListTemplates calls ListTemplatesFunc.
+func (mock *RoleService) ListTemplates() []role.Role {
+	if mock.ListTemplatesFunc == nil {
+		panic("RoleService.ListTemplatesFunc: method is nil but RoleService.ListTemplates was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockListTemplates.Lock()
+	mock.calls.ListTemplates = append(mock.calls.ListTemplates, callInfo)
+	mock.lockListTemplates.Unlock()
+	return mock.ListTemplatesFunc()
+}
+
+// ListTemplatesCalls gets all the calls that were made to ListTemplates.
+// Check the length with:
+//
+//	len(mockedRoleService.ListTemplatesCalls())
+func (mock *RoleService) ListTemplatesCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockListTemplates.RLock()
+	calls = mock.calls.ListTemplates
+	mock.lockListTemplates.RUnlock()
+	return calls
+}
+
+// PurgeDeleted calls PurgeDeletedFunc.
+func (mock *RoleService) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	if mock.PurgeDeletedFunc == nil {
+		panic("RoleService.PurgeDeletedFunc: method is nil but RoleService.PurgeDeleted was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Before time.Time
+	}{
+		Ctx:    ctx,
+		Before: before,
+	}
+	mock.lockPurgeDeleted.Lock()
+	mock.calls.PurgeDeleted = append(mock.calls.PurgeDeleted, callInfo)
+	mock.lockPurgeDeleted.Unlock()
+	return mock.PurgeDeletedFunc(ctx, before)
+}
+
+// PurgeDeletedCalls gets all the calls that were made to PurgeDeleted.
+// Check the length with:
+//
+//	len(mockedRoleService.PurgeDeletedCalls())
+func (mock *RoleService) PurgeDeletedCalls() []struct {
+	Ctx    context.Context
+	Before time.Time
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Before time.Time
+	}
+	mock.lockPurgeDeleted.RLock()
+	calls = mock.calls.PurgeDeleted
+	mock.lockPurgeDeleted.RUnlock()
+	return calls
+}
+
+// PurgeRole calls PurgeRoleFunc.
+func (mock *RoleService) PurgeRole(ctx context.Context, id int) error {
+	if mock.PurgeRoleFunc == nil {
+		panic("RoleService.PurgeRoleFunc: method is nil but RoleService.PurgeRole was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockPurgeRole.Lock()
+	mock.calls.PurgeRole = append(mock.calls.PurgeRole, callInfo)
+	mock.lockPurgeRole.Unlock()
+	return mock.PurgeRoleFunc(ctx, id)
+}
+
+// PurgeRoleCalls gets all the calls that were made to PurgeRole.
+// Check the length with:
+//
+//	len(mockedRoleService.PurgeRoleCalls())
+func (mock *RoleService) PurgeRoleCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockPurgeRole.RLock()
+	calls = mock.calls.PurgeRole
+	mock.lockPurgeRole.RUnlock()
+	return calls
+}
+
+// RemovePermission calls RemovePermissionFunc.
+func (mock *RoleService) RemovePermission(ctx context.Context, id int, permission string) error {
+	if mock.RemovePermissionFunc == nil {
+		panic("RoleService.RemovePermissionFunc: method is nil but RoleService.RemovePermission was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		ID         int
+		Permission string
+	}{
+		Ctx:        ctx,
+		ID:         id,
+		Permission: permission,
+	}
+	mock.lockRemovePermission.Lock()
+	mock.calls.RemovePermission = append(mock.calls.RemovePermission, callInfo)
+	mock.lockRemovePermission.Unlock()
+	return mock.RemovePermissionFunc(ctx, id, permission)
+}
+
+// RemovePermissionCalls gets all the calls that were made to RemovePermission.
+// Check the length with:
+//
+//	len(mockedRoleService.RemovePermissionCalls())
+func (mock *RoleService) RemovePermissionCalls() []struct {
+	Ctx        context.Context
+	ID         int
+	Permission string
+} {
+	var calls []struct {
+		Ctx        context.Context
+		ID         int
+		Permission string
+	}
+	mock.lockRemovePermission.RLock()
+	calls = mock.calls.RemovePermission
+	mock.lockRemovePermission.RUnlock()
+	return calls
+}
+
+// RestoreRole calls RestoreRoleFunc.
+func (mock *RoleService) RestoreRole(ctx context.Context, id int) error {
+	if mock.RestoreRoleFunc == nil {
+		panic("RoleService.RestoreRoleFunc: method is nil but RoleService.RestoreRole was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockRestoreRole.Lock()
+	mock.calls.RestoreRole = append(mock.calls.RestoreRole, callInfo)
+	mock.lockRestoreRole.Unlock()
+	return mock.RestoreRoleFunc(ctx, id)
+}
+
+// RestoreRoleCalls gets all the calls that were made to RestoreRole.
+// Check the length with:
+//
+//	len(mockedRoleService.RestoreRoleCalls())
+func (mock *RoleService) RestoreRoleCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockRestoreRole.RLock()
+	calls = mock.calls.RestoreRole
+	mock.lockRestoreRole.RUnlock()
+	return calls
+}
+
+// SeedDefaults calls SeedDefaultsFunc.
+func (mock *RoleService) SeedDefaults(ctx context.Context) (int, error) {
+	if mock.SeedDefaultsFunc == nil {
+		panic("RoleService.SeedDefaultsFunc: method is nil but RoleService.SeedDefaults was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockSeedDefaults.Lock()
+	mock.calls.SeedDefaults = append(mock.calls.SeedDefaults, callInfo)
+	mock.lockSeedDefaults.Unlock()
+	return mock.SeedDefaultsFunc(ctx)
+}
+
+// SeedDefaultsCalls gets all the calls that were made to SeedDefaults.
+// Check the length with:
+//
+//	len(mockedRoleService.SeedDefaultsCalls())
+func (mock *RoleService) SeedDefaultsCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockSeedDefaults.RLock()
+	calls = mock.calls.SeedDefaults
+	mock.lockSeedDefaults.RUnlock()
+	return calls
+}
+
+// UpdatePermissions calls UpdatePermissionsFunc.
+func (mock *RoleService) UpdatePermissions(ctx context.Context, id int, permissions []string) error {
+	if mock.UpdatePermissionsFunc == nil {
+		panic("RoleService.UpdatePermissionsFunc: method is nil but RoleService.UpdatePermissions was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		ID          int
+		Permissions []string
+	}{
+		Ctx:         ctx,
+		ID:          id,
+		Permissions: permissions,
+	}
+	mock.lockUpdatePermissions.Lock()
+	mock.calls.UpdatePermissions = append(mock.calls.UpdatePermissions, callInfo)
+	mock.lockUpdatePermissions.Unlock()
+	return mock.UpdatePermissionsFunc(ctx, id, permissions)
+}
+
+// UpdatePermissionsCalls gets all the calls that were made to UpdatePermissions.
+// Check the length with:
+//
+//	len(mockedRoleService.UpdatePermissionsCalls())
+func (mock *RoleService) UpdatePermissionsCalls() []struct {
+	Ctx         context.Context
+	ID          int
+	Permissions []string
+} {
+	var calls []struct {
+		Ctx         context.Context
+		ID          int
+		Permissions []string
+	}
+	mock.lockUpdatePermissions.RLock()
+	calls = mock.calls.UpdatePermissions
+	mock.lockUpdatePermissions.RUnlock()
+	return calls
+}
+
+// UpdateRole calls UpdateRoleFunc.
+func (mock *RoleService) UpdateRole(ctx context.Context, id int, roleMoqParam role.Role) error {
+	if mock.UpdateRoleFunc == nil {
+		panic("RoleService.UpdateRoleFunc: method is nil but RoleService.UpdateRole was just called")
+	}
+	callInfo := struct {
+		Ctx          context.Context
+		ID           int
+		RoleMoqParam role.Role
+	}{
+		Ctx:          ctx,
+		ID:           id,
+		RoleMoqParam: roleMoqParam,
+	}
+	mock.lockUpdateRole.Lock()
+	mock.calls.UpdateRole = append(mock.calls.UpdateRole, callInfo)
+	mock.lockUpdateRole.Unlock()
+	return mock.UpdateRoleFunc(ctx, id, roleMoqParam)
+}
+
+// UpdateRoleCalls gets all the calls that were made to UpdateRole.
+// Check the length with:
+//
+//	len(mockedRoleService.UpdateRoleCalls())
+func (mock *RoleService) UpdateRoleCalls() []struct {
+	Ctx          context.Context
+	ID           int
+	RoleMoqParam role.Role
+} {
+	var calls []struct {
+		Ctx          context.Context
+		ID           int
+		RoleMoqParam role.Role
+	}
+	mock.lockUpdateRole.RLock()
+	calls = mock.calls.UpdateRole
+	mock.lockUpdateRole.RUnlock()
+	return calls
+}
@@ -0,0 +1,226 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mock
+
+import (
+	"context"
+	"github.com/iteranya/practicing-go/internal/entities/store"
+	"sync"
+)
+
+// Ensure, that StoreService does implement store.StoreService.
+// If this is not the case, regenerate this file with moq.
+var _ store.StoreService = &StoreService{}
+
+// StoreService is a mock implementation of store.StoreService.
+//
+//	func TestSomethingThatUsesStoreService(t *testing.T) {
+//
+//		// make and configure a mocked store.StoreService
+//		mockedStoreService := &StoreService{
+//			CreateStoreFunc: func(ctx context.Context, s store.Store) (*store.Store, error) {
+//				panic("mock out the CreateStore method")
+//			},
+//			GetStoreFunc: func(ctx context.Context, idOrSlug any) (*store.Store, error) {
+//				panic("mock out the GetStore method")
+//			},
+//			ListStoresFunc: func(ctx context.Context) ([]*store.Store, error) {
+//				panic("mock out the ListStores method")
+//			},
+//			ResolveFunc: func(ctx context.Context, slug string) (context.Context, error) {
+//				panic("mock out the Resolve method")
+//			},
+//		}
+//
+//		// use mockedStoreService in code that requires store.StoreService
+//		// and then make assertions.
+//
+//	}
+type StoreService struct {
+	// CreateStoreFunc mocks the CreateStore method.
+	CreateStoreFunc func(ctx context.Context, s store.Store) (*store.Store, error)
+
+	// GetStoreFunc mocks the GetStore method.
+	GetStoreFunc func(ctx context.Context, idOrSlug any) (*store.Store, error)
+
+	// ListStoresFunc mocks the ListStores method.
+	ListStoresFunc func(ctx context.Context) ([]*store.Store, error)
+
+	// ResolveFunc mocks the Resolve method.
+	ResolveFunc func(ctx context.Context, slug string) (context.Context, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// CreateStore holds details about calls to the CreateStore method.
+		CreateStore []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// S is the s argument value.
+			S store.Store
+		}
+		// GetStore holds details about calls to the GetStore method.
+		GetStore []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IdOrSlug is the idOrSlug argument value.
+			IdOrSlug any
+		}
+		// ListStores holds details about calls to the ListStores method.
+		ListStores []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// Resolve holds details about calls to the Resolve method.
+		Resolve []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Slug is the slug argument value.
+			Slug string
+		}
+	}
+	lockCreateStore sync.RWMutex
+	lockGetStore    sync.RWMutex
+	lockListStores  sync.RWMutex
+	lockResolve     sync.RWMutex
+}
+
+// CreateStore calls CreateStoreFunc.
+func (mock *StoreService) CreateStore(ctx context.Context, s store.Store) (*store.Store, error) {
+	if mock.CreateStoreFunc == nil {
+		panic("StoreService.CreateStoreFunc: method is nil but StoreService.CreateStore was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		S   store.Store
+	}{
+		Ctx: ctx,
+		S:   s,
+	}
+	mock.lockCreateStore.Lock()
+	mock.calls.CreateStore = append(mock.calls.CreateStore, callInfo)
+	mock.lockCreateStore.Unlock()
+	return mock.CreateStoreFunc(ctx, s)
+}
+
+// CreateStoreCalls gets all the calls that were made to CreateStore.
+// Check the length with:
+//
+//	len(mockedStoreService.CreateStoreCalls())
+func (mock *StoreService) CreateStoreCalls() []struct {
+	Ctx context.Context
+	S   store.Store
+} {
+	var calls []struct {
+		Ctx context.Context
+		S   store.Store
+	}
+	mock.lockCreateStore.RLock()
+	calls = mock.calls.CreateStore
+	mock.lockCreateStore.RUnlock()
+	return calls
+}
+
+// GetStore calls GetStoreFunc.
+func (mock *StoreService) GetStore(ctx context.Context, idOrSlug any) (*store.Store, error) {
+	if mock.GetStoreFunc == nil {
+		panic("StoreService.GetStoreFunc: method is nil but StoreService.GetStore was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		IdOrSlug any
+	}{
+		Ctx:      ctx,
+		IdOrSlug: idOrSlug,
+	}
+	mock.lockGetStore.Lock()
+	mock.calls.GetStore = append(mock.calls.GetStore, callInfo)
+	mock.lockGetStore.Unlock()
+	return mock.GetStoreFunc(ctx, idOrSlug)
+}
+
+// GetStoreCalls gets all the calls that were made to GetStore.
+// Check the length with:
+//
+//	len(mockedStoreService.GetStoreCalls())
+func (mock *StoreService) GetStoreCalls() []struct {
+	Ctx      context.Context
+	IdOrSlug any
+} {
+	var calls []struct {
+		Ctx      context.Context
+		IdOrSlug any
+	}
+	mock.lockGetStore.RLock()
+	calls = mock.calls.GetStore
+	mock.lockGetStore.RUnlock()
+	return calls
+}
+
+// ListStores calls ListStoresFunc.
+func (mock *StoreService) ListStores(ctx context.Context) ([]*store.Store, error) {
+	if mock.ListStoresFunc == nil {
+		panic("StoreService.ListStoresFunc: method is nil but StoreService.ListStores was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockListStores.Lock()
+	mock.calls.ListStores = append(mock.calls.ListStores, callInfo)
+	mock.lockListStores.Unlock()
+	return mock.ListStoresFunc(ctx)
+}
+
+// ListStoresCalls gets all the calls that were made to ListStores.
+// Check the length with:
+//
+//	len(mockedStoreService.ListStoresCalls())
+func (mock *StoreService) ListStoresCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockListStores.RLock()
+	calls = mock.calls.ListStores
+	mock.lockListStores.RUnlock()
+	return calls
+}
+
+// Resolve calls ResolveFunc.
+func (mock *StoreService) Resolve(ctx context.Context, slug string) (context.Context, error) {
+	if mock.ResolveFunc == nil {
+		panic("StoreService.ResolveFunc: method is nil but StoreService.Resolve was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Slug string
+	}{
+		Ctx:  ctx,
+		Slug: slug,
+	}
+	mock.lockResolve.Lock()
+	mock.calls.Resolve = append(mock.calls.Resolve, callInfo)
+	mock.lockResolve.Unlock()
+	return mock.ResolveFunc(ctx, slug)
+}
+
+// ResolveCalls gets all the calls that were made to Resolve.
+// Check the length with:
+//
+//	len(mockedStoreService.ResolveCalls())
+func (mock *StoreService) ResolveCalls() []struct {
+	Ctx  context.Context
+	Slug string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Slug string
+	}
+	mock.lockResolve.RLock()
+	calls = mock.calls.Resolve
+	mock.lockResolve.RUnlock()
+	return calls
+}
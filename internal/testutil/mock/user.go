@@ -0,0 +1,2511 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mock
+
+import (
+	"context"
+	"github.com/iteranya/practicing-go/internal/entities/user"
+	"github.com/iteranya/practicing-go/internal/utils"
+	"io"
+	"sync"
+	"time"
+)
+
+// Ensure, that UserService does implement user.UserService.
+// If this is not the case, regenerate this file with moq.
+var _ user.UserService = &UserService{}
+
+// UserService is a mock implementation of user.UserService.
+//
+//	func TestSomethingThatUsesUserService(t *testing.T) {
+//
+//		// make and configure a mocked user.UserService
+//		mockedUserService := &UserService{
+//			AcceptPolicyFunc: func(ctx context.Context, userId int, version string) (*user.PolicyStatus, error) {
+//				panic("mock out the AcceptPolicy method")
+//			},
+//			AnonymizeUserFunc: func(ctx context.Context, id int) error {
+//				panic("mock out the AnonymizeUser method")
+//			},
+//			ChangeOwnPasswordFunc: func(ctx context.Context, id int, currentPassword string, newPassword string) error {
+//				panic("mock out the ChangeOwnPassword method")
+//			},
+//			ChangePasswordFunc: func(ctx context.Context, id int, newPassword string) error {
+//				panic("mock out the ChangePassword method")
+//			},
+//			CheckPolicyAcceptedFunc: func(ctx context.Context, userId int) error {
+//				panic("mock out the CheckPolicyAccepted method")
+//			},
+//			ClearExpiredTempRolesFunc: func(ctx context.Context) (int, error) {
+//				panic("mock out the ClearExpiredTempRoles method")
+//			},
+//			ClockInFunc: func(ctx context.Context, userId int) (*user.TimeEntry, error) {
+//				panic("mock out the ClockIn method")
+//			},
+//			ClockOutFunc: func(ctx context.Context, userId int) (*user.TimeEntry, error) {
+//				panic("mock out the ClockOut method")
+//			},
+//			ClockStatusFunc: func(ctx context.Context, userId int) (*user.TimeEntry, error) {
+//				panic("mock out the ClockStatus method")
+//			},
+//			DeleteUserFunc: func(ctx context.Context, id int) error {
+//				panic("mock out the DeleteUser method")
+//			},
+//			DeviceLoginFunc: func(ctx context.Context, deviceToken string) (*user.TokenPair, *user.User, error) {
+//				panic("mock out the DeviceLogin method")
+//			},
+//			ExportCSVFunc: func(ctx context.Context, params user.UserServiceListParams, w io.Writer) error {
+//				panic("mock out the ExportCSV method")
+//			},
+//			ExternalLoginFunc: func(ctx context.Context, ident user.ExternalIdentity, provisionRole string) (*user.TokenPair, *user.User, error) {
+//				panic("mock out the ExternalLogin method")
+//			},
+//			GetHoursReportFunc: func(ctx context.Context, start time.Time, end time.Time, userId int) ([]user.HoursLine, error) {
+//				panic("mock out the GetHoursReport method")
+//			},
+//			GetLocationScopeFunc: func(ctx context.Context, id int) (utils.LocationScope, error) {
+//				panic("mock out the GetLocationScope method")
+//			},
+//			GetLocationsFunc: func(ctx context.Context, id int) ([]int, error) {
+//				panic("mock out the GetLocations method")
+//			},
+//			GetProfileFunc: func(ctx context.Context, id int) (*user.Profile, error) {
+//				panic("mock out the GetProfile method")
+//			},
+//			GetSubjectFunc: func(ctx context.Context, id int) (utils.Subject, error) {
+//				panic("mock out the GetSubject method")
+//			},
+//			GetUserFunc: func(ctx context.Context, idOrUsername any) (*user.User, error) {
+//				panic("mock out the GetUser method")
+//			},
+//			GetUsernameHistoryFunc: func(ctx context.Context, id int) ([]user.UsernameChange, error) {
+//				panic("mock out the GetUsernameHistory method")
+//			},
+//			GrantTempRoleFunc: func(ctx context.Context, id int, role string, expiresAt time.Time) error {
+//				panic("mock out the GrantTempRole method")
+//			},
+//			IssueAPITokenFunc: func(ctx context.Context, id int) (*user.TokenPair, error) {
+//				panic("mock out the IssueAPIToken method")
+//			},
+//			ListActivityFunc: func(ctx context.Context, id int, params user.ActivityListParams) ([]*user.Activity, error) {
+//				panic("mock out the ListActivity method")
+//			},
+//			ListDevicesFunc: func(ctx context.Context, userId int) ([]*user.Device, error) {
+//				panic("mock out the ListDevices method")
+//			},
+//			ListLoginAttemptsFunc: func(ctx context.Context, params user.LoginAttemptListParams) ([]*user.LoginAttempt, error) {
+//				panic("mock out the ListLoginAttempts method")
+//			},
+//			ListUsersFunc: func(ctx context.Context, params user.UserServiceListParams) (*user.UserPage, error) {
+//				panic("mock out the ListUsers method")
+//			},
+//			LoginFunc: func(ctx context.Context, username string, password string, captchaToken string) (*user.TokenPair, *user.User, error) {
+//				panic("mock out the Login method")
+//			},
+//			LogoutFunc: func(ctx context.Context, userId int) error {
+//				panic("mock out the Logout method")
+//			},
+//			MagicLinkLoginFunc: func(ctx context.Context, token string) (*user.TokenPair, *user.User, error) {
+//				panic("mock out the MagicLinkLogin method")
+//			},
+//			PINLoginFunc: func(ctx context.Context, username string, pin string) (*user.TokenPair, *user.User, error) {
+//				panic("mock out the PINLogin method")
+//			},
+//			PurgeDeletedFunc: func(ctx context.Context, before time.Time) (int, error) {
+//				panic("mock out the PurgeDeleted method")
+//			},
+//			RefreshFunc: func(ctx context.Context, refreshToken string) (*user.TokenPair, error) {
+//				panic("mock out the Refresh method")
+//			},
+//			RegisterDeviceFunc: func(ctx context.Context, userId int, name string) (*user.Device, string, error) {
+//				panic("mock out the RegisterDevice method")
+//			},
+//			RegisterUserFunc: func(ctx context.Context, input user.UserInput) (*user.User, error) {
+//				panic("mock out the RegisterUser method")
+//			},
+//			RemoveAvatarFunc: func(ctx context.Context, id int) error {
+//				panic("mock out the RemoveAvatar method")
+//			},
+//			RequestMagicLinkFunc: func(ctx context.Context, email string) error {
+//				panic("mock out the RequestMagicLink method")
+//			},
+//			RestoreUserFunc: func(ctx context.Context, id int) error {
+//				panic("mock out the RestoreUser method")
+//			},
+//			RevokeDeviceFunc: func(ctx context.Context, userId int, deviceId int) error {
+//				panic("mock out the RevokeDevice method")
+//			},
+//			RevokeTempRoleFunc: func(ctx context.Context, id int) error {
+//				panic("mock out the RevokeTempRole method")
+//			},
+//			SetAvatarFunc: func(ctx context.Context, id int, image io.Reader) (string, error) {
+//				panic("mock out the SetAvatar method")
+//			},
+//			SetLocationsFunc: func(ctx context.Context, id int, locationIds []int) error {
+//				panic("mock out the SetLocations method")
+//			},
+//			SetPINFunc: func(ctx context.Context, id int, pin string) error {
+//				panic("mock out the SetPIN method")
+//			},
+//			ToggleActiveFunc: func(ctx context.Context, id int, active bool) error {
+//				panic("mock out the ToggleActive method")
+//			},
+//			UnlockFunc: func(ctx context.Context, id int) error {
+//				panic("mock out the Unlock method")
+//			},
+//			UpdateSettingsFunc: func(ctx context.Context, id int, patch user.SettingsPatch) (user.Settings, error) {
+//				panic("mock out the UpdateSettings method")
+//			},
+//			UpdateUserFunc: func(ctx context.Context, id int, input user.UserInput) error {
+//				panic("mock out the UpdateUser method")
+//			},
+//			ValidateSessionFunc: func(ctx context.Context, claims *user.Claims) error {
+//				panic("mock out the ValidateSession method")
+//			},
+//		}
+//
+//		// use mockedUserService in code that requires user.UserService
+//		// and then make assertions.
+//
+//	}
+type UserService struct {
+	// AcceptPolicyFunc mocks the AcceptPolicy method.
+	AcceptPolicyFunc func(ctx context.Context, userId int, version string) (*user.PolicyStatus, error)
+
+	// AnonymizeUserFunc mocks the AnonymizeUser method.
+	AnonymizeUserFunc func(ctx context.Context, id int) error
+
+	// ChangeOwnPasswordFunc mocks the ChangeOwnPassword method.
+	ChangeOwnPasswordFunc func(ctx context.Context, id int, currentPassword string, newPassword string) error
+
+	// ChangePasswordFunc mocks the ChangePassword method.
+	ChangePasswordFunc func(ctx context.Context, id int, newPassword string) error
+
+	// CheckPolicyAcceptedFunc mocks the CheckPolicyAccepted method.
+	CheckPolicyAcceptedFunc func(ctx context.Context, userId int) error
+
+	// ClearExpiredTempRolesFunc mocks the ClearExpiredTempRoles method.
+	ClearExpiredTempRolesFunc func(ctx context.Context) (int, error)
+
+	// ClockInFunc mocks the ClockIn method.
+	ClockInFunc func(ctx context.Context, userId int) (*user.TimeEntry, error)
+
+	// ClockOutFunc mocks the ClockOut method.
+	ClockOutFunc func(ctx context.Context, userId int) (*user.TimeEntry, error)
+
+	// ClockStatusFunc mocks the ClockStatus method.
+	ClockStatusFunc func(ctx context.Context, userId int) (*user.TimeEntry, error)
+
+	// DeleteUserFunc mocks the DeleteUser method.
+	DeleteUserFunc func(ctx context.Context, id int) error
+
+	// DeviceLoginFunc mocks the DeviceLogin method.
+	DeviceLoginFunc func(ctx context.Context, deviceToken string) (*user.TokenPair, *user.User, error)
+
+	// ExportCSVFunc mocks the ExportCSV method.
+	ExportCSVFunc func(ctx context.Context, params user.UserServiceListParams, w io.Writer) error
+
+	// ExternalLoginFunc mocks the ExternalLogin method.
+	ExternalLoginFunc func(ctx context.Context, ident user.ExternalIdentity, provisionRole string) (*user.TokenPair, *user.User, error)
+
+	// GetHoursReportFunc mocks the GetHoursReport method.
+	GetHoursReportFunc func(ctx context.Context, start time.Time, end time.Time, userId int) ([]user.HoursLine, error)
+
+	// GetLocationScopeFunc mocks the GetLocationScope method.
+	GetLocationScopeFunc func(ctx context.Context, id int) (utils.LocationScope, error)
+
+	// GetLocationsFunc mocks the GetLocations method.
+	GetLocationsFunc func(ctx context.Context, id int) ([]int, error)
+
+	// GetProfileFunc mocks the GetProfile method.
+	GetProfileFunc func(ctx context.Context, id int) (*user.Profile, error)
+
+	// GetSubjectFunc mocks the GetSubject method.
+	GetSubjectFunc func(ctx context.Context, id int) (utils.Subject, error)
+
+	// GetUserFunc mocks the GetUser method.
+	GetUserFunc func(ctx context.Context, idOrUsername any) (*user.User, error)
+
+	// GetUsernameHistoryFunc mocks the GetUsernameHistory method.
+	GetUsernameHistoryFunc func(ctx context.Context, id int) ([]user.UsernameChange, error)
+
+	// GrantTempRoleFunc mocks the GrantTempRole method.
+	GrantTempRoleFunc func(ctx context.Context, id int, role string, expiresAt time.Time) error
+
+	// IssueAPITokenFunc mocks the IssueAPIToken method.
+	IssueAPITokenFunc func(ctx context.Context, id int) (*user.TokenPair, error)
+
+	// ListActivityFunc mocks the ListActivity method.
+	ListActivityFunc func(ctx context.Context, id int, params user.ActivityListParams) ([]*user.Activity, error)
+
+	// ListDevicesFunc mocks the ListDevices method.
+	ListDevicesFunc func(ctx context.Context, userId int) ([]*user.Device, error)
+
+	// ListLoginAttemptsFunc mocks the ListLoginAttempts method.
+	ListLoginAttemptsFunc func(ctx context.Context, params user.LoginAttemptListParams) ([]*user.LoginAttempt, error)
+
+	// ListUsersFunc mocks the ListUsers method.
+	ListUsersFunc func(ctx context.Context, params user.UserServiceListParams) (*user.UserPage, error)
+
+	// LoginFunc mocks the Login method.
+	LoginFunc func(ctx context.Context, username string, password string, captchaToken string) (*user.TokenPair, *user.User, error)
+
+	// LogoutFunc mocks the Logout method.
+	LogoutFunc func(ctx context.Context, userId int) error
+
+	// MagicLinkLoginFunc mocks the MagicLinkLogin method.
+	MagicLinkLoginFunc func(ctx context.Context, token string) (*user.TokenPair, *user.User, error)
+
+	// PINLoginFunc mocks the PINLogin method.
+	PINLoginFunc func(ctx context.Context, username string, pin string) (*user.TokenPair, *user.User, error)
+
+	// PurgeDeletedFunc mocks the PurgeDeleted method.
+	PurgeDeletedFunc func(ctx context.Context, before time.Time) (int, error)
+
+	// RefreshFunc mocks the Refresh method.
+	RefreshFunc func(ctx context.Context, refreshToken string) (*user.TokenPair, error)
+
+	// RegisterDeviceFunc mocks the RegisterDevice method.
+	RegisterDeviceFunc func(ctx context.Context, userId int, name string) (*user.Device, string, error)
+
+	// RegisterUserFunc mocks the RegisterUser method.
+	RegisterUserFunc func(ctx context.Context, input user.UserInput) (*user.User, error)
+
+	// RemoveAvatarFunc mocks the RemoveAvatar method.
+	RemoveAvatarFunc func(ctx context.Context, id int) error
+
+	// RequestMagicLinkFunc mocks the RequestMagicLink method.
+	RequestMagicLinkFunc func(ctx context.Context, email string) error
+
+	// RestoreUserFunc mocks the RestoreUser method.
+	RestoreUserFunc func(ctx context.Context, id int) error
+
+	// RevokeDeviceFunc mocks the RevokeDevice method.
+	RevokeDeviceFunc func(ctx context.Context, userId int, deviceId int) error
+
+	// RevokeTempRoleFunc mocks the RevokeTempRole method.
+	RevokeTempRoleFunc func(ctx context.Context, id int) error
+
+	// SetAvatarFunc mocks the SetAvatar method.
+	SetAvatarFunc func(ctx context.Context, id int, image io.Reader) (string, error)
+
+	// SetLocationsFunc mocks the SetLocations method.
+	SetLocationsFunc func(ctx context.Context, id int, locationIds []int) error
+
+	// SetPINFunc mocks the SetPIN method.
+	SetPINFunc func(ctx context.Context, id int, pin string) error
+
+	// ToggleActiveFunc mocks the ToggleActive method.
+	ToggleActiveFunc func(ctx context.Context, id int, active bool) error
+
+	// UnlockFunc mocks the Unlock method.
+	UnlockFunc func(ctx context.Context, id int) error
+
+	// UpdateSettingsFunc mocks the UpdateSettings method.
+	UpdateSettingsFunc func(ctx context.Context, id int, patch user.SettingsPatch) (user.Settings, error)
+
+	// UpdateUserFunc mocks the UpdateUser method.
+	UpdateUserFunc func(ctx context.Context, id int, input user.UserInput) error
+
+	// ValidateSessionFunc mocks the ValidateSession method.
+	ValidateSessionFunc func(ctx context.Context, claims *user.Claims) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// AcceptPolicy holds details about calls to the AcceptPolicy method.
+		AcceptPolicy []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserId is the userId argument value.
+			UserId int
+			// Version is the version argument value.
+			Version string
+		}
+		// AnonymizeUser holds details about calls to the AnonymizeUser method.
+		AnonymizeUser []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// ChangeOwnPassword holds details about calls to the ChangeOwnPassword method.
+		ChangeOwnPassword []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// CurrentPassword is the currentPassword argument value.
+			CurrentPassword string
+			// NewPassword is the newPassword argument value.
+			NewPassword string
+		}
+		// ChangePassword holds details about calls to the ChangePassword method.
+		ChangePassword []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// NewPassword is the newPassword argument value.
+			NewPassword string
+		}
+		// CheckPolicyAccepted holds details about calls to the CheckPolicyAccepted method.
+		CheckPolicyAccepted []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserId is the userId argument value.
+			UserId int
+		}
+		// ClearExpiredTempRoles holds details about calls to the ClearExpiredTempRoles method.
+		ClearExpiredTempRoles []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// ClockIn holds details about calls to the ClockIn method.
+		ClockIn []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserId is the userId argument value.
+			UserId int
+		}
+		// ClockOut holds details about calls to the ClockOut method.
+		ClockOut []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserId is the userId argument value.
+			UserId int
+		}
+		// ClockStatus holds details about calls to the ClockStatus method.
+		ClockStatus []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserId is the userId argument value.
+			UserId int
+		}
+		// DeleteUser holds details about calls to the DeleteUser method.
+		DeleteUser []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// DeviceLogin holds details about calls to the DeviceLogin method.
+		DeviceLogin []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// DeviceToken is the deviceToken argument value.
+			DeviceToken string
+		}
+		// ExportCSV holds details about calls to the ExportCSV method.
+		ExportCSV []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Params is the params argument value.
+			Params user.UserServiceListParams
+			// W is the w argument value.
+			W io.Writer
+		}
+		// ExternalLogin holds details about calls to the ExternalLogin method.
+		ExternalLogin []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ident is the ident argument value.
+			Ident user.ExternalIdentity
+			// ProvisionRole is the provisionRole argument value.
+			ProvisionRole string
+		}
+		// GetHoursReport holds details about calls to the GetHoursReport method.
+		GetHoursReport []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Start is the start argument value.
+			Start time.Time
+			// End is the end argument value.
+			End time.Time
+			// UserId is the userId argument value.
+			UserId int
+		}
+		// GetLocationScope holds details about calls to the GetLocationScope method.
+		GetLocationScope []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// GetLocations holds details about calls to the GetLocations method.
+		GetLocations []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// GetProfile holds details about calls to the GetProfile method.
+		GetProfile []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// GetSubject holds details about calls to the GetSubject method.
+		GetSubject []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// GetUser holds details about calls to the GetUser method.
+		GetUser []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// IdOrUsername is the idOrUsername argument value.
+			IdOrUsername any
+		}
+		// GetUsernameHistory holds details about calls to the GetUsernameHistory method.
+		GetUsernameHistory []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// GrantTempRole holds details about calls to the GrantTempRole method.
+		GrantTempRole []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Role is the role argument value.
+			Role string
+			// ExpiresAt is the expiresAt argument value.
+			ExpiresAt time.Time
+		}
+		// IssueAPIToken holds details about calls to the IssueAPIToken method.
+		IssueAPIToken []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// ListActivity holds details about calls to the ListActivity method.
+		ListActivity []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Params is the params argument value.
+			Params user.ActivityListParams
+		}
+		// ListDevices holds details about calls to the ListDevices method.
+		ListDevices []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserId is the userId argument value.
+			UserId int
+		}
+		// ListLoginAttempts holds details about calls to the ListLoginAttempts method.
+		ListLoginAttempts []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Params is the params argument value.
+			Params user.LoginAttemptListParams
+		}
+		// ListUsers holds details about calls to the ListUsers method.
+		ListUsers []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Params is the params argument value.
+			Params user.UserServiceListParams
+		}
+		// Login holds details about calls to the Login method.
+		Login []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Username is the username argument value.
+			Username string
+			// Password is the password argument value.
+			Password string
+			// CaptchaToken is the captchaToken argument value.
+			CaptchaToken string
+		}
+		// Logout holds details about calls to the Logout method.
+		Logout []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserId is the userId argument value.
+			UserId int
+		}
+		// MagicLinkLogin holds details about calls to the MagicLinkLogin method.
+		MagicLinkLogin []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Token is the token argument value.
+			Token string
+		}
+		// PINLogin holds details about calls to the PINLogin method.
+		PINLogin []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Username is the username argument value.
+			Username string
+			// Pin is the pin argument value.
+			Pin string
+		}
+		// PurgeDeleted holds details about calls to the PurgeDeleted method.
+		PurgeDeleted []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Before is the before argument value.
+			Before time.Time
+		}
+		// Refresh holds details about calls to the Refresh method.
+		Refresh []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// RefreshToken is the refreshToken argument value.
+			RefreshToken string
+		}
+		// RegisterDevice holds details about calls to the RegisterDevice method.
+		RegisterDevice []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserId is the userId argument value.
+			UserId int
+			// Name is the name argument value.
+			Name string
+		}
+		// RegisterUser holds details about calls to the RegisterUser method.
+		RegisterUser []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Input is the input argument value.
+			Input user.UserInput
+		}
+		// RemoveAvatar holds details about calls to the RemoveAvatar method.
+		RemoveAvatar []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// RequestMagicLink holds details about calls to the RequestMagicLink method.
+		RequestMagicLink []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Email is the email argument value.
+			Email string
+		}
+		// RestoreUser holds details about calls to the RestoreUser method.
+		RestoreUser []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// RevokeDevice holds details about calls to the RevokeDevice method.
+		RevokeDevice []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserId is the userId argument value.
+			UserId int
+			// DeviceId is the deviceId argument value.
+			DeviceId int
+		}
+		// RevokeTempRole holds details about calls to the RevokeTempRole method.
+		RevokeTempRole []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// SetAvatar holds details about calls to the SetAvatar method.
+		SetAvatar []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Image is the image argument value.
+			Image io.Reader
+		}
+		// SetLocations holds details about calls to the SetLocations method.
+		SetLocations []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// LocationIds is the locationIds argument value.
+			LocationIds []int
+		}
+		// SetPIN holds details about calls to the SetPIN method.
+		SetPIN []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Pin is the pin argument value.
+			Pin string
+		}
+		// ToggleActive holds details about calls to the ToggleActive method.
+		ToggleActive []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Active is the active argument value.
+			Active bool
+		}
+		// Unlock holds details about calls to the Unlock method.
+		Unlock []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+		}
+		// UpdateSettings holds details about calls to the UpdateSettings method.
+		UpdateSettings []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Patch is the patch argument value.
+			Patch user.SettingsPatch
+		}
+		// UpdateUser holds details about calls to the UpdateUser method.
+		UpdateUser []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int
+			// Input is the input argument value.
+			Input user.UserInput
+		}
+		// ValidateSession holds details about calls to the ValidateSession method.
+		ValidateSession []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Claims is the claims argument value.
+			Claims *user.Claims
+		}
+	}
+	lockAcceptPolicy          sync.RWMutex
+	lockAnonymizeUser         sync.RWMutex
+	lockChangeOwnPassword     sync.RWMutex
+	lockChangePassword        sync.RWMutex
+	lockCheckPolicyAccepted   sync.RWMutex
+	lockClearExpiredTempRoles sync.RWMutex
+	lockClockIn               sync.RWMutex
+	lockClockOut              sync.RWMutex
+	lockClockStatus           sync.RWMutex
+	lockDeleteUser            sync.RWMutex
+	lockDeviceLogin           sync.RWMutex
+	lockExportCSV             sync.RWMutex
+	lockExternalLogin         sync.RWMutex
+	lockGetHoursReport        sync.RWMutex
+	lockGetLocationScope      sync.RWMutex
+	lockGetLocations          sync.RWMutex
+	lockGetProfile            sync.RWMutex
+	lockGetSubject            sync.RWMutex
+	lockGetUser               sync.RWMutex
+	lockGetUsernameHistory    sync.RWMutex
+	lockGrantTempRole         sync.RWMutex
+	lockIssueAPIToken         sync.RWMutex
+	lockListActivity          sync.RWMutex
+	lockListDevices           sync.RWMutex
+	lockListLoginAttempts     sync.RWMutex
+	lockListUsers             sync.RWMutex
+	lockLogin                 sync.RWMutex
+	lockLogout                sync.RWMutex
+	lockMagicLinkLogin        sync.RWMutex
+	lockPINLogin              sync.RWMutex
+	lockPurgeDeleted          sync.RWMutex
+	lockRefresh               sync.RWMutex
+	lockRegisterDevice        sync.RWMutex
+	lockRegisterUser          sync.RWMutex
+	lockRemoveAvatar          sync.RWMutex
+	lockRequestMagicLink      sync.RWMutex
+	lockRestoreUser           sync.RWMutex
+	lockRevokeDevice          sync.RWMutex
+	lockRevokeTempRole        sync.RWMutex
+	lockSetAvatar             sync.RWMutex
+	lockSetLocations          sync.RWMutex
+	lockSetPIN                sync.RWMutex
+	lockToggleActive          sync.RWMutex
+	lockUnlock                sync.RWMutex
+	lockUpdateSettings        sync.RWMutex
+	lockUpdateUser            sync.RWMutex
+	lockValidateSession       sync.RWMutex
+}
+
+// AcceptPolicy calls AcceptPolicyFunc.
+func (mock *UserService) AcceptPolicy(ctx context.Context, userId int, version string) (*user.PolicyStatus, error) {
+	if mock.AcceptPolicyFunc == nil {
+		panic("UserService.AcceptPolicyFunc: method is nil but UserService.AcceptPolicy was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		UserId  int
+		Version string
+	}{
+		Ctx:     ctx,
+		UserId:  userId,
+		Version: version,
+	}
+	mock.lockAcceptPolicy.Lock()
+	mock.calls.AcceptPolicy = append(mock.calls.AcceptPolicy, callInfo)
+	mock.lockAcceptPolicy.Unlock()
+	return mock.AcceptPolicyFunc(ctx, userId, version)
+}
+
+// AcceptPolicyCalls gets all the calls that were made to AcceptPolicy.
+// Check the length with:
+//
+//	len(mockedUserService.AcceptPolicyCalls())
+func (mock *UserService) AcceptPolicyCalls() []struct {
+	Ctx     context.Context
+	UserId  int
+	Version string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		UserId  int
+		Version string
+	}
+	mock.lockAcceptPolicy.RLock()
+	calls = mock.calls.AcceptPolicy
+	mock.lockAcceptPolicy.RUnlock()
+	return calls
+}
+
+// AnonymizeUser calls AnonymizeUserFunc.
+func (mock *UserService) AnonymizeUser(ctx context.Context, id int) error {
+	if mock.AnonymizeUserFunc == nil {
+		panic("UserService.AnonymizeUserFunc: method is nil but UserService.AnonymizeUser was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockAnonymizeUser.Lock()
+	mock.calls.AnonymizeUser = append(mock.calls.AnonymizeUser, callInfo)
+	mock.lockAnonymizeUser.Unlock()
+	return mock.AnonymizeUserFunc(ctx, id)
+}
+
+// AnonymizeUserCalls gets all the calls that were made to AnonymizeUser.
+// Check the length with:
+//
+//	len(mockedUserService.AnonymizeUserCalls())
+func (mock *UserService) AnonymizeUserCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockAnonymizeUser.RLock()
+	calls = mock.calls.AnonymizeUser
+	mock.lockAnonymizeUser.RUnlock()
+	return calls
+}
+
+// ChangeOwnPassword calls ChangeOwnPasswordFunc.
+func (mock *UserService) ChangeOwnPassword(ctx context.Context, id int, currentPassword string, newPassword string) error {
+	if mock.ChangeOwnPasswordFunc == nil {
+		panic("UserService.ChangeOwnPasswordFunc: method is nil but UserService.ChangeOwnPassword was just called")
+	}
+	callInfo := struct {
+		Ctx             context.Context
+		ID              int
+		CurrentPassword string
+		NewPassword     string
+	}{
+		Ctx:             ctx,
+		ID:              id,
+		CurrentPassword: currentPassword,
+		NewPassword:     newPassword,
+	}
+	mock.lockChangeOwnPassword.Lock()
+	mock.calls.ChangeOwnPassword = append(mock.calls.ChangeOwnPassword, callInfo)
+	mock.lockChangeOwnPassword.Unlock()
+	return mock.ChangeOwnPasswordFunc(ctx, id, currentPassword, newPassword)
+}
+
+// ChangeOwnPasswordCalls gets all the calls that were made to ChangeOwnPassword.
+// Check the length with:
+//
+//	len(mockedUserService.ChangeOwnPasswordCalls())
+func (mock *UserService) ChangeOwnPasswordCalls() []struct {
+	Ctx             context.Context
+	ID              int
+	CurrentPassword string
+	NewPassword     string
+} {
+	var calls []struct {
+		Ctx             context.Context
+		ID              int
+		CurrentPassword string
+		NewPassword     string
+	}
+	mock.lockChangeOwnPassword.RLock()
+	calls = mock.calls.ChangeOwnPassword
+	mock.lockChangeOwnPassword.RUnlock()
+	return calls
+}
+
+// ChangePassword calls ChangePasswordFunc.
+func (mock *UserService) ChangePassword(ctx context.Context, id int, newPassword string) error {
+	if mock.ChangePasswordFunc == nil {
+		panic("UserService.ChangePasswordFunc: method is nil but UserService.ChangePassword was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		ID          int
+		NewPassword string
+	}{
+		Ctx:         ctx,
+		ID:          id,
+		NewPassword: newPassword,
+	}
+	mock.lockChangePassword.Lock()
+	mock.calls.ChangePassword = append(mock.calls.ChangePassword, callInfo)
+	mock.lockChangePassword.Unlock()
+	return mock.ChangePasswordFunc(ctx, id, newPassword)
+}
+
+// ChangePasswordCalls gets all the calls that were made to ChangePassword.
+// Check the length with:
+//
+//	len(mockedUserService.ChangePasswordCalls())
+func (mock *UserService) ChangePasswordCalls() []struct {
+	Ctx         context.Context
+	ID          int
+	NewPassword string
+} {
+	var calls []struct {
+		Ctx         context.Context
+		ID          int
+		NewPassword string
+	}
+	mock.lockChangePassword.RLock()
+	calls = mock.calls.ChangePassword
+	mock.lockChangePassword.RUnlock()
+	return calls
+}
+
+// CheckPolicyAccepted calls CheckPolicyAcceptedFunc.
+func (mock *UserService) CheckPolicyAccepted(ctx context.Context, userId int) error {
+	if mock.CheckPolicyAcceptedFunc == nil {
+		panic("UserService.CheckPolicyAcceptedFunc: method is nil but UserService.CheckPolicyAccepted was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		UserId int
+	}{
+		Ctx:    ctx,
+		UserId: userId,
+	}
+	mock.lockCheckPolicyAccepted.Lock()
+	mock.calls.CheckPolicyAccepted = append(mock.calls.CheckPolicyAccepted, callInfo)
+	mock.lockCheckPolicyAccepted.Unlock()
+	return mock.CheckPolicyAcceptedFunc(ctx, userId)
+}
+
+// CheckPolicyAcceptedCalls gets all the calls that were made to CheckPolicyAccepted.
+// Check the length with:
+//
+//	len(mockedUserService.CheckPolicyAcceptedCalls())
+func (mock *UserService) CheckPolicyAcceptedCalls() []struct {
+	Ctx    context.Context
+	UserId int
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserId int
+	}
+	mock.lockCheckPolicyAccepted.RLock()
+	calls = mock.calls.CheckPolicyAccepted
+	mock.lockCheckPolicyAccepted.RUnlock()
+	return calls
+}
+
+// ClearExpiredTempRoles calls ClearExpiredTempRolesFunc.
+func (mock *UserService) ClearExpiredTempRoles(ctx context.Context) (int, error) {
+	if mock.ClearExpiredTempRolesFunc == nil {
+		panic("UserService.ClearExpiredTempRolesFunc: method is nil but UserService.ClearExpiredTempRoles was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockClearExpiredTempRoles.Lock()
+	mock.calls.ClearExpiredTempRoles = append(mock.calls.ClearExpiredTempRoles, callInfo)
+	mock.lockClearExpiredTempRoles.Unlock()
+	return mock.ClearExpiredTempRolesFunc(ctx)
+}
+
+// ClearExpiredTempRolesCalls gets all the calls that were made to ClearExpiredTempRoles.
+// Check the length with:
+//
+//	len(mockedUserService.ClearExpiredTempRolesCalls())
+func (mock *UserService) ClearExpiredTempRolesCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockClearExpiredTempRoles.RLock()
+	calls = mock.calls.ClearExpiredTempRoles
+	mock.lockClearExpiredTempRoles.RUnlock()
+	return calls
+}
+
+// ClockIn calls ClockInFunc.
+func (mock *UserService) ClockIn(ctx context.Context, userId int) (*user.TimeEntry, error) {
+	if mock.ClockInFunc == nil {
+		panic("UserService.ClockInFunc: method is nil but UserService.ClockIn was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		UserId int
+	}{
+		Ctx:    ctx,
+		UserId: userId,
+	}
+	mock.lockClockIn.Lock()
+	mock.calls.ClockIn = append(mock.calls.ClockIn, callInfo)
+	mock.lockClockIn.Unlock()
+	return mock.ClockInFunc(ctx, userId)
+}
+
+// ClockInCalls gets all the calls that were made to ClockIn.
+// Check the length with:
+//
+//	len(mockedUserService.ClockInCalls())
+func (mock *UserService) ClockInCalls() []struct {
+	Ctx    context.Context
+	UserId int
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserId int
+	}
+	mock.lockClockIn.RLock()
+	calls = mock.calls.ClockIn
+	mock.lockClockIn.RUnlock()
+	return calls
+}
+
+// ClockOut calls ClockOutFunc.
+func (mock *UserService) ClockOut(ctx context.Context, userId int) (*user.TimeEntry, error) {
+	if mock.ClockOutFunc == nil {
+		panic("UserService.ClockOutFunc: method is nil but UserService.ClockOut was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		UserId int
+	}{
+		Ctx:    ctx,
+		UserId: userId,
+	}
+	mock.lockClockOut.Lock()
+	mock.calls.ClockOut = append(mock.calls.ClockOut, callInfo)
+	mock.lockClockOut.Unlock()
+	return mock.ClockOutFunc(ctx, userId)
+}
+
+// ClockOutCalls gets all the calls that were made to ClockOut.
+// Check the length with:
+//
+//	len(mockedUserService.ClockOutCalls())
+func (mock *UserService) ClockOutCalls() []struct {
+	Ctx    context.Context
+	UserId int
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserId int
+	}
+	mock.lockClockOut.RLock()
+	calls = mock.calls.ClockOut
+	mock.lockClockOut.RUnlock()
+	return calls
+}
+
+// ClockStatus calls ClockStatusFunc.
+func (mock *UserService) ClockStatus(ctx context.Context, userId int) (*user.TimeEntry, error) {
+	if mock.ClockStatusFunc == nil {
+		panic("UserService.ClockStatusFunc: method is nil but UserService.ClockStatus was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		UserId int
+	}{
+		Ctx:    ctx,
+		UserId: userId,
+	}
+	mock.lockClockStatus.Lock()
+	mock.calls.ClockStatus = append(mock.calls.ClockStatus, callInfo)
+	mock.lockClockStatus.Unlock()
+	return mock.ClockStatusFunc(ctx, userId)
+}
+
+// ClockStatusCalls gets all the calls that were made to ClockStatus.
+// Check the length with:
+//
+//	len(mockedUserService.ClockStatusCalls())
+func (mock *UserService) ClockStatusCalls() []struct {
+	Ctx    context.Context
+	UserId int
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserId int
+	}
+	mock.lockClockStatus.RLock()
+	calls = mock.calls.ClockStatus
+	mock.lockClockStatus.RUnlock()
+	return calls
+}
+
+// DeleteUser calls DeleteUserFunc.
+func (mock *UserService) DeleteUser(ctx context.Context, id int) error {
+	if mock.DeleteUserFunc == nil {
+		panic("UserService.DeleteUserFunc: method is nil but UserService.DeleteUser was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockDeleteUser.Lock()
+	mock.calls.DeleteUser = append(mock.calls.DeleteUser, callInfo)
+	mock.lockDeleteUser.Unlock()
+	return mock.DeleteUserFunc(ctx, id)
+}
+
+// DeleteUserCalls gets all the calls that were made to DeleteUser.
+// Check the length with:
+//
+//	len(mockedUserService.DeleteUserCalls())
+func (mock *UserService) DeleteUserCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockDeleteUser.RLock()
+	calls = mock.calls.DeleteUser
+	mock.lockDeleteUser.RUnlock()
+	return calls
+}
+
+// DeviceLogin calls DeviceLoginFunc.
+func (mock *UserService) DeviceLogin(ctx context.Context, deviceToken string) (*user.TokenPair, *user.User, error) {
+	if mock.DeviceLoginFunc == nil {
+		panic("UserService.DeviceLoginFunc: method is nil but UserService.DeviceLogin was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		DeviceToken string
+	}{
+		Ctx:         ctx,
+		DeviceToken: deviceToken,
+	}
+	mock.lockDeviceLogin.Lock()
+	mock.calls.DeviceLogin = append(mock.calls.DeviceLogin, callInfo)
+	mock.lockDeviceLogin.Unlock()
+	return mock.DeviceLoginFunc(ctx, deviceToken)
+}
+
+// DeviceLoginCalls gets all the calls that were made to DeviceLogin.
+// Check the length with:
+//
+//	len(mockedUserService.DeviceLoginCalls())
+func (mock *UserService) DeviceLoginCalls() []struct {
+	Ctx         context.Context
+	DeviceToken string
+} {
+	var calls []struct {
+		Ctx         context.Context
+		DeviceToken string
+	}
+	mock.lockDeviceLogin.RLock()
+	calls = mock.calls.DeviceLogin
+	mock.lockDeviceLogin.RUnlock()
+	return calls
+}
+
+// ExportCSV calls ExportCSVFunc.
+func (mock *UserService) ExportCSV(ctx context.Context, params user.UserServiceListParams, w io.Writer) error {
+	if mock.ExportCSVFunc == nil {
+		panic("UserService.ExportCSVFunc: method is nil but UserService.ExportCSV was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Params user.UserServiceListParams
+		W      io.Writer
+	}{
+		Ctx:    ctx,
+		Params: params,
+		W:      w,
+	}
+	mock.lockExportCSV.Lock()
+	mock.calls.ExportCSV = append(mock.calls.ExportCSV, callInfo)
+	mock.lockExportCSV.Unlock()
+	return mock.ExportCSVFunc(ctx, params, w)
+}
+
+// ExportCSVCalls gets all the calls that were made to ExportCSV.
+// Check the length with:
+//
+//	len(mockedUserService.ExportCSVCalls())
+func (mock *UserService) ExportCSVCalls() []struct {
+	Ctx    context.Context
+	Params user.UserServiceListParams
+	W      io.Writer
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Params user.UserServiceListParams
+		W      io.Writer
+	}
+	mock.lockExportCSV.RLock()
+	calls = mock.calls.ExportCSV
+	mock.lockExportCSV.RUnlock()
+	return calls
+}
+
+// ExternalLogin calls ExternalLoginFunc.
+func (mock *UserService) ExternalLogin(ctx context.Context, ident user.ExternalIdentity, provisionRole string) (*user.TokenPair, *user.User, error) {
+	if mock.ExternalLoginFunc == nil {
+		panic("UserService.ExternalLoginFunc: method is nil but UserService.ExternalLogin was just called")
+	}
+	callInfo := struct {
+		Ctx           context.Context
+		Ident         user.ExternalIdentity
+		ProvisionRole string
+	}{
+		Ctx:           ctx,
+		Ident:         ident,
+		ProvisionRole: provisionRole,
+	}
+	mock.lockExternalLogin.Lock()
+	mock.calls.ExternalLogin = append(mock.calls.ExternalLogin, callInfo)
+	mock.lockExternalLogin.Unlock()
+	return mock.ExternalLoginFunc(ctx, ident, provisionRole)
+}
+
+// ExternalLoginCalls gets all the calls that were made to ExternalLogin.
+// Check the length with:
+//
+//	len(mockedUserService.ExternalLoginCalls())
+func (mock *UserService) ExternalLoginCalls() []struct {
+	Ctx           context.Context
+	Ident         user.ExternalIdentity
+	ProvisionRole string
+} {
+	var calls []struct {
+		Ctx           context.Context
+		Ident         user.ExternalIdentity
+		ProvisionRole string
+	}
+	mock.lockExternalLogin.RLock()
+	calls = mock.calls.ExternalLogin
+	mock.lockExternalLogin.RUnlock()
+	return calls
+}
+
+// GetHoursReport calls GetHoursReportFunc.
+func (mock *UserService) GetHoursReport(ctx context.Context, start time.Time, end time.Time, userId int) ([]user.HoursLine, error) {
+	if mock.GetHoursReportFunc == nil {
+		panic("UserService.GetHoursReportFunc: method is nil but UserService.GetHoursReport was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Start  time.Time
+		End    time.Time
+		UserId int
+	}{
+		Ctx:    ctx,
+		Start:  start,
+		End:    end,
+		UserId: userId,
+	}
+	mock.lockGetHoursReport.Lock()
+	mock.calls.GetHoursReport = append(mock.calls.GetHoursReport, callInfo)
+	mock.lockGetHoursReport.Unlock()
+	return mock.GetHoursReportFunc(ctx, start, end, userId)
+}
+
+// GetHoursReportCalls gets all the calls that were made to GetHoursReport.
+// Check the length with:
+//
+//	len(mockedUserService.GetHoursReportCalls())
+func (mock *UserService) GetHoursReportCalls() []struct {
+	Ctx    context.Context
+	Start  time.Time
+	End    time.Time
+	UserId int
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Start  time.Time
+		End    time.Time
+		UserId int
+	}
+	mock.lockGetHoursReport.RLock()
+	calls = mock.calls.GetHoursReport
+	mock.lockGetHoursReport.RUnlock()
+	return calls
+}
+
+// GetLocationScope calls GetLocationScopeFunc.
+func (mock *UserService) GetLocationScope(ctx context.Context, id int) (utils.LocationScope, error) {
+	if mock.GetLocationScopeFunc == nil {
+		panic("UserService.GetLocationScopeFunc: method is nil but UserService.GetLocationScope was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetLocationScope.Lock()
+	mock.calls.GetLocationScope = append(mock.calls.GetLocationScope, callInfo)
+	mock.lockGetLocationScope.Unlock()
+	return mock.GetLocationScopeFunc(ctx, id)
+}
+
+// GetLocationScopeCalls gets all the calls that were made to GetLocationScope.
+// Check the length with:
+//
+//	len(mockedUserService.GetLocationScopeCalls())
+func (mock *UserService) GetLocationScopeCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockGetLocationScope.RLock()
+	calls = mock.calls.GetLocationScope
+	mock.lockGetLocationScope.RUnlock()
+	return calls
+}
+
+// GetLocations calls GetLocationsFunc.
+func (mock *UserService) GetLocations(ctx context.Context, id int) ([]int, error) {
+	if mock.GetLocationsFunc == nil {
+		panic("UserService.GetLocationsFunc: method is nil but UserService.GetLocations was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetLocations.Lock()
+	mock.calls.GetLocations = append(mock.calls.GetLocations, callInfo)
+	mock.lockGetLocations.Unlock()
+	return mock.GetLocationsFunc(ctx, id)
+}
+
+// GetLocationsCalls gets all the calls that were made to GetLocations.
+// Check the length with:
+//
+//	len(mockedUserService.GetLocationsCalls())
+func (mock *UserService) GetLocationsCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockGetLocations.RLock()
+	calls = mock.calls.GetLocations
+	mock.lockGetLocations.RUnlock()
+	return calls
+}
+
+// GetProfile calls GetProfileFunc.
+func (mock *UserService) GetProfile(ctx context.Context, id int) (*user.Profile, error) {
+	if mock.GetProfileFunc == nil {
+		panic("UserService.GetProfileFunc: method is nil but UserService.GetProfile was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetProfile.Lock()
+	mock.calls.GetProfile = append(mock.calls.GetProfile, callInfo)
+	mock.lockGetProfile.Unlock()
+	return mock.GetProfileFunc(ctx, id)
+}
+
+// GetProfileCalls gets all the calls that were made to GetProfile.
+// Check the length with:
+//
+//	len(mockedUserService.GetProfileCalls())
+func (mock *UserService) GetProfileCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockGetProfile.RLock()
+	calls = mock.calls.GetProfile
+	mock.lockGetProfile.RUnlock()
+	return calls
+}
+
+// GetSubject calls GetSubjectFunc.
+func (mock *UserService) GetSubject(ctx context.Context, id int) (utils.Subject, error) {
+	if mock.GetSubjectFunc == nil {
+		panic("UserService.GetSubjectFunc: method is nil but UserService.GetSubject was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetSubject.Lock()
+	mock.calls.GetSubject = append(mock.calls.GetSubject, callInfo)
+	mock.lockGetSubject.Unlock()
+	return mock.GetSubjectFunc(ctx, id)
+}
+
+// GetSubjectCalls gets all the calls that were made to GetSubject.
+// Check the length with:
+//
+//	len(mockedUserService.GetSubjectCalls())
+func (mock *UserService) GetSubjectCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockGetSubject.RLock()
+	calls = mock.calls.GetSubject
+	mock.lockGetSubject.RUnlock()
+	return calls
+}
+
+// GetUser calls GetUserFunc.
+func (mock *UserService) GetUser(ctx context.Context, idOrUsername any) (*user.User, error) {
+	if mock.GetUserFunc == nil {
+		panic("UserService.GetUserFunc: method is nil but UserService.GetUser was just called")
+	}
+	callInfo := struct {
+		Ctx          context.Context
+		IdOrUsername any
+	}{
+		Ctx:          ctx,
+		IdOrUsername: idOrUsername,
+	}
+	mock.lockGetUser.Lock()
+	mock.calls.GetUser = append(mock.calls.GetUser, callInfo)
+	mock.lockGetUser.Unlock()
+	return mock.GetUserFunc(ctx, idOrUsername)
+}
+
+// GetUserCalls gets all the calls that were made to GetUser.
+// Check the length with:
+//
+//	len(mockedUserService.GetUserCalls())
+func (mock *UserService) GetUserCalls() []struct {
+	Ctx          context.Context
+	IdOrUsername any
+} {
+	var calls []struct {
+		Ctx          context.Context
+		IdOrUsername any
+	}
+	mock.lockGetUser.RLock()
+	calls = mock.calls.GetUser
+	mock.lockGetUser.RUnlock()
+	return calls
+}
+
+// GetUsernameHistory calls GetUsernameHistoryFunc.
+func (mock *UserService) GetUsernameHistory(ctx context.Context, id int) ([]user.UsernameChange, error) {
+	if mock.GetUsernameHistoryFunc == nil {
+		panic("UserService.GetUsernameHistoryFunc: method is nil but UserService.GetUsernameHistory was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetUsernameHistory.Lock()
+	mock.calls.GetUsernameHistory = append(mock.calls.GetUsernameHistory, callInfo)
+	mock.lockGetUsernameHistory.Unlock()
+	return mock.GetUsernameHistoryFunc(ctx, id)
+}
+
+// GetUsernameHistoryCalls gets all the calls that were made to GetUsernameHistory.
+// Check the length with:
+//
+//	len(mockedUserService.GetUsernameHistoryCalls())
+func (mock *UserService) GetUsernameHistoryCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockGetUsernameHistory.RLock()
+	calls = mock.calls.GetUsernameHistory
+	mock.lockGetUsernameHistory.RUnlock()
+	return calls
+}
+
+// GrantTempRole calls GrantTempRoleFunc.
+func (mock *UserService) GrantTempRole(ctx context.Context, id int, role string, expiresAt time.Time) error {
+	if mock.GrantTempRoleFunc == nil {
+		panic("UserService.GrantTempRoleFunc: method is nil but UserService.GrantTempRole was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		ID        int
+		Role      string
+		ExpiresAt time.Time
+	}{
+		Ctx:       ctx,
+		ID:        id,
+		Role:      role,
+		ExpiresAt: expiresAt,
+	}
+	mock.lockGrantTempRole.Lock()
+	mock.calls.GrantTempRole = append(mock.calls.GrantTempRole, callInfo)
+	mock.lockGrantTempRole.Unlock()
+	return mock.GrantTempRoleFunc(ctx, id, role, expiresAt)
+}
+
+// GrantTempRoleCalls gets all the calls that were made to GrantTempRole.
+// Check the length with:
+//
+//	len(mockedUserService.GrantTempRoleCalls())
+func (mock *UserService) GrantTempRoleCalls() []struct {
+	Ctx       context.Context
+	ID        int
+	Role      string
+	ExpiresAt time.Time
+} {
+	var calls []struct {
+		Ctx       context.Context
+		ID        int
+		Role      string
+		ExpiresAt time.Time
+	}
+	mock.lockGrantTempRole.RLock()
+	calls = mock.calls.GrantTempRole
+	mock.lockGrantTempRole.RUnlock()
+	return calls
+}
+
+// IssueAPIToken calls IssueAPITokenFunc.
+func (mock *UserService) IssueAPIToken(ctx context.Context, id int) (*user.TokenPair, error) {
+	if mock.IssueAPITokenFunc == nil {
+		panic("UserService.IssueAPITokenFunc: method is nil but UserService.IssueAPIToken was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockIssueAPIToken.Lock()
+	mock.calls.IssueAPIToken = append(mock.calls.IssueAPIToken, callInfo)
+	mock.lockIssueAPIToken.Unlock()
+	return mock.IssueAPITokenFunc(ctx, id)
+}
+
+// IssueAPITokenCalls gets all the calls that were made to IssueAPIToken.
+// Check the length with:
+//
+//	len(mockedUserService.IssueAPITokenCalls())
+func (mock *UserService) IssueAPITokenCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockIssueAPIToken.RLock()
+	calls = mock.calls.IssueAPIToken
+	mock.lockIssueAPIToken.RUnlock()
+	return calls
+}
+
+// ListActivity calls ListActivityFunc.
+func (mock *UserService) ListActivity(ctx context.Context, id int, params user.ActivityListParams) ([]*user.Activity, error) {
+	if mock.ListActivityFunc == nil {
+		panic("UserService.ListActivityFunc: method is nil but UserService.ListActivity was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		ID     int
+		Params user.ActivityListParams
+	}{
+		Ctx:    ctx,
+		ID:     id,
+		Params: params,
+	}
+	mock.lockListActivity.Lock()
+	mock.calls.ListActivity = append(mock.calls.ListActivity, callInfo)
+	mock.lockListActivity.Unlock()
+	return mock.ListActivityFunc(ctx, id, params)
+}
+
+// ListActivityCalls gets all the calls that were made to ListActivity.
+// Check the length with:
+//
+//	len(mockedUserService.ListActivityCalls())
+func (mock *UserService) ListActivityCalls() []struct {
+	Ctx    context.Context
+	ID     int
+	Params user.ActivityListParams
+} {
+	var calls []struct {
+		Ctx    context.Context
+		ID     int
+		Params user.ActivityListParams
+	}
+	mock.lockListActivity.RLock()
+	calls = mock.calls.ListActivity
+	mock.lockListActivity.RUnlock()
+	return calls
+}
+
+// ListDevices calls ListDevicesFunc.
+func (mock *UserService) ListDevices(ctx context.Context, userId int) ([]*user.Device, error) {
+	if mock.ListDevicesFunc == nil {
+		panic("UserService.ListDevicesFunc: method is nil but UserService.ListDevices was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		UserId int
+	}{
+		Ctx:    ctx,
+		UserId: userId,
+	}
+	mock.lockListDevices.Lock()
+	mock.calls.ListDevices = append(mock.calls.ListDevices, callInfo)
+	mock.lockListDevices.Unlock()
+	return mock.ListDevicesFunc(ctx, userId)
+}
+
+// ListDevicesCalls gets all the calls that were made to ListDevices.
+// Check the length with:
+//
+//	len(mockedUserService.ListDevicesCalls())
+func (mock *UserService) ListDevicesCalls() []struct {
+	Ctx    context.Context
+	UserId int
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserId int
+	}
+	mock.lockListDevices.RLock()
+	calls = mock.calls.ListDevices
+	mock.lockListDevices.RUnlock()
+	return calls
+}
+
+// ListLoginAttempts calls ListLoginAttemptsFunc.
+func (mock *UserService) ListLoginAttempts(ctx context.Context, params user.LoginAttemptListParams) ([]*user.LoginAttempt, error) {
+	if mock.ListLoginAttemptsFunc == nil {
+		panic("UserService.ListLoginAttemptsFunc: method is nil but UserService.ListLoginAttempts was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Params user.LoginAttemptListParams
+	}{
+		Ctx:    ctx,
+		Params: params,
+	}
+	mock.lockListLoginAttempts.Lock()
+	mock.calls.ListLoginAttempts = append(mock.calls.ListLoginAttempts, callInfo)
+	mock.lockListLoginAttempts.Unlock()
+	return mock.ListLoginAttemptsFunc(ctx, params)
+}
+
+// ListLoginAttemptsCalls gets all the calls that were made to ListLoginAttempts.
+// Check the length with:
+//
+//	len(mockedUserService.ListLoginAttemptsCalls())
+func (mock *UserService) ListLoginAttemptsCalls() []struct {
+	Ctx    context.Context
+	Params user.LoginAttemptListParams
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Params user.LoginAttemptListParams
+	}
+	mock.lockListLoginAttempts.RLock()
+	calls = mock.calls.ListLoginAttempts
+	mock.lockListLoginAttempts.RUnlock()
+	return calls
+}
+
+// ListUsers calls ListUsersFunc.
+func (mock *UserService) ListUsers(ctx context.Context, params user.UserServiceListParams) (*user.UserPage, error) {
+	if mock.ListUsersFunc == nil {
+		panic("UserService.ListUsersFunc: method is nil but UserService.ListUsers was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Params user.UserServiceListParams
+	}{
+		Ctx:    ctx,
+		Params: params,
+	}
+	mock.lockListUsers.Lock()
+	mock.calls.ListUsers = append(mock.calls.ListUsers, callInfo)
+	mock.lockListUsers.Unlock()
+	return mock.ListUsersFunc(ctx, params)
+}
+
+// ListUsersCalls gets all the calls that were made to ListUsers.
+// Check the length with:
+//
+//	len(mockedUserService.ListUsersCalls())
+func (mock *UserService) ListUsersCalls() []struct {
+	Ctx    context.Context
+	Params user.UserServiceListParams
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Params user.UserServiceListParams
+	}
+	mock.lockListUsers.RLock()
+	calls = mock.calls.ListUsers
+	mock.lockListUsers.RUnlock()
+	return calls
+}
+
+// Login calls LoginFunc.
+func (mock *UserService) Login(ctx context.Context, username string, password string, captchaToken string) (*user.TokenPair, *user.User, error) {
+	if mock.LoginFunc == nil {
+		panic("UserService.LoginFunc: method is nil but UserService.Login was just called")
+	}
+	callInfo := struct {
+		Ctx          context.Context
+		Username     string
+		Password     string
+		CaptchaToken string
+	}{
+		Ctx:          ctx,
+		Username:     username,
+		Password:     password,
+		CaptchaToken: captchaToken,
+	}
+	mock.lockLogin.Lock()
+	mock.calls.Login = append(mock.calls.Login, callInfo)
+	mock.lockLogin.Unlock()
+	return mock.LoginFunc(ctx, username, password, captchaToken)
+}
+
+// LoginCalls gets all the calls that were made to Login.
+// Check the length with:
+//
+//	len(mockedUserService.LoginCalls())
+func (mock *UserService) LoginCalls() []struct {
+	Ctx          context.Context
+	Username     string
+	Password     string
+	CaptchaToken string
+} {
+	var calls []struct {
+		Ctx          context.Context
+		Username     string
+		Password     string
+		CaptchaToken string
+	}
+	mock.lockLogin.RLock()
+	calls = mock.calls.Login
+	mock.lockLogin.RUnlock()
+	return calls
+}
+
+// Logout calls LogoutFunc.
+func (mock *UserService) Logout(ctx context.Context, userId int) error {
+	if mock.LogoutFunc == nil {
+		panic("UserService.LogoutFunc: method is nil but UserService.Logout was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		UserId int
+	}{
+		Ctx:    ctx,
+		UserId: userId,
+	}
+	mock.lockLogout.Lock()
+	mock.calls.Logout = append(mock.calls.Logout, callInfo)
+	mock.lockLogout.Unlock()
+	return mock.LogoutFunc(ctx, userId)
+}
+
+// LogoutCalls gets all the calls that were made to Logout.
+// Check the length with:
+//
+//	len(mockedUserService.LogoutCalls())
+func (mock *UserService) LogoutCalls() []struct {
+	Ctx    context.Context
+	UserId int
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserId int
+	}
+	mock.lockLogout.RLock()
+	calls = mock.calls.Logout
+	mock.lockLogout.RUnlock()
+	return calls
+}
+
+// MagicLinkLogin calls MagicLinkLoginFunc.
+func (mock *UserService) MagicLinkLogin(ctx context.Context, token string) (*user.TokenPair, *user.User, error) {
+	if mock.MagicLinkLoginFunc == nil {
+		panic("UserService.MagicLinkLoginFunc: method is nil but UserService.MagicLinkLogin was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Token string
+	}{
+		Ctx:   ctx,
+		Token: token,
+	}
+	mock.lockMagicLinkLogin.Lock()
+	mock.calls.MagicLinkLogin = append(mock.calls.MagicLinkLogin, callInfo)
+	mock.lockMagicLinkLogin.Unlock()
+	return mock.MagicLinkLoginFunc(ctx, token)
+}
+
+// MagicLinkLoginCalls gets all the calls that were made to MagicLinkLogin.
+// Check the length with:
+//
+//	len(mockedUserService.MagicLinkLoginCalls())
+func (mock *UserService) MagicLinkLoginCalls() []struct {
+	Ctx   context.Context
+	Token string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Token string
+	}
+	mock.lockMagicLinkLogin.RLock()
+	calls = mock.calls.MagicLinkLogin
+	mock.lockMagicLinkLogin.RUnlock()
+	return calls
+}
+
+// PINLogin calls PINLoginFunc.
+func (mock *UserService) PINLogin(ctx context.Context, username string, pin string) (*user.TokenPair, *user.User, error) {
+	if mock.PINLoginFunc == nil {
+		panic("UserService.PINLoginFunc: method is nil but UserService.PINLogin was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Username string
+		Pin      string
+	}{
+		Ctx:      ctx,
+		Username: username,
+		Pin:      pin,
+	}
+	mock.lockPINLogin.Lock()
+	mock.calls.PINLogin = append(mock.calls.PINLogin, callInfo)
+	mock.lockPINLogin.Unlock()
+	return mock.PINLoginFunc(ctx, username, pin)
+}
+
+// PINLoginCalls gets all the calls that were made to PINLogin.
+// Check the length with:
+//
+//	len(mockedUserService.PINLoginCalls())
+func (mock *UserService) PINLoginCalls() []struct {
+	Ctx      context.Context
+	Username string
+	Pin      string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Username string
+		Pin      string
+	}
+	mock.lockPINLogin.RLock()
+	calls = mock.calls.PINLogin
+	mock.lockPINLogin.RUnlock()
+	return calls
+}
+
+// PurgeDeleted calls PurgeDeletedFunc.
+func (mock *UserService) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	if mock.PurgeDeletedFunc == nil {
+		panic("UserService.PurgeDeletedFunc: method is nil but UserService.PurgeDeleted was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Before time.Time
+	}{
+		Ctx:    ctx,
+		Before: before,
+	}
+	mock.lockPurgeDeleted.Lock()
+	mock.calls.PurgeDeleted = append(mock.calls.PurgeDeleted, callInfo)
+	mock.lockPurgeDeleted.Unlock()
+	return mock.PurgeDeletedFunc(ctx, before)
+}
+
+// PurgeDeletedCalls gets all the calls that were made to PurgeDeleted.
+// Check the length with:
+//
+//	len(mockedUserService.PurgeDeletedCalls())
+func (mock *UserService) PurgeDeletedCalls() []struct {
+	Ctx    context.Context
+	Before time.Time
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Before time.Time
+	}
+	mock.lockPurgeDeleted.RLock()
+	calls = mock.calls.PurgeDeleted
+	mock.lockPurgeDeleted.RUnlock()
+	return calls
+}
+
+// Refresh calls RefreshFunc.
+func (mock *UserService) Refresh(ctx context.Context, refreshToken string) (*user.TokenPair, error) {
+	if mock.RefreshFunc == nil {
+		panic("UserService.RefreshFunc: method is nil but UserService.Refresh was just called")
+	}
+	callInfo := struct {
+		Ctx          context.Context
+		RefreshToken string
+	}{
+		Ctx:          ctx,
+		RefreshToken: refreshToken,
+	}
+	mock.lockRefresh.Lock()
+	mock.calls.Refresh = append(mock.calls.Refresh, callInfo)
+	mock.lockRefresh.Unlock()
+	return mock.RefreshFunc(ctx, refreshToken)
+}
+
+// RefreshCalls gets all the calls that were made to Refresh.
+// Check the length with:
+//
+//	len(mockedUserService.RefreshCalls())
+func (mock *UserService) RefreshCalls() []struct {
+	Ctx          context.Context
+	RefreshToken string
+} {
+	var calls []struct {
+		Ctx          context.Context
+		RefreshToken string
+	}
+	mock.lockRefresh.RLock()
+	calls = mock.calls.Refresh
+	mock.lockRefresh.RUnlock()
+	return calls
+}
+
+// RegisterDevice calls RegisterDeviceFunc.
+func (mock *UserService) RegisterDevice(ctx context.Context, userId int, name string) (*user.Device, string, error) {
+	if mock.RegisterDeviceFunc == nil {
+		panic("UserService.RegisterDeviceFunc: method is nil but UserService.RegisterDevice was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		UserId int
+		Name   string
+	}{
+		Ctx:    ctx,
+		UserId: userId,
+		Name:   name,
+	}
+	mock.lockRegisterDevice.Lock()
+	mock.calls.RegisterDevice = append(mock.calls.RegisterDevice, callInfo)
+	mock.lockRegisterDevice.Unlock()
+	return mock.RegisterDeviceFunc(ctx, userId, name)
+}
+
+// RegisterDeviceCalls gets all the calls that were made to RegisterDevice.
+// Check the length with:
+//
+//	len(mockedUserService.RegisterDeviceCalls())
+func (mock *UserService) RegisterDeviceCalls() []struct {
+	Ctx    context.Context
+	UserId int
+	Name   string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserId int
+		Name   string
+	}
+	mock.lockRegisterDevice.RLock()
+	calls = mock.calls.RegisterDevice
+	mock.lockRegisterDevice.RUnlock()
+	return calls
+}
+
+// RegisterUser calls RegisterUserFunc.
+func (mock *UserService) RegisterUser(ctx context.Context, input user.UserInput) (*user.User, error) {
+	if mock.RegisterUserFunc == nil {
+		panic("UserService.RegisterUserFunc: method is nil but UserService.RegisterUser was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Input user.UserInput
+	}{
+		Ctx:   ctx,
+		Input: input,
+	}
+	mock.lockRegisterUser.Lock()
+	mock.calls.RegisterUser = append(mock.calls.RegisterUser, callInfo)
+	mock.lockRegisterUser.Unlock()
+	return mock.RegisterUserFunc(ctx, input)
+}
+
+// RegisterUserCalls gets all the calls that were made to RegisterUser.
+// Check the length with:
+//
+//	len(mockedUserService.RegisterUserCalls())
+func (mock *UserService) RegisterUserCalls() []struct {
+	Ctx   context.Context
+	Input user.UserInput
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Input user.UserInput
+	}
+	mock.lockRegisterUser.RLock()
+	calls = mock.calls.RegisterUser
+	mock.lockRegisterUser.RUnlock()
+	return calls
+}
+
+// RemoveAvatar calls RemoveAvatarFunc.
+func (mock *UserService) RemoveAvatar(ctx context.Context, id int) error {
+	if mock.RemoveAvatarFunc == nil {
+		panic("UserService.RemoveAvatarFunc: method is nil but UserService.RemoveAvatar was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockRemoveAvatar.Lock()
+	mock.calls.RemoveAvatar = append(mock.calls.RemoveAvatar, callInfo)
+	mock.lockRemoveAvatar.Unlock()
+	return mock.RemoveAvatarFunc(ctx, id)
+}
+
+// RemoveAvatarCalls gets all the calls that were made to RemoveAvatar.
+// Check the length with:
+//
+//	len(mockedUserService.RemoveAvatarCalls())
+func (mock *UserService) RemoveAvatarCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockRemoveAvatar.RLock()
+	calls = mock.calls.RemoveAvatar
+	mock.lockRemoveAvatar.RUnlock()
+	return calls
+}
+
+// RequestMagicLink calls RequestMagicLinkFunc.
+func (mock *UserService) RequestMagicLink(ctx context.Context, email string) error {
+	if mock.RequestMagicLinkFunc == nil {
+		panic("UserService.RequestMagicLinkFunc: method is nil but UserService.RequestMagicLink was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Email string
+	}{
+		Ctx:   ctx,
+		Email: email,
+	}
+	mock.lockRequestMagicLink.Lock()
+	mock.calls.RequestMagicLink = append(mock.calls.RequestMagicLink, callInfo)
+	mock.lockRequestMagicLink.Unlock()
+	return mock.RequestMagicLinkFunc(ctx, email)
+}
+
+// RequestMagicLinkCalls gets all the calls that were made to RequestMagicLink.
+// Check the length with:
+//
+//	len(mockedUserService.RequestMagicLinkCalls())
+func (mock *UserService) RequestMagicLinkCalls() []struct {
+	Ctx   context.Context
+	Email string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Email string
+	}
+	mock.lockRequestMagicLink.RLock()
+	calls = mock.calls.RequestMagicLink
+	mock.lockRequestMagicLink.RUnlock()
+	return calls
+}
+
+// RestoreUser calls RestoreUserFunc.
+func (mock *UserService) RestoreUser(ctx context.Context, id int) error {
+	if mock.RestoreUserFunc == nil {
+		panic("UserService.RestoreUserFunc: method is nil but UserService.RestoreUser was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockRestoreUser.Lock()
+	mock.calls.RestoreUser = append(mock.calls.RestoreUser, callInfo)
+	mock.lockRestoreUser.Unlock()
+	return mock.RestoreUserFunc(ctx, id)
+}
+
+// RestoreUserCalls gets all the calls that were made to RestoreUser.
+// Check the length with:
+//
+//	len(mockedUserService.RestoreUserCalls())
+func (mock *UserService) RestoreUserCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockRestoreUser.RLock()
+	calls = mock.calls.RestoreUser
+	mock.lockRestoreUser.RUnlock()
+	return calls
+}
+
+// RevokeDevice calls RevokeDeviceFunc.
+func (mock *UserService) RevokeDevice(ctx context.Context, userId int, deviceId int) error {
+	if mock.RevokeDeviceFunc == nil {
+		panic("UserService.RevokeDeviceFunc: method is nil but UserService.RevokeDevice was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		UserId   int
+		DeviceId int
+	}{
+		Ctx:      ctx,
+		UserId:   userId,
+		DeviceId: deviceId,
+	}
+	mock.lockRevokeDevice.Lock()
+	mock.calls.RevokeDevice = append(mock.calls.RevokeDevice, callInfo)
+	mock.lockRevokeDevice.Unlock()
+	return mock.RevokeDeviceFunc(ctx, userId, deviceId)
+}
+
+// RevokeDeviceCalls gets all the calls that were made to RevokeDevice.
+// Check the length with:
+//
+//	len(mockedUserService.RevokeDeviceCalls())
+func (mock *UserService) RevokeDeviceCalls() []struct {
+	Ctx      context.Context
+	UserId   int
+	DeviceId int
+} {
+	var calls []struct {
+		Ctx      context.Context
+		UserId   int
+		DeviceId int
+	}
+	mock.lockRevokeDevice.RLock()
+	calls = mock.calls.RevokeDevice
+	mock.lockRevokeDevice.RUnlock()
+	return calls
+}
+
+// RevokeTempRole calls RevokeTempRoleFunc.
+func (mock *UserService) RevokeTempRole(ctx context.Context, id int) error {
+	if mock.RevokeTempRoleFunc == nil {
+		panic("UserService.RevokeTempRoleFunc: method is nil but UserService.RevokeTempRole was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockRevokeTempRole.Lock()
+	mock.calls.RevokeTempRole = append(mock.calls.RevokeTempRole, callInfo)
+	mock.lockRevokeTempRole.Unlock()
+	return mock.RevokeTempRoleFunc(ctx, id)
+}
+
+// RevokeTempRoleCalls gets all the calls that were made to RevokeTempRole.
+// Check the length with:
+//
+//	len(mockedUserService.RevokeTempRoleCalls())
+func (mock *UserService) RevokeTempRoleCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockRevokeTempRole.RLock()
+	calls = mock.calls.RevokeTempRole
+	mock.lockRevokeTempRole.RUnlock()
+	return calls
+}
+
+// SetAvatar calls SetAvatarFunc.
+func (mock *UserService) SetAvatar(ctx context.Context, id int, image io.Reader) (string, error) {
+	if mock.SetAvatarFunc == nil {
+		panic("UserService.SetAvatarFunc: method is nil but UserService.SetAvatar was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		ID    int
+		Image io.Reader
+	}{
+		Ctx:   ctx,
+		ID:    id,
+		Image: image,
+	}
+	mock.lockSetAvatar.Lock()
+	mock.calls.SetAvatar = append(mock.calls.SetAvatar, callInfo)
+	mock.lockSetAvatar.Unlock()
+	return mock.SetAvatarFunc(ctx, id, image)
+}
+
+// SetAvatarCalls gets all the calls that were made to SetAvatar.
+// Check the length with:
+//
+//	len(mockedUserService.SetAvatarCalls())
+func (mock *UserService) SetAvatarCalls() []struct {
+	Ctx   context.Context
+	ID    int
+	Image io.Reader
+} {
+	var calls []struct {
+		Ctx   context.Context
+		ID    int
+		Image io.Reader
+	}
+	mock.lockSetAvatar.RLock()
+	calls = mock.calls.SetAvatar
+	mock.lockSetAvatar.RUnlock()
+	return calls
+}
+
+// SetLocations calls SetLocationsFunc.
+func (mock *UserService) SetLocations(ctx context.Context, id int, locationIds []int) error {
+	if mock.SetLocationsFunc == nil {
+		panic("UserService.SetLocationsFunc: method is nil but UserService.SetLocations was just called")
+	}
+	callInfo := struct {
+		Ctx         context.Context
+		ID          int
+		LocationIds []int
+	}{
+		Ctx:         ctx,
+		ID:          id,
+		LocationIds: locationIds,
+	}
+	mock.lockSetLocations.Lock()
+	mock.calls.SetLocations = append(mock.calls.SetLocations, callInfo)
+	mock.lockSetLocations.Unlock()
+	return mock.SetLocationsFunc(ctx, id, locationIds)
+}
+
+// SetLocationsCalls gets all the calls that were made to SetLocations.
+// Check the length with:
+//
+//	len(mockedUserService.SetLocationsCalls())
+func (mock *UserService) SetLocationsCalls() []struct {
+	Ctx         context.Context
+	ID          int
+	LocationIds []int
+} {
+	var calls []struct {
+		Ctx         context.Context
+		ID          int
+		LocationIds []int
+	}
+	mock.lockSetLocations.RLock()
+	calls = mock.calls.SetLocations
+	mock.lockSetLocations.RUnlock()
+	return calls
+}
+
+// SetPIN calls SetPINFunc.
+func (mock *UserService) SetPIN(ctx context.Context, id int, pin string) error {
+	if mock.SetPINFunc == nil {
+		panic("UserService.SetPINFunc: method is nil but UserService.SetPIN was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+		Pin string
+	}{
+		Ctx: ctx,
+		ID:  id,
+		Pin: pin,
+	}
+	mock.lockSetPIN.Lock()
+	mock.calls.SetPIN = append(mock.calls.SetPIN, callInfo)
+	mock.lockSetPIN.Unlock()
+	return mock.SetPINFunc(ctx, id, pin)
+}
+
+// SetPINCalls gets all the calls that were made to SetPIN.
+// Check the length with:
+//
+//	len(mockedUserService.SetPINCalls())
+func (mock *UserService) SetPINCalls() []struct {
+	Ctx context.Context
+	ID  int
+	Pin string
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+		Pin string
+	}
+	mock.lockSetPIN.RLock()
+	calls = mock.calls.SetPIN
+	mock.lockSetPIN.RUnlock()
+	return calls
+}
+
+// ToggleActive calls ToggleActiveFunc.
+func (mock *UserService) ToggleActive(ctx context.Context, id int, active bool) error {
+	if mock.ToggleActiveFunc == nil {
+		panic("UserService.ToggleActiveFunc: method is nil but UserService.ToggleActive was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		ID     int
+		Active bool
+	}{
+		Ctx:    ctx,
+		ID:     id,
+		Active: active,
+	}
+	mock.lockToggleActive.Lock()
+	mock.calls.ToggleActive = append(mock.calls.ToggleActive, callInfo)
+	mock.lockToggleActive.Unlock()
+	return mock.ToggleActiveFunc(ctx, id, active)
+}
+
+// ToggleActiveCalls gets all the calls that were made to ToggleActive.
+// Check the length with:
+//
+//	len(mockedUserService.ToggleActiveCalls())
+func (mock *UserService) ToggleActiveCalls() []struct {
+	Ctx    context.Context
+	ID     int
+	Active bool
+} {
+	var calls []struct {
+		Ctx    context.Context
+		ID     int
+		Active bool
+	}
+	mock.lockToggleActive.RLock()
+	calls = mock.calls.ToggleActive
+	mock.lockToggleActive.RUnlock()
+	return calls
+}
+
+// Unlock calls UnlockFunc.
+func (mock *UserService) Unlock(ctx context.Context, id int) error {
+	if mock.UnlockFunc == nil {
+		panic("UserService.UnlockFunc: method is nil but UserService.Unlock was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockUnlock.Lock()
+	mock.calls.Unlock = append(mock.calls.Unlock, callInfo)
+	mock.lockUnlock.Unlock()
+	return mock.UnlockFunc(ctx, id)
+}
+
+// UnlockCalls gets all the calls that were made to Unlock.
+// Check the length with:
+//
+//	len(mockedUserService.UnlockCalls())
+func (mock *UserService) UnlockCalls() []struct {
+	Ctx context.Context
+	ID  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int
+	}
+	mock.lockUnlock.RLock()
+	calls = mock.calls.Unlock
+	mock.lockUnlock.RUnlock()
+	return calls
+}
+
+// UpdateSettings calls UpdateSettingsFunc.
+func (mock *UserService) UpdateSettings(ctx context.Context, id int, patch user.SettingsPatch) (user.Settings, error) {
+	if mock.UpdateSettingsFunc == nil {
+		panic("UserService.UpdateSettingsFunc: method is nil but UserService.UpdateSettings was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		ID    int
+		Patch user.SettingsPatch
+	}{
+		Ctx:   ctx,
+		ID:    id,
+		Patch: patch,
+	}
+	mock.lockUpdateSettings.Lock()
+	mock.calls.UpdateSettings = append(mock.calls.UpdateSettings, callInfo)
+	mock.lockUpdateSettings.Unlock()
+	return mock.UpdateSettingsFunc(ctx, id, patch)
+}
+
+// UpdateSettingsCalls gets all the calls that were made to UpdateSettings.
+// Check the length with:
+//
+//	len(mockedUserService.UpdateSettingsCalls())
+func (mock *UserService) UpdateSettingsCalls() []struct {
+	Ctx   context.Context
+	ID    int
+	Patch user.SettingsPatch
+} {
+	var calls []struct {
+		Ctx   context.Context
+		ID    int
+		Patch user.SettingsPatch
+	}
+	mock.lockUpdateSettings.RLock()
+	calls = mock.calls.UpdateSettings
+	mock.lockUpdateSettings.RUnlock()
+	return calls
+}
+
+// UpdateUser calls UpdateUserFunc.
+func (mock *UserService) UpdateUser(ctx context.Context, id int, input user.UserInput) error {
+	if mock.UpdateUserFunc == nil {
+		panic("UserService.UpdateUserFunc: method is nil but UserService.UpdateUser was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		ID    int
+		Input user.UserInput
+	}{
+		Ctx:   ctx,
+		ID:    id,
+		Input: input,
+	}
+	mock.lockUpdateUser.Lock()
+	mock.calls.UpdateUser = append(mock.calls.UpdateUser, callInfo)
+	mock.lockUpdateUser.Unlock()
+	return mock.UpdateUserFunc(ctx, id, input)
+}
+
+// UpdateUserCalls gets all the calls that were made to UpdateUser.
+// Check the length with:
+//
+//	len(mockedUserService.UpdateUserCalls())
+func (mock *UserService) UpdateUserCalls() []struct {
+	Ctx   context.Context
+	ID    int
+	Input user.UserInput
+} {
+	var calls []struct {
+		Ctx   context.Context
+		ID    int
+		Input user.UserInput
+	}
+	mock.lockUpdateUser.RLock()
+	calls = mock.calls.UpdateUser
+	mock.lockUpdateUser.RUnlock()
+	return calls
+}
+
+// ValidateSession calls ValidateSessionFunc.
+func (mock *UserService) ValidateSession(ctx context.Context, claims *user.Claims) error {
+	if mock.ValidateSessionFunc == nil {
+		panic("UserService.ValidateSessionFunc: method is nil but UserService.ValidateSession was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Claims *user.Claims
+	}{
+		Ctx:    ctx,
+		Claims: claims,
+	}
+	mock.lockValidateSession.Lock()
+	mock.calls.ValidateSession = append(mock.calls.ValidateSession, callInfo)
+	mock.lockValidateSession.Unlock()
+	return mock.ValidateSessionFunc(ctx, claims)
+}
+
+// ValidateSessionCalls gets all the calls that were made to ValidateSession.
+// Check the length with:
+//
+//	len(mockedUserService.ValidateSessionCalls())
+func (mock *UserService) ValidateSessionCalls() []struct {
+	Ctx    context.Context
+	Claims *user.Claims
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Claims *user.Claims
+	}
+	mock.lockValidateSession.RLock()
+	calls = mock.calls.ValidateSession
+	mock.lockValidateSession.RUnlock()
+	return calls
+}
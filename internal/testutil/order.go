@@ -0,0 +1,312 @@
+package testutil
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/entities/order"
+)
+
+type orderRow struct {
+	order.Order // Created and Updated live in stamps
+	stamps
+}
+
+func (r orderRow) rowID() int { return r.Id }
+
+func (r orderRow) out() *order.Order {
+	o := jsonCopy(r.Order)
+	o.Created, o.Updated = unix(r.created), unix(r.updated)
+	return &o
+}
+
+// Orders returns the DB as an order.OrderRepository.
+func (db *DB) Orders() order.OrderRepository {
+	return orderRepository{db}
+}
+
+type orderRepository struct {
+	db *DB
+}
+
+func (r orderRepository) Create(ctx context.Context, client database.SQLClient, o *order.Order) error {
+	if len(o.Items) == 0 || o.ClerkId == 0 {
+		return order.ErrInvalidOrderInput
+	}
+	if o.Paid < 0 || o.Total < 0 {
+		return order.ErrInvalidPayment
+	}
+	defer r.db.lock()()
+
+	if err := r.db.checkOrderStore(ctx, o.ClerkId, o.LocationId); err != nil {
+		return err
+	}
+	if o.Change == 0 && o.Paid > 0 {
+		o.Change = o.Paid - o.Total
+	}
+
+	row := orderRow{Order: jsonCopy(*o), stamps: newStamps(ctx)}
+	row.Id, row.Revision = r.db.nextID("orders"), 1
+	row.Created, row.Updated = 0, 0
+	r.db.t.orders[row.Id] = row
+
+	o.Id, o.Revision, o.Created, o.Updated = row.Id, row.Revision, unix(row.created), unix(row.updated)
+	return nil
+}
+
+func (r orderRepository) Import(ctx context.Context, client database.SQLClient, orders []*order.Order) ([]bool, error) {
+	defer r.db.lock()()
+
+	imported := make([]bool, len(orders))
+	for i, o := range orders {
+		if _, ok := r.db.t.orders[o.Id]; ok {
+			continue
+		}
+		row := orderRow{
+			Order:  jsonCopy(*o),
+			stamps: stamps{store: database.StoreOf(ctx), created: fromUnix(o.Created), updated: fromUnix(o.Updated)},
+		}
+		row.Revision = max(o.Revision, 1)
+		row.Created, row.Updated = 0, 0
+		r.db.t.orders[o.Id] = row
+		r.db.sawID("orders", o.Id)
+		imported[i] = true
+	}
+	return imported, nil
+}
+
+func (r orderRepository) GetByID(ctx context.Context, id int) (*order.Order, error) {
+	defer r.db.lock()()
+	row, ok := r.db.order(ctx, id)
+	if !ok {
+		return nil, order.ErrOrderNotFound
+	}
+	return row.out(), nil
+}
+
+func (r orderRepository) Update(ctx context.Context, o *order.Order) error {
+	if o.Id == 0 {
+		return order.ErrInvalidOrderInput
+	}
+	defer r.db.lock()()
+
+	if err := r.db.checkOrderStore(ctx, o.ClerkId, 0); err != nil {
+		return err
+	}
+	row, ok := r.db.order(ctx, o.Id)
+	if !ok {
+		return order.ErrOrderNotFound
+	}
+	if row.Revision != o.Revision {
+		return order.ErrOrderConflict
+	}
+
+	u := jsonCopy(*o)
+	row.Items, row.ClerkId, row.Total, row.Paid, row.Change, row.Custom = u.Items, u.ClerkId, u.Total, u.Paid, u.Change, u.Custom
+	row.updated = time.Now()
+	row.Revision++
+	r.db.t.orders[o.Id] = row
+	o.Revision++
+	return nil
+}
+
+func (r orderRepository) Delete(ctx context.Context, id int) error {
+	defer r.db.lock()()
+	if _, ok := r.db.order(ctx, id); !ok {
+		return order.ErrOrderNotFound
+	}
+	delete(r.db.t.orders, id)
+	return nil
+}
+
+func (r orderRepository) List(ctx context.Context, opts order.OrderListOptions) ([]*order.Order, int, error) {
+	defer r.db.lock()()
+
+	table := r.db.t.orders
+	if opts.Archived {
+		table = r.db.t.archive
+	}
+	rows := sorted(table, func(row orderRow) bool {
+		return row.in(ctx) &&
+			(opts.ClerkId <= 0 || row.ClerkId == opts.ClerkId) &&
+			atLocation(row, opts.LocationIds) &&
+			(opts.MinTotal <= 0 || row.Total >= opts.MinTotal) &&
+			(opts.MaxTotal <= 0 || row.Total <= opts.MaxTotal) &&
+			(opts.StartDate == nil || !row.created.Before(*opts.StartDate)) &&
+			(opts.EndDate == nil || !row.created.After(*opts.EndDate)) &&
+			inPeriod(opts.Period, row.created, row.updated)
+	}, nil)
+	total := listTotal(rows, opts.Total)
+
+	k := database.Keyset{
+		Column:    database.SortColumn(opts.SortBy, "created_at", "id", "total", "created_at", "updated_at"),
+		Direction: database.SortDirection(opts.SortOrder, "DESC"),
+	}
+	rows, err := page(rows, k, opts.After, opts.Limit, opts.Offset, func(row orderRow) any {
+		switch k.Column {
+		case "total":
+			return row.Total
+		case "created_at":
+			return row.created.Unix()
+		case "updated_at":
+			return row.updated.Unix()
+		}
+		return int64(row.Id)
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", order.ErrInvalidOrderInput, err)
+	}
+	return collect(rows, orderRow.out), total, nil
+}
+
+func (r orderRepository) GetByDateRange(ctx context.Context, start, end time.Time) ([]*order.Order, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.orders,
+		func(row orderRow) bool { return row.in(ctx) && between(row.created, start, end) },
+		newestOrder)
+	return collect(rows, orderRow.out), nil
+}
+
+func (r orderRepository) UpdatePayment(ctx context.Context, id int, paid int64, revision int) error {
+	if paid < 0 {
+		return order.ErrInvalidPayment
+	}
+	defer r.db.lock()()
+
+	row, ok := r.db.order(ctx, id)
+	if !ok {
+		return order.ErrOrderNotFound
+	}
+	if row.Revision != revision {
+		return order.ErrOrderConflict
+	}
+	row.Paid, row.Change, row.updated = paid, paid-row.Total, time.Now()
+	row.Revision++
+	r.db.t.orders[id] = row
+	return nil
+}
+
+func (r orderRepository) SetStatus(ctx context.Context, client database.SQLClient, id int, status string) error {
+	defer r.db.lock()()
+	row, ok := r.db.order(ctx, id)
+	if !ok {
+		return order.ErrOrderNotFound
+	}
+	row.Status, row.updated = status, time.Now()
+	row.Revision++
+	r.db.t.orders[id] = row
+	return nil
+}
+
+func (r orderRepository) GetTotalSales(ctx context.Context, start, end time.Time, clerkId int, locationIds []int, archived bool) (int64, error) {
+	defer r.db.lock()()
+	var total int64
+	for _, row := range r.db.sales(ctx, start, end, locationIds, archived) {
+		if clerkId == 0 || row.ClerkId == clerkId {
+			total += row.Total
+		}
+	}
+	return total, nil
+}
+
+func (r orderRepository) GetClerkSales(ctx context.Context, clerkId int, start, end time.Time, locationIds []int, archived bool) (int64, error) {
+	defer r.db.lock()()
+	var total int64
+	for _, row := range r.db.sales(ctx, start, end, locationIds, archived) {
+		if row.ClerkId == clerkId {
+			total += row.Total
+		}
+	}
+	return total, nil
+}
+
+func (r orderRepository) GetAverageOrderValue(ctx context.Context, start, end time.Time, clerkId int, locationIds []int, archived bool) (float64, error) {
+	defer r.db.lock()()
+	var total int64
+	var count int
+	for _, row := range r.db.sales(ctx, start, end, locationIds, archived) {
+		if clerkId == 0 || row.ClerkId == clerkId {
+			total += row.Total
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	return float64(total) / float64(count), nil
+}
+
+func (r orderRepository) Count(ctx context.Context) (int, error) {
+	defer r.db.lock()()
+	return len(sorted(r.db.t.orders, func(row orderRow) bool { return row.in(ctx) }, nil)), nil
+}
+
+func (r orderRepository) GetRecentOrders(ctx context.Context, limit int) ([]*order.Order, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.orders, func(row orderRow) bool { return row.in(ctx) }, newestOrder)
+	return collect(rows[:min(limit, len(rows))], orderRow.out), nil
+}
+
+func (r orderRepository) Archive(ctx context.Context, client database.SQLClient, before time.Time, limit int) (int, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.orders, func(row orderRow) bool { return row.in(ctx) && row.created.Before(before) }, nil)
+	rows = rows[:min(limit, len(rows))]
+	for _, row := range rows {
+		r.db.t.archive[row.Id] = row
+		delete(r.db.t.orders, row.Id)
+	}
+	return len(rows), nil
+}
+
+// order returns the order with the given id in the store of ctx.
+func (db *DB) order(ctx context.Context, id int) (orderRow, bool) {
+	row, ok := db.t.orders[id]
+	return row, ok && row.in(ctx)
+}
+
+// checkOrderStore refuses a clerk or location (if any) from another store.
+func (db *DB) checkOrderStore(ctx context.Context, clerkId, locationId int) error {
+	if u, ok := db.t.users[clerkId]; !ok || !u.in(ctx) {
+		return fmt.Errorf("%w: unknown clerk", order.ErrInvalidOrderInput)
+	}
+	if locationId == 0 {
+		return nil
+	}
+	if _, ok := db.location(ctx, locationId); !ok {
+		return fmt.Errorf("%w: unknown location", order.ErrInvalidOrderInput)
+	}
+	return nil
+}
+
+// sales returns the orders the aggregates count: those of the store rung up
+// in the range, void ones aside, with the archive if asked.
+func (db *DB) sales(ctx context.Context, start, end time.Time, locationIds []int, archived bool) []orderRow {
+	tables := []map[int]orderRow{db.t.orders}
+	if archived {
+		tables = append(tables, db.t.archive)
+	}
+	var rows []orderRow
+	for _, table := range tables {
+		for _, row := range table {
+			if row.in(ctx) && row.Status != order.StatusVoid && between(row.created, start, end) && atLocation(row, locationIds) {
+				rows = append(rows, row)
+			}
+		}
+	}
+	return rows
+}
+
+// atLocation matches an order against a list of location ids, nil for
+// every location; 0 in the list matches orders without a location.
+func atLocation(row orderRow, locationIds []int) bool {
+	return locationIds == nil || slices.Contains(locationIds, row.LocationId)
+}
+
+// newestOrder orders by creation time, most recent first.
+func newestOrder(a, b orderRow) int {
+	return cmp.Compare(b.created.UnixNano(), a.created.UnixNano())
+}
@@ -0,0 +1,292 @@
+package testutil
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/entities/product"
+)
+
+type productRow struct {
+	product.Product // Created, Updated and Deleted live in stamps
+	stamps
+	auto86 bool // Switched off by SyncProductAvailability, not by hand
+}
+
+func (r productRow) rowID() int { return r.Id }
+
+func (r productRow) out() *product.Product {
+	p := jsonCopy(r.Product)
+	p.Created, p.Updated, p.Deleted = unix(r.created), unix(r.updated), unix(r.deleted)
+	return &p
+}
+
+// Products returns the DB as a product.ProductRepository.
+func (db *DB) Products() product.ProductRepository {
+	return productRepository{db}
+}
+
+type productRepository struct {
+	db *DB
+}
+
+func (r productRepository) Create(ctx context.Context, p *product.Product) error {
+	if p.Slug == "" || p.Name == "" {
+		return product.ErrInvalidProductInput
+	}
+	defer r.db.lock()()
+
+	if r.db.productBySlug(ctx, p.Slug, false) != nil {
+		return product.ErrDuplicateProductSlug
+	}
+	row := productRow{Product: jsonCopy(*p), stamps: newStamps(ctx)}
+	row.Id, row.Revision = r.db.nextID("products"), 1
+	row.Created, row.Updated, row.Deleted = 0, 0, 0
+	r.db.t.products[row.Id] = row
+
+	p.Id, p.Revision, p.Created, p.Updated = row.Id, row.Revision, unix(row.created), unix(row.updated)
+	return nil
+}
+
+func (r productRepository) Import(ctx context.Context, client database.SQLClient, products []*product.Product) ([]bool, error) {
+	defer r.db.lock()()
+
+	imported := make([]bool, len(products))
+	for i, p := range products {
+		if _, ok := r.db.t.products[p.Id]; ok || r.db.productBySlug(ctx, p.Slug, false) != nil {
+			continue
+		}
+		row := productRow{
+			Product: jsonCopy(*p),
+			stamps:  stamps{store: database.StoreOf(ctx), created: fromUnix(p.Created), updated: fromUnix(p.Updated), deleted: fromUnix(p.Deleted)},
+		}
+		row.Revision = max(p.Revision, 1)
+		row.Created, row.Updated, row.Deleted = 0, 0, 0
+		r.db.t.products[p.Id] = row
+		r.db.sawID("products", p.Id)
+		imported[i] = true
+	}
+	return imported, nil
+}
+
+func (r productRepository) GetByID(ctx context.Context, id int) (*product.Product, error) {
+	defer r.db.lock()()
+	row, ok := r.db.product(ctx, id)
+	if !ok || !row.live() {
+		return nil, product.ErrProductNotFound
+	}
+	return row.out(), nil
+}
+
+func (r productRepository) GetBySlug(ctx context.Context, slug string) (*product.Product, error) {
+	defer r.db.lock()()
+	row := r.db.productBySlug(ctx, slug, true)
+	if row == nil {
+		return nil, product.ErrProductNotFound
+	}
+	return row.out(), nil
+}
+
+func (r productRepository) Update(ctx context.Context, p *product.Product) error {
+	if p.Id == 0 {
+		return product.ErrInvalidProductInput
+	}
+	defer r.db.lock()()
+
+	row, ok := r.db.product(ctx, p.Id)
+	if !ok || !row.live() {
+		return product.ErrProductNotFound
+	}
+	if row.Revision != p.Revision {
+		return product.ErrProductConflict
+	}
+	if other := r.db.productBySlug(ctx, p.Slug, false); other != nil && other.Id != p.Id {
+		return product.ErrDuplicateProductSlug
+	}
+
+	auto86 := row.auto86 && !row.Avail && !p.Avail // Kept only while still unavailable
+	updated := productRow{Product: jsonCopy(*p), stamps: row.stamps, auto86: auto86}
+	updated.Revision++
+	updated.Created, updated.Updated, updated.Deleted = 0, 0, 0
+	updated.updated = time.Now()
+	r.db.t.products[p.Id] = updated
+	p.Revision++
+	return nil
+}
+
+func (r productRepository) Delete(ctx context.Context, id int) error {
+	defer r.db.lock()()
+	row, ok := r.db.product(ctx, id)
+	if !ok || !row.live() {
+		return product.ErrProductNotFound
+	}
+	row.deleted, row.updated = time.Now(), time.Now()
+	row.Revision++
+	r.db.t.products[id] = row
+	return nil
+}
+
+func (r productRepository) Restore(ctx context.Context, id int) error {
+	defer r.db.lock()()
+	row, ok := r.db.product(ctx, id)
+	if !ok || row.live() {
+		return product.ErrProductNotFound
+	}
+	row.deleted, row.updated = time.Time{}, time.Now()
+	row.Revision++
+	r.db.t.products[id] = row
+	return nil
+}
+
+// Purge is refused while a bundle, trashed or not, still lists the product.
+func (r productRepository) Purge(ctx context.Context, id int) error {
+	defer r.db.lock()()
+	row, ok := r.db.product(ctx, id)
+	if !ok || row.live() {
+		return product.ErrProductNotFound
+	}
+	for _, other := range r.db.t.products {
+		if other.Id != id && other.in(ctx) && other.Items != nil && slices.Contains(*other.Items, row.Slug) {
+			return product.ErrProductInUse
+		}
+	}
+	delete(r.db.t.products, id)
+	return nil
+}
+
+func (r productRepository) TrashedBefore(ctx context.Context, cutoff time.Time) ([]int, error) {
+	defer r.db.lock()()
+	return trashedBefore(ctx, r.db.t.products, cutoff, nil), nil
+}
+
+func (r productRepository) List(ctx context.Context, opts product.ProductListOptions) ([]*product.Product, int, error) {
+	defer r.db.lock()()
+
+	rows := sorted(r.db.t.products, func(row productRow) bool {
+		return row.in(ctx) && row.live() != opts.Deleted &&
+			(opts.Tag == "" || row.Tag == opts.Tag) &&
+			(opts.Label == "" || row.Label == opts.Label) &&
+			(opts.Avail == nil || row.Avail == *opts.Avail) &&
+			(opts.MinPrice <= 0 || row.Price >= opts.MinPrice) &&
+			(opts.MaxPrice <= 0 || row.Price <= opts.MaxPrice) &&
+			inPeriod(opts.Period, row.created, row.updated)
+	}, nil)
+	total := listTotal(rows, opts.Total)
+
+	// The same order as the SQL repository's keyset
+	k := database.Keyset{
+		Column:    database.SortColumn(opts.SortBy, "id", "name", "price", "slug", "created_at", "updated_at"),
+		Direction: database.SortDirection(opts.SortOrder, "ASC"),
+	}
+	rows, err := page(rows, k, opts.After, opts.Limit, opts.Offset, func(row productRow) any {
+		switch k.Column {
+		case "name":
+			return row.Name
+		case "price":
+			return row.Price
+		case "slug":
+			return row.Slug
+		case "created_at":
+			return row.created.Unix()
+		case "updated_at":
+			return row.updated.Unix()
+		}
+		return int64(row.Id)
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", product.ErrInvalidProductInput, err)
+	}
+	return collect(rows, productRow.out), total, nil
+}
+
+func (r productRepository) SetAvailability(ctx context.Context, id int, avail bool) error {
+	defer r.db.lock()()
+	row, ok := r.db.product(ctx, id)
+	if !ok || !row.live() {
+		return product.ErrProductNotFound
+	}
+	// A manual toggle overrides any automatic (stock-driven) decision
+	row.Avail, row.auto86, row.updated = avail, false, time.Now()
+	row.Revision++
+	r.db.t.products[id] = row
+	return nil
+}
+
+func (r productRepository) GetAvailable(ctx context.Context) ([]*product.Product, error) {
+	return r.byName(ctx, func(row productRow) bool { return row.Avail })
+}
+
+func (r productRepository) GetByTag(ctx context.Context, tag string) ([]*product.Product, error) {
+	return r.byName(ctx, func(row productRow) bool { return row.Tag == tag })
+}
+
+func (r productRepository) GetByLabel(ctx context.Context, label string) ([]*product.Product, error) {
+	return r.byName(ctx, func(row productRow) bool { return row.Label == label })
+}
+
+func (r productRepository) GetBundles(ctx context.Context) ([]*product.Product, error) {
+	return r.byName(ctx, func(row productRow) bool { return row.Items != nil })
+}
+
+func (r productRepository) GetWithRecipe(ctx context.Context) ([]*product.Product, error) {
+	return r.byName(ctx, func(row productRow) bool { return row.Recipe != nil })
+}
+
+func (r productRepository) Search(ctx context.Context, query string) ([]*product.Product, error) {
+	return r.byName(ctx, func(row productRow) bool {
+		return like(row.Name, query) || like(row.Desc, query) || like(row.Tag, query)
+	})
+}
+
+func (r productRepository) UpdatePrice(ctx context.Context, id int, price int64) error {
+	defer r.db.lock()()
+	row, ok := r.db.product(ctx, id)
+	if !ok || !row.live() {
+		return product.ErrProductNotFound
+	}
+	row.Price, row.updated = price, time.Now()
+	row.Revision++
+	r.db.t.products[id] = row
+	return nil
+}
+
+func (r productRepository) GetByPriceRange(ctx context.Context, minPrice, maxPrice int64) ([]*product.Product, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.products,
+		func(row productRow) bool {
+			return row.in(ctx) && row.live() && row.Price >= minPrice && row.Price <= maxPrice
+		},
+		func(a, b productRow) int { return cmp.Compare(a.Price, b.Price) })
+	return collect(rows, productRow.out), nil
+}
+
+// byName returns the live products of the store that pass keep, by name.
+func (r productRepository) byName(ctx context.Context, keep func(productRow) bool) ([]*product.Product, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.products,
+		func(row productRow) bool { return row.in(ctx) && row.live() && keep(row) },
+		func(a, b productRow) int { return cmp.Compare(a.Name, b.Name) })
+	return collect(rows, productRow.out), nil
+}
+
+// product returns the product with the given id in the store of ctx, live
+// or not.
+func (db *DB) product(ctx context.Context, id int) (productRow, bool) {
+	row, ok := db.t.products[id]
+	return row, ok && row.in(ctx)
+}
+
+// productBySlug returns the product with the given slug in the store of
+// ctx, if there is one; with live set, only if it isn't in the trash.
+func (db *DB) productBySlug(ctx context.Context, slug string, live bool) *productRow {
+	for _, row := range db.t.products {
+		if row.in(ctx) && row.Slug == slug && (row.live() || !live) {
+			return &row
+		}
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package testutil
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/entities/role"
+)
+
+type roleRow struct {
+	role.Role // Created, Updated and Deleted live in stamps
+	stamps
+}
+
+func (r roleRow) rowID() int { return r.Id }
+
+func (r roleRow) out() *role.Role {
+	ro := jsonCopy(r.Role)
+	ro.Created, ro.Updated, ro.Deleted = unix(r.created), unix(r.updated), unix(r.deleted)
+	return &ro
+}
+
+// Roles returns the DB as a role.RoleRepository.
+func (db *DB) Roles() role.RoleRepository {
+	return roleRepository{db}
+}
+
+type roleRepository struct {
+	db *DB
+}
+
+func (r roleRepository) Create(ctx context.Context, ro *role.Role) error {
+	if ro.Slug == "" || ro.Name == "" {
+		return role.ErrInvalidRoleInput
+	}
+	if ro.Permissions == nil {
+		ro.Permissions = []string{}
+	}
+	defer r.db.lock()()
+
+	if r.db.roleBySlug(ctx, ro.Slug, false) != nil {
+		return role.ErrDuplicateRoleSlug
+	}
+	row := roleRow{Role: jsonCopy(*ro), stamps: newStamps(ctx)}
+	row.Id = r.db.nextID("roles")
+	row.Created, row.Updated, row.Deleted = 0, 0, 0
+	r.db.t.roles[row.Id] = row
+
+	ro.Id, ro.Created, ro.Updated = row.Id, unix(row.created), unix(row.updated)
+	return nil
+}
+
+func (r roleRepository) Import(ctx context.Context, client database.SQLClient, ro *role.Role) (bool, error) {
+	defer r.db.lock()()
+
+	if _, ok := r.db.t.roles[ro.Id]; ok || r.db.roleBySlug(ctx, ro.Slug, false) != nil {
+		return false, nil
+	}
+	if ro.Permissions == nil {
+		ro.Permissions = []string{}
+	}
+	row := roleRow{
+		Role:   jsonCopy(*ro),
+		stamps: stamps{store: database.StoreOf(ctx), created: fromUnix(ro.Created), updated: fromUnix(ro.Updated), deleted: fromUnix(ro.Deleted)},
+	}
+	row.Created, row.Updated, row.Deleted = 0, 0, 0
+	r.db.t.roles[ro.Id] = row
+	r.db.sawID("roles", ro.Id)
+	return true, nil
+}
+
+func (r roleRepository) GetByID(ctx context.Context, id int) (*role.Role, error) {
+	defer r.db.lock()()
+	row, ok := r.db.role(ctx, id)
+	if !ok || !row.live() {
+		return nil, role.ErrRoleNotFound
+	}
+	return row.out(), nil
+}
+
+func (r roleRepository) GetBySlug(ctx context.Context, slug string) (*role.Role, error) {
+	defer r.db.lock()()
+	row := r.db.roleBySlug(ctx, slug, true)
+	if row == nil {
+		return nil, role.ErrRoleNotFound
+	}
+	return row.out(), nil
+}
+
+func (r roleRepository) Update(ctx context.Context, ro *role.Role) error {
+	if ro.Id == 0 {
+		return role.ErrInvalidRoleInput
+	}
+	defer r.db.lock()()
+
+	row, ok := r.db.role(ctx, ro.Id)
+	if !ok || !row.live() {
+		return role.ErrRoleNotFound
+	}
+	if other := r.db.roleBySlug(ctx, ro.Slug, false); other != nil && other.Id != ro.Id {
+		return role.ErrDuplicateRoleSlug
+	}
+
+	now := time.Now()
+	if row.Slug != ro.Slug {
+		r.db.reparentRoles(ctx, row.Slug, ro.Slug, now)
+	}
+	row.Slug, row.Name, row.Parent, row.updated = ro.Slug, ro.Name, ro.Parent, now
+	row.Permissions = jsonCopy(ro.Permissions)
+	r.db.t.roles[ro.Id] = row
+	return nil
+}
+
+func (r roleRepository) Delete(ctx context.Context, id int) error {
+	defer r.db.lock()()
+	return r.db.trashRole(ctx, id)
+}
+
+func (r roleRepository) DeleteAndReassign(ctx context.Context, id int, toSlug string) (int, error) {
+	defer r.db.lock()()
+
+	row, ok := r.db.role(ctx, id)
+	if !ok || !row.live() {
+		return 0, role.ErrRoleNotFound
+	}
+	if err := r.db.trashRole(ctx, id); err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for uid, u := range r.db.t.users {
+		if u.in(ctx) && u.Role == row.Slug {
+			u.Role, u.updated = toSlug, time.Now()
+			u.Version++
+			u.Revision++
+			r.db.t.users[uid] = u
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+func (r roleRepository) Restore(ctx context.Context, id int) error {
+	defer r.db.lock()()
+	row, ok := r.db.role(ctx, id)
+	if !ok || row.live() {
+		return role.ErrRoleNotFound
+	}
+	row.deleted, row.updated = time.Time{}, time.Now()
+	r.db.t.roles[id] = row
+	return nil
+}
+
+func (r roleRepository) Purge(ctx context.Context, id int) error {
+	defer r.db.lock()()
+	row, ok := r.db.role(ctx, id)
+	if !ok || row.live() {
+		return role.ErrRoleNotFound
+	}
+	delete(r.db.t.roles, id)
+	return nil
+}
+
+func (r roleRepository) TrashedBefore(ctx context.Context, cutoff time.Time) ([]int, error) {
+	defer r.db.lock()()
+	return trashedBefore(ctx, r.db.t.roles, cutoff, nil), nil
+}
+
+func (r roleRepository) CountUsers(ctx context.Context, slug string) (int, error) {
+	defer r.db.lock()()
+	n := 0
+	for _, u := range r.db.t.users {
+		if u.in(ctx) && u.live() && u.Role == slug {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r roleRepository) CountUsersByRole(ctx context.Context) (map[string]int, error) {
+	defer r.db.lock()()
+	now := time.Now()
+	counts := map[string]int{}
+	for _, u := range r.db.t.users {
+		if !u.in(ctx) || !u.live() {
+			continue
+		}
+		counts[u.Role]++
+		if u.TempRole != "" && u.TempRoleExpiresAt != nil && u.TempRoleExpiresAt.After(now) {
+			counts[u.TempRole]++
+		}
+	}
+	return counts, nil
+}
+
+func (r roleRepository) RevokeSessions(ctx context.Context, slugs []string) (int, error) {
+	defer r.db.lock()()
+	n := 0
+	for id, u := range r.db.t.users {
+		if u.in(ctx) && (slices.Contains(slugs, u.Role) || u.TempRole != "" && slices.Contains(slugs, u.TempRole)) {
+			u.Version++
+			r.db.t.users[id] = u
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r roleRepository) List(ctx context.Context) ([]*role.Role, error) {
+	return r.list(ctx, true)
+}
+
+func (r roleRepository) ListDeleted(ctx context.Context) ([]*role.Role, error) {
+	return r.list(ctx, false)
+}
+
+func (r roleRepository) list(ctx context.Context, live bool) ([]*role.Role, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.roles,
+		func(row roleRow) bool { return row.in(ctx) && row.live() == live },
+		func(a, b roleRow) int { return cmp.Compare(a.Name, b.Name) })
+	return collect(rows, roleRow.out), nil
+}
+
+// role returns the role with the given id in the store of ctx, live or not.
+func (db *DB) role(ctx context.Context, id int) (roleRow, bool) {
+	row, ok := db.t.roles[id]
+	return row, ok && row.in(ctx)
+}
+
+// roleBySlug returns the role with the given slug in the store of ctx, if
+// there is one; with live set, only if it isn't in the trash.
+func (db *DB) roleBySlug(ctx context.Context, slug string, live bool) *roleRow {
+	for _, row := range db.t.roles {
+		if row.in(ctx) && row.Slug == slug && (row.live() || !live) {
+			return &row
+		}
+	}
+	return nil
+}
+
+// trashRole moves a live role to the trash, and its children stop
+// inheriting from it.
+func (db *DB) trashRole(ctx context.Context, id int) error {
+	row, ok := db.role(ctx, id)
+	if !ok || !row.live() {
+		return role.ErrRoleNotFound
+	}
+	now := time.Now()
+	row.deleted, row.updated = now, now
+	db.t.roles[id] = row
+	db.reparentRoles(ctx, row.Slug, "", now)
+	return nil
+}
+
+// reparentRoles points the children of the role from at to, "" for none.
+func (db *DB) reparentRoles(ctx context.Context, from, to string, now time.Time) {
+	for id, child := range db.t.roles {
+		if child.in(ctx) && child.Parent == from {
+			child.Parent, child.updated = to, now
+			db.t.roles[id] = child
+		}
+	}
+}
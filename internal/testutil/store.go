@@ -0,0 +1,75 @@
+package testutil
+
+import (
+	"context"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/entities/store"
+)
+
+type storeRow struct {
+	id         int
+	slug, name string
+	created    time.Time
+}
+
+func (r storeRow) rowID() int { return r.id }
+
+func (r storeRow) out() *store.Store {
+	return &store.Store{Id: r.id, Slug: r.slug, Name: r.name, Created: unix(r.created)}
+}
+
+// Stores returns the DB as a store.StoreRepository.
+func (db *DB) Stores() store.StoreRepository {
+	return storeRepository{db}
+}
+
+type storeRepository struct {
+	db *DB
+}
+
+func (r storeRepository) Create(ctx context.Context, s *store.Store) error {
+	if s.Slug == "" || s.Name == "" {
+		return store.ErrInvalidStoreInput
+	}
+	defer r.db.lock()()
+
+	for _, row := range r.db.t.stores {
+		if row.slug == s.Slug {
+			return store.ErrDuplicateStoreSlug
+		}
+	}
+	row := storeRow{id: r.db.nextID("stores"), slug: s.Slug, name: s.Name, created: time.Now()}
+	r.db.t.stores[row.id] = row
+	s.Id, s.Created = row.id, unix(row.created)
+	return nil
+}
+
+func (r storeRepository) GetByID(ctx context.Context, id int) (*store.Store, error) {
+	defer r.db.lock()()
+	row, ok := r.db.t.stores[id]
+	if !ok {
+		return nil, store.ErrStoreNotFound
+	}
+	return row.out(), nil
+}
+
+func (r storeRepository) GetBySlug(ctx context.Context, slug string) (*store.Store, error) {
+	defer r.db.lock()()
+	for _, row := range r.db.t.stores {
+		if row.slug == slug {
+			return row.out(), nil
+		}
+	}
+	return nil, store.ErrStoreNotFound
+}
+
+func (r storeRepository) List(ctx context.Context) ([]*store.Store, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.stores, func(storeRow) bool { return true }, nil)
+	var stores []*store.Store
+	for _, row := range rows {
+		stores = append(stores, row.out())
+	}
+	return stores, nil
+}
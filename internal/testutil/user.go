@@ -0,0 +1,944 @@
+package testutil
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
+	"github.com/iteranya/practicing-go/internal/entities/user"
+)
+
+type userRow struct {
+	user.User // CreatedAt, UpdatedAt, DeletedAt and StoreId live in stamps, Version is the token version
+	stamps
+	pinHash      string
+	failedLogins int
+	lockedUntil  time.Time
+	anonymized   time.Time
+}
+
+func (r userRow) rowID() int { return r.Id }
+
+func (r userRow) out() *user.User {
+	u := copyUser(r.User)
+	u.Version = 0 // Never read back, like token_version
+	u.CreatedAt, u.UpdatedAt, u.StoreId = r.created, r.updated, r.store
+	if !r.live() {
+		deleted := r.deleted
+		u.DeletedAt = &deleted
+	}
+	return &u
+}
+
+// copyUser copies u through JSON, keeping the fields it doesn't serialize.
+func copyUser(u user.User) user.User {
+	c := jsonCopy(u)
+	c.Hash, c.Version, c.StoreId = u.Hash, u.Version, u.StoreId
+	return c
+}
+
+type historyRow struct {
+	id, userId int
+	username   string
+	changedAt  time.Time
+}
+
+func (r historyRow) rowID() int { return r.id }
+
+type userLocation struct {
+	userId, locationId int
+}
+
+type policyKey struct {
+	userId  int
+	version string
+}
+
+type loginAttemptRow struct {
+	user.LoginAttempt
+	store int
+}
+
+func (r loginAttemptRow) rowID() int { return r.Id }
+
+type activityRow struct {
+	user.Activity
+}
+
+func (r activityRow) rowID() int { return r.Id }
+
+type deviceRow struct {
+	user.Device
+	hash    string
+	revoked time.Time
+}
+
+func (r deviceRow) rowID() int { return r.Id }
+
+// tokenRow is a magic link or refresh token, keyed by its hash. used is
+// when a magic link was used, or a refresh token revoked.
+type tokenRow struct {
+	userId  int
+	expires time.Time
+	used    time.Time
+}
+
+type timeEntryRow struct {
+	user.TimeEntry
+}
+
+func (r timeEntryRow) rowID() int { return r.Id }
+
+// Users returns the DB as a user.UserRepository.
+func (db *DB) Users() user.UserRepository {
+	return userRepository{db}
+}
+
+type userRepository struct {
+	db *DB
+}
+
+func (r userRepository) Create(ctx context.Context, u *user.User) error {
+	if u.Username == "" || u.Hash == "" {
+		return user.ErrInvalidUserInput
+	}
+	defer r.db.lock()()
+
+	if err := r.db.userKeysTaken(ctx, u.Username, u.Email, 0); err != nil {
+		return err
+	}
+	u.StoreId = database.StoreOf(ctx)
+	row := userRow{User: copyUser(*u), stamps: newStamps(ctx)}
+	row.Id, row.Revision, row.Version = r.db.nextID("users"), 1, 0
+	row.CreatedAt, row.UpdatedAt, row.DeletedAt = time.Time{}, time.Time{}, nil
+	r.db.t.users[row.Id] = row
+
+	u.Id, u.Revision, u.CreatedAt, u.UpdatedAt = row.Id, row.Revision, row.created, row.updated
+	return nil
+}
+
+func (r userRepository) Import(ctx context.Context, client database.SQLClient, u *user.User, anonymized bool, locationIds []int) (bool, error) {
+	defer r.db.lock()()
+
+	u.StoreId = database.StoreOf(ctx)
+	if _, ok := r.db.t.users[u.Id]; ok || r.db.userKeysTaken(ctx, u.Username, u.Email, 0) != nil {
+		return false, nil
+	}
+	row := userRow{User: copyUser(*u), stamps: stamps{store: u.StoreId, created: u.CreatedAt, updated: u.UpdatedAt}}
+	if u.DeletedAt != nil {
+		row.deleted = *u.DeletedAt
+		if anonymized {
+			row.anonymized = *u.DeletedAt
+		}
+	}
+	row.Revision, row.Version = max(u.Revision, 1), 0
+	row.CreatedAt, row.UpdatedAt, row.DeletedAt = time.Time{}, time.Time{}, nil
+	r.db.t.users[u.Id] = row
+	for _, locationId := range locationIds {
+		r.db.t.userLocations[userLocation{u.Id, locationId}] = true
+	}
+	r.db.sawID("users", u.Id)
+	return true, nil
+}
+
+func (r userRepository) GetByID(ctx context.Context, id int) (*user.User, error) {
+	defer r.db.lock()()
+	row, ok := r.db.user(ctx, id)
+	if !ok {
+		return nil, user.ErrUserNotFound
+	}
+	return row.out(), nil
+}
+
+func (r userRepository) GetByUsername(ctx context.Context, username string) (*user.User, error) {
+	return r.getOne(ctx, func(row userRow) bool { return strings.EqualFold(row.Username, username) })
+}
+
+func (r userRepository) GetByEmail(ctx context.Context, email string) (*user.User, error) {
+	return r.getOne(ctx, func(row userRow) bool {
+		return row.live() && row.Email != "" && row.Email == strings.ToLower(email)
+	})
+}
+
+func (r userRepository) GetByFormerUsername(ctx context.Context, username string) (*user.User, error) {
+	defer r.db.lock()()
+	history := sorted(r.db.t.usernameHistory,
+		func(h historyRow) bool {
+			u, ok := r.db.user(ctx, h.userId)
+			return ok && u.in(ctx) && strings.EqualFold(h.username, username)
+		},
+		newestHistory)
+	if len(history) == 0 {
+		return nil, user.ErrUserNotFound
+	}
+	return r.db.t.users[history[0].userId].out(), nil
+}
+
+func (r userRepository) UsernameTaken(ctx context.Context, username string, exceptId int) (bool, error) {
+	defer r.db.lock()()
+	for _, row := range r.db.t.users {
+		if row.in(ctx) && row.Id != exceptId && strings.EqualFold(row.Username, username) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r userRepository) GetUsernameHistory(ctx context.Context, id int) ([]user.UsernameChange, error) {
+	defer r.db.lock()()
+	history := []user.UsernameChange{}
+	for _, h := range sorted(r.db.t.usernameHistory, func(h historyRow) bool { return h.userId == id }, newestHistory) {
+		history = append(history, user.UsernameChange{Username: h.username, ChangedAt: h.changedAt})
+	}
+	return history, nil
+}
+
+func (r userRepository) Update(ctx context.Context, u *user.User) error {
+	if u.Id == 0 {
+		return user.ErrInvalidUserInput
+	}
+	defer r.db.lock()()
+
+	row, ok := r.db.user(ctx, u.Id)
+	if !ok || !row.live() {
+		return user.ErrUserNotFound
+	}
+	if row.Revision != u.Revision {
+		return user.ErrUserConflict
+	}
+	if err := r.db.userKeysTaken(ctx, u.Username, u.Email, u.Id); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if row.Username != u.Username {
+		id := r.db.nextID("username_history")
+		r.db.t.usernameHistory[id] = historyRow{id: id, userId: u.Id, username: row.Username, changedAt: now}
+	}
+	c := copyUser(*u)
+	row.Username, row.DisplayName, row.Hash, row.Role = c.Username, c.DisplayName, c.Hash, c.Role
+	row.Active, row.Setting, row.Custom, row.Email = c.Active, c.Setting, c.Custom, c.Email
+	row.updated = now
+	row.Revision++
+	r.db.t.users[u.Id] = row
+	u.Revision++
+	return nil
+}
+
+func (r userRepository) Trash(ctx context.Context, id int) error {
+	defer r.db.lock()()
+	row, ok := r.db.user(ctx, id)
+	if !ok || !row.live() {
+		return user.ErrUserNotFound
+	}
+	row.deleted, row.updated, row.Active = time.Now(), time.Now(), false
+	row.Version++
+	row.Revision++
+	r.db.t.users[id] = row
+	return nil
+}
+
+func (r userRepository) Restore(ctx context.Context, id int) error {
+	defer r.db.lock()()
+	row, ok := r.db.user(ctx, id)
+	if !ok {
+		return user.ErrUserNotFound
+	}
+	if !row.anonymized.IsZero() {
+		return user.ErrUserAnonymized
+	}
+	if row.live() {
+		return user.ErrUserNotFound
+	}
+	row.deleted, row.updated = time.Time{}, time.Now()
+	row.Revision++
+	r.db.t.users[id] = row
+	return nil
+}
+
+func (r userRepository) Anonymize(ctx context.Context, id int) error {
+	defer r.db.lock()()
+	row, ok := r.db.user(ctx, id)
+	if !ok || !row.anonymized.IsZero() {
+		return user.ErrUserNotFound
+	}
+
+	now := time.Now()
+	deleted := "deleted-" + strconv.Itoa(id)
+	row.Username, row.DisplayName, row.Hash, row.pinHash, row.Active = deleted, "Deleted user", "", "", false
+	row.LastLoginIP, row.AvatarURL, row.Email, row.Setting, row.Custom = "", "", "", user.Settings{}, nil
+	row.Version++
+	row.Revision++
+	row.updated, row.anonymized = now, now
+	if row.live() {
+		row.deleted = now
+	}
+	r.db.t.users[id] = row
+
+	for hash, t := range r.db.t.refreshTokens {
+		if t.userId == id {
+			delete(r.db.t.refreshTokens, hash)
+		}
+	}
+	for did, d := range r.db.t.devices {
+		if d.UserId == id {
+			delete(r.db.t.devices, did)
+		}
+	}
+	for aid, a := range r.db.t.activity {
+		if a.UserId == id {
+			a.IP = ""
+			r.db.t.activity[aid] = a
+		}
+	}
+	for aid, a := range r.db.t.loginAttempts {
+		if a.UserId != nil && *a.UserId == id {
+			a.Username, a.IP, a.UserAgent = deleted, "", ""
+			r.db.t.loginAttempts[aid] = a
+		}
+	}
+	for hid, h := range r.db.t.usernameHistory {
+		if h.userId == id {
+			delete(r.db.t.usernameHistory, hid)
+		}
+	}
+	for hash, t := range r.db.t.magicLinks {
+		if t.userId == id {
+			delete(r.db.t.magicLinks, hash)
+		}
+	}
+	return nil
+}
+
+func (r userRepository) TrashedBefore(ctx context.Context, cutoff time.Time) ([]int, error) {
+	defer r.db.lock()()
+	return trashedBefore(ctx, r.db.t.users, cutoff, func(row userRow) bool { return row.anonymized.IsZero() }), nil
+}
+
+func (r userRepository) SetAvatar(ctx context.Context, id int, url string) (string, error) {
+	defer r.db.lock()()
+	row, ok := r.db.user(ctx, id)
+	if !ok || !row.live() {
+		return "", user.ErrUserNotFound
+	}
+	previous := row.AvatarURL
+	row.AvatarURL = url
+	r.db.t.users[id] = row
+	return previous, nil
+}
+
+func (r userRepository) List(ctx context.Context, opts user.UserListOptions) ([]*user.User, int, error) {
+	defer r.db.lock()()
+
+	rows := sorted(r.db.t.users, func(row userRow) bool {
+		switch {
+		case opts.Deleted && opts.Anonymized:
+			if row.anonymized.IsZero() {
+				return false
+			}
+		case opts.Deleted:
+			if row.live() || !row.anonymized.IsZero() {
+				return false
+			}
+		default:
+			if !row.live() {
+				return false
+			}
+		}
+		return row.in(ctx) &&
+			(opts.Query == "" || like(row.Username, opts.Query) || like(row.DisplayName, opts.Query)) &&
+			(opts.Role == "" || row.Role == opts.Role) &&
+			(opts.Active == nil || row.Active == *opts.Active) &&
+			inPeriod(opts.Period, row.created, row.updated)
+	}, nil)
+	total := listTotal(rows, opts.Total)
+
+	column := database.SortColumn(opts.SortBy, "username", "id", "username", "display_name", "created_at", "updated_at", "last_login_at")
+	k := database.Keyset{
+		Column:    column,
+		Direction: database.SortDirection(opts.SortOrder, "ASC"),
+		Nullable:  column == "last_login_at",
+	}
+	rows, err := page(rows, k, opts.After, opts.Limit, opts.Offset, func(row userRow) any {
+		switch k.Column {
+		case "username":
+			return row.Username
+		case "display_name":
+			return row.DisplayName
+		case "created_at":
+			return row.created.Unix()
+		case "updated_at":
+			return row.updated.Unix()
+		case "last_login_at":
+			if row.LastLoginAt == nil {
+				return nil
+			}
+			return row.LastLoginAt.Unix()
+		}
+		return int64(row.Id)
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", user.ErrInvalidUserInput, err)
+	}
+	users := collect(rows, userRow.out)
+	if users == nil {
+		users = []*user.User{}
+	}
+	return users, total, nil
+}
+
+func (r userRepository) UpdatePassword(ctx context.Context, id int, hash string, mustChange bool) error {
+	return r.update(ctx, id, func(row *userRow) {
+		row.Hash, row.MustChangePassword, row.updated = hash, mustChange, time.Now()
+		row.Version++
+		row.Revision++
+	})
+}
+
+func (r userRepository) RehashPassword(ctx context.Context, id int, hash string) error {
+	if err := r.update(ctx, id, func(row *userRow) { row.Hash = hash }); err != user.ErrUserNotFound {
+		return err
+	}
+	return nil // An UPDATE matching nothing isn't an error
+}
+
+func (r userRepository) UpdateSettings(ctx context.Context, id int, patch user.SettingsPatch) (user.Settings, error) {
+	var settings user.Settings
+	err := r.update(ctx, id, func(row *userRow) {
+		merged := map[string]string{}
+		b, _ := json.Marshal(row.Setting)
+		_ = json.Unmarshal(b, &merged)
+		for k, v := range patch.Set {
+			merged[k] = v
+		}
+		for _, k := range patch.Unset {
+			delete(merged, k)
+		}
+		b, _ = json.Marshal(merged)
+		row.Setting = user.Settings{}
+		_ = json.Unmarshal(b, &row.Setting)
+		row.updated = time.Now()
+		row.Revision++
+		settings = row.Setting
+	})
+	return settings, err
+}
+
+func (r userRepository) SetActive(ctx context.Context, id int, active bool) error {
+	defer r.db.lock()()
+	row, ok := r.db.user(ctx, id)
+	if !ok || !row.live() {
+		return user.ErrUserNotFound
+	}
+	if !active {
+		row.Version++ // Deactivating also invalidates tokens already issued
+	}
+	row.Active, row.updated = active, time.Now()
+	row.Revision++
+	r.db.t.users[id] = row
+	return nil
+}
+
+func (r userRepository) GetByRole(ctx context.Context, role string) ([]*user.User, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.users,
+		func(row userRow) bool { return row.in(ctx) && row.live() && row.Role == role },
+		func(a, b userRow) int { return cmp.Compare(a.Username, b.Username) })
+	return collect(rows, userRow.out), nil
+}
+
+func (r userRepository) Count(ctx context.Context) (int, error) {
+	defer r.db.lock()()
+	return len(sorted(r.db.t.users, func(row userRow) bool { return row.in(ctx) }, nil)), nil
+}
+
+func (r userRepository) SetPinHash(ctx context.Context, id int, hash string) error {
+	return r.update(ctx, id, func(row *userRow) { row.pinHash = hash })
+}
+
+func (r userRepository) GetPinHash(ctx context.Context, id int) (string, error) {
+	defer r.db.lock()()
+	row, ok := r.db.user(ctx, id)
+	if !ok {
+		return "", user.ErrUserNotFound
+	}
+	return row.pinHash, nil
+}
+
+func (r userRepository) GetLockedUntil(ctx context.Context, id int) (time.Time, error) {
+	defer r.db.lock()()
+	row, ok := r.db.user(ctx, id)
+	if !ok || !row.lockedUntil.After(time.Now()) {
+		return time.Time{}, nil
+	}
+	return row.lockedUntil, nil
+}
+
+func (r userRepository) GetFailedLogins(ctx context.Context, id int) (int, error) {
+	defer r.db.lock()()
+	row, ok := r.db.user(ctx, id)
+	if !ok {
+		return 0, user.ErrUserNotFound
+	}
+	return row.failedLogins, nil
+}
+
+func (r userRepository) RecordFailedLogin(ctx context.Context, id int, maxAttempts int, cooldown time.Duration) (time.Time, error) {
+	var until time.Time
+	err := r.update(ctx, id, func(row *userRow) {
+		now := time.Now()
+		if maxAttempts > 0 && row.failedLogins+1 >= maxAttempts {
+			row.lockedUntil, row.failedLogins = now.Add(cooldown), 0
+		} else {
+			row.failedLogins++
+		}
+		if row.lockedUntil.After(now) {
+			until = row.lockedUntil
+		}
+	})
+	return until, err
+}
+
+func (r userRepository) ClearFailedLogins(ctx context.Context, id int) error {
+	return r.update(ctx, id, func(row *userRow) { row.failedLogins, row.lockedUntil = 0, time.Time{} })
+}
+
+func (r userRepository) SetTempRole(ctx context.Context, id int, role string, expiresAt *time.Time) error {
+	return r.update(ctx, id, func(row *userRow) {
+		row.TempRole, row.TempRoleExpiresAt = role, jsonCopy(expiresAt)
+	})
+}
+
+func (r userRepository) ClearExpiredTempRoles(ctx context.Context) (int, error) {
+	defer r.db.lock()()
+	now := time.Now()
+	n := 0
+	for id, row := range r.db.t.users {
+		if row.in(ctx) && row.TempRole != "" && row.TempRoleExpiresAt != nil && !row.TempRoleExpiresAt.After(now) {
+			row.TempRole, row.TempRoleExpiresAt = "", nil
+			r.db.t.users[id] = row
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r userRepository) RecordLogin(ctx context.Context, id int, ip string) error {
+	if err := r.update(ctx, id, func(row *userRow) {
+		now := time.Now()
+		row.LastLoginAt, row.LastLoginIP = &now, ip
+	}); err != user.ErrUserNotFound {
+		return err
+	}
+	return nil
+}
+
+func (r userRepository) RecordLoginAttempt(ctx context.Context, a *user.LoginAttempt) error {
+	defer r.db.lock()()
+	if a.UserId == nil {
+		for _, row := range r.db.t.users {
+			if row.in(ctx) && strings.EqualFold(row.Username, a.Username) {
+				id := row.Id
+				a.UserId = &id
+				break
+			}
+		}
+	}
+	a.Id, a.CreatedAt = r.db.nextID("login_attempts"), time.Now()
+	r.db.t.loginAttempts[a.Id] = loginAttemptRow{jsonCopy(*a), database.StoreOf(ctx)}
+	return nil
+}
+
+func (r userRepository) ListLoginAttempts(ctx context.Context, userId int, username string, limit, offset int) ([]*user.LoginAttempt, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.loginAttempts,
+		func(a loginAttemptRow) bool {
+			matched := userId > 0 && a.UserId != nil && *a.UserId == userId ||
+				userId == 0 && strings.EqualFold(a.Username, username)
+			return matched && a.store == database.StoreOf(ctx)
+		},
+		func(a, b loginAttemptRow) int {
+			return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.Id, a.Id))
+		})
+	rows = rows[min(offset, len(rows)):]
+	attempts := collect(rows[:min(limit, len(rows))], func(a loginAttemptRow) *user.LoginAttempt {
+		out := jsonCopy(a.LoginAttempt)
+		return &out
+	})
+	if attempts == nil {
+		attempts = []*user.LoginAttempt{}
+	}
+	return attempts, nil
+}
+
+func (r userRepository) HasLocations(ctx context.Context) (bool, error) {
+	defer r.db.lock()()
+	for _, l := range r.db.t.locations {
+		if l.in(ctx) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r userRepository) GetLocationIDs(ctx context.Context, userId int) ([]int, error) {
+	defer r.db.lock()()
+	ids := []int{}
+	for ul := range r.db.t.userLocations {
+		if ul.userId == userId {
+			ids = append(ids, ul.locationId)
+		}
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (r userRepository) GetLocationSlugs(ctx context.Context, userId int, all bool) (map[int]string, error) {
+	defer r.db.lock()()
+	slugs := map[int]string{}
+	for id, l := range r.db.t.locations {
+		if l.in(ctx) && (all || r.db.t.userLocations[userLocation{userId, id}]) {
+			slugs[id] = l.Slug
+		}
+	}
+	return slugs, nil
+}
+
+func (r userRepository) SetLocations(ctx context.Context, userId int, locationIds []int) error {
+	defer r.db.lock()()
+	if _, ok := r.db.user(ctx, userId); !ok {
+		return user.ErrUserNotFound
+	}
+	for _, id := range locationIds {
+		if _, ok := r.db.location(ctx, id); !ok {
+			return fmt.Errorf("%w: unknown location", user.ErrInvalidUserInput)
+		}
+	}
+	for ul := range r.db.t.userLocations {
+		if ul.userId == userId && !slices.Contains(locationIds, ul.locationId) {
+			delete(r.db.t.userLocations, ul)
+		}
+	}
+	for _, id := range locationIds {
+		r.db.t.userLocations[userLocation{userId, id}] = true
+	}
+	return nil
+}
+
+func (r userRepository) AcceptPolicy(ctx context.Context, userId int, version string) (*user.PolicyAcceptance, error) {
+	defer r.db.lock()()
+	key := policyKey{userId, version}
+	at, ok := r.db.t.policies[key]
+	if !ok {
+		at = time.Now() // Accepting again keeps the original time
+		r.db.t.policies[key] = at
+	}
+	return &user.PolicyAcceptance{Version: version, AcceptedAt: at}, nil
+}
+
+func (r userRepository) GetPolicyAcceptance(ctx context.Context, userId int) (*user.PolicyAcceptance, error) {
+	defer r.db.lock()()
+	var latest *user.PolicyAcceptance
+	for k, at := range r.db.t.policies {
+		if k.userId == userId && (latest == nil || at.After(latest.AcceptedAt)) {
+			latest = &user.PolicyAcceptance{Version: k.version, AcceptedAt: at}
+		}
+	}
+	return latest, nil
+}
+
+func (r userRepository) GetTokenVersion(ctx context.Context, id int) (int, bool, error) {
+	defer r.db.lock()()
+	row, ok := r.db.user(ctx, id)
+	if !ok {
+		return 0, false, user.ErrUserNotFound
+	}
+	return row.Version, row.Active, nil
+}
+
+func (r userRepository) BumpTokenVersion(ctx context.Context, id int) error {
+	return r.update(ctx, id, func(row *userRow) { row.Version++ })
+}
+
+func (r userRepository) RevokeRefreshTokens(ctx context.Context, userId int) error {
+	defer r.db.lock()()
+	r.db.revokeRefreshTokens(userId)
+	return nil
+}
+
+func (r userRepository) LogActivity(ctx context.Context, a *user.Activity) error {
+	defer r.db.lock()()
+	a.Id, a.CreatedAt = r.db.nextID("user_activity"), time.Now()
+	r.db.t.activity[a.Id] = activityRow{jsonCopy(*a)}
+	return nil
+}
+
+func (r userRepository) ListActivity(ctx context.Context, userId, limit, offset int) ([]*user.Activity, error) {
+	defer r.db.lock()()
+	rows := sorted(r.db.t.activity,
+		func(a activityRow) bool { return a.UserId == userId },
+		func(a, b activityRow) int {
+			return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.Id, a.Id))
+		})
+	rows = rows[min(offset, len(rows)):]
+	activity := collect(rows[:min(limit, len(rows))], func(a activityRow) *user.Activity {
+		out := jsonCopy(a.Activity)
+		return &out
+	})
+	if activity == nil {
+		activity = []*user.Activity{}
+	}
+	return activity, nil
+}
+
+func (r userRepository) CreateDevice(ctx context.Context, d *user.Device, hash string) error {
+	defer r.db.lock()()
+	d.Id, d.CreatedAt = r.db.nextID("user_devices"), time.Now()
+	r.db.t.devices[d.Id] = deviceRow{Device: jsonCopy(*d), hash: hash}
+	return nil
+}
+
+func (r userRepository) ListDevices(ctx context.Context, userId int) ([]*user.Device, error) {
+	defer r.db.lock()()
+	now := time.Now()
+	rows := sorted(r.db.t.devices,
+		func(d deviceRow) bool { return d.UserId == userId && d.revoked.IsZero() && d.ExpiresAt.After(now) },
+		func(a, b deviceRow) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	devices := collect(rows, func(d deviceRow) *user.Device {
+		out := jsonCopy(d.Device)
+		return &out
+	})
+	if devices == nil {
+		devices = []*user.Device{}
+	}
+	return devices, nil
+}
+
+func (r userRepository) UseDevice(ctx context.Context, hash string, expiresAt time.Time) (int, error) {
+	defer r.db.lock()()
+	now := time.Now()
+	for id, d := range r.db.t.devices {
+		if _, ok := r.db.user(ctx, d.UserId); ok && d.hash == hash && d.revoked.IsZero() && d.ExpiresAt.After(now) {
+			d.LastUsedAt, d.ExpiresAt = &now, expiresAt
+			r.db.t.devices[id] = d
+			return d.UserId, nil
+		}
+	}
+	return 0, user.ErrInvalidDevice
+}
+
+func (r userRepository) RevokeDevice(ctx context.Context, userId, deviceId int) error {
+	defer r.db.lock()()
+	d, ok := r.db.t.devices[deviceId]
+	if !ok || d.UserId != userId || !d.revoked.IsZero() {
+		return user.ErrDeviceNotFound
+	}
+	d.revoked = time.Now()
+	r.db.t.devices[deviceId] = d
+	return nil
+}
+
+func (r userRepository) RevokeDevices(ctx context.Context, userId int) error {
+	defer r.db.lock()()
+	for id, d := range r.db.t.devices {
+		if d.UserId == userId && d.revoked.IsZero() {
+			d.revoked = time.Now()
+			r.db.t.devices[id] = d
+		}
+	}
+	return nil
+}
+
+func (r userRepository) ClockIn(ctx context.Context, userId int) (*user.TimeEntry, error) {
+	defer r.db.lock()()
+	if r.db.openTimeEntry(userId) != nil {
+		return nil, user.ErrAlreadyClockedIn
+	}
+	e := user.TimeEntry{Id: r.db.nextID("time_entries"), UserId: userId, ClockIn: time.Now()}
+	r.db.t.timeEntries[e.Id] = timeEntryRow{e}
+	return &e, nil
+}
+
+func (r userRepository) ClockOut(ctx context.Context, userId int) (*user.TimeEntry, error) {
+	defer r.db.lock()()
+	e := r.db.openTimeEntry(userId)
+	if e == nil {
+		return nil, user.ErrNotClockedIn
+	}
+	now := time.Now()
+	e.ClockOut = &now
+	r.db.t.timeEntries[e.Id] = timeEntryRow{*e}
+	return e, nil
+}
+
+func (r userRepository) GetOpenTimeEntry(ctx context.Context, userId int) (*user.TimeEntry, error) {
+	defer r.db.lock()()
+	return r.db.openTimeEntry(userId), nil
+}
+
+func (r userRepository) GetHoursReport(ctx context.Context, start, end time.Time, userId int) ([]user.HoursLine, error) {
+	defer r.db.lock()()
+
+	now := time.Now()
+	lines := map[int]*user.HoursLine{}
+	for _, e := range sorted(r.db.t.timeEntries, always, nil) {
+		u, ok := r.db.user(ctx, e.UserId)
+		out := now
+		if e.ClockOut != nil {
+			out = *e.ClockOut
+		}
+		if !ok || (userId != 0 && e.UserId != userId) || !e.ClockIn.Before(end) || !out.After(start) {
+			continue
+		}
+		l := lines[e.UserId]
+		if l == nil {
+			l = &user.HoursLine{UserId: u.Id, Username: u.Username, DisplayName: u.DisplayName}
+			lines[e.UserId] = l
+		}
+		l.Shifts++
+		l.Seconds += int64(minTime(out, end).Sub(maxTime(e.ClockIn, start)).Seconds())
+	}
+
+	report := []user.HoursLine{}
+	for _, l := range lines {
+		l.Hours = math.Round(float64(l.Seconds)/36) / 100
+		report = append(report, *l)
+	}
+	slices.SortFunc(report, func(a, b user.HoursLine) int { return cmp.Compare(a.Username, b.Username) })
+	return report, nil
+}
+
+func (r userRepository) CreateMagicLink(ctx context.Context, userId int, hash string, expiresAt time.Time) error {
+	defer r.db.lock()()
+	r.db.t.magicLinks[hash] = tokenRow{userId: userId, expires: expiresAt}
+	return nil
+}
+
+func (r userRepository) UseMagicLink(ctx context.Context, hash string) (int, error) {
+	defer r.db.lock()()
+	t, ok := r.db.t.magicLinks[hash]
+	if _, ours := r.db.user(ctx, t.userId); !ok || !ours || !t.used.IsZero() || !t.expires.After(time.Now()) {
+		return 0, user.ErrInvalidMagicLink
+	}
+	t.used = time.Now()
+	r.db.t.magicLinks[hash] = t
+	return t.userId, nil
+}
+
+func (r userRepository) CreateRefreshToken(ctx context.Context, userId int, hash string, expiresAt time.Time) error {
+	defer r.db.lock()()
+	r.db.t.refreshTokens[hash] = tokenRow{userId: userId, expires: expiresAt}
+	return nil
+}
+
+func (r userRepository) RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (int, error) {
+	defer r.db.lock()()
+	t, ok := r.db.t.refreshTokens[oldHash]
+	if _, ours := r.db.user(ctx, t.userId); !ok || !ours {
+		return 0, user.ErrInvalidRefresh
+	}
+	if !t.used.IsZero() {
+		// A rotated token coming back means it leaked
+		r.db.revokeRefreshTokens(t.userId)
+		return 0, user.ErrInvalidRefresh
+	}
+	if time.Now().After(t.expires) {
+		return 0, user.ErrInvalidRefresh
+	}
+
+	t.used = time.Now()
+	r.db.t.refreshTokens[oldHash] = t
+	r.db.t.refreshTokens[newHash] = tokenRow{userId: t.userId, expires: expiresAt}
+	return t.userId, nil
+}
+
+// getOne returns the user of the store of ctx that passes keep.
+func (r userRepository) getOne(ctx context.Context, keep func(userRow) bool) (*user.User, error) {
+	defer r.db.lock()()
+	for _, row := range sorted(r.db.t.users, func(row userRow) bool { return row.in(ctx) && keep(row) }, nil) {
+		return row.out(), nil
+	}
+	return nil, user.ErrUserNotFound
+}
+
+// update applies change to the user with the given id in the store of ctx,
+// deleted or not.
+func (r userRepository) update(ctx context.Context, id int, change func(*userRow)) error {
+	defer r.db.lock()()
+	row, ok := r.db.user(ctx, id)
+	if !ok {
+		return user.ErrUserNotFound
+	}
+	change(&row)
+	r.db.t.users[id] = row
+	return nil
+}
+
+// user returns the user with the given id in the store of ctx, deleted or
+// not.
+func (db *DB) user(ctx context.Context, id int) (userRow, bool) {
+	row, ok := db.t.users[id]
+	return row, ok && row.in(ctx)
+}
+
+// userKeysTaken reports the username (in any case) or (non-empty) email
+// being used by another user of the store of ctx than exceptId.
+func (db *DB) userKeysTaken(ctx context.Context, username, email string, exceptId int) error {
+	for _, row := range db.t.users {
+		if !row.in(ctx) || row.Id == exceptId {
+			continue
+		}
+		if strings.EqualFold(row.Username, username) {
+			return user.ErrDuplicateUsername
+		}
+		if email != "" && row.Email == email {
+			return user.ErrDuplicateEmail
+		}
+	}
+	return nil
+}
+
+func (db *DB) revokeRefreshTokens(userId int) {
+	for hash, t := range db.t.refreshTokens {
+		if t.userId == userId && t.used.IsZero() {
+			t.used = time.Now()
+			db.t.refreshTokens[hash] = t
+		}
+	}
+}
+
+func (db *DB) openTimeEntry(userId int) *user.TimeEntry {
+	for _, e := range db.t.timeEntries {
+		if e.UserId == userId && e.ClockOut == nil {
+			out := e.TimeEntry
+			return &out
+		}
+	}
+	return nil
+}
+
+// newestHistory orders username history most recent first.
+func newestHistory(a, b historyRow) int {
+	return cmp.Or(b.changedAt.Compare(a.changedAt), cmp.Compare(b.id, a.id))
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrNotOwner = errors.New("this belongs to another user")
+
+// Subject is the caller as the service layer sees it: who they are and what
+// their role grants. Authorize puts it in the context once the route's
+// permission check has passed.
+type Subject struct {
+	UserID      int
+	Permissions []string // Resolved, including inherited ones
+
+	// Locations the caller works at, id -> slug, for permissions scoped with
+	// ScopeSeparator. Only filled in when the role has scoped entries.
+	Locations map[int]string
+}
+
+// Can reports whether the subject's role grants perm at any of the
+// subject's locations. Services with located records check CanAt too.
+func (s Subject) Can(perm string) bool {
+	if HasPermission(s.Permissions, perm) {
+		return true
+	}
+	for _, slug := range s.Locations {
+		if HasPermissionAt(s.Permissions, perm, slug) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAt reports whether the subject's role grants perm at one location,
+// counting only entries scoped to that location besides unscoped ones.
+func (s Subject) CanAt(perm string, locationID int) bool {
+	slug, ok := s.Locations[locationID]
+	if !ok {
+		return HasPermission(s.Permissions, perm)
+	}
+	return HasPermissionAt(s.Permissions, perm, slug)
+}
+
+// SubjectFrom returns the caller, and false when the context carries none.
+func SubjectFrom(ctx context.Context) (Subject, bool) {
+	s, ok := ctx.Value(SubjectKey).(Subject)
+	return s, ok
+}
+
+// Rule is an attribute-based check on one resource, for what a route
+// permission can't express on its own ("only your own orders"). It returns
+// nil to allow, or an error saying why not.
+type Rule[R any] func(s Subject, res R) error
+
+// Check runs the rules in order and returns the first refusal. Like
+// LocationScope, a context without a Subject (internal jobs) isn't checked.
+func Check[R any](ctx context.Context, res R, rules ...Rule[R]) error {
+	s, ok := SubjectFrom(ctx)
+	if !ok {
+		return nil
+	}
+	for _, rule := range rules {
+		if err := rule(s, res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unless skips rule for subjects whose role grants perm, e.g. managers who
+// may void anyone's orders.
+func Unless[R any](perm string, rule Rule[R]) Rule[R] {
+	return func(s Subject, res R) error {
+		if s.Can(perm) {
+			return nil
+		}
+		return rule(s, res)
+	}
+}
+
+// UnlessAt is Unless for a resource at a location: a grant scoped to
+// another store doesn't skip the rule.
+func UnlessAt[R Located](perm string, rule Rule[R]) Rule[R] {
+	return func(s Subject, res R) error {
+		if s.CanAt(perm, res.LocationID()) {
+			return nil
+		}
+		return rule(s, res)
+	}
+}
+
+// Located is a resource that belongs to one location, such as an order and
+// the store it was rung up at.
+type Located interface {
+	LocationID() int
+}
+
+// Owned is a resource that belongs to one user, such as an order and the
+// clerk who rang it up.
+type Owned interface {
+	OwnerID() int
+}
+
+// OwnerOnly is a Rule allowing only the resource's owner; everyone else gets
+// ErrNotOwner. Wrap it in Unless to let a permission override it.
+func OwnerOnly[R Owned](s Subject, res R) error {
+	if res.OwnerID() != s.UserID {
+		return ErrNotOwner
+	}
+	return nil
+}
+
+// CheckOwner is the common case of Check: only the owner, or a caller whose
+// role grants overridePerm, may act on res.
+func CheckOwner[R Owned](ctx context.Context, res R, overridePerm string) error {
+	return Check(ctx, res, Unless(overridePerm, OwnerOnly[R]))
+}
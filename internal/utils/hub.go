@@ -0,0 +1,53 @@
+package utils
+
+import "sync"
+
+// hubBuffer is how many undelivered events a subscriber may lag behind
+// before further events to it are dropped.
+const hubBuffer = 16
+
+// Hub fans events out to live subscribers, such as SSE streams. Publish
+// never blocks: a subscriber that stops reading misses events instead of
+// stalling whatever raised them.
+type Hub[T any] struct {
+	mu   sync.Mutex
+	subs map[chan T]struct{}
+}
+
+func NewHub[T any]() *Hub[T] {
+	return &Hub[T]{subs: make(map[chan T]struct{})}
+}
+
+// Subscribe returns a channel of events and a function that unsubscribes
+// and closes it. The caller must call cancel when done.
+func (h *Hub[T]) Subscribe() (<-chan T, func()) {
+	ch := make(chan T, hubBuffer)
+
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	cancel := func() {
+		h.mu.Lock()
+		if _, ok := h.subs[ch]; ok {
+			delete(h.subs, ch)
+			close(ch)
+		}
+		h.mu.Unlock()
+	}
+
+	return ch, cancel
+}
+
+// Publish delivers the event to every subscriber that has room for it.
+func (h *Hub[T]) Publish(event T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs {
+		select {
+		case ch <- event:
+		default: // slow subscriber, drop
+		}
+	}
+}
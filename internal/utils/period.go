@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/iteranya/practicing-go/internal/database"
+)
+
+// PeriodFromQuery reads the created_from, created_to, updated_from and
+// updated_to parameters listings share. Each is a date (2006-01-02) or an
+// RFC 3339 time; a date in a _to parameter covers the whole day. Values that
+// don't parse are ignored, like other list filters.
+func PeriodFromQuery(query url.Values) database.Period {
+	return database.Period{
+		CreatedFrom: parseBound(query.Get("created_from"), false),
+		CreatedTo:   parseBound(query.Get("created_to"), true),
+		UpdatedFrom: parseBound(query.Get("updated_from"), false),
+		UpdatedTo:   parseBound(query.Get("updated_to"), true),
+	}
+}
+
+func parseBound(s string, end bool) time.Time {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		if end {
+			return t.Add(24*time.Hour - time.Nanosecond)
+		}
+		return t
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC()
+	}
+	return time.Time{}
+}
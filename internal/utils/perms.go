@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"context"
+	"slices"
 	"sort"
 	"strings"
 )
@@ -14,17 +16,22 @@ const (
 	ProductAdmin   = "product:*"
 	UserAdmin      = "user:*"
 	RoleAdmin      = "role:*"
+	LocationAdmin  = "location:*"
 	// Inventory
 	PermInventoryCreate = "inventory:create"
 	PermInventoryRead   = "inventory:read"
 	PermInventoryUpdate = "inventory:update"
 	PermInventoryDelete = "inventory:delete"
+	PermInventoryCost   = "inventory:cost" // See unit costs and stock values
 
 	// Order
-	PermOrderCreate = "order:create"
-	PermOrderRead   = "order:read"
-	PermOrderUpdate = "order:update"
-	PermOrderDelete = "order:delete"
+	PermOrderCreate   = "order:create"
+	PermOrderRead     = "order:read"
+	PermOrderUpdate   = "order:update"
+	PermOrderDelete   = "order:delete"
+	PermOrderVoidAny  = "order:void_any"  // Void any order, not just your own recent ones
+	PermOrderSalesAll = "order:sales_all" // See every clerk's orders and sales, not just your own
+	PermOrderPayAny   = "order:pay_any"   // Take payment on any order, not just your own
 
 	// Product
 	PermProductCreate = "product:create"
@@ -33,16 +40,24 @@ const (
 	PermProductDelete = "product:delete"
 
 	// User
-	PermUserCreate = "user:create"
-	PermUserRead   = "user:read"
-	PermUserUpdate = "user:update"
-	PermUserDelete = "user:delete"
+	PermUserCreate   = "user:create"
+	PermUserRead     = "user:read"
+	PermUserUpdate   = "user:update"
+	PermUserDelete   = "user:delete"
+	PermUserAPIToken = "user:api_token" // Mint long-lived API tokens that act as any user
 
 	// Role
 	PermRoleCreate = "role:create"
 	PermRoleRead   = "role:read"
 	PermRoleUpdate = "role:update"
 	PermRoleDelete = "role:delete"
+
+	// Location
+	PermLocationCreate = "location:create"
+	PermLocationRead   = "location:read"
+	PermLocationUpdate = "location:update"
+	PermLocationDelete = "location:delete"
+	PermLocationAll    = "location:all" // See and ring up orders at every store, not just assigned ones
 )
 
 // --- Validation Map ---
@@ -54,12 +69,16 @@ var validPermissions = map[string]struct{}{
 	PermInventoryRead:   {},
 	PermInventoryUpdate: {},
 	PermInventoryDelete: {},
+	PermInventoryCost:   {},
 
 	// Order
-	PermOrderCreate: {},
-	PermOrderRead:   {},
-	PermOrderUpdate: {},
-	PermOrderDelete: {},
+	PermOrderCreate:   {},
+	PermOrderRead:     {},
+	PermOrderUpdate:   {},
+	PermOrderDelete:   {},
+	PermOrderVoidAny:  {},
+	PermOrderSalesAll: {},
+	PermOrderPayAny:   {},
 
 	// Product
 	PermProductCreate: {},
@@ -68,22 +87,46 @@ var validPermissions = map[string]struct{}{
 	PermProductDelete: {},
 
 	// User
-	PermUserCreate: {},
-	PermUserRead:   {},
-	PermUserUpdate: {},
-	PermUserDelete: {},
+	PermUserCreate:   {},
+	PermUserRead:     {},
+	PermUserUpdate:   {},
+	PermUserDelete:   {},
+	PermUserAPIToken: {},
 
 	// Role
 	PermRoleCreate: {},
 	PermRoleRead:   {},
 	PermRoleUpdate: {},
 	PermRoleDelete: {},
+
+	// Location
+	PermLocationCreate: {},
+	PermLocationRead:   {},
+	PermLocationUpdate: {},
+	PermLocationDelete: {},
+	PermLocationAll:    {},
 }
 
 // --- Functions ---
 
-// IsValidPermission checks if a string matches one of the defined permission constants.
+// IsValidPermission checks if a string matches one of the defined permission
+// constants or a "resource:*" wildcard over them, in any of the forms a role
+// may hold: denied ("!order:delete"), scoped to a location
+// ("order:delete@store-2") or both.
 func IsValidPermission(perm string) bool {
+	perm = strings.TrimPrefix(perm, DenyPrefix)
+	perm, scope, scoped := strings.Cut(perm, ScopeSeparator)
+	if scoped && scope == "" {
+		return false
+	}
+	if resource, ok := strings.CutSuffix(perm, ":*"); ok {
+		for p := range validPermissions {
+			if strings.HasPrefix(p, resource+":") {
+				return true
+			}
+		}
+		return false
+	}
 	_, ok := validPermissions[perm]
 	return ok
 }
@@ -99,26 +142,95 @@ func GetAllPermissions() []string {
 	return perms
 }
 
+// DenyPrefix marks a negative permission: "!order:delete" takes
+// order:delete away even when another entry (such as "order:*") grants it.
+const DenyPrefix = "!"
+
+// ScopeSeparator limits an entry to one location by slug:
+// "inventory:update@store-2" only counts for staff assigned to store-2, and
+// where a service tracks locations (orders), only for that store's records.
+// It combines with the other forms, e.g. "!order:*@store-3".
+const ScopeSeparator = "@"
+
 // HasPermission checks if a list of user permissions contains the required one.
+// Entries scoped to a location are ignored; see HasPermissionAt.
+//
+// Precedence: a matching deny ("!perm" or "!resource:*") always wins, no
+// matter where it appears in the list or how specific the grant is. Only
+// then are grants checked. With role inheritance the lists are merged, so a
+// deny on a parent role can't be granted back by a child.
 func HasPermission(userPerms []string, requiredPerm string) bool {
+	return HasPermissionAt(userPerms, requiredPerm)
+}
+
+// HasPermissionAt is HasPermission for a caller acting at the given
+// locations (slugs): entries scoped to one of them count as well as unscoped
+// ones. A scoped deny only takes the permission away at its location.
+func HasPermissionAt(userPerms []string, requiredPerm string, locations ...string) bool {
+	applies := func(p string) (string, bool) {
+		p, scope, scoped := strings.Cut(p, ScopeSeparator)
+		return p, !scoped || slices.Contains(locations, scope)
+	}
+
 	for _, p := range userPerms {
-		if p == requiredPerm {
-			return true
-		}
-		// Future proofing: If you ever decide to use wildcards like "inventory:*"
-		if strings.HasSuffix(p, ":*") {
-			prefix := strings.TrimSuffix(p, ":*")
-			if strings.HasPrefix(requiredPerm, prefix+":") {
-				return true
+		if denied, ok := strings.CutPrefix(p, DenyPrefix); ok {
+			if denied, ok := applies(denied); ok && permMatches(denied, requiredPerm) {
+				return false
 			}
 		}
 	}
+
+	for _, p := range userPerms {
+		if strings.HasPrefix(p, DenyPrefix) {
+			continue
+		}
+		if p, ok := applies(p); ok && permMatches(p, requiredPerm) {
+			return true
+		}
+	}
+	return false
+}
+
+// permMatches reports whether p covers requiredPerm, either exactly or as a
+// "resource:*" wildcard.
+func permMatches(p, requiredPerm string) bool {
+	if p == requiredPerm {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(p, ":*"); ok {
+		return strings.HasPrefix(requiredPerm, prefix+":")
+	}
 	return false
 }
 
 type ContextKey string
 
 const (
-	UserIDKey ContextKey = "userID"   // Holds the int ID of the logged in user
-	RoleKey   ContextKey = "userRole" // Holds the string slug of the user's role
+	UserIDKey    ContextKey = "userID"    // Holds the int ID of the logged in user
+	RoleKey      ContextKey = "userRole"  // Holds the string slug of the user's role
+	ClientIPKey  ContextKey = "clientIP"  // Holds the string address of the caller
+	UserAgentKey ContextKey = "userAgent" // Holds the caller's User-Agent header
+	LocationKey  ContextKey = "locations" // Holds the caller's LocationScope
+	SubjectKey   ContextKey = "subject"   // Holds the caller's Subject
 )
+
+// LocationScope is the set of stores the caller may work with. Services
+// read it from the context; when it's missing (internal jobs) nothing is
+// filtered.
+type LocationScope struct {
+	All bool  // Every location, for staff with location:all
+	IDs []int // Assigned locations otherwise, possibly none
+}
+
+// Allows reports whether the scope covers location id. Location 0, the
+// orders rung up before locations were set up, is open to everyone.
+func (s LocationScope) Allows(id int) bool {
+	return s.All || id == 0 || slices.Contains(s.IDs, id)
+}
+
+// LocationScopeFrom returns the caller's scope, and false when the context
+// carries none.
+func LocationScopeFrom(ctx context.Context) (LocationScope, bool) {
+	s, ok := ctx.Value(LocationKey).(LocationScope)
+	return s, ok
+}
@@ -0,0 +1,95 @@
+package utils
+
+import "testing"
+
+func TestHasPermissionAt(t *testing.T) {
+	tests := []struct {
+		name      string
+		perms     []string
+		required  string
+		locations []string
+		want      bool
+	}{
+		{"exact grant", []string{"order:read"}, "order:read", nil, true},
+		{"wildcard grant", []string{"order:*"}, "order:delete", nil, true},
+		{"wildcard of another resource", []string{"product:*"}, "order:delete", nil, false},
+		{"nothing granted", nil, "order:read", nil, false},
+
+		// A deny wins wherever it is in the list and however specific the grant
+		{"deny after wildcard", []string{"order:*", "!order:delete"}, "order:delete", nil, false},
+		{"deny before grant", []string{"!order:delete", "order:delete"}, "order:delete", nil, false},
+		{"wildcard deny over exact grant", []string{"order:delete", "!order:*"}, "order:delete", nil, false},
+		{"deny leaves the rest", []string{"order:*", "!order:delete"}, "order:read", nil, true},
+
+		// Scoped entries only count at their location
+		{"scoped grant elsewhere", []string{"inventory:update@north"}, "inventory:update", []string{"south"}, false},
+		{"scoped grant here", []string{"inventory:update@north"}, "inventory:update", []string{"south", "north"}, true},
+		{"scoped grant without a location", []string{"inventory:update@north"}, "inventory:update", nil, false},
+		{"unscoped grant anywhere", []string{"inventory:update"}, "inventory:update", []string{"north"}, true},
+		{"scoped wildcard", []string{"inventory:*@north"}, "inventory:delete", []string{"north"}, true},
+
+		// A scoped deny takes a grant away only at its location
+		{"scoped deny here", []string{"order:*", "!order:*@north"}, "order:delete", []string{"north"}, false},
+		{"scoped deny elsewhere", []string{"order:*", "!order:*@north"}, "order:delete", []string{"south"}, true},
+		{"scoped deny without a location", []string{"order:*", "!order:delete@north"}, "order:delete", nil, true},
+		{"scoped deny over scoped grant", []string{"order:delete@north", "!order:delete@north"}, "order:delete", []string{"north"}, false},
+		{"unscoped deny over scoped grant", []string{"order:delete@north", "!order:delete"}, "order:delete", []string{"north"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasPermissionAt(tt.perms, tt.required, tt.locations...); got != tt.want {
+				t.Errorf("HasPermissionAt(%q, %q, %q) = %v, want %v", tt.perms, tt.required, tt.locations, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubjectCanAt(t *testing.T) {
+	s := Subject{
+		UserID:      1,
+		Permissions: []string{"order:read", "order:void_any@north", "!order:read@south"},
+		Locations:   map[int]string{1: "north", 2: "south"},
+	}
+	tests := []struct {
+		perm     string
+		location int
+		want     bool
+	}{
+		{"order:void_any", 1, true},
+		{"order:void_any", 2, false},
+		{"order:void_any", 3, false}, // Not one of theirs: unscoped entries only
+		{"order:read", 1, true},
+		{"order:read", 2, false},
+		{"order:read", 3, true},
+	}
+	for _, tt := range tests {
+		if got := s.CanAt(tt.perm, tt.location); got != tt.want {
+			t.Errorf("CanAt(%q, %d) = %v, want %v", tt.perm, tt.location, got, tt.want)
+		}
+	}
+
+	// Can counts a grant at any of their locations
+	if !s.Can("order:void_any") {
+		t.Error(`Can("order:void_any") = false, want true through north`)
+	}
+}
+
+func TestIsValidPermission(t *testing.T) {
+	for perm, want := range map[string]bool{
+		"order:delete":         true,
+		"order:delete@store-2": true,
+		"order:*":              true,
+		"!order:delete":        true,
+		"!order:*@store-3":     true,
+		"order:delete@":        false,
+		"order:launch":         false,
+		"!order:delte":         false,
+		"widget:*":             false,
+		"*":                    false,
+		"!":                    false,
+	} {
+		if got := IsValidPermission(perm); got != want {
+			t.Errorf("IsValidPermission(%q) = %v, want %v", perm, got, want)
+		}
+	}
+}
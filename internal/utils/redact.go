@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Fields can be hidden from callers who lack a permission by tagging them:
+//
+//	UnitCost int64 `perm:"inventory:cost"`
+//
+// Redact removes such fields from a response, so one endpoint can serve both
+// a manager and a cashier token. It follows the same JSON names as
+// encoding/json, through pointers, slices, maps and nested structs.
+
+// Redact returns v ready to be encoded as JSON, without the fields the
+// caller's Subject may not see. Values without tagged fields, and contexts
+// without a Subject (internal callers), are returned unchanged.
+func Redact(ctx context.Context, v any) (any, error) {
+	s, ok := SubjectFrom(ctx)
+	if !ok || v == nil || !hasPermFields(reflect.TypeOf(v)) {
+		return v, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber() // Keep int64 amounts exact
+	var out any
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	prune(reflect.ValueOf(v), out, s)
+	return out, nil
+}
+
+// Allowed reports whether the caller may see fields tagged with perm, for
+// output that isn't JSON (CSV exports). Like Redact, it allows everything
+// when the context carries no Subject.
+func Allowed(ctx context.Context, perm string) bool {
+	s, ok := SubjectFrom(ctx)
+	return !ok || s.Can(perm)
+}
+
+// prune walks v and its decoded JSON form side by side, deleting the keys of
+// fields s may not see.
+func prune(v reflect.Value, out any, s Subject) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		obj, ok := out.(map[string]any)
+		if !ok {
+			return // Has its own MarshalJSON
+		}
+		t := v.Type()
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, skip := jsonName(f)
+			if skip {
+				continue
+			}
+			if f.Anonymous && name == "" {
+				prune(v.Field(i), obj, s) // Embedded fields are flattened
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if perm := f.Tag.Get("perm"); perm != "" && !s.Can(perm) {
+				delete(obj, name)
+				continue
+			}
+			if child, ok := obj[name]; ok {
+				prune(v.Field(i), child, s)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		arr, ok := out.([]any)
+		if !ok {
+			return
+		}
+		for i := 0; i < v.Len() && i < len(arr); i++ {
+			prune(v.Index(i), arr[i], s)
+		}
+	case reflect.Map:
+		obj, ok := out.(map[string]any)
+		if !ok {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if child, ok := obj[fmt.Sprint(iter.Key().Interface())]; ok {
+				prune(iter.Value(), child, s)
+			}
+		}
+	}
+}
+
+// jsonName returns the name encoding/json uses for f, "" for the Go field
+// name, and skip for fields tagged "-".
+func jsonName(f reflect.StructField) (name string, skip bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ = strings.Cut(tag, ",")
+	return name, false
+}
+
+var permFieldTypes sync.Map // reflect.Type -> bool
+
+// hasPermFields reports whether t contains a perm-tagged field anywhere, so
+// most responses skip the re-encoding entirely.
+func hasPermFields(t reflect.Type) bool {
+	if cached, ok := permFieldTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	found := scanPermFields(t, map[reflect.Type]bool{})
+	permFieldTypes.Store(t, found)
+	return found
+}
+
+func scanPermFields(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return scanPermFields(t.Elem(), seen)
+	case reflect.Interface:
+		return true // Could hold anything, e.g. a map[string]any wrapper
+	case reflect.Struct:
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if f.Tag.Get("perm") != "" || scanPermFields(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
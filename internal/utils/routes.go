@@ -0,0 +1,14 @@
+package utils
+
+import "net/http"
+
+// Route declares one protected endpoint: its ServeMux pattern, the
+// permission the caller's role needs, and the handler. Handlers list their
+// routes and main wraps each one in Authorize, so no route can be registered
+// without deciding who may call it. Perm "" means any signed-in user, for
+// endpoints that only touch the caller's own data (/me, /devices).
+type Route struct {
+	Pattern string
+	Perm    string
+	Handler http.HandlerFunc
+}
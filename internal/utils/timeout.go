@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"context"
+	"time"
+)
+
+// A request still running once the server can no longer write its response
+// (its WriteTimeout) has its context cancelled, so the queries it runs stop
+// instead of holding connections for nobody. Handlers that stream for
+// longer lift the server's write deadline and this timeout together.
+
+const requestTimerKey ContextKey = "requestTimer"
+
+// WithRequestTimeout returns a copy of ctx that is cancelled after d unless
+// LiftRequestTimeout is called on it first; 0 leaves it to the caller.
+func WithRequestTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if d <= 0 {
+		return ctx, cancel
+	}
+	timer := time.AfterFunc(d, cancel)
+	return context.WithValue(ctx, requestTimerKey, timer), func() {
+		timer.Stop()
+		cancel()
+	}
+}
+
+// LiftRequestTimeout keeps the timeout of WithRequestTimeout from
+// cancelling ctx.
+func LiftRequestTimeout(ctx context.Context) {
+	if timer, ok := ctx.Value(requestTimerKey).(*time.Timer); ok {
+		timer.Stop()
+	}
+}